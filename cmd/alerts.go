@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/scanner"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// alertsCmd repräsentiert den alerts-Befehl
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Manage watch mode alerts",
+	Long: `Alerts deliver watch mode events to webhooks, programs, syslog or e-mail.
+
+Events: device-new, device-offline, device-back, flapping, ip-change, mac-change,
+cert-expiring
+
+Alerts are configured in the config file ($HOME/.netspy.yaml):
+
+  alerts:
+    - type: webhook
+      url: https://hooks.example.com/netspy
+      headers: {Authorization: "Bearer secret"}
+      events: [device-offline, device-back]
+      hold_down: 2m          # report offline only if still offline after 2m
+      retries: 3
+      retry_delay: 5s
+    - type: exec
+      command: /usr/local/bin/notify.sh   # NETSPY_* env vars, JSON on stdin
+      filter: "vendor=Synology"           # same syntax as the watch filter
+    - type: syslog
+      address: udp://logserver:514        # empty = local syslog
+    - type: smtp
+      host: mail.example.com
+      port: 587
+      from: netspy@example.com
+      to: [ops@example.com]
+      username: netspy
+      password: secret
+      events: [device-new, mac-change]
+      debounce: 1h           # same event for the same device at most once per hour`,
+}
+
+// alertsTestCmd sendet ein Test-Ereignis an alle Routen
+var alertsTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test event to all configured alerts",
+	Long: `Send a synthetic device-offline event to every configured alert
+(filters, hold-down and debounce are bypassed) and report delivery errors.`,
+	Args: cobra.NoArgs,
+	RunE: runAlertsTest,
+}
+
+func init() {
+	rootCmd.AddCommand(alertsCmd)
+	alertsCmd.AddCommand(alertsTestCmd)
+}
+
+// alertConfigs liest die Alert-Routen aus der Konfiguration
+func alertConfigs() ([]alert.Config, error) {
+	var configs []alert.Config
+	if err := viper.UnmarshalKey("alerts", &configs); err != nil {
+		return nil, fmt.Errorf("invalid alerts configuration: %v", err)
+	}
+	return configs, nil
+}
+
+// loadAlerts erstellt den Alert-Bus aus der Konfiguration (nil ohne Alerts)
+func loadAlerts() (*alert.Bus, error) {
+	configs, err := alertConfigs()
+	if err != nil || len(configs) == 0 {
+		return nil, err
+	}
+	return alert.NewBusFromConfig(configs)
+}
+
+func runAlertsTest(cmd *cobra.Command, args []string) error {
+	configs, err := alertConfigs()
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		color.Yellow("[INFO] No alerts configured (see 'netspy alerts --help')\n")
+		return nil
+	}
+
+	event := alert.NewEvent(alert.DeviceOffline, scanner.Host{
+		IP:         net.ParseIP("192.0.2.1"),
+		MAC:        "02:00:00:00:00:01",
+		Hostname:   "netspy-test",
+		DeviceType: "Test",
+	}, "offline", time.Now())
+	event.Network = "192.0.2.0/24"
+	event.Message = "netspy test alert: " + event.Summary()
+
+	failed := 0
+	for i, cfg := range configs {
+		// Direkt an den Sink - Filter, Hold-Down und Debounce der Route gelten für Tests nicht
+		route, err := alert.NewRoute(cfg)
+		if err != nil {
+			color.Red("[ERROR] alert %d (%s): %v\n", i+1, cfg.Type, err)
+			failed++
+			continue
+		}
+
+		if err := route.Sink.Send(cmd.Context(), event); err != nil {
+			color.Red("[ERROR] %s: %v\n", route.Sink.Name(), err)
+			failed++
+			continue
+		}
+		color.Green("[OK] %s\n", route.Sink.Name())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alerts failed", failed, len(configs))
+	}
+	return nil
+}
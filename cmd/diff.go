@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"netspy/pkg/crash"
+	"netspy/pkg/diff"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat string
+	diffIgnore []string
+)
+
+// diffCmd repräsentiert den diff-Befehl
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two scan results",
+	Long: `Compare two scan results written by "netspy scan -f json" or "netspy scan -f csv".
+
+Hosts are matched by MAC address first (detects IP changes), then by IP address
+(detects MAC changes). Reported changes:
+
+  new_host, vanished_host, ip_changed, mac_changed, hostname_changed,
+  vendor_changed, device_type_changed, ports_opened, ports_closed
+
+Exit code is 0 if the scans are identical and 1 if changes were found.
+
+Examples:
+  netspy diff yesterday.json today.json
+  netspy diff old.csv new.json --format markdown > report.md
+  netspy diff old.json new.json --ignore hostname_changed,device_type_changed`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "table", "Output format (table, json, markdown)")
+	diffCmd.Flags().StringSliceVar(&diffIgnore, "ignore", []string{}, "Change kinds to ignore (e.g. hostname_changed,ports_opened)")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	opts := diff.Options{Ignore: make(map[diff.ChangeKind]bool)}
+	for _, name := range diffIgnore {
+		kind, err := parseChangeKind(name)
+		if err != nil {
+			return err
+		}
+		opts.Ignore[kind] = true
+	}
+
+	oldHosts, err := diff.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	newHosts, err := diff.LoadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	result := diff.Compare(oldHosts, newHosts, opts)
+	if err := diff.Write(os.Stdout, result, diffFormat); err != nil {
+		return err
+	}
+
+	// Wie diff(1): Exit-Code 1 bei Unterschieden (Sentinel vorher entfernen - kein unsauberer Exit)
+	if result.HasChanges() {
+		crash.StopSentinel()
+		os.Exit(1)
+	}
+	return nil
+}
+
+// parseChangeKind prüft den Namen einer Änderungsart
+func parseChangeKind(name string) (diff.ChangeKind, error) {
+	names := make([]string, 0, len(diff.Kinds))
+	for _, kind := range diff.Kinds {
+		if strings.EqualFold(strings.TrimSpace(name), string(kind)) {
+			return kind, nil
+		}
+		names = append(names, string(kind))
+	}
+	return "", fmt.Errorf("unknown change kind %q (valid: %s)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"netspy/pkg/inventory"
+	"netspy/pkg/output"
+	"netspy/pkg/scanner"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var inventoryFormat string
+
+// inventoryCmd repräsentiert den inventory-Befehl
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory [network]",
+	Short: "Show the persistent device inventory",
+	Long: `Show all devices recorded in the inventory database.
+
+Devices are identified by their MAC address and keep their IP and hostname history
+across runs. The inventory is written by "netspy watch" (enabled by default) and by
+"netspy scan --record".
+
+Examples:
+  netspy inventory                        # All known devices
+  netspy inventory 192.168.1.0/24         # Devices last seen in this network
+  netspy inventory --format json          # Full history as JSON
+  netspy inventory --db ./lab.db          # Use a different database file`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: runInventory,
+}
+
+func init() {
+	rootCmd.AddCommand(inventoryCmd)
+
+	inventoryCmd.Flags().StringVarP(&inventoryFormat, "format", "f", "table", "Output format (table, json)")
+}
+
+// inventoryPath gibt den Pfad der Inventar-Datenbank zurück (--db oder Standard-Pfad)
+func inventoryPath() (string, error) {
+	if path := viper.GetString("db"); path != "" {
+		return path, nil
+	}
+	return inventory.DefaultPath()
+}
+
+// recordInventory speichert die Scan-Ergebnisse im Inventar (nur mit --record)
+func recordInventory(network *net.IPNet, hosts []scanner.Host) error {
+	if !recordScan {
+		return nil
+	}
+
+	path, err := inventoryPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine inventory path: %v", err)
+	}
+
+	store, err := inventory.Open(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if _, err := store.Record(inventory.Scan{Source: "scan", Mode: scanMode}, network, hosts); err != nil {
+		return fmt.Errorf("failed to record scan: %v", err)
+	}
+
+	if !isQuiet() && strings.ToLower(format) == "table" {
+		color.White("Recorded %d online hosts in %s\n", countOnline(hosts), path)
+	}
+	return nil
+}
+
+func runInventory(cmd *cobra.Command, args []string) error {
+	path, err := inventoryPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine inventory path: %v", err)
+	}
+
+	store, err := inventory.Open(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var devices []inventory.Device
+	if len(args) == 1 {
+		_, netCIDR, err := net.ParseCIDR(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid CIDR: %v", err)
+		}
+		devices, err = store.DevicesInNetwork(netCIDR)
+		if err != nil {
+			return err
+		}
+	} else {
+		devices, err = store.Devices()
+		if err != nil {
+			return err
+		}
+	}
+
+	if strings.ToLower(inventoryFormat) == "json" {
+		data, err := json.MarshalIndent(devices, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(devices) == 0 {
+		color.Yellow("[INFO] No devices in inventory %s\n", path)
+		return nil
+	}
+
+	color.Cyan("%-16s %-18s %-24s %-8s %-17s %-17s %s\n", "IP", "MAC", "Hostname", "Status", "First Seen", "Last Seen", "IPs")
+	for _, device := range devices {
+		hostname, _ := device.CurrentHostname()
+		line := fmt.Sprintf("%-16s %-18s %-24s %-8s %-17s %-17s %d\n",
+			device.CurrentIP(), device.MAC, output.Truncate(hostname, 24), device.Status,
+			device.FirstSeen.Format("2006-01-02 15:04"), device.LastSeen.Format("2006-01-02 15:04"), len(device.IPs))
+
+		if device.Status == "offline" {
+			color.New(color.FgRed).Print(line)
+		} else {
+			fmt.Print(line)
+		}
+	}
+	fmt.Printf("\n%d devices (%s)\n", len(devices), path)
+	return nil
+}
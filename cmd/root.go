@@ -55,18 +55,26 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initLearnedVendors)
+	cobra.OnInitialize(initConfig, initLearnedVendors, initRules)
 
 	// Globale Flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.netspy.yaml)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "verbose output")
 	rootCmd.PersistentFlags().Bool("quiet", false, "quiet output")
 	rootCmd.PersistentFlags().BoolVar(&FullOutput, "full-output", false, "show full output without truncation (hostnames, banners, etc.)")
+	rootCmd.PersistentFlags().String("db", "", "inventory database file (default is netspy/inventory.db in the user data directory)")
+	rootCmd.PersistentFlags().String("vendor-db", "", "IEEE vendor registry, index or oui.csv (default is netspy/oui-index.gz in the user data directory)")
+	rootCmd.PersistentFlags().String("rules", "", "device classification rules file (YAML, see \"netspy rules\")")
+	rootCmd.PersistentFlags().Bool("no-online-lookup", false, "never look up unknown MAC vendors online (api.macvendors.com)")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "show version information")
 
 	// Flags an Viper binden
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	_ = viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	_ = viper.BindPFlag("db", rootCmd.PersistentFlags().Lookup("db"))
+	_ = viper.BindPFlag("vendor-db", rootCmd.PersistentFlags().Lookup("vendor-db"))
+	_ = viper.BindPFlag("rules", rootCmd.PersistentFlags().Lookup("rules"))
+	_ = viper.BindPFlag("no-online-lookup", rootCmd.PersistentFlags().Lookup("no-online-lookup"))
 }
 
 // getVersion gibt die aktuelle Version zurück
@@ -99,4 +107,10 @@ func initLearnedVendors() {
 	// Versuche die Learned-Vendors-Datei zu laden
 	// Fehler werden ignoriert, da die Datei beim ersten Start nicht existiert
 	_ = discovery.InitLearnedVendors()
+	discovery.SetOnlineLookup(!viper.GetBool("no-online-lookup"))
+
+	// IEEE-Register wird erst beim ersten Lookup geladen (fehlt es, bleibt alles beim Alten)
+	if path, err := vendorRegistryPath(); err == nil {
+		discovery.SetOUIRegistryFile(path)
+	}
 }
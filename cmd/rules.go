@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"netspy/pkg/discovery"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// rulesCmd repräsentiert den rules-Befehl
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Show and check device classification rules",
+	Long: `Device types are determined by weighted classification rules. Every matching rule adds
+its weight to its device type; the type with the highest total wins. The confidence
+drops when rules for other types match as well. The watch details dialog and the JSON
+output ("classification") list the rules that fired.
+
+Custom rules are loaded with --rules (or "rules" in the config file). They are evaluated
+before the builtin rules; set "defaults: false" to replace the builtin rules entirely.
+
+Examples:
+  netspy rules show > rules.yaml          # Builtin rules as a template
+  netspy rules check rules.yaml           # Validate a rules file
+  netspy watch 192.168.1.0/24 --rules rules.yaml`,
+}
+
+// rulesShowCmd gibt die eingebauten Regeln aus
+var rulesShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the builtin rules as YAML",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _ = os.Stdout.Write(discovery.DefaultRulesYAML())
+	},
+}
+
+// rulesCheckCmd prüft eine Regeldatei
+var rulesCheckCmd = &cobra.Command{
+	Use:   "check <file>",
+	Short: "Validate a rules file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ruleset, err := discovery.LoadRules(args[0])
+		if err != nil {
+			return err
+		}
+		color.Green("%s: OK (%d rules including builtin rules)\n", args[0], ruleset.Len())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesShowCmd, rulesCheckCmd)
+}
+
+// initRules lädt die Klassifizierungsregeln aus --rules bzw. der Konfiguration
+func initRules() {
+	path := viper.GetString("rules")
+	if path == "" {
+		return
+	}
+	ruleset, err := discovery.LoadRules(path)
+	if err != nil {
+		cobra.CheckErr(fmt.Errorf("failed to load rules: %v", err))
+	}
+	discovery.SetRules(ruleset)
+}
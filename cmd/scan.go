@@ -1,10 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net"
-	"os/exec"
-	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,6 +12,8 @@ import (
 	"netspy/pkg/discovery"
 	"netspy/pkg/output"
 	"netspy/pkg/scanner"
+	"netspy/pkg/service"
+	"netspy/pkg/snmp"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -22,8 +24,24 @@ var (
 	concurrent int
 	timeout    time.Duration
 	format     string
+	portArgs   []string // --ports, z.B. "22,80,u:53,161"
 	ports      []int
+	udpPorts   []int
 	scanMode   string
+	scanIPv6   bool
+	recordScan bool
+
+	scanServices bool
+	scanCerts    bool
+	scanSNMP     bool
+	scanOS       bool
+	scanFilter   string
+	scanSort     []string
+	scanColumns  []string
+	outputOpts   output.Options
+
+	snmpCommunities []string           // --snmp-community
+	snmpCredentials []snmp.Credentials // Aus Config ("snmp:") bzw. --snmp-community
 )
 
 // scanCmd repräsentiert den scan-Befehl
@@ -40,12 +58,34 @@ Scan modes:
   hybrid:       ARP discovery + ping/port details (best accuracy + details)
   icmp:         ICMP ping scan (best for remote networks without open ports)
 
+Custom modes are probe pipelines joined with "+", e.g. "arp+icmp+tcp/22,3389".
+Named pipelines can be declared in the config file:
+
+  modes:
+    servers: "icmp+tcp/22,3389+dns"
+
+Available probes: ` + strings.Join(scanner.RegisteredProbes(), ", ") + `
+
 Examples:
   netspy scan 192.168.1.0/24                      # Conservative scan (default)
   netspy scan 192.168.1.0/24 --mode arp           # ARP scan only
   netspy scan 192.168.1.0/24 --mode hybrid        # ARP + ping details (recommended!)
   netspy scan 192.168.1.0/24 --mode hybrid --ports 22,80,443  # ARP + specific ports
-  netspy scan 10.10.1.0/24 --mode icmp            # ICMP ping (remote networks)`,
+  netspy scan 192.168.1.0/24 --mode hybrid --services          # + service/version detection
+  netspy scan 192.168.1.0/24 --mode hybrid --snmp              # + SNMP sysName, sysDescr, interfaces
+  netspy scan 10.0.0.0/24 --snmp-community public,netz         # SNMP with specific v2c communities
+  netspy scan 192.168.1.0/24 --mode hybrid --os --filter os=linux  # OS family from TTL, SYN-ACK and banners
+  netspy scan 192.168.1.0/24 -p 22,443,u:53,123,161           # TCP and UDP ports (u: = UDP)
+  netspy scan 10.10.1.0/24 --mode icmp            # ICMP ping (remote networks)
+  netspy scan 10.10.1.0/24 --mode "icmp+tcp/22,3389+dns"  # Custom probe pipeline
+  netspy scan fd00::/64                           # IPv6 neighbor discovery (local prefix)
+  netspy scan 192.168.1.0/24 --mode arp --record  # Record results in the device inventory
+
+Filtering, sorting and column selection (table, json and csv):
+  netspy scan 192.168.1.0/24 --mode hybrid --filter 'vendor=Apple && !port=22'
+  netspy scan 192.168.1.0/24 -p 9100 --filter 'port=9100' -f csv --columns ip,hostname,vendor
+  netspy scan 192.168.1.0/24 --sort rtt,-ip --columns ip,mac,vendor,ports
+  netspy scan 192.168.1.0/24 --mode hybrid --certs --filter 'expires<30d' --columns ip,hostname,cert`,
 	Args: cobra.ExactArgs(1),
 	RunE: runScan,
 }
@@ -57,8 +97,18 @@ func init() {
 	scanCmd.Flags().IntVarP(&concurrent, "concurrent", "c", 0, "Number of concurrent scans")
 	scanCmd.Flags().DurationVarP(&timeout, "timeout", "t", 0, "Timeout per host")
 	scanCmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, json, csv)")
-	scanCmd.Flags().IntSliceVarP(&ports, "ports", "p", []int{}, "Specific ports to scan")
-	scanCmd.Flags().StringVar(&scanMode, "mode", "conservative", "Scan mode (conservative, fast, thorough, arp, hybrid, icmp)")
+	scanCmd.Flags().StringSliceVarP(&portArgs, "ports", "p", []string{}, "Specific ports to scan, u: switches to UDP (e.g. 22,80,u:53,161)")
+	scanCmd.Flags().BoolVar(&scanIPv6, "ipv6", true, "Discover IPv6 neighbors and correlate them with IPv4 hosts by MAC (arp/hybrid modes)")
+	scanCmd.Flags().BoolVar(&recordScan, "record", false, "Record results in the persistent device inventory (see 'netspy inventory')")
+	scanCmd.Flags().StringVar(&scanMode, "mode", "conservative", "Scan mode (conservative, fast, thorough, arp, hybrid, icmp, config mode name or probe pipeline)")
+	scanCmd.Flags().BoolVar(&scanServices, "services", false, "Detect service, product and version on open ports (without --ports the common service ports are scanned)")
+	scanCmd.Flags().BoolVar(&scanCerts, "certs", false, "Collect TLS certificates (subject, SANs, issuer, validity, key) from TLS ports and open ports")
+	scanCmd.Flags().BoolVar(&scanSNMP, "snmp", false, "Query SNMP agents for sysName, sysDescr, location, uptime and interfaces (credentials from the 'snmp' config key, default community public)")
+	scanCmd.Flags().BoolVar(&scanOS, "os", false, "Guess the OS family from reply TTLs, TCP window and options of SYN-ACKs (raw socket, Linux) and banners")
+	scanCmd.Flags().StringSliceVar(&snmpCommunities, "snmp-community", nil, "SNMP v2c communities to try instead of the configured credentials (implies --snmp)")
+	scanCmd.Flags().StringVar(&scanFilter, "filter", "", "Only output hosts matching this filter expression (same syntax as the watch filter)")
+	scanCmd.Flags().StringSliceVar(&scanSort, "sort", nil, "Sort keys, prefix with - for descending (e.g. rtt,-ip)")
+	scanCmd.Flags().StringSliceVar(&scanColumns, "columns", nil, "Output columns ("+strings.Join(output.ColumnNames(), ", ")+")")
 }
 
 // isQuiet prüft ob quiet-Modus aktiviert ist
@@ -67,34 +117,62 @@ func isQuiet() bool {
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	network := args[0]
 
-	// Modus validieren
-	validModes := map[string]bool{
-		"conservative": true,
-		"fast":         true,
-		"thorough":     true,
-		"arp":          true,
-		"hybrid":       true,
-		"icmp":         true,
+	// Filter, Sortierung und Spalten vor dem Scan prüfen
+	outputOpts = output.Options{Filter: scanFilter, Sort: scanSort, Columns: scanColumns}
+	if err := outputOpts.Validate(); err != nil {
+		return err
+	}
+
+	// Ports aufteilen: TCP und nach "u:" UDP
+	var err error
+	if ports, udpPorts, err = scanner.ParsePorts(strings.Join(portArgs, ",")); err != nil {
+		return fmt.Errorf("invalid --ports: %v", err)
+	}
+
+	// SNMP-Zugangsdaten: --snmp-community ersetzt die Config
+	if len(snmpCommunities) > 0 {
+		scanSNMP = true
+	}
+	if scanSNMP {
+		if snmpCredentials, err = loadSNMPCredentials(snmpCommunities); err != nil {
+			return err
+		}
+	}
+
+	// Diensterkennung braucht offene Ports - ohne --ports die üblichen Dienst-Ports prüfen
+	if scanServices && len(ports) == 0 && len(udpPorts) == 0 {
+		ports = service.CommonPorts
+	}
+
+	// Modus auflösen (eingebauter Modus, Config-Modus oder Probe-Pipeline)
+	mode, err := resolveScanMode(scanMode)
+	if err != nil {
+		return err
 	}
-	if !validModes[scanMode] {
-		return fmt.Errorf("invalid scan mode: %s (valid: conservative, fast, thorough, arp, hybrid, icmp)", scanMode)
+
+	// IPv6-Präfixe lassen sich nicht durchzählen - Neighbor Discovery verwenden
+	if _, netCIDR, err := net.ParseCIDR(network); err == nil && netCIDR.IP.To4() == nil {
+		if mode == "arp" || mode == "hybrid" || len(discovery.GenerateIPsFromCIDR(netCIDR)) == 0 {
+			return runIPv6Scan(ctx, netCIDR)
+		}
 	}
 
 	// Hybrid-Scanning verwenden falls gewünscht
-	if scanMode == "hybrid" {
-		return runHybridScan(network)
+	if mode == "hybrid" {
+		return runHybridScan(ctx, network)
 	}
 
 	// ARP-Scanning verwenden falls gewünscht
-	if scanMode == "arp" {
-		return runARPScan(network)
+	if mode == "arp" {
+		return runARPScan(ctx, network)
 	}
 
 	// ICMP-Scanning verwenden falls gewünscht
-	if scanMode == "icmp" {
-		return runICMPScan(network)
+	if mode == "icmp" {
+		return runICMPScan(ctx, network)
 	}
 
 	// Netzwerk-Eingabe für normale Scans validieren
@@ -110,29 +188,52 @@ func runScan(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create scanner configuration
-	config := createScanConfig()
-	s := scanner.New(config)
+	config := createScanConfig(mode)
+	if config.Pipeline, err = buildPipeline(mode, config); err != nil {
+		return err
+	}
+	quiet := isQuiet()
 
 	// Scan-Info ausgeben (außer im quiet-Modus)
-	if !isQuiet() {
-		color.Cyan(" Scanning %s (%d hosts) in %s mode\n", network, len(hosts), scanMode)
+	if !quiet {
+		color.Cyan(" Scanning %s (%d hosts) in %s mode\n", network, len(hosts), mode)
 		color.White("  Workers: %d, Timeout: %v\n\n", config.Concurrency, config.Timeout)
+
+		config.Progress = func(p scanner.Progress) {
+			if p.Done%20 == 0 || p.Done == p.Total {
+				fmt.Printf(" %d/%d scanned, %d found (%.0f/sec)\n", p.Done, p.Total, p.Found, p.Rate())
+			}
+		}
 	}
 
-	// Scan durchführen
-	results, err := s.ScanHosts(hosts, nil)
+	// Scan durchführen - Ergebnisse werden gestreamt und hier eingesammelt
+	start := time.Now()
+	stream, err := scanner.New(config).Scan(ctx, hosts)
 	if err != nil {
 		return fmt.Errorf("scan failed: %v", err)
 	}
+	results := scanner.Collect(stream)
+
+	if !quiet {
+		elapsed := time.Since(start)
+		fmt.Printf("[OK] Scan completed in %.1fs (%.0f hosts/sec)\n",
+			elapsed.Seconds(), float64(len(hosts))/elapsed.Seconds())
+		fmt.Printf("[Summary] Found %d online hosts out of %d scanned\n\n", countOnline(results), len(hosts))
+	}
 
 	// Gateway-Flags setzen (heuristische Erkennung)
 	scanner.SetGatewayFlags(results, netCIDR)
 
 	// Ergebnisse ausgeben
-	return output.PrintResults(results, format)
+	if err := output.PrintResults(results, format, outputOpts); err != nil {
+		return err
+	}
+
+	// Im Inventar speichern (nur mit --record)
+	return recordInventory(netCIDR, results)
 }
 
-func runHybridScan(network string) error {
+func runHybridScan(ctx context.Context, network string) error {
 	quiet := isQuiet()
 
 	// Parse network
@@ -167,18 +268,23 @@ func runHybridScan(network string) error {
 			color.Cyan("Step 1: ARP-based host discovery...\n")
 		}
 
-		// Populate ARP table first
-		if !quiet {
-			color.Cyan("Populating ARP table...\n")
-		}
-		if err := populateARPTable(netCIDR); err != nil {
+		// Aktiver ARP-Sweep (Raw-Socket), sonst ARP-Tabelle befüllen und auslesen
+		var sweepErr error
+		arpHosts, sweepErr = sweepARP(ctx, netCIDR)
+		if sweepErr != nil {
 			if !quiet {
-				color.Yellow("[WARN] Warning: %v\n", err)
+				color.Yellow("[INFO] Active ARP sweep unavailable (%v), using system ARP table\n", sweepErr)
+				color.Cyan("Populating ARP table...\n")
+			}
+			if err := populateARPTable(netCIDR); err != nil {
+				if !quiet {
+					color.Yellow("[WARN] Warning: %v\n", err)
+				}
 			}
-		}
 
-		// Read ARP table
-		arpHosts = readCurrentARPTable(netCIDR)
+			// Read ARP table
+			arpHosts = readCurrentARPTable(netCIDR)
+		}
 		if !quiet {
 			color.Green("[OK] ARP found %d active hosts\n\n", len(arpHosts))
 		}
@@ -196,7 +302,7 @@ func runHybridScan(network string) error {
 		}
 
 		// Use ICMP scan instead of TCP
-		return runICMPScan(network)
+		return runICMPScan(ctx, network)
 	}
 
 	// Step 1.5: SSDP/UPnP Discovery für zusätzliche Device-Infos
@@ -218,24 +324,50 @@ func runHybridScan(network string) error {
 		}
 	}
 
+	// Step 1.6: DNS-SD - per mDNS beworbene Dienste (AirPlay, IPP, Chromecast, HomeKit, ...)
+	if !quiet {
+		color.Cyan("Step 1.6: DNS-SD service browsing (timeout 2s)...\n")
+	}
+	dnssdServices, err := discovery.BrowseDNSSD(ctx, 2*time.Second)
+	if err != nil {
+		if !quiet {
+			color.Yellow("[WARN] DNS-SD browsing failed: %v\n", err)
+		}
+	} else if !quiet {
+		color.Green("[OK] DNS-SD found services on %d devices\n\n", len(dnssdServices))
+	}
+
 	// Step 2: Ping + Port details for ARP-discovered hosts
 	if !quiet {
 		color.Cyan("Step 2: Getting ping/port details for discovered hosts...\n")
 	}
-	enhancedHosts := enhanceHostsWithDetails(arpHosts, ssdpDevices)
+	enhancedHosts, err := enhanceHostsWithDetails(ctx, arpHosts, ssdpDevices, dnssdServices)
+	if err != nil {
+		return err
+	}
 
 	if !quiet {
 		color.Green("[OK] Enhanced %d hosts with ping/port details\n\n", len(enhancedHosts))
 	}
 
+	// Step 3: IPv6-Nachbarn suchen und über die MAC zuordnen
+	if scanIPv6 {
+		enhancedHosts = discoverIPv6Neighbors(ctx, netCIDR, enhancedHosts, quiet)
+	}
+
 	// Gateway-Flags setzen (heuristische Erkennung)
 	scanner.SetGatewayFlags(enhancedHosts, netCIDR)
 
 	// Ergebnisse ausgeben
-	return output.PrintResults(enhancedHosts, format)
+	if err := output.PrintResults(enhancedHosts, format, outputOpts); err != nil {
+		return err
+	}
+
+	// Im Inventar speichern (nur mit --record)
+	return recordInventory(netCIDR, enhancedHosts)
 }
 
-func runARPScan(network string) error {
+func runARPScan(ctx context.Context, network string) error {
 	quiet := isQuiet()
 
 	// Parse network
@@ -266,6 +398,22 @@ func runARPScan(network string) error {
 
 	// Nur ARP versuchen wenn lokales Netzwerk
 	if isLocal {
+		// Aktiver ARP-Sweep über Raw-Socket (sendet who-has an jede Adresse)
+		if !quiet {
+			color.Cyan("Sending ARP requests to all addresses...\n")
+		}
+		sweepHosts, sweepErr := sweepARP(ctx, netCIDR)
+		if sweepErr == nil {
+			if !quiet {
+				color.Green("[OK] ARP sweep found %d hosts\n", len(sweepHosts))
+			}
+			finalHosts = sweepHosts
+		} else if !quiet {
+			color.Yellow("[INFO] Active ARP sweep unavailable (%v), using system ARP table\n", sweepErr)
+		}
+	}
+
+	if isLocal && len(finalHosts) == 0 {
 		// Step 1: Check current ARP table
 		if !quiet {
 			color.Cyan("Step 1: Checking current ARP table...\n")
@@ -307,17 +455,27 @@ func runARPScan(network string) error {
 		}
 
 		// Use ICMP scan instead of TCP
-		return runICMPScan(network)
+		return runICMPScan(ctx, network)
+	}
+
+	// IPv6-Nachbarn suchen und über die MAC zuordnen
+	if scanIPv6 {
+		finalHosts = discoverIPv6Neighbors(ctx, netCIDR, finalHosts, quiet)
 	}
 
 	// Gateway-Flags setzen (heuristische Erkennung)
 	scanner.SetGatewayFlags(finalHosts, netCIDR)
 
 	// Ergebnisse ausgeben
-	return output.PrintResults(finalHosts, format)
+	if err := output.PrintResults(finalHosts, format, outputOpts); err != nil {
+		return err
+	}
+
+	// Im Inventar speichern (nur mit --record)
+	return recordInventory(netCIDR, finalHosts)
 }
 
-func runICMPScan(network string) error {
+func runICMPScan(ctx context.Context, network string) error {
 	quiet := isQuiet()
 
 	// Parse network
@@ -330,21 +488,16 @@ func runICMPScan(network string) error {
 	ips := discovery.GenerateIPsFromCIDR(netCIDR)
 
 	if !quiet {
-		color.Cyan("ICMP scan: Using system ping command\n")
+		color.Cyan("ICMP scan: Using %s ICMP socket\n", discovery.SharedICMPEngine().Mode())
 		color.White("Strategy: ICMP echo request (best for remote networks without open TCP ports)\n")
 		color.Cyan("Scanning %d hosts...\n\n", len(ips))
 	}
 
-	var hosts []scanner.Host
-	var mutex sync.Mutex
-	var wg sync.WaitGroup
-
 	// Concurrency settings
 	concurrencyLimit := concurrent
 	if concurrencyLimit == 0 {
 		concurrencyLimit = 50 // Default for ICMP
 	}
-	semaphore := make(chan struct{}, concurrencyLimit)
 
 	// Timeout settings
 	pingTimeout := timeout
@@ -352,44 +505,26 @@ func runICMPScan(network string) error {
 		pingTimeout = 1000 * time.Millisecond
 	}
 
-	completed := int64(0)
-	start := time.Now()
-
-	for _, ip := range ips {
-		wg.Add(1)
-		go func(targetIP net.IP) {
-			defer wg.Done()
-
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// Perform ICMP ping using system command
-			success, rtt := icmpPing(targetIP.String(), pingTimeout)
-			if success {
-				host := scanner.Host{
-					IP:     targetIP,
-					RTT:    rtt,
-					Online: true,
-				}
-
-				mutex.Lock()
-				hosts = append(hosts, host)
-				mutex.Unlock()
-			}
-
-			// Progress tracking
-			if !quiet {
-				done := atomic.AddInt64(&completed, 1)
-				if done%50 == 0 || done == int64(len(ips)) {
-					elapsed := time.Since(start)
-					rate := float64(done) / elapsed.Seconds()
-					color.White("   Progress: %d/%d (%.0f/sec)\n", done, len(ips), rate)
-				}
+	// Zwei Echo-Requests pro Host über die gemeinsame ICMP-Engine (verlusttolerant)
+	config := scanner.Config{
+		Concurrency: concurrencyLimit,
+		Timeout:     pingTimeout,
+		Fast:        true,
+		ICMP:        true,
+	}
+	if !quiet {
+		config.Progress = func(p scanner.Progress) {
+			if p.Done%50 == 0 || p.Done == p.Total {
+				color.White("   Progress: %d/%d (%.0f/sec)\n", p.Done, p.Total, p.Rate())
 			}
-		}(ip)
+		}
 	}
 
-	wg.Wait()
+	results, err := scanner.New(config).Scan(ctx, ips)
+	if err != nil {
+		return fmt.Errorf("scan failed: %v", err)
+	}
+	hosts := scanner.Collect(results)
 
 	if !quiet {
 		color.Green("\n[OK] ICMP scan completed: %d hosts found\n\n", len(hosts))
@@ -399,54 +534,98 @@ func runICMPScan(network string) error {
 	scanner.SetGatewayFlags(hosts, netCIDR)
 
 	// Ergebnisse ausgeben
-	return output.PrintResults(hosts, format)
+	if err := output.PrintResults(hosts, format, outputOpts); err != nil {
+		return err
+	}
+
+	// Im Inventar speichern (nur mit --record)
+	return recordInventory(netCIDR, hosts)
 }
 
-// icmpPing performs an ICMP ping using the system ping command
-// Works on Windows, Linux, and macOS without admin rights
-func icmpPing(ip string, timeout time.Duration) (bool, time.Duration) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "windows":
-		// Windows: -n count, -w timeout in milliseconds
-		timeoutMs := int(timeout.Milliseconds())
-		if timeoutMs < 1 {
-			timeoutMs = 1
-		}
-		cmd = exec.Command("ping", "-n", "1", "-w", fmt.Sprintf("%d", timeoutMs), ip)
-	case "darwin":
-		// macOS: -c count, -W timeout in milliseconds
-		timeoutMs := int(timeout.Milliseconds())
-		if timeoutMs < 1 {
-			timeoutMs = 1
-		}
-		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutMs), ip)
-	default:
-		// Linux: -c count, -W timeout in seconds (minimum 1)
-		timeoutSec := int(timeout.Seconds())
-		if timeoutSec < 1 {
-			timeoutSec = 1
+// discoverIPv6Neighbors ergänzt die Hosts um IPv6-Adressen (ICMPv6 an ff02::1 + NDP-Tabelle)
+func discoverIPv6Neighbors(ctx context.Context, network *net.IPNet, hosts []scanner.Host, quiet bool) []scanner.Host {
+	if !quiet {
+		color.Cyan("IPv6 neighbor discovery (ICMPv6 echo to ff02::1)...\n")
+	}
+
+	before := len(hosts)
+	result, err := scanner.DiscoverIPv6(ctx, network, hosts, time.Second)
+	if err != nil {
+		if !quiet {
+			color.Yellow("[INFO] IPv6 neighbor discovery unavailable: %v\n\n", err)
 		}
-		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutSec), ip)
+		return hosts
+	}
+
+	if !quiet {
+		dualStack := 0
+		for _, host := range result[:before] {
+			if len(host.IPv6) > 0 {
+				dualStack++
+			}
+		}
+		color.Green("[OK] %d dual-stack hosts, %d IPv6-only hosts\n\n", dualStack, len(result)-before)
+	}
+	return result
+}
+
+// runIPv6Scan sucht Hosts in einem lokalen IPv6-Präfix per Neighbor Discovery
+func runIPv6Scan(ctx context.Context, network *net.IPNet) error {
+	quiet := isQuiet()
+
+	iface, _, err := discovery.InterfaceForNetwork(network)
+	if err != nil {
+		return fmt.Errorf("IPv6 prefix %s is not on a local interface (large IPv6 prefixes cannot be swept): %v", network, err)
 	}
 
-	startTime := time.Now()
-	err := cmd.Run()
-	rtt := time.Since(startTime)
+	if !quiet {
+		color.Cyan("IPv6 scan: Neighbor discovery on %s\n", iface.Name)
+		color.White("Strategy: ICMPv6 echo to all-nodes multicast (ff02::1) + neighbor table\n\n")
+	}
 
+	neighbors, err := discovery.DiscoverIPv6Neighbors(ctx, iface, nil, 2*time.Second)
 	if err != nil {
-		return false, 0
+		return fmt.Errorf("IPv6 neighbor discovery failed: %v", err)
+	}
+
+	// Hosts unter ihrer Adresse im gesuchten Präfix melden
+	var hosts []scanner.Host
+	for _, host := range scanner.CorrelateIPv6(nil, neighbors) {
+		for _, addr := range host.IPv6 {
+			if network.Contains(addr.IP) {
+				host.IP = addr.IP
+				hosts = append(hosts, host)
+				break
+			}
+		}
+	}
+
+	if !quiet {
+		color.Green("[OK] IPv6 scan completed: %d hosts found\n\n", len(hosts))
 	}
-	return true, rtt
+
+	// Gateway-Flags setzen (heuristische Erkennung)
+	scanner.SetGatewayFlags(hosts, network)
+
+	// Ergebnisse ausgeben
+	if err := output.PrintResults(hosts, format, outputOpts); err != nil {
+		return err
+	}
+
+	// Im Inventar speichern (nur mit --record)
+	return recordInventory(network, hosts)
 }
 
-func enhanceHostsWithDetails(arpHosts []scanner.Host, ssdpDevices map[string]discovery.SSDPDevice) []scanner.Host {
+func enhanceHostsWithDetails(ctx context.Context, arpHosts []scanner.Host, ssdpDevices map[string]discovery.SSDPDevice, dnssdServices map[string][]discovery.DNSSDService) ([]scanner.Host, error) {
+	pipeline, err := hybridPipeline(ssdpDevices, dnssdServices)
+	if err != nil {
+		return nil, err
+	}
+
 	var enhancedHosts []scanner.Host
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 
-	// Create a simple pinger for RTT measurement
 	semaphore := make(chan struct{}, 20) // Limit concurrency
 
 	for _, host := range arpHosts {
@@ -458,105 +637,125 @@ func enhanceHostsWithDetails(arpHosts []scanner.Host, ssdpDevices map[string]dis
 			defer func() { <-semaphore }()
 
 			// Enhance this host with ping/port details
-			enhanced := enhanceHost(h, ssdpDevices)
+			pipeline.Run(ctx, &h)
 
 			mutex.Lock()
-			enhancedHosts = append(enhancedHosts, enhanced)
+			enhancedHosts = append(enhancedHosts, h)
 			mutex.Unlock()
 		}(host)
 	}
 
 	wg.Wait()
-	return enhancedHosts
+	return enhancedHosts, nil
 }
 
-func enhanceHost(host scanner.Host, ssdpDevices map[string]discovery.SSDPDevice) scanner.Host {
-	// Start with ARP data (IP, MAC, Vendor)
-	enhanced := host
+// hybridPipeline baut die Probe-Pipeline für die Detail-Phase des Hybrid-Scans:
+// TCP-RTT, Hostname (DNS, mDNS, SSDP), DNS-SD, Ports, HTTP-Banner, Dienste, Zertifikate, SNMP und OS
+func hybridPipeline(ssdpDevices map[string]discovery.SSDPDevice, dnssdServices map[string][]discovery.DNSSDService) (*scanner.Pipeline, error) {
+	config := scanner.Config{Timeout: 500 * time.Millisecond, Ports: ports, UDPPorts: udpPorts, SNMP: snmpCredentials}
 
-	// Add RTT via TCP connect on common ports
-	start := time.Now()
-	// Try common web ports first (most devices)
-	if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.IP.String(), "80"), 500*time.Millisecond); err == nil {
-		_ = conn.Close() // Ignore close error
-		enhanced.RTT = time.Since(start)
-	} else if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.IP.String(), "443"), 500*time.Millisecond); err == nil {
-		_ = conn.Close() // Ignore close error
-		enhanced.RTT = time.Since(start)
-	} else if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.IP.String(), "22"), 500*time.Millisecond); err == nil {
-		_ = conn.Close() // Ignore close error
-		enhanced.RTT = time.Since(start)
-	} else if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.IP.String(), "445"), 500*time.Millisecond); err == nil {
-		// Port 445 (SMB) - always open on Windows systems
-		_ = conn.Close() // Ignore close error
-		enhanced.RTT = time.Since(start)
-	} else if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.IP.String(), "135"), 500*time.Millisecond); err == nil {
-		// Port 135 (RPC) - Windows RPC endpoint mapper
-		_ = conn.Close() // Ignore close error
-		enhanced.RTT = time.Since(start)
-	}
-
-	// Add hostname if not already present - use fast resolution for scans
-	if enhanced.Hostname == "" {
-		result := discovery.ResolveFast(host.IP, 500*time.Millisecond)
-		if result.Hostname != "" {
-			enhanced.Hostname = result.Hostname
-			enhanced.HostnameSource = result.Source
-		}
-	}
-
-	// Fallback to SSDP device name if still no hostname
-	if enhanced.Hostname == "" {
-		if ssdpDevice, found := ssdpDevices[host.IP.String()]; found {
-			deviceName := discovery.GetSSDPDeviceName(ssdpDevice)
-			if deviceName != "" {
-				enhanced.Hostname = deviceName
-				enhanced.HostnameSource = "SSDP"
-			}
-		}
+	pipeline, err := scanner.ParsePipeline("tcp/80,443,22,445,135+dns+mdns", config)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.Probes = append(pipeline.Probes, scanner.NewSSDPProbe(ssdpDevices))
+	if dnssdServices != nil {
+		pipeline.Probes = append(pipeline.Probes, scanner.NewDNSSDProbe(dnssdServices))
 	}
 
-	// Add port scanning if requested
-	if len(ports) > 0 {
-		enhanced.Ports = scanSpecificPorts(host.IP, ports)
+	tail := []string{"http"}
+	if len(ports) > 0 || len(udpPorts) > 0 {
+		tail = []string{"ports", "http"}
+	}
+	if scanServices {
+		tail = append(tail, "services")
+	}
+	if scanCerts {
+		tail = append(tail, "tls")
+	}
+	if scanSNMP {
+		tail = append(tail, "snmp")
+	}
+	if scanOS {
+		tail = append(tail, "os")
 	}
+	for _, spec := range tail {
+		probe, err := scanner.NewProbe(spec, config)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Probes = append(pipeline.Probes, probe)
+	}
+
+	return pipeline, nil
+}
 
-	// Grab HTTP banner from common web ports
-	if banner := discovery.GrabHTTPBanner(host.IP.String(), 2*time.Second); banner != nil {
-		enhanced.HTTPBanner = banner.String()
+// resolveScanMode löst einen Modus-Namen auf. Eingebaute Modi werden unverändert
+// zurückgegeben, Modi aus der Config ("modes:") und freie Angaben wie
+// "arp+icmp+tcp/22" werden als Probe-Pipeline zurückgegeben.
+func resolveScanMode(mode string) (string, error) {
+	if custom, ok := viper.GetStringMapString("modes")[strings.ToLower(mode)]; ok {
+		mode = custom
 	}
 
-	// Detect device type based on available information
-	enhanced.DeviceType = discovery.DetectDeviceType(
-		enhanced.Hostname,
-		enhanced.MAC,
-		enhanced.Vendor,
-		enhanced.Ports,
-	)
+	switch mode {
+	case "arp", "hybrid":
+		return mode, nil
+	}
+	if _, ok := scanner.BuiltinMode(mode); ok {
+		return mode, nil
+	}
 
-	return enhanced
+	if _, err := scanner.ParsePipeline(mode, scanner.Config{}); err != nil {
+		return "", fmt.Errorf("invalid scan mode %q: %v (valid: conservative, fast, thorough, arp, hybrid, icmp or a probe pipeline)", mode, err)
+	}
+	return mode, nil
 }
 
-func scanSpecificPorts(ip net.IP, portList []int) []int {
-	var openPorts []int
-	var mutex sync.Mutex
-	var wg sync.WaitGroup
+// buildPipeline erzeugt die Probe-Pipeline für einen aufgelösten Modus.
+// Mit --ports, --services, --certs, --snmp und --os werden Port-, Dienst-, TLS-, SNMP-
+// und OS-Probe angehängt, falls die Pipeline sie nicht enthält.
+func buildPipeline(mode string, config scanner.Config) (*scanner.Pipeline, error) {
+	spec := mode
+	if builtin, ok := scanner.BuiltinMode(mode); ok {
+		spec = builtin
+	}
 
-	for _, port := range portList {
-		wg.Add(1)
-		go func(p int) {
-			defer wg.Done()
-			if conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip.String(), p), 300*time.Millisecond); err == nil {
-				_ = conn.Close() // Ignore close error
-				mutex.Lock()
-				openPorts = append(openPorts, p)
-				mutex.Unlock()
-			}
-		}(port)
+	pipeline, err := scanner.ParsePipeline(spec, config)
+	if err != nil {
+		return nil, err
+	}
+	extra := ""
+	if (len(config.Ports) > 0 || len(config.UDPPorts) > 0) && !pipeline.Has("ports") {
+		extra += "+ports"
+	}
+	if scanServices && !pipeline.Has("services") {
+		extra += "+services"
 	}
+	if scanCerts && !pipeline.Has("tls") {
+		extra += "+tls"
+	}
+	if scanSNMP && !pipeline.Has("snmp") {
+		extra += "+snmp"
+	}
+	if scanOS && !pipeline.Has("os") {
+		extra += "+os"
+	}
+	if extra != "" {
+		return scanner.ParsePipeline(spec+extra, config)
+	}
+	return pipeline, nil
+}
 
-	wg.Wait()
-	return openPorts
+// sweepARP führt einen aktiven ARP-Sweep durch (Linux, benötigt CAP_NET_RAW)
+func sweepARP(ctx context.Context, network *net.IPNet) ([]scanner.Host, error) {
+	arpScanner := discovery.NewARPScanner(500 * time.Millisecond)
+	arpEntries, err := arpScanner.Sweep(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+
+	return arpEntriesToHosts(arpEntries), nil
 }
 
 func readCurrentARPTable(network *net.IPNet) []scanner.Host {
@@ -568,18 +767,22 @@ func readCurrentARPTable(network *net.IPNet) []scanner.Host {
 		return nil
 	}
 
-	// Convert ARPEntry to scanner.Host
+	return arpEntriesToHosts(arpEntries)
+}
+
+// arpEntriesToHosts konvertiert ARP-Einträge in scanner.Host
+func arpEntriesToHosts(arpEntries []discovery.ARPEntry) []scanner.Host {
 	var hosts []scanner.Host
 	for _, entry := range arpEntries {
 		vendor := discovery.GetMACVendor(entry.MAC.String())
 		host := scanner.Host{
-			IP:         entry.IP,
-			MAC:        entry.MAC.String(),
-			Vendor:     vendor,
-			RTT:        entry.RTT,
-			Online:     entry.Online,
-			DeviceType: discovery.DetectDeviceType("", entry.MAC.String(), vendor, nil),
+			IP:     entry.IP,
+			MAC:    entry.MAC.String(),
+			Vendor: vendor,
+			RTT:    entry.RTT,
+			Online: entry.Online,
 		}
+		scanner.Classify(&host)
 		hosts = append(hosts, host)
 	}
 
@@ -645,18 +848,53 @@ func populateARPTable(network *net.IPNet) error {
 	return nil
 }
 
-func createScanConfig() scanner.Config {
+// countOnline zählt die Online-Hosts einer Ergebnisliste
+func countOnline(hosts []scanner.Host) int {
+	count := 0
+	for _, h := range hosts {
+		if h.Online {
+			count++
+		}
+	}
+	return count
+}
+
+// loadSNMPCredentials liest die SNMP-Zugangsdaten aus der Config (Schlüssel "snmp");
+// communities ersetzen sie durch v2c-Communities
+func loadSNMPCredentials(communities []string) ([]snmp.Credentials, error) {
+	var credentials []snmp.Credentials
+	if len(communities) > 0 {
+		for _, community := range communities {
+			credentials = append(credentials, snmp.Credentials{Community: community})
+		}
+	} else if err := viper.UnmarshalKey("snmp", &credentials); err != nil {
+		return nil, fmt.Errorf("invalid snmp configuration: %v", err)
+	}
+
+	for i, creds := range credentials {
+		normalized, err := creds.Normalize()
+		if err != nil {
+			return nil, fmt.Errorf("invalid snmp configuration (entry %d): %v", i+1, err)
+		}
+		credentials[i] = normalized
+	}
+	return credentials, nil
+}
+
+func createScanConfig(mode string) scanner.Config {
 	config := scanner.Config{
 		Concurrency: concurrent,
 		Timeout:     timeout,
 		Ports:       ports,
-		Fast:        scanMode == "fast",
-		Thorough:    scanMode == "thorough",
+		UDPPorts:    udpPorts,
+		SNMP:        snmpCredentials,
+		Fast:        mode == "fast",
+		Thorough:    mode == "thorough",
 		Quiet:       isQuiet(),
 	}
 
 	// Conservative defaults to avoid false positives
-	switch scanMode {
+	switch mode {
 	case "thorough":
 		if config.Concurrency == 0 {
 			config.Concurrency = 20
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/paths"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	vendorsReplace      bool
+	vendorsListLearned  bool
+	vendorsListOverride bool
+	vendorsListBuiltin  bool
+	vendorsExportFormat string
+	vendorsExportSource []string
+)
+
+// vendorsCmd repräsentiert den vendors-Befehl
+var vendorsCmd = &cobra.Command{
+	Use:   "vendors",
+	Short: "Manage the MAC vendor database",
+	Long: `Manage the database used to resolve MAC addresses to vendor names.
+
+Vendors are resolved in this order:
+  override   Entries added with "netspy vendors add" (longest prefix wins)
+  ieee       28-bit (MA-M) and 36-bit (MA-S) assignments of the imported IEEE registry
+  learned    Vendors learned online from api.macvendors.com
+  builtin    The builtin OUI list
+  ieee       24-bit (MA-L) assignments of the imported IEEE registry
+
+Without an imported registry, unknown vendors are looked up online in the background
+(disable with --no-online-lookup). After "netspy vendors import", lookups are done
+fully offline. Learned vendors and overrides are stored in the user data directory.
+
+Examples:
+  netspy vendors lookup 3c:22:fb:12:34:56
+  netspy vendors list --learned
+  netspy vendors add 02:42:ac "Docker Bridge"
+  netspy vendors remove 02:42:ac
+  netspy vendors export --format json > vendors.json`,
+}
+
+// vendorsLookupCmd zeigt, welche Quelle eine MAC auflöst
+var vendorsLookupCmd = &cobra.Command{
+	Use:   "lookup <mac>...",
+	Short: "Show the vendor of MAC addresses and which source answered",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runVendorsLookup,
+}
+
+// vendorsListCmd listet gelernte Vendors und Overrides
+var vendorsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List overrides and learned vendors",
+	Long: `List the vendor entries managed by netspy. Without flags, overrides and learned
+vendors are shown.`,
+	Args: cobra.NoArgs,
+	RunE: runVendorsList,
+}
+
+// vendorsAddCmd legt einen Override an
+var vendorsAddCmd = &cobra.Command{
+	Use:   "add <prefix> <vendor>",
+	Short: "Override the vendor for a MAC prefix",
+	Long: `Override the vendor for a MAC prefix (6 to 12 hex digits, e.g. 3c:22:fb, f8:b5:68:d
+or a full MAC address). Overrides take precedence over all other sources.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runVendorsAdd,
+}
+
+// vendorsRemoveCmd entfernt Overrides bzw. gelernte Vendors
+var vendorsRemoveCmd = &cobra.Command{
+	Use:   "remove <prefix>",
+	Short: "Remove an override or a learned vendor",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runVendorsRemove,
+}
+
+// vendorsExportCmd exportiert die Hersteller-Datenbank
+var vendorsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the vendor database as CSV or JSON",
+	Args:  cobra.NoArgs,
+	RunE:  runVendorsExport,
+}
+
+// vendorsImportCmd importiert die IEEE-Register
+var vendorsImportCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import IEEE OUI registries for offline vendor lookups",
+	Long: `Import the IEEE MAC address registries into a compact local index.
+
+Download the CSV files from https://standards-oui.ieee.org:
+  oui.csv     MA-L (24-bit prefixes)
+  mam.csv     MA-M (28-bit prefixes)
+  oui36.csv   MA-S (36-bit prefixes)
+  iab.csv     IAB  (36-bit prefixes, legacy)
+
+Entries are merged into the existing index unless --replace is given. The index is
+stored as netspy/oui-index.gz in the user data directory (see --vendor-db).
+
+Examples:
+  netspy vendors import oui.csv mam.csv oui36.csv
+  netspy vendors import --replace oui.csv`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runVendorsImport,
+}
+
+func init() {
+	rootCmd.AddCommand(vendorsCmd)
+	vendorsCmd.AddCommand(vendorsImportCmd, vendorsLookupCmd, vendorsListCmd, vendorsAddCmd, vendorsRemoveCmd, vendorsExportCmd)
+
+	vendorsImportCmd.Flags().BoolVar(&vendorsReplace, "replace", false, "Replace the existing index instead of merging")
+	vendorsListCmd.Flags().BoolVar(&vendorsListLearned, "learned", false, "List vendors learned online")
+	vendorsListCmd.Flags().BoolVar(&vendorsListOverride, "overrides", false, "List overrides")
+	vendorsListCmd.Flags().BoolVar(&vendorsListBuiltin, "builtin", false, "List the builtin OUI list")
+	vendorsExportCmd.Flags().StringVarP(&vendorsExportFormat, "format", "f", "csv", "Output format (csv, json)")
+	vendorsExportCmd.Flags().StringSliceVar(&vendorsExportSource, "source", []string{"override", "learned", "builtin", "ieee"}, "Sources to export (override, learned, builtin, ieee)")
+}
+
+// vendorRegistryPath gibt den Pfad des IEEE-Index zurück (--vendor-db oder Standard-Pfad)
+func vendorRegistryPath() (string, error) {
+	if path := viper.GetString("vendor-db"); path != "" {
+		return path, nil
+	}
+	return paths.DataFile("oui-index.gz")
+}
+
+func runVendorsImport(cmd *cobra.Command, args []string) error {
+	path, err := vendorRegistryPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine vendor index path: %v", err)
+	}
+
+	registry := discovery.NewOUIRegistry()
+	if !vendorsReplace {
+		existing, err := discovery.LoadOUIRegistry(path)
+		switch {
+		case err == nil:
+			registry = existing
+		case !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("failed to read existing index (use --replace to overwrite): %v", err)
+		}
+	}
+
+	for _, file := range args {
+		f, err := os.Open(file) // #nosec G304 -- vom Benutzer angegebene Datei
+		if err != nil {
+			return err
+		}
+		entries, err := discovery.ParseIEEERegistry(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for _, entry := range entries {
+			registry.Add(entry)
+		}
+		if !isQuiet() {
+			fmt.Printf("%-24s %d entries\n", file, len(entries))
+		}
+	}
+
+	if err := registry.SaveIndex(path); err != nil {
+		return fmt.Errorf("failed to write vendor index: %v", err)
+	}
+
+	counts := registry.Counts()
+	color.Green("Imported %d assignments (MA-L %d, MA-M %d, MA-S %d) into %s\n",
+		registry.Len(), counts["MA-L"], counts["MA-M"], counts["MA-S"], path)
+	return nil
+}
+
+// activeVendorRegistry gibt das IEEE-Register zurück und warnt, wenn es nicht lesbar ist
+func activeVendorRegistry() *discovery.OUIRegistry {
+	registry, err := discovery.ActiveOUIRegistry()
+	if err != nil {
+		color.Yellow("[WARN] IEEE registry not loaded: %v\n", err)
+	}
+	return registry
+}
+
+func runVendorsLookup(cmd *cobra.Command, args []string) error {
+	activeVendorRegistry()
+
+	color.Cyan("%-18s %-26s %-9s %s\n", "MAC", "Vendor", "Source", "Prefix")
+	for _, mac := range args {
+		match := discovery.LookupVendor(mac)
+		if match.Vendor == "" {
+			color.Yellow("%-18s %-26s %-9s %s\n", mac, "unknown", "-", "-")
+			continue
+		}
+		line := fmt.Sprintf("%-18s %-26s %-9s %s", mac, match.Vendor, match.Source, match.Prefix)
+		if match.Organization != "" && match.Organization != match.Vendor {
+			line += " (" + match.Organization + ")"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runVendorsList(cmd *cobra.Command, args []string) error {
+	showAll := !vendorsListLearned && !vendorsListOverride && !vendorsListBuiltin
+
+	var entries []discovery.VendorEntry
+	if showAll || vendorsListOverride {
+		entries = append(entries, discovery.VendorOverrideEntries()...)
+	}
+	if showAll || vendorsListLearned {
+		entries = append(entries, discovery.LearnedVendorEntries()...)
+	}
+	if vendorsListBuiltin {
+		entries = append(entries, discovery.BuiltinVendorEntries()...)
+	}
+
+	learnedFile, overridesFile := discovery.VendorFiles()
+	if len(entries) == 0 {
+		color.Yellow("[INFO] No vendor entries (%s, %s)\n", overridesFile, learnedFile)
+		return nil
+	}
+
+	color.Cyan("%-18s %-9s %s\n", "Prefix", "Source", "Vendor")
+	for _, entry := range entries {
+		fmt.Printf("%-18s %-9s %s\n", entry.Prefix, entry.Source, entry.Vendor)
+	}
+	fmt.Printf("\n%d entries (%s, %s)\n", len(entries), overridesFile, learnedFile)
+	return nil
+}
+
+func runVendorsAdd(cmd *cobra.Command, args []string) error {
+	vendor := strings.Join(args[1:], " ")
+	if err := discovery.AddVendorOverride(args[0], vendor); err != nil {
+		return err
+	}
+	match := discovery.LookupVendor(args[0])
+	color.Green("Override %s = %s\n", match.Prefix, vendor)
+	return nil
+}
+
+func runVendorsRemove(cmd *cobra.Command, args []string) error {
+	override, err := discovery.RemoveVendorOverride(args[0])
+	if err != nil {
+		return err
+	}
+	learned, err := discovery.ForgetLearnedVendor(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case override && learned:
+		color.Green("Removed override and learned vendor for %s\n", args[0])
+	case override:
+		color.Green("Removed override for %s\n", args[0])
+	case learned:
+		color.Green("Removed learned vendor for %s\n", args[0])
+	default:
+		return fmt.Errorf("no override or learned vendor for %s", args[0])
+	}
+	return nil
+}
+
+func runVendorsExport(cmd *cobra.Command, args []string) error {
+	var entries []discovery.VendorEntry
+	for _, source := range vendorsExportSource {
+		switch strings.ToLower(strings.TrimSpace(source)) {
+		case "override":
+			entries = append(entries, discovery.VendorOverrideEntries()...)
+		case "learned":
+			entries = append(entries, discovery.LearnedVendorEntries()...)
+		case "builtin":
+			entries = append(entries, discovery.BuiltinVendorEntries()...)
+		case "ieee":
+			if registry := activeVendorRegistry(); registry != nil {
+				entries = append(entries, registry.VendorEntries()...)
+			}
+		default:
+			return fmt.Errorf("unknown source %q (use override, learned, builtin, ieee)", source)
+		}
+	}
+
+	switch strings.ToLower(vendorsExportFormat) {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{"prefix", "vendor", "source"})
+		for _, entry := range entries {
+			_ = w.Write([]string{entry.Prefix, entry.Vendor, entry.Source})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unknown format %q (use csv, json)", vendorsExportFormat)
+	}
+}
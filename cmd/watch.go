@@ -1,21 +1,63 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"netspy/pkg/alert"
+	"netspy/pkg/crash"
+	"netspy/pkg/discovery"
+	"netspy/pkg/metrics"
+	"netspy/pkg/scanner"
 	"netspy/pkg/watch"
+	"netspy/pkg/web"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
 	watchInterval time.Duration
 	watchMode     string
-	maxThreads    int // Maximum concurrent threads (0 = auto-calculate based on network size)
+	maxThreads    int  // Maximum concurrent threads (0 = auto-calculate based on network size)
+	watchIPv6     bool // IPv6-Nachbarn suchen und zuordnen
+	watchInv      bool // Geräte im persistenten Inventar speichern
+
+	// Headless-Modus (NDJSON statt Oberfläche)
+	watchHeadless bool
+	watchSnapshot bool
+	watchOutput   string
+	watchMaxSize  int
+	watchMaxFiles int
+
+	// Passiver Modus (nur mitlesen, nichts senden)
+	watchPassive        bool
+	watchInterface      string
+	watchPcap           string
+	watchPassiveTimeout time.Duration
+
+	watchNeighbors bool // LLDP/CDP-Ankündigungen auswerten
 )
 
+// handlesSignals ist gesetzt, solange ein Befehl Ctrl+C/SIGTERM selbst behandelt
+var handlesSignals atomic.Bool
+
+// HandlesSignals meldet, ob der laufende Befehl sich bei Ctrl+C/SIGTERM selbst sauber
+// beendet (z.B. "watch --headless" schreibt noch ausstehende Ereignisse weg)
+func HandlesSignals() bool {
+	return handlesSignals.Load()
+}
+
 // watchCmd repräsentiert den watch-Befehl
 var watchCmd = &cobra.Command{
 	Use:   "watch [network]",
@@ -24,16 +66,71 @@ var watchCmd = &cobra.Command{
 
 Monitors the network at regular intervals and reports when devices appear or disappear.
 Tracks timestamps for when each device was first seen, last seen, and status changes.
+Devices are stored in the persistent inventory (keyed by MAC), so this history
+survives restarts. Use --inventory=false to disable it.
+
+Events (device-new, device-offline, device-back, flapping, ip-change, mac-change,
+cert-expiring) can be sent to webhooks, programs, syslog or e-mail - see
+"netspy alerts --help".
+
+With --certs the TLS ports of online hosts (443, 465, 636, 853, 993, 995, 8443,
+8883 and the open ports found by the scan) are checked once per hour. The
+certificates are shown in the host details and the snapshot; certificates that
+expire within --cert-warn-days are reported as cert-expiring (once, and again
+when they have expired).
+
+With --passive no packets are sent at all: netspy listens on the interface of
+the network (or --interface, needs root or CAP_NET_RAW) and builds the device
+list from the ARP, DHCP, mDNS, SSDP, NetBIOS, LLMNR and LLDP/CDP traffic it sees.
+Devices that stay silent for --passive-timeout are reported offline. With
+--pcap a capture file (pcap, Ethernet or Linux cooked) is read instead; headless
+this reports the devices once and exits.
+
+Switches, access points and IP phones announce themselves via LLDP/CDP. netspy
+listens for these announcements on the interface of the network (needs
+CAP_NET_RAW, local networks only, disable with --neighbors=false): the header
+shows the switch port this host is connected to, the host details show port,
+VLAN and management address of network equipment.
 
 If no network is specified, you'll be prompted to select from available network interfaces.
 
+With --headless no UI is started: every state change is written as one JSON object
+per line (NDJSON) to stdout or to a file (--output, rotated at --max-size). Devices
+found by the first scan are reported as device-new with "initial": true. Add
+--snapshot to also write the complete device list after every scan. Ctrl+C or
+SIGTERM stops the scan loop and flushes pending alerts before exiting.
+
+With --listen an HTTP server is started next to the UI (or headless stream):
+a web dashboard mirroring the table, a REST API (/api/devices, /api/devices/<ip>,
+/api/stats), a Server-Sent-Events feed of state changes (/api/events) and
+POST /api/scan to trigger a scan (disabled with --read-only). Protect it with
+--auth-token (Bearer header or ?token=) and/or --auth-user/--auth-password.
+Secrets can also be set via NETSPY_WEB_TOKEN / NETSPY_WEB_PASSWORD or the
+config file (web: token/user/password) so they don't show up in the process list.
+
+Prometheus metrics are served on /metrics (per device: up, RTT, flaps, uptime;
+plus scan count, scan duration and active threads). In headless mode
+--metrics-file writes the same data for the node_exporter textfile collector
+after every scan. For large networks limit the cardinality with
+--metrics-labels (ip is always included) and --metrics-max-series.
+
 Examples:
   netspy watch                                     # Auto-detect and select network
   netspy watch 192.168.1.0/24                      # Monitor with default 60s interval
   netspy watch 192.168.1.0/24 --interval 30s       # Check every 30 seconds
   netspy watch 192.168.1.0/24 --mode hybrid        # Use hybrid scanning mode (local networks)
   netspy watch 192.168.1.0/24 --mode arp           # Use ARP scanning mode (local networks)
-  netspy watch 10.10.1.0/24 --mode icmp            # Use ICMP ping (best for remote networks)`,
+  netspy watch 10.10.1.0/24 --mode icmp            # Use ICMP ping (best for remote networks)
+  netspy watch 10.10.1.0/24 --mode "icmp+tcp/22"   # Custom probe pipeline
+  netspy watch 192.168.1.0/24 -p 22,443,u:161      # Also check these TCP/UDP ports every scan
+  netspy watch 192.168.1.0/24 --headless | jq .    # Event stream on stdout
+  netspy watch 192.168.1.0/24 --headless --snapshot --output /var/log/netspy.ndjson
+  netspy watch 192.168.1.0/24 --listen :8080 --read-only --auth-user ops
+  netspy watch 192.168.1.0/24 --headless --certs --cert-warn-days 14
+  sudo netspy watch 192.168.1.0/24 --passive --interface eth0
+  netspy watch 192.168.1.0/24 --headless --pcap capture.pcap | jq .ip
+  netspy watch 10.0.0.0/16 --headless --output /dev/null \
+    --metrics-file /var/lib/node_exporter/textfile/netspy.prom --metrics-labels ip,vendor`,
 	Args: cobra.RangeArgs(0, 1),
 	RunE: runWatch,
 }
@@ -43,14 +140,59 @@ func init() {
 
 	// Flags für watch-Befehl hinzufügen
 	watchCmd.Flags().DurationVar(&watchInterval, "interval", 60*time.Second, "Scan interval")
-	watchCmd.Flags().StringVar(&watchMode, "mode", "hybrid", "Scan mode (hybrid, arp, icmp, fast, thorough, conservative)")
-	watchCmd.Flags().IntSliceVarP(&ports, "ports", "p", []int{}, "Specific ports to scan")
+	watchCmd.Flags().StringVar(&watchMode, "mode", "hybrid", "Scan mode (hybrid, arp, icmp, fast, thorough, conservative, config mode name or probe pipeline)")
+	watchCmd.Flags().StringSliceVarP(&portArgs, "ports", "p", []string{}, "Also check these ports of online hosts every scan, u: switches to UDP (e.g. 22,80,u:53,161)")
+	watchCmd.Flags().BoolVar(&watchIPv6, "ipv6", true, "Discover IPv6 neighbors and show them with their IPv4 hosts (local networks)")
+	watchCmd.Flags().BoolVar(&watchInv, "inventory", true, "Load and update the persistent device inventory (see 'netspy inventory')")
 	watchCmd.Flags().IntVar(&maxThreads, "max-threads", 0, "Maximum concurrent threads (0 = auto-calculate based on network size)")
+	watchCmd.Flags().BoolVar(&watchHeadless, "headless", false, "Run without UI and write state changes as NDJSON")
+	watchCmd.Flags().BoolVar(&watchSnapshot, "snapshot", false, "Headless: also write a full device snapshot after every scan")
+	watchCmd.Flags().StringVar(&watchOutput, "output", "", "Headless: write to this file instead of stdout")
+	watchCmd.Flags().IntVar(&watchMaxSize, "max-size", 100, "Headless: rotate the output file at this size in MB (0 = never)")
+	watchCmd.Flags().IntVar(&watchMaxFiles, "max-files", watch.DefaultMaxFiles, "Headless: number of rotated output files to keep")
+	watchCmd.Flags().BoolVar(&watchPassive, "passive", false, "Don't scan: build the device list from sniffed ARP, DHCP, mDNS, SSDP, NetBIOS, LLMNR and LLDP/CDP traffic")
+	watchCmd.Flags().StringVar(&watchInterface, "interface", "", "Capture interface for --passive and --neighbors (default: interface of the network)")
+	watchCmd.Flags().StringVar(&watchPcap, "pcap", "", "Passive: read frames from this pcap file instead of an interface")
+	watchCmd.Flags().DurationVar(&watchPassiveTimeout, "passive-timeout", 10*time.Minute, "Passive: report devices offline after this long without traffic")
+	watchCmd.Flags().BoolVar(&watchNeighbors, "neighbors", true, "Listen for LLDP/CDP announcements (switch port of this host, data of network equipment)")
+
+	// Web-Server (auch über die Konfiguration "web:" bzw. Umgebungsvariablen)
+	watchCmd.Flags().String("listen", "", "Serve web dashboard and API on this address (e.g. :8080)")
+	watchCmd.Flags().Bool("read-only", false, "Web: disable write endpoints (trigger scan)")
+	watchCmd.Flags().String("auth-token", "", "Web: require this bearer token (or NETSPY_WEB_TOKEN)")
+	watchCmd.Flags().String("auth-user", "", "Web: require basic auth with this user")
+	watchCmd.Flags().String("auth-password", "", "Web: basic auth password (or NETSPY_WEB_PASSWORD)")
+	_ = viper.BindPFlag("web.listen", watchCmd.Flags().Lookup("listen"))
+	_ = viper.BindPFlag("web.read_only", watchCmd.Flags().Lookup("read-only"))
+	_ = viper.BindPFlag("web.token", watchCmd.Flags().Lookup("auth-token"))
+	_ = viper.BindPFlag("web.user", watchCmd.Flags().Lookup("auth-user"))
+	_ = viper.BindPFlag("web.password", watchCmd.Flags().Lookup("auth-password"))
+	_ = viper.BindEnv("web.token", "NETSPY_WEB_TOKEN")
+	_ = viper.BindEnv("web.password", "NETSPY_WEB_PASSWORD")
+
+	// Prometheus-Metriken (/metrics und Textfile-Collector)
+	watchCmd.Flags().String("metrics-file", "", "Headless: write Prometheus metrics to this file after every scan (node_exporter textfile collector)")
+	watchCmd.Flags().StringSlice("metrics-labels", metrics.DeviceLabels, "Per-device metric labels (ip, mac, hostname, vendor, device_type)")
+	watchCmd.Flags().Int("metrics-max-series", metrics.DefaultMaxSeries, "Maximum devices exported per metric (-1 = no per-device metrics)")
+	_ = viper.BindPFlag("metrics.file", watchCmd.Flags().Lookup("metrics-file"))
+	_ = viper.BindPFlag("metrics.labels", watchCmd.Flags().Lookup("metrics-labels"))
+	_ = viper.BindPFlag("metrics.max_series", watchCmd.Flags().Lookup("metrics-max-series"))
+
+	// Zertifikats-Inventur
+	watchCmd.Flags().Bool("certs", false, "Read TLS certificates of online hosts (hourly) and alert on expiring ones")
+	watchCmd.Flags().Int("cert-warn-days", 30, "Report certificates expiring within this many days (cert-expiring)")
+	_ = viper.BindPFlag("certs.enabled", watchCmd.Flags().Lookup("certs"))
+	_ = viper.BindPFlag("certs.warn_days", watchCmd.Flags().Lookup("cert-warn-days"))
 }
 
 func runWatch(cmd *cobra.Command, args []string) error {
 	var network string
 
+	// Headless gibt es keine interaktive Netzwerk-Auswahl
+	if len(args) == 0 && watchHeadless {
+		return fmt.Errorf("--headless requires a network (e.g. netspy watch 192.168.1.0/24 --headless)")
+	}
+
 	// Wenn kein Netzwerk angegeben, erkennen und Benutzer zur Auswahl auffordern
 	if len(args) == 0 {
 		detectedNetwork, err := watch.DetectAndSelectNetwork()
@@ -68,7 +210,325 @@ func runWatch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid CIDR: %v", err)
 	}
 
+	// Modus auflösen (eingebauter Modus, Config-Modus oder Probe-Pipeline)
+	passive := watchPassive || watchPcap != ""
+	mode := "passive"
+	if passive {
+		if cmd.Flags().Changed("mode") {
+			return fmt.Errorf("--mode cannot be combined with --passive/--pcap")
+		}
+	} else if mode, err = resolveScanMode(watchMode); err != nil {
+		return err
+	}
+
+	// Ports aufteilen: TCP und nach "u:" UDP
+	if ports, udpPorts, err = scanner.ParsePorts(strings.Join(portArgs, ",")); err != nil {
+		return fmt.Errorf("invalid --ports: %v", err)
+	}
+	if passive && (len(ports) > 0 || len(udpPorts) > 0) {
+		return fmt.Errorf("--ports cannot be combined with --passive/--pcap")
+	}
+
+	if viper.GetString("metrics.file") != "" && !watchHeadless {
+		return fmt.Errorf("--metrics-file requires --headless (use --listen for /metrics)")
+	}
+	metricsOpts, err := metricsOptions()
+	if err != nil {
+		return err
+	}
+
+	// Alert-Routen aus der Konfiguration (alerts:) laden
+	bus, err := loadAlerts()
+	if err != nil {
+		return err
+	}
+
+	if watchHeadless {
+		return runHeadless(network, netCIDR, mode, passive, bus, metricsOpts)
+	}
+
 	// tview App erstellen und starten
-	app := watch.NewTviewApp(network, netCIDR, watchMode, watchInterval, maxThreads)
+	app := watch.NewTviewApp(network, netCIDR, mode, watchInterval, maxThreads)
+	if bus != nil {
+		defer bus.Close()
+	}
+	if err := setupMonitor(app.Monitor, bus); err != nil {
+		color.Yellow("[INFO] %v\n", err)
+	}
+	if passive {
+		stopCapture, err := startPassive(app.Monitor, netCIDR, os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer stopCapture()
+	} else if watchNeighbors {
+		stopNeighbors := startNeighbors(app.Monitor, netCIDR, os.Stdout)
+		defer stopNeighbors()
+	}
+
+	server, err := startWeb(app.Monitor, metricsOpts, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer stopWeb(server)
+
 	return app.Run()
 }
+
+// setupMonitor überträgt IPv6-, Alert-, Port-, Zertifikats- und Inventar-Einstellungen auf
+// den Monitor. Fehler sind nicht fatal - Watch läuft dann ohne die Funktion.
+func setupMonitor(monitor *watch.Monitor, bus *alert.Bus) error {
+	monitor.SetIPv6Discovery(watchIPv6)
+	if bus != nil {
+		monitor.SetAlerts(bus)
+	}
+
+	// Zusätzlich zu prüfende Ports (--ports)
+	if err := monitor.SetPorts(ports, udpPorts); err != nil {
+		return fmt.Errorf("port check disabled: %v", err)
+	}
+
+	// Zertifikats-Inventur der TLS-Ports
+	if viper.GetBool("certs.enabled") {
+		warning := time.Duration(viper.GetInt("certs.warn_days")) * 24 * time.Hour
+		if err := monitor.SetCertificates(warning); err != nil {
+			return fmt.Errorf("certificate inventory disabled: %v", err)
+		}
+	}
+
+	// Persistentes Inventar laden
+	if !watchInv {
+		return nil
+	}
+	path, err := inventoryPath()
+	if err == nil {
+		err = monitor.SetInventory(path)
+	}
+	if err != nil {
+		return fmt.Errorf("device inventory disabled: %v", err)
+	}
+	return nil
+}
+
+// runHeadless führt den Watch-Modus ohne Oberfläche aus. Da stdout den Ereignis-Strom
+// enthalten kann, gehen Meldungen nach stderr.
+func runHeadless(network string, netCIDR *net.IPNet, mode string, passive bool, bus *alert.Bus, metricsOpts metrics.Options) error {
+	var out io.Writer = os.Stdout
+	if watchOutput != "" {
+		writer, err := watch.NewRotatingWriter(watchOutput, int64(watchMaxSize)*1024*1024, watchMaxFiles)
+		if err != nil {
+			return fmt.Errorf("failed to open output: %v", err)
+		}
+		defer writer.Close()
+		out = writer
+	}
+
+	monitor := watch.NewMonitor(network, netCIDR, mode, watchInterval, maxThreads)
+	if bus != nil {
+		defer bus.Close()
+		bus.OnError = func(route string, event alert.Event, err error) {
+			fmt.Fprintf(os.Stderr, "[WARN] Alert %s (%s %s) failed: %v\n", route, event.Type, event.IP, err)
+		}
+	}
+	if err := setupMonitor(monitor, bus); err != nil {
+		fmt.Fprintf(os.Stderr, "[INFO] %v\n", err)
+	}
+	if passive {
+		stopCapture, err := startPassive(monitor, netCIDR, os.Stderr)
+		if err != nil {
+			return err
+		}
+		defer stopCapture()
+	} else if watchNeighbors {
+		stopNeighbors := startNeighbors(monitor, netCIDR, os.Stderr)
+		defer stopNeighbors()
+	}
+
+	server, err := startWeb(monitor, metricsOpts, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer stopWeb(server)
+
+	// Ctrl+C/SIGTERM beendet den Scan-Loop; Alerts werden danach noch zugestellt
+	handlesSignals.Store(true)
+	defer handlesSignals.Store(false)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	headless := watch.NewHeadless(monitor, out, watchSnapshot)
+	if path := viper.GetString("metrics.file"); path != "" {
+		headless.AfterScan = func() {
+			if err := metrics.WriteFile(path, monitor.Stats(), monitor.Snapshot(), metricsOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] Failed to write metrics: %v\n", err)
+			}
+		}
+	}
+
+	// Eine pcap-Datei ändert sich nicht mehr - nach dem ersten Scan beenden
+	if watchPcap != "" {
+		afterScan := headless.AfterScan
+		headless.AfterScan = func() {
+			if afterScan != nil {
+				afterScan()
+			}
+			stop()
+		}
+	}
+
+	return headless.Run(ctx)
+}
+
+// startPassive versorgt den Monitor im passiven Modus mit mitgeschnittenen Frames:
+// eine pcap-Datei (--pcap) wird vorab vollständig gelesen, ein Interface im
+// Hintergrund mitgeschnitten, bis die zurückgegebene Funktion aufgerufen wird.
+func startPassive(monitor *watch.Monitor, netCIDR *net.IPNet, log io.Writer) (func(), error) {
+	collector := scanner.NewPassiveCollector(netCIDR)
+
+	if watchPcap != "" {
+		reader, err := discovery.OpenPcap(watchPcap)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if err := collector.Capture(context.Background(), tapNeighbors(monitor, reader)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", watchPcap, err)
+		}
+		fmt.Fprintf(log, "[INFO] Read %d frames from %s\n", collector.Frames(), watchPcap)
+		monitor.SetPassive(collector, 0)
+		return func() {}, nil
+	}
+
+	iface, err := captureInterface(netCIDR)
+	if err != nil {
+		return nil, err
+	}
+	capture, err := discovery.OpenCapture(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture on %s: %v", iface.Name, err)
+	}
+	source := tapNeighbors(monitor, capture)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	crash.SafeGo("passiveCapture", func() {
+		defer close(done)
+		if err := collector.Capture(ctx, source); err != nil {
+			fmt.Fprintf(log, "[WARN] Capture on %s stopped: %v\n", iface.Name, err)
+		}
+	})
+
+	monitor.SetPassive(collector, watchPassiveTimeout)
+	fmt.Fprintf(log, "[INFO] Passive mode: listening on %s (offline after %v without traffic)\n", iface.Name, watchPassiveTimeout)
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// tapNeighbors wertet im passiven Modus zusätzlich die LLDP/CDP-Ankündigungen aus
+func tapNeighbors(monitor *watch.Monitor, source discovery.FrameSource) discovery.FrameSource {
+	if !watchNeighbors {
+		return source
+	}
+	listener := discovery.NewNeighborListener()
+	monitor.SetNeighbors(listener)
+	return listener.Tap(source)
+}
+
+// startNeighbors hört im Hintergrund auf LLDP/CDP-Ankündigungen am Interface des
+// Netzwerks. Bei entfernten Netzen und ohne Raw-Socket-Rechte bleibt das still aus.
+func startNeighbors(monitor *watch.Monitor, netCIDR *net.IPNet, log io.Writer) func() {
+	iface, err := captureInterface(netCIDR)
+	if err != nil {
+		if watchInterface != "" {
+			fmt.Fprintf(log, "[INFO] LLDP/CDP neighbors disabled: %v\n", err)
+		}
+		return func() {}
+	}
+	source, err := discovery.OpenNeighborCapture(iface)
+	if err != nil {
+		if !errors.Is(err, discovery.ErrRawSocketUnavailable) {
+			fmt.Fprintf(log, "[INFO] LLDP/CDP neighbors disabled: %v\n", err)
+		}
+		return func() {}
+	}
+
+	listener := discovery.NewNeighborListener()
+	monitor.SetNeighbors(listener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	crash.SafeGo("neighborListener", func() {
+		defer close(done)
+		_ = listener.Listen(ctx, source)
+	})
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// captureInterface gibt das Interface für Mitschnitte zurück: --interface oder das
+// Interface, über das das Netzwerk direkt erreichbar ist
+func captureInterface(netCIDR *net.IPNet) (*net.Interface, error) {
+	var iface *net.Interface
+	var err error
+	if watchInterface != "" {
+		iface, err = net.InterfaceByName(watchInterface)
+	} else {
+		iface, _, err = discovery.InterfaceForNetwork(netCIDR)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no capture interface (use --interface): %v", err)
+	}
+	return iface, nil
+}
+
+// startWeb startet den Web-Server, falls --listen (bzw. web.listen) gesetzt ist
+func startWeb(monitor *watch.Monitor, metricsOpts metrics.Options, log io.Writer) (*web.Server, error) {
+	opts := web.Options{
+		Metrics:  metricsOpts,
+		Addr:     viper.GetString("web.listen"),
+		Token:    viper.GetString("web.token"),
+		Username: viper.GetString("web.user"),
+		Password: viper.GetString("web.password"),
+		ReadOnly: viper.GetBool("web.read_only"),
+	}
+	if opts.Addr == "" {
+		return nil, nil
+	}
+	if opts.Username != "" && opts.Password == "" {
+		return nil, fmt.Errorf("--auth-user requires a password (--auth-password or NETSPY_WEB_PASSWORD)")
+	}
+
+	server := web.New(monitor, opts)
+	if err := server.Start(); err != nil {
+		return nil, fmt.Errorf("web server: %v", err)
+	}
+
+	fmt.Fprintf(log, "[INFO] Web dashboard on http://%s/\n", server.Addr())
+	if opts.Token == "" && opts.Username == "" {
+		fmt.Fprintf(log, "[WARN] Web dashboard has no authentication (see --auth-token/--auth-user)\n")
+	}
+	return server, nil
+}
+
+// stopWeb beendet den Web-Server (nil = nicht gestartet)
+func stopWeb(server *web.Server) {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+}
+
+// metricsOptions liest Label-Allowlist und Serien-Limit der Metriken
+func metricsOptions() (metrics.Options, error) {
+	opts := metrics.Options{
+		Labels:    viper.GetStringSlice("metrics.labels"),
+		MaxSeries: viper.GetInt("metrics.max_series"),
+	}
+	return opts, opts.Validate()
+}
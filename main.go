@@ -25,6 +25,10 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
+		if cmd.HandlesSignals() {
+			// Befehl beendet sich selbst sauber - erst ein zweites Signal erzwingt das Ende
+			<-sigChan
+		}
 		crash.StopSentinel()
 		os.Exit(0)
 	}()
@@ -0,0 +1,274 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"netspy/pkg/filter"
+)
+
+// queueSize begrenzt die wartenden Ereignisse pro Route
+const queueSize = 256
+
+// ErrQueueFull wird gemeldet, wenn eine Route Ereignisse nicht schnell genug zustellt
+var ErrQueueFull = errors.New("alert queue full, event dropped")
+
+// Sink stellt Ereignisse zu (Webhook, Exec, Syslog, SMTP, ...)
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Route verbindet einen Sink mit Filter, Hold-Down/Debounce und Retry-Einstellungen
+type Route struct {
+	Name string
+	Sink Sink
+
+	// Events beschränkt die Route auf bestimmte Ereignisarten (leer = alle)
+	Events []EventType
+
+	// Filter ist ein pkg/filter-Ausdruck auf den Ereignis-Feldern (z.B. "192.168.1.0/24 && event=device-offline")
+	Filter string
+
+	// HoldDown verzögert device-offline um diese Dauer; kommt das Gerät vorher zurück,
+	// werden offline und back verworfen (kurze Aussetzer lösen keinen Alarm aus)
+	HoldDown time.Duration
+
+	// Debounce unterdrückt gleiche Ereignisse (Art + Gerät) innerhalb dieses Zeitfensters
+	Debounce time.Duration
+
+	// Retries ist die Anzahl zusätzlicher Zustellversuche, RetryDelay die erste Wartezeit (verdoppelt sich)
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// Bus verteilt Ereignisse an die Routen. Jede Route stellt in einer eigenen Goroutine zu,
+// damit langsame Sinks weder andere Routen noch den Scan-Loop blockieren.
+type Bus struct {
+	routes []*route
+
+	// OnError wird bei endgültig fehlgeschlagener Zustellung aufgerufen (optional, darf nicht Publish aufrufen)
+	OnError func(route string, event Event, err error)
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closing chan struct{} // Beim Schließen werden keine weiteren Retries abgewartet
+	wg      sync.WaitGroup
+	closed  bool
+	mu      sync.Mutex
+}
+
+// route ist der Laufzeit-Zustand einer Route
+type route struct {
+	Route
+	filter *filter.Filter
+	events map[EventType]bool
+	queue  chan Event
+
+	mu       sync.Mutex
+	held     map[string]*time.Timer // Hold-Down: Geräte-Key -> Timer für device-offline
+	lastSent map[string]time.Time   // Debounce: Art + Geräte-Key -> letzte Zustellung
+}
+
+// NewBus erstellt einen Bus und startet die Zustell-Goroutinen
+func NewBus(routes ...Route) (*Bus, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bus{ctx: ctx, cancel: cancel, closing: make(chan struct{})}
+
+	for i, r := range routes {
+		if r.Sink == nil {
+			cancel()
+			return nil, fmt.Errorf("alert route %d has no sink", i+1)
+		}
+		if r.Name == "" {
+			r.Name = r.Sink.Name()
+		}
+
+		rs := &route{
+			Route:    r,
+			queue:    make(chan Event, queueSize),
+			held:     make(map[string]*time.Timer),
+			lastSent: make(map[string]time.Time),
+		}
+
+		if r.Filter != "" {
+			if err := filter.Validate(r.Filter); err != nil {
+				cancel()
+				return nil, fmt.Errorf("alert route %s: invalid filter: %v", r.Name, err)
+			}
+			rs.filter = filter.New(r.Filter).WithIPField("ip").WithAliases(filterAliases)
+		}
+		if len(r.Events) > 0 {
+			rs.events = make(map[EventType]bool)
+			for _, t := range r.Events {
+				rs.events[t] = true
+			}
+		}
+
+		b.routes = append(b.routes, rs)
+		b.wg.Add(1)
+		go b.deliver(rs)
+	}
+
+	return b, nil
+}
+
+// Publish verteilt ein Ereignis an alle passenden Routen (blockiert nicht)
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Message == "" {
+		event.Message = event.Summary()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	for _, r := range b.routes {
+		if r.matches(event) {
+			b.dispatch(r, event)
+		}
+	}
+}
+
+// Close verwirft zurückgehaltene Ereignisse, stellt die bereits eingereihten zu (ohne weitere
+// Retries) und wartet darauf
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.closing)
+	for _, r := range b.routes {
+		r.mu.Lock()
+		for key, timer := range r.held {
+			timer.Stop()
+			delete(r.held, key)
+		}
+		r.mu.Unlock()
+		close(r.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	b.cancel()
+}
+
+// matches prüft Ereignisart und Filter einer Route
+func (r *route) matches(event Event) bool {
+	if r.events != nil && !r.events[event.Type] {
+		return false
+	}
+	return r.filter == nil || r.filter.Match(event.Fields())
+}
+
+// dispatch wendet Hold-Down und Debounce an und reiht das Ereignis ein (b.mu ist gesperrt)
+func (b *Bus) dispatch(r *route, event Event) {
+	key := event.DeviceKey()
+
+	if r.HoldDown > 0 {
+		r.mu.Lock()
+		switch event.Type {
+		case DeviceOffline:
+			if _, pending := r.held[key]; !pending {
+				r.held[key] = time.AfterFunc(r.HoldDown, func() {
+					b.releaseHeld(r, key, event)
+				})
+			}
+			r.mu.Unlock()
+			return
+		case DeviceBack:
+			if timer, pending := r.held[key]; pending {
+				// Gerät kam innerhalb der Hold-Down-Zeit zurück - beide Ereignisse verwerfen
+				timer.Stop()
+				delete(r.held, key)
+				r.mu.Unlock()
+				return
+			}
+		}
+		r.mu.Unlock()
+	}
+
+	b.enqueue(r, event)
+}
+
+// releaseHeld stellt ein device-offline nach Ablauf der Hold-Down-Zeit zu
+func (b *Bus) releaseHeld(r *route, key string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.mu.Lock()
+	_, pending := r.held[key]
+	delete(r.held, key)
+	r.mu.Unlock()
+
+	if pending && !b.closed {
+		b.enqueue(r, event)
+	}
+}
+
+// enqueue wendet Debounce an und übergibt das Ereignis der Zustell-Goroutine (b.mu ist gesperrt)
+func (b *Bus) enqueue(r *route, event Event) {
+	if r.Debounce > 0 {
+		key := string(event.Type) + "|" + event.DeviceKey()
+		now := time.Now()
+
+		r.mu.Lock()
+		last, seen := r.lastSent[key]
+		if seen && now.Sub(last) < r.Debounce {
+			r.mu.Unlock()
+			return
+		}
+		r.lastSent[key] = now
+		r.mu.Unlock()
+	}
+
+	select {
+	case r.queue <- event:
+	default:
+		b.reportError(r, event, ErrQueueFull)
+	}
+}
+
+// deliver stellt die Ereignisse einer Route nacheinander zu (mit Retries)
+func (b *Bus) deliver(r *route) {
+	defer b.wg.Done()
+
+	for event := range r.queue {
+		delay := r.RetryDelay
+		if delay <= 0 {
+			delay = time.Second
+		}
+
+		err := r.Sink.Send(b.ctx, event)
+	retry:
+		for attempt := 0; err != nil && attempt < r.Retries; attempt++ {
+			select {
+			case <-time.After(delay):
+			case <-b.closing:
+				break retry
+			}
+			delay *= 2
+			err = r.Sink.Send(b.ctx, event)
+		}
+		if err != nil {
+			b.reportError(r, event, err)
+		}
+	}
+}
+
+// reportError meldet einen Zustellfehler an OnError
+func (b *Bus) reportError(r *route, event Event, err error) {
+	if b.OnError != nil {
+		b.OnError(r.Name, event, err)
+	}
+}
@@ -0,0 +1,247 @@
+package alert_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/scanner"
+)
+
+// recordingSink merkt sich zugestellte Ereignisse und kann die ersten Versuche fehlschlagen lassen
+type recordingSink struct {
+	mu       sync.Mutex
+	events   []alert.Event
+	attempts int
+	failures int
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(ctx context.Context, event alert.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("temporary failure")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) types() []alert.EventType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var types []alert.EventType
+	for _, event := range s.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func event(t alert.EventType, ip, mac string) alert.Event {
+	host := scanner.Host{IP: net.ParseIP(ip), MAC: mac, Vendor: "Synology", DeviceType: "NAS"}
+	return alert.NewEvent(t, host, "online", time.Now())
+}
+
+var _ = Describe("Bus", func() {
+	var sink *recordingSink
+
+	BeforeEach(func() {
+		sink = &recordingSink{}
+	})
+
+	It("should deliver events with a generated message", func() {
+		bus, err := alert.NewBus(alert.Route{Sink: sink})
+		Expect(err).NotTo(HaveOccurred())
+
+		bus.Publish(event(alert.DeviceNew, "192.0.2.10", "aa:bb:cc:00:00:01"))
+		bus.Close()
+
+		Expect(sink.events).To(HaveLen(1))
+		Expect(sink.events[0].Message).To(Equal("New device 192.0.2.10 [Synology]"))
+	})
+
+	It("should filter by event type and filter expression", func() {
+		bus, err := alert.NewBus(alert.Route{
+			Sink:   sink,
+			Events: []alert.EventType{alert.DeviceOffline, alert.DeviceNew},
+			Filter: "192.0.2.0/24 && event=device-offline",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.10", ""))
+		bus.Publish(event(alert.DeviceOffline, "198.51.100.1", ""))
+		bus.Publish(event(alert.DeviceNew, "192.0.2.11", ""))
+		bus.Publish(event(alert.DeviceBack, "192.0.2.10", ""))
+		bus.Close()
+
+		Expect(sink.types()).To(Equal([]alert.EventType{alert.DeviceOffline}))
+	})
+
+	It("should reject invalid filters", func() {
+		_, err := alert.NewBus(alert.Route{Sink: sink, Filter: "ip=("})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should suppress short outages during the hold-down window", func() {
+		bus, err := alert.NewBus(alert.Route{Sink: sink, HoldDown: 100 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		defer bus.Close()
+
+		// Kurzer Aussetzer: offline + back innerhalb der Hold-Down-Zeit
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.10", "aa:bb:cc:00:00:01"))
+		bus.Publish(event(alert.DeviceBack, "192.0.2.10", "aa:bb:cc:00:00:01"))
+
+		// Echter Ausfall
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.11", "aa:bb:cc:00:00:02"))
+
+		Eventually(sink.types).Should(Equal([]alert.EventType{alert.DeviceOffline}))
+		Consistently(sink.types, 200*time.Millisecond).Should(HaveLen(1))
+		Expect(sink.events[0].IP).To(Equal("192.0.2.11"))
+	})
+
+	It("should debounce repeated events per device", func() {
+		bus, err := alert.NewBus(alert.Route{Sink: sink, Debounce: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.10", "aa:bb:cc:00:00:01"))
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.10", "aa:bb:cc:00:00:01"))
+		bus.Publish(event(alert.DeviceOffline, "192.0.2.11", "aa:bb:cc:00:00:02"))
+		bus.Publish(event(alert.DeviceBack, "192.0.2.10", "aa:bb:cc:00:00:01"))
+		bus.Close()
+
+		Expect(sink.types()).To(Equal([]alert.EventType{alert.DeviceOffline, alert.DeviceOffline, alert.DeviceBack}))
+	})
+
+	It("should retry failed deliveries", func() {
+		sink.failures = 2
+		bus, err := alert.NewBus(alert.Route{Sink: sink, Retries: 2, RetryDelay: time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		bus.Publish(event(alert.DeviceNew, "192.0.2.10", ""))
+		Eventually(sink.types).Should(HaveLen(1))
+		bus.Close()
+
+		Expect(sink.attempts).To(Equal(3))
+	})
+
+	It("should report deliveries that failed after all retries", func() {
+		sink.failures = 10
+		bus, err := alert.NewBus(alert.Route{Name: "ops", Sink: sink, Retries: 1, RetryDelay: time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		var mu sync.Mutex
+		var failedRoutes []string
+		bus.OnError = func(route string, event alert.Event, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failedRoutes = append(failedRoutes, route)
+		}
+
+		bus.Publish(event(alert.DeviceNew, "192.0.2.10", ""))
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return failedRoutes
+		}).Should(Equal([]string{"ops"}))
+		bus.Close()
+
+		Expect(sink.attempts).To(Equal(2))
+	})
+
+	It("should ignore events after Close", func() {
+		bus, err := alert.NewBus(alert.Route{Sink: sink})
+		Expect(err).NotTo(HaveOccurred())
+		bus.Close()
+		bus.Close()
+
+		bus.Publish(event(alert.DeviceNew, "192.0.2.10", ""))
+		Expect(sink.events).To(BeEmpty())
+	})
+})
+
+var _ = Describe("FlapDetector", func() {
+	It("should report flapping once per episode", func() {
+		detector := alert.NewFlapDetector(3, time.Minute)
+		start := time.Now()
+
+		Expect(detector.Observe("a", start)).To(BeFalse())
+		Expect(detector.Observe("a", start.Add(10*time.Second))).To(BeFalse())
+		Expect(detector.Observe("a", start.Add(20*time.Second))).To(BeTrue())
+		Expect(detector.Observe("a", start.Add(30*time.Second))).To(BeFalse())
+
+		// Nach dem Zeitfenster beruhigt - ein neuer Flapping-Zyklus wird wieder gemeldet
+		later := start.Add(10 * time.Minute)
+		Expect(detector.Observe("a", later)).To(BeFalse())
+		Expect(detector.Observe("a", later.Add(time.Second))).To(BeFalse())
+		Expect(detector.Observe("a", later.Add(2*time.Second))).To(BeTrue())
+
+		Expect(detector.Observe("b", start)).To(BeFalse())
+	})
+})
+
+var _ = Describe("ExpiryDetector", func() {
+	It("should report expiring and expired certificates once", func() {
+		detector := alert.NewExpiryDetector(30 * 24 * time.Hour)
+		now := time.Now()
+		notAfter := now.Add(40 * 24 * time.Hour)
+
+		Expect(detector.Observe("a", notAfter, now)).To(BeFalse())
+		Expect(detector.Observe("a", notAfter, now.Add(11*24*time.Hour))).To(BeTrue())
+		Expect(detector.Observe("a", notAfter, now.Add(20*24*time.Hour))).To(BeFalse())
+		Expect(detector.Observe("a", notAfter, now.Add(41*24*time.Hour))).To(BeTrue())
+		Expect(detector.Observe("a", notAfter, now.Add(50*24*time.Hour))).To(BeFalse())
+
+		// Bereits abgelaufen: nur eine Meldung
+		Expect(detector.Observe("b", now.Add(-time.Hour), now)).To(BeTrue())
+		Expect(detector.Observe("b", now.Add(-time.Hour), now)).To(BeFalse())
+	})
+
+	It("should describe the certificate in the summary", func() {
+		notAfter := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		e := alert.Event{
+			Type: alert.CertExpiring, Time: notAfter.Add(-10 * 24 * time.Hour), IP: "192.0.2.10",
+			Port: 443, Certificate: "nas.local", NotAfter: &notAfter,
+		}
+		Expect(e.Summary()).To(Equal("Certificate nas.local on 192.0.2.10:443 expires in 10 days (2026-03-01)"))
+		Expect(e.Fields()["port"]).To(Equal("443"))
+
+		e.Time = notAfter.Add(time.Hour)
+		Expect(e.Summary()).To(Equal("Certificate nas.local on 192.0.2.10:443 expired on 2026-03-01"))
+	})
+})
+
+var _ = Describe("Config", func() {
+	It("should build routes for all sink types", func() {
+		bus, err := alert.NewBusFromConfig([]alert.Config{
+			{Type: "webhook", URL: "http://127.0.0.1:1/hook", Events: []string{"device-offline", "FLAPPING"}},
+			{Type: "exec", Command: "true"},
+			{Type: "syslog", Address: "udp://127.0.0.1:514", Facility: "local0"},
+			{Type: "smtp", Host: "127.0.0.1", From: "a@example.com", To: []string{"b@example.com"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		bus.Close()
+	})
+
+	It("should reject invalid configurations", func() {
+		for _, cfg := range []alert.Config{
+			{},
+			{Type: "pager"},
+			{Type: "webhook"},
+			{Type: "exec"},
+			{Type: "smtp", Host: "mail"},
+			{Type: "syslog", Facility: "nope"},
+			{Type: "webhook", URL: "http://x", Events: []string{"device-gone"}},
+		} {
+			_, err := alert.NewRoute(cfg)
+			Expect(err).To(HaveOccurred(), "config %+v", cfg)
+		}
+	})
+})
@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCertWarning ist die Vorwarnzeit für ablaufende Zertifikate
+const DefaultCertWarning = 30 * 24 * time.Hour
+
+// ExpiryDetector erkennt Zertifikate, die innerhalb der Vorwarnzeit ablaufen
+type ExpiryDetector struct {
+	Within time.Duration // Vorwarnzeit vor dem Ablaufdatum
+
+	mu       sync.Mutex
+	reported map[string]expiryState
+}
+
+type expiryState int
+
+const (
+	expiryOK expiryState = iota
+	expirySoon
+	expiryPast
+)
+
+// NewExpiryDetector erstellt einen Detektor (0 = DefaultCertWarning)
+func NewExpiryDetector(within time.Duration) *ExpiryDetector {
+	if within <= 0 {
+		within = DefaultCertWarning
+	}
+	return &ExpiryDetector{
+		Within:   within,
+		reported: make(map[string]expiryState),
+	}
+}
+
+// Observe prüft ein Zertifikat (key = Gerät, Port und Fingerprint). Gibt true
+// zurück, wenn es neu in die Vorwarnzeit fällt oder inzwischen abgelaufen ist -
+// jeder Zustand wird pro Zertifikat nur einmal gemeldet.
+func (d *ExpiryDetector) Observe(key string, notAfter, at time.Time) bool {
+	state := expiryOK
+	switch {
+	case !at.Before(notAfter):
+		state = expiryPast
+	case notAfter.Sub(at) <= d.Within:
+		state = expirySoon
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if state <= d.reported[key] {
+		return false
+	}
+	d.reported[key] = state
+	return true
+}
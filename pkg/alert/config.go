@@ -0,0 +1,136 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config beschreibt eine Alert-Route in der Konfigurationsdatei (Schlüssel "alerts")
+//
+//	alerts:
+//	  - type: webhook
+//	    url: https://hooks.example.com/netspy
+//	    events: [device-offline, device-back]
+//	    filter: "vendor=Synology"
+//	    hold_down: 2m
+//	    retries: 3
+type Config struct {
+	Name       string        `mapstructure:"name"`
+	Type       string        `mapstructure:"type"` // webhook, exec, syslog, smtp
+	Events     []string      `mapstructure:"events"`
+	Filter     string        `mapstructure:"filter"`
+	HoldDown   time.Duration `mapstructure:"hold_down"`
+	Debounce   time.Duration `mapstructure:"debounce"`
+	Retries    int           `mapstructure:"retries"`
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+
+	// webhook
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+
+	// exec
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+
+	// syslog
+	Address  string `mapstructure:"address"`
+	Tag      string `mapstructure:"tag"`
+	Facility string `mapstructure:"facility"`
+
+	// smtp
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	NoTLS    bool     `mapstructure:"no_tls"`
+}
+
+// NewRoute erstellt eine Route aus einem Konfigurationseintrag
+func NewRoute(cfg Config) (Route, error) {
+	sink, err := newSink(cfg)
+	if err != nil {
+		return Route{}, err
+	}
+
+	route := Route{
+		Name:       cfg.Name,
+		Sink:       sink,
+		Filter:     cfg.Filter,
+		HoldDown:   cfg.HoldDown,
+		Debounce:   cfg.Debounce,
+		Retries:    cfg.Retries,
+		RetryDelay: cfg.RetryDelay,
+	}
+
+	for _, name := range cfg.Events {
+		eventType, err := ParseEventType(name)
+		if err != nil {
+			return Route{}, err
+		}
+		route.Events = append(route.Events, eventType)
+	}
+
+	return route, nil
+}
+
+// NewBusFromConfig erstellt einen Bus aus den Konfigurationseinträgen
+func NewBusFromConfig(configs []Config) (*Bus, error) {
+	routes := make([]Route, 0, len(configs))
+	for i, cfg := range configs {
+		route, err := NewRoute(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("alert %d (%s): %v", i+1, cfg.Type, err)
+		}
+		routes = append(routes, route)
+	}
+	return NewBus(routes...)
+}
+
+// ParseEventType prüft den Namen einer Ereignisart
+func ParseEventType(name string) (EventType, error) {
+	names := make([]string, 0, len(EventTypes))
+	for _, t := range EventTypes {
+		if strings.EqualFold(strings.TrimSpace(name), string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("unknown event %q (valid: %s)", name, strings.Join(names, ", "))
+}
+
+// newSink erstellt den Sink eines Konfigurationseintrags
+func newSink(cfg Config) (Sink, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook requires url")
+		}
+		return &WebhookSink{URL: cfg.URL, Headers: cfg.Headers, Timeout: cfg.Timeout}, nil
+	case "exec":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("exec requires command")
+		}
+		return &ExecSink{Command: cfg.Command, Args: cfg.Args, Timeout: cfg.Timeout}, nil
+	case "syslog":
+		if _, ok := syslogFacilities[strings.ToLower(cfg.Facility)]; cfg.Facility != "" && !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+		}
+		return &SyslogSink{Address: cfg.Address, Tag: cfg.Tag, Facility: cfg.Facility, Timeout: cfg.Timeout}, nil
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, fmt.Errorf("smtp requires host, from and to")
+		}
+		return &SMTPSink{
+			Host: cfg.Host, Port: cfg.Port, From: cfg.From, To: cfg.To,
+			Username: cfg.Username, Password: cfg.Password, NoTLS: cfg.NoTLS, Timeout: cfg.Timeout,
+		}, nil
+	case "":
+		return nil, fmt.Errorf("missing type (webhook, exec, syslog, smtp)")
+	default:
+		return nil, fmt.Errorf("unknown type %q (webhook, exec, syslog, smtp)", cfg.Type)
+	}
+}
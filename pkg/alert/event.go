@@ -0,0 +1,146 @@
+package alert
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"netspy/pkg/scanner"
+)
+
+// EventType ist die Art eines Watch-Ereignisses
+type EventType string
+
+const (
+	DeviceNew      EventType = "device-new"     // Gerät zum ersten Mal gesehen
+	DeviceOffline  EventType = "device-offline" // Gerät nicht mehr erreichbar
+	DeviceBack     EventType = "device-back"    // Gerät wieder online
+	DeviceFlapping EventType = "flapping"       // Häufige Statuswechsel in kurzer Zeit
+	IPChanged      EventType = "ip-change"      // Gleiche MAC unter neuer IP
+	MACChanged     EventType = "mac-change"     // Gleiche IP mit anderer MAC
+	CertExpiring   EventType = "cert-expiring"  // TLS-Zertifikat läuft bald ab oder ist abgelaufen
+)
+
+// EventTypes enthält alle Ereignisarten
+var EventTypes = []EventType{DeviceNew, DeviceOffline, DeviceBack, DeviceFlapping, IPChanged, MACChanged, CertExpiring}
+
+// Event ist ein Ereignis aus dem Watch-Modus
+type Event struct {
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	Network    string    `json:"network,omitempty"`
+	IP         string    `json:"ip"`
+	MAC        string    `json:"mac,omitempty"`
+	Hostname   string    `json:"hostname,omitempty"`
+	Vendor     string    `json:"vendor,omitempty"`
+	DeviceType string    `json:"device_type,omitempty"`
+	Status     string    `json:"status"`
+	Old        string    `json:"old,omitempty"` // Vorheriger Wert bei ip-change/mac-change
+	FlapCount  int       `json:"flap_count,omitempty"`
+	Initial    bool      `json:"initial,omitempty"` // Beim ersten Scan entdeckt (Bestandsaufnahme, kein Alert)
+	Message    string    `json:"message"`
+
+	// cert-expiring: Port und Server-Zertifikat
+	Port        int        `json:"port,omitempty"`
+	Certificate string     `json:"certificate,omitempty"` // Inhaber (Common Name)
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+}
+
+// NewEvent erstellt ein Ereignis für einen Host
+func NewEvent(eventType EventType, host scanner.Host, status string, at time.Time) Event {
+	return Event{
+		Type:       eventType,
+		Time:       at,
+		IP:         host.IP.String(),
+		MAC:        host.MAC,
+		Hostname:   host.Hostname,
+		Vendor:     host.Vendor,
+		DeviceType: host.DeviceType,
+		Status:     status,
+	}
+}
+
+// DeviceKey identifiziert das Gerät eines Ereignisses (MAC, sonst IP)
+func (e Event) DeviceKey() string {
+	if mac, err := net.ParseMAC(e.MAC); err == nil {
+		return mac.String()
+	}
+	return e.IP
+}
+
+// Summary gibt eine einzeilige Beschreibung des Ereignisses zurück
+func (e Event) Summary() string {
+	device := e.IP
+	if e.Hostname != "" {
+		device = fmt.Sprintf("%s (%s)", e.IP, e.Hostname)
+	}
+
+	switch e.Type {
+	case DeviceNew:
+		if e.Vendor != "" {
+			return fmt.Sprintf("New device %s [%s]", device, e.Vendor)
+		}
+		return fmt.Sprintf("New device %s", device)
+	case DeviceOffline:
+		return fmt.Sprintf("Device %s went offline", device)
+	case DeviceBack:
+		return fmt.Sprintf("Device %s is back online", device)
+	case DeviceFlapping:
+		return fmt.Sprintf("Device %s is flapping (%d status changes)", device, e.FlapCount)
+	case IPChanged:
+		return fmt.Sprintf("Device %s changed IP from %s to %s", e.MAC, e.Old, e.IP)
+	case MACChanged:
+		return fmt.Sprintf("IP %s changed MAC from %s to %s", e.IP, e.Old, e.MAC)
+	case CertExpiring:
+		target := net.JoinHostPort(e.IP, strconv.Itoa(e.Port))
+		if e.Hostname != "" {
+			target += " (" + e.Hostname + ")"
+		}
+		if e.NotAfter == nil {
+			return fmt.Sprintf("Certificate %s on %s expires soon", e.Certificate, target)
+		}
+		date := e.NotAfter.Format("2006-01-02")
+		if !e.Time.Before(*e.NotAfter) {
+			return fmt.Sprintf("Certificate %s on %s expired on %s", e.Certificate, target, date)
+		}
+		days := int(e.NotAfter.Sub(e.Time).Hours() / 24)
+		return fmt.Sprintf("Certificate %s on %s expires in %d days (%s)", e.Certificate, target, days, date)
+	default:
+		return fmt.Sprintf("%s: %s", e.Type, device)
+	}
+}
+
+// Fields gibt die Felder für pkg/filter-Ausdrücke zurück
+// (gleiche Feldnamen wie der Filter im Watch-Modus, zusätzlich "event" und "old")
+func (e Event) Fields() map[string]string {
+	fields := map[string]string{
+		"event":  string(e.Type),
+		"ip":     e.IP,
+		"host":   e.Hostname,
+		"mac":    e.MAC,
+		"vendor": e.Vendor,
+		"device": e.DeviceType,
+		"status": e.Status,
+		"old":    e.Old,
+		"port":   "",
+		"cert":   e.Certificate,
+	}
+	if e.Port > 0 {
+		fields["port"] = strconv.Itoa(e.Port)
+	}
+	return fields
+}
+
+// filterAliases entsprechen den Aliasen des Watch-Filters
+var filterAliases = map[string]string{
+	"hostname": "host",
+	"h":        "host",
+	"m":        "mac",
+	"v":        "vendor",
+	"i":        "ip",
+	"s":        "status",
+	"dev":      "device",
+	"type":     "device",
+	"e":        "event",
+}
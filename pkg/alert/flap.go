@@ -0,0 +1,64 @@
+package alert
+
+import (
+	"sync"
+	"time"
+)
+
+// Standardwerte für die Flapping-Erkennung
+const (
+	DefaultFlapThreshold = 4
+	DefaultFlapWindow    = 30 * time.Minute
+)
+
+// FlapDetector erkennt Geräte mit vielen Statuswechseln in kurzer Zeit
+type FlapDetector struct {
+	Threshold int           // Anzahl Statuswechsel ...
+	Window    time.Duration // ... innerhalb dieses Zeitfensters
+
+	mu       sync.Mutex
+	changes  map[string][]time.Time
+	flapping map[string]bool
+}
+
+// NewFlapDetector erstellt einen Detektor (0 = Standardwerte)
+func NewFlapDetector(threshold int, window time.Duration) *FlapDetector {
+	if threshold <= 0 {
+		threshold = DefaultFlapThreshold
+	}
+	if window <= 0 {
+		window = DefaultFlapWindow
+	}
+	return &FlapDetector{
+		Threshold: threshold,
+		Window:    window,
+		changes:   make(map[string][]time.Time),
+		flapping:  make(map[string]bool),
+	}
+}
+
+// Observe registriert einen Statuswechsel eines Geräts. Gibt true zurück, wenn das
+// Gerät dadurch zu flappen beginnt (nur einmal, bis es sich wieder beruhigt hat).
+func (d *FlapDetector) Observe(key string, at time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	recent := d.changes[key][:0]
+	for _, t := range d.changes[key] {
+		if at.Sub(t) < d.Window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, at)
+	d.changes[key] = recent
+
+	if len(recent) < d.Threshold {
+		d.flapping[key] = false
+		return false
+	}
+	if d.flapping[key] {
+		return false
+	}
+	d.flapping[key] = true
+	return true
+}
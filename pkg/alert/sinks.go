@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSinkTimeout begrenzt eine einzelne Zustellung
+const defaultSinkTimeout = 10 * time.Second
+
+// WebhookSink sendet Ereignisse als JSON per HTTP POST
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string
+	Timeout time.Duration
+	Client  *http.Client // optional (Standard: http.DefaultClient)
+}
+
+// Name gibt den Sink-Namen zurück
+func (s *WebhookSink) Name() string { return "webhook " + s.URL }
+
+// Send sendet das Ereignis; Antworten außerhalb 2xx gelten als Fehler
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, orDefault(s.Timeout, defaultSinkTimeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "netspy")
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ExecSink startet ein Programm pro Ereignis. Die Ereignis-Felder stehen als
+// NETSPY_*-Umgebungsvariablen bereit, das komplette Ereignis als JSON auf stdin.
+type ExecSink struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+// Name gibt den Sink-Namen zurück
+func (s *ExecSink) Name() string { return "exec " + s.Command }
+
+// Send führt das Programm aus; ein Exit-Code ungleich 0 gilt als Fehler
+func (s *ExecSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, orDefault(s.Timeout, defaultSinkTimeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	cmd.Env = append(os.Environ(), EventEnv(event)...)
+	cmd.Stdin = bytes.NewReader(body)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%v: %s", err, truncate(msg, 200))
+		}
+		return err
+	}
+	return nil
+}
+
+// EventEnv gibt die Umgebungsvariablen eines Ereignisses zurück
+func EventEnv(event Event) []string {
+	return []string{
+		"NETSPY_EVENT=" + string(event.Type),
+		"NETSPY_TIME=" + event.Time.Format(time.RFC3339),
+		"NETSPY_NETWORK=" + event.Network,
+		"NETSPY_IP=" + event.IP,
+		"NETSPY_MAC=" + event.MAC,
+		"NETSPY_HOSTNAME=" + event.Hostname,
+		"NETSPY_VENDOR=" + event.Vendor,
+		"NETSPY_DEVICE_TYPE=" + event.DeviceType,
+		"NETSPY_STATUS=" + event.Status,
+		"NETSPY_OLD=" + event.Old,
+		"NETSPY_FLAP_COUNT=" + strconv.Itoa(event.FlapCount),
+		"NETSPY_MESSAGE=" + event.Message,
+		"NETSPY_PORT=" + strconv.Itoa(event.Port),
+		"NETSPY_CERTIFICATE=" + event.Certificate,
+	}
+}
+
+// orDefault gibt d zurück, oder def wenn d nicht gesetzt ist
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// truncate kürzt Fehlermeldungen externer Programme
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,226 @@
+package alert_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/alert"
+)
+
+var _ = Describe("Sinks", func() {
+	var (
+		ctx context.Context
+		ev  alert.Event
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ev = event(alert.DeviceOffline, "192.0.2.10", "aa:bb:cc:00:00:01")
+		ev.Network = "192.0.2.0/24"
+		ev.Message = ev.Summary()
+	})
+
+	Describe("WebhookSink", func() {
+		It("should POST the event as JSON", func() {
+			received := make(chan alert.Event, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+
+				var e alert.Event
+				Expect(json.NewDecoder(r.Body).Decode(&e)).To(Succeed())
+				received <- e
+			}))
+			defer server.Close()
+
+			sink := &alert.WebhookSink{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+			Expect(sink.Send(ctx, ev)).To(Succeed())
+
+			e := <-received
+			Expect(e.Type).To(Equal(alert.DeviceOffline))
+			Expect(e.IP).To(Equal("192.0.2.10"))
+			Expect(e.Message).To(Equal("Device 192.0.2.10 went offline"))
+		})
+
+		It("should fail on non-2xx responses", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			sink := &alert.WebhookSink{URL: server.URL}
+			Expect(sink.Send(ctx, ev)).To(MatchError(ContainSubstring("503")))
+		})
+	})
+
+	Describe("ExecSink", func() {
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("uses /bin/sh")
+			}
+		})
+
+		It("should pass the event via environment and stdin", func() {
+			out := filepath.Join(GinkgoT().TempDir(), "out")
+			sink := &alert.ExecSink{
+				Command: "/bin/sh",
+				Args:    []string{"-c", `printf "%s %s %s\n" "$NETSPY_EVENT" "$NETSPY_IP" "$NETSPY_NETWORK" > "$0"; cat >> "$0"`, out},
+			}
+			Expect(sink.Send(ctx, ev)).To(Succeed())
+
+			data, err := os.ReadFile(out)
+			Expect(err).NotTo(HaveOccurred())
+			lines := strings.SplitN(string(data), "\n", 2)
+			Expect(lines[0]).To(Equal("device-offline 192.0.2.10 192.0.2.0/24"))
+			Expect(lines[1]).To(ContainSubstring(`"mac":"aa:bb:cc:00:00:01"`))
+		})
+
+		It("should report failing commands with their output", func() {
+			sink := &alert.ExecSink{Command: "/bin/sh", Args: []string{"-c", "echo boom >&2; exit 3"}}
+			Expect(sink.Send(ctx, ev)).To(MatchError(ContainSubstring("boom")))
+		})
+	})
+
+	Describe("SyslogSink", func() {
+		It("should send an RFC 3164 message over UDP", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			sink := &alert.SyslogSink{Address: "udp://" + conn.LocalAddr().String(), Tag: "netspy-test", Facility: "local0"}
+			Expect(sink.Send(ctx, ev)).To(Succeed())
+
+			buf := make([]byte, 2048)
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).NotTo(HaveOccurred())
+
+			// local0 (16) * 8 + warning (4) = 132
+			msg := string(buf[:n])
+			Expect(msg).To(HavePrefix("<132>"))
+			Expect(msg).To(ContainSubstring("netspy-test["))
+			Expect(msg).To(ContainSubstring("Device 192.0.2.10 went offline event=device-offline ip=192.0.2.10"))
+		})
+	})
+
+	Describe("SMTPSink", func() {
+		It("should deliver the event as e-mail", func() {
+			server := newFakeSMTP()
+			defer server.Close()
+
+			host, port, _ := net.SplitHostPort(server.Addr())
+			var portNum int
+			fmt.Sscanf(port, "%d", &portNum)
+
+			sink := &alert.SMTPSink{Host: host, Port: portNum, From: "netspy@example.com", To: []string{"ops@example.com"}}
+			Expect(sink.Send(ctx, ev)).To(Succeed())
+
+			var mail fakeMail
+			Eventually(server.mails).Should(Receive(&mail))
+			Expect(mail.from).To(Equal("<netspy@example.com>"))
+			Expect(mail.to).To(Equal([]string{"<ops@example.com>"}))
+			Expect(mail.data).To(ContainSubstring("Subject: [netspy] Device 192.0.2.10 went offline"))
+			Expect(mail.data).To(ContainSubstring("MAC:         aa:bb:cc:00:00:01"))
+		})
+	})
+})
+
+// fakeMail ist eine vom Test-SMTP-Server empfangene Nachricht
+type fakeMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP ist ein minimaler SMTP-Server für Tests (ohne TLS/Auth)
+type fakeSMTP struct {
+	listener net.Listener
+	mails    chan fakeMail
+}
+
+func newFakeSMTP() *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	s := &fakeSMTP{listener: listener, mails: make(chan fakeMail, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) Addr() string { return s.listener.Addr().String() }
+func (s *fakeSMTP) Close()       { s.listener.Close() }
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	var mail fakeMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = append(mail.to, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			mail.data = data.String()
+			s.mails <- mail
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
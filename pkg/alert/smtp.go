@@ -0,0 +1,119 @@
+package alert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPSink verschickt Ereignisse per E-Mail. STARTTLS wird verwendet, wenn der
+// Server es anbietet (außer bei NoTLS).
+type SMTPSink struct {
+	Host     string
+	Port     int // Standard: 25
+	From     string
+	To       []string
+	Username string
+	Password string
+	NoTLS    bool
+	Timeout  time.Duration
+}
+
+// Name gibt den Sink-Namen zurück
+func (s *SMTPSink) Name() string { return "smtp " + s.Host }
+
+// Send verschickt das Ereignis als E-Mail
+func (s *SMTPSink) Send(ctx context.Context, event Event) error {
+	if len(s.To) == 0 {
+		return fmt.Errorf("smtp: no recipients")
+	}
+
+	port := s.Port
+	if port == 0 {
+		port = 25
+	}
+	timeout := orDefault(s.Timeout, defaultSinkTimeout)
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.NoTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return fmt.Errorf("smtp starttls: %v", err)
+		}
+	}
+
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %v", err)
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(event)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message baut die E-Mail (Header + Text)
+func (s *SMTPSink) message(event Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: [netspy] %s\r\n", event.Message)
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", event.Message)
+	for _, line := range [][2]string{
+		{"Event", string(event.Type)},
+		{"Time", event.Time.Format(time.RFC3339)},
+		{"Network", event.Network},
+		{"IP", event.IP},
+		{"MAC", event.MAC},
+		{"Hostname", event.Hostname},
+		{"Vendor", event.Vendor},
+		{"Device type", event.DeviceType},
+		{"Status", event.Status},
+		{"Previous", event.Old},
+	} {
+		if line[1] != "" {
+			fmt.Fprintf(&b, "%-12s %s\r\n", line[0]+":", line[1])
+		}
+	}
+	return []byte(b.String())
+}
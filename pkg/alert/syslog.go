@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Syslog-Severities (RFC 5424)
+const (
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+)
+
+// syslogFacilities bildet Facility-Namen auf ihre Nummer ab
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// localSyslogSockets sind die üblichen lokalen Syslog-Sockets (Linux, macOS, BSD)
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogSink schreibt Ereignisse an einen Syslog-Server (RFC 3164-Format).
+// Eigene Implementierung statt log/syslog, da dieses unter Windows nicht verfügbar ist.
+type SyslogSink struct {
+	// Address: "udp://host:514", "tcp://host:514", "unix:///dev/log" oder leer (lokaler Syslog)
+	Address  string
+	Tag      string // Standard: "netspy"
+	Facility string // Standard: "daemon"
+	Timeout  time.Duration
+}
+
+// Name gibt den Sink-Namen zurück
+func (s *SyslogSink) Name() string {
+	if s.Address == "" {
+		return "syslog"
+	}
+	return "syslog " + s.Address
+}
+
+// Send schreibt das Ereignis als Syslog-Nachricht
+func (s *SyslogSink) Send(ctx context.Context, event Event) error {
+	facility, ok := syslogFacilities[strings.ToLower(s.Facility)]
+	if s.Facility == "" {
+		facility, ok = syslogFacilities["daemon"], true
+	}
+	if !ok {
+		return fmt.Errorf("unknown syslog facility %q", s.Facility)
+	}
+
+	conn, network, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tag := s.Tag
+	if tag == "" {
+		tag = "netspy"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+
+	msg := fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		facility*8+eventSeverity(event.Type),
+		event.Time.Format(time.Stamp),
+		hostname, tag, os.Getpid(),
+		syslogMessage(event))
+
+	// Stream-Verbindungen brauchen ein Trennzeichen (RFC 6587, non-transparent framing)
+	if network == "tcp" || network == "unix" {
+		msg += "\n"
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(orDefault(s.Timeout, defaultSinkTimeout)))
+	_, err = conn.Write([]byte(msg))
+	return err
+}
+
+// dial verbindet sich mit dem konfigurierten oder lokalen Syslog
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, string, error) {
+	dialer := net.Dialer{Timeout: orDefault(s.Timeout, defaultSinkTimeout)}
+
+	if s.Address == "" {
+		if runtime.GOOS == "windows" {
+			return nil, "", fmt.Errorf("no local syslog on windows, set an address (udp://host:514)")
+		}
+		for _, path := range localSyslogSockets {
+			for _, network := range []string{"unixgram", "unix"} {
+				if conn, err := dialer.DialContext(ctx, network, path); err == nil {
+					return conn, network, nil
+				}
+			}
+		}
+		return nil, "", fmt.Errorf("no local syslog socket found")
+	}
+
+	network, address := "udp", s.Address
+	if u, err := url.Parse(s.Address); err == nil && u.Scheme != "" {
+		network = u.Scheme
+		address = u.Host
+		if network == "unix" || network == "unixgram" {
+			address = u.Path
+		}
+	}
+
+	switch network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, "", fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if (network == "udp" || network == "tcp") && !strings.Contains(address, ":") {
+		address = net.JoinHostPort(address, "514")
+	}
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	return conn, network, err
+}
+
+// eventSeverity ordnet Ereignissen eine Syslog-Severity zu
+func eventSeverity(t EventType) int {
+	switch t {
+	case DeviceOffline, DeviceFlapping, MACChanged, CertExpiring:
+		return severityWarning
+	case DeviceNew, IPChanged:
+		return severityNotice
+	default:
+		return severityInfo
+	}
+}
+
+// syslogMessage formatiert die Nachricht als Text mit key=value-Paaren
+func syslogMessage(event Event) string {
+	parts := []string{event.Message, "event=" + string(event.Type), "ip=" + event.IP}
+	if event.MAC != "" {
+		parts = append(parts, "mac="+event.MAC)
+	}
+	if event.Network != "" {
+		parts = append(parts, "network="+event.Network)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,252 @@
+package diff
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+
+	"netspy/pkg/scanner"
+)
+
+// ChangeKind beschreibt die Art einer Änderung zwischen zwei Scans
+type ChangeKind string
+
+const (
+	HostAdded         ChangeKind = "new_host"
+	HostRemoved       ChangeKind = "vanished_host"
+	IPChanged         ChangeKind = "ip_changed"
+	MACChanged        ChangeKind = "mac_changed"
+	HostnameChanged   ChangeKind = "hostname_changed"
+	VendorChanged     ChangeKind = "vendor_changed"
+	DeviceTypeChanged ChangeKind = "device_type_changed"
+	PortsOpened       ChangeKind = "ports_opened"
+	PortsClosed       ChangeKind = "ports_closed"
+)
+
+// Kinds enthält alle Änderungsarten in Anzeige-Reihenfolge
+var Kinds = []ChangeKind{
+	HostAdded, HostRemoved, IPChanged, MACChanged,
+	HostnameChanged, VendorChanged, DeviceTypeChanged, PortsOpened, PortsClosed,
+}
+
+// Change ist eine einzelne Änderung. IP und MAC beziehen sich auf den neuen Scan
+// (bei verschwundenen Hosts auf den alten).
+type Change struct {
+	Kind  ChangeKind `json:"kind"`
+	IP    string     `json:"ip"`
+	MAC   string     `json:"mac,omitempty"`
+	Old   string     `json:"old,omitempty"`
+	New   string     `json:"new,omitempty"`
+	Ports []int      `json:"ports,omitempty"`
+}
+
+// Result ist das Ergebnis eines Vergleichs
+type Result struct {
+	OldHosts  int      `json:"old_hosts"`
+	NewHosts  int      `json:"new_hosts"`
+	Unchanged int      `json:"unchanged"`
+	Changes   []Change `json:"changes"`
+}
+
+// HasChanges gibt true zurück, wenn sich die Scans unterscheiden
+func (r Result) HasChanges() bool {
+	return len(r.Changes) > 0
+}
+
+// Count gibt die Anzahl der Änderungen einer Art zurück
+func (r Result) Count(kind ChangeKind) int {
+	n := 0
+	for _, change := range r.Changes {
+		if change.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Options steuert den Vergleich
+type Options struct {
+	// Ignore blendet Änderungsarten aus (z.B. HostnameChanged bei wechselhafter Namensauflösung)
+	Ignore map[ChangeKind]bool
+}
+
+// Compare vergleicht zwei Scan-Ergebnisse. Hosts werden zuerst über die MAC-Adresse
+// zugeordnet (erkennt IP-Wechsel), danach über die IP-Adresse (erkennt MAC-Wechsel).
+// Offline-Hosts werden ignoriert.
+func Compare(oldHosts, newHosts []scanner.Host, opts Options) Result {
+	oldHosts, newHosts = onlineOnly(oldHosts), onlineOnly(newHosts)
+	result := Result{OldHosts: len(oldHosts), NewHosts: len(newHosts), Changes: []Change{}}
+
+	oldMatched := make([]bool, len(oldHosts))
+	newMatched := make([]bool, len(newHosts))
+
+	var changes []Change
+	compare := func(i, j int) {
+		oldMatched[i], newMatched[j] = true, true
+		hostChanges := compareHost(oldHosts[i], newHosts[j])
+		if len(filterChanges(hostChanges, opts)) == 0 {
+			result.Unchanged++
+		}
+		changes = append(changes, hostChanges...)
+	}
+
+	// 1. Zuordnung über die MAC-Adresse
+	oldByMAC := make(map[string]int)
+	for i, host := range oldHosts {
+		if mac := normalizeMAC(host.MAC); mac != "" {
+			oldByMAC[mac] = i
+		}
+	}
+	for j, host := range newHosts {
+		if i, ok := oldByMAC[normalizeMAC(host.MAC)]; ok && !oldMatched[i] {
+			compare(i, j)
+		}
+	}
+
+	// 2. Zuordnung über die IP-Adresse
+	oldByIP := make(map[string]int)
+	for i, host := range oldHosts {
+		if !oldMatched[i] {
+			oldByIP[host.IP.String()] = i
+		}
+	}
+	for j, host := range newHosts {
+		if newMatched[j] {
+			continue
+		}
+		if i, ok := oldByIP[host.IP.String()]; ok && !oldMatched[i] {
+			compare(i, j)
+		}
+	}
+
+	// 3. Übrige Hosts sind neu bzw. verschwunden
+	for j, host := range newHosts {
+		if !newMatched[j] {
+			changes = append(changes, Change{Kind: HostAdded, IP: host.IP.String(), MAC: host.MAC, New: describeHost(host)})
+		}
+	}
+	for i, host := range oldHosts {
+		if !oldMatched[i] {
+			changes = append(changes, Change{Kind: HostRemoved, IP: host.IP.String(), MAC: host.MAC, Old: describeHost(host)})
+		}
+	}
+
+	result.Changes = append(result.Changes, filterChanges(changes, opts)...)
+	sortChanges(result.Changes)
+	return result
+}
+
+// compareHost vergleicht zwei einander zugeordnete Hosts
+func compareHost(oldHost, newHost scanner.Host) []Change {
+	var changes []Change
+	ip, mac := newHost.IP.String(), newHost.MAC
+	if mac == "" {
+		mac = oldHost.MAC
+	}
+
+	add := func(kind ChangeKind, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, Change{Kind: kind, IP: ip, MAC: mac, Old: oldValue, New: newValue})
+		}
+	}
+
+	add(IPChanged, oldHost.IP.String(), ip)
+
+	// MAC-Wechsel nur melden, wenn beide Scans eine MAC kennen (ICMP/TCP-Scans liefern keine)
+	if oldHost.MAC != "" && newHost.MAC != "" {
+		add(MACChanged, normalizeMAC(oldHost.MAC), normalizeMAC(newHost.MAC))
+	}
+
+	add(HostnameChanged, oldHost.Hostname, newHost.Hostname)
+	add(VendorChanged, oldHost.Vendor, newHost.Vendor)
+	add(DeviceTypeChanged, oldHost.DeviceType, newHost.DeviceType)
+
+	if opened := portsMissing(newHost.Ports, oldHost.Ports); len(opened) > 0 {
+		changes = append(changes, Change{Kind: PortsOpened, IP: ip, MAC: mac, Ports: opened})
+	}
+	if closed := portsMissing(oldHost.Ports, newHost.Ports); len(closed) > 0 {
+		changes = append(changes, Change{Kind: PortsClosed, IP: ip, MAC: mac, Ports: closed})
+	}
+
+	return changes
+}
+
+// portsMissing gibt die Ports aus a zurück, die in b fehlen (sortiert)
+func portsMissing(a, b []int) []int {
+	present := make(map[int]bool, len(b))
+	for _, port := range b {
+		present[port] = true
+	}
+
+	var missing []int
+	for _, port := range a {
+		if !present[port] {
+			missing = append(missing, port)
+			present[port] = true // Duplikate nur einmal melden
+		}
+	}
+	sort.Ints(missing)
+	return missing
+}
+
+// describeHost fasst einen Host für neue/verschwundene Einträge zusammen
+func describeHost(host scanner.Host) string {
+	var parts []string
+	for _, part := range []string{host.Hostname, host.Vendor, host.DeviceType} {
+		if part != "" && part != "Unknown" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// filterChanges entfernt ignorierte Änderungsarten
+func filterChanges(changes []Change, opts Options) []Change {
+	if len(opts.Ignore) == 0 {
+		return changes
+	}
+	filtered := make([]Change, 0, len(changes))
+	for _, change := range changes {
+		if !opts.Ignore[change.Kind] {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
+}
+
+// sortChanges sortiert nach Änderungsart, dann numerisch nach IP
+func sortChanges(changes []Change) {
+	order := make(map[ChangeKind]int, len(Kinds))
+	for i, kind := range Kinds {
+		order[kind] = i
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return order[changes[i].Kind] < order[changes[j].Kind]
+		}
+		ipI, ipJ := net.ParseIP(changes[i].IP), net.ParseIP(changes[j].IP)
+		return bytes.Compare(ipI.To16(), ipJ.To16()) < 0
+	})
+}
+
+// onlineOnly filtert Offline-Hosts heraus
+func onlineOnly(hosts []scanner.Host) []scanner.Host {
+	online := make([]scanner.Host, 0, len(hosts))
+	for _, host := range hosts {
+		if host.Online {
+			online = append(online, host)
+		}
+	}
+	return online
+}
+
+// normalizeMAC bringt MAC-Adressen in eine einheitliche Schreibweise ("" wenn ungültig)
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return ""
+	}
+	return hw.String()
+}
@@ -0,0 +1,154 @@
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/diff"
+	"netspy/pkg/scanner"
+)
+
+func host(ip, mac string, ports ...int) scanner.Host {
+	return scanner.Host{IP: net.ParseIP(ip), MAC: mac, Ports: ports, Online: true}
+}
+
+func kinds(result diff.Result) []diff.ChangeKind {
+	var k []diff.ChangeKind
+	for _, change := range result.Changes {
+		k = append(k, change.Kind)
+	}
+	return k
+}
+
+var _ = Describe("Compare", func() {
+	It("should report no changes for identical scans", func() {
+		hosts := []scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:01", 22)}
+		result := diff.Compare(hosts, hosts, diff.Options{})
+
+		Expect(result.HasChanges()).To(BeFalse())
+		Expect(result.Unchanged).To(Equal(1))
+		Expect(result.Changes).NotTo(BeNil())
+	})
+
+	It("should report new and vanished hosts", func() {
+		result := diff.Compare(
+			[]scanner.Host{host("192.0.2.1", ""), host("192.0.2.2", "")},
+			[]scanner.Host{host("192.0.2.1", ""), host("192.0.2.3", "")},
+			diff.Options{},
+		)
+
+		Expect(kinds(result)).To(Equal([]diff.ChangeKind{diff.HostAdded, diff.HostRemoved}))
+		Expect(result.Changes[0].IP).To(Equal("192.0.2.3"))
+		Expect(result.Changes[1].IP).To(Equal("192.0.2.2"))
+	})
+
+	It("should detect IP changes for the same MAC", func() {
+		result := diff.Compare(
+			[]scanner.Host{host("192.0.2.10", "AA-BB-CC-00-00-01"), host("192.0.2.11", "aa:bb:cc:00:00:02")},
+			[]scanner.Host{host("192.0.2.11", "aa:bb:cc:00:00:01")},
+			diff.Options{},
+		)
+
+		// .11 gehört jetzt dem Gerät, das vorher .10 hatte - kein MAC-Wechsel
+		Expect(kinds(result)).To(Equal([]diff.ChangeKind{diff.HostRemoved, diff.IPChanged}))
+		ipChange := result.Changes[1]
+		Expect(ipChange.Old).To(Equal("192.0.2.10"))
+		Expect(ipChange.New).To(Equal("192.0.2.11"))
+	})
+
+	It("should detect MAC changes for the same IP", func() {
+		result := diff.Compare(
+			[]scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:01")},
+			[]scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:02")},
+			diff.Options{},
+		)
+
+		Expect(kinds(result)).To(Equal([]diff.ChangeKind{diff.MACChanged}))
+	})
+
+	It("should not report a MAC change when one scan has no MACs", func() {
+		result := diff.Compare(
+			[]scanner.Host{host("192.0.2.1", "")},
+			[]scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:01")},
+			diff.Options{},
+		)
+
+		Expect(result.HasChanges()).To(BeFalse())
+	})
+
+	It("should report attribute changes and opened/closed ports", func() {
+		before := host("192.0.2.1", "aa:bb:cc:00:00:01", 22, 80)
+		before.Hostname, before.Vendor, before.DeviceType = "nas", "Synology", "NAS"
+		after := host("192.0.2.1", "aa:bb:cc:00:00:01", 443, 22)
+		after.Hostname, after.Vendor, after.DeviceType = "nas.local", "Synology", "Server"
+
+		result := diff.Compare([]scanner.Host{before}, []scanner.Host{after}, diff.Options{})
+
+		Expect(kinds(result)).To(Equal([]diff.ChangeKind{
+			diff.HostnameChanged, diff.DeviceTypeChanged, diff.PortsOpened, diff.PortsClosed,
+		}))
+		Expect(result.Changes[2].Ports).To(Equal([]int{443}))
+		Expect(result.Changes[3].Ports).To(Equal([]int{80}))
+	})
+
+	It("should ignore offline hosts and ignored change kinds", func() {
+		offline := host("192.0.2.5", "")
+		offline.Online = false
+		before := host("192.0.2.1", "")
+		before.Hostname = "a"
+		after := host("192.0.2.1", "")
+		after.Hostname = "b"
+
+		result := diff.Compare(
+			[]scanner.Host{before},
+			[]scanner.Host{after, offline},
+			diff.Options{Ignore: map[diff.ChangeKind]bool{diff.HostnameChanged: true}},
+		)
+
+		Expect(result.HasChanges()).To(BeFalse())
+		Expect(result.Unchanged).To(Equal(1))
+	})
+})
+
+var _ = Describe("Write", func() {
+	var result diff.Result
+
+	BeforeEach(func() {
+		result = diff.Compare(
+			[]scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:01", 22)},
+			[]scanner.Host{host("192.0.2.1", "aa:bb:cc:00:00:01", 22, 443), host("192.0.2.2", "")},
+			diff.Options{},
+		)
+	})
+
+	It("should write JSON", func() {
+		var buf bytes.Buffer
+		Expect(diff.Write(&buf, result, "json")).To(Succeed())
+
+		var decoded diff.Result
+		Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded.Changes).To(HaveLen(2))
+		Expect(decoded.Changes[1].Ports).To(Equal([]int{443}))
+	})
+
+	It("should write Markdown", func() {
+		var buf bytes.Buffer
+		Expect(diff.Write(&buf, result, "markdown")).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("| New host | `192.0.2.2` |"))
+		Expect(buf.String()).To(ContainSubstring("| Ports opened | `192.0.2.1` | `aa:bb:cc:00:00:01` | 443 |"))
+	})
+
+	It("should write a table", func() {
+		var buf bytes.Buffer
+		Expect(diff.Write(&buf, result, "table")).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("1 new host, 1 ports opened"))
+	})
+
+	It("should reject unknown formats", func() {
+		Expect(diff.Write(&bytes.Buffer{}, result, "xml")).To(HaveOccurred())
+	})
+})
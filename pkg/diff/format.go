@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// kindLabels sind die Anzeigenamen der Änderungsarten
+var kindLabels = map[ChangeKind]string{
+	HostAdded:         "New host",
+	HostRemoved:       "Vanished host",
+	IPChanged:         "IP changed",
+	MACChanged:        "MAC changed",
+	HostnameChanged:   "Hostname changed",
+	VendorChanged:     "Vendor changed",
+	DeviceTypeChanged: "Device type changed",
+	PortsOpened:       "Ports opened",
+	PortsClosed:       "Ports closed",
+}
+
+// Write gibt das Ergebnis im angegebenen Format aus (table, json, markdown)
+func Write(w io.Writer, result Result, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		return writeJSON(w, result)
+	case "markdown", "md":
+		return writeMarkdown(w, result)
+	case "table", "":
+		return writeTable(w, result)
+	default:
+		return fmt.Errorf("unsupported format: %s (table, json, markdown)", format)
+	}
+}
+
+func writeJSON(w io.Writer, result Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func writeTable(w io.Writer, result Result) error {
+	if !result.HasChanges() {
+		color.New(color.FgGreen).Fprintf(w, "[OK] No changes (%d hosts)\n", result.NewHosts)
+		return nil
+	}
+
+	for _, change := range result.Changes {
+		marker, c := "~", color.New(color.FgYellow)
+		switch change.Kind {
+		case HostAdded, PortsOpened:
+			marker, c = "+", color.New(color.FgGreen)
+		case HostRemoved, PortsClosed:
+			marker, c = "-", color.New(color.FgRed)
+		}
+
+		c.Fprintf(w, "%s %-20s %-16s %-18s %s\n", marker, kindLabels[change.Kind], change.IP, change.MAC, describeChange(change))
+	}
+
+	fmt.Fprintf(w, "\n%s\n", summary(result))
+	return nil
+}
+
+func writeMarkdown(w io.Writer, result Result) error {
+	fmt.Fprintf(w, "## Scan diff\n\n%s\n", summary(result))
+	if !result.HasChanges() {
+		return nil
+	}
+
+	fmt.Fprintln(w, "\n| Change | IP | MAC | Details |")
+	fmt.Fprintln(w, "|--------|----|-----|---------|")
+	for _, change := range result.Changes {
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+			kindLabels[change.Kind], markdownCode(change.IP), markdownCode(change.MAC), markdownEscape(describeChange(change)))
+	}
+	return nil
+}
+
+// describeChange beschreibt den Inhalt einer Änderung ("alt -> neu" bzw. Portliste)
+func describeChange(change Change) string {
+	switch {
+	case len(change.Ports) > 0:
+		ports := make([]string, len(change.Ports))
+		for i, port := range change.Ports {
+			ports[i] = strconv.Itoa(port)
+		}
+		return strings.Join(ports, ", ")
+	case change.Kind == HostAdded:
+		return change.New
+	case change.Kind == HostRemoved:
+		return change.Old
+	default:
+		return fmt.Sprintf("%s -> %s", orDash(change.Old), orDash(change.New))
+	}
+}
+
+// summary fasst die Anzahl der Änderungen zusammen
+func summary(result Result) string {
+	var parts []string
+	for _, kind := range Kinds {
+		if n := result.Count(kind); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToLower(kindLabels[kind])))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("No changes (%d hosts before, %d after)", result.OldHosts, result.NewHosts)
+	}
+	return fmt.Sprintf("%s (%d hosts before, %d after)", strings.Join(parts, ", "), result.OldHosts, result.NewHosts)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
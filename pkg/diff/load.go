@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"netspy/pkg/scanner"
+)
+
+// LoadFile liest ein Scan-Ergebnis im JSON- oder CSV-Format ("netspy scan -f json|csv").
+// Das Format wird an der Dateiendung bzw. am Inhalt erkannt.
+func LoadFile(path string) ([]scanner.Host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := Load(data, strings.ToLower(filepath.Ext(path)) == ".csv")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return hosts, nil
+}
+
+// Load parst ein Scan-Ergebnis. Ohne forceCSV wird JSON erkannt, wenn der Inhalt mit
+// '[' beginnt (oder "null" ist - die JSON-Ausgabe eines leeren Scans).
+func Load(data []byte, forceCSV bool) ([]scanner.Host, error) {
+	trimmed := bytes.TrimSpace(data)
+	if !forceCSV && (bytes.HasPrefix(trimmed, []byte("[")) || bytes.Equal(trimmed, []byte("null"))) {
+		return parseJSON(trimmed)
+	}
+	return parseCSV(data)
+}
+
+// parseJSON liest die JSON-Ausgabe (Array von scanner.Host)
+func parseJSON(data []byte) ([]scanner.Host, error) {
+	var hosts []scanner.Host
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("invalid JSON scan result: %v", err)
+	}
+	for i := range hosts {
+		if hosts[i].IP == nil {
+			return nil, fmt.Errorf("host %d has no IP address", i+1)
+		}
+	}
+	return hosts, nil
+}
+
+// parseCSV liest die CSV-Ausgabe. Die Spalten werden über die Kopfzeile zugeordnet;
+// ältere Dateien ohne Quoting (Vendor mit Komma, z.B. "Apple, Inc.") werden toleriert,
+// indem überzählige Felder der Vendor-Spalte zugeschlagen werden.
+func parseCSV(data []byte) ([]scanner.Host, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV scan result: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["ip"]; !ok {
+		return nil, fmt.Errorf("invalid CSV scan result: missing IP column")
+	}
+
+	var hosts []scanner.Host
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV scan result: %v", err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		record = mergeOverflow(record, header, columns)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		host, err := hostFromCSV(field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// mergeOverflow fasst überzählige Felder in der Vendor-Spalte zusammen
+func mergeOverflow(record, header []string, columns map[string]int) []string {
+	extra := len(record) - len(header)
+	vendor, ok := columns["vendor"]
+	if extra <= 0 || !ok {
+		return record
+	}
+
+	merged := make([]string, 0, len(header))
+	merged = append(merged, record[:vendor]...)
+	merged = append(merged, strings.Join(record[vendor:vendor+extra+1], ","))
+	merged = append(merged, record[vendor+extra+1:]...)
+	return merged
+}
+
+// hostFromCSV baut einen Host aus den Feldern einer CSV-Zeile
+func hostFromCSV(field func(string) string) (scanner.Host, error) {
+	ip := net.ParseIP(field("ip"))
+	if ip == nil {
+		return scanner.Host{}, fmt.Errorf("invalid IP address %q", field("ip"))
+	}
+
+	host := scanner.Host{
+		IP:         ip,
+		Hostname:   field("hostname"),
+		MAC:        field("mac"),
+		Vendor:     field("vendor"),
+		DeviceType: field("devicetype"),
+		Online:     true, // Die CSV-Ausgabe enthält nur Online-Hosts
+	}
+
+	if rtt := field("rtt"); rtt != "" {
+		ms, err := strconv.ParseFloat(rtt, 64)
+		if err != nil {
+			return host, fmt.Errorf("invalid RTT %q", rtt)
+		}
+		host.RTT = time.Duration(ms * float64(time.Millisecond))
+	}
+
+	for _, p := range splitList(field("ports")) {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return host, fmt.Errorf("invalid port %q", p)
+		}
+		host.Ports = append(host.Ports, port)
+	}
+
+	for _, addr := range splitList(field("ipv6")) {
+		if ip := net.ParseIP(addr); ip != nil {
+			host.IPv6 = append(host.IPv6, scanner.IPv6Address{IP: ip})
+		}
+	}
+
+	return host, nil
+}
+
+// splitList trennt eine mit ';' getrennte Liste
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
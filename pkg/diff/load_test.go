@@ -0,0 +1,76 @@
+package diff_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/diff"
+)
+
+var _ = Describe("Load", func() {
+	It("should parse the JSON output", func() {
+		hosts, err := diff.Load([]byte(`[
+  {"ip": "192.0.2.1", "mac": "aa:bb:cc:00:00:01", "ports": [22, 80], "rtt": 1500000, "online": true}
+]`), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].IP.String()).To(Equal("192.0.2.1"))
+		Expect(hosts[0].Ports).To(Equal([]int{22, 80}))
+		Expect(hosts[0].Online).To(BeTrue())
+	})
+
+	It("should accept the JSON output of an empty scan", func() {
+		hosts, err := diff.Load([]byte("null\n"), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts).To(BeEmpty())
+	})
+
+	It("should parse the CSV output", func() {
+		hosts, err := diff.Load([]byte(`IP,Hostname,RTT,MAC,Vendor,DeviceType,Ports,IPv6
+192.0.2.1,router,1.50,aa:bb:cc:00:00:01,"Apple, Inc.",Router,22;80,fd00::1;fe80::1
+192.0.2.2,,,,,,,
+`), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts).To(HaveLen(2))
+		Expect(hosts[0].Hostname).To(Equal("router"))
+		Expect(hosts[0].Vendor).To(Equal("Apple, Inc."))
+		Expect(hosts[0].RTT).To(Equal(1500 * time.Microsecond))
+		Expect(hosts[0].Ports).To(Equal([]int{22, 80}))
+		Expect(hosts[0].IPv6).To(HaveLen(2))
+		Expect(hosts[1].Online).To(BeTrue())
+	})
+
+	It("should tolerate unquoted vendors containing commas", func() {
+		hosts, err := diff.Load([]byte(`IP,Hostname,RTT,MAC,Vendor,DeviceType,Ports,IPv6
+192.0.2.1,,,aa:bb:cc:00:00:01,Apple, Inc.,Smartphone,443,
+`), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts[0].Vendor).To(Equal("Apple, Inc."))
+		Expect(hosts[0].DeviceType).To(Equal("Smartphone"))
+		Expect(hosts[0].Ports).To(Equal([]int{443}))
+	})
+
+	It("should reject invalid input", func() {
+		_, err := diff.Load([]byte("IP,Hostname\nnot-an-ip,x\n"), false)
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+
+		_, err = diff.Load([]byte("Hostname\nx\n"), false)
+		Expect(err).To(HaveOccurred())
+
+		_, err = diff.Load([]byte(`[{"hostname": "x"}]`), false)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should detect the format from the file extension", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "scan.csv")
+		Expect(os.WriteFile(path, []byte("IP\n192.0.2.1\n"), 0o600)).To(Succeed())
+
+		hosts, err := diff.LoadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,303 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRawSocketUnavailable wird zurückgegeben, wenn kein Raw-Socket geöffnet werden kann
+// (fehlende CAP_NET_RAW-Berechtigung oder nicht unterstützte Plattform).
+// Aufrufer fallen dann auf das Parsen der System-ARP-Tabelle zurück.
+var ErrRawSocketUnavailable = errors.New("raw socket unavailable (requires CAP_NET_RAW or root)")
+
+const (
+	etherTypeARP  = 0x0806
+	etherTypeIPv4 = 0x0800
+
+	arpOpRequest = 1
+	arpOpReply   = 2
+
+	ethHeaderLen = 14
+	arpPacketLen = 28
+	minFrameLen  = 60 // Ethernet-Mindestlänge ohne FCS
+)
+
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// FrameConn abstrahiert eine Verbindung auf Ethernet-Ebene.
+// Unter Linux wird sie durch einen AF_PACKET-Socket bereitgestellt, in Tests
+// kann eine beliebige Paketquelle injiziert werden.
+type FrameConn interface {
+	ReadFrame(buf []byte) (int, error)
+	WriteFrame(frame []byte) error
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// ARPSweeper sendet ARP-Requests (who-has) an alle Adressen eines Netzwerks
+// und sammelt die Antworten inklusive RTT pro Host
+type ARPSweeper struct {
+	conn     FrameConn
+	srcMAC   net.HardwareAddr
+	srcIP    net.IP
+	timeout  time.Duration // Wartezeit auf Antworten nach dem letzten Request
+	interval time.Duration // Pause zwischen zwei Requests (schont Switches)
+	retries  int           // Zusätzliche Runden für Hosts ohne Antwort
+}
+
+// NewARPSweeper erstellt einen ARP-Sweeper auf einer bestehenden Frame-Verbindung
+func NewARPSweeper(conn FrameConn, srcMAC net.HardwareAddr, srcIP net.IP, timeout time.Duration) *ARPSweeper {
+	return &ARPSweeper{
+		conn:     conn,
+		srcMAC:   srcMAC,
+		srcIP:    srcIP.To4(),
+		timeout:  timeout,
+		interval: 200 * time.Microsecond,
+		retries:  1,
+	}
+}
+
+// SetInterval setzt die Pause zwischen zwei gesendeten Requests
+func (s *ARPSweeper) SetInterval(interval time.Duration) {
+	s.interval = interval
+}
+
+// SetRetries setzt die Anzahl zusätzlicher Runden für nicht antwortende Hosts
+func (s *ARPSweeper) SetRetries(retries int) {
+	s.retries = retries
+}
+
+// Sweep sendet who-has Requests für jede Adresse im Netzwerk und liefert
+// alle antwortenden Hosts sortiert nach IP zurück
+func (s *ARPSweeper) Sweep(ctx context.Context, network *net.IPNet) ([]ARPEntry, error) {
+	return s.SweepTargets(ctx, GenerateIPsFromCIDR(network))
+}
+
+// SweepTargets sendet who-has Requests an die angegebenen Adressen.
+// Antworten von Adressen, die nicht angefragt wurden, werden ignoriert.
+func (s *ARPSweeper) SweepTargets(ctx context.Context, targets []net.IP) ([]ARPEntry, error) {
+	if s.srcIP == nil {
+		return nil, fmt.Errorf("ARP sweep requires an IPv4 source address")
+	}
+
+	var mu sync.Mutex
+	sent := make(map[string]time.Time, len(targets))
+	replies := make(map[string]ARPEntry)
+
+	// Antworten in eigener Goroutine einsammeln
+	done := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		buf := make([]byte, 1514)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			_ = s.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, err := s.conn.ReadFrame(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					continue
+				}
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Kurze Pause, damit dauerhafte Fehler nicht die CPU blockieren
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			ip, mac, ok := parseARPReply(buf[:n])
+			if !ok {
+				continue
+			}
+
+			received := time.Now()
+			mu.Lock()
+			key := ip.String()
+			if sentAt, wasSent := sent[key]; wasSent {
+				if _, seen := replies[key]; !seen {
+					replies[key] = ARPEntry{
+						IP:     ip,
+						MAC:    mac,
+						RTT:    received.Sub(sentAt),
+						Online: true,
+					}
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+
+	var sendErr error
+	for round := 0; round <= s.retries && sendErr == nil; round++ {
+		for _, ip := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+
+			// Eigene Adresse und bereits beantwortete Hosts überspringen
+			if ip.To4() == nil || ip.Equal(s.srcIP) {
+				continue
+			}
+			key := ip.String()
+			mu.Lock()
+			_, answered := replies[key]
+			mu.Unlock()
+			if answered {
+				continue
+			}
+
+			frame := buildARPRequest(s.srcMAC, s.srcIP, ip)
+			mu.Lock()
+			// RTT ab der ersten Anfrage messen - eine späte Antwort darauf
+			// darf nicht mit dem Zeitpunkt des Retries verrechnet werden
+			if _, wasSent := sent[key]; !wasSent {
+				sent[key] = time.Now()
+			}
+			mu.Unlock()
+			if err := s.conn.WriteFrame(frame); err != nil {
+				sendErr = fmt.Errorf("failed to send ARP request: %v", err)
+				break
+			}
+
+			if s.interval > 0 {
+				time.Sleep(s.interval)
+			}
+		}
+
+		// Auf späte Antworten warten
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.timeout):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(done)
+	<-readerDone
+
+	mu.Lock()
+	entries := make([]ARPEntry, 0, len(replies))
+	for _, entry := range replies {
+		entries = append(entries, entry)
+	}
+	mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return binary.BigEndian.Uint32(entries[i].IP.To4()) < binary.BigEndian.Uint32(entries[j].IP.To4())
+	})
+
+	if sendErr != nil && len(entries) == 0 {
+		return nil, sendErr
+	}
+	return entries, nil
+}
+
+// buildARPRequest baut einen Ethernet-Frame mit ARP who-has Request
+func buildARPRequest(srcMAC net.HardwareAddr, srcIP, dstIP net.IP) []byte {
+	frame := make([]byte, minFrameLen)
+
+	// Ethernet-Header
+	copy(frame[0:6], broadcastMAC)
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+
+	// ARP-Payload
+	arp := frame[ethHeaderLen:]
+	binary.BigEndian.PutUint16(arp[0:2], 1) // Hardware-Typ Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], etherTypeIPv4)
+	arp[4] = 6 // Länge Hardware-Adresse
+	arp[5] = 4 // Länge Protokoll-Adresse
+	binary.BigEndian.PutUint16(arp[6:8], arpOpRequest)
+	copy(arp[8:14], srcMAC)
+	copy(arp[14:18], srcIP.To4())
+	// Target-MAC bleibt 00:00:00:00:00:00
+	copy(arp[24:28], dstIP.To4())
+
+	return frame
+}
+
+// parseARPReply extrahiert Sender-IP und -MAC aus einem ARP-Reply-Frame
+func parseARPReply(frame []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(frame) < ethHeaderLen+arpPacketLen {
+		return nil, nil, false
+	}
+	if binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return nil, nil, false
+	}
+
+	arp := frame[ethHeaderLen:]
+	if binary.BigEndian.Uint16(arp[2:4]) != etherTypeIPv4 || arp[4] != 6 || arp[5] != 4 {
+		return nil, nil, false
+	}
+	if binary.BigEndian.Uint16(arp[6:8]) != arpOpReply {
+		return nil, nil, false
+	}
+
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, arp[8:14])
+	ip := net.IPv4(arp[14], arp[15], arp[16], arp[17]).To4()
+
+	// Broadcast/Multicast-Absender ignorieren
+	if mac[0]&0x01 != 0 {
+		return nil, nil, false
+	}
+
+	return ip, mac, true
+}
+
+// Sweep führt einen aktiven ARP-Sweep über das Interface des Zielnetzwerks durch.
+// Ohne Raw-Socket-Berechtigung wird ErrRawSocketUnavailable zurückgegeben.
+func (a *ARPScanner) Sweep(ctx context.Context, network *net.IPNet) ([]ARPEntry, error) {
+	iface, srcIP, err := InterfaceForNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := OpenARPConn(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	sweeper := NewARPSweeper(conn, iface.HardwareAddr, srcIP, a.timeout)
+	return sweeper.Sweep(ctx, network)
+}
+
+// SweepTargets führt einen aktiven ARP-Sweep für einzelne Adressen durch.
+// Alle Ziele müssen im Subnetz desselben lokalen Interfaces liegen.
+func (a *ARPScanner) SweepTargets(ctx context.Context, targets []net.IP) ([]ARPEntry, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	iface, srcIP, err := InterfaceForNetwork(&net.IPNet{IP: targets[0], Mask: net.CIDRMask(32, 32)})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := OpenARPConn(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	sweeper := NewARPSweeper(conn, iface.HardwareAddr, srcIP, a.timeout)
+	return sweeper.SweepTargets(ctx, targets)
+}
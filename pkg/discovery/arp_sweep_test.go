@@ -0,0 +1,197 @@
+package discovery_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+// fakeFrameConn simuliert ein Ethernet-Segment: beantwortet ARP-Requests
+// für bekannte Hosts nach einer festen Verzögerung
+type fakeFrameConn struct {
+	mu         sync.Mutex
+	responders map[string]net.HardwareAddr
+	dropFirst  map[string]bool // Erste Anfrage verwerfen (testet Retries)
+	requests   map[string]int
+	delay      time.Duration
+	inbox      chan []byte
+	deadline   time.Time
+}
+
+func newFakeFrameConn(delay time.Duration) *fakeFrameConn {
+	return &fakeFrameConn{
+		responders: make(map[string]net.HardwareAddr),
+		dropFirst:  make(map[string]bool),
+		requests:   make(map[string]int),
+		delay:      delay,
+		inbox:      make(chan []byte, 1024),
+	}
+}
+
+func (f *fakeFrameConn) WriteFrame(frame []byte) error {
+	Expect(len(frame)).To(BeNumerically(">=", 42))
+	Expect(binary.BigEndian.Uint16(frame[12:14])).To(Equal(uint16(0x0806)))
+	Expect(net.HardwareAddr(frame[0:6]).String()).To(Equal("ff:ff:ff:ff:ff:ff"))
+	Expect(binary.BigEndian.Uint16(frame[20:22])).To(Equal(uint16(1))) // who-has
+
+	senderMAC := net.HardwareAddr(append([]byte(nil), frame[22:28]...))
+	senderIP := net.IP(append([]byte(nil), frame[28:32]...))
+	target := net.IP(frame[38:42]).String()
+
+	f.mu.Lock()
+	f.requests[target]++
+	count := f.requests[target]
+	mac, ok := f.responders[target]
+	drop := f.dropFirst[target] && count == 1
+	f.mu.Unlock()
+
+	if !ok || drop {
+		return nil
+	}
+
+	reply := buildTestARPReply(mac, net.ParseIP(target), senderMAC, senderIP)
+	time.AfterFunc(f.delay, func() { f.inbox <- reply })
+	return nil
+}
+
+func (f *fakeFrameConn) ReadFrame(buf []byte) (int, error) {
+	f.mu.Lock()
+	deadline := f.deadline
+	f.mu.Unlock()
+
+	select {
+	case frame := <-f.inbox:
+		return copy(buf, frame), nil
+	case <-time.After(time.Until(deadline)):
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (f *fakeFrameConn) SetReadDeadline(t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deadline = t
+	return nil
+}
+
+func (f *fakeFrameConn) Close() error { return nil }
+
+func buildTestARPReply(senderMAC net.HardwareAddr, senderIP net.IP, targetMAC net.HardwareAddr, targetIP net.IP) []byte {
+	frame := make([]byte, 60)
+	copy(frame[0:6], targetMAC)
+	copy(frame[6:12], senderMAC)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0806)
+	binary.BigEndian.PutUint16(frame[14:16], 1)
+	binary.BigEndian.PutUint16(frame[16:18], 0x0800)
+	frame[18] = 6
+	frame[19] = 4
+	binary.BigEndian.PutUint16(frame[20:22], 2) // is-at
+	copy(frame[22:28], senderMAC)
+	copy(frame[28:32], senderIP.To4())
+	copy(frame[32:38], targetMAC)
+	copy(frame[38:42], targetIP.To4())
+	return frame
+}
+
+var _ = Describe("ARP Sweep", func() {
+	var (
+		conn    *fakeFrameConn
+		srcMAC  net.HardwareAddr
+		srcIP   net.IP
+		network *net.IPNet
+	)
+
+	BeforeEach(func() {
+		conn = newFakeFrameConn(5 * time.Millisecond)
+		srcMAC, _ = net.ParseMAC("02:00:00:00:00:01")
+		srcIP = net.ParseIP("10.0.0.1")
+		_, network, _ = net.ParseCIDR("10.0.0.0/24")
+	})
+
+	It("should find hosts anywhere in the subnet, not only the first 50", func() {
+		conn.responders["10.0.0.2"], _ = net.ParseMAC("aa:bb:cc:00:00:02")
+		conn.responders["10.0.0.200"], _ = net.ParseMAC("aa:bb:cc:00:00:c8")
+
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, 100*time.Millisecond)
+		sweeper.SetInterval(0)
+		entries, err := sweeper.Sweep(context.Background(), network)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].IP.String()).To(Equal("10.0.0.2"))
+		Expect(entries[0].MAC.String()).To(Equal("aa:bb:cc:00:00:02"))
+		Expect(entries[0].Online).To(BeTrue())
+		Expect(entries[0].RTT).To(BeNumerically(">=", 5*time.Millisecond))
+		Expect(entries[1].IP.String()).To(Equal("10.0.0.200"))
+	})
+
+	It("should send one request per address and skip its own IP", func() {
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, 20*time.Millisecond)
+		sweeper.SetInterval(0)
+		sweeper.SetRetries(0)
+		_, err := sweeper.Sweep(context.Background(), network)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conn.requests).To(HaveLen(253)) // .1-.254 ohne eigene Adresse
+		Expect(conn.requests).NotTo(HaveKey("10.0.0.1"))
+	})
+
+	It("should retry hosts that did not answer the first request", func() {
+		conn.responders["10.0.0.77"], _ = net.ParseMAC("aa:bb:cc:00:00:4d")
+		conn.dropFirst["10.0.0.77"] = true
+
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, 50*time.Millisecond)
+		sweeper.SetInterval(0)
+		entries, err := sweeper.Sweep(context.Background(), network)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(conn.requests["10.0.0.77"]).To(Equal(2))
+	})
+
+	It("should measure the RTT of a late reply from the first request", func() {
+		conn = newFakeFrameConn(80 * time.Millisecond)
+		conn.responders["10.0.0.9"], _ = net.ParseMAC("aa:bb:cc:00:00:09")
+
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, 50*time.Millisecond)
+		sweeper.SetInterval(0)
+		entries, err := sweeper.SweepTargets(context.Background(), []net.IP{net.ParseIP("10.0.0.9")})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(conn.requests["10.0.0.9"]).To(Equal(2))
+		Expect(entries[0].RTT).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("should ignore replies from outside the target network", func() {
+		outsideMAC, _ := net.ParseMAC("aa:bb:cc:00:00:99")
+		conn.inbox <- buildTestARPReply(outsideMAC, net.ParseIP("192.168.1.5"), srcMAC, srcIP)
+
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, 20*time.Millisecond)
+		sweeper.SetInterval(0)
+		entries, err := sweeper.Sweep(context.Background(), network)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should stop when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		sweeper := discovery.NewARPSweeper(conn, srcMAC, srcIP, time.Second)
+		start := time.Now()
+		_, err := sweeper.Sweep(ctx, network)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+})
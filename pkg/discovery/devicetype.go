@@ -1,6 +1,7 @@
 package discovery
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -16,218 +17,192 @@ const (
 	DeviceTypeUnknown    = "Unknown"
 )
 
-// DetectDeviceType bestimmt den Gerätetyp basierend auf verfügbaren Informationen
+// DetectDeviceType bestimmt den Gerätetyp aus Hostname, MAC, Vendor und offenen Ports
+// mit dem aktiven Regelsatz (siehe Classify)
 func DetectDeviceType(hostname, mac, vendor string, ports []int) string {
-	hostname = strings.ToLower(hostname)
-	vendor = strings.ToLower(vendor)
-
-	// 1. Check for randomized MAC (privacy feature on smartphones)
-	if isLocallyAdministeredMAC(mac) && vendor == "" {
-		// High confidence this is a smartphone with MAC randomization
-		if containsAny(hostname, []string{"iphone", "android", "mobile"}) {
-			return DeviceTypeSmartphone + " (Privacy)"
-		}
-		// Default to smartphone for locally-administered MACs without vendor
-		return DeviceTypeSmartphone + " (Privacy)"
-	}
-
-	// 2. Hostname pattern matching (most reliable)
-	deviceType := detectByHostname(hostname)
-	if deviceType != DeviceTypeUnknown {
-		return deviceType
-	}
-
-	// 3. Vendor-based detection
-	deviceType = detectByVendor(vendor)
-	if deviceType != DeviceTypeUnknown {
-		return deviceType
-	}
-
-	// 4. Port-based OS fingerprinting
-	deviceType = detectByPorts(ports)
-	if deviceType != DeviceTypeUnknown {
-		return deviceType
-	}
-
-	return DeviceTypeUnknown
+	return Classify(DeviceFacts{Hostname: hostname, MAC: mac, Vendor: vendor, Ports: ports}).Type
 }
 
-// detectByHostname identifiziert Gerätetyp anhand von Hostname-Mustern
-func detectByHostname(hostname string) string {
-	// Apple mobile devices
-	if containsAny(hostname, []string{"iphone"}) {
-		return DeviceTypeSmartphone
-	}
-	if containsAny(hostname, []string{"ipad"}) {
-		return DeviceTypeTablet
-	}
-
-	// Apple computers
-	if containsAny(hostname, []string{"macbook", "imac", "mac-", "macos"}) {
-		return DeviceTypeComputer
-	}
-	if containsAny(hostname, []string{"appletv", "apple-tv"}) {
-		return DeviceTypeIoT
-	}
+// DetectDeviceTypeSNMP bestimmt den Gerätetyp aus der SNMP-Selbstauskunft
+// (sysDescr, sysObjectID). Sie ist zuverlässiger als Hostname und Vendor und
+// hat daher Vorrang, sofern sie einen Typ liefert.
+func DetectDeviceTypeSNMP(sysDescr, sysObjectID string) string {
+	descr := strings.ToLower(sysDescr)
 
-	// Android devices
-	if containsAny(hostname, []string{"android-", "android_"}) {
-		return DeviceTypeSmartphone
-	}
-	if containsAny(hostname, []string{"galaxy", "samsung"}) {
-		return DeviceTypeSmartphone
-	}
-	if containsAny(hostname, []string{"pixel"}) {
-		return DeviceTypeSmartphone
+	// Beschreibung zuerst: HP und Cisco bauen sowohl Drucker/APs als auch Switches
+	if containsAny(descr, []string{"printer", "laserjet", "officejet", "pagewide", "jetdirect", "imagerunner",
+		"workcentre", "versalink", "ecosys", "bizhub", "mfc-", "hl-", "lexmark"}) {
+		return DeviceTypePrinter
 	}
-
-	// Windows devices
-	if containsAny(hostname, []string{"desktop-", "-pc", "windows", "win10", "win11"}) {
-		return DeviceTypeComputer
+	if containsAny(descr, []string{"access point", "aironet", "unifi ap", "uap-", "wireless lan", "wlan"}) {
+		return DeviceTypeNetwork + " (Access Point)"
 	}
-	if containsAny(hostname, []string{"laptop"}) {
-		return DeviceTypeComputer
+	if containsAny(descr, []string{"firewall", "fortigate", "pfsense", "opnsense", "sonicwall", "asa software"}) {
+		return DeviceTypeNetwork + " (Firewall)"
 	}
-
-	// Linux devices
-	if containsAny(hostname, []string{"ubuntu", "debian", "fedora", "centos", "arch", "linux"}) {
-		return DeviceTypeComputer
+	if containsAny(descr, []string{"switch", "catalyst", "procurve", "nexus", "edgeswitch", "usw-", "junos ex", "cbs350", "sg350"}) {
+		return DeviceTypeNetwork + " (Switch)"
 	}
-
-	// Network equipment
-	if containsAny(hostname, []string{"router", "gateway", "switch", "ap-", "access-point"}) {
-		return DeviceTypeNetwork
+	if containsAny(descr, []string{"router", "routeros", "fritz!box", "edgeos", "vyos"}) {
+		return DeviceTypeNetwork + " (Router)"
 	}
-
-	// IoT devices - includes tasmota, home automation, and other IoT keywords
-	if containsAny(hostname, []string{"homeassistant", "home-assistant", "openhab", "domoticz"}) {
-		return DeviceTypeIoT
+	if containsAny(descr, []string{"synology", "diskstation", "qnap", "readynas", "truenas"}) {
+		return DeviceTypeServer + " (NAS)"
 	}
-	if containsAny(hostname, []string{"hue-", "philips-hue", "ring-", "nest-", "alexa", "echo-", "tasmota"}) {
-		return DeviceTypeIoT
+	if containsAny(descr, []string{"smart-ups", "powerchute", "ups network management"}) {
+		return DeviceTypeIoT + " (UPS)"
 	}
-	if containsAny(hostname, []string{"smarttv", "smart-tv", "roku", "chromecast", "firetv"}) {
-		return DeviceTypeIoT
-	}
-	if containsAny(hostname, []string{"camera", "cam-", "ipcam"}) {
-		return DeviceTypeIoT
-	}
-
-	// Printers
-	if containsAny(hostname, []string{"printer", "print-", "hp-", "canon-", "epson-", "brother-"}) {
-		return DeviceTypePrinter
+	if strings.Contains(descr, "windows") {
+		return "Windows Computer"
 	}
 
-	// Servers
-	if containsAny(hostname, []string{"server", "srv-", "nas", "storage"}) {
-		return DeviceTypeServer
+	// Enterprise-Nummer der sysObjectID (1.3.6.1.4.1.<enterprise>...)
+	if enterprise, ok := snmpEnterprise(sysObjectID); ok {
+		switch enterprise {
+		case 9, 2636, 14988, 41112, 4526, 171, 11863, 25506, 14823, 12356, 6486, 1916:
+			// Cisco, Juniper, MikroTik, Ubiquiti, Netgear, D-Link, TP-Link, H3C,
+			// Aruba, Fortinet, Alcatel-Lucent, Extreme
+			return DeviceTypeNetwork
+		case 367, 1602, 2435, 1248, 253, 641, 1347, 18334, 2385:
+			// Ricoh, Canon, Brother, Epson, Xerox, Lexmark, Kyocera, Konica Minolta, Sharp
+			return DeviceTypePrinter
+		case 6574, 24681:
+			// Synology, QNAP
+			return DeviceTypeServer + " (NAS)"
+		case 311:
+			// Microsoft
+			return "Windows Computer"
+		}
 	}
 
 	return DeviceTypeUnknown
 }
 
-// detectByVendor identifiziert Gerätetyp anhand von MAC-Vendor
-func detectByVendor(vendor string) string {
-	// Apple devices - generally computers (Mac, MacBook, iMac)
-	// Note: iPhones/iPads usually use MAC randomization, so Apple vendor = likely Mac
-	if strings.Contains(vendor, "apple") {
-		return DeviceTypeComputer
-	}
-
-	// Smartphone manufacturers
-	if containsAny(vendor, []string{"samsung", "huawei", "xiaomi", "oppo", "vivo", "oneplus"}) {
-		return DeviceTypeSmartphone
-	}
-	if containsAny(vendor, []string{"google"}) {
-		return DeviceTypeComputer // Google Nest, Chromecast, etc. are IoT but default to computer
-	}
-
-	// Network equipment vendors
-	if containsAny(vendor, []string{"cisco", "ubiquiti", "tp-link", "netgear", "asus router", "d-link", "mikrotik"}) {
-		return DeviceTypeNetwork
+// DetectDeviceTypeDNSSD bestimmt den Gerätetyp aus den per DNS-SD beworbenen
+// Diensten: zuerst aus Apple-Modellkennungen der TXT-Daten ("MacBookPro18,3",
+// "iPhone14,2"), dann aus den Dienst-Typen (Drucker, HomeKit, Streaming)
+func DetectDeviceTypeDNSSD(services []DNSSDService) string {
+	has := func(types ...string) bool {
+		for _, svc := range services {
+			for _, serviceType := range types {
+				if strings.EqualFold(svc.Type, serviceType) {
+					return true
+				}
+			}
+		}
+		return false
 	}
 
-	// HP differenzieren: Enterprise vs Consumer
-	if containsAny(vendor, []string{"hp enterprise", "hpe ", "hewlett packard enterprise"}) {
-		return DeviceTypeNetwork // HPE = Server, Switches, Enterprise-Lösungen
+	for _, svc := range services {
+		model := strings.ToLower(svc.Model())
+		switch {
+		case strings.HasPrefix(model, "iphone"):
+			return DeviceTypeSmartphone
+		case strings.HasPrefix(model, "ipad"):
+			return DeviceTypeTablet
+		case containsAny(model, []string{"macbook", "imac", "macmini", "macpro", "macstudio"}),
+			strings.HasPrefix(model, "mac") && strings.Contains(model, ","):
+			return DeviceTypeComputer
+		case strings.HasPrefix(model, "appletv"), strings.HasPrefix(model, "audioaccessory"):
+			return DeviceTypeIoT + " (Media)"
+		}
 	}
 
-	// Printer vendors (HP Inc. = Consumer + Drucker)
-	if containsAny(vendor, []string{"hewlett packard", "hp inc", "canon", "epson", "brother", "xerox", "lexmark"}) {
+	switch {
+	case has("_ipp._tcp", "_ipps._tcp", "_printer._tcp", "_pdl-datastream._tcp", "_uscan._tcp", "_scanner._tcp"):
 		return DeviceTypePrinter
-	}
-
-	// IoT device vendors - includes Espressif, Raspberry Pi
-	if containsAny(vendor, []string{"philips", "ring", "nest", "amazon", "sonos", "lifx", "espressif", "shelly"}) {
+	case has("_hap._tcp", "_hap._udp", "_matter._tcp", "_hue._tcp", "_shelly._tcp"):
 		return DeviceTypeIoT
-	}
-
-	// Raspberry Pi - can be used as computer or IoT, default to Computer
-	if containsAny(vendor, []string{"raspberry"}) {
-		return DeviceTypeComputer
-	}
-
-	// Computer/server vendors
-	if containsAny(vendor, []string{"dell", "lenovo", "hp inc", "microsoft", "intel", "asustek", "gigabyte", "msi"}) {
+	case has("_googlecast._tcp", "_airplay._tcp", "_raop._tcp", "_spotify-connect._tcp", "_sonos._tcp", "_amzn-wplay._tcp"):
+		return DeviceTypeIoT + " (Media)"
+	case has("_adisk._tcp") && has("_smb._tcp", "_afpovertcp._tcp"):
+		return DeviceTypeServer + " (NAS)" // Time-Machine-Ziel mit Freigaben
+	case has("_workstation._tcp", "_rfb._tcp"):
 		return DeviceTypeComputer
 	}
-
 	return DeviceTypeUnknown
 }
 
-// detectByPorts führt OS-Fingerprinting basierend auf offenen Ports durch
-func detectByPorts(ports []int) string {
-	if len(ports) == 0 {
+// DetectDeviceTypeUPnP bestimmt den Gerätetyp aus der UPnP-Gerätebeschreibung:
+// zuerst aus dem Gerätetyp und den Diensten, dann aus Hersteller und Modell
+func DetectDeviceTypeUPnP(device *UPnPDevice) string {
+	if device == nil {
 		return DeviceTypeUnknown
 	}
+	types := strings.ToLower(device.DeviceType + " " + strings.Join(device.Services, " "))
+	product := strings.ToLower(device.Manufacturer + " " + device.Model())
 
-	hasPort := func(port int) bool {
-		for _, p := range ports {
-			if p == port {
-				return true
-			}
-		}
-		return false
-	}
-
-	// Windows signatures
-	if hasPort(445) || hasPort(135) || hasPort(139) {
-		return "Windows Computer"
-	}
-	if hasPort(3389) {
-		return "Windows Server (RDP)"
+	switch {
+	case containsAny(types, []string{":printer:", ":scanner:", ":printbasic:"}):
+		return DeviceTypePrinter
+	case containsAny(types, []string{":wlanaccesspoint", ":wlanconfiguration"}) && !strings.Contains(types, ":internetgatewaydevice:"):
+		return DeviceTypeNetwork + " (Access Point)"
+	case containsAny(types, []string{":internetgatewaydevice:", ":wanconnectiondevice:", ":wandevice:", ":wanipconnection:", ":wanpppconnection:"}):
+		return DeviceTypeNetwork + " (Router)"
+	case containsAny(product, []string{"synology", "qnap", "diskstation", "readynas", "truenas"}):
+		return DeviceTypeServer + " (NAS)"
+	case containsAny(types, []string{":mediarenderer:", ":zoneplayer:", "urn:dial-multiscreen-org:"}):
+		return DeviceTypeIoT + " (Media)"
+	case containsAny(product, []string{"philips hue", "hue bridge", "shelly", "tasmota"}):
+		return DeviceTypeIoT
+	case containsAny(types, []string{":mediaserver:"}):
+		return DeviceTypeServer
 	}
+	return DeviceTypeUnknown
+}
 
-	// Unix/Linux server signatures
-	if hasPort(22) {
-		// SSH + Web ports = Linux/Server
-		if hasPort(80) || hasPort(443) {
-			return DeviceTypeServer + " (Linux/Server)"
-		}
-		return DeviceTypeServer + " (Unix/Linux System)"
+// DetectDeviceTypeNeighbor bestimmt den Gerätetyp aus den per LLDP/CDP gemeldeten
+// Fähigkeiten. Reine Endgeräte ("station", z.B. Server mit lldpd) bleiben unbekannt.
+func DetectDeviceTypeNeighbor(neighbor *Neighbor) string {
+	if neighbor == nil {
+		return DeviceTypeUnknown
 	}
-
-	// Generic web server (HTTP/HTTPS only)
-	if (hasPort(80) || hasPort(443)) && !hasPort(445) && !hasPort(22) {
-		return DeviceTypeServer
+	switch {
+	case neighbor.HasCapability("phone"):
+		return DeviceTypeIoT + " (Phone)"
+	case neighbor.HasCapability("wlan-ap"):
+		return DeviceTypeNetwork + " (Access Point)"
+	case neighbor.HasCapability("router") && neighbor.HasCapability("bridge"):
+		return DeviceTypeNetwork // Layer-3-Switch
+	case neighbor.HasCapability("router"):
+		return DeviceTypeNetwork + " (Router)"
+	case neighbor.HasCapability("bridge"):
+		return DeviceTypeNetwork + " (Switch)"
 	}
+	return DeviceTypeUnknown
+}
 
-	// Printer signature
-	if hasPort(631) || hasPort(9100) {
+// DetectDeviceTypeDHCP bestimmt den Gerätetyp aus der DHCP Vendor Class (Option 60)
+func DetectDeviceTypeDHCP(vendorClass string) string {
+	class := strings.ToLower(vendorClass)
+	switch {
+	case strings.HasPrefix(class, "android-dhcp"):
+		return DeviceTypeSmartphone
+	case strings.HasPrefix(class, "msft"):
+		return "Windows Computer"
+	case containsAny(class, []string{"cisco ap", "aruba ap", "airespace"}):
+		return DeviceTypeNetwork + " (Access Point)"
+	case containsAny(class, []string{"polycom", "yealink", "snom", "cisco systems, inc. ip phone"}):
+		return DeviceTypeIoT + " (Phone)"
+	case containsAny(class, []string{"hp printer", "canon", "brother", "epson"}):
 		return DeviceTypePrinter
 	}
+	return DeviceTypeUnknown
+}
 
-	// Web server/IoT
-	if hasPort(8080) || hasPort(8443) {
-		return "Web Server/IoT"
+// snmpEnterprise liefert die Enterprise-Nummer einer sysObjectID
+func snmpEnterprise(sysObjectID string) (int, bool) {
+	const enterprises = "1.3.6.1.4.1."
+	oid := strings.TrimPrefix(sysObjectID, ".")
+	if !strings.HasPrefix(oid, enterprises) {
+		return 0, false
 	}
-
-	// Database servers
-	if hasPort(3306) || hasPort(5432) || hasPort(27017) {
-		return "Database Server"
+	rest := oid[len(enterprises):]
+	if i := strings.IndexByte(rest, '.'); i >= 0 {
+		rest = rest[:i]
 	}
-
-	return DeviceTypeUnknown
+	enterprise, err := strconv.Atoi(rest)
+	return enterprise, err == nil
 }
 
 // isLocallyAdministeredMAC prüft ob MAC-Adresse locally-administered Bit gesetzt hat
@@ -85,4 +85,96 @@ var _ = Describe("Device Type Detection", func() {
 			})
 		})
 	})
+
+	Describe("DetectDeviceTypeSNMP", func() {
+		It("should classify by sysDescr", func() {
+			Expect(discovery.DetectDeviceTypeSNMP("HP ETHERNET MULTI-ENVIRONMENT,ROM none,JETDIRECT,JD153", "1.3.6.1.4.1.11.2.3.9.1")).To(Equal("Printer"))
+			Expect(discovery.DetectDeviceTypeSNMP("HPE OfficeConnect Switch 1820 24G J9980A", "1.3.6.1.4.1.11.2.3.7.11.181")).To(Equal("Network Equipment (Switch)"))
+			Expect(discovery.DetectDeviceTypeSNMP("Linux UAP-AC-Pro 4.4.153 #1 SMP", "1.3.6.1.4.1.8072.3.2.10")).To(Equal("Network Equipment (Access Point)"))
+			Expect(discovery.DetectDeviceTypeSNMP("Linux DiskStation 4.4.302+ #72806 SMP", "1.3.6.1.4.1.8072.3.2.10")).To(Equal("Server (NAS)"))
+		})
+
+		It("should fall back to the sysObjectID enterprise", func() {
+			Expect(discovery.DetectDeviceTypeSNMP("Cisco IOS Software, C2960X Software", "1.3.6.1.4.1.9.1.1208")).To(Equal("Network Equipment"))
+			Expect(discovery.DetectDeviceTypeSNMP("", ".1.3.6.1.4.1.2435.2.3.9.1")).To(Equal("Printer"))
+		})
+
+		It("should return Unknown for generic agents", func() {
+			Expect(discovery.DetectDeviceTypeSNMP("Linux web01 6.1.0-18-amd64", "1.3.6.1.4.1.8072.3.2.10")).To(Equal("Unknown"))
+			Expect(discovery.DetectDeviceTypeSNMP("", "")).To(Equal("Unknown"))
+		})
+	})
+
+	Describe("DetectDeviceTypeDNSSD", func() {
+		It("should prefer Apple model identifiers from TXT records", func() {
+			airplay := discovery.DNSSDService{Type: "_airplay._tcp", TXT: map[string]string{"model": "MacBookPro18,3"}}
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{airplay})).To(Equal("Computer"))
+
+			airplay.TXT["model"] = "AppleTV6,2"
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{airplay})).To(Equal("IoT Device (Media)"))
+
+			info := discovery.DNSSDService{Type: "_device-info._tcp", TXT: map[string]string{"model": "Mac14,2"}}
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{info})).To(Equal("Computer"))
+		})
+
+		It("should classify by service type", func() {
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{{Type: "_http._tcp"}, {Type: "_ipp._tcp"}})).To(Equal("Printer"))
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{{Type: "_hap._tcp"}})).To(Equal("IoT Device"))
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{{Type: "_googlecast._tcp"}})).To(Equal("IoT Device (Media)"))
+			Expect(discovery.DetectDeviceTypeDNSSD([]discovery.DNSSDService{{Type: "_http._tcp"}})).To(Equal("Unknown"))
+			Expect(discovery.DetectDeviceTypeDNSSD(nil)).To(Equal("Unknown"))
+		})
+	})
+
+	Describe("DetectDeviceTypeUPnP", func() {
+		It("should classify by device and service types", func() {
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{
+				DeviceType: "urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+				Services:   []string{"urn:schemas-upnp-org:service:WANIPConnection:1"},
+			})).To(Equal("Network Equipment (Router)"))
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{
+				DeviceType: "urn:schemas-upnp-org:device:Printer:1",
+			})).To(Equal("Printer"))
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{
+				DeviceType: "urn:schemas-upnp-org:device:ZonePlayer:1",
+			})).To(Equal("IoT Device (Media)"))
+		})
+
+		It("should fall back to manufacturer and model", func() {
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{
+				DeviceType:   "urn:schemas-upnp-org:device:Basic:1",
+				Manufacturer: "Synology", ModelName: "DS920+",
+			})).To(Equal("Server (NAS)"))
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{
+				DeviceType: "urn:schemas-upnp-org:device:Basic:1", Manufacturer: "Royal Philips Electronics",
+				ModelName: "Philips hue bridge 2015",
+			})).To(Equal("IoT Device"))
+			Expect(discovery.DetectDeviceTypeUPnP(&discovery.UPnPDevice{DeviceType: "urn:schemas-upnp-org:device:Basic:1"})).To(Equal("Unknown"))
+			Expect(discovery.DetectDeviceTypeUPnP(nil)).To(Equal("Unknown"))
+		})
+	})
+
+	Describe("DetectDeviceTypeNeighbor", func() {
+		It("should classify by LLDP/CDP capabilities", func() {
+			detect := func(capabilities ...string) string {
+				return discovery.DetectDeviceTypeNeighbor(&discovery.Neighbor{Capabilities: capabilities})
+			}
+			Expect(detect("bridge")).To(Equal("Network Equipment (Switch)"))
+			Expect(detect("bridge", "router")).To(Equal("Network Equipment"))
+			Expect(detect("router")).To(Equal("Network Equipment (Router)"))
+			Expect(detect("bridge", "wlan-ap")).To(Equal("Network Equipment (Access Point)"))
+			Expect(detect("bridge", "phone")).To(Equal("IoT Device (Phone)"))
+			Expect(detect("station")).To(Equal("Unknown"))
+			Expect(discovery.DetectDeviceTypeNeighbor(nil)).To(Equal("Unknown"))
+		})
+	})
+
+	Describe("DetectDeviceTypeDHCP", func() {
+		It("should classify by DHCP vendor class", func() {
+			Expect(discovery.DetectDeviceTypeDHCP("android-dhcp-14")).To(Equal("Smartphone"))
+			Expect(discovery.DetectDeviceTypeDHCP("MSFT 5.0")).To(Equal("Windows Computer"))
+			Expect(discovery.DetectDeviceTypeDHCP("dhcpcd-9.4.1:Linux-6.1.0:x86_64")).To(Equal("Unknown"))
+			Expect(discovery.DetectDeviceTypeDHCP("")).To(Equal("Unknown"))
+		})
+	})
 })
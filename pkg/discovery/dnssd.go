@@ -0,0 +1,522 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNS-SD (RFC 6763) über mDNS: Dienst-Typen, Instanzen und deren SRV/TXT/A-Records
+const (
+	dnssdServicesName = "_services._dns-sd._udp.local."
+
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+
+	// Obergrenzen gegen geschwätzige oder böswillige Responder
+	dnssdMaxTypes     = 256
+	dnssdMaxInstances = 2048
+)
+
+var errDNSFormat = errors.New("malformed DNS message")
+
+// DNSSDService ist ein per DNS-SD beworbener Dienst eines Geräts
+type DNSSDService struct {
+	Type     string            `json:"type"`           // z.B. "_ipp._tcp"
+	Instance string            `json:"instance"`       // z.B. "HP LaserJet M404"
+	Host     string            `json:"host,omitempty"` // SRV-Ziel ohne ".local"
+	Port     int               `json:"port,omitempty"`
+	TXT      map[string]string `json:"txt,omitempty"` // Schlüssel in Kleinbuchstaben
+}
+
+// Name gibt den Dienstnamen ohne Unterstriche und Protokoll zurück ("_ipp._tcp" → "ipp")
+func (s DNSSDService) Name() string {
+	name, _, _ := strings.Cut(s.Type, ".")
+	return strings.TrimPrefix(name, "_")
+}
+
+// Model gibt die Modellkennung aus den TXT-Daten zurück (z.B. "AppleTV6,2",
+// "Chromecast Ultra" oder "HP LaserJet M404"), sonst ""
+func (s DNSSDService) Model() string {
+	// md: Chromecast/HomeKit, model: AirPlay/device-info, am: RAOP, ty/usb_MDL: IPP/LPD
+	for _, key := range []string{"model", "md", "am", "ty", "usb_mdl", "product"} {
+		if value := strings.Trim(s.TXT[key], "() "); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// String gibt den Dienst kompakt aus: `_ipp._tcp "HP LaserJet" :631 (HP LaserJet M404)`
+func (s DNSSDService) String() string {
+	var sb strings.Builder
+	sb.WriteString(s.Type)
+	if s.Instance != "" {
+		sb.WriteString(" " + strconv.Quote(s.Instance))
+	}
+	if s.Port > 0 {
+		sb.WriteString(" :" + strconv.Itoa(s.Port))
+	}
+	if model := s.Model(); model != "" && model != s.Instance {
+		sb.WriteString(" (" + model + ")")
+	}
+	return sb.String()
+}
+
+// DNSSDBrowser sucht per DNS-SD nach den im lokalen Netz beworbenen Diensten
+type DNSSDBrowser struct {
+	Address string        // Standard: mDNS-Multicast 224.0.0.251:5353
+	Timeout time.Duration // Gesamtdauer der Suche (Standard: 2s)
+}
+
+// BrowseDNSSD sucht im lokalen Netz nach per DNS-SD beworbenen Diensten und gibt
+// sie nach IPv4-Adresse des Geräts gruppiert zurück
+func BrowseDNSSD(ctx context.Context, timeout time.Duration) (map[string][]DNSSDService, error) {
+	return DNSSDBrowser{Timeout: timeout}.Browse(ctx)
+}
+
+// Browse fragt die Dienst-Typen ab ("_services._dns-sd._udp.local"), dann die Instanzen
+// jedes Typs und - falls nicht schon mitgeliefert - deren SRV-, TXT- und A-Records.
+// Die Abfragen kommen von einem beliebigen Port, die Responder antworten daher per
+// Unicast (Legacy Unicast, RFC 6762 6.7).
+func (b DNSSDBrowser) Browse(ctx context.Context) (map[string][]DNSSDService, error) {
+	address := b.Address
+	if address == "" {
+		address = mdnsAddress
+	}
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mDNS address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create UDP connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	// Einmalige Wiederholung aller offenen Fragen nach einem Drittel der Zeit (UDP-Verluste)
+	retry := time.Now().Add(timeout / 3)
+
+	state := newDNSSDState()
+	send := func(questions []dnsQuestion) error {
+		for len(questions) > 0 {
+			batch := questions
+			if len(batch) > 16 {
+				batch = batch[:16]
+			}
+			questions = questions[len(batch):]
+			if _, err := conn.WriteToUDP(buildDNSQuery(batch), addr); err != nil {
+				return fmt.Errorf("failed to send DNS-SD query: %w", err)
+			}
+		}
+		return nil
+	}
+	if err := send(state.pending()); err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, 9000)
+	for ctx.Err() == nil {
+		readDeadline := deadline
+		if !retry.IsZero() && retry.Before(deadline) {
+			readDeadline = retry
+		}
+		_ = conn.SetReadDeadline(readDeadline)
+
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && !retry.IsZero() && time.Now().Before(deadline) {
+				retry = time.Time{}
+				state.asked = make(map[string]bool)
+				_ = send(state.pending())
+				continue
+			}
+			break // Timeout erreicht - normal
+		}
+
+		state.add(buffer[:n], from.IP)
+		if err := send(state.pending()); err != nil {
+			break
+		}
+	}
+
+	return state.results(), nil
+}
+
+// dnsQuestion ist eine Frage einer DNS-Abfrage
+type dnsQuestion struct {
+	name  string
+	qtype uint16
+}
+
+// dnssdInstance sammelt die Records einer Dienst-Instanz
+type dnssdInstance struct {
+	name        string // Vollständiger Name ("HP\.LaserJet._ipp._tcp.local.")
+	label       string // Instanz-Name ohne Maskierung ("HP.LaserJet")
+	serviceType string // "_ipp._tcp.local."
+	target      string // SRV-Ziel ("drucker.local.")
+	port        int
+	txt         map[string]string
+	hasSRV      bool
+	hasTXT      bool
+	source      net.IP // Absender der ersten Antwort (Fallback ohne A-Record)
+}
+
+// dnssdState ist der Zwischenstand einer Suche (Schlüssel in Kleinbuchstaben,
+// DNS-Namen sind unabhängig von Groß-/Kleinschreibung)
+type dnssdState struct {
+	types     map[string]string // Schlüssel → Dienst-Typ
+	instances map[string]*dnssdInstance
+	order     []string          // Instanzen in Reihenfolge des Auftauchens
+	addrs     map[string]net.IP // Hostname → IPv4-Adresse
+	asked     map[string]bool   // Bereits gestellte Fragen ("name|typ")
+}
+
+func newDNSSDState() *dnssdState {
+	return &dnssdState{
+		types:     make(map[string]string),
+		instances: make(map[string]*dnssdInstance),
+		addrs:     make(map[string]net.IP),
+		asked:     make(map[string]bool),
+	}
+}
+
+// add übernimmt die Records einer Antwort (Answer- und Additional-Section)
+func (s *dnssdState) add(data []byte, source net.IP) {
+	records, err := parseDNSRecords(data)
+	if err != nil {
+		return
+	}
+
+	// Erst Typen und Instanzen, damit SRV/TXT derselben Antwort zugeordnet werden können
+	for _, record := range records {
+		if record.rrtype != dnsTypePTR {
+			continue
+		}
+		target, _, err := readDNSName(data, record.offset)
+		if err != nil {
+			continue
+		}
+		owner := strings.ToLower(record.name)
+		switch {
+		case owner == dnssdServicesName:
+			if isServiceType(target) && len(s.types) < dnssdMaxTypes {
+				s.types[strings.ToLower(target)] = target
+			}
+		case isServiceType(record.name):
+			key := strings.ToLower(target)
+			if _, exists := s.instances[key]; exists || len(s.instances) >= dnssdMaxInstances {
+				continue
+			}
+			if !strings.HasSuffix(key, "."+owner) {
+				continue // Instanz gehört nicht zum Typ
+			}
+			// Genau ein Label vor dem Typ; nicht über Byte-Längen, da Kleinschreibung
+			// die Länge ändern kann (Kelvin-Zeichen → "k")
+			labels := splitDNSName(target)
+			if len(labels) != len(splitDNSName(record.name))+1 {
+				continue
+			}
+			s.instances[key] = &dnssdInstance{name: target, label: labels[0], serviceType: record.name, source: source}
+			s.order = append(s.order, key)
+			if len(s.types) < dnssdMaxTypes {
+				s.types[owner] = record.name
+			}
+		}
+	}
+
+	for _, record := range records {
+		key := strings.ToLower(record.name)
+		switch record.rrtype {
+		case dnsTypeSRV:
+			instance := s.instances[key]
+			if instance == nil || record.length < 7 {
+				continue
+			}
+			target, _, err := readDNSName(data, record.offset+6)
+			if err != nil {
+				continue
+			}
+			instance.port = int(binary.BigEndian.Uint16(data[record.offset+4:]))
+			instance.target = target
+			instance.hasSRV = true
+		case dnsTypeTXT:
+			if instance := s.instances[key]; instance != nil {
+				instance.txt = parseTXT(data[record.offset : record.offset+record.length])
+				instance.hasTXT = true
+			}
+		case dnsTypeA:
+			if record.length == 4 {
+				s.addrs[key] = net.IP(append([]byte(nil), data[record.offset:record.offset+4]...))
+			}
+		}
+	}
+}
+
+// pending gibt die noch offenen Fragen zurück und merkt sie sich als gestellt
+func (s *dnssdState) pending() []dnsQuestion {
+	var questions []dnsQuestion
+	ask := func(name string, qtype uint16) {
+		key := strings.ToLower(name) + "|" + strconv.Itoa(int(qtype))
+		if !s.asked[key] {
+			s.asked[key] = true
+			questions = append(questions, dnsQuestion{name: name, qtype: qtype})
+		}
+	}
+
+	ask(dnssdServicesName, dnsTypePTR)
+	types := make([]string, 0, len(s.types))
+	for _, serviceType := range s.types {
+		types = append(types, serviceType)
+	}
+	sort.Strings(types)
+	for _, serviceType := range types {
+		ask(serviceType, dnsTypePTR)
+	}
+	for _, key := range s.order {
+		instance := s.instances[key]
+		if !instance.hasSRV {
+			ask(instance.name, dnsTypeSRV)
+		}
+		if !instance.hasTXT {
+			ask(instance.name, dnsTypeTXT)
+		}
+		if instance.target != "" && s.addrs[strings.ToLower(instance.target)] == nil {
+			ask(instance.target, dnsTypeA)
+		}
+	}
+	return questions
+}
+
+// results gruppiert die Instanzen nach IPv4-Adresse (A-Record des SRV-Ziels,
+// sonst Absender der Antwort) und sortiert sie nach Typ und Name
+func (s *dnssdState) results() map[string][]DNSSDService {
+	result := make(map[string][]DNSSDService)
+	for _, key := range s.order {
+		instance := s.instances[key]
+		ip := s.addrs[strings.ToLower(instance.target)]
+		if ip == nil {
+			ip = instance.source.To4()
+		}
+		if ip == nil {
+			continue
+		}
+
+		serviceType := strings.TrimSuffix(strings.TrimSuffix(instance.serviceType, "."), ".local")
+		svc := DNSSDService{
+			Type:     serviceType,
+			Instance: instance.label,
+			Host:     strings.TrimSuffix(strings.TrimSuffix(instance.target, "."), ".local"),
+			Port:     instance.port,
+			TXT:      instance.txt,
+		}
+		result[ip.String()] = append(result[ip.String()], svc)
+	}
+
+	for _, services := range result {
+		sort.Slice(services, func(i, j int) bool {
+			if services[i].Type != services[j].Type {
+				return services[i].Type < services[j].Type
+			}
+			return services[i].Instance < services[j].Instance
+		})
+	}
+	return result
+}
+
+// isServiceType prüft auf einen Dienst-Typ wie "_ipp._tcp.local." (ohne Subtypen)
+func isServiceType(name string) bool {
+	lower := strings.ToLower(name)
+	if !strings.HasPrefix(lower, "_") || strings.Contains(lower, "._sub.") {
+		return false
+	}
+	return strings.HasSuffix(lower, "._tcp.local.") || strings.HasSuffix(lower, "._udp.local.")
+}
+
+// dnsRecord ist ein Resource Record; offset/length verweisen auf die RDATA im Paket
+type dnsRecord struct {
+	name   string
+	rrtype uint16
+	offset int
+	length int
+}
+
+// parseDNSRecords liest alle Records (Answer, Authority, Additional) einer Antwort
+func parseDNSRecords(data []byte) ([]dnsRecord, error) {
+	if len(data) < 12 || data[2]&0x80 == 0 {
+		return nil, errDNSFormat
+	}
+	questions := int(binary.BigEndian.Uint16(data[4:]))
+	count := int(binary.BigEndian.Uint16(data[6:])) + int(binary.BigEndian.Uint16(data[8:])) + int(binary.BigEndian.Uint16(data[10:]))
+
+	pos := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = next + 4 // Typ und Klasse
+	}
+
+	records := make([]dnsRecord, 0, count)
+	for i := 0; i < count; i++ {
+		name, next, err := readDNSName(data, pos)
+		if err != nil {
+			return records, err
+		}
+		pos = next
+		if pos+10 > len(data) {
+			return records, errDNSFormat
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+8:]))
+		if pos+10+length > len(data) {
+			return records, errDNSFormat
+		}
+		records = append(records, dnsRecord{
+			name:   name,
+			rrtype: binary.BigEndian.Uint16(data[pos:]),
+			offset: pos + 10,
+			length: length,
+		})
+		pos += 10 + length
+	}
+	return records, nil
+}
+
+// readDNSName liest einen (ggf. komprimierten) Namen ab offset und gibt ihn mit
+// abschließendem Punkt zurück. Punkte und Backslashes innerhalb eines Labels werden
+// maskiert ("HP\.LaserJet"), wie es DNS-SD für Instanznamen vorsieht.
+func readDNSName(data []byte, offset int) (string, int, error) {
+	var sb strings.Builder
+	next := -1
+	pos := offset
+	for jumps := 0; ; {
+		if pos >= len(data) {
+			return "", 0, errDNSFormat
+		}
+		length := int(data[pos])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = pos + 1
+			}
+			if sb.Len() == 0 {
+				sb.WriteByte('.')
+			}
+			return sb.String(), next, nil
+		case length&0xC0 == 0xC0:
+			if pos+1 >= len(data) {
+				return "", 0, errDNSFormat
+			}
+			// Zeiger: höchstens 16 Sprünge (Schutz vor Schleifen)
+			if jumps++; jumps > 16 {
+				return "", 0, errDNSFormat
+			}
+			if next < 0 {
+				next = pos + 2
+			}
+			pos = int(binary.BigEndian.Uint16(data[pos:]) & 0x3FFF)
+		case length&0xC0 != 0:
+			return "", 0, errDNSFormat
+		default:
+			if pos+1+length > len(data) || sb.Len()+length > 1024 {
+				return "", 0, errDNSFormat
+			}
+			for _, c := range data[pos+1 : pos+1+length] {
+				if c == '.' || c == '\\' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteByte(c)
+			}
+			sb.WriteByte('.')
+			pos += 1 + length
+		}
+	}
+}
+
+// splitDNSName zerlegt einen maskierten Namen in seine Labels
+func splitDNSName(name string) []string {
+	var labels []string
+	var label []byte
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '\\' && i+1 < len(name):
+			i++
+			label = append(label, name[i])
+		case c == '.':
+			labels = append(labels, string(label))
+			label = label[:0]
+		default:
+			label = append(label, c)
+		}
+	}
+	if len(label) > 0 {
+		labels = append(labels, string(label))
+	}
+	return labels
+}
+
+// buildDNSQuery erstellt eine mDNS-Abfrage mit mehreren Fragen (Klasse IN)
+func buildDNSQuery(questions []dnsQuestion) []byte {
+	query := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(query[4:], uint16(len(questions)))
+	for _, question := range questions {
+		for _, label := range splitDNSName(question.name) {
+			if len(label) > 63 {
+				label = label[:63]
+			}
+			query = append(query, byte(len(label)))
+			query = append(query, label...)
+		}
+		query = append(query, 0x00)
+		query = binary.BigEndian.AppendUint16(query, question.qtype)
+		query = binary.BigEndian.AppendUint16(query, 0x0001)
+	}
+	return query
+}
+
+// parseTXT zerlegt TXT-RDATA in Schlüssel/Wert-Paare (RFC 6763 6.3-6.4)
+func parseTXT(rdata []byte) map[string]string {
+	txt := make(map[string]string)
+	for pos := 0; pos < len(rdata); {
+		length := int(rdata[pos])
+		pos++
+		if pos+length > len(rdata) {
+			break
+		}
+		entry := string(rdata[pos : pos+length])
+		pos += length
+
+		key, value, _ := strings.Cut(entry, "=")
+		key = strings.ToLower(key)
+		if key == "" {
+			continue
+		}
+		// Bei doppelten Schlüsseln gilt der erste
+		if _, exists := txt[key]; !exists {
+			txt[key] = strings.ToValidUTF8(value, "")
+		}
+	}
+	if len(txt) == 0 {
+		return nil
+	}
+	return txt
+}
@@ -0,0 +1,187 @@
+package discovery_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+// dnsRR ist ein Resource Record für den Test-Responder (Name als Labels, ohne Kompression)
+type dnsRR struct {
+	name  []string
+	rtype uint16
+	data  []byte
+}
+
+func dnsName(labels ...string) []byte {
+	var out []byte
+	for _, label := range labels {
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0)
+}
+
+func dnsResponse(answers, additionals []dnsRR) []byte {
+	msg := []byte{0, 0, 0x84, 0, 0, 0, 0, byte(len(answers)), 0, 0, 0, byte(len(additionals))}
+	for _, rr := range append(answers, additionals...) {
+		msg = append(msg, dnsName(rr.name...)...)
+		msg = binary.BigEndian.AppendUint16(msg, rr.rtype)
+		msg = append(msg, 0x80, 0x01, 0, 0, 0x11, 0x94) // Klasse IN (Cache-Flush), TTL 4500
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(rr.data)))
+		msg = append(msg, rr.data...)
+	}
+	return msg
+}
+
+func srvData(port uint16, target ...string) []byte {
+	data := binary.BigEndian.AppendUint16([]byte{0, 0, 0, 0}, port) // Priorität, Gewicht, Port
+	return append(data, dnsName(target...)...)
+}
+
+func txtData(entries ...string) []byte {
+	var data []byte
+	for _, entry := range entries {
+		data = append(data, byte(len(entry)))
+		data = append(data, entry...)
+	}
+	return data
+}
+
+// questions liest die Fragen einer Abfrage als "label|label|...#typ"
+func questions(query []byte) []string {
+	var result []string
+	count := int(binary.BigEndian.Uint16(query[4:]))
+	pos := 12
+	for i := 0; i < count; i++ {
+		var labels []string
+		for query[pos] != 0 {
+			length := int(query[pos])
+			labels = append(labels, strings.ToLower(string(query[pos+1:pos+1+length])))
+			pos += 1 + length
+		}
+		qtype := binary.BigEndian.Uint16(query[pos+1:])
+		pos += 5
+		result = append(result, strings.Join(labels, "|")+"#"+map[uint16]string{1: "A", 12: "PTR", 16: "TXT", 33: "SRV"}[qtype])
+	}
+	return result
+}
+
+var _ = Describe("DNS-SD", func() {
+	printer := []string{"HP LaserJet M404 [1A2B]", "_ipp", "_tcp", "local"}
+	appleTV := []string{"Wohnzimmer 2.OG", "_airplay", "_tcp", "local"}
+
+	// Antworten des Test-Responders: der Drucker liefert SRV/TXT/A gleich mit
+	// (RFC 6763 12.1), das Apple TV nur auf gezielte Nachfrage und ohne A-Record
+	replies := map[string][]byte{
+		"_services|_dns-sd|_udp|local#PTR": dnsResponse([]dnsRR{
+			{name: []string{"_services", "_dns-sd", "_udp", "local"}, rtype: 12, data: dnsName("_ipp", "_tcp", "local")},
+			{name: []string{"_services", "_dns-sd", "_udp", "local"}, rtype: 12, data: dnsName("_airplay", "_tcp", "local")},
+		}, nil),
+		"_ipp|_tcp|local#PTR": dnsResponse([]dnsRR{
+			{name: []string{"_ipp", "_tcp", "local"}, rtype: 12, data: dnsName(printer...)},
+		}, []dnsRR{
+			{name: printer, rtype: 33, data: srvData(631, "drucker", "local")},
+			{name: printer, rtype: 16, data: txtData("txtvers=1", "ty=HP LaserJet Pro M404dn", "TY=ignored", "rp=ipp/print")},
+			{name: []string{"drucker", "local"}, rtype: 1, data: []byte{192, 168, 1, 50}},
+		}),
+		"_airplay|_tcp|local#PTR": dnsResponse([]dnsRR{
+			{name: []string{"_airplay", "_tcp", "local"}, rtype: 12, data: dnsName(appleTV...)},
+		}, nil),
+		"wohnzimmer 2.og|_airplay|_tcp|local#SRV": dnsResponse([]dnsRR{
+			{name: appleTV, rtype: 33, data: srvData(7000, "appletv", "local")},
+		}, nil),
+		"wohnzimmer 2.og|_airplay|_tcp|local#TXT": dnsResponse([]dnsRR{
+			{name: appleTV, rtype: 16, data: txtData("model=AppleTV6,2", "deviceid=AA:BB:CC:DD:EE:FF")},
+		}, nil),
+	}
+
+	It("should enumerate service types and resolve their instances", func() {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				n, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				// Kaputtes Paket mit Zeiger-Schleife darf die Suche nicht stören
+				_, _ = conn.WriteTo([]byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0xc0, 12}, addr)
+				for _, question := range questions(buf[:n]) {
+					if reply, ok := replies[question]; ok {
+						_, _ = conn.WriteTo(reply, addr)
+					}
+				}
+			}
+		}()
+
+		browser := discovery.DNSSDBrowser{Address: conn.LocalAddr().String(), Timeout: 600 * time.Millisecond}
+		services, err := browser.Browse(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(HaveLen(2))
+
+		// A-Record des SRV-Ziels hat Vorrang vor dem Absender
+		Expect(services["192.168.1.50"]).To(Equal([]discovery.DNSSDService{{
+			Type: "_ipp._tcp", Instance: "HP LaserJet M404 [1A2B]", Host: "drucker", Port: 631,
+			TXT: map[string]string{"txtvers": "1", "ty": "HP LaserJet Pro M404dn", "rp": "ipp/print"},
+		}}))
+		Expect(services["192.168.1.50"][0].Model()).To(Equal("HP LaserJet Pro M404dn"))
+
+		tv := services["127.0.0.1"]
+		Expect(tv).To(HaveLen(1))
+		Expect(tv[0].Instance).To(Equal("Wohnzimmer 2.OG"))
+		Expect(tv[0].Name()).To(Equal("airplay"))
+		Expect(tv[0].Host).To(Equal("appletv"))
+		Expect(tv[0].Port).To(Equal(7000))
+		Expect(tv[0].String()).To(Equal(`_airplay._tcp "Wohnzimmer 2.OG" :7000 (AppleTV6,2)`))
+	})
+
+	It("should take the instance name from the labels of a non-ASCII service type", func() {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+
+		// Kelvin-Zeichen (3 Bytes) wird kleingeschrieben zum einfachen "k"
+		reply := dnsResponse([]dnsRR{
+			{name: []string{"_\u212a", "_tcp", "local"}, rtype: 12, data: dnsName("x", "_k", "_tcp", "local")},
+		}, nil)
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				_, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				_, _ = conn.WriteTo(reply, addr)
+			}
+		}()
+
+		browser := discovery.DNSSDBrowser{Address: conn.LocalAddr().String(), Timeout: 300 * time.Millisecond}
+		services, err := browser.Browse(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services["127.0.0.1"]).To(HaveLen(1))
+		Expect(services["127.0.0.1"][0].Type).To(Equal("_\u212a._tcp"))
+		Expect(services["127.0.0.1"][0].Instance).To(Equal("x"))
+	})
+
+	It("should return no services without responders", func() {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+
+		browser := discovery.DNSSDBrowser{Address: conn.LocalAddr().String(), Timeout: 200 * time.Millisecond}
+		services, err := browser.Browse(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(BeEmpty())
+	})
+})
@@ -0,0 +1,147 @@
+//go:build linux
+
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// packetConn ist eine FrameConn auf Basis eines AF_PACKET-Sockets
+type packetConn struct {
+	file *os.File
+}
+
+// OpenFrameConn öffnet einen AF_PACKET-Socket auf dem Interface, der nur Frames
+// mit dem angegebenen EtherType empfängt. Benötigt CAP_NET_RAW.
+func OpenFrameConn(iface *net.Interface, etherType uint16) (FrameConn, error) {
+	proto := htons(etherType)
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, int(proto))
+	if err != nil {
+		if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+			return nil, fmt.Errorf("%w: %v", ErrRawSocketUnavailable, err)
+		}
+		return nil, fmt.Errorf("failed to open packet socket: %v", err)
+	}
+
+	addr := &unix.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind packet socket to %s: %v", iface.Name, err)
+	}
+
+	// Nicht-blockierender FD wird vom Go-Poller verwaltet (Deadlines funktionieren)
+	file := os.NewFile(uintptr(fd), "packet:"+iface.Name)
+	return &packetConn{file: file}, nil
+}
+
+// OpenARPConn öffnet eine FrameConn für ARP-Frames auf dem Interface
+func OpenARPConn(iface *net.Interface) (FrameConn, error) {
+	return OpenFrameConn(iface, etherTypeARP)
+}
+
+// OpenCaptureConn öffnet eine FrameConn, die alle Frames des Interfaces empfängt,
+// auch Multicast-Frames von Gruppen, denen kein lokaler Prozess beigetreten ist
+// (mDNS, SSDP, LLMNR, LLDP). Benötigt CAP_NET_RAW.
+func OpenCaptureConn(iface *net.Interface) (FrameConn, error) {
+	conn, err := OpenFrameConn(iface, unix.ETH_P_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := conn.(*packetConn).file.SyscallConn()
+	if err == nil {
+		controlErr := raw.Control(func(fd uintptr) {
+			err = unix.SetsockoptPacketMreq(int(fd), unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP,
+				&unix.PacketMreq{Ifindex: int32(iface.Index), Type: unix.PACKET_MR_ALLMULTI}) // #nosec G115 -- Interface-Index passt in int32
+		})
+		if err == nil {
+			err = controlErr
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to enable multicast reception on %s: %v", iface.Name, err)
+	}
+	return conn, nil
+}
+
+// neighborFilter lässt nur LLDP-Frames (EtherType 0x88cc) und 802.3-Frames mit
+// Längenfeld (CDP) durch, damit nicht jeder Frame in den Userspace kopiert wird
+var neighborFilter = []unix.SockFilter{
+	{Code: unix.BPF_LD | unix.BPF_H | unix.BPF_ABS, K: 12},
+	{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: etherTypeLLDP},
+	{Code: unix.BPF_JMP | unix.BPF_JGT | unix.BPF_K, Jt: 1, K: 1500},
+	{Code: unix.BPF_RET | unix.BPF_K, K: 0xffff},
+	{Code: unix.BPF_RET | unix.BPF_K, K: 0},
+}
+
+// neighborGroups sind die Multicast-Adressen von LLDP und CDP
+var neighborGroups = []net.HardwareAddr{
+	{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e},
+	{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc},
+}
+
+// OpenNeighborConn öffnet eine FrameConn, die nur LLDP- und CDP-Frames empfängt.
+// Benötigt CAP_NET_RAW.
+func OpenNeighborConn(iface *net.Interface) (FrameConn, error) {
+	conn, err := OpenFrameConn(iface, unix.ETH_P_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := conn.(*packetConn).file.SyscallConn()
+	if err == nil {
+		controlErr := raw.Control(func(fd uintptr) {
+			err = unix.SetsockoptSockFprog(int(fd), unix.SOL_SOCKET, unix.SO_ATTACH_FILTER,
+				&unix.SockFprog{Len: uint16(len(neighborFilter)), Filter: &neighborFilter[0]}) // #nosec G115 -- feste Länge
+			for _, group := range neighborGroups {
+				if err != nil {
+					return
+				}
+				mreq := &unix.PacketMreq{Ifindex: int32(iface.Index), Type: unix.PACKET_MR_MULTICAST, Alen: 6} // #nosec G115 -- Interface-Index passt in int32
+				copy(mreq.Address[:], group)
+				err = unix.SetsockoptPacketMreq(int(fd), unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, mreq)
+			}
+		})
+		if err == nil {
+			err = controlErr
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to set up LLDP/CDP reception on %s: %v", iface.Name, err)
+	}
+	return conn, nil
+}
+
+func (c *packetConn) ReadFrame(buf []byte) (int, error) {
+	return c.file.Read(buf)
+}
+
+func (c *packetConn) WriteFrame(frame []byte) error {
+	_, err := c.file.Write(frame)
+	return err
+}
+
+func (c *packetConn) SetReadDeadline(t time.Time) error {
+	return c.file.SetReadDeadline(t)
+}
+
+func (c *packetConn) Close() error {
+	return c.file.Close()
+}
+
+// htons konvertiert in Network Byte Order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
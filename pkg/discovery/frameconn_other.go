@@ -0,0 +1,29 @@
+//go:build !linux
+
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// OpenFrameConn ist nur unter Linux (AF_PACKET) verfügbar
+func OpenFrameConn(iface *net.Interface, etherType uint16) (FrameConn, error) {
+	return nil, fmt.Errorf("%w: not supported on %s", ErrRawSocketUnavailable, runtime.GOOS)
+}
+
+// OpenARPConn ist nur unter Linux (AF_PACKET) verfügbar
+func OpenARPConn(iface *net.Interface) (FrameConn, error) {
+	return OpenFrameConn(iface, etherTypeARP)
+}
+
+// OpenCaptureConn ist nur unter Linux (AF_PACKET) verfügbar
+func OpenCaptureConn(iface *net.Interface) (FrameConn, error) {
+	return OpenFrameConn(iface, 0)
+}
+
+// OpenNeighborConn ist nur unter Linux (AF_PACKET) verfügbar
+func OpenNeighborConn(iface *net.Interface) (FrameConn, error) {
+	return OpenFrameConn(iface, 0)
+}
@@ -36,9 +36,24 @@ func GetDefaultGateway() net.IP {
 	return nil
 }
 
-// IsGateway prüft ob die angegebene IP das Default-Gateway ist
+// GetDefaultGatewayIPv6 gibt die IPv6-Adresse des Default-Gateways zurück
+func GetDefaultGatewayIPv6() net.IP {
+	// macOS: route -n get -inet6 default
+	// Format: "   gateway: fe80::1%en0"
+	output, err := exec.Command("route", "-n", "get", "-inet6", "default").Output()
+	if err != nil {
+		return nil
+	}
+
+	return parseIPv6Gateway(string(output), regexp.MustCompile(`^\s*gateway:\s+([0-9a-fA-F:]+)`))
+}
+
+// IsGateway prüft ob die angegebene IP das Default-Gateway ist (IPv4 oder IPv6)
 func IsGateway(ip net.IP) bool {
 	gateway := GetDefaultGateway()
+	if ip.To4() == nil {
+		gateway = GetDefaultGatewayIPv6()
+	}
 	if gateway == nil {
 		return false
 	}
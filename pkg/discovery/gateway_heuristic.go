@@ -2,6 +2,8 @@ package discovery
 
 import (
 	"net"
+	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -67,10 +69,10 @@ func isCommonGatewayIP(ip net.IP, network *net.IPNet) bool {
 		return false
 	}
 
-	// Hole den Host-Teil der IP
+	// IPv6: Router haben üblicherweise die erste Adresse des Präfixes (z.B. 2001:db8::1)
 	ip4 := ip.To4()
 	if ip4 == nil {
-		return false // Nur IPv4 unterstützt
+		return ip.Equal(getFirstUsableIP(network))
 	}
 
 	networkIP := network.IP.To4()
@@ -147,3 +149,17 @@ func ClearGatewayCache() {
 	defer gatewayCacheMutex.Unlock()
 	gatewayCache = make(map[string]net.IP)
 }
+
+// parseIPv6Gateway sucht in der Ausgabe eines Routing-Befehls die erste IPv6-Gateway-Adresse.
+// Zonen-Angaben (z.B. "%en0") werden vom Regex nicht erfasst.
+func parseIPv6Gateway(output string, gatewayRegex *regexp.Regexp) net.IP {
+	for _, line := range strings.Split(output, "\n") {
+		matches := gatewayRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if len(matches) > 1 {
+			if gateway := net.ParseIP(matches[1]); gateway != nil && gateway.To4() == nil {
+				return gateway
+			}
+		}
+	}
+	return nil
+}
@@ -56,9 +56,24 @@ func GetDefaultGateway() net.IP {
 	return nil
 }
 
-// IsGateway prüft ob die angegebene IP das Default-Gateway ist
+// GetDefaultGatewayIPv6 gibt die IPv6-Adresse des Default-Gateways zurück
+func GetDefaultGatewayIPv6() net.IP {
+	// Linux: ip -6 route show default
+	// Format: "default via fe80::1 dev eth0 proto ra metric 1024"
+	output, err := exec.Command("ip", "-6", "route", "show", "default").Output()
+	if err != nil {
+		return nil
+	}
+
+	return parseIPv6Gateway(string(output), regexp.MustCompile(`^default\s+via\s+([0-9a-fA-F:]+)`))
+}
+
+// IsGateway prüft ob die angegebene IP das Default-Gateway ist (IPv4 oder IPv6)
 func IsGateway(ip net.IP) bool {
 	gateway := GetDefaultGateway()
+	if ip.To4() == nil {
+		gateway = GetDefaultGatewayIPv6()
+	}
 	if gateway == nil {
 		return false
 	}
@@ -36,9 +36,24 @@ func GetDefaultGateway() net.IP {
 	return nil
 }
 
-// IsGateway prüft ob die angegebene IP das Default-Gateway ist
+// GetDefaultGatewayIPv6 gibt die IPv6-Adresse des Default-Gateways zurück
+func GetDefaultGatewayIPv6() net.IP {
+	// Windows: route print -6 ::/0
+	// Format: " 12    266 ::/0                     fe80::1"
+	output, err := exec.Command("route", "print", "-6", "::/0").Output()
+	if err != nil {
+		return nil
+	}
+
+	return parseIPv6Gateway(string(output), regexp.MustCompile(`::/0\s+([0-9a-fA-F:]+)`))
+}
+
+// IsGateway prüft ob die angegebene IP das Default-Gateway ist (IPv4 oder IPv6)
 func IsGateway(ip net.IP) bool {
 	gateway := GetDefaultGateway()
+	if ip.To4() == nil {
+		gateway = GetDefaultGatewayIPv6()
+	}
 	if gateway == nil {
 		return false
 	}
@@ -144,6 +144,38 @@ func ResolveBackground(ip net.IP, timeout time.Duration) HostnameResult {
 	}
 }
 
+// ResolveVia löst einen Hostnamen mit genau einer Methode auf
+// Methoden: "dns", "mdns", "netbios", "llmnr", "http"
+func ResolveVia(ip net.IP, method string, timeout time.Duration) HostnameResult {
+	var name string
+	var err error
+
+	switch method {
+	case "dns":
+		var names []string
+		names, err = net.LookupAddr(ip.String())
+		if err == nil && len(names) > 0 {
+			name = names[0]
+		}
+	case "mdns":
+		name, err = QueryMDNSName(ip, timeout)
+	case "netbios":
+		name, err = QueryNetBIOSName(ip, timeout)
+	case "llmnr":
+		name, err = QueryLLMNRDirect(ip, timeout)
+	case "http":
+		name, err = QueryHTTPHostname(ip.String(), timeout)
+	default:
+		return HostnameResult{}
+	}
+
+	name = cleanHostname(name)
+	if err != nil || name == "" {
+		return HostnameResult{}
+	}
+	return HostnameResult{Hostname: name, Source: method}
+}
+
 // cleanHostname removes unwanted suffixes and formats the hostname
 func cleanHostname(hostname string) string {
 	if hostname == "" {
@@ -0,0 +1,382 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// Betriebsarten der ICMP-Engine
+const (
+	ICMPModeDatagram = "datagram" // Unprivilegierter ICMP-Socket (Linux ping_group_range, macOS)
+	ICMPModeRaw      = "raw"      // Raw-Socket (root / CAP_NET_RAW / Windows-Admin)
+	ICMPModeSystem   = "system"   // Fallback: System-ping-Befehl
+)
+
+const icmpProtocolIPv4 = 1
+
+// ICMPResult ist das Ergebnis eines oder mehrerer Echo-Requests an einen Host
+type ICMPResult struct {
+	IP       net.IP
+	Sent     int
+	Received int
+	RTT      time.Duration // Durchschnittliche RTT aller Antworten
+	MinRTT   time.Duration
+	MaxRTT   time.Duration
+	TTL      int // TTL der letzten Antwort (0 = unbekannt)
+}
+
+// Alive gibt true zurück, wenn mindestens ein Echo-Reply empfangen wurde
+func (r ICMPResult) Alive() bool {
+	return r.Received > 0
+}
+
+// Loss gibt den Paketverlust als Anteil zwischen 0 und 1 zurück
+func (r ICMPResult) Loss() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Sent-r.Received) / float64(r.Sent)
+}
+
+// echoReply wird von der Lese-Goroutine an einen wartenden Ping-Aufruf zugestellt
+type echoReply struct {
+	received time.Time
+	ttl      int
+}
+
+// pendingEcho ist ein ausstehender Echo-Request
+type pendingEcho struct {
+	ip    string
+	reply chan echoReply
+}
+
+// ICMPEngine bündelt viele gleichzeitige Echo-Requests über einen einzigen Socket.
+// Antworten werden über Sequenznummer (bei Raw-Sockets zusätzlich die ID) und Absender zugeordnet.
+type ICMPEngine struct {
+	conn *icmp.PacketConn
+	mode string
+	id   int
+
+	mu      sync.Mutex
+	seq     uint16
+	pending map[uint16]*pendingEcho
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var (
+	sharedICMPEngine     *ICMPEngine
+	sharedICMPEngineOnce sync.Once
+)
+
+// SharedICMPEngine gibt die prozessweite ICMP-Engine zurück.
+// Sie wird beim ersten Aufruf erzeugt; lässt sich kein ICMP-Socket öffnen,
+// wird auf den System-ping-Befehl ausgewichen.
+func SharedICMPEngine() *ICMPEngine {
+	sharedICMPEngineOnce.Do(func() {
+		engine, err := NewICMPEngine()
+		if err != nil {
+			engine = &ICMPEngine{mode: ICMPModeSystem, done: make(chan struct{})}
+		}
+		sharedICMPEngine = engine
+	})
+	return sharedICMPEngine
+}
+
+// NewICMPEngine öffnet einen Datagram-ICMP-Socket, ersatzweise einen Raw-Socket
+func NewICMPEngine() (*ICMPEngine, error) {
+	mode := ICMPModeDatagram
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		mode = ICMPModeRaw
+		var rawErr error
+		conn, rawErr = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+		if rawErr != nil {
+			return nil, fmt.Errorf("failed to open ICMP socket (datagram: %v, raw: %v)", err, rawErr)
+		}
+	}
+
+	// Die TTL der Antworten kommt über Control Messages (nicht überall unterstützt)
+	_ = conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+
+	e := &ICMPEngine{
+		conn:    conn,
+		mode:    mode,
+		id:      os.Getpid() & 0xffff,
+		pending: make(map[uint16]*pendingEcho),
+		done:    make(chan struct{}),
+	}
+	go e.readLoop()
+
+	return e, nil
+}
+
+// Mode gibt zurück, wie die Engine Echos sendet (datagram, raw oder system)
+func (e *ICMPEngine) Mode() string {
+	return e.mode
+}
+
+// Close schließt den Socket der Engine
+func (e *ICMPEngine) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		close(e.done)
+		if e.conn != nil {
+			err = e.conn.Close()
+		}
+	})
+	return err
+}
+
+// Ping sendet einen einzelnen Echo-Request und wartet auf die Antwort.
+// Liefert RTT und TTL der Antwort oder bei Timeout einen Fehler.
+func (e *ICMPEngine) Ping(ctx context.Context, ip net.IP, timeout time.Duration) (time.Duration, int, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0, 0, fmt.Errorf("ICMP engine supports IPv4 only: %s", ip)
+	}
+
+	if e.mode == ICMPModeSystem {
+		ok, rtt := systemPing(ip4.String(), timeout)
+		if !ok {
+			return 0, 0, fmt.Errorf("no reply from %s", ip4)
+		}
+		return rtt, 0, nil
+	}
+
+	seq, pending := e.register(ip4.String())
+	defer e.unregister(seq)
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   e.id,
+			Seq:  int(seq),
+			Data: e.payload(),
+		},
+	}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: ip4}
+	if e.mode == ICMPModeDatagram {
+		dst = &net.UDPAddr{IP: ip4}
+	}
+
+	sent := time.Now()
+	if _, err := e.conn.WriteTo(packet, dst); err != nil {
+		return 0, 0, fmt.Errorf("failed to send echo to %s: %v", ip4, err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case reply := <-pending.reply:
+		return reply.received.Sub(sent), reply.ttl, nil
+	case <-timer.C:
+		return 0, 0, fmt.Errorf("timeout waiting for echo reply from %s", ip4)
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	case <-e.done:
+		return 0, 0, errors.New("ICMP engine closed")
+	}
+}
+
+// PingHost sendet count Echo-Requests (im Abstand interval) und fasst RTT, TTL und Verlust zusammen
+func (e *ICMPEngine) PingHost(ctx context.Context, ip net.IP, count int, interval, timeout time.Duration) ICMPResult {
+	if count < 1 {
+		count = 1
+	}
+
+	result := ICMPResult{IP: ip, Sent: count}
+
+	type probe struct {
+		rtt time.Duration
+		ttl int
+		err error
+	}
+	results := make(chan probe, count)
+
+	var wg sync.WaitGroup
+sendLoop:
+	for i := 0; i < count; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				result.Sent = i
+				break sendLoop
+			case <-time.After(interval):
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rtt, ttl, err := e.Ping(ctx, ip, timeout)
+			results <- probe{rtt: rtt, ttl: ttl, err: err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var total time.Duration
+	for p := range results {
+		if p.err != nil {
+			continue
+		}
+		result.Received++
+		total += p.rtt
+		if result.MinRTT == 0 || p.rtt < result.MinRTT {
+			result.MinRTT = p.rtt
+		}
+		if p.rtt > result.MaxRTT {
+			result.MaxRTT = p.rtt
+		}
+		if p.ttl > 0 {
+			result.TTL = p.ttl
+		}
+	}
+	if result.Received > 0 {
+		result.RTT = total / time.Duration(result.Received)
+	}
+
+	return result
+}
+
+// register vergibt eine freie Sequenznummer für einen ausstehenden Echo-Request
+func (e *ICMPEngine) register(ip string) (uint16, *pendingEcho) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for {
+		e.seq++
+		if _, inUse := e.pending[e.seq]; !inUse {
+			break
+		}
+	}
+
+	p := &pendingEcho{ip: ip, reply: make(chan echoReply, 1)}
+	e.pending[e.seq] = p
+	return e.seq, p
+}
+
+// unregister entfernt einen ausstehenden Echo-Request
+func (e *ICMPEngine) unregister(seq uint16) {
+	e.mu.Lock()
+	delete(e.pending, seq)
+	e.mu.Unlock()
+}
+
+// payload gibt die Nutzdaten zurück (Zeitstempel, wie bei ping auf 56 Bytes aufgefüllt)
+func (e *ICMPEngine) payload() []byte {
+	data := make([]byte, 56)
+	binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+	return data
+}
+
+// readLoop empfängt Echo-Replies und verteilt sie an die wartenden Ping-Aufrufe
+func (e *ICMPEngine) readLoop() {
+	buf := make([]byte, 1500)
+	pc := e.conn.IPv4PacketConn()
+
+	for {
+		n, cm, peer, err := pc.ReadFrom(buf)
+		received := time.Now()
+		if err != nil {
+			select {
+			case <-e.done:
+				return
+			default:
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		data := buf[:n]
+		ttl := 0
+		if cm != nil {
+			ttl = cm.TTL
+		}
+
+		// Raw-Sockets liefern unter Umständen den IPv4-Header mit
+		if len(data) >= 20 && data[0]>>4 == 4 {
+			headerLen := int(data[0]&0x0f) * 4
+			if len(data) < headerLen {
+				continue
+			}
+			if ttl == 0 {
+				ttl = int(data[8])
+			}
+			data = data[headerLen:]
+		}
+
+		msg, err := icmp.ParseMessage(icmpProtocolIPv4, data)
+		if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok {
+			continue
+		}
+
+		// Datagram-Sockets ersetzen die ID (der Kernel nutzt den Socket-Port), Raw-Sockets sehen jede Antwort
+		if e.mode == ICMPModeRaw && echo.ID != e.id {
+			continue
+		}
+
+		e.dispatch(uint16(echo.Seq), peerIP(peer), echoReply{received: received, ttl: ttl})
+	}
+}
+
+// dispatch stellt eine Antwort dem passenden ausstehenden Echo-Request zu
+func (e *ICMPEngine) dispatch(seq uint16, from string, reply echoReply) {
+	e.mu.Lock()
+	p, ok := e.pending[seq]
+	e.mu.Unlock()
+
+	if !ok || p.ip != from {
+		return
+	}
+
+	select {
+	case p.reply <- reply:
+	default:
+	}
+}
+
+// peerIP gibt die IP-Adresse des Absenders als String zurück
+func peerIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.To4().String()
+	case *net.IPAddr:
+		return a.IP.To4().String()
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return addr.String()
+		}
+		return host
+	}
+}
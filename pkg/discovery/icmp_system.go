@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// systemPing sendet einen ICMP-Ping über den System-ping-Befehl.
+// Wird von der ICMP-Engine nur genutzt, wenn sich kein ICMP-Socket öffnen lässt
+// (z.B. Windows ohne Admin-Rechte). Die RTT enthält den Prozessstart.
+func systemPing(ip string, timeout time.Duration) (bool, time.Duration) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		// Windows: -n Anzahl, -w Timeout in Millisekunden
+		timeoutMs := int(timeout.Milliseconds())
+		if timeoutMs < 1 {
+			timeoutMs = 1
+		}
+		cmd = exec.Command("ping", "-n", "1", "-w", fmt.Sprintf("%d", timeoutMs), ip)
+	case "darwin":
+		// macOS: -c Anzahl, -W Timeout in Millisekunden
+		timeoutMs := int(timeout.Milliseconds())
+		if timeoutMs < 1 {
+			timeoutMs = 1
+		}
+		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutMs), ip)
+	default:
+		// Linux: -c Anzahl, -W Timeout in Sekunden (mindestens 1)
+		timeoutSec := int(timeout.Seconds())
+		if timeoutSec < 1 {
+			timeoutSec = 1
+		}
+		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutSec), ip)
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	rtt := time.Since(start)
+
+	if err != nil {
+		return false, 0
+	}
+	return true, rtt
+}
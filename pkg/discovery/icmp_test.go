@@ -0,0 +1,101 @@
+package discovery_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+var _ = Describe("ICMP Engine", func() {
+	var engine *discovery.ICMPEngine
+
+	BeforeEach(func() {
+		var err error
+		engine, err = discovery.NewICMPEngine()
+		if err != nil {
+			Skip("ICMP sockets not available: " + err.Error())
+		}
+	})
+
+	AfterEach(func() {
+		if engine != nil {
+			_ = engine.Close()
+		}
+	})
+
+	It("should report a socket-based mode", func() {
+		Expect(engine.Mode()).To(BeElementOf(discovery.ICMPModeDatagram, discovery.ICMPModeRaw))
+	})
+
+	It("should ping localhost with real RTT and TTL", func() {
+		rtt, ttl, err := engine.Ping(context.Background(), net.ParseIP("127.0.0.1"), time.Second)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rtt).To(BeNumerically(">", 0))
+		Expect(rtt).To(BeNumerically("<", 100*time.Millisecond))
+		Expect(ttl).To(BeNumerically(">=", 0))
+	})
+
+	It("should multiplex many outstanding echoes over one socket", func() {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		successes := 0
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := engine.Ping(context.Background(), net.ParseIP("127.0.0.1"), 2*time.Second)
+				if err == nil {
+					mu.Lock()
+					successes++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(successes).To(Equal(50))
+	})
+
+	It("should aggregate loss over several echoes", func() {
+		result := engine.PingHost(context.Background(), net.ParseIP("127.0.0.1"), 3, 10*time.Millisecond, time.Second)
+
+		Expect(result.Sent).To(Equal(3))
+		Expect(result.Received).To(Equal(3))
+		Expect(result.Alive()).To(BeTrue())
+		Expect(result.Loss()).To(BeZero())
+		Expect(result.MinRTT).To(BeNumerically("<=", result.RTT))
+		Expect(result.MaxRTT).To(BeNumerically(">=", result.RTT))
+	})
+
+	It("should time out for unreachable hosts", func() {
+		// TEST-NET-2 (RFC 5737) - sollte nie antworten
+		result := engine.PingHost(context.Background(), net.ParseIP("198.51.100.77"), 2, 0, 200*time.Millisecond)
+
+		Expect(result.Alive()).To(BeFalse())
+		Expect(result.Loss()).To(Equal(1.0))
+	})
+
+	It("should reject IPv6 targets", func() {
+		_, _, err := engine.Ping(context.Background(), net.ParseIP("::1"), time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return early when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		_, _, err := engine.Ping(ctx, net.ParseIP("198.51.100.77"), 5*time.Second)
+
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+})
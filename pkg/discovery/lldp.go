@@ -0,0 +1,256 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	etherTypeLLDP = 0x88cc
+
+	// CDP wird als 802.3-Frame mit SNAP-Header (OUI Cisco, Protokoll 0x2000) verschickt
+	cdpSNAPHeader = "\xaa\xaa\x03\x00\x00\x0c\x20\x00"
+)
+
+var errNeighborFormat = errors.New("malformed LLDP/CDP frame")
+
+// Neighbor beschreibt ein Gerät, das sich per LLDP oder CDP ankündigt (meist ein
+// Switch, aber auch Access Points, Router und IP-Telefone)
+type Neighbor struct {
+	Protocol          string   `json:"protocol"`             // "lldp" oder "cdp"
+	ChassisID         string   `json:"chassis_id,omitempty"` // MAC-Adresse oder Name des Geräts
+	PortID            string   `json:"port_id,omitempty"`    // Port, aus dem der Frame gesendet wurde ("Gi1/0/12")
+	PortDescription   string   `json:"port_description,omitempty"`
+	SystemName        string   `json:"system_name,omitempty"`
+	SystemDescription string   `json:"system_description,omitempty"`
+	ManagementIP      net.IP   `json:"management_ip,omitempty"`
+	VLAN              int      `json:"vlan,omitempty"`         // Port-VLAN (PVID bzw. Native VLAN)
+	Capabilities      []string `json:"capabilities,omitempty"` // "bridge", "router", "wlan-ap", "phone", ...
+}
+
+// Port gibt den Port lesbar zurück ("Gi1/0/12 (Büro 2.13)")
+func (n Neighbor) Port() string {
+	if n.PortDescription != "" && n.PortDescription != n.PortID {
+		return n.PortID + " (" + n.PortDescription + ")"
+	}
+	return n.PortID
+}
+
+// HasCapability prüft, ob das Gerät eine Fähigkeit meldet ("bridge", "router", ...)
+func (n Neighbor) HasCapability(capability string) bool {
+	for _, c := range n.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// String gibt den Nachbarn kompakt aus: "core-sw1 Gi1/0/12 VLAN 20 (10.0.0.2)"
+func (n Neighbor) String() string {
+	name := n.SystemName
+	if name == "" {
+		name = n.ChassisID
+	}
+	parts := []string{name}
+	if port := n.Port(); port != "" {
+		parts = append(parts, port)
+	}
+	if n.VLAN > 0 {
+		parts = append(parts, fmt.Sprintf("VLAN %d", n.VLAN))
+	}
+	if n.ManagementIP != nil {
+		parts = append(parts, "("+n.ManagementIP.String()+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// decodeNeighbor liest LLDP (EtherType 0x88cc) bzw. CDP (802.3 mit Längenfeld)
+func decodeNeighbor(etherType uint16, payload []byte) *Neighbor {
+	var neighbor *Neighbor
+	var err error
+	switch {
+	case etherType == etherTypeLLDP:
+		neighbor, err = ParseLLDP(payload)
+	case etherType <= 1500 && int(etherType) <= len(payload):
+		neighbor, err = ParseCDP(payload[:etherType])
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return neighbor
+}
+
+// lldpCapabilities sind die Fähigkeiten in Bit-Reihenfolge (IEEE 802.1AB, 8.5.8)
+var lldpCapabilities = []string{"other", "repeater", "bridge", "wlan-ap", "router", "phone", "docsis", "station"}
+
+// ParseLLDP liest die TLVs eines LLDP-Frames (Nutzdaten nach dem Ethernet-Header)
+func ParseLLDP(payload []byte) (*Neighbor, error) {
+	neighbor := &Neighbor{Protocol: "lldp"}
+	for pos := 0; pos+2 <= len(payload); {
+		header := binary.BigEndian.Uint16(payload[pos:])
+		tlvType, length := int(header>>9), int(header&0x01ff)
+		pos += 2
+		if pos+length > len(payload) {
+			return nil, errNeighborFormat
+		}
+		value := payload[pos : pos+length]
+		pos += length
+
+		switch tlvType {
+		case 0: // End of LLDPDU
+			pos = len(payload)
+		case 1: // Chassis ID
+			neighbor.ChassisID = lldpID(value, 4, 5)
+		case 2: // Port ID
+			neighbor.PortID = lldpID(value, 3, 4)
+		case 4:
+			neighbor.PortDescription = cleanNeighborString(value)
+		case 5:
+			neighbor.SystemName = cleanNeighborString(value)
+		case 6:
+			neighbor.SystemDescription = cleanNeighborString(value)
+		case 7: // Fähigkeiten (verfügbar, aktiviert)
+			if len(value) == 4 {
+				neighbor.Capabilities = capabilityNames(binary.BigEndian.Uint16(value[2:]), lldpCapabilities)
+			}
+		case 8: // Management-Adresse: Länge, Subtyp (1 = IPv4), Adresse
+			if len(value) >= 6 && value[0] == 5 && value[1] == 1 && neighbor.ManagementIP == nil {
+				neighbor.ManagementIP = net.IP(append([]byte(nil), value[2:6]...))
+			}
+		case 127: // Organisationsspezifisch: IEEE 802.1 Port VLAN ID
+			if len(value) >= 6 && value[0] == 0x00 && value[1] == 0x80 && value[2] == 0xc2 && value[3] == 1 {
+				neighbor.VLAN = int(binary.BigEndian.Uint16(value[4:]))
+			}
+		}
+	}
+
+	if neighbor.ChassisID == "" && neighbor.SystemName == "" {
+		return nil, errNeighborFormat
+	}
+	return neighbor, nil
+}
+
+// lldpID liest Chassis- bzw. Port-ID: MAC-Adressen (Subtyp macSubtype) und
+// IPv4-Adressen (Subtyp addrSubtype) werden lesbar formatiert, alle übrigen als Text
+func lldpID(value []byte, macSubtype, addrSubtype byte) string {
+	if len(value) < 2 {
+		return ""
+	}
+	subtype, id := value[0], value[1:]
+	switch {
+	case subtype == macSubtype && len(id) == 6:
+		return net.HardwareAddr(id).String()
+	case subtype == addrSubtype && len(id) == 5 && id[0] == 1:
+		return net.IP(id[1:]).String()
+	}
+	return cleanNeighborString(id)
+}
+
+// cdpCapabilities sind die CDP-Fähigkeiten in Bit-Reihenfolge
+var cdpCapabilities = []string{"router", "bridge", "bridge", "bridge", "station", "igmp", "repeater", "phone", "remote", "cvta", "two-port-mac-relay"}
+
+// ParseCDP liest einen CDP-Frame (LLC/SNAP-Nutzdaten nach dem 802.3-Header)
+func ParseCDP(payload []byte) (*Neighbor, error) {
+	if len(payload) < len(cdpSNAPHeader)+4 || string(payload[:len(cdpSNAPHeader)]) != cdpSNAPHeader {
+		return nil, errNeighborFormat
+	}
+	payload = payload[len(cdpSNAPHeader)+4:] // Version, TTL, Prüfsumme
+
+	neighbor := &Neighbor{Protocol: "cdp"}
+	var platform, version string
+	for pos := 0; pos+4 <= len(payload); {
+		tlvType := binary.BigEndian.Uint16(payload[pos:])
+		length := int(binary.BigEndian.Uint16(payload[pos+2:]))
+		if length < 4 || pos+length > len(payload) {
+			return nil, errNeighborFormat
+		}
+		value := payload[pos+4 : pos+length]
+		pos += length
+
+		switch tlvType {
+		case 0x0001: // Device ID
+			neighbor.ChassisID = cleanNeighborString(value)
+			neighbor.SystemName = neighbor.ChassisID
+		case 0x0002, 0x0016: // Adressen bzw. Management-Adressen
+			if ip := cdpAddress(value); ip != nil && (neighbor.ManagementIP == nil || tlvType == 0x0016) {
+				neighbor.ManagementIP = ip
+			}
+		case 0x0003:
+			neighbor.PortID = cleanNeighborString(value)
+		case 0x0004:
+			if len(value) == 4 {
+				neighbor.Capabilities = capabilityNames(uint16(binary.BigEndian.Uint32(value)), cdpCapabilities) // #nosec G115 -- nur die unteren Bits sind belegt
+			}
+		case 0x0005:
+			version, _, _ = strings.Cut(cleanNeighborString(value), "\n")
+		case 0x0006:
+			platform = cleanNeighborString(value)
+		case 0x000a: // Native VLAN
+			if len(value) == 2 {
+				neighbor.VLAN = int(binary.BigEndian.Uint16(value))
+			}
+		}
+	}
+
+	neighbor.SystemDescription = strings.TrimSpace(platform + " " + strings.TrimSpace(version))
+	if neighbor.ChassisID == "" {
+		return nil, errNeighborFormat
+	}
+	return neighbor, nil
+}
+
+// cdpAddress liest die erste IPv4-Adresse einer CDP-Adressliste
+func cdpAddress(value []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(value))
+	pos := 4
+	for i := 0; i < count && pos+2 <= len(value); i++ {
+		protoLen := int(value[pos+1])
+		if pos+2+protoLen+2 > len(value) {
+			return nil
+		}
+		proto := value[pos+2 : pos+2+protoLen]
+		addrLen := int(binary.BigEndian.Uint16(value[pos+2+protoLen:]))
+		addr := pos + 4 + protoLen
+		if addr+addrLen > len(value) {
+			return nil
+		}
+		// NLPID 0xCC = IP
+		if value[pos] == 1 && protoLen == 1 && proto[0] == 0xcc && addrLen == 4 {
+			return net.IP(append([]byte(nil), value[addr:addr+4]...))
+		}
+		pos = addr + addrLen
+	}
+	return nil
+}
+
+// capabilityNames übersetzt ein Fähigkeiten-Bitfeld in Namen (ohne Duplikate)
+func capabilityNames(bits uint16, names []string) []string {
+	var result []string
+	for i, name := range names {
+		if bits&(1<<i) == 0 {
+			continue
+		}
+		duplicate := false
+		for _, existing := range result {
+			duplicate = duplicate || existing == name
+		}
+		if !duplicate {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// cleanNeighborString entfernt Nullbytes und überflüssige Leerzeichen
+func cleanNeighborString(value []byte) string {
+	return strings.TrimSpace(strings.Trim(string(value), "\x00"))
+}
@@ -0,0 +1,507 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+const icmpProtocolIPv6 = 58
+
+// IPv6-Adresstypen (siehe ClassifyIPv6)
+const (
+	IPv6LinkLocal = "link-local" // fe80::/10
+	IPv6SLAAC     = "slaac"      // Interface-ID aus der MAC abgeleitet (EUI-64)
+	IPv6Privacy   = "privacy"    // Zufällige Interface-ID (RFC 4941 / RFC 7217)
+	IPv6Static    = "static"     // Kleine Interface-ID, typisch manuell oder DHCPv6 (z.B. ::1)
+)
+
+// allNodesMulticast ist die Link-lokale Multicast-Adresse aller IPv6-Knoten
+var allNodesMulticast = net.ParseIP("ff02::1")
+
+// NDPEntry repräsentiert einen Eintrag der IPv6-Neighbor-Tabelle
+type NDPEntry struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	Interface string
+	Router    bool // Nachbar hat sich als Router angekündigt
+	RTT       time.Duration
+}
+
+// ReadNDPTable liest die IPv6-Neighbor-Tabelle des Systems
+// (ip -6 neigh / ndp -an / netsh interface ipv6 show neighbors)
+func ReadNDPTable() ([]NDPEntry, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("ip", "-6", "neigh", "show")
+	case "darwin":
+		cmd = exec.Command("ndp", "-an")
+	case "windows":
+		cmd = exec.Command("netsh", "interface", "ipv6", "show", "neighbors")
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read neighbor table: %v", err)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		return parseLinuxNDPOutput(string(output)), nil
+	case "darwin":
+		return parseMacNDPOutput(string(output)), nil
+	default:
+		return parseWindowsNDPOutput(string(output)), nil
+	}
+}
+
+// parseLinuxNDPOutput parst die Ausgabe von "ip -6 neigh show"
+// Format: fe80::1 dev eth0 lladdr 52:54:00:12:34:56 router REACHABLE
+func parseLinuxNDPOutput(output string) []NDPEntry {
+	var entries []NDPEntry
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil || ip.To4() != nil {
+			continue
+		}
+
+		entry := NDPEntry{IP: ip}
+		for i := 1; i < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				if i+1 < len(fields) {
+					entry.Interface = fields[i+1]
+				}
+			case "lladdr":
+				if i+1 < len(fields) {
+					entry.MAC, _ = net.ParseMAC(fields[i+1])
+				}
+			case "router":
+				entry.Router = true
+			}
+		}
+
+		state := fields[len(fields)-1]
+		if state == "FAILED" || state == "INCOMPLETE" {
+			continue
+		}
+
+		if isUsableNeighbor(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// parseMacNDPOutput parst die Ausgabe von "ndp -an"
+// Format: fe80::1%en0  0:11:22:33:44:55  en0 23h59m58s S R
+func parseMacNDPOutput(output string) []NDPEntry {
+	var entries []NDPEntry
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.Contains(line, "incomplete") {
+			continue
+		}
+
+		addr, _, _ := strings.Cut(fields[0], "%")
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() != nil {
+			continue
+		}
+
+		mac, err := net.ParseMAC(normalizeMacAddress(fields[1]))
+		if err != nil {
+			continue
+		}
+
+		entry := NDPEntry{IP: ip, MAC: mac, Interface: fields[2]}
+
+		// Spalten: Neighbor, Linklayer, Netif, Expire, St, Flgs - "R" in Flgs markiert Router
+		for _, field := range fields[min(5, len(fields)):] {
+			if field == "R" {
+				entry.Router = true
+			}
+		}
+
+		if isUsableNeighbor(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// parseWindowsNDPOutput parst die Ausgabe von "netsh interface ipv6 show neighbors"
+// Format:
+//
+//	Interface 12: Ethernet
+//	fe80::1                                       00-11-22-33-44-55  Reachable (Router)
+func parseWindowsNDPOutput(output string) []NDPEntry {
+	var entries []NDPEntry
+	interfaceRegex := regexp.MustCompile(`^Interface\s+\d+:\s+(.+)$`)
+	currentInterface := ""
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if matches := interfaceRegex.FindStringSubmatch(line); matches != nil {
+			currentInterface = strings.TrimSpace(matches[1])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil || ip.To4() != nil {
+			continue
+		}
+
+		mac, err := net.ParseMAC(strings.ReplaceAll(fields[1], "-", ":"))
+		if err != nil {
+			continue
+		}
+
+		state := strings.Join(fields[2:], " ")
+		if strings.HasPrefix(state, "Unreachable") || strings.HasPrefix(state, "Incomplete") {
+			continue
+		}
+
+		entry := NDPEntry{
+			IP:        ip,
+			MAC:       mac,
+			Interface: currentInterface,
+			Router:    strings.Contains(state, "(Router)"),
+		}
+		if isUsableNeighbor(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// isUsableNeighbor filtert Multicast-Adressen, Multicast-MACs und leere MACs
+func isUsableNeighbor(entry NDPEntry) bool {
+	if len(entry.MAC) != 6 || entry.MAC[0]&0x01 != 0 {
+		return false
+	}
+	if entry.IP.IsMulticast() || entry.IP.IsUnspecified() || entry.IP.IsLoopback() {
+		return false
+	}
+	// 00:00:00:00:00:00 (z.B. Windows "Permanent"-Einträge)
+	for _, b := range entry.MAC {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SolicitedNodeMulticast gibt die Solicited-Node-Multicast-Adresse (ff02::1:ffXX:XXXX)
+// einer IPv6-Adresse zurück. Nur der Knoten mit dieser Adresse (und wenige andere) empfängt sie.
+func SolicitedNodeMulticast(ip net.IP) net.IP {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil
+	}
+	addr := net.ParseIP("ff02::1:ff00:0")
+	copy(addr[13:], ip16[13:])
+	return addr
+}
+
+// LinkLocalFromMAC bildet die EUI-64-basierte Link-Local-Adresse einer MAC (fe80::/64)
+func LinkLocalFromMAC(mac net.HardwareAddr) net.IP {
+	if len(mac) != 6 {
+		return nil
+	}
+	ip := make(net.IP, net.IPv6len)
+	ip[0], ip[1] = 0xfe, 0x80
+	copy(ip[8:], eui64InterfaceID(mac))
+	return ip
+}
+
+// eui64InterfaceID bildet die modifizierte EUI-64 Interface-ID (U/L-Bit invertiert, ff:fe eingefügt)
+func eui64InterfaceID(mac net.HardwareAddr) []byte {
+	return []byte{mac[0] ^ 0x02, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+}
+
+// ClassifyIPv6 ordnet eine IPv6-Adresse eines Geräts einem Adresstyp zu.
+// mac darf nil sein - SLAAC (EUI-64) wird dann nicht erkannt.
+func ClassifyIPv6(ip net.IP, mac net.HardwareAddr) string {
+	ip16 := ip.To16()
+	if ip16 == nil || ip.To4() != nil {
+		return ""
+	}
+
+	if len(mac) == 6 && string(ip16[8:]) == string(eui64InterfaceID(mac)) {
+		if ip.IsLinkLocalUnicast() {
+			return IPv6LinkLocal
+		}
+		return IPv6SLAAC
+	}
+	if ip.IsLinkLocalUnicast() {
+		return IPv6LinkLocal
+	}
+
+	// Interface-IDs wie ::1 oder ::fe sind manuell vergeben (oder DHCPv6)
+	if ip16[8] == 0 && ip16[9] == 0 && ip16[10] == 0 && ip16[11] == 0 && ip16[12] == 0 && ip16[13] == 0 {
+		return IPv6Static
+	}
+
+	return IPv6Privacy
+}
+
+// EchoIPv6 sendet je einen ICMPv6 Echo-Request an alle Ziele (Unicast oder Multicast)
+// und sammelt bis zum Timeout alle Antworten. Liefert die RTT pro antwortender Adresse.
+// Für Link-Local- und Multicast-Ziele wird das angegebene Interface verwendet.
+func EchoIPv6(ctx context.Context, iface *net.Interface, targets []net.IP, timeout time.Duration) (map[string]time.Duration, error) {
+	raw := false
+	conn, err := icmp.ListenPacket("udp6", "::")
+	if err != nil {
+		var rawErr error
+		conn, rawErr = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+		if rawErr != nil {
+			return nil, fmt.Errorf("failed to open ICMPv6 socket (datagram: %v, raw: %v)", err, rawErr)
+		}
+		raw = true
+	}
+	defer func() { _ = conn.Close() }()
+
+	pc := conn.IPv6PacketConn()
+	if iface != nil {
+		_ = pc.SetMulticastInterface(iface)
+	}
+	if raw {
+		// Nur Echo-Replies zustellen lassen
+		var filter ipv6.ICMPFilter
+		filter.SetAll(true)
+		filter.Accept(ipv6.ICMPTypeEchoReply)
+		_ = pc.SetICMPFilter(&filter)
+	}
+
+	// Abbruch über ctx schließt den Socket und beendet das Lesen
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	id := os.Getpid() & 0xffff
+	seq := rand.Intn(0xffff)
+	sent := make(map[int]time.Time, len(targets))
+
+	for i, target := range targets {
+		msg := icmp.Message{
+			Type: ipv6.ICMPTypeEchoRequest,
+			Body: &icmp.Echo{ID: id, Seq: (seq + i) & 0xffff, Data: []byte("netspy")},
+		}
+		packet, err := msg.Marshal(nil) // Prüfsumme berechnet der Kernel
+		if err != nil {
+			return nil, err
+		}
+
+		zone := ""
+		if iface != nil && (target.IsLinkLocalUnicast() || target.IsLinkLocalMulticast() || target.IsInterfaceLocalMulticast()) {
+			zone = iface.Name
+		}
+		var dst net.Addr = &net.UDPAddr{IP: target, Zone: zone}
+		if raw {
+			dst = &net.IPAddr{IP: target, Zone: zone}
+		}
+
+		sent[(seq+i)&0xffff] = time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil && len(targets) == 1 {
+			return nil, fmt.Errorf("failed to send echo to %s: %v", target, err)
+		}
+	}
+
+	responders := make(map[string]time.Duration)
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1500)
+
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		received := time.Now()
+		if err != nil {
+			break // Timeout, Abbruch oder geschlossener Socket
+		}
+
+		msg, err := icmp.ParseMessage(icmpProtocolIPv6, buf[:n])
+		if err != nil || msg.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok {
+			continue
+		}
+		// Datagram-Sockets schreiben die ID um, Raw-Sockets sehen alle Replies
+		if raw && echo.ID != id {
+			continue
+		}
+		sentAt, ok := sent[echo.Seq]
+		if !ok {
+			continue
+		}
+
+		ip := peerIPv6(peer)
+		if ip == "" {
+			continue
+		}
+		if _, seen := responders[ip]; !seen {
+			responders[ip] = received.Sub(sentAt)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return responders, ctx.Err()
+	}
+	return responders, nil
+}
+
+// peerIPv6 extrahiert die IPv6-Adresse (ohne Zone) eines Absenders
+func peerIPv6(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.String()
+	case *net.IPAddr:
+		return a.IP.String()
+	default:
+		return ""
+	}
+}
+
+// PingIPv6 sendet einen ICMPv6 Echo-Request an eine Unicast-Adresse
+func PingIPv6(ctx context.Context, ip net.IP, timeout time.Duration) (time.Duration, error) {
+	var iface *net.Interface
+	if ip.IsLinkLocalUnicast() {
+		// Link-Local-Adressen brauchen ein Interface - das erste mit IPv6 nehmen
+		iface, _, _ = InterfaceForNetwork(&net.IPNet{IP: ip, Mask: net.CIDRMask(64, 128)})
+	}
+
+	responders, err := EchoIPv6(ctx, iface, []net.IP{ip}, timeout)
+	if err != nil {
+		return 0, err
+	}
+	rtt, ok := responders[ip.String()]
+	if !ok {
+		return 0, fmt.Errorf("no reply from %s", ip)
+	}
+	return rtt, nil
+}
+
+// DiscoverIPv6Neighbors findet IPv6-Nachbarn auf einem Interface: ICMPv6-Echo an alle Knoten
+// (ff02::1) sowie an die Solicited-Node-Adressen der Kandidaten; die Antworten füllen die
+// Neighbor-Tabelle, aus der anschließend die MAC-Adressen gelesen werden.
+func DiscoverIPv6Neighbors(ctx context.Context, iface *net.Interface, candidates []net.IP, timeout time.Duration) ([]NDPEntry, error) {
+	targets := []net.IP{allNodesMulticast}
+	seen := map[string]bool{allNodesMulticast.String(): true}
+	for _, candidate := range candidates {
+		group := SolicitedNodeMulticast(candidate)
+		if group != nil && !seen[group.String()] {
+			seen[group.String()] = true
+			targets = append(targets, group)
+		}
+	}
+
+	responders, err := EchoIPv6(ctx, iface, targets, timeout)
+	if err != nil && len(responders) == 0 {
+		return nil, err
+	}
+
+	entries, err := neighborsOnInterface(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	// Antwortende Hosts ohne Tabelleneintrag per Unicast ansprechen - dabei löst der
+	// Kernel eine Neighbor Solicitation aus und legt den Eintrag an
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		known[entry.IP.String()] = true
+	}
+	var missing []net.IP
+	for ip := range responders {
+		if !known[ip] && !isLocalAddress(iface, ip) {
+			missing = append(missing, net.ParseIP(ip))
+		}
+	}
+	if len(missing) > 0 && ctx.Err() == nil {
+		_, _ = EchoIPv6(ctx, iface, missing, timeout/2)
+		if entries, err = neighborsOnInterface(iface); err != nil {
+			return nil, err
+		}
+	}
+
+	// Nur aktiv bestätigte Nachbarn melden (veraltete Tabelleneinträge ignorieren)
+	var result []NDPEntry
+	for _, entry := range entries {
+		if rtt, ok := responders[entry.IP.String()]; ok {
+			entry.RTT = rtt
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// neighborsOnInterface liest die Neighbor-Tabelle gefiltert auf ein Interface
+func neighborsOnInterface(iface *net.Interface) ([]NDPEntry, error) {
+	entries, err := ReadNDPTable()
+	if err != nil {
+		return nil, err
+	}
+	if iface == nil {
+		return entries, nil
+	}
+
+	var filtered []NDPEntry
+	for _, entry := range entries {
+		if entry.Interface == "" || entry.Interface == iface.Name {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
+
+// isLocalAddress prüft ob eine Adresse dem Interface selbst gehört
+func isLocalAddress(iface *net.Interface, ip string) bool {
+	if iface == nil {
+		return false
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.String() == ip {
+			return true
+		}
+	}
+	return false
+}
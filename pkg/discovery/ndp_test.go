@@ -0,0 +1,55 @@
+package discovery_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+var _ = Describe("IPv6 Neighbor Discovery", func() {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+
+	Describe("LinkLocalFromMAC", func() {
+		It("should build the EUI-64 link-local address", func() {
+			Expect(discovery.LinkLocalFromMAC(mac).String()).To(Equal("fe80::211:22ff:fe33:4455"))
+		})
+
+		It("should reject non-Ethernet addresses", func() {
+			Expect(discovery.LinkLocalFromMAC(net.HardwareAddr{1, 2, 3})).To(BeNil())
+		})
+	})
+
+	Describe("SolicitedNodeMulticast", func() {
+		It("should use the last 24 bits of the address", func() {
+			ip := net.ParseIP("2001:db8::1:2:3:abcd")
+			Expect(discovery.SolicitedNodeMulticast(ip).String()).To(Equal("ff02::1:ff03:abcd"))
+		})
+	})
+
+	Describe("ClassifyIPv6", func() {
+		It("should detect link-local addresses", func() {
+			Expect(discovery.ClassifyIPv6(net.ParseIP("fe80::1234:5678:9abc:def0"), mac)).To(Equal(discovery.IPv6LinkLocal))
+			Expect(discovery.ClassifyIPv6(net.ParseIP("fe80::211:22ff:fe33:4455"), mac)).To(Equal(discovery.IPv6LinkLocal))
+		})
+
+		It("should detect SLAAC addresses derived from the MAC", func() {
+			Expect(discovery.ClassifyIPv6(net.ParseIP("2001:db8::211:22ff:fe33:4455"), mac)).To(Equal(discovery.IPv6SLAAC))
+		})
+
+		It("should detect privacy addresses", func() {
+			Expect(discovery.ClassifyIPv6(net.ParseIP("2001:db8::8d3a:11f2:93c4:5e01"), mac)).To(Equal(discovery.IPv6Privacy))
+			Expect(discovery.ClassifyIPv6(net.ParseIP("fd00::8d3a:11f2:93c4:5e01"), nil)).To(Equal(discovery.IPv6Privacy))
+		})
+
+		It("should detect static addresses", func() {
+			Expect(discovery.ClassifyIPv6(net.ParseIP("2001:db8::1"), mac)).To(Equal(discovery.IPv6Static))
+		})
+
+		It("should ignore IPv4 addresses", func() {
+			Expect(discovery.ClassifyIPv6(net.ParseIP("192.168.1.1"), mac)).To(BeEmpty())
+		})
+	})
+})
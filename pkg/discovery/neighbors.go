@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxNeighbors begrenzt die gemerkten Nachbarn (z.B. beim Lesen eines Trunk-Mitschnitts)
+const maxNeighbors = 1024
+
+// uplinkWindow ist der Zeitraum, in dem LLDP- und CDP-Ankündigungen als gleichzeitig
+// gelten (CDP sendet standardmäßig alle 60 Sekunden)
+const uplinkWindow = 3 * time.Minute
+
+// NeighborListener sammelt die per LLDP und CDP angekündigten Nachbarn. Da Switches
+// diese Frames nicht weiterleiten, stammen live empfangene Ankündigungen vom Port, an
+// dem das eigene Interface hängt (siehe Uplink). Sicher für nebenläufige Nutzung.
+type NeighborListener struct {
+	mu        sync.Mutex
+	neighbors map[string]*neighborEntry // Schlüssel: Protokoll, Chassis-ID und Port
+}
+
+type neighborEntry struct {
+	neighbor Neighbor
+	source   string // Absender-MAC des Frames (Port-MAC)
+	lastSeen time.Time
+}
+
+// NewNeighborListener erstellt einen leeren Listener
+func NewNeighborListener() *NeighborListener {
+	return &NeighborListener{neighbors: make(map[string]*neighborEntry)}
+}
+
+// Listen liest Frames aus source, bis die Quelle endet (pcap-Datei) oder ctx beendet
+// wird (Live-Mitschnitt). Bei Abbruch über ctx wird die Quelle geschlossen.
+func (l *NeighborListener) Listen(ctx context.Context, source FrameSource) error {
+	stop := context.AfterFunc(ctx, func() { _ = source.Close() })
+	defer stop()
+
+	for {
+		frame, err := source.NextFrame()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		l.Add(frame)
+	}
+}
+
+// Add wertet einen Frame aus. Gibt true zurück, wenn er eine LLDP/CDP-Ankündigung war.
+func (l *NeighborListener) Add(frame Frame) bool {
+	etherType, src, payload, ok := ethernetPayload(frame.Data)
+	if !ok || (etherType != etherTypeLLDP && etherType > 1500) {
+		return false
+	}
+	neighbor := decodeNeighbor(etherType, payload)
+	if neighbor == nil {
+		return false
+	}
+
+	key := neighbor.Protocol + "|" + neighbor.ChassisID + "|" + neighbor.PortID
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.neighbors[key] == nil && len(l.neighbors) >= maxNeighbors {
+		return true
+	}
+	l.neighbors[key] = &neighborEntry{neighbor: *neighbor, source: src.String(), lastSeen: frame.Time}
+	return true
+}
+
+// Tap gibt eine Quelle zurück, die alle Frames von source weiterreicht und dabei
+// die LLDP/CDP-Ankündigungen auswertet (z.B. für den passiven Modus)
+func (l *NeighborListener) Tap(source FrameSource) FrameSource {
+	return &neighborTap{FrameSource: source, listener: l}
+}
+
+type neighborTap struct {
+	FrameSource
+	listener *NeighborListener
+}
+
+func (t *neighborTap) NextFrame() (Frame, error) {
+	frame, err := t.FrameSource.NextFrame()
+	if err == nil {
+		t.listener.Add(frame)
+	}
+	return frame, err
+}
+
+// Neighbors gibt alle bisher gesehenen Nachbarn zurück (nach Name und Port sortiert)
+func (l *NeighborListener) Neighbors() []Neighbor {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	neighbors := make([]Neighbor, 0, len(l.neighbors))
+	for _, entry := range l.neighbors {
+		neighbors = append(neighbors, entry.neighbor)
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].SystemName != neighbors[j].SystemName {
+			return neighbors[i].SystemName < neighbors[j].SystemName
+		}
+		if neighbors[i].PortID != neighbors[j].PortID {
+			return neighbors[i].PortID < neighbors[j].PortID
+		}
+		return neighbors[i].Protocol > neighbors[j].Protocol
+	})
+	return neighbors
+}
+
+// Uplink gibt den Nachbarn zurück, der sich zuletzt angekündigt hat - bei einem
+// Live-Mitschnitt der Switch-Port des eigenen Interfaces (nil = keiner gesehen).
+// Kündigt sich derselbe Switch per LLDP und CDP an, wird LLDP bevorzugt.
+func (l *NeighborListener) Uplink() *Neighbor {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var latest *neighborEntry
+	for _, entry := range l.neighbors {
+		if latest == nil || entry.lastSeen.After(latest.lastSeen) {
+			latest = entry
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	best := latest
+	for _, entry := range l.neighbors {
+		if entry.neighbor.Protocol == "lldp" && best.neighbor.Protocol != "lldp" && latest.lastSeen.Sub(entry.lastSeen) < uplinkWindow {
+			best = entry
+		}
+	}
+	neighbor := best.neighbor
+	return &neighbor
+}
+
+// Lookup sucht den Nachbarn eines Geräts über die Management-Adresse oder die MAC
+// (Chassis-ID bzw. Absender der Ankündigung); nil, wenn sich das Gerät nicht ankündigt
+func (l *NeighborListener) Lookup(ip net.IP, mac string) *Neighbor {
+	mac = strings.ToLower(mac)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found *neighborEntry
+	for _, entry := range l.neighbors {
+		n := entry.neighbor
+		match := (ip != nil && n.ManagementIP.Equal(ip)) ||
+			(mac != "" && (strings.EqualFold(n.ChassisID, mac) || entry.source == mac))
+		if match && (found == nil || entry.lastSeen.After(found.lastSeen)) {
+			found = entry
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	neighbor := found.neighbor
+	return &neighbor
+}
@@ -0,0 +1,94 @@
+package discovery_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+var _ = Describe("NeighborListener", func() {
+	at := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	var listener *discovery.NeighborListener
+
+	BeforeEach(func() {
+		listener = discovery.NewNeighborListener()
+	})
+
+	It("should collect LLDP and CDP neighbours from a capture", func() {
+		arp := ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1"))
+		file := pcapFile(
+			discovery.Frame{Time: at, Data: ethFrame(switchMAC, 0x88cc, lldpPayload())},
+			discovery.Frame{Time: at.Add(time.Second), Data: arp},
+			discovery.Frame{Time: at.Add(2 * time.Second), Data: cdpFrame()},
+		)
+		reader, err := discovery.NewPcapReader(bytes.NewReader(file))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(listener.Listen(context.Background(), reader)).To(Succeed())
+
+		neighbors := listener.Neighbors()
+		Expect(neighbors).To(HaveLen(2))
+		Expect(neighbors[0].SystemName).To(Equal("core-sw1"))
+		Expect(neighbors[0].PortID).To(Equal("Gi1/0/12"))
+		Expect(neighbors[0].VLAN).To(Equal(20))
+		Expect(neighbors[1].SystemName).To(Equal("core-sw2.example.net"))
+		Expect(neighbors[1].ManagementIP.String()).To(Equal("10.0.0.3"))
+	})
+
+	It("should report the most recent announcement as uplink, preferring LLDP", func() {
+		Expect(listener.Uplink()).To(BeNil())
+
+		Expect(listener.Add(discovery.Frame{Time: at, Data: cdpFrame()})).To(BeTrue())
+		Expect(listener.Uplink().Protocol).To(Equal("cdp"))
+
+		listener.Add(discovery.Frame{Time: at.Add(30 * time.Second), Data: ethFrame(switchMAC, 0x88cc, lldpPayload())})
+		listener.Add(discovery.Frame{Time: at.Add(60 * time.Second), Data: cdpFrame()})
+		uplink := listener.Uplink()
+		Expect(uplink.Protocol).To(Equal("lldp"))
+		Expect(uplink.String()).To(Equal("core-sw1 Gi1/0/12 (Büro 2.13) VLAN 20 (10.0.0.2)"))
+
+		// Eine deutlich neuere CDP-Ankündigung gewinnt (Kabel umgesteckt)
+		listener.Add(discovery.Frame{Time: at.Add(10 * time.Minute), Data: cdpFrame()})
+		Expect(listener.Uplink().Protocol).To(Equal("cdp"))
+	})
+
+	It("should find neighbours by management address or MAC", func() {
+		listener.Add(discovery.Frame{Time: at, Data: ethFrame(switchMAC, 0x88cc, lldpPayload())})
+
+		Expect(listener.Lookup(net.ParseIP("10.0.0.2"), "")).NotTo(BeNil())
+		Expect(listener.Lookup(nil, "00:1B:54:AA:BB:CC").SystemName).To(Equal("core-sw1"))
+		Expect(listener.Lookup(net.ParseIP("10.0.0.9"), "aa:bb:cc:dd:ee:ff")).To(BeNil())
+	})
+
+	It("should pass frames through a tap", func() {
+		arp := ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1"))
+		reader, err := discovery.NewPcapReader(bytes.NewReader(pcapFile(
+			discovery.Frame{Time: at, Data: arp},
+			discovery.Frame{Time: at, Data: cdpFrame()},
+		)))
+		Expect(err).NotTo(HaveOccurred())
+
+		source := listener.Tap(reader)
+		frame, err := source.NextFrame()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(frame.Data).To(Equal(arp))
+		Expect(listener.Neighbors()).To(BeEmpty())
+		_, err = source.NextFrame()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listener.Neighbors()).To(HaveLen(1))
+		Expect(source.Close()).To(Succeed())
+	})
+
+	It("should ignore other frames", func() {
+		Expect(listener.Add(discovery.Frame{Time: at, Data: ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1"))})).To(BeFalse())
+		Expect(listener.Add(discovery.Frame{Time: at, Data: ethFrame(switchMAC, 0x88cc, lldpPayload()[:15])})).To(BeFalse())
+		Expect(listener.Neighbors()).To(BeEmpty())
+	})
+})
@@ -1,6 +1,7 @@
 package discovery
 
 import (
+	"fmt"
 	"net"
 )
 
@@ -33,8 +34,8 @@ func GetLocalNetworks() ([]*net.IPNet, error) {
 				ipNet = &net.IPNet{IP: v.IP, Mask: v.IP.DefaultMask()}
 			}
 
-			// Nur IPv4, keine Loopback
-			if ipNet != nil && ipNet.IP.To4() != nil && !ipNet.IP.IsLoopback() {
+			// IPv4 und globale/ULA IPv6-Präfixe, keine Loopback- und Link-Local-Adressen
+			if ipNet != nil && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
 				localNetworks = append(localNetworks, ipNet)
 			}
 		}
@@ -69,8 +70,51 @@ func networksOverlap(net1, net2 *net.IPNet) bool {
 // GetLocalIP gibt die erste lokale nicht-loopback IPv4 Adresse zurück
 func GetLocalIP() net.IP {
 	localNets, err := GetLocalNetworks()
-	if err != nil || len(localNets) == 0 {
+	if err != nil {
 		return nil
 	}
-	return localNets[0].IP
+	for _, localNet := range localNets {
+		if localNet.IP.To4() != nil {
+			return localNet.IP
+		}
+	}
+	return nil
+}
+
+// InterfaceForNetwork sucht das lokale Interface, dessen Subnetz das Zielnetzwerk
+// überlappt, und gibt es zusammen mit der lokalen Quelladresse zurück.
+// Die Adressfamilie (IPv4/IPv6) richtet sich nach dem Zielnetzwerk.
+func InterfaceForNetwork(targetNetwork *net.IPNet) (*net.Interface, net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	wantIPv6 := targetNetwork.IP.To4() == nil
+
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || (ipNet.IP.To4() == nil) != wantIPv6 {
+				continue
+			}
+			if networksOverlap(ipNet, targetNetwork) {
+				if !wantIPv6 {
+					return iface, ipNet.IP.To4(), nil
+				}
+				return iface, ipNet.IP, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no local interface found for network %s", targetNetwork.String())
 }
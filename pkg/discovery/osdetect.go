@@ -0,0 +1,303 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Betriebssystem-Familien von GuessOS
+const (
+	OSLinux   = "Linux"
+	OSWindows = "Windows"
+	OSApple   = "macOS/iOS"
+	OSBSD     = "BSD"
+	OSNetwork = "Network OS" // Cisco IOS, RouterOS, JunOS, ...
+	OSUnix    = "Unix"       // Linux, macOS oder BSD (nur die TTL ist bekannt)
+)
+
+// unixFamilies teilen sich die Start-TTL 64
+var unixFamilies = []string{OSLinux, OSApple, OSBSD}
+
+// TCPFingerprint beschreibt das SYN-ACK eines offenen Ports
+type TCPFingerprint struct {
+	Port    int    `json:"port"`
+	TTL     int    `json:"ttl"`
+	Window  int    `json:"window"`
+	Options string `json:"options"` // z.B. "M1460,S,T,N,W7" (MSS, SACK, Timestamps, NOP, Window Scale)
+}
+
+// Layout gibt die Reihenfolge der TCP-Optionen ohne Werte zurück ("M,S,T,N,W").
+// Füllende End-of-Options am Schluss werden ignoriert.
+func (fp TCPFingerprint) Layout() string {
+	kinds := strings.Split(fp.Options, ",")
+	for i, kind := range kinds {
+		kinds[i] = strings.TrimRight(kind, "0123456789")
+	}
+	for len(kinds) > 0 && kinds[len(kinds)-1] == "E" {
+		kinds = kinds[:len(kinds)-1]
+	}
+	return strings.Join(kinds, ",")
+}
+
+// ParseTCPSynAck liest Fenstergröße und Optionen aus einem TCP-Segment (ohne IP-Header).
+// ttl ist die TTL des IP-Pakets. Nur SYN-ACKs werden ausgewertet.
+func ParseTCPSynAck(segment []byte, ttl int) (TCPFingerprint, bool) {
+	if len(segment) < 20 {
+		return TCPFingerprint{}, false
+	}
+	const flagSYN, flagACK = 0x02, 0x10
+	if segment[13]&(flagSYN|flagACK) != flagSYN|flagACK {
+		return TCPFingerprint{}, false
+	}
+	headerLen := int(segment[12]>>4) * 4
+	if headerLen < 20 || headerLen > len(segment) {
+		return TCPFingerprint{}, false
+	}
+
+	fp := TCPFingerprint{
+		Port:   int(binary.BigEndian.Uint16(segment)),
+		TTL:    ttl,
+		Window: int(binary.BigEndian.Uint16(segment[14:])),
+	}
+
+	var options []string
+	data := segment[20:headerLen]
+	for len(data) > 0 {
+		kind := data[0]
+		switch kind {
+		case 0:
+			options = append(options, "E")
+			data = data[1:]
+			continue
+		case 1:
+			options = append(options, "N")
+			data = data[1:]
+			continue
+		}
+		if len(data) < 2 || int(data[1]) < 2 || int(data[1]) > len(data) {
+			break // Fehlerhafte Option - Rest verwerfen
+		}
+		value := data[2:data[1]]
+		switch {
+		case kind == 2 && len(value) == 2:
+			options = append(options, "M"+strconv.Itoa(int(binary.BigEndian.Uint16(value))))
+		case kind == 3 && len(value) == 1:
+			options = append(options, "W"+strconv.Itoa(int(value[0])))
+		case kind == 4:
+			options = append(options, "S")
+		case kind == 8:
+			options = append(options, "T")
+		default:
+			options = append(options, "?"+strconv.Itoa(int(kind)))
+		}
+		data = data[data[1]:]
+	}
+	fp.Options = strings.Join(options, ",")
+	return fp, true
+}
+
+// OSSignals sind die Anhaltspunkte für GuessOS
+type OSSignals struct {
+	TTL     int             // TTL einer ICMP-Antwort (0 = unbekannt)
+	TCP     *TCPFingerprint // SYN-ACK eines offenen Ports (nil = unbekannt)
+	Banners []string        // HTTP-Server, Dienst-Banner, SNMP sysDescr
+}
+
+// OSHint ist die geschätzte Betriebssystem-Familie eines Geräts
+type OSHint struct {
+	Family     string          `json:"family,omitempty"` // leer, wenn kein Anhaltspunkt passt
+	Confidence int             `json:"confidence"`       // 0-100
+	Evidence   []string        `json:"evidence,omitempty"`
+	TTL        int             `json:"ttl,omitempty"` // Ausgewertete TTL (ICMP)
+	TCP        *TCPFingerprint `json:"tcp,omitempty"`
+}
+
+// String gibt Familie und Sicherheit zurück, z.B. "Linux (70%)"
+func (h *OSHint) String() string {
+	if h == nil || h.Family == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d%%)", h.Family, h.Confidence)
+}
+
+// osEvidence ist ein Anhaltspunkt für eine oder mehrere Familien
+type osEvidence struct {
+	families []string
+	weight   int
+	text     string
+}
+
+// tcpLayouts sind typische Optionen im SYN-ACK (Reihenfolge ohne Werte)
+var tcpLayouts = []struct {
+	layout string
+	family string
+	weight int
+}{
+	{"M,S,T,N,W", OSLinux, 50},
+	{"M,N,N,S,N,W", OSLinux, 40}, // ohne Timestamps
+	{"M,N,W,N,N,S", OSWindows, 50},
+	{"M,N,W,N,N,T,S", OSApple, 50},
+	{"M,N,W,S,T", OSBSD, 40},
+}
+
+// tcpWindows sind typische Fenstergrößen im SYN-ACK
+var tcpWindows = map[int][]string{
+	5792:  {OSLinux},
+	14480: {OSLinux},
+	14600: {OSLinux},
+	28960: {OSLinux},
+	29200: {OSLinux},
+	43440: {OSLinux},
+	65160: {OSLinux},
+	8192:  {OSWindows},
+	65535: {OSApple, OSBSD},
+}
+
+// osBanners erkennen die Familie an Banner-Texten (Distribution, Server, Plattform)
+var osBanners = []struct {
+	family  string
+	pattern *regexp.Regexp
+}{
+	{OSNetwork, regexp.MustCompile(`(?i)cisco|routeros|mikrotik|junos|fortios|fortigate|arubaos|procurve|comware`)},
+	{OSWindows, regexp.MustCompile(`(?i)microsoft|windows|win32|win64`)},
+	{OSApple, regexp.MustCompile(`(?i)darwin|mac ?os`)},
+	{OSBSD, regexp.MustCompile(`(?i)freebsd|openbsd|netbsd|pfsense|opnsense`)},
+	{OSLinux, regexp.MustCompile(`(?i)ubuntu|debian|raspbian|centos|red ?hat|rhel|fedora|alpine|suse|openwrt|linux`)},
+}
+
+// InitialTTL gibt die wahrscheinliche Start-TTL einer empfangenen TTL zurück (32, 64, 128, 255)
+func InitialTTL(ttl int) int {
+	switch {
+	case ttl <= 0:
+		return 0
+	case ttl <= 32:
+		return 32
+	case ttl <= 64:
+		return 64
+	case ttl <= 128:
+		return 128
+	default:
+		return 255
+	}
+}
+
+// GuessOS schätzt die Betriebssystem-Familie aus der Start-TTL, dem SYN-ACK eines
+// offenen Ports und Banner-Texten. Jeder Anhaltspunkt gibt seinen Familien ein Gewicht;
+// die Familie mit der höchsten Summe gewinnt, die Sicherheit sinkt mit Widersprüchen
+// (wie bei den Klassifizierungsregeln). Passt nur die TTL 64, lautet das Ergebnis "Unix".
+// Ohne Anhaltspunkte liefert GuessOS nil.
+func GuessOS(signals OSSignals) *OSHint {
+	var evidence []osEvidence
+
+	if fp := signals.TCP; fp != nil {
+		layout := fp.Layout()
+		for _, known := range tcpLayouts {
+			if layout == known.layout {
+				evidence = append(evidence, osEvidence{[]string{known.family}, known.weight,
+					fmt.Sprintf("TCP options %s (port %d)", layout, fp.Port)})
+				break
+			}
+		}
+		if families, ok := tcpWindows[fp.Window]; ok {
+			evidence = append(evidence, osEvidence{families, 20, fmt.Sprintf("TCP window %d", fp.Window)})
+		}
+	}
+
+	// TTL des SYN-ACK bevorzugen, sonst die der ICMP-Antwort
+	ttl := signals.TTL
+	if signals.TCP != nil && signals.TCP.TTL > 0 {
+		ttl = signals.TCP.TTL
+	}
+	text := fmt.Sprintf("TTL %d (initial %d)", ttl, InitialTTL(ttl))
+	switch InitialTTL(ttl) {
+	case 64:
+		evidence = append(evidence, osEvidence{unixFamilies, 30, text})
+	case 128:
+		evidence = append(evidence, osEvidence{[]string{OSWindows}, 40, text})
+	case 255:
+		evidence = append(evidence, osEvidence{[]string{OSNetwork}, 20, text})
+	}
+
+	// Pro Familie zählt nur das erste passende Banner
+	matched := make(map[string]bool)
+	for _, banner := range signals.Banners {
+		for _, known := range osBanners {
+			if matched[known.family] {
+				continue
+			}
+			if m := known.pattern.FindString(banner); m != "" {
+				matched[known.family] = true
+				evidence = append(evidence, osEvidence{[]string{known.family}, 45,
+					fmt.Sprintf("banner %q ~ %q", truncateBanner(banner), m)})
+			}
+		}
+	}
+
+	if len(evidence) == 0 {
+		if signals.TCP != nil {
+			return &OSHint{TTL: signals.TTL, TCP: signals.TCP}
+		}
+		return nil
+	}
+
+	total := 0
+	scores := make(map[string]int)
+	var order []string
+	for _, e := range evidence {
+		total += e.weight
+		for _, family := range e.families {
+			if _, ok := scores[family]; !ok {
+				order = append(order, family)
+			}
+			scores[family] += e.weight
+		}
+	}
+
+	best, bestScore := "", 0
+	var tied []string
+	for _, family := range order {
+		switch score := scores[family]; {
+		case score > bestScore:
+			best, bestScore, tied = family, score, []string{family}
+		case score == bestScore:
+			tied = append(tied, family)
+		}
+	}
+	if len(tied) > 1 && isUnixFamilies(tied) {
+		best = OSUnix
+	}
+
+	hint := &OSHint{
+		Family:     best,
+		Confidence: (min(bestScore, 100)*bestScore + total/2) / total,
+		TTL:        signals.TTL,
+		TCP:        signals.TCP,
+	}
+	for _, e := range evidence {
+		hint.Evidence = append(hint.Evidence, e.text)
+	}
+	return hint
+}
+
+// isUnixFamilies prüft, ob alle Familien Unix-Varianten sind
+func isUnixFamilies(families []string) bool {
+	for _, family := range families {
+		if !slices.Contains(unixFamilies, family) {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateBanner kürzt lange Banner für die Begründung
+func truncateBanner(banner string) string {
+	banner = strings.TrimSpace(banner)
+	if runes := []rune(banner); len(runes) > 60 {
+		return string(runes[:57]) + "..."
+	}
+	return banner
+}
@@ -0,0 +1,134 @@
+package discovery_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+// synAck baut ein TCP-Segment (Quellport 22) mit Flags, Fenster und Optionen
+func synAck(flags byte, window uint16, options ...byte) []byte {
+	headerLen := 20 + len(options)
+	segment := make([]byte, headerLen)
+	segment[0], segment[1] = 0, 22
+	segment[12] = byte(headerLen/4) << 4
+	segment[13] = flags
+	segment[14], segment[15] = byte(window>>8), byte(window)
+	copy(segment[20:], options)
+	return segment
+}
+
+var _ = Describe("OS fingerprinting", func() {
+	var (
+		linuxOptions   = []byte{2, 4, 0x05, 0xb4, 4, 2, 8, 10, 0, 0, 0, 1, 0, 0, 0, 0, 1, 3, 3, 7}
+		windowsOptions = []byte{2, 4, 0x05, 0xb4, 1, 3, 3, 8, 1, 1, 4, 2}
+		appleOptions   = []byte{2, 4, 0x05, 0xb4, 1, 3, 3, 6, 1, 1, 8, 10, 0, 0, 0, 1, 0, 0, 0, 0, 4, 2, 0, 0}
+	)
+
+	Describe("ParseTCPSynAck", func() {
+		It("should read window and options of a SYN-ACK", func() {
+			fp, ok := discovery.ParseTCPSynAck(synAck(0x12, 65160, linuxOptions...), 63)
+			Expect(ok).To(BeTrue())
+			Expect(fp).To(Equal(discovery.TCPFingerprint{Port: 22, TTL: 63, Window: 65160, Options: "M1460,S,T,N,W7"}))
+			Expect(fp.Layout()).To(Equal("M,S,T,N,W"))
+
+			fp, _ = discovery.ParseTCPSynAck(synAck(0x12, 65535, appleOptions...), 64)
+			Expect(fp.Options).To(Equal("M1460,N,W6,N,N,T,S,E,E"))
+			Expect(fp.Layout()).To(Equal("M,N,W,N,N,T,S"))
+		})
+
+		It("should ignore other segments and broken options", func() {
+			_, ok := discovery.ParseTCPSynAck(synAck(0x10, 512), 64)
+			Expect(ok).To(BeFalse())
+			_, ok = discovery.ParseTCPSynAck([]byte{0, 22, 0, 0}, 64)
+			Expect(ok).To(BeFalse())
+
+			fp, ok := discovery.ParseTCPSynAck(synAck(0x12, 8192, 2, 4, 0x05, 0xb4, 3, 9, 0, 0), 128)
+			Expect(ok).To(BeTrue())
+			Expect(fp.Options).To(Equal("M1460"))
+		})
+	})
+
+	Describe("GuessOS", func() {
+		It("should combine SYN-ACK and TTL", func() {
+			fp, _ := discovery.ParseTCPSynAck(synAck(0x12, 65160, linuxOptions...), 63)
+			hint := discovery.GuessOS(discovery.OSSignals{TCP: &fp})
+			Expect(hint.Family).To(Equal(discovery.OSLinux))
+			Expect(hint.Confidence).To(Equal(100))
+			Expect(hint.Evidence).To(Equal([]string{"TCP options M,S,T,N,W (port 22)", "TCP window 65160", "TTL 63 (initial 64)"}))
+			Expect(hint.String()).To(Equal("Linux (100%)"))
+
+			fp, _ = discovery.ParseTCPSynAck(synAck(0x12, 64240, windowsOptions...), 128)
+			Expect(discovery.GuessOS(discovery.OSSignals{TTL: 128, TCP: &fp}).Family).To(Equal(discovery.OSWindows))
+
+			fp, _ = discovery.ParseTCPSynAck(synAck(0x12, 65535, appleOptions...), 64)
+			Expect(discovery.GuessOS(discovery.OSSignals{TCP: &fp}).Family).To(Equal(discovery.OSApple))
+		})
+
+		It("should only narrow TTL 64 down to Unix", func() {
+			hint := discovery.GuessOS(discovery.OSSignals{TTL: 61})
+			Expect(hint.Family).To(Equal(discovery.OSUnix))
+			Expect(hint.Confidence).To(Equal(30))
+
+			hint = discovery.GuessOS(discovery.OSSignals{TTL: 61, Banners: []string{"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"}})
+			Expect(hint.Family).To(Equal(discovery.OSLinux))
+			Expect(hint.Confidence).To(Equal(75))
+			Expect(hint.Evidence[1]).To(Equal(`banner "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6" ~ "Ubuntu"`))
+		})
+
+		It("should lower the confidence for contradicting signals", func() {
+			hint := discovery.GuessOS(discovery.OSSignals{TTL: 127, Banners: []string{"Apache/2.4.41 (Ubuntu)"}})
+			Expect(hint.Family).To(Equal(discovery.OSLinux))
+			Expect(hint.Confidence).To(Equal(24)) // 45 × 45 / 85
+
+			hint = discovery.GuessOS(discovery.OSSignals{TTL: 127, Banners: []string{"Microsoft-IIS/10.0"}})
+			Expect(hint.Family).To(Equal(discovery.OSWindows))
+			Expect(hint.Confidence).To(Equal(85))
+		})
+
+		It("should return nil without signals", func() {
+			Expect(discovery.GuessOS(discovery.OSSignals{})).To(BeNil())
+			Expect(discovery.GuessOS(discovery.OSSignals{Banners: []string{"nginx"}})).To(BeNil())
+
+			fp := discovery.TCPFingerprint{Port: 80, Window: 1024, Options: "M536"}
+			hint := discovery.GuessOS(discovery.OSSignals{TCP: &fp})
+			Expect(hint.Family).To(BeEmpty())
+			Expect(hint.TCP).To(Equal(&fp))
+		})
+	})
+
+	Describe("FingerprintTCP", func() {
+		It("should capture the SYN-ACK of a local port", func() {
+			listener, err := net.Listen("tcp4", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					_ = conn.Close()
+				}
+			}()
+			port := listener.Addr().(*net.TCPAddr).Port
+
+			fp, err := discovery.FingerprintTCP(context.Background(), net.ParseIP("127.0.0.1"), port, time.Second)
+			if errors.Is(err, discovery.ErrRawSocketUnavailable) {
+				Skip("raw socket not available: " + err.Error())
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runtime.GOOS).To(Equal("linux"))
+			Expect(fp.Port).To(Equal(port))
+			Expect(fp.TTL).To(Equal(64))
+			Expect(discovery.GuessOS(discovery.OSSignals{TCP: fp}).Family).To(Equal(discovery.OSLinux))
+		})
+	})
+})
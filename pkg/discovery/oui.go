@@ -0,0 +1,345 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ouiIndexHeader ist die erste Zeile des Index, den "netspy vendors import" schreibt
+const ouiIndexHeader = "# netspy OUI index v1"
+
+// maxVendorNameLength begrenzt die Länge der angezeigten Herstellernamen
+const maxVendorNameLength = 30
+
+// OUIEntry ist eine Zuteilung aus den IEEE-Registern
+type OUIEntry struct {
+	Prefix       string `json:"prefix"`       // Hex-Ziffern: 6 (MA-L), 7 (MA-M) oder 9 (MA-S, IAB)
+	Organization string `json:"organization"` // Name laut IEEE, ungekürzt
+}
+
+// Bits gibt die Länge des Präfixes in Bit zurück (24, 28 oder 36)
+func (e OUIEntry) Bits() int {
+	return len(e.Prefix) * 4
+}
+
+// Registry gibt den Namen des IEEE-Registers zurück
+func (e OUIEntry) Registry() string {
+	switch len(e.Prefix) {
+	case 6:
+		return "MA-L"
+	case 7:
+		return "MA-M"
+	default:
+		return "MA-S"
+	}
+}
+
+// ouiAssignmentLength ist die Anzahl Hex-Ziffern einer Zuteilung pro Register
+var ouiAssignmentLength = map[string]int{"MA-L": 6, "MA-M": 7, "MA-S": 9, "IAB": 9}
+
+// OUIRegistry ordnet MAC-Präfixe mit 24, 28 und 36 Bit Herstellern zu. Lookup
+// liefert die längste passende Zuteilung. Sicher für nebenläufiges Lesen.
+type OUIRegistry struct {
+	prefixes map[string]string // Hex-Präfix in Großbuchstaben → Organisation
+}
+
+// NewOUIRegistry erstellt ein leeres Register
+func NewOUIRegistry() *OUIRegistry {
+	return &OUIRegistry{prefixes: make(map[string]string)}
+}
+
+// Add fügt eine Zuteilung hinzu bzw. ersetzt sie
+func (r *OUIRegistry) Add(entry OUIEntry) {
+	r.prefixes[strings.ToUpper(entry.Prefix)] = entry.Organization
+}
+
+// Len gibt die Anzahl der Zuteilungen zurück
+func (r *OUIRegistry) Len() int {
+	return len(r.prefixes)
+}
+
+// Counts gibt die Anzahl der Zuteilungen pro Register zurück ("MA-L", "MA-M", "MA-S")
+func (r *OUIRegistry) Counts() map[string]int {
+	counts := make(map[string]int)
+	for prefix := range r.prefixes {
+		counts[OUIEntry{Prefix: prefix}.Registry()]++
+	}
+	return counts
+}
+
+// Lookup sucht die längste Zuteilung (36, 28, dann 24 Bit), die zur MAC passt
+func (r *OUIRegistry) Lookup(mac string) (OUIEntry, bool) {
+	digits := macHexDigits(mac)
+	for _, length := range []int{9, 7, 6} {
+		if len(digits) < length {
+			continue
+		}
+		if organization, ok := r.prefixes[digits[:length]]; ok {
+			return OUIEntry{Prefix: digits[:length], Organization: organization}, true
+		}
+	}
+	return OUIEntry{}, false
+}
+
+// Entries gibt alle Zuteilungen nach Präfix sortiert zurück
+func (r *OUIRegistry) Entries() []OUIEntry {
+	entries := make([]OUIEntry, 0, len(r.prefixes))
+	for prefix, organization := range r.prefixes {
+		entries = append(entries, OUIEntry{Prefix: prefix, Organization: organization})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Prefix < entries[j].Prefix })
+	return entries
+}
+
+// VendorEntries gibt alle Zuteilungen im Präfix-Format der übrigen Vendor-Quellen
+// zurück ("3C:22:FB", "F8:B5:68:D"), nach Präfix sortiert
+func (r *OUIRegistry) VendorEntries() []VendorEntry {
+	entries := make([]VendorEntry, 0, len(r.prefixes))
+	for _, entry := range r.Entries() {
+		prefix, _ := formatVendorPrefix(entry.Prefix)
+		entries = append(entries, VendorEntry{Prefix: prefix, Vendor: entry.Organization, Source: "ieee"})
+	}
+	return entries
+}
+
+// WriteIndex schreibt das Register als kompakten Index (gzip, eine Zeile pro Zuteilung)
+func (r *OUIRegistry) WriteIndex(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	_, _ = bw.WriteString(ouiIndexHeader + "\n")
+	for _, entry := range r.Entries() {
+		_, _ = bw.WriteString(entry.Prefix + "\t" + entry.Organization + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// SaveIndex schreibt den Index atomar nach path (Lookups anderer Prozesse sehen nie
+// eine halbe Datei)
+func (r *OUIRegistry) SaveIndex(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := r.WriteIndex(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadOUIIndex liest einen mit WriteIndex geschriebenen Index
+func ReadOUIIndex(r io.Reader) (*OUIRegistry, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OUI index: %v", err)
+	}
+	defer zr.Close()
+
+	registry := NewOUIRegistry()
+	scanner := bufio.NewScanner(zr)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			if line != ouiIndexHeader {
+				return nil, errors.New("invalid OUI index: unknown format")
+			}
+			continue
+		}
+		prefix, organization, ok := strings.Cut(line, "\t")
+		if !ok || !validOUIPrefix(prefix) {
+			return nil, fmt.Errorf("invalid OUI index line %q", line)
+		}
+		registry.prefixes[prefix] = organization
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid OUI index: %v", err)
+	}
+	return registry, nil
+}
+
+// ParseIEEERegistry liest eine CSV-Datei der IEEE (oui.csv, mam.csv, oui36.csv oder
+// iab.csv: "Registry,Assignment,Organization Name,Organization Address")
+func ParseIEEERegistry(r io.Reader) ([]OUIEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var entries []OUIEntry
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(record) < 3 {
+			continue
+		}
+
+		registry := strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff")
+		length, known := ouiAssignmentLength[registry]
+		if !known {
+			continue // Kopfzeile oder unbekanntes Register
+		}
+		prefix := strings.ToUpper(strings.TrimSpace(record[1]))
+		if len(prefix) != length || !validOUIPrefix(prefix) {
+			return nil, fmt.Errorf("line %d: invalid %s assignment %q", line, registry, record[1])
+		}
+		entries = append(entries, OUIEntry{Prefix: prefix, Organization: strings.Join(strings.Fields(record[2]), " ")})
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no IEEE registry entries found (expected oui.csv, mam.csv or oui36.csv)")
+	}
+	return entries, nil
+}
+
+// LoadOUIRegistry lädt einen Index ("netspy vendors import") oder eine IEEE-CSV-Datei
+func LoadOUIRegistry(path string) (*OUIRegistry, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Pfad aus Konfiguration bzw. Datenverzeichnis
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return ReadOUIIndex(bytes.NewReader(data))
+	}
+
+	entries, err := ParseIEEERegistry(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	registry := NewOUIRegistry()
+	for _, entry := range entries {
+		registry.Add(entry)
+	}
+	return registry, nil
+}
+
+// validOUIPrefix prüft, ob ein Präfix aus 6, 7 oder 9 Hex-Ziffern (Großbuchstaben) besteht
+func validOUIPrefix(prefix string) bool {
+	if len(prefix) != 6 && len(prefix) != 7 && len(prefix) != 9 {
+		return false
+	}
+	for _, c := range prefix {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// macHexDigits gibt die Hex-Ziffern einer MAC-Adresse in Großbuchstaben zurück
+// ("aa:bb:cc:dd:ee:ff", "AA-BB-..." und "aabb.ccdd.eeff" werden akzeptiert)
+func macHexDigits(mac string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToUpper(mac) {
+		switch {
+		case (c >= '0' && c <= '9') || (c >= 'A' && c <= 'F'):
+			sb.WriteRune(c)
+		case c == ':' || c == '-' || c == '.':
+		default:
+			return ""
+		}
+	}
+	return sb.String()
+}
+
+// organizationSuffixes sind Rechtsformen, die für die Anzeige entfernt werden
+var organizationSuffixes = []string{
+	"inc", "incorporated", "llc", "ltd", "limited", "corp", "corporation", "co", "company",
+	"gmbh", "ag", "kg", "se", "sa", "s.a", "sas", "bv", "b.v", "nv", "oy", "ab", "as", "a/s",
+	"spa", "s.p.a", "srl", "s.r.l", "pte", "pty", "plc", "co.,ltd", "co., ltd",
+}
+
+// shortenOrganization kürzt einen IEEE-Organisationsnamen für die Anzeige
+// ("Cisco Systems, Inc" → "Cisco Systems", "HUAWEI TECHNOLOGIES CO.,LTD" → "HUAWEI TECHNOLOGIES")
+func shortenOrganization(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	for changed := true; changed; {
+		changed = false
+		name = strings.TrimRight(name, " ,.")
+		lower := strings.ToLower(name)
+		for _, suffix := range organizationSuffixes {
+			if len(lower) > len(suffix)+1 && strings.HasSuffix(lower, suffix) {
+				if c := lower[len(lower)-len(suffix)-1]; c == ' ' || c == ',' || c == '.' {
+					name = name[:len(name)-len(suffix)]
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(name) > maxVendorNameLength {
+		name = strings.TrimSpace(string([]rune(name)[:maxVendorNameLength]))
+	}
+	return name
+}
+
+// Aktives IEEE-Register (siehe SetOUIRegistryFile und UseOUIRegistry)
+var (
+	ouiRegistry     *OUIRegistry
+	ouiRegistryFile string
+	ouiRegistryErr  error
+	ouiRegistryDone bool
+	ouiRegistryMux  sync.Mutex
+)
+
+// SetOUIRegistryFile legt die Datei (Index oder IEEE-CSV) fest, aus der das Register
+// beim ersten Lookup geladen wird. Fehlt die Datei, bleibt das Register leer.
+func SetOUIRegistryFile(path string) {
+	ouiRegistryMux.Lock()
+	defer ouiRegistryMux.Unlock()
+	ouiRegistryFile = path
+	ouiRegistry, ouiRegistryErr, ouiRegistryDone = nil, nil, false
+}
+
+// UseOUIRegistry setzt das Register direkt (nil = kein Register)
+func UseOUIRegistry(registry *OUIRegistry) {
+	ouiRegistryMux.Lock()
+	defer ouiRegistryMux.Unlock()
+	ouiRegistryFile = ""
+	ouiRegistry, ouiRegistryErr, ouiRegistryDone = registry, nil, true
+}
+
+// ActiveOUIRegistry gibt das geladene Register zurück (nil = keins). Der Fehler ist
+// gesetzt, wenn die Datei existiert, aber nicht gelesen werden konnte.
+func ActiveOUIRegistry() (*OUIRegistry, error) {
+	ouiRegistryMux.Lock()
+	defer ouiRegistryMux.Unlock()
+
+	if !ouiRegistryDone {
+		ouiRegistryDone = true
+		if ouiRegistryFile != "" {
+			ouiRegistry, ouiRegistryErr = LoadOUIRegistry(ouiRegistryFile)
+			if errors.Is(ouiRegistryErr, os.ErrNotExist) {
+				ouiRegistryErr = nil
+			}
+		}
+	}
+	return ouiRegistry, ouiRegistryErr
+}
@@ -0,0 +1,123 @@
+package discovery_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+const (
+	ieeeMAL = "\ufeffRegistry,Assignment,Organization Name,Organization Address\n" +
+		"MA-L,F8B568,\"Shenzhen  Example Co.,Ltd\",\"Shenzhen, CN\"\n" +
+		"MA-L,70B3D5,IEEE Registration Authority,\"445 Hoes Lane Piscataway NJ US 08554\"\n" +
+		"MA-L,00000C,\"Cisco Systems, Inc\",\"170 West Tasman Drive San Jose CA US 95134\"\n"
+	ieeeMAM = "Registry,Assignment,Organization Name,Organization Address\n" +
+		"MA-M,F8B568D,Sensorik GmbH,Musterstr. 1 Berlin DE 10115\n"
+	ieeeMAS = "Registry,Assignment,Organization Name,Organization Address\n" +
+		"MA-S,70B3D5F2C,Tiny Devices Ltd.,\"1 Main St, Cambridge GB\"\n"
+)
+
+// ieeeRegistry lädt die drei Beispiel-Register in ein Register
+func ieeeRegistry() *discovery.OUIRegistry {
+	registry := discovery.NewOUIRegistry()
+	for _, csv := range []string{ieeeMAL, ieeeMAM, ieeeMAS} {
+		entries, err := discovery.ParseIEEERegistry(strings.NewReader(csv))
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range entries {
+			registry.Add(entry)
+		}
+	}
+	return registry
+}
+
+var _ = Describe("OUIRegistry", func() {
+	It("should parse the IEEE CSV registries", func() {
+		entries, err := discovery.ParseIEEERegistry(strings.NewReader(ieeeMAL))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0]).To(Equal(discovery.OUIEntry{Prefix: "F8B568", Organization: "Shenzhen Example Co.,Ltd"}))
+		Expect(entries[0].Bits()).To(Equal(24))
+
+		_, err = discovery.ParseIEEERegistry(strings.NewReader("MA-M,F8B56,Broken\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid MA-M assignment")))
+		_, err = discovery.ParseIEEERegistry(strings.NewReader("name,value\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the longest matching assignment", func() {
+		registry := ieeeRegistry()
+		Expect(registry.Counts()).To(Equal(map[string]int{"MA-L": 3, "MA-M": 1, "MA-S": 1}))
+
+		entry, ok := registry.Lookup("70:b3:d5:f2:c1:23")
+		Expect(ok).To(BeTrue())
+		Expect(entry.Organization).To(Equal("Tiny Devices Ltd."))
+		Expect(entry.Bits()).To(Equal(36))
+
+		entry, _ = registry.Lookup("F8-B5-68-D0-00-01")
+		Expect(entry.Registry()).To(Equal("MA-M"))
+		entry, _ = registry.Lookup("f8b5.68e0.0001")
+		Expect(entry.Registry()).To(Equal("MA-L"))
+		entry, _ = registry.Lookup("70:b3:d5:00:00:01")
+		Expect(entry.Organization).To(Equal("IEEE Registration Authority"))
+
+		_, ok = registry.Lookup("02:00:00:00:00:01")
+		Expect(ok).To(BeFalse())
+		_, ok = registry.Lookup("not a mac")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should round-trip through the compact index", func() {
+		registry := ieeeRegistry()
+		path := filepath.Join(GinkgoT().TempDir(), "oui-index.gz")
+		Expect(registry.SaveIndex(path)).To(Succeed())
+
+		loaded, err := discovery.LoadOUIRegistry(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Entries()).To(Equal(registry.Entries()))
+
+		var buf bytes.Buffer
+		Expect(registry.WriteIndex(&buf)).To(Succeed())
+		Expect(buf.Len()).To(BeNumerically("<", len(ieeeMAL+ieeeMAM+ieeeMAS)))
+	})
+
+	It("should export the assignments with formatted prefixes", func() {
+		entries := ieeeRegistry().VendorEntries()
+		Expect(entries).To(HaveLen(5))
+		Expect(entries).To(ContainElement(discovery.VendorEntry{Prefix: "00:00:0C", Vendor: "Cisco Systems, Inc", Source: "ieee"}))
+		Expect(entries).To(ContainElement(discovery.VendorEntry{Prefix: "F8:B5:68:D", Vendor: "Sensorik GmbH", Source: "ieee"}))
+		Expect(entries).To(ContainElement(discovery.VendorEntry{Prefix: "70:B3:D5:F2:C", Vendor: "Tiny Devices Ltd.", Source: "ieee"}))
+	})
+
+	It("should load IEEE CSV files directly", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "mam.csv")
+		Expect(os.WriteFile(path, []byte(ieeeMAM), 0600)).To(Succeed())
+
+		registry, err := discovery.LoadOUIRegistry(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry.Len()).To(Equal(1))
+	})
+
+	Describe("GetMACVendor with an imported registry", func() {
+		BeforeEach(func() {
+			discovery.UseOUIRegistry(ieeeRegistry())
+			DeferCleanup(discovery.UseOUIRegistry, (*discovery.OUIRegistry)(nil))
+		})
+
+		It("should prefer 28/36-bit assignments over the builtin list", func() {
+			Expect(discovery.GetMACVendor("f8:b5:68:d1:22:33")).To(Equal("Sensorik"))
+			Expect(discovery.GetMACVendor("f8:b5:68:e1:22:33")).To(Equal("Shenzhen Example"))
+			Expect(discovery.GetMACVendor("70:b3:d5:f2:c0:01")).To(Equal("Tiny Devices"))
+		})
+
+		It("should fall back to the registry after the builtin list", func() {
+			Expect(discovery.GetMACVendor("00:03:93:12:34:56")).To(ContainSubstring("Apple"))
+			Expect(discovery.GetMACVendor("00:00:0c:12:34:56")).To(Equal("Cisco Systems"))
+		})
+	})
+})
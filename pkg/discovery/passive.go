@@ -0,0 +1,334 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	etherTypeVLAN = 0x8100
+	ipProtoUDP    = 17
+
+	portDHCPServer = 67
+	portDHCPClient = 68
+	portNetBIOSNS  = 137
+	portSSDP       = 1900
+	portMDNS       = 5353
+	portLLMNR      = 5355
+
+	dhcpMagicCookie = 0x63825363
+)
+
+// Observation ist das, was ein mitgeschnittener Frame über ein Gerät verrät.
+// Ein Frame kann mehrere Geräte betreffen (mDNS-Antworten mit Diensten anderer Geräte).
+type Observation struct {
+	Time        time.Time
+	Protocol    string // "arp", "dhcp", "mdns", "ssdp", "netbios", "llmnr", "lldp", "cdp"
+	MAC         net.HardwareAddr
+	IP          net.IP // IPv4 (nil, wenn der Frame keine Adresse verrät)
+	Hostname    string
+	VendorClass string         // DHCP Option 60 ("MSFT 5.0", "android-dhcp-13")
+	Services    []DNSSDService // Per mDNS angekündigte Dienste
+	SSDP        *SSDPDevice    // SSDP NOTIFY bzw. Antwort
+	Neighbor    *Neighbor      // LLDP/CDP-Ankündigung
+}
+
+// DecodeFrame wertet einen Ethernet-Frame aus (ARP, DHCP, mDNS, SSDP, NetBIOS,
+// LLMNR, LLDP und CDP). Andere und fehlerhafte Frames liefern nichts.
+func DecodeFrame(frame Frame) []Observation {
+	etherType, src, payload, ok := ethernetPayload(frame.Data)
+	if !ok {
+		return nil
+	}
+
+	var observations []Observation
+	switch etherType {
+	case etherTypeARP:
+		observations = decodeARP(payload)
+	case etherTypeIPv4:
+		observations = decodeIPv4(payload, src)
+	default:
+		if neighbor := decodeNeighbor(etherType, payload); neighbor != nil {
+			observations = neighborObservation(neighbor, src)
+		}
+	}
+
+	for i := range observations {
+		observations[i].Time = frame.Time
+	}
+	return observations
+}
+
+// ethernetPayload gibt EtherType (bzw. 802.3-Länge), Absender-MAC und Nutzdaten eines
+// Ethernet-Frames zurück; ein VLAN-Tag wird übersprungen
+func ethernetPayload(data []byte) (uint16, net.HardwareAddr, []byte, bool) {
+	if len(data) < ethHeaderLen {
+		return 0, nil, nil, false
+	}
+	src := net.HardwareAddr(append([]byte(nil), data[6:12]...))
+	etherType := binary.BigEndian.Uint16(data[12:])
+	payload := data[ethHeaderLen:]
+	if etherType == etherTypeVLAN && len(payload) >= 4 {
+		etherType = binary.BigEndian.Uint16(payload[2:])
+		payload = payload[4:]
+	}
+	return etherType, src, payload, true
+}
+
+// decodeARP liefert den Absender eines ARP-Requests bzw. einer Antwort. ARP-Probes
+// (Absender 0.0.0.0, RFC 5227) verraten noch keine Adresse.
+func decodeARP(payload []byte) []Observation {
+	if len(payload) < arpPacketLen || binary.BigEndian.Uint16(payload) != 1 ||
+		binary.BigEndian.Uint16(payload[2:]) != etherTypeIPv4 || payload[4] != 6 || payload[5] != 4 {
+		return nil
+	}
+	ip := net.IP(append([]byte(nil), payload[14:18]...))
+	if ip.IsUnspecified() {
+		return nil
+	}
+	mac := net.HardwareAddr(append([]byte(nil), payload[8:14]...))
+	return []Observation{{Protocol: "arp", MAC: mac, IP: ip}}
+}
+
+// decodeIPv4 wertet die UDP-Protokolle der Namens- und Adressvergabe aus
+func decodeIPv4(payload []byte, src net.HardwareAddr) []Observation {
+	if len(payload) < 20 || payload[0]>>4 != 4 || payload[9] != ipProtoUDP {
+		return nil
+	}
+	headerLen := int(payload[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(payload[2:]))
+	fragment := binary.BigEndian.Uint16(payload[6:])
+	if headerLen < 20 || totalLen < headerLen+8 || totalLen > len(payload) || fragment&0x3fff != 0 {
+		return nil // Fragmente werden nicht zusammengesetzt
+	}
+	srcIP := net.IP(append([]byte(nil), payload[12:16]...))
+	udp := payload[headerLen:totalLen]
+	srcPort := binary.BigEndian.Uint16(udp)
+	dstPort := binary.BigEndian.Uint16(udp[2:])
+	data := udp[8:]
+
+	switch {
+	case srcPort == portDHCPClient && dstPort == portDHCPServer, srcPort == portDHCPServer && dstPort == portDHCPClient:
+		return decodeDHCP(data)
+	}
+
+	// Alle übrigen Protokolle verraten (mindestens) den Absender
+	if srcIP.IsUnspecified() || srcIP.IsMulticast() || srcIP.Equal(net.IPv4bcast) {
+		return nil
+	}
+	sender := Observation{MAC: src, IP: srcIP}
+	switch {
+	case srcPort == portMDNS || dstPort == portMDNS:
+		sender.Protocol = "mdns"
+		return decodeMDNS(data, sender)
+	case srcPort == portLLMNR || dstPort == portLLMNR:
+		sender.Protocol = "llmnr"
+		if srcPort == portLLMNR {
+			sender.Hostname = dnsNameForAddress(data, srcIP)
+		}
+	case srcPort == portNetBIOSNS && dstPort == portNetBIOSNS:
+		sender.Protocol = "netbios"
+		sender.Hostname = decodeNetBIOSName(data, srcIP)
+	case srcPort == portSSDP || dstPort == portSSDP:
+		sender.Protocol = "ssdp"
+		if device := decodeSSDP(data, srcIP); device != nil {
+			sender.SSDP = device
+		}
+	default:
+		return nil
+	}
+	return []Observation{sender}
+}
+
+// decodeDHCP liest Client-MAC, Adresse, Hostname (Option 12) und Vendor-Class
+// (Option 60). Client-Nachrichten liefern die Adresse aus ciaddr bzw. der
+// angefragten Adresse (Option 50, nur REQUEST), ACKs die vergebene (yiaddr).
+func decodeDHCP(data []byte) []Observation {
+	if len(data) < 240 || data[1] != 1 || data[2] != 6 || binary.BigEndian.Uint32(data[236:]) != dhcpMagicCookie {
+		return nil
+	}
+	observation := Observation{
+		Protocol: "dhcp",
+		MAC:      net.HardwareAddr(append([]byte(nil), data[28:34]...)),
+	}
+
+	var messageType byte
+	var requested net.IP
+	for pos := 240; pos < len(data); {
+		code := data[pos]
+		if code == 0 { // Padding
+			pos++
+			continue
+		}
+		if code == 255 || pos+1 >= len(data) {
+			break
+		}
+		length := int(data[pos+1])
+		if pos+2+length > len(data) {
+			break
+		}
+		value := data[pos+2 : pos+2+length]
+		pos += 2 + length
+
+		switch code {
+		case 12:
+			observation.Hostname = strings.TrimSpace(string(bytes.TrimRight(value, "\x00")))
+		case 50:
+			if length == 4 {
+				requested = net.IP(append([]byte(nil), value...))
+			}
+		case 53:
+			if length == 1 {
+				messageType = value[0]
+			}
+		case 60:
+			observation.VendorClass = strings.TrimSpace(string(value))
+		}
+	}
+
+	ciaddr, yiaddr := net.IP(data[12:16]), net.IP(data[16:20])
+	switch {
+	case data[0] == 2 && messageType == 5: // ACK
+		observation.IP = yiaddr
+	case data[0] == 2: // OFFER, NAK: Adresse ist noch nicht vergeben
+		return nil
+	case !ciaddr.IsUnspecified():
+		observation.IP = ciaddr
+	case messageType == 3: // REQUEST
+		observation.IP = requested
+	}
+	if observation.IP != nil {
+		observation.IP = append(net.IP(nil), observation.IP...)
+		if observation.IP.IsUnspecified() {
+			observation.IP = nil
+		}
+	}
+	return []Observation{observation}
+}
+
+// decodeMDNS liest aus einer mDNS-Antwort den eigenen Namen des Absenders
+// (A-Record mit seiner Adresse) und die angekündigten Dienste. Dienste, deren
+// SRV-Ziel auf eine andere Adresse auflöst, werden diesem Gerät zugeordnet.
+func decodeMDNS(data []byte, sender Observation) []Observation {
+	observations := []Observation{sender}
+	if len(data) < 12 || data[2]&0x80 == 0 {
+		return observations // Anfrage: nur Absender
+	}
+	observations[0].Hostname = dnsNameForAddress(data, sender.IP)
+
+	state := newDNSSDState()
+	state.add(data, sender.IP)
+	for ip, services := range state.results() {
+		if ip == sender.IP.String() {
+			observations[0].Services = services
+			continue
+		}
+		observations = append(observations, Observation{Protocol: "mdns", IP: net.ParseIP(ip).To4(), Services: services})
+	}
+	return observations
+}
+
+// dnsNameForAddress sucht in einer DNS-Antwort (mDNS, LLMNR) den A-Record mit der
+// Adresse ip und gibt dessen Namen ohne ".local" zurück
+func dnsNameForAddress(data []byte, ip net.IP) string {
+	records, err := parseDNSRecords(data)
+	if err != nil && len(records) == 0 {
+		return ""
+	}
+	for _, record := range records {
+		if record.rrtype == dnsTypeA && record.length == 4 && ip.Equal(net.IP(data[record.offset:record.offset+4])) {
+			return strings.TrimSuffix(strings.TrimSuffix(record.name, "."), ".local")
+		}
+	}
+	return ""
+}
+
+// decodeNetBIOSName liest den Namen aus einer Namensregistrierung bzw. -erneuerung
+// (Broadcast) oder einer positiven Antwort auf eine Namensanfrage. Nur eindeutige
+// Rechnernamen (Suffix 0x00 und 0x20) werden übernommen, keine Gruppennamen.
+func decodeNetBIOSName(data []byte, ip net.IP) string {
+	if len(data) < 12+34+10 {
+		return ""
+	}
+	flags := binary.BigEndian.Uint16(data[2:])
+	response, opcode := flags&0x8000 != 0, (flags>>11)&0x0f
+	switch {
+	case !response && (opcode == 5 || opcode == 8 || opcode == 9): // Registrierung, Erneuerung
+	case response && opcode == 0 && flags&0x000f == 0 && binary.BigEndian.Uint16(data[6:]) > 0:
+	default:
+		return ""
+	}
+
+	// Kodierter Name (RFC 1001, 14.1): Länge 32, je Byte zwei Zeichen 'A'+Halbbyte
+	if data[12] != 32 || data[12+33] != 0 {
+		return ""
+	}
+	name := make([]byte, 16)
+	for i := range name {
+		high, low := data[13+2*i]-'A', data[14+2*i]-'A'
+		if high > 15 || low > 15 {
+			return ""
+		}
+		name[i] = high<<4 | low
+	}
+	if suffix := name[15]; suffix != 0x00 && suffix != 0x20 {
+		return ""
+	}
+
+	// NB-Record: Flags (Gruppe?) und Adresse prüfen. Bei Registrierungen folgt er
+	// als Additional Record auf die Frage, der Name ist meist ein Zeiger.
+	pos := 12 + 34 + 4 // Name, Typ und Klasse der Frage bzw. Antwort
+	if !response {
+		if binary.BigEndian.Uint16(data[4:]) != 1 || binary.BigEndian.Uint16(data[10:]) != 1 {
+			return ""
+		}
+		if data[pos]&0xc0 == 0xc0 {
+			pos += 2
+		} else {
+			pos += 34
+		}
+		pos += 4 // Typ und Klasse
+	}
+	pos += 4 // TTL
+	if pos+8 > len(data) || binary.BigEndian.Uint16(data[pos:]) < 6 {
+		return ""
+	}
+	nbFlags := binary.BigEndian.Uint16(data[pos+2:])
+	if nbFlags&0x8000 != 0 || !ip.Equal(net.IP(data[pos+4:pos+8])) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(name[:15]), "\x00"))
+}
+
+// decodeSSDP liest eine SSDP-Ankündigung (NOTIFY) oder Antwort (HTTP/1.1 200 OK).
+// M-SEARCH-Anfragen verraten nur den Absender.
+func decodeSSDP(data []byte, ip net.IP) *SSDPDevice {
+	text := string(data)
+	if !strings.HasPrefix(text, "NOTIFY ") && !strings.HasPrefix(text, "HTTP/1.1 200") {
+		return nil
+	}
+	device := parseSSDPResponse(text, ip.String())
+	if device.ST == "" {
+		// NOTIFY meldet den Typ als NT
+		for _, line := range strings.Split(text, "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(key), "NT") {
+				device.ST = strings.TrimSpace(value)
+			}
+		}
+	}
+	return &device
+}
+
+// neighborObservation ordnet eine LLDP/CDP-Ankündigung dem Absender zu
+func neighborObservation(neighbor *Neighbor, src net.HardwareAddr) []Observation {
+	return []Observation{{
+		Protocol: neighbor.Protocol,
+		MAC:      src,
+		IP:       neighbor.ManagementIP.To4(),
+		Hostname: neighbor.SystemName,
+		Neighbor: neighbor,
+	}}
+}
@@ -0,0 +1,328 @@
+package discovery_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+var (
+	hostMAC   = net.HardwareAddr{0x3c, 0x22, 0xfb, 0x10, 0x20, 0x30}
+	switchMAC = net.HardwareAddr{0x00, 0x1b, 0x54, 0xaa, 0xbb, 0xcc}
+)
+
+// ethFrame baut einen Ethernet-Frame (an Broadcast)
+func ethFrame(src net.HardwareAddr, etherType uint16, payload []byte) []byte {
+	frame := append([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, src...)
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	return append(frame, payload...)
+}
+
+// udpFrame baut einen IPv4/UDP-Frame (Prüfsummen werden nicht ausgewertet)
+func udpFrame(src net.HardwareAddr, srcIP, dstIP string, srcPort, dstPort uint16, payload []byte) []byte {
+	ip := []byte{0x45, 0, 0, 0, 0, 0, 0, 0, 64, 17, 0, 0}
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(payload)))
+	ip = append(ip, net.ParseIP(srcIP).To4()...)
+	ip = append(ip, net.ParseIP(dstIP).To4()...)
+	ip = binary.BigEndian.AppendUint16(ip, srcPort)
+	ip = binary.BigEndian.AppendUint16(ip, dstPort)
+	ip = binary.BigEndian.AppendUint16(ip, uint16(8+len(payload)))
+	ip = append(ip, 0, 0)
+	return ethFrame(src, 0x0800, append(ip, payload...))
+}
+
+func arpPacket(op uint16, mac net.HardwareAddr, ip string, targetIP string) []byte {
+	packet := []byte{0, 1, 8, 0, 6, 4}
+	packet = binary.BigEndian.AppendUint16(packet, op)
+	packet = append(packet, mac...)
+	packet = append(packet, net.ParseIP(ip).To4()...)
+	packet = append(packet, 0, 0, 0, 0, 0, 0)
+	return append(packet, net.ParseIP(targetIP).To4()...)
+}
+
+func dhcpPacket(op byte, mac net.HardwareAddr, yiaddr string, options ...[]byte) []byte {
+	packet := make([]byte, 240)
+	packet[0], packet[1], packet[2] = op, 1, 6
+	copy(packet[16:], net.ParseIP(yiaddr).To4())
+	copy(packet[28:], mac)
+	binary.BigEndian.PutUint32(packet[236:], 0x63825363)
+	for _, option := range options {
+		packet = append(packet, option...)
+	}
+	return append(packet, 255)
+}
+
+func dhcpOption(code byte, value []byte) []byte {
+	return append([]byte{code, byte(len(value))}, value...)
+}
+
+// netbiosRegistration baut eine Namensregistrierung (RFC 1002, 4.2.2)
+func netbiosRegistration(name string, suffix byte, ip string, group bool) []byte {
+	packet := []byte{0x12, 0x34, 0x29, 0x10, 0, 1, 0, 0, 0, 0, 0, 1, 32}
+	padded := []byte(name + "               ")[:15]
+	for _, b := range append(padded, suffix) {
+		packet = append(packet, 'A'+b>>4, 'A'+b&0x0f)
+	}
+	packet = append(packet, 0, 0, 0x20, 0, 1)                                  // Typ NB, Klasse IN
+	packet = append(packet, 0xc0, 0x0c, 0, 0x20, 0, 1, 0, 0, 0x0e, 0x10, 0, 6) // Zeiger, NB, IN, TTL, Länge
+	flags := uint16(0)
+	if group {
+		flags = 0x8000
+	}
+	packet = binary.BigEndian.AppendUint16(packet, flags)
+	return append(packet, net.ParseIP(ip).To4()...)
+}
+
+func tlv(tlvType int, value ...byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(tlvType<<9|len(value))), value...)
+}
+
+func lldpPayload() []byte {
+	var payload []byte
+	payload = append(payload, tlv(1, append([]byte{4}, switchMAC...)...)...)
+	payload = append(payload, tlv(2, append([]byte{5}, "Gi1/0/12"...)...)...)
+	payload = append(payload, tlv(3, 0, 120)...)
+	payload = append(payload, tlv(4, []byte("Büro 2.13")...)...)
+	payload = append(payload, tlv(5, []byte("core-sw1")...)...)
+	payload = append(payload, tlv(6, []byte("Cisco IOS Software, C2960X")...)...)
+	payload = append(payload, tlv(7, 0x00, 0x14, 0x00, 0x04)...)
+	payload = append(payload, tlv(8, 5, 1, 10, 0, 0, 2, 2, 0, 0, 0, 1, 0)...)
+	payload = append(payload, tlv(127, 0x00, 0x80, 0xc2, 1, 0, 20)...)
+	return append(payload, tlv(0)...)
+}
+
+func cdpTLV(tlvType uint16, value ...byte) []byte {
+	out := binary.BigEndian.AppendUint16(nil, tlvType)
+	out = binary.BigEndian.AppendUint16(out, uint16(4+len(value)))
+	return append(out, value...)
+}
+
+func cdpFrame() []byte {
+	payload := []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00, 2, 180, 0, 0}
+	payload = append(payload, cdpTLV(0x0001, []byte("core-sw2.example.net")...)...)
+	payload = append(payload, cdpTLV(0x0002, 0, 0, 0, 1, 1, 1, 0xcc, 0, 4, 10, 0, 0, 3)...)
+	payload = append(payload, cdpTLV(0x0003, []byte("GigabitEthernet0/1")...)...)
+	payload = append(payload, cdpTLV(0x0004, 0, 0, 0, 0x28)...)
+	payload = append(payload, cdpTLV(0x0005, []byte("Cisco IOS Software, C2960X Software\nTechnical Support")...)...)
+	payload = append(payload, cdpTLV(0x0006, []byte("cisco WS-C2960X-48FPD-L")...)...)
+	payload = append(payload, cdpTLV(0x000a, 0, 10)...)
+
+	frame := append([]byte{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}, switchMAC...)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	return append(append(frame, payload...), 0, 0, 0, 0) // Padding
+}
+
+// pcapFile schreibt Frames im pcap-Format (Mikrosekunden, Little Endian)
+func pcapFile(frames ...discovery.Frame) []byte {
+	out := binary.LittleEndian.AppendUint32(nil, 0xa1b2c3d4)
+	out = append(out, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	out = binary.LittleEndian.AppendUint32(out, 65535)
+	out = binary.LittleEndian.AppendUint32(out, 1)
+	for _, frame := range frames {
+		out = binary.LittleEndian.AppendUint32(out, uint32(frame.Time.Unix()))
+		out = binary.LittleEndian.AppendUint32(out, uint32(frame.Time.Nanosecond()/1000))
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(frame.Data)))
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(frame.Data)))
+		out = append(out, frame.Data...)
+	}
+	return out
+}
+
+var _ = Describe("Passive discovery", func() {
+	at := time.Date(2025, 3, 14, 9, 30, 0, 123000, time.UTC)
+	decode := func(data []byte) []discovery.Observation {
+		return discovery.DecodeFrame(discovery.Frame{Time: at, Data: data})
+	}
+
+	Describe("DecodeFrame", func() {
+		It("should decode ARP senders but ignore probes", func() {
+			observations := decode(ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1")))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("arp"))
+			Expect(observations[0].MAC.String()).To(Equal("3c:22:fb:10:20:30"))
+			Expect(observations[0].IP.String()).To(Equal("192.168.1.23"))
+			Expect(observations[0].Time).To(Equal(at))
+
+			Expect(decode(ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "0.0.0.0", "192.168.1.23")))).To(BeEmpty())
+		})
+
+		It("should decode 802.1Q tagged frames", func() {
+			tagged := append([]byte{0, 20, 0x08, 0x06}, arpPacket(2, hostMAC, "192.168.1.23", "192.168.1.1")...)
+			Expect(decode(ethFrame(hostMAC, 0x8100, tagged))).To(HaveLen(1))
+		})
+
+		It("should decode DHCP hostname, vendor class and address", func() {
+			request := dhcpPacket(1, hostMAC, "0.0.0.0",
+				dhcpOption(53, []byte{3}), dhcpOption(50, []byte{192, 168, 1, 23}),
+				dhcpOption(12, []byte("buero-pc")), dhcpOption(60, []byte("MSFT 5.0")))
+			observations := decode(udpFrame(hostMAC, "0.0.0.0", "255.255.255.255", 68, 67, request))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("dhcp"))
+			Expect(observations[0].IP.String()).To(Equal("192.168.1.23"))
+			Expect(observations[0].Hostname).To(Equal("buero-pc"))
+			Expect(observations[0].VendorClass).To(Equal("MSFT 5.0"))
+
+			// DISCOVER: Name schon bekannt, Adresse noch nicht
+			discover := dhcpPacket(1, hostMAC, "0.0.0.0", dhcpOption(53, []byte{1}), dhcpOption(12, []byte("buero-pc")))
+			observations = decode(udpFrame(hostMAC, "0.0.0.0", "255.255.255.255", 68, 67, discover))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].IP).To(BeNil())
+			Expect(observations[0].MAC.String()).To(Equal("3c:22:fb:10:20:30"))
+
+			// ACK vom Server: Client-MAC aus chaddr, Adresse aus yiaddr
+			ack := dhcpPacket(2, hostMAC, "192.168.1.24", dhcpOption(53, []byte{5}))
+			observations = decode(udpFrame(switchMAC, "192.168.1.1", "192.168.1.24", 67, 68, ack))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].MAC.String()).To(Equal("3c:22:fb:10:20:30"))
+			Expect(observations[0].IP.String()).To(Equal("192.168.1.24"))
+
+			offer := dhcpPacket(2, hostMAC, "192.168.1.24", dhcpOption(53, []byte{2}))
+			Expect(decode(udpFrame(switchMAC, "192.168.1.1", "192.168.1.24", 67, 68, offer))).To(BeEmpty())
+		})
+
+		It("should decode mDNS host names and announced services", func() {
+			printer := []string{"Büro", "_ipp", "_tcp", "local"}
+			announcement := dnsResponse([]dnsRR{
+				{name: []string{"_ipp", "_tcp", "local"}, rtype: 12, data: dnsName(printer...)},
+			}, []dnsRR{
+				{name: printer, rtype: 33, data: srvData(631, "drucker", "local")},
+				{name: printer, rtype: 16, data: txtData("ty=HP LaserJet Pro M404dn")},
+				{name: []string{"drucker", "local"}, rtype: 1, data: []byte{192, 168, 1, 50}},
+			})
+			observations := decode(udpFrame(hostMAC, "192.168.1.50", "224.0.0.251", 5353, 5353, announcement))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("mdns"))
+			Expect(observations[0].Hostname).To(Equal("drucker"))
+			Expect(observations[0].Services).To(HaveLen(1))
+			Expect(observations[0].Services[0].Model()).To(Equal("HP LaserJet Pro M404dn"))
+
+			// Anfragen verraten nur den Absender
+			query := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+			observations = decode(udpFrame(hostMAC, "192.168.1.60", "224.0.0.251", 5353, 5353, query))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Hostname).To(BeEmpty())
+			Expect(observations[0].IP.String()).To(Equal("192.168.1.60"))
+		})
+
+		It("should decode NetBIOS name registrations", func() {
+			registration := netbiosRegistration("BUERO-PC", 0x00, "192.168.1.23", false)
+			observations := decode(udpFrame(hostMAC, "192.168.1.23", "192.168.1.255", 137, 137, registration))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("netbios"))
+			Expect(observations[0].Hostname).To(Equal("BUERO-PC"))
+
+			// Gruppennamen (Arbeitsgruppe) und Dienst-Suffixe sind keine Rechnernamen
+			group := netbiosRegistration("WORKGROUP", 0x00, "192.168.1.23", true)
+			Expect(decode(udpFrame(hostMAC, "192.168.1.23", "192.168.1.255", 137, 137, group))[0].Hostname).To(BeEmpty())
+			browser := netbiosRegistration("BUERO-PC", 0x1d, "192.168.1.23", false)
+			Expect(decode(udpFrame(hostMAC, "192.168.1.23", "192.168.1.255", 137, 137, browser))[0].Hostname).To(BeEmpty())
+		})
+
+		It("should decode LLMNR responses", func() {
+			response := dnsResponse([]dnsRR{{name: []string{"buero-pc"}, rtype: 1, data: []byte{192, 168, 1, 23}}}, nil)
+			observations := decode(udpFrame(hostMAC, "192.168.1.23", "192.168.1.30", 5355, 50123, response))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("llmnr"))
+			Expect(observations[0].Hostname).To(Equal("buero-pc"))
+		})
+
+		It("should decode SSDP announcements", func() {
+			notify := "NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNT: urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+				"NTS: ssdp:alive\r\nLOCATION: http://192.168.1.40:1400/xml/device_description.xml\r\nSERVER: Linux UPnP/1.0 Sonos/70.3\r\n\r\n"
+			observations := decode(udpFrame(hostMAC, "192.168.1.40", "239.255.255.250", 1900, 1900, []byte(notify)))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].SSDP).NotTo(BeNil())
+			Expect(observations[0].SSDP.IP).To(Equal("192.168.1.40"))
+			Expect(observations[0].SSDP.Location).To(Equal("http://192.168.1.40:1400/xml/device_description.xml"))
+			Expect(observations[0].SSDP.ST).To(Equal("urn:schemas-upnp-org:device:MediaRenderer:1"))
+		})
+
+		It("should decode LLDP and CDP announcements", func() {
+			observations := decode(ethFrame(switchMAC, 0x88cc, lldpPayload()))
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].Protocol).To(Equal("lldp"))
+			Expect(observations[0].IP.String()).To(Equal("10.0.0.2"))
+			Expect(observations[0].Hostname).To(Equal("core-sw1"))
+			Expect(*observations[0].Neighbor).To(Equal(discovery.Neighbor{
+				Protocol: "lldp", ChassisID: "00:1b:54:aa:bb:cc", PortID: "Gi1/0/12", PortDescription: "Büro 2.13",
+				SystemName: "core-sw1", SystemDescription: "Cisco IOS Software, C2960X",
+				ManagementIP: net.IP{10, 0, 0, 2}, VLAN: 20, Capabilities: []string{"bridge"},
+			}))
+			Expect(observations[0].Neighbor.String()).To(Equal("core-sw1 Gi1/0/12 (Büro 2.13) VLAN 20 (10.0.0.2)"))
+
+			observations = decode(cdpFrame())
+			Expect(observations).To(HaveLen(1))
+			neighbor := observations[0].Neighbor
+			Expect(neighbor.Protocol).To(Equal("cdp"))
+			Expect(neighbor.SystemName).To(Equal("core-sw2.example.net"))
+			Expect(neighbor.PortID).To(Equal("GigabitEthernet0/1"))
+			Expect(neighbor.ManagementIP.String()).To(Equal("10.0.0.3"))
+			Expect(neighbor.VLAN).To(Equal(10))
+			Expect(neighbor.Capabilities).To(Equal([]string{"bridge", "igmp"}))
+			Expect(neighbor.SystemDescription).To(Equal("cisco WS-C2960X-48FPD-L Cisco IOS Software, C2960X Software"))
+		})
+
+		It("should ignore truncated and unrelated frames", func() {
+			Expect(decode(nil)).To(BeEmpty())
+			Expect(decode(ethFrame(hostMAC, 0x86dd, make([]byte, 40)))).To(BeEmpty())
+			Expect(decode(ethFrame(switchMAC, 0x88cc, lldpPayload()[:15]))).To(BeEmpty())
+			frame := udpFrame(hostMAC, "192.168.1.23", "192.168.1.255", 137, 137, netbiosRegistration("BUERO-PC", 0, "192.168.1.23", false))
+			Expect(decode(frame[:60])).To(BeEmpty())
+		})
+	})
+
+	Describe("PcapReader", func() {
+		It("should read frames with their capture time", func() {
+			arp := ethFrame(hostMAC, 0x0806, arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1"))
+			file := pcapFile(discovery.Frame{Time: at, Data: arp}, discovery.Frame{Time: at.Add(time.Second), Data: cdpFrame()})
+
+			reader, err := discovery.NewPcapReader(bytes.NewReader(file))
+			Expect(err).NotTo(HaveOccurred())
+			frame, err := reader.NextFrame()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(frame.Time.Equal(at)).To(BeTrue())
+			Expect(frame.Data).To(Equal(arp))
+			frame, err = reader.NextFrame()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(frame.Time.Equal(at.Add(time.Second))).To(BeTrue())
+			_, err = reader.NextFrame()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("should convert Linux cooked captures to Ethernet frames", func() {
+			arp := arpPacket(1, hostMAC, "192.168.1.23", "192.168.1.1")
+			cooked := append([]byte{0, 1, 0, 1, 0, 6}, hostMAC...)
+			cooked = append(cooked, 0, 0, 0x08, 0x06)
+			file := pcapFile(discovery.Frame{Time: at, Data: append(cooked, arp...)})
+			binary.LittleEndian.PutUint32(file[20:], 113)
+
+			reader, err := discovery.NewPcapReader(bytes.NewReader(file))
+			Expect(err).NotTo(HaveOccurred())
+			frame, err := reader.NextFrame()
+			Expect(err).NotTo(HaveOccurred())
+			observations := discovery.DecodeFrame(frame)
+			Expect(observations).To(HaveLen(1))
+			Expect(observations[0].IP.String()).To(Equal("192.168.1.23"))
+		})
+
+		It("should reject pcapng and other link types", func() {
+			_, err := discovery.NewPcapReader(bytes.NewReader([]byte{0x0a, 0x0d, 0x0d, 0x0a, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+			Expect(err).To(MatchError(ContainSubstring("pcapng")))
+
+			file := pcapFile()
+			binary.LittleEndian.PutUint32(file[20:], 105) // 802.11
+			_, err = discovery.NewPcapReader(bytes.NewReader(file))
+			Expect(err).To(MatchError(ContainSubstring("link type 105")))
+
+			_, err = discovery.NewPcapReader(bytes.NewReader([]byte("not a capture")))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	pcapMagicMicro  = 0xa1b2c3d4
+	pcapMagicNano   = 0xa1b23c4d
+	pcapngMagic     = 0x0a0d0d0a
+	pcapHeaderLen   = 24
+	pcapRecordLen   = 16
+	pcapMaxSnapLen  = 262144
+	linkTypeEther   = 1
+	linkTypeLinuxSL = 113 // Linux "cooked" capture (tcpdump -i any)
+)
+
+// Frame ist ein mitgeschnittener Ethernet-Frame mit Empfangszeit
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// FrameSource liefert mitgeschnittene Frames - live von einem Interface
+// (OpenCapture) oder aus einer pcap-Datei (OpenPcap). NextFrame gibt am Ende
+// der Datei bzw. nach Close io.EOF zurück.
+type FrameSource interface {
+	NextFrame() (Frame, error)
+	Close() error
+}
+
+// PcapReader liest Frames aus einer pcap-Datei (libpcap-Format, wie von
+// "tcpdump -w" geschrieben). pcapng wird nicht unterstützt.
+type PcapReader struct {
+	r        *bufio.Reader
+	closer   io.Closer
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+}
+
+// OpenPcap öffnet eine pcap-Datei
+func OpenPcap(path string) (*PcapReader, error) {
+	file, err := os.Open(path) // #nosec G304 -- Pfad stammt vom Benutzer (--pcap)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewPcapReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	reader.closer = file
+	return reader, nil
+}
+
+// NewPcapReader liest den Datei-Header und prüft Format und Link-Typ
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	p := &PcapReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, pcapHeaderLen)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return nil, fmt.Errorf("invalid pcap file: %w", err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicro:
+		p.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicro:
+		p.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNano:
+		p.order, p.nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == pcapMagicNano:
+		p.order, p.nano = binary.BigEndian, true
+	case binary.BigEndian.Uint32(header) == pcapngMagic:
+		return nil, errors.New("pcapng is not supported (convert with: editcap -F pcap in.pcapng out.pcap)")
+	default:
+		return nil, errors.New("invalid pcap file: unknown magic number")
+	}
+
+	p.linkType = p.order.Uint32(header[20:]) & 0x0fffffff
+	if p.linkType != linkTypeEther && p.linkType != linkTypeLinuxSL {
+		return nil, fmt.Errorf("unsupported pcap link type %d (only Ethernet and Linux cooked captures)", p.linkType)
+	}
+	return p, nil
+}
+
+// NextFrame liest den nächsten Frame. Linux-cooked-Frames werden in Ethernet-Frames
+// umgesetzt (Ziel-MAC ist dann unbekannt und wird als Broadcast eingetragen).
+func (p *PcapReader) NextFrame() (Frame, error) {
+	record := make([]byte, pcapRecordLen)
+	if _, err := io.ReadFull(p.r, record); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Frame{}, fmt.Errorf("truncated pcap record: %w", err)
+		}
+		return Frame{}, err
+	}
+	seconds := int64(p.order.Uint32(record))
+	fraction := int64(p.order.Uint32(record[4:]))
+	captured := p.order.Uint32(record[8:])
+	if captured > pcapMaxSnapLen {
+		return Frame{}, fmt.Errorf("invalid pcap record length %d", captured)
+	}
+
+	data := make([]byte, captured)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return Frame{}, fmt.Errorf("truncated pcap record: %w", err)
+	}
+
+	if !p.nano {
+		fraction *= int64(time.Microsecond)
+	}
+	frame := Frame{Time: time.Unix(seconds, fraction), Data: data}
+	if p.linkType == linkTypeLinuxSL {
+		frame.Data = cookedToEthernet(data)
+	}
+	return frame, nil
+}
+
+// Close schließt die Datei (falls mit OpenPcap geöffnet)
+func (p *PcapReader) Close() error {
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+	return nil
+}
+
+// cookedToEthernet setzt einen Linux-cooked-Frame (SLL, 16 Byte Header) in einen
+// Ethernet-Frame um: Absender-Adresse und Protokoll bleiben erhalten
+func cookedToEthernet(data []byte) []byte {
+	if len(data) < 16 {
+		return nil
+	}
+	frame := make([]byte, ethHeaderLen, ethHeaderLen+len(data)-16)
+	copy(frame[0:6], broadcastMAC)
+	if binary.BigEndian.Uint16(data[4:]) == 6 {
+		copy(frame[6:12], data[6:12])
+	}
+	copy(frame[12:14], data[14:16])
+	return append(frame, data[16:]...)
+}
+
+// captureSource ist eine FrameSource auf Basis einer FrameConn (Live-Mitschnitt)
+type captureSource struct {
+	conn FrameConn
+	buf  []byte
+}
+
+// OpenCapture startet einen Live-Mitschnitt aller Frames auf dem Interface
+// (AF_PACKET, nur Linux, benötigt CAP_NET_RAW). Es werden keine Pakete gesendet.
+func OpenCapture(iface *net.Interface) (FrameSource, error) {
+	conn, err := OpenCaptureConn(iface)
+	if err != nil {
+		return nil, err
+	}
+	return &captureSource{conn: conn, buf: make([]byte, 65536)}, nil
+}
+
+// OpenNeighborCapture startet einen Live-Mitschnitt, der nur LLDP- und CDP-Frames
+// liefert (AF_PACKET mit Filter, nur Linux, benötigt CAP_NET_RAW)
+func OpenNeighborCapture(iface *net.Interface) (FrameSource, error) {
+	conn, err := OpenNeighborConn(iface)
+	if err != nil {
+		return nil, err
+	}
+	return &captureSource{conn: conn, buf: make([]byte, 65536)}, nil
+}
+
+func (c *captureSource) NextFrame() (Frame, error) {
+	n, err := c.conn.ReadFrame(c.buf)
+	if err != nil {
+		if errors.Is(err, os.ErrClosed) {
+			return Frame{}, io.EOF
+		}
+		return Frame{}, err
+	}
+	return Frame{Time: time.Now(), Data: append([]byte(nil), c.buf[:n]...)}, nil
+}
+
+func (c *captureSource) Close() error {
+	return c.conn.Close()
+}
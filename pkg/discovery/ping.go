@@ -120,18 +120,38 @@ func incrementIP(ip net.IP, offset int) net.IP {
 	return result
 }
 
+// maxIPv6HostBits begrenzt die Aufzählung von IPv6-Präfixen (/112 = 65536 Adressen).
+// Größere Präfixe (z.B. /64) werden per Neighbor Discovery statt per Sweep erkundet.
+const maxIPv6HostBits = 16
+
 // GenerateIPsFromCIDR generates IP addresses
+// IPv4: all usable host addresses (without network and broadcast address)
+// IPv6: all addresses except the subnet-router anycast address, only up to /112
 func GenerateIPsFromCIDR(network *net.IPNet) []net.IP {
 	ip := network.IP.Mask(network.Mask)
 	ones, bits := network.Mask.Size()
 	hostBits := bits - ones
-	numHosts := 1 << hostBits
 
-	// Handle /32 edge case (single host)
+	// Handle /32 and /128 edge case (single host)
 	if ones == 32 || ones == 128 {
 		return []net.IP{ip}
 	}
 
+	// IPv6 hat keine Broadcast-Adresse; zu große Präfixe nicht aufzählen
+	if bits == 128 {
+		if hostBits > maxIPv6HostBits {
+			return nil
+		}
+		numHosts := 1 << hostBits
+		ips := make([]net.IP, 0, numHosts-1)
+		for i := 1; i < numHosts; i++ {
+			ips = append(ips, incrementIP(ip, i))
+		}
+		return ips
+	}
+
+	numHosts := 1 << hostBits
+
 	// Handle /31 edge case (point-to-point)
 	if numHosts == 2 {
 		return []net.IP{
@@ -140,23 +160,9 @@ func GenerateIPsFromCIDR(network *net.IPNet) []net.IP {
 		}
 	}
 
-	maxHosts := numHosts - 2
-	ips := make([]net.IP, 0, maxHosts)
-
-	// Hinweis entfernt - stört tview-Modus
-	_ = maxHosts // Compiler-Warnung vermeiden
-
+	ips := make([]net.IP, 0, numHosts-2)
 	for i := 1; i < numHosts-1; i++ {
-		currentIP := make(net.IP, len(ip))
-		copy(currentIP, ip)
-
-		for j := len(currentIP) - 1; j >= 0; j-- {
-			currentIP[j] += byte(i >> (8 * (len(currentIP) - 1 - j)))
-			if currentIP[j] != 0 {
-				break
-			}
-		}
-
+		currentIP := incrementIP(ip, i)
 		if network.Contains(currentIP) {
 			ips = append(ips, currentIP)
 		}
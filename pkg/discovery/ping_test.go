@@ -66,6 +66,22 @@ var _ = Describe("Ping", func() {
 			})
 		})
 
+		Context("with IPv6 prefix", func() {
+			It("should generate all addresses of a small prefix except the anycast address", func() {
+				_, network, _ := net.ParseCIDR("2001:db8::/125")
+				ips := discovery.GenerateIPsFromCIDR(network)
+
+				Expect(ips).To(HaveLen(7))
+				Expect(ips[0].String()).To(Equal("2001:db8::1"))
+				Expect(ips[6].String()).To(Equal("2001:db8::7"))
+			})
+
+			It("should not enumerate a /64", func() {
+				_, network, _ := net.ParseCIDR("2001:db8::/64")
+				Expect(discovery.GenerateIPsFromCIDR(network)).To(BeEmpty())
+			})
+		})
+
 		Context("with single host /32", func() {
 			It("should generate single IP", func() {
 				_, network, _ := net.ParseCIDR("192.168.1.1/32")
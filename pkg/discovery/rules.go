@@ -0,0 +1,492 @@
+package discovery
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultRulesYAML enthält die eingebauten Klassifizierungsregeln
+//
+//go:embed default_rules.yaml
+var defaultRulesYAML []byte
+
+// maxRuleWeight ist das höchste Gewicht einer Regel (entspricht 100 % Sicherheit)
+const maxRuleWeight = 100
+
+// ruleDetectors sind die eingebauten Auswertungen der Selbstauskunft eines Geräts,
+// die Regeln über "detector" einbinden können
+var ruleDetectors = map[string]func(DeviceFacts) (string, string){
+	"snmp": func(f DeviceFacts) (string, string) {
+		return DetectDeviceTypeSNMP(f.SNMPDescr, f.SNMPObjectID), "SNMP " + firstNonEmpty(f.SNMPDescr, f.SNMPObjectID)
+	},
+	"lldp": func(f DeviceFacts) (string, string) {
+		if f.Neighbor == nil {
+			return DeviceTypeUnknown, ""
+		}
+		return DetectDeviceTypeNeighbor(f.Neighbor), strings.ToUpper(f.Neighbor.Protocol) + " capabilities " + strings.Join(f.Neighbor.Capabilities, ", ")
+	},
+	"dnssd": func(f DeviceFacts) (string, string) {
+		types := make([]string, 0, len(f.DNSSD))
+		for _, svc := range f.DNSSD {
+			if model := svc.Model(); model != "" {
+				types = append(types, "model "+model)
+			}
+			types = append(types, svc.Type)
+		}
+		return DetectDeviceTypeDNSSD(f.DNSSD), "DNS-SD " + strings.Join(uniqueStrings(types), ", ")
+	},
+	"upnp": func(f DeviceFacts) (string, string) {
+		if f.UPnP == nil {
+			return DeviceTypeUnknown, ""
+		}
+		return DetectDeviceTypeUPnP(f.UPnP), "UPnP " + f.UPnP.String()
+	},
+	"dhcp": func(f DeviceFacts) (string, string) {
+		return DetectDeviceTypeDHCP(f.DHCPVendor), "DHCP vendor class " + f.DHCPVendor
+	},
+}
+
+// DeviceFacts sind die Merkmale eines Geräts, die Klassifizierungsregeln auswerten
+type DeviceFacts struct {
+	Hostname     string
+	MAC          string
+	Vendor       string
+	Ports        []int
+	DNSSD        []DNSSDService
+	UPnP         *UPnPDevice
+	HTTPTitle    string
+	SNMPDescr    string
+	SNMPObjectID string
+	Neighbor     *Neighbor
+	DHCPVendor   string
+	OS           string // Betriebssystem-Familie (siehe GuessOS), leer wenn unsicher
+}
+
+// Classification ist das Ergebnis der Regelauswertung
+type Classification struct {
+	Type       string      `json:"type"`
+	Confidence int         `json:"confidence"`      // 0-100
+	Matches    []RuleMatch `json:"rules,omitempty"` // ausgelöste Regeln in Auswertungsreihenfolge
+}
+
+// RuleMatch ist eine ausgelöste Regel
+type RuleMatch struct {
+	Rule   string `json:"rule"`
+	Type   string `json:"type"`
+	Weight int    `json:"weight"`
+	Reason string `json:"reason"` // z.B. `hostname "iPhone-von-Max" ~ "iPhone"`
+}
+
+// Rule ist eine Klassifizierungsregel. Alle angegebenen Bedingungen müssen zutreffen.
+// Bei Regeln einer Gruppe zählt nur die erste zutreffende (z.B. eine Hostname-Regel).
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type,omitempty"`     // Gerätetyp (entfällt bei detector)
+	Weight   int      `yaml:"weight"`             // 1-100
+	Group    string   `yaml:"group,omitempty"`    // nur die erste zutreffende Regel der Gruppe zählt
+	Detector string   `yaml:"detector,omitempty"` // eingebaute Auswertung: snmp, lldp, dnssd, upnp, dhcp
+	When     RuleWhen `yaml:"match,omitempty"`
+}
+
+// RuleWhen sind die Bedingungen einer Regel. Texte sind reguläre Ausdrücke
+// (Groß-/Kleinschreibung egal), Listen treffen zu, wenn ein Eintrag passt.
+type RuleWhen struct {
+	Hostname            string   `yaml:"hostname,omitempty"`
+	Vendor              string   `yaml:"vendor,omitempty"`
+	OUI                 []string `yaml:"oui,omitempty"`       // MAC-Präfixe mit 6 bis 12 Hex-Ziffern
+	Ports               []int    `yaml:"ports,omitempty"`     // mindestens einer offen
+	AllPorts            []int    `yaml:"all_ports,omitempty"` // alle offen
+	Services            []string `yaml:"services,omitempty"`  // DNS-SD-Diensttypen, z.B. _ipp._tcp
+	SSDPModel           string   `yaml:"ssdp_model,omitempty"`
+	HTTPTitle           string   `yaml:"http_title,omitempty"`
+	OS                  string   `yaml:"os,omitempty"` // Betriebssystem-Familie, z.B. windows
+	LocallyAdministered *bool    `yaml:"locally_administered,omitempty"`
+}
+
+// Ruleset ist ein Satz geprüfter Regeln in Auswertungsreihenfolge
+type Ruleset struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	hostname, vendor, ssdpModel, httpTitle, os *regexp.Regexp
+	oui                                        []string // Hex-Ziffern
+}
+
+// rulesFile ist der Aufbau einer Regeldatei
+type rulesFile struct {
+	Defaults *bool  `yaml:"defaults"` // false = eingebaute Regeln nicht laden
+	Rules    []Rule `yaml:"rules"`
+}
+
+// ParseRules liest Regeln im YAML-Format ("rules: [...]")
+func ParseRules(data []byte) (*Ruleset, error) {
+	file, err := decodeRulesFile(data)
+	if err != nil {
+		return nil, err
+	}
+	return compileRules(file.Rules)
+}
+
+// LoadRules lädt eine Regeldatei. Ihre Regeln werden vor den eingebauten ausgewertet
+// (bei Gleichstand gewinnen sie); mit "defaults: false" ersetzen sie diese.
+func LoadRules(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Pfad aus Konfiguration
+	if err != nil {
+		return nil, err
+	}
+	file, err := decodeRulesFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	rules := file.Rules
+	if file.Defaults == nil || *file.Defaults {
+		defaults, _ := decodeRulesFile(defaultRulesYAML)
+		rules = append(rules, defaults.Rules...)
+	}
+	ruleset, err := compileRules(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ruleset, nil
+}
+
+// DefaultRulesYAML gibt die eingebauten Regeln im YAML-Format zurück
+// (Vorlage für eigene Regeldateien)
+func DefaultRulesYAML() []byte {
+	return bytes.Clone(defaultRulesYAML)
+}
+
+func decodeRulesFile(data []byte) (*rulesFile, error) {
+	var file rulesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func compileRules(rules []Rule) (*Ruleset, error) {
+	ruleset := &Ruleset{rules: make([]compiledRule, 0, len(rules))}
+	names := make(map[string]bool)
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d: missing name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %q: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule.Name, err)
+		}
+		ruleset.rules = append(ruleset.rules, compiled)
+	}
+	return ruleset, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	compiled := compiledRule{Rule: rule}
+	when := rule.When
+
+	if rule.Weight < 1 || rule.Weight > maxRuleWeight {
+		return compiled, fmt.Errorf("weight must be between 1 and %d", maxRuleWeight)
+	}
+	if rule.Detector != "" {
+		if _, ok := ruleDetectors[rule.Detector]; !ok {
+			return compiled, fmt.Errorf("unknown detector %q (use snmp, lldp, dnssd, upnp, dhcp)", rule.Detector)
+		}
+		return compiled, nil
+	}
+	if rule.Type == "" {
+		return compiled, errors.New("missing type")
+	}
+
+	var err error
+	for _, field := range []struct {
+		pattern string
+		target  **regexp.Regexp
+	}{
+		{when.Hostname, &compiled.hostname},
+		{when.Vendor, &compiled.vendor},
+		{when.SSDPModel, &compiled.ssdpModel},
+		{when.HTTPTitle, &compiled.httpTitle},
+		{when.OS, &compiled.os},
+	} {
+		if field.pattern == "" {
+			continue
+		}
+		if *field.target, err = regexp.Compile("(?i)" + field.pattern); err != nil {
+			return compiled, fmt.Errorf("invalid pattern %q: %v", field.pattern, err)
+		}
+	}
+	for _, prefix := range when.OUI {
+		digits := macHexDigits(prefix)
+		if len(digits) < 6 || len(digits) > 12 {
+			return compiled, fmt.Errorf("invalid OUI %q", prefix)
+		}
+		compiled.oui = append(compiled.oui, digits)
+	}
+
+	if compiled.hostname == nil && compiled.vendor == nil && compiled.ssdpModel == nil && compiled.httpTitle == nil && compiled.os == nil &&
+		len(compiled.oui) == 0 && len(when.Ports) == 0 && len(when.AllPorts) == 0 && len(when.Services) == 0 &&
+		when.LocallyAdministered == nil {
+		return compiled, errors.New("no match conditions")
+	}
+	return compiled, nil
+}
+
+// Len gibt die Anzahl der Regeln zurück
+func (rs *Ruleset) Len() int {
+	return len(rs.rules)
+}
+
+// Classify wertet alle Regeln aus. Jeder Gerätetyp sammelt die Gewichte seiner Regeln
+// und die seines Grundtyps ("Network Equipment" stützt "Network Equipment (Switch)");
+// der Typ mit der höchsten Summe gewinnt, bei Gleichstand der zuerst ausgelöste.
+// Die Sicherheit sinkt mit widersprechenden Regeln: Summe (max. 100) × Summe / alle Gewichte.
+func (rs *Ruleset) Classify(facts DeviceFacts) Classification {
+	var matches []RuleMatch
+	groups := make(map[string]bool)
+	for i := range rs.rules {
+		rule := &rs.rules[i]
+		if rule.Group != "" && groups[rule.Group] {
+			continue
+		}
+		deviceType, reason, ok := rule.evaluate(facts)
+		if !ok {
+			continue
+		}
+		if rule.Group != "" {
+			groups[rule.Group] = true
+		}
+		matches = append(matches, RuleMatch{Rule: rule.Name, Type: deviceType, Weight: rule.Weight, Reason: reason})
+	}
+	if len(matches) == 0 {
+		return Classification{Type: DeviceTypeUnknown}
+	}
+
+	total := 0
+	for _, match := range matches {
+		total += match.Weight
+	}
+
+	best, bestScore := "", 0
+	for _, candidate := range matches {
+		base, _, _ := strings.Cut(candidate.Type, " (")
+		score := 0
+		for _, match := range matches {
+			if match.Type == candidate.Type || (match.Type == base && base != candidate.Type) {
+				score += match.Weight
+			}
+		}
+		if score > bestScore {
+			best, bestScore = candidate.Type, score
+		}
+	}
+
+	return Classification{
+		Type:       best,
+		Confidence: (min(bestScore, maxRuleWeight)*bestScore + total/2) / total,
+		Matches:    matches,
+	}
+}
+
+// evaluate prüft eine Regel und gibt Gerätetyp und Begründung zurück
+func (r *compiledRule) evaluate(facts DeviceFacts) (string, string, bool) {
+	if r.Detector != "" {
+		deviceType, reason := ruleDetectors[r.Detector](facts)
+		return deviceType, reason, deviceType != DeviceTypeUnknown
+	}
+
+	var reasons []string
+	matchText := func(re *regexp.Regexp, field, value string) bool {
+		if re == nil {
+			return true
+		}
+		if !re.MatchString(value) {
+			return false
+		}
+		if value == "" {
+			reasons = append(reasons, "no "+field)
+		} else {
+			reasons = append(reasons, field+" "+strconv.Quote(value)+" ~ "+strconv.Quote(re.FindString(value)))
+		}
+		return true
+	}
+
+	var model string
+	if facts.UPnP != nil {
+		model = strings.TrimSpace(facts.UPnP.Manufacturer + " " + facts.UPnP.Model())
+	}
+	if !matchText(r.hostname, "hostname", facts.Hostname) || !matchText(r.vendor, "vendor", facts.Vendor) ||
+		!matchText(r.ssdpModel, "SSDP model", model) || !matchText(r.httpTitle, "HTTP title", facts.HTTPTitle) ||
+		!matchText(r.os, "OS", facts.OS) {
+		return "", "", false
+	}
+
+	if len(r.oui) > 0 {
+		digits := macHexDigits(facts.MAC)
+		prefix := ""
+		for _, oui := range r.oui {
+			if strings.HasPrefix(digits, oui) {
+				prefix = oui
+				break
+			}
+		}
+		if prefix == "" {
+			return "", "", false
+		}
+		formatted, _ := formatVendorPrefix(prefix)
+		reasons = append(reasons, "OUI "+formatted)
+	}
+
+	if la := r.When.LocallyAdministered; la != nil {
+		if facts.MAC == "" || isLocallyAdministeredMAC(facts.MAC) != *la {
+			return "", "", false
+		}
+		if *la {
+			reasons = append(reasons, "locally administered MAC")
+		} else {
+			reasons = append(reasons, "globally unique MAC")
+		}
+	}
+
+	if len(r.When.Ports) > 0 {
+		open := intersectPorts(facts.Ports, r.When.Ports)
+		if len(open) == 0 {
+			return "", "", false
+		}
+		reasons = append(reasons, "port "+joinInts(open))
+	}
+	if len(r.When.AllPorts) > 0 {
+		if len(intersectPorts(facts.Ports, r.When.AllPorts)) != len(uniqueInts(r.When.AllPorts)) {
+			return "", "", false
+		}
+		reasons = append(reasons, "ports "+joinInts(r.When.AllPorts))
+	}
+
+	if len(r.When.Services) > 0 {
+		var found []string
+		for _, svc := range facts.DNSSD {
+			for _, serviceType := range r.When.Services {
+				if strings.EqualFold(svc.Type, serviceType) {
+					found = append(found, svc.Type)
+				}
+			}
+		}
+		if len(found) == 0 {
+			return "", "", false
+		}
+		reasons = append(reasons, "service "+strings.Join(uniqueStrings(found), ", "))
+	}
+
+	return r.Type, strings.Join(reasons, ", "), true
+}
+
+// intersectPorts gibt die Ports aus wanted zurück, die in open enthalten sind
+func intersectPorts(open, wanted []int) []int {
+	var found []int
+	for _, port := range uniqueInts(wanted) {
+		for _, p := range open {
+			if p == port {
+				found = append(found, port)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	var unique []int
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Aktiver Regelsatz (siehe SetRules)
+var (
+	activeRules    *Ruleset
+	activeRulesMux sync.RWMutex
+	defaultRules   = sync.OnceValue(func() *Ruleset {
+		ruleset, err := ParseRules(defaultRulesYAML)
+		if err != nil {
+			panic("invalid builtin rules: " + err.Error())
+		}
+		return ruleset
+	})
+)
+
+// DefaultRules gibt die eingebauten Regeln zurück
+func DefaultRules() *Ruleset {
+	return defaultRules()
+}
+
+// SetRules setzt den Regelsatz für Classify (nil = eingebaute Regeln)
+func SetRules(ruleset *Ruleset) {
+	activeRulesMux.Lock()
+	defer activeRulesMux.Unlock()
+	activeRules = ruleset
+}
+
+// Classify bestimmt Gerätetyp und Sicherheit mit dem aktiven Regelsatz
+func Classify(facts DeviceFacts) Classification {
+	activeRulesMux.RLock()
+	ruleset := activeRules
+	activeRulesMux.RUnlock()
+	if ruleset == nil {
+		ruleset = DefaultRules()
+	}
+	return ruleset.Classify(facts)
+}
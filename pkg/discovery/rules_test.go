@@ -0,0 +1,149 @@
+package discovery_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+var _ = Describe("Classification rules", func() {
+	Describe("builtin rules", func() {
+		It("should only fall back to privacy mode without other evidence", func() {
+			c := discovery.Classify(discovery.DeviceFacts{MAC: "da:a1:19:00:00:01"})
+			Expect(c.Type).To(Equal("Smartphone (Privacy)"))
+			Expect(c.Confidence).To(Equal(10))
+
+			c = discovery.Classify(discovery.DeviceFacts{Hostname: "DESKTOP-4711", MAC: "da:a1:19:00:00:01"})
+			Expect(c.Type).To(Equal("Computer"))
+			Expect(c.Matches).To(HaveLen(2))
+			Expect(c.Confidence).To(BeNumerically("<", 50))
+		})
+
+		It("should let base types support their subtypes", func() {
+			c := discovery.Classify(discovery.DeviceFacts{
+				Vendor:   "Cisco",
+				Neighbor: &discovery.Neighbor{Protocol: "lldp", Capabilities: []string{"bridge"}},
+			})
+			Expect(c.Type).To(Equal("Network Equipment (Switch)"))
+			Expect(c.Confidence).To(Equal(100))
+			Expect(c.Matches).To(Equal([]discovery.RuleMatch{
+				{Rule: "lldp", Type: "Network Equipment (Switch)", Weight: 90, Reason: "LLDP capabilities bridge"},
+				{Rule: "vendor-network", Type: "Network Equipment", Weight: 30, Reason: `vendor "Cisco" ~ "Cisco"`},
+			}))
+		})
+
+		It("should lower the confidence for contradicting rules", func() {
+			c := discovery.Classify(discovery.DeviceFacts{Hostname: "iPhone-von-Max", Vendor: "Apple"})
+			Expect(c.Type).To(Equal("Smartphone"))
+			Expect(c.Confidence).To(Equal(31)) // 50 × 50 / 80
+			Expect(c.Matches[0].Reason).To(Equal(`hostname "iPhone-von-Max" ~ "iPhone"`))
+		})
+
+		It("should count only the first matching rule of a group", func() {
+			c := discovery.Classify(discovery.DeviceFacts{Hostname: "samsung-nas", Ports: []int{22, 80}})
+			Expect(c.Type).To(Equal("Smartphone"))
+			Expect(c.Matches).To(HaveLen(2))
+			Expect(c.Matches[1].Rule).To(Equal("ports-ssh-web"))
+			Expect(c.Matches[1].Reason).To(Equal("port 80, ports 22"))
+		})
+
+		It("should use the OS family", func() {
+			c := discovery.Classify(discovery.DeviceFacts{OS: discovery.OSWindows, Ports: []int{445}})
+			Expect(c.Type).To(Equal("Windows Computer"))
+			Expect(c.Confidence).To(Equal(45))
+			Expect(c.Matches[1]).To(Equal(discovery.RuleMatch{
+				Rule: "os-windows", Type: "Windows Computer", Weight: 25, Reason: `OS "Windows" ~ "Windows"`,
+			}))
+		})
+
+		It("should return Unknown without evidence", func() {
+			c := discovery.Classify(discovery.DeviceFacts{MAC: "00:11:22:33:44:55"})
+			Expect(c.Type).To(Equal("Unknown"))
+			Expect(c.Confidence).To(BeZero())
+			Expect(c.Matches).To(BeEmpty())
+		})
+
+		It("should ship as YAML", func() {
+			ruleset, err := discovery.ParseRules(discovery.DefaultRulesYAML())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ruleset.Len()).To(Equal(discovery.DefaultRules().Len()))
+		})
+	})
+
+	Describe("custom rules", func() {
+		writeRules := func(content string) string {
+			path := filepath.Join(GinkgoT().TempDir(), "rules.yaml")
+			Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+			return path
+		}
+
+		AfterEach(func() {
+			discovery.SetRules(nil)
+		})
+
+		It("should evaluate custom rules before the builtin rules", func() {
+			ruleset, err := discovery.LoadRules(writeRules(`
+rules:
+  - name: lab-sensors
+    type: IoT Device (Sensor)
+    weight: 60
+    match:
+      oui: ["f8:b5:68:d"]
+      http_title: sensor
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ruleset.Len()).To(BeNumerically(">", discovery.DefaultRules().Len()))
+			discovery.SetRules(ruleset)
+
+			c := discovery.Classify(discovery.DeviceFacts{MAC: "f8:b5:68:d1:00:01", HTTPTitle: "Sensor Dashboard", Vendor: "Espressif"})
+			Expect(c.Type).To(Equal("IoT Device (Sensor)"))
+			Expect(c.Confidence).To(Equal(90))
+			Expect(c.Matches[0].Reason).To(Equal(`HTTP title "Sensor Dashboard" ~ "Sensor", OUI F8:B5:68:D`))
+
+			Expect(discovery.DetectDeviceType("", "f8:b5:68:e1:00:01", "Espressif", nil)).To(Equal("IoT Device"))
+		})
+
+		It("should replace the builtin rules on request", func() {
+			ruleset, err := discovery.LoadRules(writeRules(`
+defaults: false
+rules:
+  - name: sonos
+    type: Speaker
+    weight: 80
+    match: {ssdp_model: 'sonos (one|five)'}
+  - name: airplay
+    type: Speaker
+    weight: 40
+    match: {services: [_airplay._tcp, _raop._tcp]}
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ruleset.Len()).To(Equal(2))
+
+			c := ruleset.Classify(discovery.DeviceFacts{
+				UPnP:  &discovery.UPnPDevice{Manufacturer: "Sonos, Inc.", ModelName: "Sonos One"},
+				DNSSD: []discovery.DNSSDService{{Type: "_raop._tcp"}, {Type: "_raop._tcp"}},
+			})
+			Expect(c.Type).To(Equal("Speaker"))
+			Expect(c.Matches[1].Reason).To(Equal("service _raop._tcp"))
+			Expect(ruleset.Classify(discovery.DeviceFacts{Hostname: "iphone"}).Type).To(Equal("Unknown"))
+		})
+
+		DescribeTable("should reject invalid rules",
+			func(content, message string) {
+				_, err := discovery.LoadRules(writeRules(content))
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("unknown field", "rules:\n  - name: a\n    type: X\n    weight: 10\n    match: {hostnme: x}\n", "field hostnme not found"),
+			Entry("invalid pattern", "rules:\n  - name: a\n    type: X\n    weight: 10\n    match: {hostname: '('}\n", `rule "a": invalid pattern`),
+			Entry("weight", "rules:\n  - name: a\n    type: X\n    weight: 0\n    match: {hostname: x}\n", "weight must be between 1 and 100"),
+			Entry("detector", "rules:\n  - name: a\n    detector: nmap\n    weight: 10\n", `unknown detector "nmap"`),
+			Entry("duplicate", "rules:\n  - name: snmp\n    type: X\n    weight: 10\n    match: {hostname: x}\n", `rule "snmp": duplicate name`),
+			Entry("no conditions", "rules:\n  - name: a\n    type: X\n    weight: 10\n", "no match conditions"),
+			Entry("invalid OUI", "rules:\n  - name: a\n    type: X\n    weight: 10\n    match: {oui: [aa:bb]}\n", `invalid OUI "aa:bb"`),
+		)
+	})
+})
@@ -0,0 +1,149 @@
+//go:build linux
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// synAckWait ist die Wartezeit auf das mitgelesene SYN-ACK nach dem Verbindungsaufbau
+const synAckWait = 200 * time.Millisecond
+
+// tcpListener liest über einen Raw-Socket eine Kopie aller eingehenden TCP-Segmente
+// mit und reicht SYN-ACKs an wartende FingerprintTCP-Aufrufe weiter
+type tcpListener struct {
+	conn *ipv4.RawConn
+
+	mu      sync.Mutex
+	pending map[string]chan TCPFingerprint // "ip:port" der Gegenstelle
+}
+
+var (
+	sharedTCPListener     *tcpListener
+	sharedTCPListenerErr  error
+	sharedTCPListenerOnce sync.Once
+)
+
+// sharedTCPFingerprintListener gibt den prozessweiten Listener zurück (beim ersten Aufruf geöffnet)
+func sharedTCPFingerprintListener() (*tcpListener, error) {
+	sharedTCPListenerOnce.Do(func() {
+		conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+		if err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				sharedTCPListenerErr = fmt.Errorf("%w: %v", ErrRawSocketUnavailable, err)
+			} else {
+				sharedTCPListenerErr = fmt.Errorf("failed to open raw TCP socket: %v", err)
+			}
+			return
+		}
+		raw, err := ipv4.NewRawConn(conn)
+		if err != nil {
+			_ = conn.Close()
+			sharedTCPListenerErr = fmt.Errorf("failed to open raw TCP socket: %v", err)
+			return
+		}
+		sharedTCPListener = &tcpListener{conn: raw, pending: make(map[string]chan TCPFingerprint)}
+		go sharedTCPListener.readLoop()
+	})
+	return sharedTCPListener, sharedTCPListenerErr
+}
+
+// FingerprintTCP baut eine Verbindung zu einem Port auf und liest TTL, Fenstergröße und
+// TCP-Optionen des SYN-ACK über einen Raw-Socket mit. Benötigt CAP_NET_RAW
+// (sonst ErrRawSocketUnavailable); ein geschlossener Port liefert den Fehler des Verbindungsaufbaus.
+func FingerprintTCP(ctx context.Context, ip net.IP, port int, timeout time.Duration) (*TCPFingerprint, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("TCP fingerprinting supports IPv4 only: %s", ip)
+	}
+	listener, err := sharedTCPFingerprintListener()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := net.JoinHostPort(ip4.String(), strconv.Itoa(port))
+	reply, err := listener.register(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.unregister(addr)
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.Close()
+
+	// Das SYN-ACK kam vor dem Ende des Verbindungsaufbaus - die Lese-Goroutine kann
+	// es aber noch nicht zugestellt haben
+	timer := time.NewTimer(synAckWait)
+	defer timer.Stop()
+	select {
+	case fp := <-reply:
+		return &fp, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("no SYN-ACK from %s captured", addr)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// register meldet das Warten auf ein SYN-ACK der Gegenstelle an
+func (l *tcpListener) register(addr string) (chan TCPFingerprint, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, busy := l.pending[addr]; busy {
+		return nil, fmt.Errorf("TCP fingerprint of %s already in progress", addr)
+	}
+	reply := make(chan TCPFingerprint, 1)
+	l.pending[addr] = reply
+	return reply, nil
+}
+
+func (l *tcpListener) unregister(addr string) {
+	l.mu.Lock()
+	delete(l.pending, addr)
+	l.mu.Unlock()
+}
+
+// readLoop wertet die mitgelesenen Segmente aus
+func (l *tcpListener) readLoop() {
+	buf := make([]byte, 1500)
+	for {
+		header, payload, _, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if header == nil || header.Src == nil || header.FragOff != 0 || header.Flags&ipv4.MoreFragments != 0 {
+			continue
+		}
+
+		fp, ok := ParseTCPSynAck(payload, header.TTL)
+		if !ok {
+			continue
+		}
+		addr := net.JoinHostPort(header.Src.String(), strconv.Itoa(fp.Port))
+		l.mu.Lock()
+		if reply, ok := l.pending[addr]; ok {
+			select {
+			case reply <- fp:
+			default: // Wiederholtes SYN-ACK - das erste zählt
+			}
+		}
+		l.mu.Unlock()
+	}
+}
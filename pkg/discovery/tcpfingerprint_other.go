@@ -0,0 +1,17 @@
+//go:build !linux
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+// FingerprintTCP ist nur unter Linux verfügbar - andere Systeme reichen eingehende
+// TCP-Segmente nicht an Raw-Sockets weiter
+func FingerprintTCP(ctx context.Context, ip net.IP, port int, timeout time.Duration) (*TCPFingerprint, error) {
+	return nil, fmt.Errorf("%w: TCP fingerprinting not supported on %s", ErrRawSocketUnavailable, runtime.GOOS)
+}
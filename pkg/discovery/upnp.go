@@ -0,0 +1,179 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDescriptionSize begrenzt die Gerätebeschreibung (übliche Größe: wenige KB)
+const maxDescriptionSize = 512 * 1024
+
+// UPnPDevice enthält die Angaben aus der UPnP-Gerätebeschreibung (XML unter LOCATION)
+type UPnPDevice struct {
+	DeviceType   string   `json:"device_type,omitempty"` // z.B. "urn:schemas-upnp-org:device:MediaRenderer:1"
+	FriendlyName string   `json:"friendly_name,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	ModelName    string   `json:"model_name,omitempty"`
+	ModelNumber  string   `json:"model_number,omitempty"`
+	SerialNumber string   `json:"serial_number,omitempty"`
+	Services     []string `json:"services,omitempty"` // serviceType des Geräts und aller eingebetteten Geräte
+}
+
+// Model gibt Modellname und -nummer zusammen zurück ("Sonos One S22"), ohne Dopplung
+func (d UPnPDevice) Model() string {
+	switch {
+	case d.ModelNumber == "" || strings.Contains(d.ModelName, d.ModelNumber):
+		return d.ModelName
+	case d.ModelName == "":
+		return d.ModelNumber
+	}
+	return d.ModelName + " " + d.ModelNumber
+}
+
+// TypeName gibt den Kurznamen des Gerätetyps zurück ("MediaRenderer")
+func (d UPnPDevice) TypeName() string {
+	return upnpTypeName(d.DeviceType)
+}
+
+// ServiceNames gibt die Kurznamen der Dienste zurück ("AVTransport", "RenderingControl")
+func (d UPnPDevice) ServiceNames() []string {
+	names := make([]string, 0, len(d.Services))
+	seen := make(map[string]bool, len(d.Services))
+	for _, serviceType := range d.Services {
+		if name := upnpTypeName(serviceType); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// String gibt das Gerät kompakt aus: "Wohnzimmer - Sonos One (ZonePlayer)"
+func (d UPnPDevice) String() string {
+	var parts []string
+	if d.FriendlyName != "" {
+		parts = append(parts, d.FriendlyName)
+	}
+	product := strings.TrimSpace(d.Manufacturer + " " + d.Model())
+	if brand := strings.Fields(d.Manufacturer); len(brand) > 0 && d.Model() != "" &&
+		strings.HasPrefix(strings.ToLower(d.Model()), strings.ToLower(strings.TrimRight(brand[0], ",."))) {
+		product = d.Model() // "Sonos One" statt "Sonos, Inc. Sonos One"
+	}
+	if product != "" && product != d.FriendlyName {
+		parts = append(parts, product)
+	}
+	text := strings.Join(parts, " - ")
+	if typeName := d.TypeName(); typeName != "" {
+		text += " (" + typeName + ")"
+	}
+	return strings.TrimSpace(text)
+}
+
+// upnpTypeName liefert den Typ-Namen einer UPnP-URN
+// ("urn:schemas-upnp-org:service:AVTransport:1" → "AVTransport")
+func upnpTypeName(urn string) string {
+	parts := strings.Split(urn, ":")
+	if len(parts) >= 4 {
+		return parts[3]
+	}
+	return urn
+}
+
+// upnpDescription ist das Wurzelelement der Gerätebeschreibung (UPnP Device Architecture 2.3)
+type upnpDescription struct {
+	Device upnpXMLDevice `xml:"device"`
+}
+
+type upnpXMLDevice struct {
+	DeviceType   string `xml:"deviceType"`
+	FriendlyName string `xml:"friendlyName"`
+	Manufacturer string `xml:"manufacturer"`
+	ModelName    string `xml:"modelName"`
+	ModelNumber  string `xml:"modelNumber"`
+	SerialNumber string `xml:"serialNumber"`
+	Services     []struct {
+		ServiceType string `xml:"serviceType"`
+	} `xml:"serviceList>service"`
+	Devices []upnpXMLDevice `xml:"deviceList>device"`
+}
+
+// collectServices sammelt die serviceType des Geräts und seiner eingebetteten Geräte
+func (d upnpXMLDevice) collectServices(services []string, depth int) []string {
+	for _, svc := range d.Services {
+		if serviceType := strings.TrimSpace(svc.ServiceType); serviceType != "" {
+			services = append(services, serviceType)
+		}
+	}
+	if depth < 4 {
+		for _, embedded := range d.Devices {
+			services = embedded.collectServices(services, depth+1)
+		}
+	}
+	return services
+}
+
+// FetchUPnPDescription lädt die Gerätebeschreibung eines per SSDP gefundenen Geräts.
+// Die LOCATION muss auf das antwortende Gerät selbst zeigen - sonst könnte ein Gerät
+// netspy beliebige URLs abrufen lassen und fremde Angaben unterschieben.
+func FetchUPnPDescription(ctx context.Context, device SSDPDevice, timeout time.Duration) (*UPnPDevice, error) {
+	location, err := url.Parse(device.Location)
+	if err != nil || (location.Scheme != "http" && location.Scheme != "https") {
+		return nil, fmt.Errorf("invalid UPnP location %q", device.Location)
+	}
+	if ip := net.ParseIP(location.Hostname()); ip == nil || !ip.Equal(net.ParseIP(device.IP)) {
+		return nil, fmt.Errorf("UPnP location %q does not point to %s", device.Location, device.IP)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // Weiterleitungen könnten das Gerät verlassen
+		},
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch UPnP description: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch UPnP description: %s", response.Status)
+	}
+
+	return ParseUPnPDescription(io.LimitReader(response.Body, maxDescriptionSize))
+}
+
+// ParseUPnPDescription liest eine UPnP-Gerätebeschreibung (Wurzelgerät samt Diensten
+// der eingebetteten Geräte)
+func ParseUPnPDescription(r io.Reader) (*UPnPDevice, error) {
+	var description upnpDescription
+	if err := xml.NewDecoder(r).Decode(&description); err != nil {
+		return nil, fmt.Errorf("invalid UPnP description: %w", err)
+	}
+	root := description.Device
+	if root.DeviceType == "" && root.FriendlyName == "" {
+		return nil, fmt.Errorf("invalid UPnP description: no device element")
+	}
+
+	clean := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	return &UPnPDevice{
+		DeviceType:   clean(root.DeviceType),
+		FriendlyName: clean(root.FriendlyName),
+		Manufacturer: clean(root.Manufacturer),
+		ModelName:    clean(root.ModelName),
+		ModelNumber:  clean(root.ModelNumber),
+		SerialNumber: clean(root.SerialNumber),
+		Services:     root.collectServices(nil, 0),
+	}, nil
+}
@@ -0,0 +1,109 @@
+package discovery_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+)
+
+// sonosDescription ist eine gekürzte Gerätebeschreibung eines Sonos-Lautsprechers
+const sonosDescription = `<?xml version="1.0" encoding="utf-8" ?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:ZonePlayer:1</deviceType>
+    <friendlyName>192.168.1.40 - Sonos One - RINCON_48A6B8000001</friendlyName>
+    <manufacturer>Sonos, Inc.</manufacturer>
+    <modelNumber>S18</modelNumber>
+    <modelName>Sonos One</modelName>
+    <serialNumber>48-A6-B8-00-00-01:A</serialNumber>
+    <serviceList>
+      <service><serviceType>urn:schemas-upnp-org:service:AlarmClock:1</serviceType></service>
+      <service><serviceType>urn:schemas-upnp-org:service:DeviceProperties:1</serviceType></service>
+    </serviceList>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+        <friendlyName>Wohnzimmer - Sonos One Media Renderer</friendlyName>
+        <serviceList>
+          <service><serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType></service>
+          <service><serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType></service>
+        </serviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+var _ = Describe("UPnP", func() {
+	Describe("ParseUPnPDescription", func() {
+		It("should extract the root device and all services", func() {
+			device, err := discovery.ParseUPnPDescription(strings.NewReader(sonosDescription))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.FriendlyName).To(Equal("192.168.1.40 - Sonos One - RINCON_48A6B8000001"))
+			Expect(device.Manufacturer).To(Equal("Sonos, Inc."))
+			Expect(device.Model()).To(Equal("Sonos One S18"))
+			Expect(device.SerialNumber).To(Equal("48-A6-B8-00-00-01:A"))
+			Expect(device.TypeName()).To(Equal("ZonePlayer"))
+			Expect(device.ServiceNames()).To(Equal([]string{"AlarmClock", "DeviceProperties", "RenderingControl", "AVTransport"}))
+			Expect(device.String()).To(Equal("192.168.1.40 - Sonos One - RINCON_48A6B8000001 - Sonos One S18 (ZonePlayer)"))
+		})
+
+		It("should reject documents without a device", func() {
+			_, err := discovery.ParseUPnPDescription(strings.NewReader(`<root><specVersion/></root>`))
+			Expect(err).To(HaveOccurred())
+			_, err = discovery.ParseUPnPDescription(strings.NewReader(`HTTP/1.1 200 OK`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FetchUPnPDescription", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/xml/device_description.xml":
+					w.Header().Set("Content-Type", "text/xml")
+					_, _ = w.Write([]byte(sonosDescription))
+				case "/redirect":
+					http.Redirect(w, r, "http://192.0.2.1/description.xml", http.StatusFound)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should fetch the description from the LOCATION URL", func() {
+			device, err := discovery.FetchUPnPDescription(context.Background(), discovery.SSDPDevice{
+				IP: "127.0.0.1", Location: server.URL + "/xml/device_description.xml",
+			}, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.ModelName).To(Equal("Sonos One"))
+		})
+
+		It("should only fetch from the responding device", func() {
+			_, err := discovery.FetchUPnPDescription(context.Background(), discovery.SSDPDevice{
+				IP: "192.168.1.40", Location: server.URL + "/xml/device_description.xml",
+			}, time.Second)
+			Expect(err).To(MatchError(ContainSubstring("does not point to 192.168.1.40")))
+
+			_, err = discovery.FetchUPnPDescription(context.Background(), discovery.SSDPDevice{
+				IP: "127.0.0.1", Location: server.URL + "/redirect",
+			}, time.Second)
+			Expect(err).To(HaveOccurred())
+
+			_, err = discovery.FetchUPnPDescription(context.Background(), discovery.SSDPDevice{
+				IP: "127.0.0.1", Location: "file:///etc/passwd",
+			}, time.Second)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -4,24 +4,67 @@ import "strings"
 
 // GetMACVendor gibt den Vendor-Namen für eine gegebene MAC-Adresse zurück OUI
 func GetMACVendor(mac string) string {
-	// Normalize MAC address format
-	mac = normalizeMACForLookup(mac)
-	if len(mac) < 8 {
-		return ""
+	match := LookupVendor(mac)
+	if match.Vendor != "" {
+		return match.Vendor
 	}
 
-	// Try learned vendors first (priority), then builtin
-	vendor := GetLearnedVendor(mac)
-	if vendor != "" {
-		return vendor
+	// Mit IEEE-Register bleibt die Suche offline
+	if registry, _ := ActiveOUIRegistry(); registry == nil && len(macHexDigits(mac)) >= 6 {
+		// If unknown, trigger async lookup to learn for next time
+		LookupAndLearnVendor(normalizeMACForLookup(mac))
 	}
 
-	// If unknown, trigger async lookup to learn for next time
-	LookupAndLearnVendor(mac)
-
 	return ""
 }
 
+// VendorMatch beschreibt, welche Quelle einen Vendor geliefert hat
+type VendorMatch struct {
+	Vendor       string `json:"vendor"`
+	Source       string `json:"source"`                 // "override", "ieee", "learned", "builtin" ("" = unbekannt)
+	Prefix       string `json:"prefix"`                 // passender Präfix, z.B. "F8:B5:68:D"
+	Organization string `json:"organization,omitempty"` // ungekürzter Name laut IEEE-Register
+}
+
+// LookupVendor sucht den Vendor einer MAC ohne Online-Abfrage. Reihenfolge: Overrides,
+// IEEE-Zuteilungen mit 28/36 Bit, gelernte, eingebaute, IEEE-Zuteilungen mit 24 Bit.
+func LookupVendor(mac string) VendorMatch {
+	digits := macHexDigits(mac)
+	if len(digits) < 6 {
+		return VendorMatch{}
+	}
+
+	if prefix, vendor := lookupVendorOverride(digits); vendor != "" {
+		return VendorMatch{Vendor: vendor, Source: "override", Prefix: prefix}
+	}
+
+	// IEEE-Register (falls importiert): 28/36-Bit-Zuteilungen sind genauer als jede OUI
+	var ieee *VendorMatch
+	if registry, _ := ActiveOUIRegistry(); registry != nil {
+		if entry, ok := registry.Lookup(digits); ok {
+			prefix, _ := formatVendorPrefix(entry.Prefix)
+			ieee = &VendorMatch{Vendor: shortenOrganization(entry.Organization), Source: "ieee", Prefix: prefix, Organization: entry.Organization}
+			if entry.Bits() > 24 {
+				return *ieee
+			}
+		}
+	}
+
+	// Try learned vendors first (priority), then builtin
+	oui := normalizeMACForLookup(digits)
+	if vendor, source := lookupLearnedVendor(oui); vendor != "" {
+		match := VendorMatch{Vendor: vendor, Source: source, Prefix: oui}
+		if ieee != nil {
+			match.Organization = ieee.Organization
+		}
+		return match
+	}
+	if ieee != nil {
+		return *ieee
+	}
+	return VendorMatch{}
+}
+
 // normalizeMACForLookup converts MAC address to standard format AA:BB:CC:DD:EE:FF
 func normalizeMACForLookup(mac string) string {
 	// Remove all separators (colons, dashes, dots)
@@ -2,48 +2,131 @@ package discovery
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"netspy/pkg/paths"
+)
+
+// Dateinamen im Datenverzeichnis (siehe paths.DataDir)
+const (
+	learnedVendorsName  = "vendor_learned.txt"
+	vendorOverridesName = "vendor_overrides.txt"
 )
 
+const learnedVendorsHeader = `# NetSpy Learned MAC Vendors
+# Automatisch erweitert durch API-Lookups (api.macvendors.com)
+# Format: OUI = Vendor Name
+# Sie können diese Datei manuell bearbeiten
+
+`
+
+const vendorOverridesHeader = `# NetSpy MAC Vendor Overrides
+# Verwaltet mit "netspy vendors add/remove", haben Vorrang vor allen anderen Quellen
+# Format: Präfix (6 bis 12 Hex-Ziffern) = Vendor Name
+
+`
+
 // LearnedVendors speichert dynamisch gelernte MAC-Vendors
 var (
-	learnedVendors     = make(map[string]string)
-	learnedVendorsMux  sync.RWMutex
-	learnedVendorsFile string
-	lookupInProgress   = make(map[string]bool) // Track ongoing lookups
-	lookupMux          sync.Mutex
+	learnedVendors       = make(map[string]string) // "AA:BB:CC" → Vendor
+	vendorOverrides      = make(map[string]string) // "AA:BB:CC[:D...]" → Vendor
+	learnedVendorsMux    sync.RWMutex
+	learnedVendorsFile   string
+	vendorOverridesFile  string
+	onlineLookupDisabled atomic.Bool
+	lookupInProgress     = make(map[string]bool) // Track ongoing lookups
+	lookupMux            sync.Mutex
 )
 
-// InitLearnedVendors initialisiert die Learned-Vendors-Datei
+// VendorEntry ist ein Eintrag der Hersteller-Datenbank
+type VendorEntry struct {
+	Prefix string `json:"prefix"` // z.B. "3C:22:FB" oder "F8:B5:68:D"
+	Vendor string `json:"vendor"`
+	Source string `json:"source"` // "override", "learned", "builtin" oder "ieee"
+}
+
+// InitLearnedVendors lädt gelernte Vendors und Overrides aus dem Datenverzeichnis.
+// Eine vorhandene vendor_learned.txt neben dem Executable (ältere Versionen) wird
+// beim ersten Start übernommen.
 func InitLearnedVendors() error {
-	// Finde das Executable-Verzeichnis
-	exe, err := os.Executable()
+	dir, err := paths.DataDir()
 	if err != nil {
 		return err
 	}
-	exeDir := filepath.Dir(exe)
-	learnedVendorsFile = filepath.Join(exeDir, "vendor_learned.txt")
+	migrateLegacyLearnedVendors(filepath.Join(dir, learnedVendorsName))
+	return InitLearnedVendorsDir(dir)
+}
 
-	// Lade bestehende Einträge
-	return loadLearnedVendors()
+// InitLearnedVendorsDir lädt gelernte Vendors und Overrides aus dir
+func InitLearnedVendorsDir(dir string) error {
+	learned, errLearned := readVendorFile(filepath.Join(dir, learnedVendorsName))
+	overrides, errOverrides := readVendorFile(filepath.Join(dir, vendorOverridesName))
+
+	learnedVendorsMux.Lock()
+	learnedVendorsFile = filepath.Join(dir, learnedVendorsName)
+	vendorOverridesFile = filepath.Join(dir, vendorOverridesName)
+	learnedVendors, vendorOverrides = learned, overrides
+	learnedVendorsMux.Unlock()
+
+	if errLearned != nil {
+		return errLearned
+	}
+	return errOverrides
 }
 
-// loadLearnedVendors lädt die Learned-Vendors aus der Datei
-func loadLearnedVendors() error {
-	file, err := os.Open(learnedVendorsFile)
+// migrateLegacyLearnedVendors kopiert die vendor_learned.txt früherer Versionen
+// (neben dem Executable) ins Datenverzeichnis
+func migrateLegacyLearnedVendors(path string) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(exe), learnedVendorsName)) // #nosec G304 -- feste Datei neben dem Executable
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644) // #nosec G306 -- keine vertraulichen Daten
+}
+
+// SetOnlineLookup schaltet die Online-Abfrage unbekannter Vendors ein oder aus
+// (--no-online-lookup)
+func SetOnlineLookup(enabled bool) {
+	onlineLookupDisabled.Store(!enabled)
+}
+
+// VendorFiles gibt die Pfade der Dateien für gelernte Vendors und Overrides zurück
+// (leer, solange InitLearnedVendors nicht aufgerufen wurde)
+func VendorFiles() (learned, overrides string) {
+	learnedVendorsMux.RLock()
+	defer learnedVendorsMux.RUnlock()
+	return learnedVendorsFile, vendorOverridesFile
+}
+
+// readVendorFile liest eine Datei im Format "Präfix = Vendor Name" (Präfixe in
+// Großbuchstaben). Eine fehlende Datei ergibt eine leere Map.
+func readVendorFile(path string) (map[string]string, error) {
+	vendors := make(map[string]string)
+
+	file, err := os.Open(path) // #nosec G304 -- Datei im Datenverzeichnis
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Datei existiert noch nicht - das ist OK
-			return nil
+			return vendors, nil
 		}
-		return err
+		return vendors, err
 	}
 	defer func() { _ = file.Close() }()
 
@@ -62,23 +145,200 @@ func loadLearnedVendors() error {
 			continue
 		}
 
-		oui := strings.TrimSpace(parts[0])
+		prefix := strings.ToUpper(strings.TrimSpace(parts[0]))
 		vendor := strings.TrimSpace(parts[1])
 
-		if oui != "" && vendor != "" {
-			learnedVendorsMux.Lock()
-			learnedVendors[oui] = vendor
-			learnedVendorsMux.Unlock()
+		if prefix != "" && vendor != "" {
+			vendors[prefix] = vendor
+		}
+	}
+
+	return vendors, scanner.Err()
+}
+
+// updateVendorFile ändert eine Vendor-Datei unter Dateisperre: Die Datei wird neu
+// gelesen (andere Instanzen können inzwischen Einträge ergänzt haben), mit update
+// geändert und atomar ersetzt, sofern update true zurückgibt. Gibt den neuen Inhalt zurück.
+func updateVendorFile(path, header string, update func(map[string]string) bool) (map[string]string, error) {
+	if path == "" {
+		return nil, errors.New("vendor database not initialized")
+	}
+	unlock, err := paths.Lock(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	vendors, err := readVendorFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !update(vendors) {
+		return vendors, nil
+	}
+
+	prefixes := make([]string, 0, len(vendors))
+	for prefix := range vendors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, prefix := range prefixes {
+		fmt.Fprintf(&sb, "%s = %s\n", prefix, vendors[prefix])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(sb.String()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	return vendors, os.Rename(tmp.Name(), path)
+}
+
+// formatVendorPrefix prüft einen Präfix (6 bis 12 Hex-Ziffern, beliebige Trennzeichen)
+// und bringt ihn in die Form "AA:BB:CC[:D...]"
+func formatVendorPrefix(prefix string) (string, error) {
+	digits := macHexDigits(prefix)
+	if len(digits) < 6 || len(digits) > 12 {
+		return "", fmt.Errorf("invalid prefix %q (expected 6 to 12 hex digits, e.g. 3c:22:fb)", prefix)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(digits); i += 2 {
+		if i > 0 {
+			sb.WriteByte(':')
 		}
+		sb.WriteString(digits[i:min(i+2, len(digits))])
+	}
+	return sb.String(), nil
+}
+
+// AddVendorOverride legt einen Vendor für einen Präfix fest. Overrides haben Vorrang
+// vor allen anderen Quellen; der längste passende Präfix gewinnt (bis zur vollen MAC).
+func AddVendorOverride(prefix, vendor string) error {
+	key, err := formatVendorPrefix(prefix)
+	if err != nil {
+		return err
 	}
+	vendor = strings.TrimSpace(vendor)
+	if vendor == "" || strings.ContainsAny(vendor, "\r\n") {
+		return fmt.Errorf("invalid vendor name %q", vendor)
+	}
+
+	_, overridesFile := VendorFiles()
+	overrides, err := updateVendorFile(overridesFile, vendorOverridesHeader, func(m map[string]string) bool {
+		m[key] = vendor
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	learnedVendorsMux.Lock()
+	vendorOverrides = overrides
+	learnedVendorsMux.Unlock()
+	return nil
+}
 
-	return scanner.Err()
+// RemoveVendorOverride entfernt einen Override. Gibt false zurück, wenn es keinen gab.
+func RemoveVendorOverride(prefix string) (bool, error) {
+	_, overridesFile := VendorFiles()
+	return removeVendorEntry(prefix, overridesFile, vendorOverridesHeader, &vendorOverrides)
+}
+
+// ForgetLearnedVendor entfernt einen online gelernten Vendor (wird beim nächsten
+// unbekannten Gerät erneut nachgeschlagen). Gibt false zurück, wenn es keinen gab.
+func ForgetLearnedVendor(prefix string) (bool, error) {
+	learnedFile, _ := VendorFiles()
+	return removeVendorEntry(prefix, learnedFile, learnedVendorsHeader, &learnedVendors)
+}
+
+func removeVendorEntry(prefix, path, header string, target *map[string]string) (bool, error) {
+	key, err := formatVendorPrefix(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	removed := false
+	vendors, err := updateVendorFile(path, header, func(m map[string]string) bool {
+		_, removed = m[key]
+		delete(m, key)
+		return removed
+	})
+	if err != nil {
+		return false, err
+	}
+	learnedVendorsMux.Lock()
+	*target = vendors
+	learnedVendorsMux.Unlock()
+	return removed, nil
+}
+
+// LearnedVendorEntries gibt die gelernten Vendors nach Präfix sortiert zurück
+func LearnedVendorEntries() []VendorEntry {
+	learnedVendorsMux.RLock()
+	defer learnedVendorsMux.RUnlock()
+	return vendorEntries(learnedVendors, "learned")
+}
+
+// VendorOverrideEntries gibt die Overrides nach Präfix sortiert zurück
+func VendorOverrideEntries() []VendorEntry {
+	learnedVendorsMux.RLock()
+	defer learnedVendorsMux.RUnlock()
+	return vendorEntries(vendorOverrides, "override")
+}
+
+// BuiltinVendorEntries gibt die eingebaute OUI-Liste nach Präfix sortiert zurück
+func BuiltinVendorEntries() []VendorEntry {
+	return vendorEntries(ouiDatabase, "builtin")
+}
+
+func vendorEntries(vendors map[string]string, source string) []VendorEntry {
+	entries := make([]VendorEntry, 0, len(vendors))
+	for prefix, vendor := range vendors {
+		entries = append(entries, VendorEntry{Prefix: prefix, Vendor: vendor, Source: source})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Prefix < entries[j].Prefix })
+	return entries
+}
+
+// lookupVendorOverride sucht den längsten passenden Override (digits: Hex-Ziffern der MAC)
+func lookupVendorOverride(digits string) (string, string) {
+	learnedVendorsMux.RLock()
+	defer learnedVendorsMux.RUnlock()
+	if len(vendorOverrides) == 0 {
+		return "", ""
+	}
+	for length := min(len(digits), 12); length >= 6; length-- {
+		key, _ := formatVendorPrefix(digits[:length])
+		if vendor, ok := vendorOverrides[key]; ok {
+			return key, vendor
+		}
+	}
+	return "", ""
 }
 
 // GetLearnedVendor versucht zuerst aus learned vendors, dann fallback zu builtin
 func GetLearnedVendor(mac string) string {
+	vendor, _ := lookupLearnedVendor(mac)
+	return vendor
+}
+
+// lookupLearnedVendor sucht in gelernten und eingebauten Vendors und gibt die Quelle zurück
+func lookupLearnedVendor(mac string) (string, string) {
 	if mac == "" || len(mac) < 8 {
-		return ""
+		return "", ""
 	}
 
 	// OUI extrahieren (erste 3 Oktette)
@@ -88,16 +348,16 @@ func GetLearnedVendor(mac string) string {
 	learnedVendorsMux.RLock()
 	if vendor, ok := learnedVendors[oui]; ok {
 		learnedVendorsMux.RUnlock()
-		return vendor
+		return vendor, "learned"
 	}
 	learnedVendorsMux.RUnlock()
 
 	// 2. Fallback zu builtin vendors
 	if vendor, ok := ouiDatabase[oui]; ok {
-		return vendor
+		return vendor, "builtin"
 	}
 
-	return ""
+	return "", ""
 }
 
 // LookupAndLearnVendor schlägt unbekannte Vendors online nach und speichert sie
@@ -107,6 +367,10 @@ func LookupAndLearnVendor(mac string) {
 		return
 	}
 
+	if onlineLookupDisabled.Load() {
+		return
+	}
+
 	oui := strings.ToUpper(mac[:8])
 
 	// Check if already known or lookup in progress
@@ -142,7 +406,7 @@ func LookupAndLearnVendor(mac string) {
 
 		vendor := queryMACVendorAPI(mac)
 		if vendor != "" {
-			saveLearnedVendor(oui, vendor)
+			_ = saveLearnedVendor(oui, vendor)
 		}
 	}()
 }
@@ -205,29 +469,34 @@ func shortenVendorName(name string) string {
 	return strings.TrimSpace(name)
 }
 
-// saveLearnedVendor speichert einen neu gelernten Vendor in die Datei
+// saveLearnedVendor speichert einen neu gelernten Vendor in die Datei. Die Datei ist
+// dabei gesperrt, damit parallel laufende Instanzen keine Zeilen vermischen.
 func saveLearnedVendor(oui, vendor string) error {
 	// Füge zu Map hinzu
 	learnedVendorsMux.Lock()
 	learnedVendors[oui] = vendor
+	path := learnedVendorsFile
 	learnedVendorsMux.Unlock()
 
+	if path == "" {
+		return nil
+	}
+	unlock, err := paths.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Erstelle Datei falls nicht vorhanden
-	if _, err := os.Stat(learnedVendorsFile); os.IsNotExist(err) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// Schreibe Header
-		header := `# NetSpy Learned MAC Vendors
-# Automatisch erweitert durch API-Lookups (api.macvendors.com)
-# Format: OUI = Vendor Name
-# Sie können diese Datei manuell bearbeiten
-
-`
-		if err := os.WriteFile(learnedVendorsFile, []byte(header), 0644); err != nil {
+		if err := os.WriteFile(path, []byte(learnedVendorsHeader), 0644); err != nil { // #nosec G306 -- keine vertraulichen Daten
 			return err
 		}
 	}
 
 	// Append neuen Eintrag
-	file, err := os.OpenFile(learnedVendorsFile, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644) // #nosec G302 G304 -- Datei im Datenverzeichnis
 	if err != nil {
 		return err
 	}
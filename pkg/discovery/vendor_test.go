@@ -1,6 +1,11 @@
 package discovery_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -52,4 +57,70 @@ var _ = Describe("MAC Vendor Detection", func() {
 			})
 		})
 	})
+
+	Describe("vendor store", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			learned := "# NetSpy Learned MAC Vendors\n3C:22:FB = Apple\nAA:BB:CC = Example Vendor\n"
+			Expect(os.WriteFile(filepath.Join(dir, "vendor_learned.txt"), []byte(learned), 0600)).To(Succeed())
+			Expect(discovery.InitLearnedVendorsDir(dir)).To(Succeed())
+			empty := GinkgoT().TempDir()
+			DeferCleanup(discovery.InitLearnedVendorsDir, empty)
+		})
+
+		It("should report which source answered", func() {
+			Expect(discovery.LookupVendor("aa:bb:cc:00:00:01")).To(Equal(discovery.VendorMatch{Vendor: "Example Vendor", Source: "learned", Prefix: "AA:BB:CC"}))
+			Expect(discovery.LookupVendor("00:03:93:12:34:56").Source).To(Equal("builtin"))
+			Expect(discovery.LookupVendor("02:00:00:00:00:01").Source).To(BeEmpty())
+		})
+
+		It("should prefer the longest override", func() {
+			Expect(discovery.AddVendorOverride("aa-bb-cc", "Override Vendor")).To(Succeed())
+			Expect(discovery.AddVendorOverride("aabbccd", "Lab Sensor")).To(Succeed())
+
+			Expect(discovery.GetMACVendor("aa:bb:cc:d0:00:01")).To(Equal("Lab Sensor"))
+			Expect(discovery.LookupVendor("aa:bb:cc:e0:00:01")).To(Equal(discovery.VendorMatch{Vendor: "Override Vendor", Source: "override", Prefix: "AA:BB:CC"}))
+			Expect(discovery.VendorOverrideEntries()).To(HaveLen(2))
+
+			Expect(discovery.AddVendorOverride("aa:bb", "Too Short")).To(MatchError(ContainSubstring("invalid prefix")))
+			Expect(discovery.AddVendorOverride("aa:bb:cc", "")).To(HaveOccurred())
+
+			// Overrides überstehen einen Neustart
+			Expect(discovery.InitLearnedVendorsDir(dir)).To(Succeed())
+			Expect(discovery.GetMACVendor("aa:bb:cc:d0:00:01")).To(Equal("Lab Sensor"))
+		})
+
+		It("should remove overrides and learned vendors", func() {
+			Expect(discovery.AddVendorOverride("3c:22:fb", "Not Apple")).To(Succeed())
+			Expect(discovery.RemoveVendorOverride("3c:22:fb")).To(BeTrue())
+			Expect(discovery.GetMACVendor("3c:22:fb:00:00:01")).To(Equal("Apple"))
+
+			Expect(discovery.ForgetLearnedVendor("3C22FB")).To(BeTrue())
+			Expect(discovery.ForgetLearnedVendor("3C22FB")).To(BeFalse())
+			Expect(discovery.LearnedVendorEntries()).To(Equal([]discovery.VendorEntry{{Prefix: "AA:BB:CC", Vendor: "Example Vendor", Source: "learned"}}))
+
+			data, err := os.ReadFile(filepath.Join(dir, "vendor_learned.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(HavePrefix("# NetSpy Learned MAC Vendors"))
+			Expect(string(data)).NotTo(ContainSubstring("3C:22:FB"))
+		})
+
+		It("should not lose entries written concurrently", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					Expect(discovery.AddVendorOverride(fmt.Sprintf("02:00:%02x", i), fmt.Sprintf("Vendor %d", i))).To(Succeed())
+				}(i)
+			}
+			wg.Wait()
+
+			Expect(discovery.InitLearnedVendorsDir(dir)).To(Succeed())
+			Expect(discovery.VendorOverrideEntries()).To(HaveLen(20))
+		})
+	})
 })
@@ -0,0 +1,400 @@
+package filter
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// node ist ein übersetzter Teilausdruck
+type node interface {
+	match(fields map[string]string) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(fields map[string]string) bool {
+	return n.left.match(fields) && n.right.match(fields)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(fields map[string]string) bool {
+	return n.left.match(fields) || n.right.match(fields)
+}
+
+type notNode struct{ inner node }
+
+func (n notNode) match(fields map[string]string) bool {
+	return !n.inner.match(fields)
+}
+
+// fieldNode prüft ein einzelnes Feld (unbekannte Felder treffen nie zu)
+type fieldNode struct {
+	field string
+	fold  bool // case-insensitive: Feldname und -wert in Kleinbuchstaben vergleichen
+	test  func(value string) bool
+}
+
+func (n fieldNode) match(fields map[string]string) bool {
+	value, ok := lookupField(fields, n.field, n.fold)
+	if !ok {
+		return false
+	}
+	if n.fold {
+		value = strings.ToLower(value)
+	}
+	return n.test(value)
+}
+
+// searchNode sucht ohne Feldnamen in allen Text- und IP-Feldern
+type searchNode struct {
+	c    *compiler
+	test func(value string) bool
+}
+
+func (n searchNode) match(fields map[string]string) bool {
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if t := n.c.fieldType(name); t != TypeText && t != TypeIP {
+			continue
+		}
+		if n.c.fold {
+			value = strings.ToLower(value)
+		}
+		if n.test(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField liest ein Feld, bei fold ohne Beachtung der Groß/Kleinschreibung
+func lookupField(fields map[string]string, name string, fold bool) (string, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	if fold {
+		for key, value := range fields {
+			if strings.EqualFold(key, name) {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// compiler erzeugt die Knoten mit der Konfiguration eines Filters
+type compiler struct {
+	aliases map[string]string
+	types   map[string]FieldType
+	ipField string
+	fold    bool
+}
+
+// fieldName normalisiert einen Feldnamen und löst Aliase auf
+func (c *compiler) fieldName(name string) string {
+	if c.fold {
+		name = strings.ToLower(name)
+	}
+	if resolved, ok := c.aliases[name]; ok {
+		return resolved
+	}
+	return name
+}
+
+// fieldType gibt den Typ eines Felds zurück (Default: TypeText)
+func (c *compiler) fieldType(name string) FieldType {
+	if t, ok := c.types[name]; ok {
+		return t
+	}
+	if c.fold {
+		for key, t := range c.types {
+			if strings.EqualFold(key, name) {
+				return t
+			}
+		}
+	}
+	return TypeText
+}
+
+// value gibt den Vergleichswert zurück (bei fold in Kleinbuchstaben)
+func (c *compiler) value(tok token) string {
+	if c.fold {
+		return strings.ToLower(tok.text)
+	}
+	return tok.text
+}
+
+// comparison übersetzt "feld op wert"
+func (c *compiler) comparison(fieldTok, op, valueTok token) (node, error) {
+	field := c.fieldName(fieldTok.text)
+	if field == "" {
+		return nil, errorAt(fieldTok, "Empty column name")
+	}
+	fieldType := c.fieldType(field)
+
+	var test func(string) bool
+	switch op.text {
+	case "=", "!=":
+		equal, err := c.equality(fieldType, valueTok)
+		if err != nil {
+			return nil, err
+		}
+		test = equal
+	case "~", "!~":
+		re, err := c.regex(valueTok)
+		if err != nil {
+			return nil, err
+		}
+		test = func(value string) bool {
+			return anyElement(fieldType, value, re.MatchString)
+		}
+	default:
+		if valueTok.text == "" {
+			return nil, errorAt(op, "Missing value after operator")
+		}
+		ordered, err := c.ordering(fieldType, op.text, valueTok)
+		if err != nil {
+			return nil, err
+		}
+		test = ordered
+	}
+
+	if op.text == "!=" || op.text == "!~" {
+		positive := test
+		test = func(value string) bool { return !positive(value) }
+	}
+	return fieldNode{field: field, fold: c.fold, test: test}, nil
+}
+
+// in übersetzt "feld in (a, b, c)" - trifft zu, wenn einer der Werte passt
+func (c *compiler) in(fieldTok token, values []token) (node, error) {
+	field := c.fieldName(fieldTok.text)
+	fieldType := c.fieldType(field)
+
+	tests := make([]func(string) bool, 0, len(values))
+	for _, valueTok := range values {
+		equal, err := c.equality(fieldType, valueTok)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, equal)
+	}
+
+	return fieldNode{field: field, fold: c.fold, test: func(value string) bool {
+		for _, test := range tests {
+			if test(value) {
+				return true
+			}
+		}
+		return false
+	}}, nil
+}
+
+// search übersetzt einen Term ohne Feldnamen. CIDR und IP-Bereiche gelten für das
+// IP-Feld, Werte in Anführungszeichen werden immer als Text gesucht.
+func (c *compiler) search(tok token) (node, error) {
+	text := c.value(tok)
+
+	if tok.kind == tokWord && c.ipField != "" {
+		if strings.Contains(text, "/") {
+			_, network, err := net.ParseCIDR(text)
+			if err != nil {
+				return nil, errorAt(tok, "Invalid CIDR notation")
+			}
+			return fieldNode{field: c.ipField, fold: c.fold, test: func(value string) bool {
+				ip := net.ParseIP(value)
+				return ip != nil && network.Contains(ip)
+			}}, nil
+		}
+		if IsIPRange(text) {
+			if err := validateIPRange(tok); err != nil {
+				return nil, err
+			}
+			return fieldNode{field: c.ipField, fold: c.fold, test: func(value string) bool {
+				return MatchIPRange(text, value)
+			}}, nil
+		}
+	}
+
+	if tok.kind == tokWord && strings.Contains(text, "*") {
+		re := c.wildcard(text)
+		return searchNode{c: c, test: re.MatchString}, nil
+	}
+	return searchNode{c: c, test: func(value string) bool {
+		return strings.Contains(value, text)
+	}}, nil
+}
+
+// equality übersetzt den Vergleich "=" für den Typ des Felds
+func (c *compiler) equality(fieldType FieldType, tok token) (func(string) bool, error) {
+	text := c.value(tok)
+	wildcard := tok.kind == tokWord && strings.Contains(text, "*")
+
+	switch fieldType {
+	case TypeNumber:
+		if wildcard {
+			return c.wildcard(text).MatchString, nil
+		}
+		want, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, errorAt(tok, "Invalid number")
+		}
+		return func(value string) bool {
+			got, err := strconv.ParseFloat(value, 64)
+			return err == nil && got == want
+		}, nil
+
+	case TypeDuration:
+		want, err := ParseDuration(text)
+		if err != nil {
+			return nil, errorAt(tok, "Invalid duration (e.g. 50ms, 1h, 2d)")
+		}
+		return func(value string) bool {
+			got, err := ParseDuration(value)
+			return err == nil && got == want
+		}, nil
+
+	case TypeIP:
+		if want := net.ParseIP(text); want != nil {
+			return func(value string) bool {
+				return want.Equal(net.ParseIP(value))
+			}, nil
+		}
+		if tok.kind == tokWord && strings.Contains(text, "/") {
+			_, network, err := net.ParseCIDR(text)
+			if err != nil {
+				return nil, errorAt(tok, "Invalid CIDR notation")
+			}
+			return func(value string) bool {
+				ip := net.ParseIP(value)
+				return ip != nil && network.Contains(ip)
+			}, nil
+		}
+		if tok.kind == tokWord && IsIPRange(text) {
+			if err := validateIPRange(tok); err != nil {
+				return nil, err
+			}
+			return func(value string) bool {
+				return MatchIPRange(text, value)
+			}, nil
+		}
+		// Teil-Adressen wie "192.168.1." als Text
+		return c.textEquality(text, wildcard), nil
+
+	case TypeList:
+		match := c.textEquality(text, wildcard)
+		if !wildcard {
+			// Listenelemente müssen exakt passen (port=22 trifft nicht 2222)
+			match = func(item string) bool { return item == text }
+		}
+		return func(value string) bool {
+			return anyElement(TypeList, value, match)
+		}, nil
+
+	default:
+		return c.textEquality(text, wildcard), nil
+	}
+}
+
+// textEquality ist der klassische Spalten-Vergleich: Wildcard (vollständig) oder Substring
+func (c *compiler) textEquality(text string, wildcard bool) func(string) bool {
+	if wildcard {
+		return c.wildcard(text).MatchString
+	}
+	return func(value string) bool {
+		return MatchValue(text, value)
+	}
+}
+
+// ordering übersetzt die Vergleiche < <= > >= für den Typ des Felds
+func (c *compiler) ordering(fieldType FieldType, op string, tok token) (func(string) bool, error) {
+	text := c.value(tok)
+
+	switch fieldType {
+	case TypeNumber:
+		want, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, errorAt(tok, "Invalid number")
+		}
+		return func(value string) bool {
+			got, err := strconv.ParseFloat(value, 64)
+			return err == nil && orderingHolds(op, compareFloats(got, want))
+		}, nil
+
+	case TypeDuration:
+		want, err := ParseDuration(text)
+		if err != nil {
+			return nil, errorAt(tok, "Invalid duration (e.g. 50ms, 1h, 2d)")
+		}
+		return func(value string) bool {
+			got, err := ParseDuration(value)
+			return err == nil && orderingHolds(op, compareFloats(float64(got), float64(want)))
+		}, nil
+
+	case TypeIP:
+		want := net.ParseIP(text)
+		if want == nil {
+			return nil, errorAt(tok, "Invalid IP address")
+		}
+		return func(value string) bool {
+			got := net.ParseIP(value)
+			return got != nil && orderingHolds(op, compareIPs(got, want))
+		}, nil
+
+	default:
+		return func(value string) bool {
+			return anyElement(fieldType, value, func(item string) bool {
+				return item != "" && orderingHolds(op, compareNatural(item, text))
+			})
+		}, nil
+	}
+}
+
+// regex übersetzt den Wert eines "~"-Vergleichs
+func (c *compiler) regex(tok token) (*regexp.Regexp, error) {
+	pattern := tok.text
+	if c.fold {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errorAt(tok, "Invalid regular expression")
+	}
+	return re, nil
+}
+
+// wildcard übersetzt ein Wildcard-Pattern (muss das ganze Feld treffen)
+func (c *compiler) wildcard(text string) *regexp.Regexp {
+	return regexp.MustCompile(WildcardToRegex(text))
+}
+
+// anyElement wendet einen Test auf einen Wert an, bei Listen auf jedes Element
+func anyElement(fieldType FieldType, value string, test func(string) bool) bool {
+	if fieldType != TypeList {
+		return test(value)
+	}
+	for _, item := range strings.Fields(value) {
+		if test(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIPRange prüft die Grenzen eines IP-Bereichs (0-255)
+func validateIPRange(tok token) error {
+	rangePart := tok.text[strings.LastIndex(tok.text, ".")+1:]
+	bounds := strings.Split(rangePart, "-")
+	for _, bound := range bounds {
+		n, _ := strconv.Atoi(bound)
+		if n < 0 || n > 255 {
+			return errorAt(tok, "IP range values must be 0-255")
+		}
+	}
+	return nil
+}
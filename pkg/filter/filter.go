@@ -1,10 +1,16 @@
 // Package filter bietet wiederverwendbare Filterlogik für Tabellen und Listen.
-// Unterstützt boolesche Ausdrücke (AND, OR, NOT), Klammern, Spalten-Filter,
-// Wildcards, CIDR und IP-Bereiche.
+// Unterstützt boolesche Ausdrücke (AND, OR, NOT), Klammern, Spalten-Filter mit
+// typisierten Vergleichen (Text, Zahl, Dauer, IP, Liste), Regex, in-Listen,
+// Wildcards, CIDR und IP-Bereiche. Ausdrücke werden einmal übersetzt und dann
+// für jede Zeile wiederverwendet.
 //
 // Beispiele:
-//   - Einfach: "apple" (sucht in allen Feldern)
+//   - Einfach: "apple" (sucht in allen Text- und IP-Feldern)
 //   - Spalte: "vendor=Apple" (sucht nur in Vendor-Spalte)
+//   - Anführungszeichen: vendor="Apple Inc." oder host='my nas'
+//   - Vergleiche: "rtt>50ms", "flaps>=3", "uptime<1d", "port=22", "status!=online"
+//   - Regex: "host~^nas[0-9]+$" oder "vendor!~apple"
+//   - Listen: vendor in (Apple, Samsung, "AVM GmbH")
 //   - Wildcard: "192.168.*" oder "*router*"
 //   - Boolean: "apple && online" oder "apple || samsung"
 //   - Negation: "!offline" oder "NOT offline"
@@ -16,6 +22,7 @@ package filter
 import (
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // Filter repräsentiert einen konfigurierten Filter
@@ -31,9 +38,29 @@ type Filter struct {
 	// Default: "ip"
 	IPField string
 
+	// FieldTypes legt fest, wie Felder verglichen werden (z.B. {"rtt": TypeDuration})
+	// Felder ohne Eintrag sind TypeText
+	FieldTypes map[string]FieldType
+
 	// CaseSensitive aktiviert Groß/Kleinschreibung-Unterscheidung
 	// Default: false (case-insensitive)
 	CaseSensitive bool
+
+	mu       sync.Mutex
+	compiled *compiled // Übersetzter Ausdruck (Cache für Match)
+}
+
+// compiled ist ein übersetzter Ausdruck mit der Konfiguration, für die er gilt
+type compiled struct {
+	key  compileKey
+	root node
+	err  error
+}
+
+type compileKey struct {
+	expression    string
+	ipField       string
+	caseSensitive bool
 }
 
 // New erstellt einen neuen Filter mit Standard-Konfiguration
@@ -47,6 +74,14 @@ func New(expression string) *Filter {
 // WithAliases setzt Feld-Aliase (fluent API)
 func (f *Filter) WithAliases(aliases map[string]string) *Filter {
 	f.FieldAliases = aliases
+	f.reset()
+	return f
+}
+
+// WithTypes setzt die Feld-Typen für Vergleiche (fluent API)
+func (f *Filter) WithTypes(types map[string]FieldType) *Filter {
+	f.FieldTypes = types
+	f.reset()
 	return f
 }
 
@@ -62,196 +97,58 @@ func (f *Filter) CaseSensitiveMode(enabled bool) *Filter {
 	return f
 }
 
-// Match prüft ob die gegebenen Felder zum Filter passen
-// fields ist eine Map von Feldname → Wert (z.B. {"ip": "192.168.1.1", "host": "router"})
-func (f *Filter) Match(fields map[string]string) bool {
-	if f.Expression == "" {
-		return true
-	}
-
-	// Felder normalisieren (lowercase falls nicht case-sensitive)
-	normalizedFields := make(map[string]string, len(fields))
-	for k, v := range fields {
-		key := k
-		val := v
-		if !f.CaseSensitive {
-			key = strings.ToLower(k)
-			val = strings.ToLower(v)
-		}
-		normalizedFields[key] = val
-	}
-
-	// Alle Feldwerte für Suche ohne Präfix
-	allValues := make([]string, 0, len(normalizedFields))
-	for _, v := range normalizedFields {
-		allValues = append(allValues, v)
-	}
-
-	// Expression normalisieren (AND/OR/NOT → &&/||/!)
-	expr := NormalizeOperators(f.Expression)
-	if !f.CaseSensitive {
-		expr = strings.ToLower(expr)
-	}
-
-	return f.evaluateExpression(expr, normalizedFields, allValues)
+// reset verwirft den übersetzten Ausdruck (nach Änderung von Aliasen oder Typen)
+func (f *Filter) reset() {
+	f.mu.Lock()
+	f.compiled = nil
+	f.mu.Unlock()
 }
 
-// evaluateExpression evaluiert einen Filter-Ausdruck mit Klammern
-func (f *Filter) evaluateExpression(expr string, fields map[string]string, allValues []string) bool {
-	expr = strings.TrimSpace(expr)
-	if expr == "" {
-		return true
-	}
-
-	// Klammern verarbeiten (von innen nach außen)
-	for strings.Contains(expr, "(") {
-		// Innerste Klammer finden
-		start := strings.LastIndex(expr, "(")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(expr[start:], ")")
-		if end == -1 {
-			break // Ungültige Klammer
-		}
-		end += start
+// Compile übersetzt den Ausdruck und gibt Syntax- und Typfehler mit Position zurück
+// (ValidationError). Match ruft Compile automatisch auf; das Ergebnis wird
+// zwischengespeichert, bis sich Expression, IPField oder CaseSensitive ändern.
+func (f *Filter) Compile() error {
+	_, err := f.compile()
+	return err
+}
 
-		// Inhalt der Klammer evaluieren
-		inner := expr[start+1 : end]
-		result := f.evaluateExpression(inner, fields, allValues)
+// compile gibt den übersetzten Ausdruck zurück (aus dem Cache falls aktuell)
+func (f *Filter) compile() (node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-		// Ergebnis als Platzhalter einsetzen
-		placeholder := "__TRUE__"
-		if !result {
-			placeholder = "__FALSE__"
-		}
-		expr = expr[:start] + placeholder + expr[end+1:]
+	key := compileKey{f.Expression, f.IPField, f.CaseSensitive}
+	if f.compiled != nil && f.compiled.key == key {
+		return f.compiled.root, f.compiled.err
 	}
 
-	// OR hat niedrigste Priorität
-	if strings.Contains(expr, "||") {
-		orParts := strings.Split(expr, "||")
-		for _, orPart := range orParts {
-			orPart = strings.TrimSpace(orPart)
-			if orPart == "" {
-				continue
-			}
-			if f.evaluateAndExpression(orPart, fields, allValues) {
-				return true
-			}
-		}
-		return false
+	c := &compiler{
+		aliases: f.FieldAliases,
+		types:   f.FieldTypes,
+		ipField: f.IPField,
+		fold:    !f.CaseSensitive,
 	}
-
-	return f.evaluateAndExpression(expr, fields, allValues)
+	root, err := parse(f.Expression, c)
+	f.compiled = &compiled{key: key, root: root, err: err}
+	return root, err
 }
 
-// evaluateAndExpression evaluiert einen AND-Ausdruck
-func (f *Filter) evaluateAndExpression(expr string, fields map[string]string, allValues []string) bool {
-	if strings.Contains(expr, "&&") {
-		parts := strings.Split(expr, "&&")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if part == "" {
-				continue
-			}
-			if !f.evaluateSingleTerm(part, fields, allValues) {
-				return false
-			}
-		}
+// Match prüft ob die gegebenen Felder zum Filter passen
+// fields ist eine Map von Feldname → Wert (z.B. {"ip": "192.168.1.1", "host": "router"})
+// Ungültige Ausdrücke passen auf nichts (Fehler liefert Compile).
+func (f *Filter) Match(fields map[string]string) bool {
+	if f.Expression == "" {
 		return true
 	}
-	return f.evaluateSingleTerm(expr, fields, allValues)
-}
 
-// evaluateSingleTerm evaluiert einen einzelnen Filter-Term
-func (f *Filter) evaluateSingleTerm(term string, fields map[string]string, allValues []string) bool {
-	term = strings.TrimSpace(term)
-
-	// Platzhalter von Klammer-Auswertung
-	if term == "__TRUE__" || term == "__true__" {
-		return true
-	}
-	if term == "__FALSE__" || term == "__false__" {
+	root, err := f.compile()
+	if err != nil {
 		return false
 	}
-
-	// NOT-Operator
-	negated := false
-	if strings.HasPrefix(term, "!") {
-		negated = true
-		term = strings.TrimSpace(term[1:])
-	}
-
-	if term == "" {
-		return !negated
-	}
-
-	var matches bool
-
-	// Spalten-Filter prüfen (z.B. vendor=Apple)
-	if strings.Contains(term, "=") {
-		parts := strings.SplitN(term, "=", 2)
-		if len(parts) == 2 {
-			column := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			// Alias auflösen
-			if f.FieldAliases != nil {
-				if resolved, ok := f.FieldAliases[column]; ok {
-					column = resolved
-				}
-			}
-
-			if fieldValue, ok := fields[column]; ok {
-				matches = MatchValue(value, fieldValue)
-			} else {
-				matches = false // Unbekannte Spalte
-			}
-		} else {
-			matches = false
-		}
-	} else {
-		// Kein Spalten-Präfix - in allen Feldern suchen
-		// IP-Feld für spezielle Filter extrahieren
-		ipValue := ""
-		if f.IPField != "" {
-			ipValue = fields[f.IPField]
-		}
-		matches = matchAllFields(term, allValues, ipValue)
-	}
-
-	if negated {
-		return !matches
-	}
-	return matches
-}
-
-// matchAllFields prüft einen Filter gegen alle Felder
-func matchAllFields(filter string, fields []string, ipField string) bool {
-	// CIDR-Filter
-	if strings.Contains(filter, "/") && ipField != "" {
-		return MatchCIDR(filter, ipField)
-	}
-
-	// IP-Range-Filter
-	if IsIPRange(filter) && ipField != "" {
-		return MatchIPRange(filter, ipField)
-	}
-
-	// Wildcard-Filter
-	if strings.Contains(filter, "*") {
-		return MatchWildcard(filter, fields)
-	}
-
-	// Substring-Match (für Benutzerfreundlichkeit)
-	// Suche in allen Feldern nach dem Filter als Substring
-	for _, field := range fields {
-		if field != "" && strings.Contains(field, filter) {
-			return true
-		}
+	if root == nil {
+		return true
 	}
-	return false
+	return root.match(fields)
 }
 
 // NormalizeOperators ersetzt Wort-Operatoren durch Symbole
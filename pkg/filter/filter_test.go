@@ -1,6 +1,7 @@
 package filter_test
 
 import (
+	"errors"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -198,6 +199,97 @@ var _ = Describe("Filter Package", func() {
 		})
 	})
 
+	Describe("Typisierte Vergleiche", func() {
+		var fields map[string]string
+		var types map[string]filter.FieldType
+
+		BeforeEach(func() {
+			fields = map[string]string{
+				"ip":     "192.168.1.100",
+				"host":   "nas01.local",
+				"vendor": "Apple Inc.",
+				"rtt":    "62.5ms",
+				"uptime": "26h0m0s",
+				"flaps":  "3",
+				"port":   "22 80 443",
+			}
+			types = map[string]filter.FieldType{
+				"ip":     filter.TypeIP,
+				"rtt":    filter.TypeDuration,
+				"uptime": filter.TypeDuration,
+				"flaps":  filter.TypeNumber,
+				"port":   filter.TypeList,
+			}
+		})
+
+		match := func(expression string) bool {
+			return filter.New(expression).WithTypes(types).Match(fields)
+		}
+
+		It("sollte Dauern vergleichen", func() {
+			Expect(match("rtt>50ms")).To(BeTrue())
+			Expect(match("rtt<=50ms")).To(BeFalse())
+			Expect(match("uptime>1d")).To(BeTrue())
+			Expect(match("uptime<1d2h")).To(BeFalse())
+		})
+
+		It("sollte Zahlen vergleichen", func() {
+			Expect(match("flaps>=3")).To(BeTrue())
+			Expect(match("flaps>3")).To(BeFalse())
+			Expect(match("flaps!=3")).To(BeFalse())
+		})
+
+		It("sollte Listenelemente exakt vergleichen", func() {
+			Expect(match("port=22")).To(BeTrue())
+			Expect(match("port=44")).To(BeFalse())
+			Expect(match("port>1000")).To(BeFalse())
+			Expect(match("port in (8080, 443)")).To(BeTrue())
+		})
+
+		It("sollte IPs exakt und numerisch vergleichen", func() {
+			Expect(match("ip=192.168.1.10")).To(BeFalse())
+			Expect(match("ip=192.168.1.100")).To(BeTrue())
+			Expect(match("ip=192.168.1.0/24")).To(BeTrue())
+			Expect(match("ip>192.168.1.99")).To(BeTrue())
+			Expect(match("ip<192.168.1.20")).To(BeFalse())
+		})
+
+		It("sollte Regex unterstützen", func() {
+			Expect(match("host~^nas[0-9]+")).To(BeTrue())
+			Expect(match("host!~^nas")).To(BeFalse())
+			Expect(match("vendor~APPLE")).To(BeTrue())
+		})
+
+		It("sollte Werte in Anführungszeichen mit Leerzeichen matchen", func() {
+			Expect(match(`vendor="Apple Inc."`)).To(BeTrue())
+			Expect(match(`vendor in ('Samsung', "Apple Inc.")`)).To(BeTrue())
+			Expect(match(`"inc. apple"`)).To(BeFalse())
+		})
+
+		It("sollte Nicht-Text-Felder bei der Suche ohne Feldnamen ignorieren", func() {
+			Expect(match("3")).To(BeFalse())
+			Expect(match("nas01")).To(BeTrue())
+		})
+
+		It("sollte Terme ohne Operator implizit mit AND verknüpfen", func() {
+			Expect(match("apple nas01")).To(BeTrue())
+			Expect(match("apple samsung")).To(BeFalse())
+		})
+
+		It("sollte ungültige Werte typisierter Felder mit Position melden", func() {
+			err := filter.New("status=online && rtt>fast").WithTypes(types).Compile()
+			Expect(err).To(HaveOccurred())
+			var validationErr filter.ValidationError
+			Expect(errors.As(err, &validationErr)).To(BeTrue())
+			Expect(validationErr.Pos).To(Equal(21))
+			Expect(validationErr.Term).To(Equal("fast"))
+		})
+
+		It("sollte ungültige Ausdrücke nicht matchen", func() {
+			Expect(match("flaps>=many")).To(BeFalse())
+		})
+	})
+
 	Describe("Validate", func() {
 		It("sollte leeren Filter akzeptieren", func() {
 			err := filter.Validate("")
@@ -243,6 +335,26 @@ var _ = Describe("Filter Package", func() {
 			err := filter.Validate("192.168.1.1-50")
 			Expect(err).To(BeNil())
 		})
+
+		It("sollte die Position des Fehlers melden", func() {
+			err := filter.Validate("vendor=Apple && (status=online")
+			Expect(err).To(MatchError(ContainSubstring("position 17")))
+
+			err = filter.Validate("vendor=Apple && && online")
+			Expect(err).To(MatchError(ContainSubstring("position 17")))
+		})
+
+		It("sollte ungültige Regex ablehnen", func() {
+			Expect(filter.Validate("host~(")).NotTo(BeNil())
+		})
+
+		It("sollte nicht abgeschlossene Anführungszeichen ablehnen", func() {
+			Expect(filter.Validate(`vendor="Apple`)).NotTo(BeNil())
+		})
+
+		It("sollte Vergleiche ohne Wert ablehnen", func() {
+			Expect(filter.Validate("rtt>")).NotTo(BeNil())
+		})
 	})
 
 	Describe("NormalizeOperators", func() {
@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind ist die Art eines Tokens im Filter-Ausdruck
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokWord             // Wert oder Feldname ohne Anführungszeichen
+	tokString           // Wert in Anführungszeichen ("..." oder '...')
+	tokOp               // Vergleichsoperator: = == != < <= > >= ~ !~
+	tokAnd              // && oder AND
+	tokOr               // || oder OR
+	tokNot              // ! oder NOT
+	tokLParen           // (
+	tokRParen           // )
+	tokComma            // , (Trennzeichen in in-Listen)
+)
+
+// token ist ein Element des Filter-Ausdrucks mit seiner Position (Byte-Offset)
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// isWordChar prüft ob ein Zeichen Teil eines Werts ohne Anführungszeichen sein kann
+func isWordChar(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	return !strings.ContainsRune(`()!=<>~&|,"'`, r)
+}
+
+// tokenize zerlegt einen Filter-Ausdruck in Tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '&' || c == '|':
+			if i+1 >= len(input) || input[i+1] != c {
+				return nil, ValidationError{Message: "Single & or | is not an operator (use && or ||)", Term: string(c), Pos: i}
+			}
+			kind := tokAnd
+			if c == '|' {
+				kind = tokOr
+			}
+			tokens = append(tokens, token{kind, input[i : i+2], i})
+			i += 2
+		case c == '!':
+			if i+1 < len(input) && (input[i+1] == '=' || input[i+1] == '~') {
+				tokens = append(tokens, token{tokOp, input[i : i+2], i})
+				i += 2
+			} else {
+				tokens = append(tokens, token{tokNot, "!", i})
+				i++
+			}
+		case c == '=':
+			// "==" ist gleichbedeutend mit "="
+			tokens = append(tokens, token{tokOp, "=", i})
+			i++
+			if i < len(input) && input[i] == '=' {
+				i++
+			}
+		case c == '<' || c == '>':
+			if i+1 < len(input) && input[i+1] == '=' {
+				tokens = append(tokens, token{tokOp, input[i : i+2], i})
+				i += 2
+			} else {
+				tokens = append(tokens, token{tokOp, input[i : i+1], i})
+				i++
+			}
+		case c == '~':
+			tokens = append(tokens, token{tokOp, "~", i})
+			i++
+		case c == '"' || c == '\'':
+			text, end, err := readQuoted(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if !isWordChar(r) {
+					break
+				}
+				i += size
+			}
+			if i == start {
+				// Sonstige Leerzeichen (z.B. geschütztes Leerzeichen)
+				_, size := utf8.DecodeRuneInString(input[i:])
+				i += size
+				continue
+			}
+			word := input[start:i]
+			kind := tokWord
+			switch strings.ToLower(word) {
+			case "and":
+				kind = tokAnd
+			case "or":
+				kind = tokOr
+			case "not":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind, word, start})
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(input)})
+	return tokens, nil
+}
+
+// readQuoted liest einen Wert in Anführungszeichen ab Position start.
+// Backslash maskiert das Anführungszeichen und sich selbst.
+func readQuoted(input string, start int) (string, int, error) {
+	quote := input[start]
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\\' && i+1 < len(input) && (input[i+1] == quote || input[i+1] == '\\'):
+			b.WriteByte(input[i+1])
+			i++
+		case c == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, ValidationError{Message: "Unterminated quoted value", Term: input[start:], Pos: start}
+}
@@ -0,0 +1,213 @@
+package filter
+
+import "strings"
+
+// Grammatik (Priorität aufsteigend):
+//
+//	expr    = and { ("||" | "OR") and }
+//	and     = unary { ["&&" | "AND"] unary }     (ohne Operator = implizites AND)
+//	unary   = ("!" | "NOT") unary | primary
+//	primary = "(" expr ")" | term
+//	term    = value                              (Suche in allen Feldern)
+//	        | value op value                     (op: = == != < <= > >= ~ !~)
+//	        | value "in" "(" value { "," value } ")"
+//	value   = word | "quoted" | 'quoted'
+
+// parser übersetzt die Tokens direkt in ausführbare Knoten
+type parser struct {
+	tokens []token
+	pos    int
+	c      *compiler
+}
+
+// parse übersetzt einen Ausdruck (nil-Knoten bei leerem Ausdruck)
+func parse(expression string, c *compiler) (node, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, c: c}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, errorAt(tok, "Unbalanced parentheses")
+		}
+		return nil, errorAt(tok, "Unexpected token")
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokNot, tokLParen:
+			// Implizites AND: "apple online"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokLParen:
+		p.next()
+		if p.peek().kind == tokRParen {
+			return nil, errorAt(p.peek(), "Empty parentheses")
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		switch next := p.peek(); next.kind {
+		case tokRParen:
+		case tokEOF:
+			return nil, errorAt(tok, "Unclosed parenthesis")
+		default:
+			return nil, errorAt(next, "Unexpected token")
+		}
+		p.next()
+		return inner, nil
+	case tokWord, tokString:
+		return p.parseTerm()
+	case tokEOF:
+		return nil, errorAt(tok, "Expression cannot end with AND/OR/NOT")
+	case tokAnd, tokOr:
+		return nil, errorAt(tok, "Missing filter term before AND/OR")
+	case tokOp:
+		return nil, errorAt(tok, "Missing field name before operator")
+	case tokRParen:
+		return nil, errorAt(tok, "Unbalanced parentheses")
+	default:
+		return nil, errorAt(tok, "Unexpected token")
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	first := p.next()
+
+	switch next := p.peek(); {
+	case next.kind == tokOp:
+		op := p.next()
+		value := p.parseValue(op)
+		return p.c.comparison(first, op, value)
+	case next.kind == tokWord && strings.EqualFold(next.text, "in") && p.peekAt(1).kind == tokLParen:
+		p.next()
+		return p.parseIn(first)
+	}
+	return p.c.search(first)
+}
+
+// parseValue liest den Wert nach einem Operator. Ein fehlender Wert ergibt einen
+// leeren Wert ("vendor=" trifft alle Geräte mit bekanntem Feld).
+func (p *parser) parseValue(op token) token {
+	if tok := p.peek(); tok.kind == tokWord || tok.kind == tokString {
+		return p.next()
+	}
+	return token{kind: tokWord, pos: op.pos + len(op.text)}
+}
+
+// parseIn liest eine Werte-Liste: field in (a, b, "c d")
+func (p *parser) parseIn(field token) (node, error) {
+	open := p.next()
+
+	var values []token
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case tokWord, tokString:
+			values = append(values, tok)
+		case tokRParen:
+			if len(values) == 0 {
+				return nil, errorAt(tok, "Empty 'in' list")
+			}
+			return nil, errorAt(tok, "Missing value in 'in' list")
+		case tokEOF:
+			return nil, errorAt(open, "Unclosed parenthesis")
+		default:
+			return nil, errorAt(tok, "Expected value in 'in' list")
+		}
+
+		switch sep := p.next(); sep.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return p.c.in(field, values)
+		case tokEOF:
+			return nil, errorAt(open, "Unclosed parenthesis")
+		default:
+			return nil, errorAt(sep, "Expected ',' or ')' in 'in' list")
+		}
+	}
+}
+
+// errorAt erzeugt einen Fehler an der Position eines Tokens
+func errorAt(tok token, message string) error {
+	return ValidationError{Message: message, Term: tok.text, Pos: tok.pos}
+}
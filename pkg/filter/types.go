@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType bestimmt, wie Werte eines Felds verglichen werden
+type FieldType int
+
+const (
+	// TypeText vergleicht als Text: "=" ist Substring-/Wildcard-Match, "<"/">"
+	// vergleichen numerisch, wenn beide Seiten Zahlen oder Dauern sind (Default)
+	TypeText FieldType = iota
+	// TypeNumber vergleicht Ganz- und Dezimalzahlen (z.B. flaps>=3)
+	TypeNumber
+	// TypeDuration vergleicht Zeitdauern (z.B. rtt>50ms, uptime<1h, uptime>2d)
+	TypeDuration
+	// TypeIP vergleicht IP-Adressen: "=" akzeptiert IP, CIDR, Bereich und Wildcard,
+	// "<"/">" vergleichen numerisch
+	TypeIP
+	// TypeList ist eine durch Leerzeichen getrennte Liste (z.B. Ports "22 80 443");
+	// ein Vergleich trifft zu, wenn er für ein Element zutrifft
+	TypeList
+)
+
+// String gibt den Namen des Typs für Fehlermeldungen zurück
+func (t FieldType) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeDuration:
+		return "duration"
+	case TypeIP:
+		return "IP"
+	case TypeList:
+		return "list"
+	default:
+		return "text"
+	}
+}
+
+// ParseDuration parst eine Dauer wie time.ParseDuration, zusätzlich mit Tagen
+// als Einheit ("2d", "1d12h")
+func ParseDuration(value string) (time.Duration, error) {
+	if i := strings.IndexByte(value, 'd'); i > 0 {
+		if days, err := strconv.ParseFloat(value[:i], 64); err == nil {
+			duration := time.Duration(days * float64(24*time.Hour))
+			if rest := value[i+1:]; rest != "" {
+				extra, err := time.ParseDuration(rest)
+				if err != nil {
+					return 0, err
+				}
+				duration += extra
+			}
+			return duration, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+// compareIPs vergleicht zwei IP-Adressen numerisch (-1, 0, 1)
+func compareIPs(a, b net.IP) int {
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// compareNatural vergleicht zwei Texte: als Zahlen, Dauern oder IPs wenn beide
+// Seiten sich so parsen lassen, sonst lexikografisch
+func compareNatural(a, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			return compareFloats(x, y)
+		}
+	}
+	if x, err := ParseDuration(a); err == nil {
+		if y, err := ParseDuration(b); err == nil {
+			return compareFloats(float64(x), float64(y))
+		}
+	}
+	if x := net.ParseIP(a); x != nil {
+		if y := net.ParseIP(b); y != nil {
+			return compareIPs(x, y)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// orderingHolds prüft das Ergebnis eines Vergleichs (-1, 0, 1) gegen den Operator
+func orderingHolds(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
@@ -0,0 +1,450 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"netspy/pkg/paths"
+	"netspy/pkg/scanner"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket-Namen der Datenbank
+var (
+	bucketDevices   = []byte("devices")   // Geräte-Key -> Device (JSON)
+	bucketSightings = []byte("sightings") // Geräte-Key -> Unter-Bucket (Scan-ID -> Sighting)
+	bucketScans     = []byte("scans")     // Scan-ID -> Scan (JSON)
+)
+
+// DefaultMaxSightings begrenzt die gespeicherten Sichtungen pro Gerät
+const DefaultMaxSightings = 1000
+
+// ErrLocked wird zurückgegeben, wenn ein anderer netspy-Prozess die Datenbank geöffnet hat
+var ErrLocked = errors.New("inventory database is locked by another netspy process")
+
+// AddressRecord ist ein Eintrag der IP-Historie eines Geräts
+type AddressRecord struct {
+	IP        string    `json:"ip"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// HostnameRecord ist ein Eintrag der Hostname-Historie eines Geräts
+type HostnameRecord struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Device ist ein Gerät im Inventar, identifiziert über die MAC-Adresse
+// (bzw. "ip:<adresse>" für Hosts ohne bekannte MAC, z.B. aus ICMP-Scans entfernter Netze)
+type Device struct {
+	Key              string           `json:"key"`
+	MAC              string           `json:"mac,omitempty"`
+	Vendor           string           `json:"vendor,omitempty"`
+	DeviceType       string           `json:"device_type,omitempty"`
+	FirstSeen        time.Time        `json:"first_seen"`
+	LastSeen         time.Time        `json:"last_seen"`
+	Status           string           `json:"status"` // "online" oder "offline"
+	StatusSince      time.Time        `json:"status_since"`
+	FlapCount        int              `json:"flap_count"`
+	TotalOfflineTime time.Duration    `json:"total_offline_time"`
+	IPs              []AddressRecord  `json:"ips"`
+	Hostnames        []HostnameRecord `json:"hostnames,omitempty"`
+	Sightings        int              `json:"sightings"`
+}
+
+// CurrentIP gibt die zuletzt gesehene IPv4-Adresse zurück (sonst die zuletzt gesehene Adresse)
+func (d Device) CurrentIP() string {
+	best := ""
+	var bestSeen time.Time
+	bestIsV4 := false
+
+	for _, addr := range d.IPs {
+		ip := net.ParseIP(addr.IP)
+		isV4 := ip != nil && ip.To4() != nil
+		switch {
+		case best == "",
+			isV4 && !bestIsV4,
+			isV4 == bestIsV4 && addr.LastSeen.After(bestSeen):
+			best, bestSeen, bestIsV4 = addr.IP, addr.LastSeen, isV4
+		}
+	}
+	return best
+}
+
+// CurrentHostname gibt den zuletzt gesehenen Hostnamen und seine Quelle zurück
+func (d Device) CurrentHostname() (string, string) {
+	var latest *HostnameRecord
+	for i := range d.Hostnames {
+		if latest == nil || d.Hostnames[i].LastSeen.After(latest.LastSeen) {
+			latest = &d.Hostnames[i]
+		}
+	}
+	if latest == nil {
+		return "", ""
+	}
+	return latest.Name, latest.Source
+}
+
+// Sighting ist eine einzelne Sichtung eines Geräts in einem Scan
+type Sighting struct {
+	ScanID uint64        `json:"scan_id"`
+	Time   time.Time     `json:"time"`
+	IP     string        `json:"ip"`
+	RTT    time.Duration `json:"rtt,omitempty"`
+}
+
+// Scan beschreibt einen aufgezeichneten Scan-Lauf
+type Scan struct {
+	ID      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	Network string    `json:"network"`
+	Source  string    `json:"source"` // "scan" oder "watch"
+	Mode    string    `json:"mode,omitempty"`
+	Online  int       `json:"online"`
+}
+
+// Store ist das persistente Geräte-Inventar (bbolt-Datenbank)
+type Store struct {
+	db *bolt.DB
+
+	// MaxSightings begrenzt die gespeicherten Sichtungen pro Gerät (älteste werden gelöscht)
+	MaxSightings int
+}
+
+// DefaultPath gibt den Standard-Pfad der Inventar-Datenbank zurück
+func DefaultPath() (string, error) {
+	return paths.DataFile("inventory.db")
+}
+
+// Open öffnet (oder erstellt) die Inventar-Datenbank.
+// Ist sie von einem anderen Prozess geöffnet, wird nach kurzer Wartezeit ErrLocked zurückgegeben.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("failed to open inventory %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketDevices, bucketSightings, bucketScans} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize inventory: %v", err)
+	}
+
+	return &Store{db: db, MaxSightings: DefaultMaxSightings}, nil
+}
+
+// Close schließt die Datenbank
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DeviceKey gibt den Inventar-Key eines Hosts zurück (normalisierte MAC oder "ip:<adresse>")
+func DeviceKey(host scanner.Host) string {
+	if mac, err := net.ParseMAC(host.MAC); err == nil {
+		return mac.String()
+	}
+	return "ip:" + host.IP.String()
+}
+
+// Record speichert die Ergebnisse eines Scans: Online-Hosts werden angelegt bzw. aktualisiert
+// (IP-/Hostname-Historie, Sichtung). Ist network gesetzt, werden bisher online gemeldete
+// Geräte dieses Netzwerks, die im Scan fehlen, als offline markiert.
+// Gibt die ID des aufgezeichneten Scans zurück.
+func (s *Store) Record(scan Scan, network *net.IPNet, hosts []scanner.Host) (uint64, error) {
+	if scan.Time.IsZero() {
+		scan.Time = time.Now()
+	}
+	if scan.Network == "" && network != nil {
+		scan.Network = network.String()
+	}
+	now := scan.Time
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		devices := tx.Bucket(bucketDevices)
+		sightings := tx.Bucket(bucketSightings)
+		scans := tx.Bucket(bucketScans)
+
+		id, err := scans.NextSequence()
+		if err != nil {
+			return err
+		}
+		scan.ID = id
+
+		seen := make(map[string]bool)
+		for _, host := range hosts {
+			if !host.Online {
+				continue
+			}
+			scan.Online++
+
+			key := DeviceKey(host)
+			seen[key] = true
+
+			device, err := getDevice(devices, key)
+			if err != nil {
+				return err
+			}
+			if device == nil {
+				device = &Device{Key: key, FirstSeen: now, Status: "online", StatusSince: now}
+			}
+
+			observe(device, host, now)
+
+			if err := s.addSighting(sightings, device, Sighting{ScanID: id, Time: now, IP: host.IP.String(), RTT: host.RTT}); err != nil {
+				return err
+			}
+			if err := putDevice(devices, device); err != nil {
+				return err
+			}
+		}
+
+		// Fehlende Geräte des gescannten Netzwerks als offline markieren
+		if network != nil {
+			err := devices.ForEach(func(k, v []byte) error {
+				if seen[string(k)] {
+					return nil
+				}
+				var device Device
+				if err := json.Unmarshal(v, &device); err != nil {
+					return nil // Defekte Einträge überspringen
+				}
+				ip := net.ParseIP(device.CurrentIP())
+				if device.Status != "online" || ip == nil || !network.Contains(ip) {
+					return nil
+				}
+
+				device.Status = "offline"
+				device.StatusSince = now
+				device.FlapCount++
+				return putDevice(devices, &device)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(scan)
+		if err != nil {
+			return err
+		}
+		return scans.Put(itob(id), data)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return scan.ID, nil
+}
+
+// observe überträgt einen Online-Host auf ein Gerät
+func observe(device *Device, host scanner.Host, now time.Time) {
+	if device.Status == "offline" {
+		device.TotalOfflineTime += now.Sub(device.StatusSince)
+		device.Status = "online"
+		device.StatusSince = now
+		device.FlapCount++
+	}
+	device.LastSeen = now
+	device.Sightings++
+
+	if host.MAC != "" {
+		device.MAC = host.MAC
+	}
+	if host.Vendor != "" {
+		device.Vendor = host.Vendor
+	}
+	if host.DeviceType != "" && host.DeviceType != "Unknown" {
+		device.DeviceType = host.DeviceType
+	}
+
+	device.addIP(host.IP.String(), now)
+	for _, addr := range host.IPv6 {
+		device.addIP(addr.IP.String(), now)
+	}
+	if host.Hostname != "" {
+		device.addHostname(host.Hostname, host.HostnameSource, now)
+	}
+}
+
+// addIP ergänzt die IP-Historie
+func (d *Device) addIP(ip string, now time.Time) {
+	for i := range d.IPs {
+		if d.IPs[i].IP == ip {
+			d.IPs[i].LastSeen = now
+			return
+		}
+	}
+	d.IPs = append(d.IPs, AddressRecord{IP: ip, FirstSeen: now, LastSeen: now})
+}
+
+// addHostname ergänzt die Hostname-Historie (Groß-/Kleinschreibung wird ignoriert)
+func (d *Device) addHostname(name, source string, now time.Time) {
+	for i := range d.Hostnames {
+		if strings.EqualFold(d.Hostnames[i].Name, name) {
+			d.Hostnames[i].LastSeen = now
+			if source != "" {
+				d.Hostnames[i].Source = source
+			}
+			return
+		}
+	}
+	d.Hostnames = append(d.Hostnames, HostnameRecord{Name: name, Source: source, FirstSeen: now, LastSeen: now})
+}
+
+// addSighting speichert eine Sichtung und löscht die ältesten über MaxSightings hinaus
+func (s *Store) addSighting(sightings *bolt.Bucket, device *Device, sighting Sighting) error {
+	bucket, err := sightings.CreateBucketIfNotExists([]byte(device.Key))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(sighting)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put(itob(sighting.ScanID), data); err != nil {
+		return err
+	}
+
+	if s.MaxSightings > 0 && device.Sightings > s.MaxSightings {
+		cursor := bucket.Cursor()
+		if k, _ := cursor.First(); k != nil && !bytes.Equal(k, itob(sighting.ScanID)) {
+			return cursor.Delete()
+		}
+	}
+	return nil
+}
+
+// Device gibt ein Gerät anhand seines Keys zurück (nil, wenn unbekannt)
+func (s *Store) Device(key string) (*Device, error) {
+	var device *Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		device, err = getDevice(tx.Bucket(bucketDevices), key)
+		return err
+	})
+	return device, err
+}
+
+// Devices gibt alle Geräte sortiert nach aktueller IP zurück
+func (s *Store) Devices() ([]Device, error) {
+	var devices []Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDevices).ForEach(func(k, v []byte) error {
+			var device Device
+			if err := json.Unmarshal(v, &device); err != nil {
+				return nil // Defekte Einträge überspringen
+			}
+			devices = append(devices, device)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		ipI, ipJ := net.ParseIP(devices[i].CurrentIP()), net.ParseIP(devices[j].CurrentIP())
+		return bytes.Compare(ipI.To16(), ipJ.To16()) < 0
+	})
+	return devices, nil
+}
+
+// DevicesInNetwork gibt alle Geräte zurück, deren aktuelle IP im Netzwerk liegt
+func (s *Store) DevicesInNetwork(network *net.IPNet) ([]Device, error) {
+	devices, err := s.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Device
+	for _, device := range devices {
+		if ip := net.ParseIP(device.CurrentIP()); ip != nil && network.Contains(ip) {
+			filtered = append(filtered, device)
+		}
+	}
+	return filtered, nil
+}
+
+// Sightings gibt die Sichtungen eines Geräts zurück (älteste zuerst)
+func (s *Store) Sightings(key string) ([]Sighting, error) {
+	var result []Sighting
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSightings).Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var sighting Sighting
+			if err := json.Unmarshal(v, &sighting); err == nil {
+				result = append(result, sighting)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+// Scans gibt alle aufgezeichneten Scans zurück (älteste zuerst)
+func (s *Store) Scans() ([]Scan, error) {
+	var result []Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketScans).ForEach(func(k, v []byte) error {
+			var scan Scan
+			if err := json.Unmarshal(v, &scan); err == nil {
+				result = append(result, scan)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+// getDevice liest ein Gerät aus dem Bucket (nil, wenn nicht vorhanden)
+func getDevice(devices *bolt.Bucket, key string) (*Device, error) {
+	data := devices.Get([]byte(key))
+	if data == nil {
+		return nil, nil
+	}
+	var device Device
+	if err := json.Unmarshal(data, &device); err != nil {
+		return nil, fmt.Errorf("corrupt inventory entry %s: %v", key, err)
+	}
+	return &device, nil
+}
+
+// putDevice schreibt ein Gerät in den Bucket
+func putDevice(devices *bolt.Bucket, device *Device) error {
+	data, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+	return devices.Put([]byte(device.Key), data)
+}
+
+// itob kodiert eine ID als 8-Byte Big-Endian (sortiert korrekt in bbolt)
+func itob(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
@@ -0,0 +1,152 @@
+package inventory_test
+
+import (
+	"net"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/inventory"
+	"netspy/pkg/scanner"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		path    string
+		store   *inventory.Store
+		network *net.IPNet
+		start   time.Time
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "inventory.db")
+
+		var err error
+		store, err = inventory.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, network, _ = net.ParseCIDR("192.0.2.0/24")
+		start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		if store != nil {
+			Expect(store.Close()).To(Succeed())
+		}
+	})
+
+	host := func(ip, mac, hostname string) scanner.Host {
+		return scanner.Host{IP: net.ParseIP(ip), MAC: mac, Hostname: hostname, HostnameSource: "dns", Online: true}
+	}
+
+	It("should key devices by normalized MAC and fall back to the IP", func() {
+		Expect(inventory.DeviceKey(host("192.0.2.10", "AA-BB-CC-DD-EE-FF", ""))).To(Equal("aa:bb:cc:dd:ee:ff"))
+		Expect(inventory.DeviceKey(host("192.0.2.10", "", ""))).To(Equal("ip:192.0.2.10"))
+	})
+
+	It("should track IP and hostname history across scans", func() {
+		_, err := store.Record(inventory.Scan{Time: start, Source: "scan"}, network,
+			[]scanner.Host{host("192.0.2.10", "aa:bb:cc:dd:ee:ff", "laptop")})
+		Expect(err).NotTo(HaveOccurred())
+
+		later := start.Add(time.Hour)
+		_, err = store.Record(inventory.Scan{Time: later, Source: "scan"}, network,
+			[]scanner.Host{host("192.0.2.20", "aa:bb:cc:dd:ee:ff", "laptop-new")})
+		Expect(err).NotTo(HaveOccurred())
+
+		device, err := store.Device("aa:bb:cc:dd:ee:ff")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device).NotTo(BeNil())
+		Expect(device.FirstSeen).To(BeTemporally("==", start))
+		Expect(device.LastSeen).To(BeTemporally("==", later))
+		Expect(device.IPs).To(HaveLen(2))
+		Expect(device.CurrentIP()).To(Equal("192.0.2.20"))
+		Expect(device.Hostnames).To(HaveLen(2))
+
+		name, source := device.CurrentHostname()
+		Expect(name).To(Equal("laptop-new"))
+		Expect(source).To(Equal("dns"))
+
+		sightings, err := store.Sightings(device.Key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sightings).To(HaveLen(2))
+		Expect(sightings[1].IP).To(Equal("192.0.2.20"))
+	})
+
+	It("should mark missing devices offline and count flaps", func() {
+		mac := "aa:bb:cc:dd:ee:01"
+		_, err := store.Record(inventory.Scan{Time: start}, network, []scanner.Host{host("192.0.2.10", mac, "")})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.Record(inventory.Scan{Time: start.Add(time.Minute)}, network, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		device, err := store.Device(mac)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Status).To(Equal("offline"))
+		Expect(device.FlapCount).To(Equal(1))
+
+		_, err = store.Record(inventory.Scan{Time: start.Add(3 * time.Minute)}, network, []scanner.Host{host("192.0.2.10", mac, "")})
+		Expect(err).NotTo(HaveOccurred())
+
+		device, err = store.Device(mac)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Status).To(Equal("online"))
+		Expect(device.FlapCount).To(Equal(2))
+		Expect(device.TotalOfflineTime).To(Equal(2 * time.Minute))
+	})
+
+	It("should not touch devices outside the scanned network", func() {
+		mac := "aa:bb:cc:dd:ee:02"
+		_, err := store.Record(inventory.Scan{Time: start}, network, []scanner.Host{host("192.0.2.10", mac, "")})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, other, _ := net.ParseCIDR("198.51.100.0/24")
+		_, err = store.Record(inventory.Scan{Time: start.Add(time.Minute)}, other, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		device, err := store.Device(mac)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Status).To(Equal("online"))
+
+		devices, err := store.DevicesInNetwork(other)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devices).To(BeEmpty())
+	})
+
+	It("should prune the oldest sightings", func() {
+		store.MaxSightings = 3
+		mac := "aa:bb:cc:dd:ee:03"
+		for i := 0; i < 5; i++ {
+			_, err := store.Record(inventory.Scan{Time: start.Add(time.Duration(i) * time.Minute)}, network,
+				[]scanner.Host{host("192.0.2.10", mac, "")})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		sightings, err := store.Sightings(mac)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sightings).To(HaveLen(3))
+		Expect(sightings[0].Time).To(BeTemporally("==", start.Add(2*time.Minute)))
+
+		scans, err := store.Scans()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scans).To(HaveLen(5))
+		Expect(scans[4].Online).To(Equal(1))
+	})
+
+	It("should persist data across reopen", func() {
+		_, err := store.Record(inventory.Scan{Time: start}, network, []scanner.Host{host("192.0.2.10", "aa:bb:cc:dd:ee:04", "")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Close()).To(Succeed())
+
+		store, err = inventory.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		devices, err := store.Devices()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devices).To(HaveLen(1))
+		Expect(devices[0].CurrentIP()).To(Equal("192.0.2.10"))
+	})
+})
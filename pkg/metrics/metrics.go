@@ -0,0 +1,227 @@
+// Package metrics exportiert den Zustand des Watch-Modus im Prometheus-Textformat
+// (für /metrics und den Textfile-Collector des node_exporters).
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"netspy/pkg/watch"
+)
+
+// DeviceLabels sind die Labels, die pro Gerät exportiert werden können.
+// "ip" identifiziert die Serie und ist immer enthalten.
+var DeviceLabels = []string{"ip", "mac", "hostname", "vendor", "device_type"}
+
+// DefaultMaxSeries begrenzt die Zahl der Geräte-Serien pro Metrik
+const DefaultMaxSeries = 10000
+
+// Options steuert die Kardinalität der Geräte-Metriken
+type Options struct {
+	Labels    []string // Label-Allowlist (leer = alle DeviceLabels)
+	MaxSeries int      // Max. Geräte pro Metrik (0 = DefaultMaxSeries, < 0 = keine Geräte-Metriken)
+}
+
+// Validate prüft die Label-Allowlist
+func (o Options) Validate() error {
+	for _, label := range o.Labels {
+		if !contains(DeviceLabels, label) {
+			return fmt.Errorf("unknown metrics label %q (valid: %s)", label, strings.Join(DeviceLabels, ", "))
+		}
+	}
+	return nil
+}
+
+// labels gibt die zu exportierenden Labels in fester Reihenfolge zurück
+func (o Options) labels() []string {
+	if len(o.Labels) == 0 {
+		return DeviceLabels
+	}
+	labels := []string{"ip"}
+	for _, label := range DeviceLabels[1:] {
+		if contains(o.Labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// maxSeries gibt die effektive Obergrenze zurück
+func (o Options) maxSeries() int {
+	switch {
+	case o.MaxSeries == 0:
+		return DefaultMaxSeries
+	case o.MaxSeries < 0:
+		return 0
+	default:
+		return o.MaxSeries
+	}
+}
+
+// Write schreibt alle Metriken im Prometheus-Textformat
+func Write(w io.Writer, stats watch.Stats, snapshot watch.Snapshot, opts Options) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.family("netspy_scans_total", "counter", "Number of completed scans.")
+	e.sample("netspy_scans_total", "", float64(stats.Scans))
+
+	e.family("netspy_scan_duration_seconds", "gauge", "Duration of the last scan.")
+	e.sample("netspy_scan_duration_seconds", "", stats.ScanDuration.Seconds())
+
+	if !stats.LastScan.IsZero() {
+		e.family("netspy_last_scan_timestamp_seconds", "gauge", "Unix time of the last scan.")
+		e.sample("netspy_last_scan_timestamp_seconds", "", float64(stats.LastScan.UnixNano())/1e9)
+	}
+
+	e.family("netspy_active_threads", "gauge", "Currently active scanner and lookup threads.")
+	e.sample("netspy_active_threads", "", float64(stats.ActiveThreads))
+
+	e.family("netspy_devices", "gauge", "Number of known devices by status.")
+	e.sample("netspy_devices", `status="online"`, float64(stats.Online))
+	e.sample("netspy_devices", `status="offline"`, float64(stats.Offline))
+
+	// Geräte-Metriken (begrenzt auf MaxSeries Geräte)
+	devices := snapshot.Devices
+	limit := opts.maxSeries()
+	dropped := 0
+	if len(devices) > limit {
+		dropped = len(devices) - limit
+		devices = devices[:limit]
+	}
+
+	e.family("netspy_device_series_dropped", "gauge", "Devices omitted from per-device metrics because of the series limit.")
+	e.sample("netspy_device_series_dropped", "", float64(dropped))
+
+	if len(devices) > 0 {
+		labels := opts.labels()
+		deviceLabels := make([]string, len(devices))
+		for i, device := range devices {
+			deviceLabels[i] = formatLabels(labels, device)
+		}
+
+		e.family("netspy_device_up", "gauge", "Whether the device answered the last scan (1 = online).")
+		for i, device := range devices {
+			up := 0.0
+			if device.Status == "online" {
+				up = 1
+			}
+			e.sample("netspy_device_up", deviceLabels[i], up)
+		}
+
+		e.family("netspy_device_rtt_seconds", "gauge", "Last measured round-trip time of the device.")
+		for i, device := range devices {
+			if device.RTT > 0 {
+				e.sample("netspy_device_rtt_seconds", deviceLabels[i], device.RTT.Seconds())
+			}
+		}
+
+		e.family("netspy_device_flaps", "gauge", "Number of online/offline status changes of the device.")
+		for i, device := range devices {
+			e.sample("netspy_device_flaps", deviceLabels[i], float64(device.FlapCount))
+		}
+
+		e.family("netspy_device_uptime_seconds", "gauge", "Time the device has been online since first seen, excluding outages (0 while offline).")
+		for i, device := range devices {
+			uptime := 0.0
+			if device.Status == "online" {
+				uptime = device.Uptime.Seconds()
+			}
+			e.sample("netspy_device_uptime_seconds", deviceLabels[i], uptime)
+		}
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// WriteFile schreibt die Metriken atomar in eine Datei (für den Textfile-Collector
+// des node_exporters, der nur *.prom-Dateien liest und keine halben Dateien sehen darf)
+func WriteFile(path string, stats watch.Stats, snapshot watch.Snapshot, opts Options) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, stats, snapshot, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// formatLabels erzeugt die Label-Liste eines Geräts (ohne geschweifte Klammern)
+func formatLabels(labels []string, device watch.DeviceSnapshot) string {
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		var value string
+		switch label {
+		case "ip":
+			value = device.IP.String()
+		case "mac":
+			value = device.MAC
+		case "hostname":
+			value = device.Hostname
+		case "vendor":
+			value = device.Vendor
+		case "device_type":
+			value = device.DeviceType
+		}
+		parts = append(parts, label+`="`+escapeLabel(value)+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+// labelEscaper maskiert Label-Werte gemäß Textformat
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// encoder schreibt Metrik-Zeilen und merkt sich den ersten Fehler
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) family(name, metricType, help string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (e *encoder) sample(name, labels string, value float64) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	e.printf("%s %s\n", name, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (e *encoder) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
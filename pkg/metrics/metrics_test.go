@@ -0,0 +1,113 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/metrics"
+	"netspy/pkg/scanner"
+	"netspy/pkg/watch"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		stats    watch.Stats
+		snapshot watch.Snapshot
+	)
+
+	device := func(ip, status string) watch.DeviceSnapshot {
+		return watch.DeviceSnapshot{
+			Host: scanner.Host{
+				IP:         net.ParseIP(ip),
+				MAC:        "aa:bb:cc:00:00:01",
+				Hostname:   `nas "main"`,
+				Vendor:     "Synology",
+				DeviceType: "NAS",
+				RTT:        1500 * time.Microsecond,
+			},
+			Status:    status,
+			FlapCount: 2,
+			Uptime:    90 * time.Second,
+		}
+	}
+
+	BeforeEach(func() {
+		stats = watch.Stats{
+			Scans:         3,
+			ScanDuration:  2500 * time.Millisecond,
+			LastScan:      time.Unix(1700000000, 0),
+			Online:        1,
+			Offline:       1,
+			ActiveThreads: 4,
+		}
+		snapshot = watch.Snapshot{Devices: []watch.DeviceSnapshot{
+			device("192.0.2.10", "online"),
+			device("192.0.2.20", "offline"),
+		}}
+	})
+
+	write := func(opts metrics.Options) string {
+		var buf bytes.Buffer
+		Expect(metrics.Write(&buf, stats, snapshot, opts)).To(Succeed())
+		return buf.String()
+	}
+
+	It("should export scan and device metrics", func() {
+		out := write(metrics.Options{})
+
+		Expect(out).To(ContainSubstring("# TYPE netspy_scans_total counter\nnetspy_scans_total 3\n"))
+		Expect(out).To(ContainSubstring("netspy_scan_duration_seconds 2.5\n"))
+		Expect(out).To(ContainSubstring("netspy_last_scan_timestamp_seconds 1.7e+09\n"))
+		Expect(out).To(ContainSubstring("netspy_active_threads 4\n"))
+		Expect(out).To(ContainSubstring(`netspy_devices{status="offline"} 1`))
+
+		labels := `ip="192.0.2.10",mac="aa:bb:cc:00:00:01",hostname="nas \"main\"",vendor="Synology",device_type="NAS"`
+		Expect(out).To(ContainSubstring("netspy_device_up{" + labels + "} 1\n"))
+		Expect(out).To(ContainSubstring("netspy_device_rtt_seconds{" + labels + "} 0.0015\n"))
+		Expect(out).To(ContainSubstring("netspy_device_flaps{" + labels + "} 2\n"))
+		Expect(out).To(ContainSubstring("netspy_device_uptime_seconds{" + labels + "} 90\n"))
+		Expect(out).To(ContainSubstring(`netspy_device_up{ip="192.0.2.20",`))
+		Expect(out).To(MatchRegexp(`netspy_device_uptime_seconds\{ip="192.0.2.20",[^}]*\} 0\n`))
+	})
+
+	It("should restrict labels to the allowlist and always keep the IP", func() {
+		out := write(metrics.Options{Labels: []string{"vendor"}})
+		Expect(out).To(ContainSubstring(`netspy_device_up{ip="192.0.2.10",vendor="Synology"} 1`))
+		Expect(out).NotTo(ContainSubstring("mac="))
+	})
+
+	It("should limit the number of device series", func() {
+		out := write(metrics.Options{MaxSeries: 1})
+		Expect(out).To(ContainSubstring("netspy_device_series_dropped 1\n"))
+		Expect(out).NotTo(ContainSubstring(`ip="192.0.2.20"`))
+
+		out = write(metrics.Options{MaxSeries: -1})
+		Expect(out).To(ContainSubstring("netspy_device_series_dropped 2\n"))
+		Expect(out).NotTo(ContainSubstring("netspy_device_up"))
+	})
+
+	It("should reject unknown labels", func() {
+		Expect(metrics.Options{Labels: []string{"ip", "os"}}.Validate()).To(HaveOccurred())
+		Expect(metrics.Options{Labels: metrics.DeviceLabels}.Validate()).To(Succeed())
+	})
+
+	It("should write textfile collector files atomically", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "netspy.prom")
+		Expect(metrics.WriteFile(path, stats, snapshot, metrics.Options{})).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("netspy_scans_total 3"))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,583 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/filter"
+	"netspy/pkg/scanner"
+	"netspy/pkg/service"
+)
+
+// Options steuert Filter, Sortierung und Spaltenauswahl der Scan-Ausgabe
+type Options struct {
+	Filter  string   // Filter-Ausdruck (gleiche Syntax wie im Watch-Filter)
+	Sort    []string // Sortier-Schlüssel in Priorität, "-" davor = absteigend (z.B. rtt,-ip)
+	Columns []string // Auszugebende Spalten in dieser Reihenfolge (leer = Standard-Ausgabe)
+}
+
+// FilterAliases sind die Kurzformen der Filter-Felder eines gescannten Hosts
+var FilterAliases = map[string]string{
+	"hostname": "host",
+	"h":        "host",
+	"m":        "mac",
+	"v":        "vendor",
+	"i":        "ip",
+	"v6":       "ipv6",
+	"dev":      "device",
+	"type":     "device",
+	"ports":    "port",
+	"p":        "port",
+	"services": "service",
+	"svc":      "service",
+	"certs":    "cert",
+	"u":        "udp",
+	"loc":      "location",
+	"mdns":     "dnssd",
+}
+
+// FilterTypes sind die typisierten Filter-Felder eines gescannten Hosts
+var FilterTypes = map[string]filter.FieldType{
+	"ip":      filter.TypeIP,
+	"rtt":     filter.TypeDuration,
+	"ttl":     filter.TypeNumber,
+	"port":    filter.TypeList,
+	"udp":     filter.TypeList,
+	"service": filter.TypeList,
+	"dnssd":   filter.TypeList,
+	"expires": filter.TypeDuration,
+}
+
+// NewFilter erstellt einen Filter mit den Feldern und Kurzformen der Scan-Ausgabe
+func NewFilter(expression string) *filter.Filter {
+	return filter.New(expression).
+		WithIPField("ip").
+		WithAliases(FilterAliases).
+		WithTypes(FilterTypes)
+}
+
+// HostFields gibt die Filter-Felder eines Hosts zurück
+func HostFields(host scanner.Host) map[string]string {
+	fields := map[string]string{
+		"ip":     host.IP.String(),
+		"ipv6":   strings.Join(host.IPv6Strings(), " "),
+		"host":   host.Hostname,
+		"mac":    host.MAC,
+		"vendor": host.Vendor,
+		"device": host.DeviceType,
+		"banner": host.HTTPBanner,
+		"rtt":    "",
+		"ttl":    "",
+		"port":   joinPorts(host.Ports, " "),
+		"udp":    joinPorts(host.UDPPorts, " "),
+	}
+	names := make([]string, 0, len(host.Services))
+	for _, svc := range host.Services {
+		if svc.Name != "" {
+			names = append(names, svc.Name)
+		}
+	}
+	fields["service"] = strings.Join(names, " ")
+
+	// DNS-SD: beworbene Dienste ohne Unterstrich und Protokoll ("airplay ipp")
+	fields["dnssd"] = joinDNSSD(host.DNSSD, " ")
+
+	// SNMP: Standort und Text-Suche über sysName, sysDescr und sysObjectID
+	fields["location"], fields["snmp"] = "", ""
+	if host.SNMP != nil {
+		fields["location"] = host.SNMP.Location
+		fields["snmp"] = strings.Join([]string{host.SNMP.Name, host.SNMP.Descr, host.SNMP.ObjectID}, " ")
+	}
+
+	// UPnP: Text-Suche über friendlyName, Hersteller, Modell, Seriennummer und Gerätetyp
+	fields["upnp"] = ""
+	if host.UPnP != nil {
+		fields["upnp"] = strings.Join([]string{host.UPnP.FriendlyName, host.UPnP.Manufacturer,
+			host.UPnP.Model(), host.UPnP.SerialNumber, host.UPnP.TypeName()}, " ")
+	}
+
+	// Zertifikate: Text-Suche über Inhaber, SANs und Aussteller, Restlaufzeit des
+	// zuerst ablaufenden Zertifikats (negativ wenn abgelaufen)
+	var certs []string
+	for _, svc := range host.Services {
+		if cert := svc.Certificate(); cert != nil {
+			certs = append(certs, cert.Subject, strings.Join(cert.Names, " "), cert.Issuer)
+		}
+	}
+	fields["cert"] = strings.Join(certs, " ")
+	fields["expires"] = ""
+	if _, cert := earliestCertificate(host); cert != nil {
+		fields["expires"] = cert.ExpiresIn(time.Now()).Truncate(time.Second).String()
+	}
+	if host.RTT > 0 {
+		fields["rtt"] = host.RTT.String()
+	}
+	if host.TTL > 0 {
+		fields["ttl"] = strconv.Itoa(host.TTL)
+	}
+
+	// Betriebssystem-Familie ("Linux", "Windows", "macOS/iOS", "Unix", ...)
+	fields["os"] = ""
+	if host.OS != nil {
+		fields["os"] = host.OS.Family
+	}
+	return fields
+}
+
+// column beschreibt eine auswählbare Ausgabe-Spalte
+type column struct {
+	name    string
+	header  string                    // Überschrift in Tabelle und CSV
+	jsonKey string                    // Feld in der JSON-Ausgabe von scanner.Host
+	csv     func(scanner.Host) string // Wert für CSV
+	table   func(scanner.Host) string // Wert für die Tabelle ("" = "-")
+	json    func(scanner.Host) any    // Wert für JSON, falls nicht direkt ein Feld von scanner.Host
+	compare func(a, b scanner.Host) int
+	missing func(scanner.Host) bool // Kein Wert (wird beim Sortieren immer hinten einsortiert)
+}
+
+// columns sind alle Spalten in der Reihenfolge der Hilfe
+var columns = []column{
+	{
+		name: "ip", header: "IP", jsonKey: "ip",
+		csv:     func(h scanner.Host) string { return h.IP.String() },
+		compare: func(a, b scanner.Host) int { return bytes.Compare(a.IP.To16(), b.IP.To16()) },
+	},
+	{
+		name: "hostname", header: "Hostname", jsonKey: "hostname",
+		csv: func(h scanner.Host) string { return h.Hostname },
+	},
+	{
+		name: "rtt", header: "RTT", jsonKey: "rtt",
+		csv: func(h scanner.Host) string {
+			if h.RTT <= 0 {
+				return ""
+			}
+			return fmt.Sprintf("%.2f", float64(h.RTT.Microseconds())/1000.0)
+		},
+		table: func(h scanner.Host) string {
+			if h.RTT <= 0 {
+				return ""
+			}
+			return fmt.Sprintf("%.0fms", float64(h.RTT.Microseconds())/1000.0)
+		},
+		compare: func(a, b scanner.Host) int { return compareInts(int64(a.RTT), int64(b.RTT)) },
+		missing: func(h scanner.Host) bool { return h.RTT <= 0 },
+	},
+	{
+		name: "mac", header: "MAC", jsonKey: "mac",
+		csv: func(h scanner.Host) string { return h.MAC },
+	},
+	{
+		name: "vendor", header: "Vendor", jsonKey: "vendor",
+		csv: func(h scanner.Host) string { return h.Vendor },
+	},
+	{
+		name: "device", header: "DeviceType", jsonKey: "device_type",
+		csv: func(h scanner.Host) string { return h.DeviceType },
+	},
+	{
+		name: "os", header: "OS", jsonKey: "os",
+		csv: func(h scanner.Host) string {
+			if h.OS == nil {
+				return ""
+			}
+			return h.OS.Family
+		},
+		table:   func(h scanner.Host) string { return h.OS.String() },
+		missing: func(h scanner.Host) bool { return h.OS == nil || h.OS.Family == "" },
+	},
+	{
+		name: "ports", header: "Ports", jsonKey: "ports",
+		csv:     func(h scanner.Host) string { return joinPorts(h.Ports, ";") },
+		table:   func(h scanner.Host) string { return joinPorts(h.Ports, ",") },
+		compare: func(a, b scanner.Host) int { return compareInts(int64(len(a.Ports)), int64(len(b.Ports))) },
+		missing: func(h scanner.Host) bool { return len(h.Ports) == 0 },
+	},
+	{
+		name: "udp", header: "UDP", jsonKey: "udp_ports",
+		csv:     func(h scanner.Host) string { return joinPorts(h.UDPPorts, ";") },
+		table:   func(h scanner.Host) string { return joinPorts(h.UDPPorts, ",") },
+		compare: func(a, b scanner.Host) int { return compareInts(int64(len(a.UDPPorts)), int64(len(b.UDPPorts))) },
+		missing: func(h scanner.Host) bool { return len(h.UDPPorts) == 0 },
+	},
+	{
+		name: "services", header: "Services", jsonKey: "services",
+		csv:     func(h scanner.Host) string { return joinServices(h.Services, ";") },
+		table:   func(h scanner.Host) string { return joinServices(h.Services, ", ") },
+		compare: func(a, b scanner.Host) int { return compareInts(int64(len(a.Services)), int64(len(b.Services))) },
+		missing: func(h scanner.Host) bool { return len(h.Services) == 0 },
+	},
+	{
+		name: "cert", header: "Certificate", jsonKey: "cert",
+		csv: func(h scanner.Host) string {
+			port, cert := earliestCertificate(h)
+			if cert == nil {
+				return ""
+			}
+			return fmt.Sprintf("%d %s %s", port, cert.Subject, cert.NotAfter.Format("2006-01-02"))
+		},
+		table: func(h scanner.Host) string {
+			port, cert := earliestCertificate(h)
+			if cert == nil {
+				return ""
+			}
+			days := int(cert.ExpiresIn(time.Now()).Hours() / 24)
+			return fmt.Sprintf("%d %s (%dd)", port, cert.Subject, days)
+		},
+		json: func(h scanner.Host) any {
+			_, cert := earliestCertificate(h)
+			return cert
+		},
+		compare: func(a, b scanner.Host) int {
+			_, certA := earliestCertificate(a)
+			_, certB := earliestCertificate(b)
+			if certA == nil || certB == nil {
+				return 0
+			}
+			return certA.NotAfter.Compare(certB.NotAfter)
+		},
+		missing: func(h scanner.Host) bool {
+			_, cert := earliestCertificate(h)
+			return cert == nil
+		},
+	},
+	{
+		name: "dnssd", header: "DNS-SD", jsonKey: "dnssd",
+		csv:     func(h scanner.Host) string { return joinDNSSD(h.DNSSD, ";") },
+		table:   func(h scanner.Host) string { return joinDNSSD(h.DNSSD, ",") },
+		compare: func(a, b scanner.Host) int { return compareInts(int64(len(a.DNSSD)), int64(len(b.DNSSD))) },
+		missing: func(h scanner.Host) bool { return len(h.DNSSD) == 0 },
+	},
+	{
+		name: "location", header: "Location", jsonKey: "location",
+		csv: func(h scanner.Host) string {
+			if h.SNMP == nil {
+				return ""
+			}
+			return h.SNMP.Location
+		},
+		json: func(h scanner.Host) any {
+			if h.SNMP == nil {
+				return nil
+			}
+			return h.SNMP.Location
+		},
+	},
+	{
+		name: "snmp", header: "SNMP", jsonKey: "snmp",
+		csv: func(h scanner.Host) string {
+			if h.SNMP == nil {
+				return ""
+			}
+			return h.SNMP.Descr
+		},
+		missing: func(h scanner.Host) bool { return h.SNMP == nil },
+	},
+	{
+		name: "upnp", header: "UPnP", jsonKey: "upnp",
+		csv: func(h scanner.Host) string {
+			if h.UPnP == nil {
+				return ""
+			}
+			return h.UPnP.String()
+		},
+		missing: func(h scanner.Host) bool { return h.UPnP == nil },
+	},
+	{
+		name: "ipv6", header: "IPv6", jsonKey: "ipv6",
+		csv:   func(h scanner.Host) string { return strings.Join(h.IPv6Strings(), ";") },
+		table: func(h scanner.Host) string { return strings.Join(h.IPv6Strings(), ",") },
+	},
+	{
+		name: "ttl", header: "TTL", jsonKey: "ttl",
+		csv: func(h scanner.Host) string {
+			if h.TTL <= 0 {
+				return ""
+			}
+			return strconv.Itoa(h.TTL)
+		},
+		compare: func(a, b scanner.Host) int { return compareInts(int64(a.TTL), int64(b.TTL)) },
+		missing: func(h scanner.Host) bool { return h.TTL <= 0 },
+	},
+	{
+		name: "banner", header: "HTTPBanner", jsonKey: "http_banner",
+		csv: func(h scanner.Host) string { return h.HTTPBanner },
+	},
+	{
+		name: "source", header: "HostnameSource", jsonKey: "hostname_source",
+		csv: func(h scanner.Host) string { return h.HostnameSource },
+	},
+	{
+		name: "gateway", header: "Gateway", jsonKey: "is_gateway",
+		csv: func(h scanner.Host) string {
+			if h.IsGateway {
+				return "yes"
+			}
+			return ""
+		},
+	},
+}
+
+// defaultCSVColumns sind die Spalten der CSV-Ausgabe ohne --columns
+var defaultCSVColumns = []string{"ip", "hostname", "rtt", "mac", "vendor", "device", "ports", "ipv6"}
+
+// columnAliases erlaubt die Feldnamen des Filters als Spalten- und Sortier-Namen
+var columnAliases = map[string]string{
+	"host":        "hostname",
+	"port":        "ports",
+	"type":        "device",
+	"device_type": "device",
+	"http_banner": "banner",
+	"service":     "services",
+	"certs":       "cert",
+	"expires":     "cert",
+	"udp_ports":   "udp",
+	"loc":         "location",
+	"sysdescr":    "snmp",
+	"mdns":        "dnssd",
+}
+
+// ColumnNames gibt die Namen aller Spalten zurück
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// lookupColumn sucht eine Spalte nach Name oder Alias
+func lookupColumn(name string) (column, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if resolved, ok := columnAliases[name]; ok {
+		name = resolved
+	}
+	for _, col := range columns {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return column{}, false
+}
+
+// Validate prüft Filter-Ausdruck, Sortier-Schlüssel und Spalten
+func (o Options) Validate() error {
+	if err := NewFilter(o.Filter).Compile(); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	for _, key := range o.Sort {
+		if _, _, err := sortKey(key); err != nil {
+			return err
+		}
+	}
+	if _, err := o.columns(defaultCSVColumns); err != nil {
+		return err
+	}
+	return nil
+}
+
+// columns löst die gewählten Spalten auf (fallback ohne Auswahl)
+func (o Options) columns(fallback []string) ([]column, error) {
+	names := o.Columns
+	if len(names) == 0 {
+		names = fallback
+	}
+	cols := make([]column, 0, len(names))
+	for _, name := range names {
+		col, ok := lookupColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (valid: %s)", name, strings.Join(ColumnNames(), ", "))
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// sortKey parst einen Sortier-Schlüssel ("rtt" oder "-ip")
+func sortKey(key string) (column, bool, error) {
+	key = strings.TrimSpace(key)
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "-"), "+")
+
+	col, ok := lookupColumn(key)
+	if !ok {
+		return column{}, false, fmt.Errorf("unknown sort key %q (valid: %s)", key, strings.Join(ColumnNames(), ", "))
+	}
+	return col, desc, nil
+}
+
+// Apply filtert und sortiert die Hosts. Ohne Sortier-Schlüssel wird nach IP
+// sortiert, die IP entscheidet auch bei Gleichstand.
+func (o Options) Apply(hosts []scanner.Host) ([]scanner.Host, error) {
+	if o.Filter != "" {
+		f := NewFilter(o.Filter)
+		if err := f.Compile(); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		matched := make([]scanner.Host, 0, len(hosts))
+		for _, host := range hosts {
+			if f.Match(HostFields(host)) {
+				matched = append(matched, host)
+			}
+		}
+		hosts = matched
+	}
+
+	type key struct {
+		col  column
+		desc bool
+	}
+	keys := make([]key, 0, len(o.Sort)+1)
+	for _, raw := range o.Sort {
+		col, desc, err := sortKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key{col, desc})
+	}
+	ipColumn, _ := lookupColumn("ip")
+	keys = append(keys, key{col: ipColumn})
+
+	sort.SliceStable(hosts, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := hosts[i], hosts[j]
+
+			// Fehlende Werte (z.B. keine RTT) immer ans Ende
+			if k.col.missing != nil {
+				missingA, missingB := k.col.missing(a), k.col.missing(b)
+				if missingA != missingB {
+					return missingB
+				}
+			}
+
+			cmp := compareColumn(k.col, a, b)
+			if cmp == 0 {
+				continue
+			}
+			if k.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+
+	return hosts, nil
+}
+
+// compareColumn vergleicht zwei Hosts nach einer Spalte (Text ohne Groß/Kleinschreibung)
+func compareColumn(col column, a, b scanner.Host) int {
+	if col.compare != nil {
+		return col.compare(a, b)
+	}
+	return strings.Compare(strings.ToLower(col.csv(a)), strings.ToLower(col.csv(b)))
+}
+
+// selectJSON gibt pro Host nur die gewählten Felder aus (in Spaltenreihenfolge,
+// fehlende Werte als null)
+func selectJSON(hosts []scanner.Host, cols []column) ([]byte, error) {
+	var compact bytes.Buffer
+	compact.WriteByte('[')
+	for i, host := range hosts {
+		data, err := json.Marshal(host)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			compact.WriteByte(',')
+		}
+		compact.WriteByte('{')
+		for j, col := range cols {
+			if j > 0 {
+				compact.WriteByte(',')
+			}
+			value, ok := all[col.jsonKey]
+			if col.json != nil {
+				if value, err = json.Marshal(col.json(host)); err != nil {
+					return nil, err
+				}
+				ok = true
+			}
+			if !ok {
+				value = json.RawMessage("null")
+			}
+			fmt.Fprintf(&compact, "%q:%s", col.jsonKey, value)
+		}
+		compact.WriteByte('}')
+	}
+	compact.WriteByte(']')
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+func joinPorts(ports []int, sep string) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, sep)
+}
+
+// joinServices gibt die Dienste kompakt aus ("22/ssh OpenSSH 8.9p1")
+func joinServices(services []service.Service, sep string) string {
+	parts := make([]string, len(services))
+	for i, svc := range services {
+		parts[i] = svc.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// joinDNSSD verbindet die Namen der per DNS-SD beworbenen Dienste (ohne Duplikate,
+// z.B. bei mehreren Druckerwarteschlangen)
+func joinDNSSD(services []discovery.DNSSDService, sep string) string {
+	seen := make(map[string]bool, len(services))
+	names := make([]string, 0, len(services))
+	for _, svc := range services {
+		if name := svc.Name(); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, sep)
+}
+
+// earliestCertificate gibt das zuerst ablaufende Server-Zertifikat eines Hosts
+// und dessen Port zurück (nil ohne Zertifikate)
+func earliestCertificate(host scanner.Host) (int, *service.Certificate) {
+	var port int
+	var earliest *service.Certificate
+	for _, svc := range host.Services {
+		cert := svc.Certificate()
+		if cert != nil && (earliest == nil || cert.NotAfter.Before(earliest.NotAfter)) {
+			port, earliest = svc.Port, cert
+		}
+	}
+	return port, earliest
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
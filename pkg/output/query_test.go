@@ -0,0 +1,219 @@
+package output_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/output"
+	"netspy/pkg/scanner"
+	"netspy/pkg/service"
+	"netspy/pkg/snmp"
+)
+
+var _ = Describe("Options", func() {
+	var hosts []scanner.Host
+
+	BeforeEach(func() {
+		hosts = []scanner.Host{
+			{IP: net.ParseIP("192.168.1.20"), Vendor: "Apple, Inc.", RTT: 30 * time.Millisecond, Ports: []int{22, 80}},
+			{IP: net.ParseIP("192.168.1.3"), Vendor: "HP", DeviceType: "Printer", RTT: 5 * time.Millisecond, Ports: []int{9100}},
+			{IP: net.ParseIP("192.168.1.10"), Vendor: "Apple, Inc.", Ports: []int{443}},
+			{IP: net.ParseIP("192.168.1.4"), Vendor: "Apple, Inc.", RTT: 5 * time.Millisecond},
+		}
+	})
+
+	ips := func(hosts []scanner.Host) []string {
+		result := make([]string, len(hosts))
+		for i, host := range hosts {
+			result[i] = host.IP.String()
+		}
+		return result
+	}
+
+	It("should sort by IP numerically by default", func() {
+		result, err := output.Options{}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.3", "192.168.1.4", "192.168.1.10", "192.168.1.20"}))
+	})
+
+	It("should filter with the watch filter language", func() {
+		result, err := output.Options{Filter: "vendor=Apple && !port=22"}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.4", "192.168.1.10"}))
+
+		result, err = output.Options{Filter: "port=9100"}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.3"}))
+
+		result, err = output.Options{Filter: "rtt>10ms"}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.20"}))
+	})
+
+	It("should sort by multiple keys and keep missing values last", func() {
+		result, err := output.Options{Sort: []string{"rtt", "-ip"}}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.4", "192.168.1.3", "192.168.1.20", "192.168.1.10"}))
+
+		result, err = output.Options{Sort: []string{"-rtt"}}.Apply(hosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips(result)).To(Equal([]string{"192.168.1.20", "192.168.1.3", "192.168.1.4", "192.168.1.10"}))
+	})
+
+	It("should reject invalid filters, sort keys and columns", func() {
+		Expect(output.Options{Filter: "rtt>fast"}.Validate()).To(MatchError(ContainSubstring("position 5")))
+		Expect(output.Options{Sort: []string{"speed"}}.Validate()).To(MatchError(ContainSubstring("unknown sort key")))
+		Expect(output.Options{Columns: []string{"ip", "colour"}}.Validate()).To(MatchError(ContainSubstring("unknown column")))
+		Expect(output.Options{Filter: "vendor=Apple", Sort: []string{"-host"}, Columns: []string{"ip", "port"}}.Validate()).To(Succeed())
+	})
+})
+
+var _ = Describe("PrintResults", func() {
+	hosts := []scanner.Host{
+		{IP: net.ParseIP("192.168.1.20"), Vendor: "Apple, Inc.", Ports: []int{22, 80}, Online: true, Services: []service.Service{
+			{Port: 22, Protocol: "tcp", Name: "ssh", Product: "OpenSSH", Version: "9.6"},
+			{Port: 80, Protocol: "tcp", Name: "http", Product: "nginx"},
+		}},
+		{IP: net.ParseIP("192.168.1.3"), Vendor: "HP", RTT: 1500 * time.Microsecond, Online: true, UDPPorts: []int{161}, Services: []service.Service{
+			{Port: 161, Protocol: "udp", Name: "snmp", Banner: "HP ETHERNET MULTI-ENVIRONMENT"},
+			{Port: 9100, Protocol: "tcp", Name: "jetdirect"},
+		}},
+		{IP: net.ParseIP("192.168.1.4"), Online: false},
+	}
+
+	// capture liefert alles, was während fn auf stdout geschrieben wird
+	capture := func(fn func() error) string {
+		reader, writer, err := os.Pipe()
+		Expect(err).NotTo(HaveOccurred())
+		stdout := os.Stdout
+		os.Stdout = writer
+		defer func() { os.Stdout = stdout }()
+
+		done := make(chan []byte)
+		go func() {
+			data, _ := io.ReadAll(reader)
+			done <- data
+		}()
+
+		Expect(fn()).To(Succeed())
+		writer.Close()
+		return string(<-done)
+	}
+
+	It("should write the selected CSV columns", func() {
+		out := capture(func() error {
+			return output.PrintResults(hosts, "csv", output.Options{Columns: []string{"ip", "vendor", "ports", "rtt"}})
+		})
+		Expect(out).To(Equal("IP,Vendor,Ports,RTT\n" +
+			"192.168.1.3,HP,,1.50\n" +
+			"192.168.1.20,\"Apple, Inc.\",22;80,\n"))
+	})
+
+	It("should filter and write the detected services", func() {
+		out := capture(func() error {
+			return output.PrintResults(hosts, "csv", output.Options{Filter: "service=ssh", Columns: []string{"ip", "services"}})
+		})
+		Expect(out).To(Equal("IP,Services\n" +
+			"192.168.1.20,22/ssh OpenSSH 9.6;80/http nginx\n"))
+	})
+
+	It("should filter by UDP ports", func() {
+		out := capture(func() error {
+			return output.PrintResults(hosts, "csv", output.Options{Filter: "udp=161", Columns: []string{"ip", "udp", "services"}})
+		})
+		Expect(out).To(Equal("IP,UDP,Services\n" +
+			"192.168.1.3,161,u:161/snmp HP ETHERNET MULTI-ENVIRONMENT;9100/jetdirect\n"))
+	})
+
+	It("should filter by SNMP location and description", func() {
+		withSNMP := append([]scanner.Host{
+			{IP: net.ParseIP("192.168.1.2"), Hostname: "sw-keller", Online: true, SNMP: &snmp.System{
+				Name: "sw-keller", Descr: "HPE OfficeConnect Switch 1820", Location: "Keller, Rack 1",
+			}},
+		}, hosts...)
+		out := capture(func() error {
+			return output.PrintResults(withSNMP, "csv", output.Options{Filter: "loc~keller && snmp~officeconnect", Columns: []string{"ip", "hostname", "location", "snmp"}})
+		})
+		Expect(out).To(Equal("IP,Hostname,Location,SNMP\n" +
+			"192.168.1.2,sw-keller,\"Keller, Rack 1\",HPE OfficeConnect Switch 1820\n"))
+	})
+
+	It("should filter by DNS-SD service names", func() {
+		withDNSSD := append([]scanner.Host{
+			{IP: net.ParseIP("192.168.1.30"), Online: true, DNSSD: []discovery.DNSSDService{
+				{Type: "_http._tcp", Instance: "Büro"}, {Type: "_ipp._tcp", Instance: "Büro"}, {Type: "_ipp._tcp", Instance: "Büro (Fax)"},
+			}},
+			{IP: net.ParseIP("192.168.1.31"), Online: true, DNSSD: []discovery.DNSSDService{{Type: "_ipps._tcp", Instance: "Empfang"}}},
+		}, hosts...)
+		out := capture(func() error {
+			return output.PrintResults(withDNSSD, "csv", output.Options{Filter: "mdns=ipp", Columns: []string{"ip", "dnssd"}})
+		})
+		Expect(out).To(Equal("IP,DNS-SD\n192.168.1.30,http;ipp\n"))
+	})
+
+	It("should filter by UPnP device description", func() {
+		withUPnP := append([]scanner.Host{
+			{IP: net.ParseIP("192.168.1.40"), Online: true, UPnP: &discovery.UPnPDevice{
+				DeviceType:   "urn:schemas-upnp-org:device:ZonePlayer:1",
+				FriendlyName: "Wohnzimmer", Manufacturer: "Sonos, Inc.", ModelName: "Sonos One", SerialNumber: "48-A6-B8",
+			}},
+		}, hosts...)
+		out := capture(func() error {
+			return output.PrintResults(withUPnP, "csv", output.Options{Filter: "upnp~sonos", Columns: []string{"ip", "upnp"}})
+		})
+		Expect(out).To(Equal("IP,UPnP\n192.168.1.40,Wohnzimmer - Sonos One (ZonePlayer)\n"))
+	})
+
+	It("should filter by OS family", func() {
+		withOS := append([]scanner.Host{
+			{IP: net.ParseIP("192.168.1.50"), Online: true, OS: &discovery.OSHint{Family: discovery.OSLinux, Confidence: 80}},
+			{IP: net.ParseIP("192.168.1.51"), Online: true, OS: &discovery.OSHint{Family: discovery.OSWindows, Confidence: 40}},
+		}, hosts...)
+		out := capture(func() error {
+			return output.PrintResults(withOS, "csv", output.Options{Filter: "os=linux", Columns: []string{"ip", "os"}})
+		})
+		Expect(out).To(Equal("IP,OS\n192.168.1.50,Linux\n"))
+	})
+
+	It("should filter by certificate expiry", func() {
+		expiring := time.Now().Add(10 * 24 * time.Hour)
+		withCerts := []scanner.Host{
+			{IP: net.ParseIP("192.168.1.5"), Online: true, Services: []service.Service{
+				{Port: 443, Protocol: "tcp", Name: "https", TLS: true, Certificates: []service.Certificate{{Subject: "nas.local", NotAfter: expiring}}},
+			}},
+			{IP: net.ParseIP("192.168.1.6"), Online: true, Services: []service.Service{
+				{Port: 8443, Protocol: "tcp", Name: "https-alt", TLS: true, Certificates: []service.Certificate{{Subject: "unifi", NotAfter: time.Now().Add(400 * 24 * time.Hour)}}},
+			}},
+			hosts[0],
+		}
+
+		out := capture(func() error {
+			return output.PrintResults(withCerts, "csv", output.Options{Filter: "expires<30d", Columns: []string{"ip", "cert"}})
+		})
+		Expect(out).To(Equal("IP,Certificate\n" +
+			"192.168.1.5,443 nas.local " + expiring.Format("2006-01-02") + "\n"))
+	})
+
+	It("should write the selected JSON fields in column order", func() {
+		out := capture(func() error {
+			return output.PrintResults(hosts, "json", output.Options{Filter: "port=22", Columns: []string{"ip", "ports", "hostname"}})
+		})
+		Expect(out).To(Equal(`[
+  {
+    "ip": "192.168.1.20",
+    "ports": [
+      22,
+      80
+    ],
+    "hostname": null
+  }
+]
+`))
+	})
+})
@@ -1,18 +1,22 @@
 package output
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"os"
 	"strings"
+	"time"
 
 	"netspy/pkg/scanner"
+	"netspy/pkg/service"
 
 	"github.com/fatih/color"
 )
 
-// PrintResults gibt Scan-Ergebnisse im angegebenen Format aus
-func PrintResults(hosts []scanner.Host, format string) error {
+// PrintResults gibt Scan-Ergebnisse im angegebenen Format aus.
+// opts filtert und sortiert die Hosts und wählt die Spalten aus.
+func PrintResults(hosts []scanner.Host, format string, opts Options) error {
 	// Nur online Hosts filtern
 	var onlineHosts []scanner.Host
 	for _, host := range hosts {
@@ -21,20 +25,61 @@ func PrintResults(hosts []scanner.Host, format string) error {
 		}
 	}
 
-	// Nach IP-Adresse sortieren
-	sort.Slice(onlineHosts, func(i, j int) bool {
-		return onlineHosts[i].IP.String() < onlineHosts[j].IP.String()
-	})
+	// Filter anwenden und sortieren (Standard: nach IP, numerisch, IPv4 vor IPv6)
+	selected, err := opts.Apply(onlineHosts)
+	if err != nil {
+		return err
+	}
 
 	switch strings.ToLower(format) {
 	case "json":
-		return printJSON(onlineHosts)
+		return printJSON(selected, opts)
 	case "csv":
-		return printCSV(onlineHosts)
+		return printCSV(selected, opts)
 	case "table":
 		fallthrough
 	default:
-		return printSimpleTable(onlineHosts, len(hosts))
+		if len(selected) == 0 && len(onlineHosts) > 0 {
+			color.Yellow("[WARN] No hosts match the filter (%d active hosts)\n", len(onlineHosts))
+			return nil
+		}
+		if len(opts.Columns) > 0 && len(selected) > 0 {
+			return printColumnTable(selected, opts)
+		}
+		if err := printSimpleTable(selected, len(hosts)); err != nil {
+			return err
+		}
+		printServices(selected)
+		return nil
+	}
+}
+
+// printServices listet die erkannten Dienste und Zertifikate unter der Tabelle auf
+// (nur mit --services bzw. --certs)
+func printServices(hosts []scanner.Host) {
+	found := false
+	for _, host := range hosts {
+		if len(host.Services) == 0 {
+			continue
+		}
+		if !found {
+			color.Cyan("Services:\n")
+			found = true
+		}
+		color.White("  %s\n", host.IP)
+		for _, svc := range host.Services {
+			name := svc.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Printf("    %-10s %-12s %s\n", fmt.Sprintf("%d/%s", svc.Port, svc.Protocol), name, svc.Summary())
+			if cert := svc.Certificate(); cert != nil {
+				printCertificate(*cert)
+			}
+		}
+	}
+	if found {
+		fmt.Println()
 	}
 }
 
@@ -75,8 +120,18 @@ func printSimpleTable(hosts []scanner.Host, totalScanned int) error {
 
 // Legacy code removed - alle Modi nutzen jetzt responsive Tables
 
-func printJSON(hosts []scanner.Host) error {
-	data, err := json.MarshalIndent(hosts, "", "  ")
+func printJSON(hosts []scanner.Host, opts Options) error {
+	var data []byte
+	var err error
+	if len(opts.Columns) > 0 {
+		cols, colErr := opts.columns(nil)
+		if colErr != nil {
+			return colErr
+		}
+		data, err = selectJSON(hosts, cols)
+	} else {
+		data, err = json.MarshalIndent(hosts, "", "  ")
+	}
 	if err != nil {
 		return err
 	}
@@ -84,41 +139,102 @@ func printJSON(hosts []scanner.Host) error {
 	return nil
 }
 
-func printCSV(hosts []scanner.Host) error {
-	fmt.Println("IP,Hostname,RTT,MAC,Vendor,DeviceType,Ports")
+func printCSV(hosts []scanner.Host, opts Options) error {
+	cols, err := opts.columns(defaultCSVColumns)
+	if err != nil {
+		return err
+	}
+
+	// encoding/csv übernimmt das Quoting (z.B. Vendor "Apple, Inc.")
+	writer := csv.NewWriter(os.Stdout)
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.header
+	}
+	_ = writer.Write(header)
+
 	for _, host := range hosts {
-		hostname := host.Hostname
-		if hostname == "" {
-			hostname = ""
+		record := make([]string, len(cols))
+		for i, col := range cols {
+			record[i] = col.csv(host)
 		}
+		_ = writer.Write(record)
+	}
 
-		rtt := ""
-		if host.RTT > 0 {
-			rtt = fmt.Sprintf("%.2f", float64(host.RTT.Microseconds())/1000.0)
-		}
+	writer.Flush()
+	return writer.Error()
+}
 
-		mac := host.MAC
-		vendor := host.Vendor
-		deviceType := host.DeviceType
+// printCertificate gibt ein Server-Zertifikat eingerückt aus (rot wenn abgelaufen,
+// gelb wenn es in weniger als 30 Tagen abläuft)
+func printCertificate(cert service.Certificate) {
+	line := fmt.Sprintf("    %-10s %s", "", cert.String())
+	switch remaining := cert.ExpiresIn(time.Now()); {
+	case cert.Expired(time.Now()):
+		color.Red("%s [EXPIRED]\n", line)
+	case remaining < 30*24*time.Hour:
+		color.Yellow("%s [%dd left]\n", line, int(remaining.Hours()/24))
+	default:
+		fmt.Println(line)
+	}
+}
+
+// printColumnTable gibt die mit --columns gewählten Spalten als Tabelle aus
+// (Spaltenbreite nach längstem Wert, wie die responsiven Tabellen mit Truncate)
+func printColumnTable(hosts []scanner.Host, opts Options) error {
+	cols, err := opts.columns(nil)
+	if err != nil {
+		return err
+	}
 
-		ports := ""
-		if len(host.Ports) > 0 {
-			portStrs := make([]string, len(host.Ports))
-			for i, p := range host.Ports {
-				portStrs[i] = fmt.Sprintf("%d", p)
+	rows := make([][]string, len(hosts))
+	widths := make([]int, len(cols))
+	for i, col := range cols {
+		widths[i] = len(col.header)
+	}
+	for r, host := range hosts {
+		row := make([]string, len(cols))
+		for i, col := range cols {
+			value := col.csv(host)
+			if col.table != nil {
+				value = col.table(host)
+			}
+			if value == "" {
+				value = "-"
 			}
-			ports = strings.Join(portStrs, ";")
+			value = Truncate(value, maxColumnWidth)
+			row[i] = value
+			if len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+		rows[r] = row
+	}
+
+	format := func(values []string) string {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprintf("%-*s", widths[i], value)
 		}
+		return strings.TrimRight(strings.Join(parts, " "), " ")
+	}
+
+	header := make([]string, len(cols))
+	total := len(cols) - 1
+	for i, col := range cols {
+		header[i] = col.header
+		total += widths[i]
+	}
+	color.Cyan("%s\n", format(header))
+	color.White("%s\n", strings.Repeat("-", min(GetTerminalSize().GetDisplayWidth(), total)))
 
-		fmt.Printf("%s,%s,%s,%s,%s,%s,%s\n",
-			host.IP.String(),
-			hostname,
-			rtt,
-			mac,
-			vendor,
-			deviceType,
-			ports,
-		)
+	for _, row := range rows {
+		fmt.Println(format(row))
 	}
+
+	fmt.Println()
 	return nil
 }
+
+// maxColumnWidth begrenzt die Breite einer Spalte in printColumnTable
+const maxColumnWidth = 40
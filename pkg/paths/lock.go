@@ -0,0 +1,21 @@
+package paths
+
+import "os"
+
+// Lock sperrt path exklusiv über die Datei path + ".lock" (auch prozessübergreifend,
+// z.B. für mehrere parallel laufende watch-Instanzen). Blockiert, bis die Sperre frei
+// ist; unlock gibt sie wieder frei.
+func Lock(path string) (unlock func(), err error) {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644) // #nosec G302 G304 -- Sperrdatei im Datenverzeichnis
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package paths
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_EX) // #nosec G115 -- Dateideskriptor passt immer in int
+}
+
+func unlockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN) // #nosec G115 -- Dateideskriptor passt immer in int
+}
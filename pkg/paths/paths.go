@@ -0,0 +1,61 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appName ist der Verzeichnisname unter den plattformüblichen Basisverzeichnissen
+const appName = "netspy"
+
+// DataDir gibt das Verzeichnis für persistente Daten zurück (wird bei Bedarf angelegt)
+//
+//	Linux:   $XDG_DATA_HOME/netspy (Standard: ~/.local/share/netspy)
+//	macOS:   ~/Library/Application Support/netspy
+//	Windows: %LOCALAPPDATA%\netspy
+func DataDir() (string, error) {
+	var base string
+
+	switch runtime.GOOS {
+	case "windows":
+		base = os.Getenv("LOCALAPPDATA")
+		if base == "" {
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				return "", err
+			}
+			base = dir
+		}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, "Library", "Application Support")
+	default:
+		base = os.Getenv("XDG_DATA_HOME")
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			base = filepath.Join(home, ".local", "share")
+		}
+	}
+
+	dir := filepath.Join(base, appName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// DataFile gibt den Pfad einer Datei im Datenverzeichnis zurück
+func DataFile(name string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
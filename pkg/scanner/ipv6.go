@@ -0,0 +1,169 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"netspy/pkg/discovery"
+)
+
+// IPv6Address ist eine IPv6-Adresse eines Hosts mit ihrem Typ
+// ("link-local", "slaac", "privacy", "static" - siehe discovery.ClassifyIPv6)
+type IPv6Address struct {
+	IP   net.IP `json:"ip"`
+	Kind string `json:"kind"`
+}
+
+// ipv6KindOrder legt die Anzeige-Reihenfolge fest (stabile Adressen zuerst)
+var ipv6KindOrder = map[string]int{
+	discovery.IPv6Static:    0,
+	discovery.IPv6SLAAC:     1,
+	discovery.IPv6Privacy:   2,
+	discovery.IPv6LinkLocal: 3,
+}
+
+// CorrelateIPv6 ordnet IPv6-Nachbarn anhand der MAC-Adresse den IPv4-Hosts zu.
+// Nachbarn ohne passenden IPv4-Host werden als eigene (reine IPv6-)Hosts angehängt.
+func CorrelateIPv6(hosts []Host, neighbors []discovery.NDPEntry) []Host {
+	type group struct {
+		mac       net.HardwareAddr
+		addresses []IPv6Address
+		router    bool
+		rtt       time.Duration
+	}
+
+	// Nachbarn nach MAC gruppieren (Link-Local, SLAAC und Privacy-Adressen eines Geräts)
+	groups := make(map[string]*group)
+	var order []string
+	for _, neighbor := range neighbors {
+		key := neighbor.MAC.String()
+		g, ok := groups[key]
+		if !ok {
+			g = &group{mac: neighbor.MAC}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if containsIPv6(g.addresses, neighbor.IP) {
+			continue
+		}
+		g.addresses = append(g.addresses, IPv6Address{
+			IP:   neighbor.IP,
+			Kind: discovery.ClassifyIPv6(neighbor.IP, neighbor.MAC),
+		})
+		g.router = g.router || neighbor.Router
+		if g.rtt == 0 || (neighbor.RTT > 0 && neighbor.RTT < g.rtt) {
+			g.rtt = neighbor.RTT
+		}
+	}
+
+	for _, g := range groups {
+		sortIPv6Addresses(g.addresses)
+	}
+
+	// Bestehenden Hosts zuordnen
+	matched := make(map[string]bool)
+	for i := range hosts {
+		if hosts[i].MAC == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(hosts[i].MAC)
+		if err != nil {
+			continue
+		}
+		g, ok := groups[mac.String()]
+		if !ok {
+			continue
+		}
+
+		matched[mac.String()] = true
+		for _, addr := range g.addresses {
+			if !containsIPv6(hosts[i].IPv6, addr.IP) {
+				hosts[i].IPv6 = append(hosts[i].IPv6, addr)
+			}
+		}
+		sortIPv6Addresses(hosts[i].IPv6)
+		if g.router {
+			hosts[i].IsGateway = true
+		}
+	}
+
+	// Reine IPv6-Hosts anhängen (bevorzugt unter einer stabilen, nicht Link-Local-Adresse)
+	for _, key := range order {
+		if matched[key] {
+			continue
+		}
+		g := groups[key]
+		mac := g.mac.String()
+		vendor := discovery.GetMACVendor(mac)
+		host := Host{
+			IP:        g.addresses[0].IP,
+			IPv6:      g.addresses,
+			MAC:       mac,
+			Vendor:    vendor,
+			RTT:       g.rtt,
+			Online:    true,
+			IsGateway: g.router,
+		}
+		Classify(&host)
+		hosts = append(hosts, host)
+	}
+
+	return hosts
+}
+
+// DiscoverIPv6 sucht IPv6-Nachbarn auf dem Interface des (IPv4-)Netzwerks und
+// ordnet sie den Hosts zu. Die EUI-64-Link-Local-Adressen der bekannten MACs werden
+// zusätzlich über ihre Solicited-Node-Multicast-Adresse angesprochen.
+func DiscoverIPv6(ctx context.Context, network *net.IPNet, hosts []Host, timeout time.Duration) ([]Host, error) {
+	iface, _, err := discovery.InterfaceForNetwork(network)
+	if err != nil {
+		return hosts, err
+	}
+
+	var candidates []net.IP
+	for _, host := range hosts {
+		if mac, err := net.ParseMAC(host.MAC); err == nil {
+			candidates = append(candidates, discovery.LinkLocalFromMAC(mac))
+		}
+	}
+
+	neighbors, err := discovery.DiscoverIPv6Neighbors(ctx, iface, candidates, timeout)
+	if err != nil {
+		return hosts, err
+	}
+
+	return CorrelateIPv6(hosts, neighbors), nil
+}
+
+// IPv6Strings gibt die IPv6-Adressen eines Hosts als Strings zurück
+func (h Host) IPv6Strings() []string {
+	addrs := make([]string, 0, len(h.IPv6))
+	for _, addr := range h.IPv6 {
+		addrs = append(addrs, addr.IP.String())
+	}
+	return addrs
+}
+
+// containsIPv6 prüft ob eine Adresse bereits in der Liste enthalten ist
+func containsIPv6(addresses []IPv6Address, ip net.IP) bool {
+	for _, addr := range addresses {
+		if addr.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortIPv6Addresses sortiert nach Typ (stabil vor temporär vor Link-Local), dann nach Adresse
+func sortIPv6Addresses(addresses []IPv6Address) {
+	sort.SliceStable(addresses, func(i, j int) bool {
+		oi, oj := ipv6KindOrder[addresses[i].Kind], ipv6KindOrder[addresses[j].Kind]
+		if oi != oj {
+			return oi < oj
+		}
+		return bytes.Compare(addresses[i].IP.To16(), addresses[j].IP.To16()) < 0
+	})
+}
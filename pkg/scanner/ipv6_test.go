@@ -0,0 +1,64 @@
+package scanner_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
+)
+
+var _ = Describe("IPv6 correlation", func() {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	otherMAC, _ := net.ParseMAC("66:77:88:99:aa:bb")
+
+	neighbors := []discovery.NDPEntry{
+		{IP: net.ParseIP("fe80::211:22ff:fe33:4455"), MAC: mac},
+		{IP: net.ParseIP("2001:db8::8d3a:11f2:93c4:5e01"), MAC: mac},
+		{IP: net.ParseIP("2001:db8::211:22ff:fe33:4455"), MAC: mac},
+		{IP: net.ParseIP("fe80::1"), MAC: otherMAC, Router: true},
+		{IP: net.ParseIP("2001:db8::1"), MAC: otherMAC, Router: true},
+	}
+
+	It("should attach IPv6 addresses to the IPv4 host with the same MAC", func() {
+		hosts := []scanner.Host{
+			{IP: net.ParseIP("192.168.1.10"), MAC: "00:11:22:33:44:55", Online: true},
+		}
+
+		result := scanner.CorrelateIPv6(hosts, neighbors)
+		Expect(result).To(HaveLen(2))
+
+		Expect(result[0].IP.String()).To(Equal("192.168.1.10"))
+		Expect(result[0].IPv6Strings()).To(Equal([]string{
+			"2001:db8::211:22ff:fe33:4455",
+			"2001:db8::8d3a:11f2:93c4:5e01",
+			"fe80::211:22ff:fe33:4455",
+		}))
+		Expect(result[0].IPv6[0].Kind).To(Equal(discovery.IPv6SLAAC))
+		Expect(result[0].IPv6[1].Kind).To(Equal(discovery.IPv6Privacy))
+		Expect(result[0].IPv6[2].Kind).To(Equal(discovery.IPv6LinkLocal))
+	})
+
+	It("should add IPv6-only hosts under their stable address", func() {
+		result := scanner.CorrelateIPv6(nil, neighbors)
+		Expect(result).To(HaveLen(2))
+
+		router := result[1]
+		Expect(router.IP.String()).To(Equal("2001:db8::1"))
+		Expect(router.MAC).To(Equal("66:77:88:99:aa:bb"))
+		Expect(router.Online).To(BeTrue())
+		Expect(router.IsGateway).To(BeTrue())
+	})
+
+	It("should not duplicate addresses when correlating twice", func() {
+		hosts := []scanner.Host{
+			{IP: net.ParseIP("192.168.1.10"), MAC: "00:11:22:33:44:55", Online: true},
+		}
+
+		result := scanner.CorrelateIPv6(hosts, neighbors)
+		result = scanner.CorrelateIPv6(result[:1], neighbors)
+		Expect(result[0].IPv6).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,215 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"netspy/pkg/discovery"
+)
+
+// maxPendingMACs begrenzt die Angaben, die auf eine Adresse warten
+const maxPendingMACs = 4096
+
+// passiveNameRank legt fest, welche Quelle einen bereits bekannten Hostnamen ersetzt
+// (gleicher oder höherer Rang). SSDP liefert nur einen geratenen Namen.
+var passiveNameRank = map[string]int{"SSDP": 1, "dhcp": 2, "lldp": 3, "cdp": 3, "llmnr": 3, "netbios": 3, "mdns": 4}
+
+// PassiveCollector baut aus mitgeschnittenen Frames eine Geräteliste auf, ohne selbst
+// Pakete zu senden. Ausgewertet werden ARP, DHCP, mDNS, SSDP, NetBIOS, LLMNR und
+// LLDP/CDP (siehe discovery.DecodeFrame). Sicher für nebenläufige Nutzung.
+type PassiveCollector struct {
+	mu      sync.Mutex
+	network *net.IPNet // Nur Adressen in diesem Netz (nil = alle)
+	hosts   map[string]*passiveHost
+	pending map[string][]discovery.Observation // Angaben ohne Adresse (DHCP DISCOVER, LLDP), nach MAC
+	frames  int
+}
+
+type passiveHost struct {
+	host     Host
+	lastSeen time.Time
+	nameRank int
+}
+
+// NewPassiveCollector erstellt einen Collector für die Adressen eines Netzwerks
+func NewPassiveCollector(network *net.IPNet) *PassiveCollector {
+	return &PassiveCollector{
+		network: network,
+		hosts:   make(map[string]*passiveHost),
+		pending: make(map[string][]discovery.Observation),
+	}
+}
+
+// Capture liest Frames aus source, bis die Quelle endet (pcap-Datei) oder ctx
+// beendet wird (Live-Mitschnitt). Bei Abbruch über ctx wird die Quelle geschlossen,
+// um das blockierende Lesen zu beenden.
+func (c *PassiveCollector) Capture(ctx context.Context, source discovery.FrameSource) error {
+	stop := context.AfterFunc(ctx, func() { _ = source.Close() })
+	defer stop()
+
+	for {
+		frame, err := source.NextFrame()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c.AddFrame(frame)
+	}
+}
+
+// AddFrame wertet einen Frame aus
+func (c *PassiveCollector) AddFrame(frame discovery.Frame) {
+	observations := discovery.DecodeFrame(frame)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames++
+	for _, observation := range observations {
+		c.add(observation)
+	}
+}
+
+// Add übernimmt eine einzelne Beobachtung
+func (c *PassiveCollector) Add(observation discovery.Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(observation)
+}
+
+// Frames gibt die Anzahl der bisher gelesenen Frames zurück
+func (c *PassiveCollector) Frames() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames
+}
+
+func (c *PassiveCollector) add(observation discovery.Observation) {
+	var mac string
+	if len(observation.MAC) == 6 && !bytes.Equal(observation.MAC, net.HardwareAddr{0, 0, 0, 0, 0, 0}) {
+		mac = observation.MAC.String()
+	}
+
+	ip := observation.IP.To4()
+	if ip == nil || !c.accepts(ip) {
+		// Angaben merken, bis die MAC mit einer Adresse auftaucht (DHCP DISCOVER → ARP)
+		if ip == nil && mac != "" && (len(c.pending) < maxPendingMACs || c.pending[mac] != nil) {
+			c.pending[mac] = append(c.pending[mac], observation)
+		}
+		return
+	}
+
+	// Neue Adresse per DHCP: die alten Einträge der MAC sind überholt
+	if observation.Protocol == "dhcp" && mac != "" {
+		for key, entry := range c.hosts {
+			if entry.host.MAC == mac && !entry.host.IP.Equal(ip) {
+				delete(c.hosts, key)
+			}
+		}
+	}
+
+	entry := c.hosts[ip.String()]
+	if entry == nil {
+		entry = &passiveHost{host: Host{IP: ip, Online: true}}
+		c.hosts[ip.String()] = entry
+	}
+	if observation.Time.After(entry.lastSeen) {
+		entry.lastSeen = observation.Time
+	}
+
+	// LLDP/CDP-Frames kommen von der Port-MAC, nicht von der des Management-Interfaces
+	neighbor := observation.Protocol == "lldp" || observation.Protocol == "cdp"
+	if mac != "" && mac != entry.host.MAC && (entry.host.MAC == "" || !neighbor) {
+		entry.host.MAC = mac
+		entry.host.Vendor = discovery.GetMACVendor(mac)
+	}
+	if pending := c.pending[entry.host.MAC]; pending != nil {
+		delete(c.pending, entry.host.MAC)
+		for _, earlier := range pending {
+			entry.apply(earlier)
+		}
+	}
+	entry.apply(observation)
+}
+
+// accepts prüft, ob eine Adresse zum überwachten Netz gehört
+func (c *PassiveCollector) accepts(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsMulticast() || ip.Equal(net.IPv4bcast) {
+		return false
+	}
+	return c.network == nil || c.network.Contains(ip)
+}
+
+// apply übernimmt Name, Dienste und Selbstauskünfte einer Beobachtung
+func (e *passiveHost) apply(observation discovery.Observation) {
+	hostname, source := observation.Hostname, observation.Protocol
+	if hostname == "" && observation.SSDP != nil {
+		hostname, source = discovery.GetSSDPDeviceName(*observation.SSDP), "SSDP"
+	}
+	if rank := passiveNameRank[source]; hostname != "" && rank >= e.nameRank {
+		e.host.Hostname = hostname
+		e.host.HostnameSource = source
+		e.nameRank = rank
+	}
+
+	if observation.VendorClass != "" {
+		e.host.DHCPVendor = observation.VendorClass
+	}
+	if observation.Neighbor != nil {
+		e.host.Neighbor = observation.Neighbor
+	}
+	if len(observation.Services) > 0 {
+		e.host.DNSSD = mergeDNSSD(e.host.DNSSD, observation.Services)
+	}
+}
+
+// mergeDNSSD ergänzt bekannte DNS-SD-Dienste um neue bzw. aktualisierte
+// (gleicher Typ und Instanzname) und gibt eine neue, sortierte Liste zurück
+func mergeDNSSD(known, update []discovery.DNSSDService) []discovery.DNSSDService {
+	merged := make([]discovery.DNSSDService, 0, len(known)+len(update))
+	seen := make(map[string]bool, len(update))
+	for _, svc := range update {
+		seen[svc.Type+"|"+svc.Instance] = true
+		merged = append(merged, svc)
+	}
+	for _, svc := range known {
+		if !seen[svc.Type+"|"+svc.Instance] {
+			merged = append(merged, svc)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Type != merged[j].Type {
+			return merged[i].Type < merged[j].Type
+		}
+		return merged[i].Instance < merged[j].Instance
+	})
+	return merged
+}
+
+// Hosts gibt die Geräte zurück, die seit since gesehen wurden (Zero-Time = alle),
+// sortiert nach IP und mit bestimmtem Gerätetyp
+func (c *PassiveCollector) Hosts(since time.Time) []Host {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hosts := make([]Host, 0, len(c.hosts))
+	for _, entry := range c.hosts {
+		if entry.lastSeen.Before(since) {
+			continue
+		}
+		host := entry.host
+		Classify(&host)
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return bytes.Compare(hosts[i].IP, hosts[j].IP) < 0
+	})
+	return hosts
+}
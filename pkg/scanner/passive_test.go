@@ -0,0 +1,183 @@
+package scanner_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
+)
+
+// frameSource liefert feste Frames und danach io.EOF bzw. err. Mit live
+// blockiert sie wie ein Live-Mitschnitt bis zum Schließen.
+type frameSource struct {
+	frames []discovery.Frame
+	err    error
+	live   chan struct{}
+}
+
+func (s *frameSource) NextFrame() (discovery.Frame, error) {
+	if len(s.frames) == 0 {
+		if s.live != nil {
+			<-s.live
+			return discovery.Frame{}, errors.New("use of closed file")
+		}
+		if s.err != nil {
+			return discovery.Frame{}, s.err
+		}
+		return discovery.Frame{}, io.EOF
+	}
+	frame := s.frames[0]
+	s.frames = s.frames[1:]
+	return frame, nil
+}
+
+func (s *frameSource) Close() error {
+	if s.live != nil {
+		close(s.live)
+	}
+	return nil
+}
+
+// arpReply baut einen ARP-Reply-Frame des Absenders
+func arpReply(mac net.HardwareAddr, ip net.IP, at time.Time) discovery.Frame {
+	data := append([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, mac...)
+	data = append(data, 0x08, 0x06, 0x00, 0x01, 0x08, 0x00, 6, 4, 0x00, 0x02)
+	data = append(data, mac...)
+	data = append(data, ip.To4()...)
+	data = append(data, make([]byte, 10)...)
+	return discovery.Frame{Time: at, Data: data}
+}
+
+var _ = Describe("PassiveCollector", func() {
+	var (
+		network   *net.IPNet
+		collector *scanner.PassiveCollector
+		start     time.Time
+		mac       net.HardwareAddr
+	)
+
+	BeforeEach(func() {
+		_, network, _ = net.ParseCIDR("192.168.1.0/24")
+		collector = scanner.NewPassiveCollector(network)
+		start = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		mac, _ = net.ParseMAC("3c:22:fb:12:34:56")
+	})
+
+	It("should build hosts from captured frames", func() {
+		source := &frameSource{frames: []discovery.Frame{
+			arpReply(mac, net.ParseIP("192.168.1.20"), start),
+			arpReply(net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, net.ParseIP("192.168.1.1"), start),
+			arpReply(net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02}, net.ParseIP("10.0.0.1"), start),
+		}}
+
+		Expect(collector.Capture(context.Background(), source)).To(Succeed())
+		Expect(collector.Frames()).To(Equal(3))
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(2))
+		Expect(hosts[0].IP.String()).To(Equal("192.168.1.1"))
+		Expect(hosts[1].IP.String()).To(Equal("192.168.1.20"))
+		Expect(hosts[1].MAC).To(Equal("3c:22:fb:12:34:56"))
+		Expect(hosts[1].Online).To(BeTrue())
+	})
+
+	It("should return capture errors", func() {
+		source := &frameSource{err: errors.New("interface down")}
+		Expect(collector.Capture(context.Background(), source)).To(MatchError("interface down"))
+	})
+
+	It("should stop a live capture when the context is cancelled", func() {
+		source := &frameSource{
+			frames: []discovery.Frame{arpReply(mac, net.ParseIP("192.168.1.20"), start)},
+			live:   make(chan struct{}),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- collector.Capture(ctx, source) }()
+
+		Eventually(collector.Frames).Should(Equal(1))
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should prefer better hostname sources", func() {
+		ip := net.ParseIP("192.168.1.20")
+		collector.Add(discovery.Observation{Time: start, Protocol: "mdns", MAC: mac, IP: ip, Hostname: "macbook"})
+		collector.Add(discovery.Observation{Time: start, Protocol: "dhcp", MAC: mac, IP: ip, Hostname: "MacBook-Pro"})
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].Hostname).To(Equal("macbook"))
+		Expect(hosts[0].HostnameSource).To(Equal("mdns"))
+	})
+
+	It("should attach address-less observations once the MAC shows up", func() {
+		collector.Add(discovery.Observation{Time: start, Protocol: "dhcp", MAC: mac, Hostname: "android-4711", VendorClass: "android-dhcp-14"})
+		Expect(collector.Hosts(time.Time{})).To(BeEmpty())
+
+		collector.Add(discovery.Observation{Time: start.Add(time.Second), Protocol: "arp", MAC: mac, IP: net.ParseIP("192.168.1.30")})
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].Hostname).To(Equal("android-4711"))
+		Expect(hosts[0].DHCPVendor).To(Equal("android-dhcp-14"))
+		Expect(hosts[0].DeviceType).To(Equal("Smartphone"))
+	})
+
+	It("should drop the old address when DHCP hands out a new one", func() {
+		collector.Add(discovery.Observation{Time: start, Protocol: "arp", MAC: mac, IP: net.ParseIP("192.168.1.30")})
+		collector.Add(discovery.Observation{Time: start.Add(time.Minute), Protocol: "dhcp", MAC: mac, IP: net.ParseIP("192.168.1.31")})
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].IP.String()).To(Equal("192.168.1.31"))
+	})
+
+	It("should keep the interface MAC of network equipment", func() {
+		ip := net.ParseIP("192.168.1.2")
+		collector.Add(discovery.Observation{Time: start, Protocol: "arp", MAC: mac, IP: ip})
+		collector.Add(discovery.Observation{
+			Time: start, Protocol: "lldp", MAC: net.HardwareAddr{0x00, 0x1b, 0x54, 0, 0, 0x0c}, IP: ip,
+			Hostname: "core-sw1",
+			Neighbor: &discovery.Neighbor{Protocol: "lldp", SystemName: "core-sw1", ManagementIP: ip, Capabilities: []string{"bridge"}},
+		})
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].MAC).To(Equal("3c:22:fb:12:34:56"))
+		Expect(hosts[0].Neighbor.SystemName).To(Equal("core-sw1"))
+		Expect(hosts[0].DeviceType).To(Equal("Network Equipment (Switch)"))
+	})
+
+	It("should merge announced services", func() {
+		ip := net.ParseIP("192.168.1.40")
+		collector.Add(discovery.Observation{Time: start, Protocol: "mdns", IP: ip, Services: []discovery.DNSSDService{
+			{Instance: "Drucker", Type: "_ipp._tcp", Port: 631},
+		}})
+		collector.Add(discovery.Observation{Time: start, Protocol: "mdns", IP: ip, Services: []discovery.DNSSDService{
+			{Instance: "Drucker", Type: "_ipp._tcp", Port: 632},
+			{Instance: "Drucker", Type: "_http._tcp", Port: 80},
+		}})
+
+		hosts := collector.Hosts(time.Time{})
+		Expect(hosts).To(HaveLen(1))
+		Expect(hosts[0].DNSSD).To(HaveLen(2))
+		Expect(hosts[0].DNSSD[0].Type).To(Equal("_http._tcp"))
+		Expect(hosts[0].DNSSD[1].Port).To(Equal(632))
+	})
+
+	It("should only return hosts seen within the window", func() {
+		collector.Add(discovery.Observation{Time: start, Protocol: "arp", MAC: mac, IP: net.ParseIP("192.168.1.20")})
+		collector.Add(discovery.Observation{Time: start.Add(10 * time.Minute), Protocol: "arp", IP: net.ParseIP("192.168.1.21")})
+
+		Expect(collector.Hosts(start.Add(5 * time.Minute))).To(HaveLen(1))
+		Expect(collector.Hosts(time.Time{})).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,325 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"netspy/pkg/discovery"
+)
+
+// ProbeKind unterscheidet Erreichbarkeits- von Anreicherungs-Probes
+type ProbeKind int
+
+const (
+	// ProbeLiveness prüft, ob ein Host erreichbar ist
+	ProbeLiveness ProbeKind = iota
+	// ProbeEnrichment ergänzt Informationen zu einem erreichbaren Host
+	ProbeEnrichment
+)
+
+// Probe prüft einen Host oder reichert ihn mit Informationen an.
+// Liveness-Probes geben true zurück, wenn der Host geantwortet hat;
+// alle Probes dürfen Felder des Hosts setzen (RTT, MAC, Hostname, Ports, ...).
+type Probe interface {
+	Name() string
+	Kind() ProbeKind
+	Probe(ctx context.Context, host *Host) (bool, error)
+}
+
+// Preparer ist ein optionales Interface für Probes, die einmal vor dem Scan
+// über alle Ziele laufen müssen (z.B. ARP-Sweep, SSDP-Discovery)
+type Preparer interface {
+	Prepare(ctx context.Context, targets []net.IP) error
+}
+
+// ProbeFactory erzeugt eine Probe. args ist der Teil nach dem "/" in der
+// Pipeline-Spezifikation (z.B. "22,3389" bei "tcp/22,3389"), sonst leer.
+type ProbeFactory func(args string, config Config) (Probe, error)
+
+var (
+	probeRegistry   = make(map[string]ProbeFactory)
+	probeRegistryMu sync.RWMutex
+)
+
+// RegisterProbe registriert eine Probe unter einem Namen.
+// Ein bereits registrierter Name (auch eine eingebaute Probe) wird ersetzt.
+func RegisterProbe(name string, factory ProbeFactory) {
+	probeRegistryMu.Lock()
+	defer probeRegistryMu.Unlock()
+	probeRegistry[strings.ToLower(name)] = factory
+}
+
+// RegisteredProbes gibt die Namen aller registrierten Probes sortiert zurück
+func RegisteredProbes() []string {
+	probeRegistryMu.RLock()
+	defer probeRegistryMu.RUnlock()
+
+	names := make([]string, 0, len(probeRegistry))
+	for name := range probeRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProbe erzeugt eine Probe aus einer Spezifikation wie "tcp/22,3389"
+func NewProbe(spec string, config Config) (Probe, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(spec), "/")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	probeRegistryMu.RLock()
+	factory, ok := probeRegistry[name]
+	probeRegistryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown probe %q (available: %s)", name, strings.Join(RegisteredProbes(), ", "))
+	}
+
+	probe, err := factory(strings.TrimSpace(args), config)
+	if err != nil {
+		return nil, fmt.Errorf("probe %q: %v", name, err)
+	}
+	return probe, nil
+}
+
+// builtinModes bildet die eingebauten Scan-Modi auf Probe-Pipelines ab.
+// "arp" und "hybrid" sind eigene Abläufe im CLI und Watch-Modus.
+var builtinModes = map[string]string{
+	"conservative": "tcp/22,80,443,445,135+dns",
+	"fast":         "tcp/80,443,445",
+	"thorough":     "tcp-verify/22,23,25,53,80,110,135,143,445,443,993,995,3389+dns",
+	"icmp":         "icmp",
+}
+
+// BuiltinMode gibt die Probe-Pipeline eines eingebauten Scan-Modus zurück
+func BuiltinMode(name string) (string, bool) {
+	spec, ok := builtinModes[name]
+	return spec, ok
+}
+
+// Pipeline ist eine geordnete Kette von Probes, z.B. "arp+icmp+tcp/22,3389".
+// Alle Liveness-Probes laufen für jeden Host; ist mindestens eine erfolgreich,
+// gilt der Host als online und die Enrichment-Probes werden ausgeführt.
+type Pipeline struct {
+	Spec   string
+	Probes []Probe
+}
+
+// ParsePipeline erzeugt eine Pipeline aus einer durch "+" getrennten Spezifikation
+func ParsePipeline(spec string, config Config) (*Pipeline, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty probe pipeline")
+	}
+
+	pipeline := &Pipeline{Spec: spec}
+	for _, part := range strings.Split(spec, "+") {
+		if strings.TrimSpace(part) == "" {
+			return nil, fmt.Errorf("empty probe in pipeline %q", spec)
+		}
+		probe, err := NewProbe(part, config)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Probes = append(pipeline.Probes, probe)
+	}
+
+	return pipeline, nil
+}
+
+// Has prüft, ob die Pipeline eine Probe mit dem Namen enthält
+func (p *Pipeline) Has(name string) bool {
+	for _, probe := range p.Probes {
+		if probe.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Prepare ruft Prepare für alle Probes auf, die das Preparer-Interface implementieren
+func (p *Pipeline) Prepare(ctx context.Context, targets []net.IP) {
+	for _, probe := range p.Probes {
+		if preparer, ok := probe.(Preparer); ok {
+			// Fehler sind nicht fatal - die Probe liefert dann einfach keine Treffer
+			_ = preparer.Prepare(ctx, targets)
+		}
+	}
+}
+
+// Run führt die Pipeline für einen Host aus
+func (p *Pipeline) Run(ctx context.Context, host *Host) {
+	alive := false
+	hasLiveness := false
+	for _, probe := range p.Probes {
+		if probe.Kind() != ProbeLiveness {
+			continue
+		}
+		hasLiveness = true
+		if ctx.Err() != nil {
+			return
+		}
+		if ok, err := probe.Probe(ctx, host); ok && err == nil {
+			alive = true
+		}
+	}
+
+	// Pipelines nur aus Enrichment-Probes reichern bereits bekannte Hosts an
+	if hasLiveness && alive {
+		host.Online = true
+	}
+	if !host.Online {
+		return
+	}
+
+	enriched := false
+	for _, probe := range p.Probes {
+		if probe.Kind() != ProbeEnrichment {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		_, _ = probe.Probe(ctx, host)
+		enriched = true
+	}
+
+	// Gerätetyp mit den neu gewonnenen Informationen neu bestimmen
+	if enriched {
+		Classify(host)
+	}
+}
+
+// DetectDeviceType bestimmt den Gerätetyp eines Hosts mit dem aktiven Regelsatz
+// (siehe discovery.Classify). Die eingebauten Regeln gewichten die Selbstauskunft des
+// Geräts (SNMP, LLDP/CDP, DNS-SD, UPnP, DHCP Vendor Class) höher als Hostname, Vendor und Ports.
+func DetectDeviceType(host *Host) string {
+	return classifyHost(host, guessOS(host)).Type
+}
+
+// Classify bestimmt Betriebssystem, Gerätetyp und Klassifizierung eines Hosts neu
+func Classify(host *Host) {
+	host.OS = guessOS(host)
+	classification := classifyHost(host, host.OS)
+	host.DeviceType = classification.Type
+	host.Classification = &classification
+}
+
+// minRuleOSConfidence ist die Sicherheit, ab der die Betriebssystem-Familie in die
+// Klassifizierung eingeht (TTL 128 allein reicht, TTL 64 bzw. 255 allein nicht)
+const minRuleOSConfidence = 40
+
+func classifyHost(host *Host, hint *discovery.OSHint) discovery.Classification {
+	facts := discovery.DeviceFacts{
+		Hostname:   host.Hostname,
+		MAC:        host.MAC,
+		Vendor:     host.Vendor,
+		Ports:      host.Ports,
+		DNSSD:      host.DNSSD,
+		UPnP:       host.UPnP,
+		HTTPTitle:  host.HTTPTitle,
+		Neighbor:   host.Neighbor,
+		DHCPVendor: host.DHCPVendor,
+	}
+	if host.SNMP != nil {
+		facts.SNMPDescr, facts.SNMPObjectID = host.SNMP.Descr, host.SNMP.ObjectID
+	}
+	if hint != nil && hint.Confidence >= minRuleOSConfidence {
+		facts.OS = hint.Family
+	}
+	return discovery.Classify(facts)
+}
+
+// guessOS schätzt die Betriebssystem-Familie aus TTL, dem SYN-ACK der os-Probe und den
+// Bannern von HTTP, Diensten und SNMP. TTL und SYN-ACK früherer Scans bleiben erhalten.
+func guessOS(host *Host) *discovery.OSHint {
+	signals := discovery.OSSignals{TTL: host.TTL}
+	if host.OS != nil {
+		signals.TCP = host.OS.TCP
+		if signals.TTL == 0 {
+			signals.TTL = host.OS.TTL
+		}
+	}
+	if host.HTTPBanner != "" {
+		signals.Banners = append(signals.Banners, host.HTTPBanner)
+	}
+	for _, svc := range host.Services {
+		if line := svc.BannerLine(); line != "" {
+			signals.Banners = append(signals.Banners, line) // Ohne HTTP-Header und -Body
+		} else if summary := svc.Summary(); summary != "" {
+			signals.Banners = append(signals.Banners, summary)
+		}
+	}
+	if host.SNMP != nil && host.SNMP.Descr != "" {
+		signals.Banners = append(signals.Banners, host.SNMP.Descr)
+	}
+	return discovery.GuessOS(signals)
+}
+
+// parsePortArgs parst eine Port-Liste wie "22,80,8000-8010"
+func parsePortArgs(args string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(args, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		entry, err := parsePortEntry(part)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, entry...)
+	}
+	return ports, nil
+}
+
+// ParsePorts parst eine Port-Liste mit Protokoll-Präfixen wie "22,80,u:53,161":
+// "u:" schaltet auf UDP um, "t:" zurück auf TCP (gilt bis zum nächsten Präfix)
+func ParsePorts(spec string) (tcp, udp []int, err error) {
+	protocol := &tcp
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		switch prefix := strings.ToLower(part); {
+		case strings.HasPrefix(prefix, "u:"):
+			protocol, part = &udp, strings.TrimSpace(part[2:])
+		case strings.HasPrefix(prefix, "t:"):
+			protocol, part = &tcp, strings.TrimSpace(part[2:])
+		}
+		if part == "" {
+			continue
+		}
+		entry, err := parsePortEntry(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		*protocol = append(*protocol, entry...)
+	}
+	return tcp, udp, nil
+}
+
+// parsePortEntry parst einen Port oder einen Bereich wie "8000-8010"
+func parsePortEntry(part string) ([]int, error) {
+	if from, to, isRange := strings.Cut(part, "-"); isRange {
+		start, err1 := strconv.Atoi(from)
+		end, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+			return nil, fmt.Errorf("invalid port range %q", part)
+		}
+		ports := make([]int, 0, end-start+1)
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
+		return ports, nil
+	}
+
+	port, err := strconv.Atoi(part)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %q", part)
+	}
+	return []int{port}, nil
+}
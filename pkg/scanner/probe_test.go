@@ -0,0 +1,342 @@
+package scanner_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
+	"netspy/pkg/snmp"
+)
+
+// fakeProbe ist eine Test-Probe mit festem Ergebnis
+type fakeProbe struct {
+	name     string
+	kind     scanner.ProbeKind
+	result   bool
+	calls    int32
+	prepared int32
+	apply    func(host *scanner.Host)
+}
+
+func (p *fakeProbe) Name() string            { return p.name }
+func (p *fakeProbe) Kind() scanner.ProbeKind { return p.kind }
+
+func (p *fakeProbe) Probe(ctx context.Context, host *scanner.Host) (bool, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if p.result && p.apply != nil {
+		p.apply(host)
+	}
+	return p.result, nil
+}
+
+func (p *fakeProbe) Prepare(ctx context.Context, targets []net.IP) error {
+	atomic.AddInt32(&p.prepared, 1)
+	return nil
+}
+
+var _ = Describe("Probes", func() {
+	Describe("Registry", func() {
+		It("should provide the builtin probes", func() {
+			Expect(scanner.RegisteredProbes()).To(ContainElements(
+				"tcp", "tcp-verify", "icmp", "arp", "udp", "dns", "mdns", "dnssd", "netbios", "llmnr", "ssdp", "http", "ports", "services", "tls", "snmp", "os",
+			))
+		})
+
+		It("should allow registering third-party probes", func() {
+			scanner.RegisterProbe("custom-test", func(args string, config scanner.Config) (scanner.Probe, error) {
+				return &fakeProbe{name: "custom-test", kind: scanner.ProbeLiveness, result: args == "ok"}, nil
+			})
+
+			probe, err := scanner.NewProbe("custom-test/ok", scanner.Config{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probe.Name()).To(Equal("custom-test"))
+
+			ok, err := probe.Probe(context.Background(), &scanner.Host{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should reject unknown probes", func() {
+			_, err := scanner.NewProbe("does-not-exist", scanner.Config{})
+			Expect(err).To(MatchError(ContainSubstring("unknown probe")))
+		})
+	})
+
+	Describe("ParsePipeline", func() {
+		It("should parse a pipeline with probe arguments", func() {
+			pipeline, err := scanner.ParsePipeline("arp+icmp+tcp/22,3389", scanner.Config{Timeout: 500 * time.Millisecond})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pipeline.Probes).To(HaveLen(3))
+			Expect(pipeline.Has("tcp")).To(BeTrue())
+			Expect(pipeline.Has("udp")).To(BeFalse())
+		})
+
+		It("should accept port ranges", func() {
+			_, err := scanner.ParsePipeline("tcp/8000-8010,22", scanner.Config{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should reject invalid specifications", func() {
+			for _, spec := range []string{"", "icmp+", "tcp/abc", "tcp/0", "tcp/90-80", "udp/70000"} {
+				_, err := scanner.ParsePipeline(spec, scanner.Config{})
+				Expect(err).To(HaveOccurred(), "spec %q", spec)
+			}
+		})
+
+		It("should parse TCP and UDP port lists", func() {
+			tcp, udp, err := scanner.ParsePorts("22,80, u:53,161-162,t:443,U:5353")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcp).To(Equal([]int{22, 80, 443}))
+			Expect(udp).To(Equal([]int{53, 161, 162, 5353}))
+
+			_, _, err = scanner.ParsePorts("u:dns")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should resolve the builtin modes", func() {
+			for _, mode := range []string{"conservative", "fast", "thorough", "icmp"} {
+				spec, ok := scanner.BuiltinMode(mode)
+				Expect(ok).To(BeTrue())
+				_, err := scanner.ParsePipeline(spec, scanner.Config{})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			_, ok := scanner.BuiltinMode("hybrid")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Pipeline.Run", func() {
+		var ctx context.Context
+
+		BeforeEach(func() {
+			ctx = context.Background()
+		})
+
+		It("should mark a host online if any liveness probe succeeds", func() {
+			failing := &fakeProbe{name: "a", kind: scanner.ProbeLiveness}
+			succeeding := &fakeProbe{name: "b", kind: scanner.ProbeLiveness, result: true}
+			enrich := &fakeProbe{name: "c", kind: scanner.ProbeEnrichment, result: true, apply: func(h *scanner.Host) {
+				h.Hostname = "printer"
+			}}
+			pipeline := &scanner.Pipeline{Probes: []scanner.Probe{failing, succeeding, enrich}}
+
+			host := scanner.Host{IP: net.ParseIP("192.0.2.10")}
+			pipeline.Run(ctx, &host)
+
+			Expect(host.Online).To(BeTrue())
+			Expect(host.Hostname).To(Equal("printer"))
+			Expect(failing.calls).To(Equal(int32(1)))
+		})
+
+		It("should skip enrichment for offline hosts", func() {
+			enrich := &fakeProbe{name: "c", kind: scanner.ProbeEnrichment, result: true}
+			pipeline := &scanner.Pipeline{Probes: []scanner.Probe{
+				&fakeProbe{name: "a", kind: scanner.ProbeLiveness},
+				enrich,
+			}}
+
+			host := scanner.Host{IP: net.ParseIP("192.0.2.10")}
+			pipeline.Run(ctx, &host)
+
+			Expect(host.Online).To(BeFalse())
+			Expect(enrich.calls).To(BeZero())
+		})
+
+		It("should only enrich already known hosts without liveness probes", func() {
+			enrich := &fakeProbe{name: "c", kind: scanner.ProbeEnrichment, result: true}
+			pipeline := &scanner.Pipeline{Probes: []scanner.Probe{enrich}}
+
+			offline := scanner.Host{IP: net.ParseIP("192.0.2.10")}
+			pipeline.Run(ctx, &offline)
+			Expect(offline.Online).To(BeFalse())
+			Expect(enrich.calls).To(BeZero())
+
+			online := scanner.Host{IP: net.ParseIP("192.0.2.11"), Online: true}
+			pipeline.Run(ctx, &online)
+			Expect(enrich.calls).To(Equal(int32(1)))
+		})
+
+		It("should find open TCP and UDP ports", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer ln.Close()
+			udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer udp.Close()
+			go func() {
+				buf := make([]byte, 512)
+				n, addr, err := udp.ReadFrom(buf)
+				if err == nil {
+					_, _ = udp.WriteTo(buf[:n], addr)
+				}
+			}()
+
+			tcpPort := ln.Addr().(*net.TCPAddr).Port
+			udpPort := udp.LocalAddr().(*net.UDPAddr).Port
+			probe, err := scanner.NewProbe(fmt.Sprintf("ports/%d,u:%d", tcpPort, udpPort), scanner.Config{Timeout: 250 * time.Millisecond})
+			Expect(err).NotTo(HaveOccurred())
+
+			host := scanner.Host{IP: net.ParseIP("127.0.0.1"), Online: true}
+			found, err := probe.Probe(context.Background(), &host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(host.Ports).To(Equal([]int{tcpPort}))
+			Expect(host.UDPPorts).To(Equal([]int{udpPort}))
+			Expect(host.Services).To(HaveLen(1))
+			Expect(host.Services[0].Protocol).To(Equal("udp"))
+		})
+
+		It("should enrich hosts via SNMP", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+			agent := &snmp.Agent{Communities: []string{"netz"}, Objects: map[string]interface{}{
+				"1.3.6.1.2.1.1.1.0":     "HPE OfficeConnect Switch 1820 24G J9980A, PD.02.22",
+				"1.3.6.1.2.1.1.2.0":     snmp.OID("1.3.6.1.4.1.11.2.3.7.11.181"),
+				"1.3.6.1.2.1.1.5.0":     "sw-keller",
+				"1.3.6.1.2.1.1.6.0":     "Keller",
+				"1.3.6.1.2.1.2.2.1.2.1": "Port 1",
+			}}
+			go func() { _ = agent.Serve(conn) }()
+			port := conn.LocalAddr().(*net.UDPAddr).Port
+
+			probe, err := scanner.NewProbe("snmp", scanner.Config{
+				Timeout: 50 * time.Millisecond,
+				SNMP: []snmp.Credentials{
+					{Community: "public", Port: port},
+					{Community: "netz", Port: port},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			host := scanner.Host{IP: net.ParseIP("127.0.0.1"), Online: true, Hostname: "HPE Switch", HostnameSource: "SSDP", Vendor: "Apple"}
+			found, err := probe.Probe(context.Background(), &host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(host.Hostname).To(Equal("sw-keller"))
+			Expect(host.HostnameSource).To(Equal("snmp"))
+			Expect(host.DeviceType).To(Equal("Network Equipment (Switch)"))
+			Expect(host.SNMP.Location).To(Equal("Keller"))
+			Expect(host.SNMP.Interfaces).To(HaveLen(1))
+
+			_, err = scanner.NewProbe("snmp", scanner.Config{SNMP: []snmp.Credentials{{Version: "3"}}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should attach DNS-SD services to hosts", func() {
+			probe := scanner.NewDNSSDProbe(map[string][]discovery.DNSSDService{
+				"192.168.1.60": {
+					{Type: "_airplay._tcp", Instance: "Wohnzimmer", Host: "Apple-TV", Port: 7000, TXT: map[string]string{"model": "AppleTV6,2"}},
+					{Type: "_raop._tcp", Instance: "AABBCCDDEEFF@Wohnzimmer", Host: "Apple-TV", Port: 7000},
+				},
+			})
+			Expect(probe.(scanner.Preparer).Prepare(context.Background(), nil)).To(Succeed())
+
+			host := scanner.Host{IP: net.ParseIP("192.168.1.60"), Online: true, Vendor: "Apple"}
+			found, err := probe.Probe(context.Background(), &host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(host.DNSSD).To(HaveLen(2))
+			Expect(host.Hostname).To(Equal("Apple-TV"))
+			Expect(host.HostnameSource).To(Equal("mdns"))
+			Expect(host.DeviceType).To(Equal("IoT Device (Media)"))
+
+			other := scanner.Host{IP: net.ParseIP("192.168.1.61"), Online: true, Hostname: "nas", HostnameSource: "dns"}
+			found, err = probe.Probe(context.Background(), &other)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+			Expect(other.Hostname).To(Equal("nas"))
+		})
+
+		It("should merge the UPnP device description into hosts", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`<root><device>
+					<deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+					<friendlyName>FRITZ!Box 7590</friendlyName>
+					<manufacturer>AVM Berlin</manufacturer>
+					<modelName>FRITZ!Box 7590</modelName>
+					<serviceList><service><serviceType>urn:schemas-upnp-org:service:Layer3Forwarding:1</serviceType></service></serviceList>
+				</device></root>`))
+			}))
+			DeferCleanup(server.Close)
+
+			probe := scanner.NewSSDPProbe(map[string]discovery.SSDPDevice{
+				"127.0.0.1": {IP: "127.0.0.1", Location: server.URL + "/igddesc.xml", Server: "FRITZ!Box UPnP/1.0"},
+			})
+			host := scanner.Host{IP: net.ParseIP("127.0.0.1"), Online: true}
+			found, err := probe.Probe(context.Background(), &host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(host.UPnP).NotTo(BeNil())
+			Expect(host.UPnP.Manufacturer).To(Equal("AVM Berlin"))
+			Expect(host.UPnP.ServiceNames()).To(Equal([]string{"Layer3Forwarding"}))
+			Expect(host.Hostname).To(Equal("FRITZ!Box 7590"))
+			Expect(host.HostnameSource).To(Equal("SSDP"))
+			Expect(host.DeviceType).To(Equal("Network Equipment (Router)"))
+		})
+
+		It("should guess the OS from the SYN-ACK of an open port", func() {
+			listener, err := net.Listen("tcp4", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(listener.Close)
+			port := listener.Addr().(*net.TCPAddr).Port
+
+			probe, err := scanner.NewProbe(fmt.Sprintf("os/%d", port), scanner.Config{Timeout: 200 * time.Millisecond})
+			Expect(err).NotTo(HaveOccurred())
+			host := scanner.Host{IP: net.ParseIP("127.0.0.1"), Online: true, Ports: []int{port}}
+			found, err := probe.Probe(context.Background(), &host)
+			Expect(err).NotTo(HaveOccurred())
+			if host.OS == nil || host.OS.TCP == nil {
+				Skip("raw socket not available")
+			}
+			Expect(found).To(BeTrue())
+			Expect(host.OS.Family).To(Equal(discovery.OSLinux))
+			Expect(host.OS.TCP.Port).To(Equal(port))
+		})
+
+		It("should feed the OS family into the device type", func() {
+			host := scanner.Host{IP: net.ParseIP("192.0.2.5"), TTL: 127, HTTPBanner: "Microsoft-IIS/10.0"}
+			scanner.Classify(&host)
+			Expect(host.OS.Family).To(Equal(discovery.OSWindows))
+			Expect(host.OS.TTL).To(Equal(127))
+			Expect(host.DeviceType).To(Equal("Windows Computer"))
+
+			// TTL und SYN-ACK bleiben erhalten, wenn ein späterer Scan sie nicht liefert
+			host.TTL, host.HTTPBanner = 0, ""
+			scanner.Classify(&host)
+			Expect(host.OS.Family).To(Equal(discovery.OSWindows))
+			Expect(host.OS.Confidence).To(Equal(40))
+
+			// TTL 64 allein ist zu unsicher für die Klassifizierung
+			host = scanner.Host{IP: net.ParseIP("192.0.2.6"), TTL: 64}
+			scanner.Classify(&host)
+			Expect(host.OS.Family).To(Equal(discovery.OSUnix))
+			Expect(host.DeviceType).To(Equal("Unknown"))
+		})
+
+		It("should prepare probes before scanning", func() {
+			probe := &fakeProbe{name: "a", kind: scanner.ProbeLiveness, result: true}
+			s := scanner.New(scanner.Config{
+				Concurrency: 4,
+				Pipeline:    &scanner.Pipeline{Spec: "a", Probes: []scanner.Probe{probe}},
+			})
+
+			targets := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}
+			hosts, err := s.ScanHosts(targets, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hosts).To(HaveLen(2))
+			Expect(probe.prepared).To(Equal(int32(1)))
+			Expect(probe.calls).To(Equal(int32(2)))
+		})
+	})
+})
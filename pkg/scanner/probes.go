@@ -0,0 +1,793 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"netspy/pkg/discovery"
+	"netspy/pkg/service"
+	"netspy/pkg/snmp"
+)
+
+// Eingebaute Probes - Liveness: tcp, tcp-verify, icmp, arp, udp
+// Enrichment: dns, mdns, dnssd, netbios, llmnr, ssdp, http, ports, services, tls, snmp, os
+func init() {
+	RegisterProbe("tcp", func(args string, config Config) (Probe, error) {
+		return newTCPProbe("tcp", args, config, false)
+	})
+	RegisterProbe("tcp-verify", func(args string, config Config) (Probe, error) {
+		return newTCPProbe("tcp-verify", args, config, true)
+	})
+	RegisterProbe("icmp", func(args string, config Config) (Probe, error) {
+		return &icmpProbe{timeout: config.Timeout}, nil
+	})
+	RegisterProbe("arp", func(args string, config Config) (Probe, error) {
+		return &arpProbe{timeout: config.Timeout}, nil
+	})
+	RegisterProbe("udp", func(args string, config Config) (Probe, error) {
+		if args == "" {
+			args = "53,123,137,161"
+		}
+		ports, err := parsePortArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return &udpProbe{ports: ports, timeout: config.Timeout}, nil
+	})
+	RegisterProbe("ports", func(args string, config Config) (Probe, error) {
+		ports, udpPorts := config.Ports, config.UDPPorts
+		if args != "" {
+			var err error
+			if ports, udpPorts, err = ParsePorts(args); err != nil {
+				return nil, err
+			}
+		}
+		timeout := config.Timeout / 2
+		if timeout < 300*time.Millisecond {
+			timeout = 300 * time.Millisecond
+		}
+		// UDP braucht länger: keine Antwort heißt erst nach zwei Versuchen open|filtered
+		udpTimeout := 4 * config.Timeout
+		if udpTimeout < time.Second {
+			udpTimeout = time.Second
+		}
+		return &portsProbe{
+			ports:    ports,
+			udpPorts: udpPorts,
+			timeout:  timeout,
+			detector: &service.Detector{Timeout: udpTimeout},
+		}, nil
+	})
+	for _, method := range []string{"dns", "mdns", "netbios", "llmnr"} {
+		method := method
+		RegisterProbe(method, func(args string, config Config) (Probe, error) {
+			return &hostnameProbe{method: method, timeout: config.Timeout}, nil
+		})
+	}
+	RegisterProbe("ssdp", func(args string, config Config) (Probe, error) {
+		return NewSSDPProbe(nil), nil
+	})
+	RegisterProbe("dnssd", func(args string, config Config) (Probe, error) {
+		return NewDNSSDProbe(nil), nil
+	})
+	RegisterProbe("services", func(args string, config Config) (Probe, error) {
+		timeout := 4 * config.Timeout
+		if timeout < 2*time.Second {
+			timeout = 2 * time.Second
+		}
+		return &servicesProbe{detector: &service.Detector{Timeout: timeout}}, nil
+	})
+	RegisterProbe("tls", func(args string, config Config) (Probe, error) {
+		ports := service.TLSPorts
+		if args != "" {
+			var err error
+			if ports, err = parsePortArgs(args); err != nil {
+				return nil, err
+			}
+		}
+		timeout := 4 * config.Timeout
+		if timeout < 2*time.Second {
+			timeout = 2 * time.Second
+		}
+		return &tlsProbe{ports: ports, detector: &service.Detector{Timeout: timeout}}, nil
+	})
+	RegisterProbe("snmp", func(args string, config Config) (Probe, error) {
+		credentials := config.SNMP
+		if args != "" {
+			// "snmp/public,private": v2c-Communities statt der konfigurierten Zugangsdaten
+			credentials = nil
+			for _, community := range strings.Split(args, ",") {
+				credentials = append(credentials, snmp.Credentials{Community: strings.TrimSpace(community)})
+			}
+		}
+		if len(credentials) == 0 {
+			credentials = []snmp.Credentials{{Community: "public"}}
+		}
+		probe := &snmpProbe{timeout: 2 * config.Timeout}
+		if probe.timeout < time.Second {
+			probe.timeout = time.Second
+		}
+		for _, creds := range credentials {
+			normalized, err := creds.Normalize()
+			if err != nil {
+				return nil, err
+			}
+			probe.credentials = append(probe.credentials, normalized)
+		}
+		return probe, nil
+	})
+	RegisterProbe("http", func(args string, config Config) (Probe, error) {
+		timeout := 4 * config.Timeout
+		if timeout < 2*time.Second {
+			timeout = 2 * time.Second
+		}
+		return &httpProbe{timeout: timeout}, nil
+	})
+	RegisterProbe("os", func(args string, config Config) (Probe, error) {
+		ports := osProbePorts
+		if args != "" {
+			var err error
+			if ports, err = parsePortArgs(args); err != nil {
+				return nil, err
+			}
+		}
+		timeout := config.Timeout / 2
+		if timeout < 300*time.Millisecond {
+			timeout = 300 * time.Millisecond
+		}
+		return &osProbe{ports: ports, timeout: timeout}, nil
+	})
+}
+
+// tcpProbe gilt als erfolgreich, sobald ein Port eine TCP-Verbindung annimmt
+type tcpProbe struct {
+	name    string
+	ports   []int
+	timeout time.Duration
+	verify  bool // Zweite Verbindung zur Validierung (vermeidet False Positives)
+}
+
+// conservativePorts sind die Standard-Ports der tcp-Probe (Modus conservative)
+var conservativePorts = []int{22, 80, 443, 445, 135}
+
+func newTCPProbe(name, args string, config Config, verify bool) (Probe, error) {
+	ports := conservativePorts
+	if args != "" {
+		var err error
+		if ports, err = parsePortArgs(args); err != nil {
+			return nil, err
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+
+	// Timeout auf die Ports verteilen. Nur die conservative-Ports werden zusätzlich
+	// auf 300ms begrenzt, damit gefilterte Ports nicht blockieren - andere Port-Listen
+	// (z.B. thorough mit --timeout 2s) sollen langsame Hosts weiterhin finden.
+	timeout := config.Timeout / time.Duration(len(ports))
+	if timeout < 100*time.Millisecond {
+		timeout = 100 * time.Millisecond
+	}
+	if timeout > 300*time.Millisecond && !verify && slices.Equal(ports, conservativePorts) {
+		timeout = 300 * time.Millisecond
+	}
+
+	return &tcpProbe{name: name, ports: ports, timeout: timeout, verify: verify}, nil
+}
+
+func (p *tcpProbe) Name() string    { return p.name }
+func (p *tcpProbe) Kind() ProbeKind { return ProbeLiveness }
+
+func (p *tcpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	dialer := net.Dialer{Timeout: p.timeout}
+	start := time.Now()
+
+	for _, port := range p.ports {
+		addr := net.JoinHostPort(host.IP.String(), strconv.Itoa(port))
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			continue
+		}
+		_ = conn.Close() // Ignore close error
+
+		if p.verify {
+			conn2, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				continue
+			}
+			_ = conn2.Close() // Ignore close error
+		}
+
+		if host.RTT == 0 {
+			host.RTT = time.Since(start)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// icmpProbe nutzt die gemeinsame ICMP-Engine (zwei Echo-Requests), für IPv6 ICMPv6-Echo
+type icmpProbe struct {
+	timeout time.Duration
+}
+
+func (p *icmpProbe) Name() string    { return "icmp" }
+func (p *icmpProbe) Kind() ProbeKind { return ProbeLiveness }
+
+func (p *icmpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	if host.IP.To4() == nil {
+		rtt, err := discovery.PingIPv6(ctx, host.IP, p.timeout)
+		if err != nil {
+			return false, nil
+		}
+		if host.RTT == 0 {
+			host.RTT = rtt
+		}
+		return true, nil
+	}
+
+	result := discovery.SharedICMPEngine().PingHost(ctx, host.IP, 2, 20*time.Millisecond, p.timeout)
+	if !result.Alive() {
+		return false, nil
+	}
+
+	if host.RTT == 0 {
+		host.RTT = result.RTT
+	}
+	if result.TTL > 0 {
+		host.TTL = result.TTL
+	}
+	return true, nil
+}
+
+// arpProbe führt in Prepare einen ARP-Sweep über alle lokalen Ziele durch
+// (Fallback: ARP-Tabelle) und prüft pro Host nur noch das Ergebnis
+type arpProbe struct {
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]discovery.ARPEntry
+}
+
+func (p *arpProbe) Name() string    { return "arp" }
+func (p *arpProbe) Kind() ProbeKind { return ProbeLiveness }
+
+func (p *arpProbe) Prepare(ctx context.Context, targets []net.IP) error {
+	arpScanner := discovery.NewARPScanner(p.timeout)
+
+	entries, err := arpScanner.SweepTargets(ctx, targets)
+	if err != nil {
+		// Ohne Raw-Socket: ARP-Tabelle per ICMP befüllen und auslesen
+		primeARPTable(ctx, targets)
+		allIPv4 := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		if entries, err = arpScanner.ScanARPTableQuiet(allIPv4); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.entries = make(map[string]discovery.ARPEntry, len(entries))
+	for _, entry := range entries {
+		p.entries[entry.IP.String()] = entry
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *arpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	p.mu.RLock()
+	entry, ok := p.entries[host.IP.String()]
+	p.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	host.MAC = entry.MAC.String()
+	host.Vendor = discovery.GetMACVendor(host.MAC)
+	Classify(host)
+	if host.RTT == 0 {
+		host.RTT = entry.RTT
+	}
+	return true, nil
+}
+
+// primeARPTable sendet kurze ICMP-Echos, damit das OS ARP-Einträge anlegt
+func primeARPTable(ctx context.Context, targets []net.IP) {
+	engine := discovery.SharedICMPEngine()
+	semaphore := make(chan struct{}, 100)
+	var wg sync.WaitGroup
+
+	for _, ip := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(target net.IP) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			_, _, _ = engine.Ping(ctx, target, 50*time.Millisecond)
+		}(ip)
+	}
+
+	wg.Wait()
+	time.Sleep(100 * time.Millisecond) // Wait for ARP table to update
+}
+
+// udpProbe sendet leere Datagramme; jede Antwort - auch ein ICMP "port unreachable" -
+// beweist, dass der Host existiert
+type udpProbe struct {
+	ports   []int
+	timeout time.Duration
+}
+
+func (p *udpProbe) Name() string    { return "udp" }
+func (p *udpProbe) Kind() ProbeKind { return ProbeLiveness }
+
+func (p *udpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	dialer := net.Dialer{Timeout: p.timeout}
+
+	for _, port := range p.ports {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host.IP.String(), strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+
+		_ = conn.SetDeadline(time.Now().Add(p.timeout))
+		if _, err := conn.Write([]byte{0}); err != nil {
+			_ = conn.Close()
+			continue
+		}
+
+		buf := make([]byte, 512)
+		_, err = conn.Read(buf)
+		_ = conn.Close()
+
+		var netErr net.Error
+		if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+			continue // Keine Antwort (gefiltert oder Host offline)
+		}
+
+		// Antwort oder ICMP-Fehler (connection refused) - Host ist erreichbar
+		if host.RTT == 0 {
+			host.RTT = time.Since(start)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// portsProbe ermittelt offene TCP- und UDP-Ports. UDP-Ports werden mit einer
+// Anfrage ihres Protokolls geprüft; die Dienste antwortender Ports landen direkt
+// in Host.Services.
+type portsProbe struct {
+	ports    []int
+	udpPorts []int
+	timeout  time.Duration
+	detector *service.Detector // UDP-Anfragen
+}
+
+func (p *portsProbe) Name() string    { return "ports" }
+func (p *portsProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *portsProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	if len(p.ports) == 0 && len(p.udpPorts) == 0 {
+		return false, nil
+	}
+
+	var udpServices []service.Service
+	host.UDPPorts = nil
+	if len(p.udpPorts) > 0 {
+		for _, result := range p.detector.DetectAllUDP(ctx, host.IP, p.udpPorts) {
+			if result.State == service.PortOpen {
+				host.UDPPorts = append(host.UDPPorts, result.Port)
+				udpServices = append(udpServices, result.Service)
+			}
+		}
+		host.Services = mergeServices(host.Services, udpServices)
+	}
+	if len(p.ports) == 0 {
+		return len(host.UDPPorts) > 0, nil
+	}
+
+	var openPorts []int
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	dialer := net.Dialer{Timeout: p.timeout}
+	for _, port := range p.ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host.IP.String(), strconv.Itoa(port)))
+			if err != nil {
+				return
+			}
+			_ = conn.Close() // Ignore close error
+			mutex.Lock()
+			openPorts = append(openPorts, port)
+			mutex.Unlock()
+		}(port)
+	}
+	wg.Wait()
+
+	sort.Ints(openPorts)
+	host.Ports = openPorts
+	return len(openPorts) > 0 || len(host.UDPPorts) > 0, nil
+}
+
+// mergeServices ersetzt bzw. ergänzt Dienste (gleicher Port und gleiches Protokoll)
+// und sortiert nach Port
+func mergeServices(services, updates []service.Service) []service.Service {
+	merged := make([]service.Service, 0, len(services)+len(updates))
+	for _, existing := range services {
+		replaced := false
+		for _, update := range updates {
+			if update.Port == existing.Port && update.Protocol == existing.Protocol {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, existing)
+		}
+	}
+	merged = append(merged, updates...)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Port < merged[j].Port })
+	return merged
+}
+
+// servicesProbe erkennt Dienst, Produkt und Version der offenen Ports
+// (setzt die Port-Probe davor voraus)
+type servicesProbe struct {
+	detector *service.Detector
+}
+
+func (p *servicesProbe) Name() string    { return "services" }
+func (p *servicesProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *servicesProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	if len(host.Ports) == 0 {
+		return false, nil
+	}
+
+	host.Services = mergeServices(host.Services, p.detector.DetectAll(ctx, host.IP, host.Ports))
+	return true, nil
+}
+
+// tlsProbe liest die Zertifikatsketten der TLS-Ports. Geprüft werden die
+// konfigurierten Ports und alle bereits gefundenen offenen Ports.
+type tlsProbe struct {
+	ports    []int
+	detector *service.Detector
+}
+
+func (p *tlsProbe) Name() string    { return "tls" }
+func (p *tlsProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *tlsProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	candidates := make(map[int]bool, len(p.ports)+len(host.Ports))
+	for _, port := range p.ports {
+		candidates[port] = true
+	}
+	for _, port := range host.Ports {
+		candidates[port] = true
+	}
+
+	chains := make(map[int][]service.Certificate)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for port := range candidates {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			chain, err := p.detector.Certificates(ctx, host.IP, port)
+			if err != nil || len(chain) == 0 {
+				return
+			}
+			mutex.Lock()
+			chains[port] = chain
+			mutex.Unlock()
+		}(port)
+	}
+	wg.Wait()
+
+	for port, chain := range chains {
+		host.Services = service.WithCertificates(host.Services, port, chain)
+		if !containsPort(host.Ports, port) {
+			host.Ports = append(host.Ports, port)
+		}
+	}
+	sort.Ints(host.Ports)
+	return len(chains) > 0, nil
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// hostnameProbe löst den Hostnamen mit einer einzelnen Methode auf
+// (nur wenn noch kein Hostname bekannt ist)
+type hostnameProbe struct {
+	method  string
+	timeout time.Duration
+}
+
+func (p *hostnameProbe) Name() string    { return p.method }
+func (p *hostnameProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *hostnameProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	if host.Hostname != "" {
+		return false, nil
+	}
+
+	result := discovery.ResolveVia(host.IP, p.method, p.timeout)
+	if result.Hostname == "" {
+		return false, nil
+	}
+
+	host.Hostname = result.Hostname
+	host.HostnameSource = result.Source
+	return true, nil
+}
+
+// ssdpProbe sammelt UPnP-Geräte per SSDP-Multicast, lädt deren Gerätebeschreibung
+// (LOCATION) und nutzt den friendlyName als letzten Hostname-Fallback
+type ssdpProbe struct {
+	mu      sync.RWMutex
+	devices map[string]discovery.SSDPDevice
+}
+
+// NewSSDPProbe erstellt eine SSDP-Probe. Sind bereits Geräte bekannt (devices != nil),
+// wird in Prepare keine eigene Discovery mehr durchgeführt.
+func NewSSDPProbe(devices map[string]discovery.SSDPDevice) Probe {
+	return &ssdpProbe{devices: devices}
+}
+
+func (p *ssdpProbe) Name() string    { return "ssdp" }
+func (p *ssdpProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *ssdpProbe) Prepare(ctx context.Context, targets []net.IP) error {
+	p.mu.RLock()
+	known := p.devices != nil
+	p.mu.RUnlock()
+	if known {
+		return nil
+	}
+
+	devices, err := discovery.DiscoverSSDPDevices(3 * time.Second)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.devices = make(map[string]discovery.SSDPDevice, len(devices))
+	for _, device := range devices {
+		p.devices[device.IP] = device
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *ssdpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	p.mu.RLock()
+	device, found := p.devices[host.IP.String()]
+	p.mu.RUnlock()
+	if !found {
+		return false, nil
+	}
+
+	if device.Location != "" {
+		if description, err := discovery.FetchUPnPDescription(ctx, device, 2*time.Second); err == nil {
+			host.UPnP = description
+			Classify(host)
+		}
+	}
+
+	if host.Hostname == "" {
+		name := discovery.GetSSDPDeviceName(device)
+		if host.UPnP != nil && host.UPnP.FriendlyName != "" {
+			name = host.UPnP.FriendlyName
+		}
+		if name != "" {
+			host.Hostname = name
+			host.HostnameSource = "SSDP"
+		}
+	}
+	return true, nil
+}
+
+// dnssdProbe sammelt die per DNS-SD beworbenen Dienste (AirPlay, IPP, Chromecast,
+// HomeKit, ...) und ordnet sie den Hosts zu
+type dnssdProbe struct {
+	mu       sync.RWMutex
+	services map[string][]discovery.DNSSDService
+}
+
+// NewDNSSDProbe erstellt eine DNS-SD-Probe. Sind die Dienste bereits bekannt
+// (services != nil), wird in Prepare keine eigene Suche mehr durchgeführt.
+func NewDNSSDProbe(services map[string][]discovery.DNSSDService) Probe {
+	return &dnssdProbe{services: services}
+}
+
+func (p *dnssdProbe) Name() string    { return "dnssd" }
+func (p *dnssdProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *dnssdProbe) Prepare(ctx context.Context, targets []net.IP) error {
+	p.mu.RLock()
+	known := p.services != nil
+	p.mu.RUnlock()
+	if known {
+		return nil
+	}
+
+	services, err := discovery.BrowseDNSSD(ctx, 2*time.Second)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.services = services
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *dnssdProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	p.mu.RLock()
+	services, found := p.services[host.IP.String()]
+	p.mu.RUnlock()
+	if !found {
+		return false, nil
+	}
+
+	host.DNSSD = services
+	// Das SRV-Ziel ist der mDNS-Hostname des Geräts und schlägt den SSDP-Fallback
+	if host.Hostname == "" || host.HostnameSource == "SSDP" {
+		for _, svc := range services {
+			if svc.Host != "" {
+				host.Hostname = svc.Host
+				host.HostnameSource = "mdns"
+				break
+			}
+		}
+	}
+	Classify(host)
+	return true, nil
+}
+
+// snmpProbe fragt System-Gruppe und Interface-Tabelle per SNMP ab. Die
+// Zugangsdaten werden der Reihe nach probiert, bis ein Agent antwortet.
+type snmpProbe struct {
+	credentials []snmp.Credentials
+	timeout     time.Duration
+}
+
+func (p *snmpProbe) Name() string    { return "snmp" }
+func (p *snmpProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *snmpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	closed := make(map[int]bool)
+	for _, creds := range p.credentials {
+		if closed[creds.Port] {
+			continue
+		}
+		system, err := snmp.Query(ctx, host.IP, creds, p.timeout)
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if errors.Is(err, snmp.ErrUnreachable) {
+			closed[creds.Port] = true // Kein Agent auf dem Port - weitere Zugangsdaten sparen
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		host.SNMP = system
+		// sysName ist vom Admin gepflegt und schlägt nur den SSDP-Fallback
+		if system.Name != "" && (host.Hostname == "" || host.HostnameSource == "SSDP") {
+			host.Hostname = system.Name
+			host.HostnameSource = "snmp"
+		}
+		Classify(host)
+		return true, nil
+	}
+	return false, nil
+}
+
+// httpProbe liest den HTTP-Server-Banner der Web-Ports
+type httpProbe struct {
+	timeout time.Duration
+}
+
+func (p *httpProbe) Name() string    { return "http" }
+func (p *httpProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *httpProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	banner := discovery.GrabHTTPBanner(host.IP.String(), p.timeout)
+	if banner == nil {
+		return false, nil
+	}
+
+	host.HTTPBanner = banner.String()
+	host.HTTPTitle = banner.Title
+	return true, nil
+}
+
+// osProbePorts werden für das SYN-ACK der os-Probe nach den bekannten offenen Ports versucht
+var osProbePorts = []int{22, 80, 443, 445, 135, 139, 3389, 8080}
+
+// osProbe sammelt Hinweise auf das Betriebssystem: die TTL einer ICMP-Antwort und TTL,
+// Fenstergröße und Optionen des SYN-ACK eines offenen Ports (Raw-Socket, nur Linux).
+// Classify wertet sie zusammen mit den Bannern der anderen Probes aus.
+type osProbe struct {
+	ports   []int
+	timeout time.Duration
+}
+
+func (p *osProbe) Name() string    { return "os" }
+func (p *osProbe) Kind() ProbeKind { return ProbeEnrichment }
+
+func (p *osProbe) Probe(ctx context.Context, host *Host) (bool, error) {
+	if host.IP.To4() == nil {
+		return false, nil
+	}
+	if host.TTL == 0 {
+		result := discovery.SharedICMPEngine().PingHost(ctx, host.IP, 1, 0, p.timeout)
+		host.TTL = result.TTL
+	}
+
+	// Bekannte offene Ports zuerst, ein SYN-ACK genügt
+	tried := make(map[int]bool)
+	for _, port := range append(append([]int(nil), host.Ports...), p.ports...) {
+		if tried[port] {
+			continue
+		}
+		tried[port] = true
+
+		fp, err := discovery.FingerprintTCP(ctx, host.IP, port, p.timeout)
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if errors.Is(err, discovery.ErrRawSocketUnavailable) {
+			break // Ohne Raw-Socket bleiben TTL und Banner
+		}
+		if err != nil {
+			continue
+		}
+		hint := discovery.OSHint{}
+		if host.OS != nil {
+			hint = *host.OS
+		}
+		hint.TCP = fp
+		host.OS = &hint
+		break
+	}
+
+	Classify(host)
+	return host.OS != nil && host.OS.Family != "", nil
+}
@@ -1,28 +1,43 @@
 package scanner
 
 import (
-	"fmt"
+	"context"
 	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"netspy/pkg/discovery"
+	"netspy/pkg/service"
+	"netspy/pkg/snmp"
 )
 
 // Host repräsentiert einen entdeckten Netzwerk-Host
 type Host struct {
-	IP             net.IP        `json:"ip"`
-	Hostname       string        `json:"hostname,omitempty"`
-	HostnameSource string        `json:"hostname_source,omitempty"` // "netbios", "dns", "vendor"
-	MAC            string        `json:"mac,omitempty"`
-	Vendor         string        `json:"vendor,omitempty"`
-	DeviceType     string        `json:"device_type,omitempty"` // "Smartphone", "Computer", "IoT", etc.
-	HTTPBanner     string        `json:"http_banner,omitempty"` // HTTP server banner (e.g., "nginx/1.18.0")
-	RTT            time.Duration `json:"rtt,omitempty"`
-	Ports          []int         `json:"ports,omitempty"`
-	Online         bool          `json:"online"`
-	IsGateway      bool          `json:"is_gateway,omitempty"` // True wenn Host ein Gateway ist (lokal oder heuristisch erkannt)
+	IP             net.IP                    `json:"ip"`
+	IPv6           []IPv6Address             `json:"ipv6,omitempty"` // IPv6-Adressen desselben Geräts (über die MAC zugeordnet)
+	Hostname       string                    `json:"hostname,omitempty"`
+	HostnameSource string                    `json:"hostname_source,omitempty"` // "netbios", "dns", "mdns", "snmp", "vendor"
+	MAC            string                    `json:"mac,omitempty"`
+	Vendor         string                    `json:"vendor,omitempty"`
+	DeviceType     string                    `json:"device_type,omitempty"`    // "Smartphone", "Computer", "IoT", etc.
+	Classification *discovery.Classification `json:"classification,omitempty"` // Sicherheit und ausgelöste Regeln zu DeviceType
+	HTTPBanner     string                    `json:"http_banner,omitempty"`    // HTTP server banner (e.g., "nginx/1.18.0")
+	HTTPTitle      string                    `json:"http_title,omitempty"`     // Titel der Weboberfläche
+	RTT            time.Duration             `json:"rtt,omitempty"`
+	TTL            int                       `json:"ttl,omitempty"` // TTL der ICMP-Echo-Antwort (0 = unbekannt)
+	OS             *discovery.OSHint         `json:"os,omitempty"`  // Geschätzte Betriebssystem-Familie (TTL, SYN-ACK, Banner)
+	Ports          []int                     `json:"ports,omitempty"`
+	UDPPorts       []int                     `json:"udp_ports,omitempty"`   // Offene UDP-Ports (mit Antwort)
+	Services       []service.Service         `json:"services,omitempty"`    // Erkannte Dienste der offenen Ports
+	SNMP           *snmp.System              `json:"snmp,omitempty"`        // System-Gruppe und Interfaces per SNMP
+	DNSSD          []discovery.DNSSDService  `json:"dnssd,omitempty"`       // Per DNS-SD (mDNS) beworbene Dienste
+	UPnP           *discovery.UPnPDevice     `json:"upnp,omitempty"`        // UPnP-Gerätebeschreibung (per SSDP gefunden)
+	Neighbor       *discovery.Neighbor       `json:"neighbor,omitempty"`    // Eigene LLDP/CDP-Ankündigung (Switches, APs, Telefone)
+	DHCPVendor     string                    `json:"dhcp_vendor,omitempty"` // DHCP Vendor Class (Option 60, passiver Modus)
+	Online         bool                      `json:"online"`
+	IsGateway      bool                      `json:"is_gateway,omitempty"` // True wenn Host ein Gateway ist (lokal oder heuristisch erkannt)
 }
 
 // Config stores the scanner configuration
@@ -30,174 +45,219 @@ type Config struct {
 	Concurrency int
 	Timeout     time.Duration
 	Ports       []int
+	UDPPorts    []int              // UDP-Ports für die Port-Probe
+	SNMP        []snmp.Credentials // Zugangsdaten für die SNMP-Probe (Standard: Community "public")
 	RateLimit   time.Duration
-	Fast        bool
-	Thorough    bool
-	Quiet       bool // Suppress progress output
+	Fast        bool           // Geschwindigkeit vor Genauigkeit (ohne Reverse-DNS)
+	Thorough    bool           // Liefert auch Offline-Hosts zurück
+	ICMP        bool           // Erreichbarkeit per ICMP-Echo statt TCP-Ping prüfen
+	Quiet       bool           // Veraltet: der Scanner gibt selbst nichts mehr aus
+	Progress    func(Progress) // Optionaler Fortschritts-Callback (wird seriell aufgerufen)
+	Active      *int32         // Optionaler Zähler für aktive Scan-Worker
+	Pipeline    *Pipeline      // Optionale Probe-Pipeline (ersetzt ICMP/Fast/Thorough-Erkennung)
+}
+
+// Progress beschreibt den Fortschritt eines laufenden Scans
+type Progress struct {
+	Total   int           // Anzahl Ziele
+	Done    int           // Bereits gescannte Ziele
+	Found   int           // Davon online
+	Elapsed time.Duration // Laufzeit seit Scan-Start
+}
+
+// Rate gibt die Scan-Geschwindigkeit in Hosts pro Sekunde zurück
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / p.Elapsed.Seconds()
 }
 
 // Scanner führt Netzwerk-Discovery durch
 type Scanner struct {
-	config Config
-	pinger *discovery.Pinger
+	config   Config
+	pipeline *Pipeline
 }
 
 // New erstellt eine neue Scanner-Instanz
 func New(config Config) *Scanner {
-	if !config.Quiet {
-		fmt.Printf("🔧 Scanner mode: ")
-		if config.Fast {
-			fmt.Printf("Fast (speed over accuracy)\n")
-		} else if config.Thorough {
-			fmt.Printf("Thorough (accuracy over speed)\n")
-		} else {
-			fmt.Printf("Balanced (good speed + accuracy)\n")
-		}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 40
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 500 * time.Millisecond
+	}
 
-		fmt.Printf("🔧 Config: %d workers, %v timeout\n",
-			config.Concurrency, config.Timeout)
+	pipeline := config.Pipeline
+	if pipeline == nil {
+		pipeline = legacyPipeline(config)
 	}
 
 	return &Scanner{
-		config: config,
-		pinger: discovery.NewPinger(config.Timeout, config.Fast, config.Thorough),
+		config:   config,
+		pipeline: pipeline,
 	}
 }
 
-// ScanHosts scannt mit modus-angepasster Strategie
-// activeThreads ist optional - wenn nicht nil, werden aktive Scan-Threads gezählt
-func (s *Scanner) ScanHosts(ips []net.IP, activeThreads *int32) ([]Host, error) {
-	var (
-		results   []Host
-		mutex     sync.Mutex
-		wg        sync.WaitGroup
-		completed int64
-	)
+// legacyPipeline bildet die Flags ICMP/Fast/Thorough/Ports auf eine Probe-Pipeline ab
+func legacyPipeline(config Config) *Pipeline {
+	mode := "conservative"
+	if config.ICMP {
+		mode = "icmp"
+	} else if config.Fast {
+		mode = "fast"
+	} else if config.Thorough {
+		mode = "thorough"
+	}
+
+	spec := builtinModes[mode]
+	if !config.Fast && !strings.Contains(spec, "dns") {
+		spec += "+dns"
+	}
+	if len(config.Ports) > 0 || len(config.UDPPorts) > 0 {
+		spec += "+ports"
+	}
 
-	semaphore := make(chan struct{}, s.config.Concurrency)
-	total := len(ips)
+	pipeline, err := ParsePipeline(spec, config)
+	if err != nil {
+		// Nur möglich, wenn eingebaute Probes durch fehlerhafte ersetzt wurden
+		return &Pipeline{Spec: spec}
+	}
+	return pipeline
+}
 
-	if !s.config.Quiet {
-		fmt.Printf("🚀 Scanning %d hosts...\n", total)
+// Scan scannt die Ziele mit einem Worker-Pool und liefert Hosts, sobald sie fertig sind.
+// Der Channel wird geschlossen, wenn alle Ziele gescannt wurden oder ctx abgebrochen wird.
+// Es werden nur Online-Hosts geliefert (im Thorough-Modus alle).
+func (s *Scanner) Scan(ctx context.Context, targets []net.IP) (<-chan Host, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+
+	results := make(chan Host)
+	jobs := make(chan net.IP)
+
+	var (
+		wg       sync.WaitGroup
+		progress Progress
+		progMu   sync.Mutex
+	)
+	progress.Total = len(targets)
 	start := time.Now()
 
-	// Pre-allocate results
-	results = make([]Host, 0, total/4) // Estimate 25% response rate
+	workers := s.config.Concurrency
+	if workers > len(targets) {
+		workers = len(targets)
+	}
 
-	for _, ip := range ips {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
-
-		go func(targetIP net.IP) {
+		go func() {
 			defer wg.Done()
 
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			for ip := range jobs {
+				if ctx.Err() != nil {
+					continue // Restliche Jobs verwerfen
+				}
 
-			// Thread counting (optional - nur wenn activeThreads nicht nil)
-			if activeThreads != nil {
-				atomic.AddInt32(activeThreads, 1)
-				defer atomic.AddInt32(activeThreads, -1)
-			}
+				if s.config.Active != nil {
+					atomic.AddInt32(s.config.Active, 1)
+				}
+				host := s.scanHost(ctx, ip)
+				if s.config.Active != nil {
+					atomic.AddInt32(s.config.Active, -1)
+				}
 
-			host := s.scanHost(targetIP)
+				// Abgebrochene Hosts nicht als Ergebnis melden
+				if ctx.Err() != nil {
+					continue
+				}
 
-			// Add all hosts for thorough mode, only online for others
-			if host.Online || s.config.Thorough {
-				mutex.Lock()
-				results = append(results, host)
-				mutex.Unlock()
-			}
+				if s.config.Progress != nil {
+					progMu.Lock()
+					progress.Done++
+					if host.Online {
+						progress.Found++
+					}
+					progress.Elapsed = time.Since(start)
+					s.config.Progress(progress)
+					progMu.Unlock()
+				}
 
-			// Progress tracking (only if not quiet)
-			if !s.config.Quiet {
-				done := atomic.AddInt64(&completed, 1)
-				if done%20 == 0 || done == int64(total) {
-					elapsed := time.Since(start)
-					rate := float64(done) / elapsed.Seconds()
-					online := 0
-					mutex.Lock()
-					for _, h := range results {
-						if h.Online {
-							online++
-						}
+				// Add all hosts for thorough mode, only online for others
+				if host.Online || s.config.Thorough {
+					select {
+					case results <- host:
+					case <-ctx.Done():
 					}
-					mutex.Unlock()
-					fmt.Printf(" %d/%d scanned, %d found (%.0f/sec)\n",
-						done, total, online, rate)
 				}
 			}
-		}(ip)
+		}()
 	}
 
-	wg.Wait()
+	go func() {
+		defer close(jobs)
 
-	elapsed := time.Since(start)
-	onlineCount := 0
-	for _, h := range results {
-		if h.Online {
-			onlineCount++
+		// Sweeps (ARP, SSDP) einmalig vor dem eigentlichen Scan ausführen
+		s.pipeline.Prepare(ctx, targets)
+
+		for _, ip := range targets {
+			if s.config.RateLimit > 0 {
+				select {
+				case <-time.After(s.config.RateLimit):
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- ip:
+			case <-ctx.Done():
+				return
+			}
 		}
-	}
+	}()
 
-	if !s.config.Quiet {
-		fmt.Printf("[OK] Scan completed in %.1fs (%.0f hosts/sec)\n",
-			elapsed.Seconds(), float64(total)/elapsed.Seconds())
-		fmt.Printf("[Summary] Found %d online hosts out of %d scanned\n\n", onlineCount, total)
-	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
 	return results, nil
 }
 
-// scanHost mit modus-angepasster Erkennung
-func (s *Scanner) scanHost(ip net.IP) Host {
-	host := Host{
-		IP:     ip,
-		Online: false,
+// Collect liest alle Hosts aus einem Scan-Channel
+func Collect(results <-chan Host) []Host {
+	hosts := make([]Host, 0)
+	for host := range results {
+		hosts = append(hosts, host)
 	}
+	return hosts
+}
 
-	// Use appropriate ping method
-	if rtt, err := s.pinger.Ping(ip); err == nil {
-		host.Online = true
-		host.RTT = rtt
-
-		// Hostname lookup (skip in fast mode)
-		if !s.config.Fast {
-			if names, err := net.LookupAddr(ip.String()); err == nil && len(names) > 0 {
-				host.Hostname = names[0]
-			}
-		}
-
-		// Port scanning if requested
-		if len(s.config.Ports) > 0 {
-			host.Ports = s.scanPorts(ip, s.config.Ports)
-		}
+// ScanHosts scannt alle Ziele und wartet auf das Ende des Scans (Wrapper um Scan)
+// activeThreads ist optional - wenn nicht nil, werden aktive Scan-Threads gezählt
+func (s *Scanner) ScanHosts(ips []net.IP, activeThreads *int32) ([]Host, error) {
+	scan := *s
+	if activeThreads != nil {
+		scan.config.Active = activeThreads
 	}
 
-	return host
+	results, err := scan.Scan(context.Background(), ips)
+	if err != nil {
+		return nil, err
+	}
+	return Collect(results), nil
 }
 
-// scanPorts führt Port-Scanning durch
-func (s *Scanner) scanPorts(ip net.IP, ports []int) []int {
-	var openPorts []int
-	var mutex sync.Mutex
-	var wg sync.WaitGroup
-
-	for _, port := range ports {
-		wg.Add(1)
-		go func(p int) {
-			defer wg.Done()
-			if conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip.String(), p), s.config.Timeout/2); err == nil {
-				_ = conn.Close() // Ignore close error
-				mutex.Lock()
-				openPorts = append(openPorts, p)
-				mutex.Unlock()
-			}
-		}(port)
+// scanHost führt die Probe-Pipeline für einen Host aus
+func (s *Scanner) scanHost(ctx context.Context, ip net.IP) Host {
+	host := Host{
+		IP:     ip,
+		Online: false,
 	}
 
-	wg.Wait()
-	return openPorts
+	s.pipeline.Run(ctx, &host)
+	return host
 }
 
 // SetGatewayFlags markiert Gateways in der Host-Liste
@@ -207,7 +267,23 @@ func SetGatewayFlags(hosts []Host, network *net.IPNet) {
 		return
 	}
 
+	// IPv6-Default-Gateway nur ermitteln, wenn Hosts IPv6-Adressen haben
+	var gatewayV6 net.IP
+	for _, host := range hosts {
+		if len(host.IPv6) > 0 {
+			gatewayV6 = discovery.GetDefaultGatewayIPv6()
+			break
+		}
+	}
+
 	for i := range hosts {
-		hosts[i].IsGateway = discovery.IsLikelyGateway(hosts[i].IP, network)
+		isGateway := discovery.IsLikelyGateway(hosts[i].IP, network)
+		for _, addr := range hosts[i].IPv6 {
+			if gatewayV6 != nil && addr.IP.Equal(gatewayV6) {
+				isGateway = true
+			}
+		}
+		// Router-Flag aus der IPv6-Neighbor-Tabelle bleibt erhalten
+		hosts[i].IsGateway = isGateway || hosts[i].IsGateway
 	}
 }
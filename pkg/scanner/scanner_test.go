@@ -1,7 +1,9 @@
 package scanner_test
 
 import (
+	"context"
 	"net"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -117,4 +119,84 @@ var _ = Describe("Scanner", func() {
 			})
 		})
 	})
+
+	Describe("Streaming scan", func() {
+		// 203.0.113.0/24 ist TEST-NET-3 (sollte nie online sein)
+		testNet := func(n int) []net.IP {
+			ips := make([]net.IP, 0, n)
+			for i := 1; i <= n; i++ {
+				ips = append(ips, net.IPv4(203, 0, 113, byte(i)))
+			}
+			return ips
+		}
+
+		It("should stream every target in thorough mode and report progress", func() {
+			var updates []scanner.Progress
+			s := scanner.New(scanner.Config{
+				Concurrency: 8,
+				Timeout:     100 * time.Millisecond,
+				Thorough:    true,
+				Fast:        true,
+				Progress: func(p scanner.Progress) {
+					updates = append(updates, p)
+				},
+			})
+
+			results, err := s.Scan(context.Background(), testNet(8))
+			Expect(err).NotTo(HaveOccurred())
+
+			hosts := scanner.Collect(results)
+			Expect(hosts).To(HaveLen(8))
+			Expect(updates).To(HaveLen(8))
+			last := updates[len(updates)-1]
+			Expect(last.Done).To(Equal(8))
+			Expect(last.Total).To(Equal(8))
+			Expect(last.Found).To(BeNumerically("<=", 8))
+		})
+
+		It("should count active workers", func() {
+			var active int32
+			s := scanner.New(scanner.Config{
+				Concurrency: 4,
+				Timeout:     100 * time.Millisecond,
+				Fast:        true,
+				Active:      &active,
+			})
+
+			results, err := s.Scan(context.Background(), testNet(4))
+			Expect(err).NotTo(HaveOccurred())
+			scanner.Collect(results)
+			Expect(atomic.LoadInt32(&active)).To(BeZero())
+		})
+
+		It("should refuse to start with an already cancelled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := scanner.New(scanner.Config{}).Scan(ctx, testNet(1))
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("should close the channel soon after cancellation", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			s := scanner.New(scanner.Config{
+				Concurrency: 2,
+				Timeout:     300 * time.Millisecond,
+				RateLimit:   20 * time.Millisecond,
+				Thorough:    true,
+				Fast:        true,
+			})
+
+			start := time.Now()
+			results, err := s.Scan(ctx, testNet(64))
+			Expect(err).NotTo(HaveOccurred())
+			hosts := scanner.Collect(results)
+
+			// 64 Hosts mit 20ms Rate-Limit würden ohne Abbruch über eine Sekunde dauern
+			Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+			Expect(len(hosts)).To(BeNumerically("<", 64))
+		})
+	})
 })
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TLSPorts sind die Ports, auf denen ohne eigene Port-Liste Zertifikate gesucht werden
+var TLSPorts = []int{443, 465, 636, 853, 993, 995, 8443, 8883}
+
+// Certificate beschreibt ein Zertifikat der Kette eines TLS-Ports
+type Certificate struct {
+	Subject    string    `json:"subject"`         // Common Name, sonst vollständiger DN
+	Names      []string  `json:"names,omitempty"` // Subject Alternative Names (DNS und IP)
+	Issuer     string    `json:"issuer"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	KeyType    string    `json:"key_type"` // "RSA", "ECDSA", "Ed25519"
+	KeyBits    int       `json:"key_bits,omitempty"`
+	SelfSigned bool      `json:"self_signed,omitempty"`
+	SHA256     string    `json:"sha256"` // Fingerprint des DER-Zertifikats
+}
+
+// NewCertificate übernimmt die Angaben eines x509-Zertifikats
+func NewCertificate(cert *x509.Certificate) Certificate {
+	c := Certificate{
+		Subject:   cert.Subject.CommonName,
+		Issuer:    cert.Issuer.CommonName,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		Names:     append([]string{}, cert.DNSNames...),
+	}
+	if c.Subject == "" {
+		c.Subject = cert.Subject.String()
+	}
+	if c.Issuer == "" {
+		c.Issuer = cert.Issuer.String()
+	}
+	for _, ip := range cert.IPAddresses {
+		c.Names = append(c.Names, ip.String())
+	}
+	if len(c.Names) == 0 {
+		c.Names = nil
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		c.KeyType, c.KeyBits = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		c.KeyType, c.KeyBits = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		c.KeyType, c.KeyBits = "Ed25519", 256
+	default:
+		c.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+
+	// Selbst signiert: Aussteller = Inhaber und die eigene Signatur ist gültig
+	c.SelfSigned = bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+
+	sum := sha256.Sum256(cert.Raw)
+	c.SHA256 = hex.EncodeToString(sum[:])
+	return c
+}
+
+// certificateChain übernimmt die Kette eines Handshakes (Server-Zertifikat zuerst)
+func certificateChain(state tls.ConnectionState) []Certificate {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	chain := make([]Certificate, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		chain[i] = NewCertificate(cert)
+	}
+	return chain
+}
+
+// ExpiresIn gibt die restliche Gültigkeit zurück (negativ wenn abgelaufen)
+func (c Certificate) ExpiresIn(now time.Time) time.Duration {
+	return c.NotAfter.Sub(now)
+}
+
+// Expired prüft, ob das Zertifikat abgelaufen oder noch nicht gültig ist
+func (c Certificate) Expired(now time.Time) bool {
+	return now.After(c.NotAfter) || now.Before(c.NotBefore)
+}
+
+// String gibt das Zertifikat kompakt aus, z.B. "nas.local (self-signed, RSA 2048, expires 2026-03-01)"
+func (c Certificate) String() string {
+	issuer := c.Issuer
+	if c.SelfSigned {
+		issuer = "self-signed"
+	}
+	key := c.KeyType
+	if c.KeyBits > 0 {
+		key += " " + strconv.Itoa(c.KeyBits)
+	}
+	return fmt.Sprintf("%s (%s, %s, expires %s)", c.Subject, issuer, key, c.NotAfter.Format("2006-01-02"))
+}
+
+// Certificate gibt das Server-Zertifikat eines TLS-Diensts zurück (nil ohne TLS)
+func (s Service) Certificate() *Certificate {
+	if len(s.Certificates) == 0 {
+		return nil
+	}
+	return &s.Certificates[0]
+}
+
+// Certificates baut eine TLS-Verbindung auf und gibt die Zertifikatskette des
+// Ports zurück (Server-Zertifikat zuerst). Die Kette wird nicht geprüft.
+func (d *Detector) Certificates(ctx context.Context, ip net.IP, port int) ([]Certificate, error) {
+	conn, err := d.dial(ctx, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, tlsConfig(ip))
+	handshakeCtx, cancel := context.WithTimeout(ctx, d.timeout())
+	defer cancel()
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		return nil, err
+	}
+	return certificateChain(tlsConn.ConnectionState()), nil
+}
+
+// WithCertificates trägt die Zertifikate eines Ports in die Dienste ein. Ist der
+// Port noch nicht erkannt, wird er als TLS-Dienst ergänzt (nach Port sortiert).
+func WithCertificates(services []Service, port int, chain []Certificate) []Service {
+	for i := range services {
+		if services[i].Port == port && services[i].Protocol == "tcp" {
+			services[i].TLS = true
+			services[i].Certificates = chain
+			return services
+		}
+	}
+
+	name := WellKnown(port)
+	if tlsName, ok := tlsNames[name]; ok {
+		name = tlsName
+	}
+	if name == "" {
+		name = "tls"
+	}
+	services = append(services, Service{Port: port, Protocol: "tcp", Name: name, TLS: true, Certificates: chain})
+	sort.Slice(services, func(i, j int) bool { return services[i].Port < services[j].Port })
+	return services
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Identify erkennt einen Dienst an seiner Begrüßung (SSH, FTP, SMTP, POP3, IMAP,
+// HTTP, MySQL, VNC, Telnet). Port und Protokoll bleiben leer; ist der Dienst
+// mehrdeutig (z.B. "220 ready"), bleibt auch der Name leer.
+func Identify(response []byte) (Service, bool) {
+	return identify("", response)
+}
+
+// identify wertet eine Antwort aus. Binäre Antworten (RDP, SMB, MQTT, Redis)
+// werden nur für die passende Probe ausgewertet, Begrüßungen immer.
+func identify(probe string, data []byte) (Service, bool) {
+	if len(data) == 0 {
+		return Service{}, false
+	}
+
+	switch probe {
+	case "redis":
+		return identifyRedis(data)
+	case "rdp":
+		return identifyRDP(data)
+	case "smb":
+		return identifySMB(data)
+	case "mqtt":
+		return identifyMQTT(data)
+	}
+
+	for _, match := range greetings {
+		if svc, ok := match(data); ok {
+			return svc, true
+		}
+	}
+	return Service{}, false
+}
+
+// greetings erkennen Dienste an der ersten Antwort
+var greetings = []func([]byte) (Service, bool){
+	identifySSH,
+	identifyHTTP,
+	identifyMail,
+	identifyVNC,
+	identifyMySQL,
+	identifyTelnet,
+}
+
+// identifySSH: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1"
+func identifySSH(data []byte) (Service, bool) {
+	if !bytes.HasPrefix(data, []byte("SSH-")) {
+		return Service{}, false
+	}
+	line := firstLine(data)
+	svc := Service{Name: "ssh", Banner: cleanBanner(data)}
+	// Protokoll-Version überspringen: "SSH-2.0-" bzw. "SSH-1.99-"
+	parts := strings.SplitN(line, "-", 3)
+	if len(parts) == 3 {
+		software := strings.Fields(parts[2])
+		if len(software) > 0 {
+			svc.Product, svc.Version = splitProduct(software[0], "_")
+		}
+	}
+	return svc, true
+}
+
+// identifyHTTP: Statuszeile "HTTP/1.x", Produkt aus dem Server-Header
+func identifyHTTP(data []byte) (Service, bool) {
+	if !bytes.HasPrefix(data, []byte("HTTP/1.")) && !bytes.HasPrefix(data, []byte("HTTP/2")) {
+		return Service{}, false
+	}
+	svc := Service{Name: "http", Banner: cleanBanner(data)}
+	if server := strings.Fields(httpHeader(data, "Server")); len(server) > 0 {
+		svc.Product, svc.Version = splitProduct(server[0], "/")
+	}
+	return svc, true
+}
+
+// httpHeader gibt den Wert eines Headers einer HTTP-, SSDP- oder SIP-Antwort zurück
+func httpHeader(data []byte, header string) string {
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break // Ende der Header
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), header) {
+			return firstLine([]byte(value))
+		}
+	}
+	return ""
+}
+
+// Produkt-Erkennung in FTP-, SMTP-, POP3- und IMAP-Begrüßungen
+var mailProducts = []struct {
+	name    string
+	product string
+	pattern *regexp.Regexp // Erste Gruppe = Version (optional)
+}{
+	{"ftp", "vsftpd", regexp.MustCompile(`(?i)\(vsFTPd ([\d.]+)\)`)},
+	{"ftp", "ProFTPD", regexp.MustCompile(`ProFTPD ?([\d.]+[a-z]*)?`)},
+	{"ftp", "Pure-FTPd", regexp.MustCompile(`Pure-FTPd()`)},
+	{"ftp", "FileZilla Server", regexp.MustCompile(`FileZilla Server(?: version)? ?([\d.]+[a-z]*)?`)},
+	{"ftp", "Microsoft ftpd", regexp.MustCompile(`Microsoft FTP Service()`)},
+	{"smtp", "Postfix", regexp.MustCompile(`ESMTP Postfix()`)},
+	{"smtp", "Exim", regexp.MustCompile(`Exim ([\d.]+)`)},
+	{"smtp", "Sendmail", regexp.MustCompile(`Sendmail ([\d.]+[\w.]*)`)},
+	{"smtp", "Microsoft ESMTP", regexp.MustCompile(`Microsoft ESMTP MAIL Service()`)},
+	{"", "Dovecot", regexp.MustCompile(`Dovecot()`)},
+	{"", "Courier", regexp.MustCompile(`Courier-(?:IMAP|POP3)()`)},
+	{"", "Cyrus", regexp.MustCompile(`Cyrus (?:IMAP|POP3)[^ ]* v?([\d.]+)?`)},
+}
+
+// identifyMail: "220 ..." (FTP/SMTP), "+OK ..." (POP3), "* OK ..." (IMAP)
+func identifyMail(data []byte) (Service, bool) {
+	line := firstLine(data)
+	svc := Service{Banner: cleanBanner(data)}
+	switch {
+	case strings.HasPrefix(line, "220"):
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "ftp"):
+			svc.Name = "ftp"
+		case strings.Contains(lower, "smtp") || strings.Contains(lower, "mail"):
+			svc.Name = "smtp"
+		}
+	case strings.HasPrefix(line, "+OK"):
+		svc.Name = "pop3"
+	case strings.HasPrefix(line, "* OK"):
+		svc.Name = "imap"
+	default:
+		return Service{}, false
+	}
+
+	for _, known := range mailProducts {
+		if known.name != "" && svc.Name != "" && known.name != svc.Name {
+			continue
+		}
+		if m := known.pattern.FindStringSubmatch(line); m != nil {
+			if svc.Name == "" {
+				svc.Name = known.name
+			}
+			svc.Product = known.product
+			if len(m) > 1 {
+				svc.Version = m[1]
+			}
+			break
+		}
+	}
+	return svc, true
+}
+
+// identifyVNC: "RFB 003.008\n"
+func identifyVNC(data []byte) (Service, bool) {
+	var major, minor int
+	if _, err := fmt.Sscanf(string(data), "RFB %03d.%03d", &major, &minor); err != nil {
+		return Service{}, false
+	}
+	return Service{Name: "vnc", Version: fmt.Sprintf("%d.%d", major, minor), Banner: cleanBanner(data)}, true
+}
+
+// identifyMySQL: Handshake-Paket (Protokoll 10 mit Server-Version) oder
+// Fehlerpaket (z.B. "Host is not allowed to connect")
+func identifyMySQL(data []byte) (Service, bool) {
+	if len(data) < 5 {
+		return Service{}, false
+	}
+	length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+	if data[3] != 0 || length == 0 || length > 1024 {
+		return Service{}, false
+	}
+
+	switch data[4] {
+	case 0x0a:
+		end := bytes.IndexByte(data[5:], 0)
+		if end <= 0 {
+			return Service{}, false
+		}
+		version := string(data[5 : 5+end])
+		svc := Service{Name: "mysql", Product: "MySQL", Version: version, Banner: version}
+		// MariaDB meldet sich als "5.5.5-10.6.12-MariaDB-1:10.6.12+maria~ubu2004"
+		if i := strings.Index(version, "-MariaDB"); i > 0 {
+			svc.Product = "MariaDB"
+			svc.Version = strings.TrimPrefix(version[:i], "5.5.5-")
+		} else if i := strings.IndexByte(version, '-'); i > 0 {
+			svc.Version = version[:i]
+		}
+		return svc, true
+	case 0xff:
+		if len(data) < 7 {
+			return Service{}, false
+		}
+		return Service{Name: "mysql", Banner: cleanBanner(data[7:])}, true
+	}
+	return Service{}, false
+}
+
+// identifyTelnet: Option-Verhandlung (IAC WILL/WONT/DO/DONT)
+func identifyTelnet(data []byte) (Service, bool) {
+	if len(data) < 3 || data[0] != 0xff || data[1] < 0xfb {
+		return Service{}, false
+	}
+	return Service{Name: "telnet"}, true
+}
+
+// identifyRedis: Antwort auf PING bzw. INFO server
+func identifyRedis(data []byte) (Service, bool) {
+	line := firstLine(data)
+	svc := Service{Name: "redis", Product: "Redis", Banner: cleanBanner(data)}
+	switch {
+	case line == "+PONG":
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-ERR"), strings.HasPrefix(line, "-DENIED"):
+	case strings.HasPrefix(line, "$"):
+		for _, infoLine := range strings.Split(string(data), "\n") {
+			if version, ok := strings.CutPrefix(strings.TrimSpace(infoLine), "redis_version:"); ok {
+				svc.Version = version
+				return svc, true
+			}
+		}
+		return Service{}, false
+	default:
+		return Service{}, false
+	}
+	return svc, true
+}
+
+// identifyRDP: X.224 Connection Confirm auf den Connection Request
+func identifyRDP(data []byte) (Service, bool) {
+	if len(data) < 6 || data[0] != 0x03 || data[1] != 0x00 || data[5]&0xf0 != 0xd0 {
+		return Service{}, false
+	}
+	return Service{Name: "rdp"}, true
+}
+
+// smbDialects sind die Namen der SMB2-Dialekte
+var smbDialects = map[uint16]string{
+	0x0202: "2.0.2",
+	0x0210: "2.1",
+	0x0300: "3.0",
+	0x0302: "3.0.2",
+	0x0311: "3.1.1",
+}
+
+// identifySMB: SMB2 NEGOTIATE Response (gewählter Dialekt) oder SMB1-Antwort
+func identifySMB(data []byte) (Service, bool) {
+	if len(data) < 8 {
+		return Service{}, false
+	}
+	svc := Service{Name: "smb", Product: "SMB"}
+	switch string(data[4:8]) {
+	case "\xfeSMB":
+		// NetBIOS (4) + SMB2-Header (64) + StructureSize (2) + SecurityMode (2)
+		if len(data) >= 74 {
+			dialect := binary.LittleEndian.Uint16(data[72:74])
+			svc.Version = smbDialects[dialect]
+		}
+		return svc, true
+	case "\xffSMB":
+		svc.Version = "1"
+		return svc, true
+	}
+	return Service{}, false
+}
+
+// identifyMQTT: CONNACK auf CONNECT
+func identifyMQTT(data []byte) (Service, bool) {
+	if len(data) < 4 || data[0] != 0x20 || data[1] != 0x02 {
+		return Service{}, false
+	}
+	return Service{Name: "mqtt", Banner: fmt.Sprintf("CONNACK rc=%d", data[3])}, true
+}
+
+// splitProduct trennt "OpenSSH_8.9p1" bzw. "nginx/1.18.0" in Produkt und Version
+func splitProduct(software, sep string) (string, string) {
+	product, version, _ := strings.Cut(software, sep)
+	return product, version
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// probe sendet eine protokollspezifische Anfrage und wertet die Antwort aus
+type probe struct {
+	name string
+	run  func(ctx context.Context, d *Detector, conn net.Conn, ip net.IP) (Service, bool)
+}
+
+// Payloads der binären Probes
+var (
+	// RDP: X.224 Connection Request mit RDP Negotiation Request (TLS | CredSSP)
+	rdpRequest = []byte{
+		0x03, 0x00, 0x00, 0x13, 0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00,
+	}
+
+	// MQTT: CONNECT (v3.1.1, Clean Session, Keep-Alive 60s, Client-ID "netspy")
+	mqttConnect = []byte{
+		0x10, 0x12, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x3c,
+		0x00, 0x06, 'n', 'e', 't', 's', 'p', 'y',
+	}
+
+	smbNegotiate = buildSMBNegotiate()
+
+	// NetBIOS Session Request an "*SMBSERVER" - auf Port 139 Pflicht vor dem ersten SMB-Paket
+	netbiosSessionRequest = buildNetBIOSSessionRequest("*SMBSERVER", "NETSPY")
+)
+
+// buildSMBNegotiate erzeugt einen SMB2 NEGOTIATE Request (Dialekte 2.0.2 bis 3.0.2)
+// mit NetBIOS-Session-Header
+func buildSMBNegotiate() []byte {
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+
+	header := make([]byte, 64)
+	copy(header, "\xfeSMB")
+	header[4] = 64 // StructureSize
+	// Command 0 = NEGOTIATE, alle übrigen Felder 0
+
+	body := make([]byte, 36)
+	body[0] = 36                         // StructureSize
+	body[2] = byte(len(dialects))        // DialectCount
+	body[4] = 0x01                       // SecurityMode: Signing enabled
+	copy(body[12:28], "netspy-probe!!!") // ClientGuid
+	for _, dialect := range dialects {
+		body = append(body, byte(dialect), byte(dialect>>8))
+	}
+
+	payload := append(header, body...)
+	netbios := []byte{0x00, byte(len(payload) >> 16), byte(len(payload) >> 8), byte(len(payload))}
+	return append(netbios, payload...)
+}
+
+// buildNetBIOSSessionRequest erzeugt einen NetBIOS Session Request (RFC 1002) mit
+// aufgerufenem Namen (Suffix 0x20 = Server) und rufendem Namen (Suffix 0x00 = Workstation)
+func buildNetBIOSSessionRequest(called, calling string) []byte {
+	request := []byte{0x81, 0x00, 0x00, 0x44} // Typ, Flags, Länge (2 × 34 Bytes)
+	request = append(request, encodeNetBIOSName(called, 0x20)...)
+	return append(request, encodeNetBIOSName(calling, 0x00)...)
+}
+
+// encodeNetBIOSName kodiert einen Namen (mit Leerzeichen auf 15 Zeichen aufgefüllt, dazu
+// das Suffix) in First-Level-Kodierung: jedes Nibble als 'A'-'P', mit Längen- und Endbyte
+func encodeNetBIOSName(name string, suffix byte) []byte {
+	raw := append([]byte(fmt.Sprintf("%-15.15s", name)), suffix)
+	encoded := []byte{32}
+	for _, c := range raw {
+		encoded = append(encoded, 'A'+c>>4, 'A'+c&0x0f)
+	}
+	return append(encoded, 0)
+}
+
+// sendProbe sendet eine Anfrage und liest die Antwort
+func sendProbe(d *Detector, conn net.Conn, request []byte) []byte {
+	_ = conn.SetWriteDeadline(time.Now().Add(d.timeout()))
+	if _, err := conn.Write(request); err != nil {
+		return nil
+	}
+	return readResponse(conn, d.timeout())
+}
+
+// binaryProbe sendet eine feste Anfrage und wertet die Antwort für diese Probe aus
+func binaryProbe(name string, request []byte) probe {
+	return probe{name: name, run: func(_ context.Context, d *Detector, conn net.Conn, _ net.IP) (Service, bool) {
+		return identify(name, sendProbe(d, conn, request))
+	}}
+}
+
+var (
+	httpProbe = probe{name: "http", run: func(_ context.Context, d *Detector, conn net.Conn, ip net.IP) (Service, bool) {
+		response := sendProbe(d, conn, httpRequest(ip))
+		if plainHTTPOnTLS(response) {
+			return Service{}, false // Danach folgt die TLS-Probe
+		}
+		return identify("http", response)
+	}}
+
+	tlsProbe = probe{name: "tls", run: runTLS}
+
+	redisProbe = probe{name: "redis", run: func(_ context.Context, d *Detector, conn net.Conn, _ net.IP) (Service, bool) {
+		svc, ok := identify("redis", sendProbe(d, conn, []byte("PING\r\n")))
+		if ok && svc.Version == "" && svc.Banner == "+PONG" {
+			// Ohne Passwort verrät INFO die Version
+			if info, ok := identify("redis", sendProbe(d, conn, []byte("INFO server\r\n"))); ok && info.Version != "" {
+				svc.Version = info.Version
+			}
+		}
+		return svc, ok
+	}}
+
+	rdpProbe  = binaryProbe("rdp", rdpRequest)
+	smbProbe  = binaryProbe("smb", smbNegotiate)
+	mqttProbe = binaryProbe("mqtt", mqttConnect)
+
+	// SMB über NetBIOS (Port 139): erst die Session, nach positiver Antwort (0x82) das Negotiate
+	netbiosSMBProbe = probe{name: "netbios-ssn", run: func(_ context.Context, d *Detector, conn net.Conn, _ net.IP) (Service, bool) {
+		response := sendProbe(d, conn, netbiosSessionRequest)
+		if len(response) < 4 || response[0] != 0x82 {
+			return Service{}, false // 0x83 = Negative Session Response
+		}
+		return identify("smb", sendProbe(d, conn, smbNegotiate))
+	}}
+)
+
+// portProbes sind die Probes, die für einen Port zuerst versucht werden
+var portProbes = map[int]probe{
+	80:   httpProbe,
+	8000: httpProbe,
+	8008: httpProbe,
+	8080: httpProbe,
+	8888: httpProbe,
+	443:  tlsProbe,
+	465:  tlsProbe,
+	636:  tlsProbe,
+	993:  tlsProbe,
+	995:  tlsProbe,
+	8443: tlsProbe,
+	8883: tlsProbe,
+	3389: rdpProbe,
+	139:  netbiosSMBProbe,
+	445:  smbProbe,
+	1883: mqttProbe,
+	6379: redisProbe,
+}
+
+// probeNames ordnet die Namen aus Detector.Hints den Probes zu
+var probeNames = map[string]probe{
+	"http":        httpProbe,
+	"tls":         tlsProbe,
+	"redis":       redisProbe,
+	"rdp":         rdpProbe,
+	"smb":         smbProbe,
+	"netbios-ssn": netbiosSMBProbe,
+	"mqtt":        mqttProbe,
+}
+
+// ProbeNames gibt die Namen der TCP- und UDP-Probes zurück, die in Detector.Hints
+// erlaubt sind
+func ProbeNames() []string {
+	names := make([]string, 0, len(probeNames)+len(udpProbeNames))
+	for name := range probeNames {
+		names = append(names, name)
+	}
+	for name := range udpProbeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// probesFor gibt die Probes für einen Port in Reihenfolge zurück: passend zum
+// Port (Hints vor den Standard-Ports), danach HTTP und TLS als häufigste
+// Protokolle ohne Begrüßung
+func (d *Detector) probesFor(port int) []probe {
+	var probes []probe
+	hinted, ok := portProbes[port]
+	if d != nil {
+		if p, found := probeNames[d.Hints[port]]; found {
+			hinted, ok = p, true
+		}
+	}
+	if ok {
+		probes = append(probes, hinted)
+	}
+	for _, p := range []probe{httpProbe, tlsProbe} {
+		if !ok || p.name != hinted.name {
+			probes = append(probes, p)
+		}
+	}
+	return probes
+}
+
+func httpRequest(ip net.IP) []byte {
+	return []byte(fmt.Sprintf("GET / HTTP/1.0\r\nHost: %s\r\nUser-Agent: netspy\r\n\r\n", ip))
+}
+
+// plainHTTPOnTLS erkennt die Fehlerseite von TLS-Servern auf Klartext-HTTP
+// (nginx: "The plain HTTP request was sent to HTTPS port", Go: "Client sent an
+// HTTP request to an HTTPS server")
+func plainHTTPOnTLS(response []byte) bool {
+	return bytes.HasPrefix(response, []byte("HTTP/1.")) &&
+		bytes.Contains(response[:min(len(response), 16)], []byte(" 400")) &&
+		bytes.Contains(bytes.ToLower(response), []byte("https"))
+}
+
+// tlsNames sind die TLS-Varianten von Klartext-Protokollen
+var tlsNames = map[string]string{
+	"http": "https",
+	"imap": "imaps",
+	"pop3": "pop3s",
+	"smtp": "smtps",
+	"ftp":  "ftps",
+	"mqtt": "mqtts",
+	"ldap": "ldaps",
+}
+
+// runTLS führt einen TLS-Handshake durch und erkennt das Protokoll innerhalb
+// (Begrüßung wie bei IMAPS/POP3S, sonst HTTP)
+func runTLS(ctx context.Context, d *Detector, conn net.Conn, ip net.IP) (Service, bool) {
+	tlsConn := tls.Client(conn, tlsConfig(ip))
+	handshakeCtx, cancel := context.WithTimeout(ctx, d.timeout())
+	defer cancel()
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		return Service{}, false
+	}
+
+	chain := certificateChain(tlsConn.ConnectionState())
+	svc := Service{Name: "tls", TLS: true}
+	inner, ok := identify("", readResponse(tlsConn, d.greetingWait()))
+	if !ok {
+		inner, ok = identify("http", sendProbe(d, tlsConn, httpRequest(ip)))
+	}
+	if ok {
+		svc = inner
+		svc.TLS = true
+		if name, ok := tlsNames[svc.Name]; ok {
+			svc.Name = name
+		}
+	}
+	svc.Certificates = chain
+	return svc, true
+}
+
+// tlsConfig akzeptiert jedes Zertifikat - es wird nur inventarisiert, nicht geprüft
+func tlsConfig(ip net.IP) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402 - selbst signierte Zertifikate sind im LAN die Regel
+		ServerName:         ip.String(),
+	}
+}
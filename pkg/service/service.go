@@ -0,0 +1,277 @@
+// Package service erkennt Dienste auf offenen Ports (Name, Produkt, Version).
+//
+// Pro Port wird zuerst auf ein Begrüßungs-Banner gewartet (SSH, FTP, SMTP, POP3,
+// IMAP, MySQL, VNC, ...). Schweigt der Dienst, werden protokollspezifische Probes
+// gesendet - passend zum Port zuerst (z.B. RDP auf 3389, SMB auf 445), danach
+// HTTP und TLS. Die Antworten werden mit Identify ausgewertet.
+//
+// UDP-Ports (DetectUDP) bekommen eine gültige Anfrage ihres Protokolls (DNS, NTP,
+// SNMP, SIP, SSDP, ...); Antwort, ICMP-Fehler oder Schweigen ergeben den Zustand.
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service beschreibt den erkannten Dienst eines Ports
+type Service struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`          // "tcp" oder "udp"
+	Name     string `json:"name"`              // z.B. "ssh", "http", "smb" (leer = unbekannt)
+	Product  string `json:"product,omitempty"` // z.B. "OpenSSH", "nginx"
+	Version  string `json:"version,omitempty"` // z.B. "8.9p1"
+	TLS      bool   `json:"tls,omitempty"`     // Dienst spricht TLS
+	Banner   string `json:"banner,omitempty"`  // Vollständige Antwort (Steuerzeichen als '.')
+
+	Certificates []Certificate `json:"certificates,omitempty"` // Zertifikatskette bei TLS (Server-Zertifikat zuerst)
+}
+
+// Summary gibt Produkt und Version zurück, ersatzweise die erste Zeile des Banners
+// (mit " …", wenn weitere Zeilen folgen)
+func (s Service) Summary() string {
+	switch {
+	case s.Product != "" && s.Version != "":
+		return s.Product + " " + s.Version
+	case s.Product != "":
+		return s.Product
+	case s.BannerLine() != s.Banner:
+		return s.BannerLine() + " …"
+	default:
+		return s.Banner
+	}
+}
+
+// BannerLine gibt die erste Zeile des Banners zurück
+func (s Service) BannerLine() string {
+	line, _, _ := strings.Cut(s.Banner, "\n")
+	return line
+}
+
+// String gibt den Dienst kompakt aus, z.B. "22/ssh OpenSSH 8.9p1" bzw. bei UDP
+// in der Port-Schreibweise von --ports "u:53/dns dnsmasq 2.89"
+func (s Service) String() string {
+	name := s.Name
+	if name == "" {
+		name = "unknown"
+	}
+	result := fmt.Sprintf("%d/%s", s.Port, name)
+	if s.Protocol == "udp" {
+		result = "u:" + result
+	}
+	if summary := s.Summary(); summary != "" {
+		result += " " + summary
+	}
+	return result
+}
+
+// Defaults für den Detector
+const (
+	DefaultTimeout      = 3 * time.Second
+	DefaultGreetingWait = 1500 * time.Millisecond
+	maxResponse         = 4096
+)
+
+// Detector erkennt Dienste auf offenen TCP-Ports
+type Detector struct {
+	Timeout      time.Duration  // Verbindungsaufbau und Antwort pro Probe (0 = DefaultTimeout)
+	GreetingWait time.Duration  // Wartezeit auf ein Begrüßungs-Banner (0 = DefaultGreetingWait)
+	Hints        map[int]string // Zusätzliche Zuordnung Port → Probe (z.B. 6380: "redis", 1053: "dns", siehe ProbeNames)
+}
+
+func (d *Detector) timeout() time.Duration {
+	if d == nil || d.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return d.Timeout
+}
+
+func (d *Detector) greetingWait() time.Duration {
+	if d == nil || d.GreetingWait <= 0 {
+		return DefaultGreetingWait
+	}
+	return d.GreetingWait
+}
+
+// Detect erkennt den Dienst auf einem Port. Ist nichts erkennbar, wird der
+// übliche Dienst des Ports eingetragen (ohne Produkt und Version).
+func (d *Detector) Detect(ctx context.Context, ip net.IP, port int) Service {
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	fallback := Service{Port: port, Protocol: "tcp", Name: WellKnown(port)}
+
+	// 1. Begrüßung abwarten (schweigt der Dienst, nutzt die erste Probe dieselbe Verbindung)
+	conn, err := d.dial(ctx, addr)
+	if err != nil {
+		return fallback
+	}
+	greeting := readResponse(conn, d.greetingWait())
+	if len(greeting) > 0 {
+		conn.Close()
+		return d.finish(port, greeting)
+	}
+
+	// 2. Probes senden: zuerst passend zum Port, dann HTTP und TLS
+	for _, p := range d.probesFor(port) {
+		if ctx.Err() != nil {
+			break
+		}
+		if conn == nil {
+			if conn, err = d.dial(ctx, addr); err != nil {
+				break
+			}
+		}
+		if svc, ok := p.run(ctx, d, conn, ip); ok {
+			conn.Close()
+			svc.Port = port
+			svc.Protocol = "tcp"
+			if svc.Name == "" {
+				svc.Name = fallback.Name
+			}
+			return svc
+		}
+		conn.Close()
+		conn = nil
+	}
+	if conn != nil {
+		conn.Close()
+	}
+
+	return fallback
+}
+
+// finish wertet eine Antwort aus; unbekannte Antworten behalten das Banner
+func (d *Detector) finish(port int, response []byte) Service {
+	svc, ok := Identify(response)
+	if !ok {
+		svc = Service{Banner: cleanBanner(response)}
+	}
+	if svc.Name == "" {
+		svc.Name = WellKnown(port)
+	}
+	svc.Port = port
+	svc.Protocol = "tcp"
+	return svc
+}
+
+// DetectAll erkennt die Dienste mehrerer Ports parallel (Ergebnis nach Port sortiert)
+func (d *Detector) DetectAll(ctx context.Context, ip net.IP, ports []int) []Service {
+	services := make([]Service, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			services[i] = d.Detect(ctx, ip, port)
+		}(i, port)
+	}
+	wg.Wait()
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Port < services[j].Port })
+	return services
+}
+
+func (d *Detector) dial(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: d.timeout()}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// readResponse liest, was innerhalb der Wartezeit ankommt. Nach den ersten Daten
+// wird nur noch kurz auf weitere Pakete gewartet (mehrzeilige Banner).
+func readResponse(conn net.Conn, wait time.Duration) []byte {
+	buf := make([]byte, maxResponse)
+	n := 0
+	deadline := time.Now().Add(wait)
+	for n < len(buf) {
+		_ = conn.SetReadDeadline(deadline)
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil {
+			break
+		}
+		if m > 0 {
+			deadline = time.Now().Add(100 * time.Millisecond)
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	return buf[:n]
+}
+
+// cleanBanner gibt eine Antwort vollständig und druckbar zurück: Zeilenumbrüche werden
+// zu "\n", andere Steuerzeichen zu '.'. Gekürzt wird erst bei der Anzeige (siehe Summary).
+func cleanBanner(data []byte) string {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c == '\r':
+			c = '\n'
+		case c == '\n':
+		case c < 0x20 || c > 0x7e:
+			c = '.'
+		}
+		b.WriteByte(c)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// firstLine gibt die erste Zeile einer Antwort druckbar zurück (zum Auswerten)
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(cleanBanner(data), "\n")
+	return strings.TrimSpace(line)
+}
+
+// CommonPorts sind die Ports, die ohne eigene Port-Liste auf Dienste geprüft werden
+var CommonPorts = []int{
+	21, 22, 23, 25, 53, 80, 110, 139, 143, 443, 445, 465, 587, 993, 995,
+	1883, 3306, 3389, 5432, 5900, 6379, 8080, 8443,
+}
+
+// wellKnown sind die üblichen Dienste bekannter Ports
+var wellKnown = map[int]string{
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	53:    "dns",
+	80:    "http",
+	110:   "pop3",
+	135:   "msrpc",
+	139:   "netbios-ssn",
+	143:   "imap",
+	389:   "ldap",
+	443:   "https",
+	445:   "smb",
+	465:   "smtps",
+	515:   "printer",
+	548:   "afp",
+	587:   "submission",
+	631:   "ipp",
+	636:   "ldaps",
+	993:   "imaps",
+	995:   "pop3s",
+	1433:  "mssql",
+	1883:  "mqtt",
+	3306:  "mysql",
+	3389:  "rdp",
+	5432:  "postgres",
+	5672:  "amqp",
+	5900:  "vnc",
+	6379:  "redis",
+	8080:  "http-alt",
+	8443:  "https-alt",
+	8883:  "mqtts",
+	9100:  "jetdirect",
+	27017: "mongodb",
+}
+
+// WellKnown gibt den üblichen Dienst eines Ports zurück (leer wenn unbekannt)
+func WellKnown(port int) string {
+	return wellKnown[port]
+}
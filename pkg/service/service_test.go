@@ -0,0 +1,473 @@
+package service_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/service"
+)
+
+var _ = Describe("Service", func() {
+	var detector *service.Detector
+	localhost := net.ParseIP("127.0.0.1")
+
+	BeforeEach(func() {
+		detector = &service.Detector{Timeout: 500 * time.Millisecond, GreetingWait: 200 * time.Millisecond}
+	})
+
+	// listen startet einen lokalen TCP-Server, der jede Verbindung mit handle bedient
+	listen := func(handle func(conn net.Conn)) int {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(ln.Close)
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer conn.Close()
+					handle(conn)
+				}()
+			}
+		}()
+		return ln.Addr().(*net.TCPAddr).Port
+	}
+
+	// greet sendet eine Begrüßung und wartet, bis der Client trennt
+	greet := func(banner string) func(net.Conn) {
+		return func(conn net.Conn) {
+			_, _ = conn.Write([]byte(banner))
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}
+
+	// respond beantwortet die erste Anfrage mit einer festen Antwort
+	respond := func(response []byte) func(net.Conn) {
+		return func(conn net.Conn) {
+			buf := make([]byte, 4096)
+			if _, err := conn.Read(buf); err != nil {
+				return
+			}
+			_, _ = conn.Write(response)
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}
+
+	port := func(server *httptest.Server) int {
+		return server.Listener.Addr().(*net.TCPAddr).Port
+	}
+
+	Describe("Greetings", func() {
+		It("should detect OpenSSH", func() {
+			p := listen(greet("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6\r\n"))
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(svc.Port).To(Equal(p))
+			Expect(svc.Protocol).To(Equal("tcp"))
+			Expect(svc.Name).To(Equal("ssh"))
+			Expect(svc.Product).To(Equal("OpenSSH"))
+			Expect(svc.Version).To(Equal("8.9p1"))
+			Expect(svc.Banner).To(Equal("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"))
+		})
+
+		It("should detect FTP and SMTP products from 220 greetings", func() {
+			ftp := detector.Detect(context.Background(), localhost, listen(greet("220 (vsFTPd 3.0.5)\r\n")))
+			Expect(ftp.Name).To(Equal("ftp"))
+			Expect(ftp.Product).To(Equal("vsftpd"))
+			Expect(ftp.Version).To(Equal("3.0.5"))
+
+			smtp := detector.Detect(context.Background(), localhost, listen(greet("220 mail.example.com ESMTP Postfix (Ubuntu)\r\n")))
+			Expect(smtp.Name).To(Equal("smtp"))
+			Expect(smtp.Product).To(Equal("Postfix"))
+
+			exim := detector.Detect(context.Background(), localhost, listen(greet("220 mx.example.com ESMTP Exim 4.96 Mon, 01 Jan 2024\r\n")))
+			Expect(exim.Product).To(Equal("Exim"))
+			Expect(exim.Version).To(Equal("4.96"))
+		})
+
+		It("should detect POP3 and IMAP", func() {
+			pop3 := detector.Detect(context.Background(), localhost, listen(greet("+OK Dovecot ready.\r\n")))
+			Expect(pop3.Name).To(Equal("pop3"))
+			Expect(pop3.Product).To(Equal("Dovecot"))
+
+			imap := detector.Detect(context.Background(), localhost, listen(greet("* OK [CAPABILITY IMAP4rev1] Dovecot ready.\r\n")))
+			Expect(imap.Name).To(Equal("imap"))
+			Expect(imap.Product).To(Equal("Dovecot"))
+		})
+
+		It("should detect the MySQL handshake", func() {
+			payload := append([]byte{0x0a}, []byte("5.5.5-10.6.12-MariaDB-1:10.6.12+maria~ubu2004\x00")...)
+			payload = append(payload, make([]byte, 30)...)
+			packet := append([]byte{byte(len(payload)), 0, 0, 0}, payload...)
+
+			svc := detector.Detect(context.Background(), localhost, listen(greet(string(packet))))
+			Expect(svc.Name).To(Equal("mysql"))
+			Expect(svc.Product).To(Equal("MariaDB"))
+			Expect(svc.Version).To(Equal("10.6.12"))
+		})
+
+		It("should keep the banner of unknown greetings", func() {
+			svc := detector.Detect(context.Background(), localhost, listen(greet("hello\x01world\r\nsecond line\r\n")))
+			Expect(svc.Name).To(BeEmpty())
+			Expect(svc.Banner).To(Equal("hello.world\nsecond line"))
+			Expect(svc.Summary()).To(Equal("hello.world …"))
+		})
+
+		It("should keep long banners complete", func() {
+			greeting := "220-FTP " + strings.Repeat("x", 120) + "\r\n220 ready\r\n"
+			svc := detector.Detect(context.Background(), localhost, listen(greet(greeting)))
+			Expect(svc.Name).To(Equal("ftp"))
+			Expect(svc.Banner).To(Equal("220-FTP " + strings.Repeat("x", 120) + "\n220 ready"))
+		})
+	})
+
+	Describe("Probes", func() {
+		It("should detect HTTP servers by the Server header", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", "nginx/1.18.0 (Ubuntu)")
+			}))
+			DeferCleanup(server.Close)
+
+			svc := detector.Detect(context.Background(), localhost, port(server))
+			Expect(svc.Name).To(Equal("http"))
+			Expect(svc.Product).To(Equal("nginx"))
+			Expect(svc.Version).To(Equal("1.18.0"))
+			Expect(svc.TLS).To(BeFalse())
+		})
+
+		It("should detect HTTPS behind the TLS handshake", func() {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", "Caddy")
+			}))
+			DeferCleanup(server.Close)
+
+			svc := detector.Detect(context.Background(), localhost, port(server))
+			Expect(svc.Name).To(Equal("https"))
+			Expect(svc.Product).To(Equal("Caddy"))
+			Expect(svc.TLS).To(BeTrue())
+		})
+
+		It("should ask Redis for its version", func() {
+			p := listen(func(conn net.Conn) {
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.TrimSpace(line) {
+					case "PING":
+						_, _ = conn.Write([]byte("+PONG\r\n"))
+					case "INFO server":
+						info := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+						_, _ = conn.Write([]byte("$" + strconv.Itoa(len(info)) + "\r\n" + info + "\r\n"))
+					}
+				}
+			})
+			detector.Hints = map[int]string{p: "redis"}
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(svc.Name).To(Equal("redis"))
+			Expect(svc.Product).To(Equal("Redis"))
+			Expect(svc.Version).To(Equal("7.2.4"))
+		})
+
+		It("should detect Redis requiring authentication", func() {
+			p := listen(respond([]byte("-NOAUTH Authentication required.\r\n")))
+			detector.Hints = map[int]string{p: "redis"}
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(svc.Name).To(Equal("redis"))
+			Expect(svc.Version).To(BeEmpty())
+		})
+
+		It("should detect RDP by the X.224 connection confirm", func() {
+			p := listen(respond([]byte{0x03, 0x00, 0x00, 0x13, 0x0e, 0xd0, 0x00, 0x00, 0x12, 0x34, 0x00, 0x02, 0x1f, 0x08, 0x00, 0x02, 0x00, 0x00, 0x00}))
+			detector.Hints = map[int]string{p: "rdp"}
+
+			Expect(detector.Detect(context.Background(), localhost, p).Name).To(Equal("rdp"))
+		})
+
+		It("should read the negotiated SMB dialect", func() {
+			requests := make(chan []byte, 1)
+			p := listen(func(conn net.Conn) {
+				buf := make([]byte, 4096)
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				requests <- buf[:n]
+
+				response := make([]byte, 4+64+65)
+				copy(response[4:], "\xfeSMB")
+				binary.LittleEndian.PutUint16(response[4+64+4:], 0x0311)
+				binary.BigEndian.PutUint16(response[2:], uint16(len(response)-4))
+				_, _ = conn.Write(response)
+				_, _ = io.Copy(io.Discard, conn)
+			})
+			detector.Hints = map[int]string{p: "smb"}
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(string((<-requests)[4:8])).To(Equal("\xfeSMB"))
+			Expect(svc.Name).To(Equal("smb"))
+			Expect(svc.Version).To(Equal("3.1.1"))
+		})
+
+		It("should open a NetBIOS session before the SMB negotiate on port 139", func() {
+			requests := make(chan []byte, 2)
+			p := listen(func(conn net.Conn) {
+				buf := make([]byte, 4096)
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				requests <- append([]byte(nil), buf[:n]...)
+				if buf[0] != 0x81 {
+					return
+				}
+				_, _ = conn.Write([]byte{0x82, 0x00, 0x00, 0x00}) // Positive Session Response
+
+				if n, err = conn.Read(buf); err != nil {
+					return
+				}
+				requests <- buf[:n]
+				response := make([]byte, 4+64+65)
+				copy(response[4:], "\xfeSMB")
+				binary.LittleEndian.PutUint16(response[4+64+4:], 0x0302)
+				binary.BigEndian.PutUint16(response[2:], uint16(len(response)-4))
+				_, _ = conn.Write(response)
+				_, _ = io.Copy(io.Discard, conn)
+			})
+			detector.Hints = map[int]string{p: "netbios-ssn"}
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			session := <-requests
+			Expect(session).To(HaveLen(72))
+			Expect(string(session[5:37])).To(Equal("CKFDENECFDEFFCFGEFFCCACACACACACA")) // "*SMBSERVER"
+			Expect(string((<-requests)[4:8])).To(Equal("\xfeSMB"))
+			Expect(svc.Name).To(Equal("smb"))
+			Expect(svc.Version).To(Equal("3.0.2"))
+		})
+
+		It("should detect MQTT brokers by the CONNACK", func() {
+			p := listen(respond([]byte{0x20, 0x02, 0x00, 0x00}))
+			detector.Hints = map[int]string{p: "mqtt"}
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(svc.Name).To(Equal("mqtt"))
+			Expect(svc.Banner).To(Equal("CONNACK rc=0"))
+		})
+
+		It("should fall back to the well-known service of silent ports", func() {
+			p := listen(func(conn net.Conn) { _, _ = io.Copy(io.Discard, conn) })
+
+			svc := detector.Detect(context.Background(), localhost, p)
+			Expect(svc.Port).To(Equal(p))
+			Expect(svc.Name).To(Equal(service.WellKnown(p)))
+			Expect(svc.Product).To(BeEmpty())
+		})
+	})
+
+	Describe("DetectAll", func() {
+		It("should detect all ports sorted by port", func() {
+			ssh := listen(greet("SSH-2.0-dropbear_2022.83\r\n"))
+			ftp := listen(greet("220 ProFTPD 1.3.8 Server ready.\r\n"))
+
+			services := detector.DetectAll(context.Background(), localhost, []int{max(ssh, ftp), min(ssh, ftp)})
+			Expect(services).To(HaveLen(2))
+			Expect(services[0].Port).To(BeNumerically("<", services[1].Port))
+
+			byPort := map[int]service.Service{services[0].Port: services[0], services[1].Port: services[1]}
+			Expect(byPort[ssh].Product).To(Equal("dropbear"))
+			Expect(byPort[ssh].Version).To(Equal("2022.83"))
+			Expect(byPort[ftp].Product).To(Equal("ProFTPD"))
+			Expect(byPort[ftp].Version).To(Equal("1.3.8"))
+		})
+	})
+
+	Describe("Identify", func() {
+		It("should identify VNC and Telnet", func() {
+			vnc, ok := service.Identify([]byte("RFB 003.008\n"))
+			Expect(ok).To(BeTrue())
+			Expect(vnc.Name).To(Equal("vnc"))
+			Expect(vnc.Version).To(Equal("3.8"))
+
+			telnet, ok := service.Identify([]byte{0xff, 0xfd, 0x18, 0xff, 0xfd, 0x20})
+			Expect(ok).To(BeTrue())
+			Expect(telnet.Name).To(Equal("telnet"))
+		})
+
+		It("should not identify binary probe answers without the probe", func() {
+			_, ok := service.Identify([]byte{0x20, 0x02, 0x00, 0x00})
+			Expect(ok).To(BeFalse())
+
+			_, ok = service.Identify(nil)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("UDP", func() {
+		// listenUDP startet einen lokalen UDP-Server, der jede Anfrage mit answer beantwortet
+		// (nil = keine Antwort)
+		listenUDP := func(answer func(request []byte) []byte) int {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(conn.Close)
+			go func() {
+				buf := make([]byte, 2048)
+				for {
+					n, addr, err := conn.ReadFrom(buf)
+					if err != nil {
+						return
+					}
+					if response := answer(buf[:n]); response != nil {
+						_, _ = conn.WriteTo(response, addr)
+					}
+				}
+			}()
+			return conn.LocalAddr().(*net.UDPAddr).Port
+		}
+
+		It("should read the DNS server version", func() {
+			port := listenUDP(func(request []byte) []byte {
+				response := append([]byte{}, request...)
+				response[2] |= 0x80 // QR
+				response[7] = 1     // ANCOUNT
+				txt := "dnsmasq-2.89"
+				response = append(response, 0xc0, 0x0c, 0x00, 0x10, 0x00, 0x03, 0, 0, 0, 0, 0, byte(len(txt)+1), byte(len(txt)))
+				return append(response, txt...)
+			})
+			detector.Hints = map[int]string{port: "dns"}
+
+			result := detector.DetectUDP(context.Background(), localhost, port)
+			Expect(result.State).To(Equal(service.PortOpen))
+			Expect(result.Service.Protocol).To(Equal("udp"))
+			Expect(result.Service.Name).To(Equal("dns"))
+			Expect(result.Service.Product).To(Equal("dnsmasq"))
+			Expect(result.Service.Version).To(Equal("2.89"))
+			Expect(result.Service.String()).To(Equal("u:" + strconv.Itoa(port) + "/dns dnsmasq 2.89"))
+		})
+
+		It("should identify NTP and SIP answers", func() {
+			ntp := listenUDP(func(request []byte) []byte {
+				response := make([]byte, 48)
+				response[0], response[1] = 0x24, 2 // Version 4, Mode 4, Stratum 2
+				return response
+			})
+			sip := listenUDP(func(request []byte) []byte {
+				Expect(string(request)).To(HavePrefix("OPTIONS sip:netspy@127.0.0.1 SIP/2.0"))
+				return []byte("SIP/2.0 200 OK\r\nServer: FRITZ!OS\r\nContent-Length: 0\r\n\r\n")
+			})
+			detector.Hints = map[int]string{ntp: "ntp", sip: "sip"}
+
+			result := detector.DetectUDP(context.Background(), localhost, ntp)
+			Expect(result.Service.Name).To(Equal("ntp"))
+			Expect(result.Service.Banner).To(Equal("NTPv4 stratum 2"))
+
+			result = detector.DetectUDP(context.Background(), localhost, sip)
+			Expect(result.Service.Name).To(Equal("sip"))
+			Expect(result.Service.Banner).To(Equal("FRITZ!OS"))
+		})
+
+		It("should report open, closed and open|filtered ports", func() {
+			echo := listenUDP(func(request []byte) []byte { return []byte("hello\n") })
+			silent := listenUDP(func(request []byte) []byte { return nil })
+
+			// Freien Port ermitteln: kurz belegen und wieder freigeben
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			closed := conn.LocalAddr().(*net.UDPAddr).Port
+			conn.Close()
+
+			results := detector.DetectAllUDP(context.Background(), localhost, []int{silent, closed, echo})
+			byPort := map[int]service.UDPResult{}
+			for _, result := range results {
+				byPort[result.Port] = result
+			}
+			Expect(byPort[echo].State).To(Equal(service.PortOpen))
+			Expect(byPort[echo].Service.Banner).To(Equal("hello"))
+			Expect(byPort[closed].State).To(Equal(service.PortClosed))
+			Expect(byPort[silent].State).To(Equal(service.PortOpenFiltered))
+			Expect(service.WellKnownUDP(161)).To(Equal("snmp"))
+		})
+	})
+
+	Describe("Certificates", func() {
+		It("should read the certificate chain of TLS ports", func() {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			DeferCleanup(server.Close)
+
+			chain, err := detector.Certificates(context.Background(), localhost, port(server))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain).NotTo(BeEmpty())
+
+			cert := chain[0]
+			Expect(cert.Subject).To(ContainSubstring("Acme Co"))
+			Expect(cert.Names).To(ContainElements("example.com", "127.0.0.1"))
+			Expect(cert.KeyType).To(Equal("RSA"))
+			Expect(cert.KeyBits).To(BeNumerically(">=", 1024))
+			Expect(cert.SelfSigned).To(BeTrue())
+			Expect(cert.Expired(time.Now())).To(BeFalse())
+			Expect(cert.SHA256).To(HaveLen(64))
+
+			// Die Diensterkennung übernimmt die Kette ebenfalls
+			svc := detector.Detect(context.Background(), localhost, port(server))
+			Expect(svc.Certificate()).NotTo(BeNil())
+			Expect(svc.Certificate().SHA256).To(Equal(cert.SHA256))
+		})
+
+		It("should fail on ports without TLS", func() {
+			_, err := detector.Certificates(context.Background(), localhost, listen(greet("SSH-2.0-OpenSSH_9.6\r\n")))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should add certificates to detected or new services", func() {
+			chain := []service.Certificate{{Subject: "nas.local"}}
+			services := []service.Service{{Port: 8443, Protocol: "tcp", Name: "http"}}
+
+			services = service.WithCertificates(services, 8443, chain)
+			Expect(services).To(HaveLen(1))
+			Expect(services[0].TLS).To(BeTrue())
+			Expect(services[0].Certificate().Subject).To(Equal("nas.local"))
+
+			services = service.WithCertificates(services, 993, chain)
+			services = service.WithCertificates(services, 4433, chain)
+			Expect(services).To(HaveLen(3))
+			Expect(services[0].Port).To(Equal(993))
+			Expect(services[0].Name).To(Equal("imaps"))
+			Expect(services[1].Name).To(Equal("tls"))
+		})
+
+		It("should format certificates compactly", func() {
+			cert := service.Certificate{
+				Subject: "nas.local", Issuer: "nas.local", SelfSigned: true, KeyType: "RSA", KeyBits: 2048,
+				NotAfter: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			}
+			Expect(cert.String()).To(Equal("nas.local (self-signed, RSA 2048, expires 2026-03-01)"))
+			Expect(cert.ExpiresIn(cert.NotAfter.Add(-48 * time.Hour))).To(Equal(48 * time.Hour))
+		})
+	})
+
+	Describe("Formatting", func() {
+		It("should format services compactly", func() {
+			Expect(service.Service{Port: 22, Name: "ssh", Product: "OpenSSH", Version: "8.9p1"}.String()).To(Equal("22/ssh OpenSSH 8.9p1"))
+			Expect(service.Service{Port: 80, Name: "http", Product: "lighttpd"}.String()).To(Equal("80/http lighttpd"))
+			Expect(service.Service{Port: 4711}.String()).To(Equal("4711/unknown"))
+			Expect(service.WellKnown(3389)).To(Equal("rdp"))
+			Expect(service.WellKnown(4711)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Zustände eines gescannten Ports
+const (
+	PortOpen         = "open"
+	PortClosed       = "closed"
+	PortFiltered     = "filtered"
+	PortOpenFiltered = "open|filtered" // UDP: keine Antwort, aber auch kein ICMP-Fehler
+)
+
+// udpAttempts ist die Anzahl der Sendeversuche pro UDP-Port (Datagramme gehen verloren)
+const udpAttempts = 2
+
+// UDPResult ist das Ergebnis eines UDP-Ports
+type UDPResult struct {
+	Port    int
+	State   string        // PortOpen, PortClosed, PortFiltered oder PortOpenFiltered
+	Service Service       // Erkannter Dienst (nur bei PortOpen)
+	RTT     time.Duration // Zeit bis zur Antwort
+}
+
+// udpProbe ist eine gültige Anfrage für einen UDP-Dienst und die Auswertung der Antwort
+type udpProbe struct {
+	name     string
+	request  func(ip net.IP) []byte
+	identify func(data []byte) (Service, bool)
+}
+
+// fixed gibt immer dieselbe Anfrage zurück
+func fixed(request []byte) func(net.IP) []byte {
+	return func(net.IP) []byte { return request }
+}
+
+var (
+	dnsProbe     = udpProbe{name: "dns", request: fixed(dnsVersionQuery), identify: identifyDNS}
+	ntpProbe     = udpProbe{name: "ntp", request: fixed(ntpRequest), identify: identifyNTP}
+	nbstatProbe  = udpProbe{name: "netbios-ns", request: fixed(nbstatQuery), identify: identifyNBSTAT}
+	snmpProbe    = udpProbe{name: "snmp", request: fixed(snmpGetSysDescr), identify: identifySNMP}
+	ikeProbe     = udpProbe{name: "isakmp", request: fixed(ikeRequest), identify: identifyIKE}
+	natTProbe    = udpProbe{name: "ipsec-nat-t", request: fixed(append([]byte{0, 0, 0, 0}, ikeRequest...)), identify: identifyNATT}
+	openVPNProbe = udpProbe{name: "openvpn", request: fixed(openVPNReset), identify: identifyOpenVPN}
+	ssdpProbe    = udpProbe{name: "ssdp", request: fixed(ssdpSearch), identify: identifySSDP}
+	stunProbe    = udpProbe{name: "stun", request: fixed(stunBinding), identify: identifySTUN}
+	sipProbe     = udpProbe{name: "sip", request: sipOptions, identify: identifySIP}
+	mdnsProbe    = udpProbe{name: "mdns", request: fixed(mdnsQuery), identify: identifyMDNS}
+	coapProbe    = udpProbe{name: "coap", request: fixed(coapGetCore), identify: identifyCoAP}
+)
+
+// udpPortProbes sind die Anfragen für bekannte UDP-Ports
+var udpPortProbes = map[int]udpProbe{
+	53:   dnsProbe,
+	123:  ntpProbe,
+	137:  nbstatProbe,
+	161:  snmpProbe,
+	500:  ikeProbe,
+	1194: openVPNProbe,
+	1900: ssdpProbe,
+	3478: stunProbe,
+	4500: natTProbe,
+	5060: sipProbe,
+	5353: mdnsProbe,
+	5683: coapProbe,
+}
+
+// udpProbeNames ordnet die Namen aus Detector.Hints den UDP-Anfragen zu
+var udpProbeNames = func() map[string]udpProbe {
+	names := make(map[string]udpProbe, len(udpPortProbes))
+	for _, p := range udpPortProbes {
+		names[p.name] = p
+	}
+	return names
+}()
+
+// udpProbeFor gibt die Anfrage für einen UDP-Port zurück (Hints vor den Standard-Ports)
+func (d *Detector) udpProbeFor(port int) (udpProbe, bool) {
+	if d != nil {
+		if p, ok := udpProbeNames[d.Hints[port]]; ok {
+			return p, true
+		}
+	}
+	p, ok := udpPortProbes[port]
+	return p, ok
+}
+
+// DetectUDP prüft einen UDP-Port. Bekannte Ports bekommen eine gültige Anfrage
+// ihres Protokolls (DNS, NTP, SNMP, SIP, ...), andere ein einzelnes Null-Byte.
+// Eine Antwort bedeutet offen, ein ICMP "port unreachable" geschlossen (der Kernel
+// meldet es auf verbundenen Sockets ohne besondere Rechte). Bleibt beides aus, ist
+// der Port open|filtered.
+func (d *Detector) DetectUDP(ctx context.Context, ip net.IP, port int) UDPResult {
+	result := UDPResult{Port: port, State: PortOpenFiltered}
+
+	dialer := net.Dialer{Timeout: d.timeout()}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		result.State = PortFiltered
+		return result
+	}
+	defer conn.Close()
+
+	p, known := d.udpProbeFor(port)
+	request := []byte{0}
+	if known {
+		request = p.request(ip)
+	}
+
+	buf := make([]byte, maxResponse)
+	wait := d.timeout() / udpAttempts
+	for attempt := 0; attempt < udpAttempts && ctx.Err() == nil; attempt++ {
+		start := time.Now()
+		if _, err := conn.Write(request); err != nil {
+			result.State = udpErrorState(err)
+			return result
+		}
+
+		_ = conn.SetReadDeadline(start.Add(wait))
+		n, err := conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue // Keine Antwort - erneut senden
+			}
+			result.State = udpErrorState(err)
+			return result
+		}
+
+		result.State = PortOpen
+		result.RTT = time.Since(start)
+		result.Service = d.finishUDP(port, p, known, buf[:n])
+		return result
+	}
+	return result
+}
+
+// finishUDP wertet die Antwort eines UDP-Ports aus; unbekannte Antworten behalten das Banner
+func (d *Detector) finishUDP(port int, p udpProbe, known bool, response []byte) Service {
+	svc, ok := Service{}, false
+	if known {
+		svc, ok = p.identify(response)
+	}
+	if !ok {
+		svc = Service{Name: WellKnownUDP(port), Banner: cleanBanner(response)}
+	}
+	svc.Port = port
+	svc.Protocol = "udp"
+	return svc
+}
+
+// udpErrorState ordnet ICMP-Fehler einem Port-Zustand zu
+func udpErrorState(err error) string {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return PortClosed // ICMP port unreachable (Windows meldet es als Reset)
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EACCES):
+		return PortFiltered // ICMP host/net unreachable bzw. administratively prohibited
+	default:
+		return PortOpenFiltered
+	}
+}
+
+// DetectAllUDP prüft mehrere UDP-Ports parallel (Ergebnis nach Port sortiert).
+// Es laufen höchstens 8 Anfragen gleichzeitig, weil viele Systeme ICMP-Fehler
+// drosseln und geschlossene Ports sonst als open|filtered erscheinen.
+func (d *Detector) DetectAllUDP(ctx context.Context, ip net.IP, ports []int) []UDPResult {
+	results := make([]UDPResult, len(ports))
+	semaphore := make(chan struct{}, 8)
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = d.DetectUDP(ctx, ip, port)
+		}(i, port)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Port < results[j].Port })
+	return results
+}
+
+// wellKnownUDP sind die üblichen Dienste bekannter UDP-Ports
+var wellKnownUDP = map[int]string{
+	53:    "dns",
+	67:    "dhcp",
+	69:    "tftp",
+	123:   "ntp",
+	137:   "netbios-ns",
+	138:   "netbios-dgm",
+	161:   "snmp",
+	162:   "snmptrap",
+	500:   "isakmp",
+	514:   "syslog",
+	520:   "rip",
+	1194:  "openvpn",
+	1900:  "ssdp",
+	3478:  "stun",
+	4500:  "ipsec-nat-t",
+	5060:  "sip",
+	5353:  "mdns",
+	5683:  "coap",
+	51820: "wireguard",
+}
+
+// WellKnownUDP gibt den üblichen Dienst eines UDP-Ports zurück (leer wenn unbekannt)
+func WellKnownUDP(port int) string {
+	return wellKnownUDP[port]
+}
+
+// Anfragen der UDP-Probes
+var (
+	// DNS: TXT-Abfrage "version.bind" in der Klasse CHAOS (BIND, dnsmasq, Unbound, ...)
+	dnsVersionQuery = []byte{
+		0x6e, 0x73, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		7, 'v', 'e', 'r', 's', 'i', 'o', 'n', 4, 'b', 'i', 'n', 'd', 0,
+		0x00, 0x10, 0x00, 0x03,
+	}
+
+	// NTP: Client-Anfrage (Version 3, Mode 3)
+	ntpRequest = append([]byte{0x1b}, make([]byte, 47)...)
+
+	// NetBIOS: Node Status Request für "*"
+	nbstatQuery = append(append([]byte{
+		0x6e, 0x62, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 'C', 'K',
+	}, bytes.Repeat([]byte("A"), 30)...), 0x00, 0x00, 0x21, 0x00, 0x01)
+
+	// SNMP: v2c GetRequest für sysDescr.0 mit Community "public"
+	sysDescrOID     = []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}
+	snmpGetSysDescr = ber(0x30,
+		ber(0x02, []byte{0x01}),
+		ber(0x04, []byte("public")),
+		ber(0xa0,
+			ber(0x02, []byte{0x6e, 0x73}),
+			ber(0x02, []byte{0x00}),
+			ber(0x02, []byte{0x00}),
+			ber(0x30, ber(0x30, ber(0x06, sysDescrOID), ber(0x05))),
+		),
+	)
+
+	// IKEv1: Main Mode mit einem Vorschlag (3DES, SHA1, PSK, Gruppe 2)
+	ikeCookie  = []byte("netspy!!")
+	ikeRequest = buildIKERequest()
+
+	// OpenVPN: P_CONTROL_HARD_RESET_CLIENT_V2 (ohne tls-auth)
+	openVPNReset = append(append([]byte{0x38}, ikeCookie...), 0x00, 0x00, 0x00, 0x00, 0x00)
+
+	// SSDP: Unicast M-SEARCH
+	ssdpSearch = []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n")
+
+	// STUN: Binding Request
+	stunTransaction = []byte("netspy-stun!")
+	stunBinding     = append([]byte{0x00, 0x01, 0x00, 0x00, 0x21, 0x12, 0xa4, 0x42}, stunTransaction...)
+
+	// mDNS: PTR-Abfrage "_services._dns-sd._udp.local" mit Unicast-Antwort
+	mdnsQuery = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		9, '_', 's', 'e', 'r', 'v', 'i', 'c', 'e', 's',
+		7, '_', 'd', 'n', 's', '-', 's', 'd',
+		4, '_', 'u', 'd', 'p',
+		5, 'l', 'o', 'c', 'a', 'l', 0,
+		0x00, 0x0c, 0x80, 0x01,
+	}
+
+	// CoAP: GET /.well-known/core (confirmable)
+	coapGetCore = append(append([]byte{0x40, 0x01, 0x6e, 0x73, 0xbb}, ".well-known"...), append([]byte{0x04}, "core"...)...)
+)
+
+// ber kodiert ein BER-Element (nur kurze Längen, für feste Anfragen)
+func ber(tag byte, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+	return append([]byte{tag, byte(len(body))}, body...)
+}
+
+// buildIKERequest erzeugt eine IKEv1-Main-Mode-Anfrage mit SA-Payload
+func buildIKERequest() []byte {
+	attributes := []byte{
+		0x80, 0x01, 0x00, 0x05, // Verschlüsselung: 3DES
+		0x80, 0x02, 0x00, 0x02, // Hash: SHA1
+		0x80, 0x03, 0x00, 0x01, // Authentisierung: Pre-Shared Key
+		0x80, 0x04, 0x00, 0x02, // DH-Gruppe 2
+		0x80, 0x0b, 0x00, 0x01, // Lebensdauer in Sekunden ...
+		0x00, 0x0c, 0x00, 0x04, 0x00, 0x00, 0x70, 0x80, // ... 28800
+	}
+	transform := append([]byte{0x00, 0x00, 0x00, byte(8 + len(attributes)), 0x01, 0x01, 0x00, 0x00}, attributes...)
+	proposal := append([]byte{0x00, 0x00, 0x00, byte(8 + len(transform)), 0x01, 0x01, 0x00, 0x01}, transform...)
+	sa := append([]byte{0x00, 0x00, 0x00, byte(12 + len(proposal)), 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}, proposal...)
+
+	header := make([]byte, 28)
+	copy(header, ikeCookie)
+	header[16] = 0x01 // Nächste Payload: SA
+	header[17] = 0x10 // Version 1.0
+	header[18] = 0x02 // Identity Protection (Main Mode)
+	binary.BigEndian.PutUint32(header[24:], uint32(len(header)+len(sa)))
+	return append(header, sa...)
+}
+
+// sipOptions erzeugt eine OPTIONS-Anfrage (rport: Antwort an den Absender-Port)
+func sipOptions(ip net.IP) []byte {
+	return []byte(fmt.Sprintf("OPTIONS sip:netspy@%[1]s SIP/2.0\r\n"+
+		"Via: SIP/2.0/UDP netspy.invalid;branch=z9hG4bK-netspy;rport\r\n"+
+		"Max-Forwards: 70\r\n"+
+		"To: <sip:netspy@%[1]s>\r\n"+
+		"From: <sip:netspy@netspy.invalid>;tag=netspy\r\n"+
+		"Call-ID: netspy-probe\r\n"+
+		"CSeq: 1 OPTIONS\r\n"+
+		"Contact: <sip:netspy@netspy.invalid>\r\n"+
+		"Accept: application/sdp\r\n"+
+		"Content-Length: 0\r\n\r\n", ip))
+}
+
+// identifyDNS: Antwort auf "version.bind" - auch REFUSED beweist einen DNS-Server
+func identifyDNS(data []byte) (Service, bool) {
+	if len(data) < 12 || data[0] != 0x6e || data[1] != 0x73 || data[2]&0x80 == 0 {
+		return Service{}, false
+	}
+	svc := Service{Name: "dns"}
+
+	// Frage überspringen, erste Antwort lesen
+	offset, ok := skipDNSName(data, 12)
+	if !ok || binary.BigEndian.Uint16(data[6:8]) == 0 {
+		return svc, true
+	}
+	offset, ok = skipDNSName(data, offset+4)
+	if !ok || offset+10 > len(data) || binary.BigEndian.Uint16(data[offset:]) != 0x10 {
+		return svc, true
+	}
+	rdata := data[offset+10:]
+	if len(rdata) == 0 || int(rdata[0]) >= len(rdata) {
+		return svc, true
+	}
+
+	version := string(rdata[1 : 1+int(rdata[0])])
+	svc.Banner = cleanBanner([]byte(version))
+	switch {
+	case strings.HasPrefix(version, "dnsmasq-"):
+		svc.Product, svc.Version = splitProduct(version, "-")
+	case version != "" && version[0] >= '0' && version[0] <= '9':
+		// BIND meldet nur die Version, z.B. "9.18.18-0ubuntu0.22.04.1-Ubuntu"
+		svc.Product = "BIND"
+		svc.Version, _, _ = strings.Cut(version, "-")
+	default:
+		// "unbound 1.17.1", "PowerDNS Recursor 4.8.4"
+		if fields := strings.Fields(version); len(fields) > 1 {
+			svc.Product = strings.Join(fields[:len(fields)-1], " ")
+			svc.Version = fields[len(fields)-1]
+		}
+	}
+	return svc, true
+}
+
+// skipDNSName überspringt einen (ggf. komprimierten) Namen
+func skipDNSName(data []byte, offset int) (int, bool) {
+	for offset < len(data) {
+		length := int(data[offset])
+		switch {
+		case length == 0:
+			return offset + 1, true
+		case length&0xc0 == 0xc0:
+			return offset + 2, offset+2 <= len(data)
+		}
+		offset += 1 + length
+	}
+	return 0, false
+}
+
+// identifyNTP: Server-Antwort (Mode 4) mit Version und Stratum
+func identifyNTP(data []byte) (Service, bool) {
+	if len(data) < 48 || data[0]&0x07 != 4 {
+		return Service{}, false
+	}
+	return Service{Name: "ntp", Banner: fmt.Sprintf("NTPv%d stratum %d", data[0]>>3&0x07, data[1])}, true
+}
+
+// identifyNBSTAT: Node Status Response, erster Name ist der Rechnername
+func identifyNBSTAT(data []byte) (Service, bool) {
+	if len(data) < 12 || data[0] != 0x6e || data[1] != 0x62 || data[2]&0x80 == 0 {
+		return Service{}, false
+	}
+	svc := Service{Name: "netbios-ns"}
+	offset, ok := skipDNSName(data, 12)
+	if ok && offset+11+15 <= len(data) && data[offset+10] > 0 {
+		svc.Banner = strings.TrimSpace(string(data[offset+11 : offset+11+15]))
+	}
+	return svc, true
+}
+
+// identifySNMP: GetResponse, Banner ist sysDescr
+func identifySNMP(data []byte) (Service, bool) {
+	if len(data) < 2 || data[0] != 0x30 || !bytes.Contains(data, []byte{0xa2}) {
+		return Service{}, false
+	}
+	svc := Service{Name: "snmp"}
+	if i := bytes.Index(data, append([]byte{0x06, byte(len(sysDescrOID))}, sysDescrOID...)); i >= 0 {
+		value := data[i+2+len(sysDescrOID):]
+		if len(value) > 2 && value[0] == 0x04 && int(value[1]) < 0x80 && 2+int(value[1]) <= len(value) {
+			svc.Banner = cleanBanner(value[2 : 2+int(value[1])])
+		}
+	}
+	return svc, true
+}
+
+// identifyIKE: Antwort mit unserem Initiator-Cookie (SA oder Notify)
+func identifyIKE(data []byte) (Service, bool) {
+	if len(data) < 28 || !bytes.Equal(data[:8], ikeCookie) {
+		return Service{}, false
+	}
+	return Service{Name: "isakmp", Banner: fmt.Sprintf("IKEv%d", data[17]>>4)}, true
+}
+
+// identifyNATT: IKE hinter dem Non-ESP-Marker
+func identifyNATT(data []byte) (Service, bool) {
+	if len(data) < 4 || !bytes.Equal(data[:4], []byte{0, 0, 0, 0}) {
+		return Service{}, false
+	}
+	svc, ok := identifyIKE(data[4:])
+	svc.Name = "ipsec-nat-t"
+	return svc, ok
+}
+
+// identifyOpenVPN: P_CONTROL_HARD_RESET_SERVER_V2
+func identifyOpenVPN(data []byte) (Service, bool) {
+	if len(data) < 14 || data[0]>>3 != 8 {
+		return Service{}, false
+	}
+	return Service{Name: "openvpn"}, true
+}
+
+// identifySSDP: HTTP-Antwort auf M-SEARCH, Banner ist der SERVER-Header
+func identifySSDP(data []byte) (Service, bool) {
+	if !bytes.HasPrefix(data, []byte("HTTP/1.")) {
+		return Service{}, false
+	}
+	return Service{Name: "ssdp", Banner: httpHeader(data, "Server")}, true
+}
+
+// identifySTUN: Binding Success Response, Banner ist das SOFTWARE-Attribut
+func identifySTUN(data []byte) (Service, bool) {
+	if len(data) < 20 || binary.BigEndian.Uint16(data) != 0x0101 || !bytes.Equal(data[8:20], stunTransaction) {
+		return Service{}, false
+	}
+	svc := Service{Name: "stun"}
+	for offset := 20; offset+4 <= len(data); {
+		attribute := binary.BigEndian.Uint16(data[offset:])
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if offset+4+length > len(data) {
+			break
+		}
+		if attribute == 0x8022 {
+			svc.Banner = cleanBanner(data[offset+4 : offset+4+length])
+			break
+		}
+		offset += 4 + (length+3)/4*4
+	}
+	return svc, true
+}
+
+// identifySIP: Antwort auf OPTIONS, Banner aus Server- bzw. User-Agent-Header
+func identifySIP(data []byte) (Service, bool) {
+	if !bytes.HasPrefix(data, []byte("SIP/2.0 ")) {
+		return Service{}, false
+	}
+	svc := Service{Name: "sip", Banner: httpHeader(data, "Server")}
+	if svc.Banner == "" {
+		svc.Banner = httpHeader(data, "User-Agent")
+	}
+	if svc.Banner == "" {
+		svc.Banner = cleanBanner(data)
+	}
+	return svc, true
+}
+
+// identifyMDNS: Antwort (QR-Bit) auf die Dienst-Abfrage
+func identifyMDNS(data []byte) (Service, bool) {
+	if len(data) < 12 || data[2]&0x80 == 0 {
+		return Service{}, false
+	}
+	return Service{Name: "mdns"}, true
+}
+
+// identifyCoAP: Antwort (Version 1) mit unserer Message-ID
+func identifyCoAP(data []byte) (Service, bool) {
+	if len(data) < 4 || data[0]>>6 != 1 || data[2] != 0x6e || data[3] != 0x73 {
+		return Service{}, false
+	}
+	return Service{Name: "coap", Banner: fmt.Sprintf("%d.%02d", data[1]>>5, data[1]&0x1f)}, true
+}
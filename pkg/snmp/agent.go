@@ -0,0 +1,271 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OID ist ein Objektbezeichner als Wert im Agenten (z.B. sysObjectID)
+type OID string
+
+// Agent ist ein minimaler SNMP-Agent mit statischer MIB. Er beantwortet Get,
+// GetNext und GetBulk für v2c (Communities) und v3 (USM-Benutzer) und dient
+// als In-Process-Gegenstelle für Tests und Vorführungen.
+//
+// Werte in Objects: string/[]byte/net.HardwareAddr (OCTET STRING), int (INTEGER),
+// uint32 (Gauge32), uint64 (Counter64), time.Duration (TimeTicks), OID und net.IP
+type Agent struct {
+	Communities []string
+	Users       []Credentials
+	EngineID    []byte // Standard: aus der Startzeit abgeleitet
+	Boots       int64  // snmpEngineBoots (Standard 1)
+	Objects     map[string]interface{}
+
+	entries []agentEntry
+	keys    map[string]*usmKeys
+	started time.Time
+}
+
+type agentEntry struct {
+	arcs []uint32
+	v    variable
+}
+
+// Serve beantwortet Anfragen auf conn, bis conn geschlossen wird
+func (a *Agent) Serve(conn net.PacketConn) error {
+	if err := a.prepare(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if reply := a.handle(append([]byte(nil), buf[:n]...)); reply != nil {
+			_, _ = conn.WriteTo(reply, addr)
+		}
+	}
+}
+
+// prepare sortiert die MIB und lokalisiert die Schlüssel der Benutzer
+func (a *Agent) prepare() error {
+	a.started = time.Now()
+	if a.Boots == 0 {
+		a.Boots = 1
+	}
+	if len(a.EngineID) == 0 {
+		// Format 5 (RFC 3411): Enterprise-Nummer + frei wählbare Oktette
+		a.EngineID = append([]byte{0x80, 0x00, 0x00, 0x00, 0x05}, []byte(fmt.Sprintf("netspy%x", a.started.UnixNano()))...)
+	}
+
+	a.entries = nil
+	for oid, value := range a.Objects {
+		oid = strings.TrimPrefix(oid, ".")
+		v, err := newVariable(oid, value)
+		if err != nil {
+			return err
+		}
+		arcs, err := parseOID(oid)
+		if err != nil {
+			return err
+		}
+		a.entries = append(a.entries, agentEntry{arcs: arcs, v: v})
+	}
+	sort.Slice(a.entries, func(i, j int) bool { return compareOIDs(a.entries[i].arcs, a.entries[j].arcs) < 0 })
+
+	a.keys = make(map[string]*usmKeys, len(a.Users))
+	for _, user := range a.Users {
+		normalized, err := user.Normalize()
+		if err != nil {
+			return err
+		}
+		a.keys[normalized.User] = newUSMKeys(normalized, a.EngineID)
+	}
+	return nil
+}
+
+// newVariable bildet einen Go-Wert auf den passenden SNMP-Typ ab
+func newVariable(oid string, value interface{}) (variable, error) {
+	v := variable{oid: oid}
+	switch value := value.(type) {
+	case string:
+		v.tag, v.value = tagOctetString, []byte(value)
+	case []byte:
+		v.tag, v.value = tagOctetString, value
+	case net.HardwareAddr:
+		v.tag, v.value = tagOctetString, []byte(value)
+	case int:
+		v.tag, v.value = tagInteger, int64(value)
+	case uint32:
+		v.tag, v.value = tagGauge32, uint64(value)
+	case uint64:
+		v.tag, v.value = tagCounter64, value
+	case time.Duration:
+		v.tag, v.value = tagTimeTicks, uint64(value/(10*time.Millisecond))
+	case OID:
+		v.tag, v.value = tagOID, string(value)
+	case net.IP:
+		if value.To4() == nil {
+			return v, fmt.Errorf("%s: IpAddress must be IPv4", oid)
+		}
+		v.tag, v.value = tagIPAddress, value
+	default:
+		return v, fmt.Errorf("%s: unsupported value type %T", oid, value)
+	}
+	return v, nil
+}
+
+// handle beantwortet eine Nachricht (nil = verwerfen, wie bei falscher Community)
+func (a *Agent) handle(raw []byte) []byte {
+	m, err := decodeMessage(raw)
+	if err != nil {
+		return nil
+	}
+
+	if m.version == version2c {
+		for _, community := range a.Communities {
+			if string(m.community) == community {
+				reply, _ := (&message{version: version2c, community: m.community, pdu: a.respond(m.pdu)}).encode(nil)
+				return reply
+			}
+		}
+		return nil
+	}
+	return a.handleV3(m, raw)
+}
+
+func (a *Agent) handleV3(m *message, raw []byte) []byte {
+	now := int64(time.Since(a.started) / time.Second)
+	report := func(oid string, flags byte, keys *usmKeys) []byte {
+		reply, _ := (&message{
+			version: version3,
+			msgID:   m.msgID,
+			flags:   flags,
+			security: securityParameters{
+				engineID: a.EngineID,
+				boots:    a.Boots,
+				time:     now,
+				user:     m.security.user,
+			},
+			contextEngineID: a.EngineID,
+			pdu: pdu{tag: pduReport, requestID: m.pdu.requestID, variables: []variable{
+				{oid: oid, tag: tagCounter32, value: uint64(1)},
+			}},
+		}).encode(keys)
+		return reply
+	}
+
+	if len(m.security.engineID) == 0 {
+		_ = m.open(raw, nil) // Discovery: requestID für den Report
+		return report(oidUnknownEngineIDs, 0, nil)
+	}
+	keys := a.keys[string(m.security.user)]
+	if keys == nil {
+		return report(oidUsmStats+".3.0", 0, nil)
+	}
+	if m.flags&(flagAuth|flagPriv) != keys.level() {
+		return report(oidUsmStats+".1.0", 0, nil)
+	}
+	if err := m.open(raw, keys); err != nil {
+		if errors.Is(err, errWrongDigest) {
+			return report(oidUsmStats+".5.0", 0, nil)
+		}
+		return report(oidUsmStats+".6.0", 0, nil)
+	}
+
+	// Zeitfenster von 150 Sekunden (RFC 3414 3.2 Schritt 7)
+	if keys.auth != nil && (m.security.boots != a.Boots || m.security.time < now-150 || m.security.time > now+150) {
+		return report(oidNotInTimeWindows, flagAuth, &usmKeys{auth: keys.auth, authKey: keys.authKey})
+	}
+
+	reply, _ := (&message{
+		version: version3,
+		msgID:   m.msgID,
+		flags:   keys.level(),
+		security: securityParameters{
+			engineID: a.EngineID,
+			boots:    a.Boots,
+			time:     now,
+			user:     m.security.user,
+		},
+		contextEngineID: a.EngineID,
+		contextName:     m.contextName,
+		pdu:             a.respond(m.pdu),
+	}).encode(keys)
+	return reply
+}
+
+// respond beantwortet Get, GetNext und GetBulk aus der statischen MIB
+func (a *Agent) respond(request pdu) pdu {
+	response := pdu{tag: pduResponse, requestID: request.requestID}
+
+	switch request.tag {
+	case pduGet:
+		for _, v := range request.variables {
+			response.variables = append(response.variables, a.lookup(v.oid))
+		}
+	case pduGetNext:
+		for _, v := range request.variables {
+			response.variables = append(response.variables, a.next(v.oid))
+		}
+	case pduGetBulk:
+		nonRepeaters, repetitions := request.errorStatus, request.errorIndex
+		if nonRepeaters < 0 {
+			nonRepeaters = 0
+		}
+		if nonRepeaters > len(request.variables) {
+			nonRepeaters = len(request.variables)
+		}
+		for _, v := range request.variables[:nonRepeaters] {
+			response.variables = append(response.variables, a.next(v.oid))
+		}
+		cursors := request.variables[nonRepeaters:]
+		for r := 0; r < repetitions && len(cursors) > 0; r++ {
+			done := true
+			next := make([]variable, len(cursors))
+			for i, cursor := range cursors {
+				next[i] = a.next(cursor.oid)
+				if next[i].tag != tagEndOfMibView {
+					done = false
+				}
+			}
+			response.variables = append(response.variables, next...)
+			if done {
+				break
+			}
+			cursors = next
+		}
+	default:
+		response.errorStatus = 5 // genErr
+	}
+	return response
+}
+
+func (a *Agent) lookup(oid string) variable {
+	for _, entry := range a.entries {
+		if entry.v.oid == oid {
+			return entry.v
+		}
+	}
+	return variable{oid: oid, tag: tagNoSuchObject}
+}
+
+func (a *Agent) next(oid string) variable {
+	arcs, err := parseOID(oid)
+	if err == nil {
+		index := sort.Search(len(a.entries), func(i int) bool { return compareOIDs(a.entries[i].arcs, arcs) > 0 })
+		if index < len(a.entries) {
+			return a.entries[index].v
+		}
+	}
+	return variable{oid: oid, tag: tagEndOfMibView}
+}
@@ -0,0 +1,267 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER-Tags der von SNMP verwendeten ASN.1-Typen
+const (
+	tagInteger        = 0x02
+	tagOctetString    = 0x04
+	tagNull           = 0x05
+	tagOID            = 0x06
+	tagSequence       = 0x30
+	tagIPAddress      = 0x40
+	tagCounter32      = 0x41
+	tagGauge32        = 0x42
+	tagTimeTicks      = 0x43
+	tagOpaque         = 0x44
+	tagCounter64      = 0x46
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	pduGet      = 0xa0
+	pduGetNext  = 0xa1
+	pduResponse = 0xa2
+	pduGetBulk  = 0xa5
+	pduReport   = 0xa8
+)
+
+var errTruncated = errors.New("truncated BER element")
+
+// element ist ein dekodiertes TLV; content verweist in den Original-Puffer
+type element struct {
+	tag     byte
+	content []byte
+}
+
+// tlv kodiert ein Element mit beliebig langem Inhalt
+func tlv(tag byte, content ...[]byte) []byte {
+	size := 0
+	for _, part := range content {
+		size += len(part)
+	}
+	out := append([]byte{tag}, encodeLength(size)...)
+	for _, part := range content {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var digits []byte
+	for ; n > 0; n >>= 8 {
+		digits = append([]byte{byte(n)}, digits...)
+	}
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+// encodeInteger kodiert v im kürzesten Zweierkomplement
+func encodeInteger(tag byte, v int64) []byte {
+	n := 1
+	for x := v; x > 127 || x < -128; x >>= 8 {
+		n++
+	}
+	content := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		content[i] = byte(v)
+		v >>= 8
+	}
+	return tlv(tag, content)
+}
+
+// encodeUnsigned kodiert Counter, Gauges und TimeTicks (ohne Vorzeichen)
+func encodeUnsigned(tag byte, v uint64) []byte {
+	n := 1
+	for x := v; x > 0xff; x >>= 8 {
+		n++
+	}
+	content := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		content[i] = byte(v)
+		v >>= 8
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+	return tlv(tag, content)
+}
+
+func encodeOID(oid string) ([]byte, error) {
+	arcs, err := parseOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	if len(arcs) < 2 || arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+
+	content := appendBase128(nil, arcs[0]*40+arcs[1])
+	for _, arc := range arcs[2:] {
+		content = appendBase128(content, arc)
+	}
+	return tlv(tagOID, content), nil
+}
+
+func appendBase128(out []byte, v uint32) []byte {
+	var digits []byte
+	for {
+		digits = append([]byte{byte(v & 0x7f)}, digits...)
+		if v >>= 7; v == 0 {
+			break
+		}
+	}
+	for i := 0; i < len(digits)-1; i++ {
+		digits[i] |= 0x80
+	}
+	return append(out, digits...)
+}
+
+// parseOID zerlegt "1.3.6.1..." (führender Punkt erlaubt) in seine Bögen
+func parseOID(oid string) ([]uint32, error) {
+	oid = strings.TrimPrefix(oid, ".")
+	if oid == "" {
+		return nil, fmt.Errorf("empty OID")
+	}
+	parts := strings.Split(oid, ".")
+	arcs := make([]uint32, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		arcs[i] = uint32(arc)
+	}
+	return arcs, nil
+}
+
+// compareOIDs vergleicht lexikografisch nach Bögen (SNMP-Reihenfolge)
+func compareOIDs(a, b []uint32) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// hasOIDPrefix prüft, ob oid im Teilbaum root liegt
+func hasOIDPrefix(oid, root string) bool {
+	return strings.HasPrefix(oid, root+".")
+}
+
+// parseElement liest ein TLV und gibt den Rest des Puffers zurück
+func parseElement(data []byte) (element, []byte, error) {
+	if len(data) < 2 {
+		return element{}, nil, errTruncated
+	}
+	tag, size, offset := data[0], int(data[1]), 2
+	if size&0x80 != 0 {
+		digits := size & 0x7f
+		if digits == 0 || digits > 4 || len(data) < 2+digits {
+			return element{}, nil, fmt.Errorf("invalid BER length")
+		}
+		size = 0
+		for _, b := range data[2 : 2+digits] {
+			size = size<<8 | int(b)
+		}
+		offset += digits
+	}
+	if size < 0 || len(data)-offset < size {
+		return element{}, nil, errTruncated
+	}
+	return element{tag: tag, content: data[offset : offset+size]}, data[offset+size:], nil
+}
+
+// parseSequence zerlegt den Inhalt einer SEQUENCE (oder PDU) in ihre Elemente
+func parseSequence(e element, expected byte) ([]element, error) {
+	if e.tag != expected {
+		return nil, fmt.Errorf("unexpected BER tag 0x%02x (want 0x%02x)", e.tag, expected)
+	}
+	var elements []element
+	for rest := e.content; len(rest) > 0; {
+		child, remaining, err := parseElement(rest)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, child)
+		rest = remaining
+	}
+	return elements, nil
+}
+
+func decodeInteger(e element) (int64, error) {
+	if e.tag != tagInteger || len(e.content) == 0 || len(e.content) > 8 {
+		return 0, fmt.Errorf("invalid INTEGER")
+	}
+	var v int64
+	if e.content[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, b := range e.content {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+func decodeUnsigned(content []byte) (uint64, error) {
+	if len(content) == 0 || len(content) > 9 || (len(content) == 9 && content[0] != 0) {
+		return 0, fmt.Errorf("invalid unsigned value")
+	}
+	var v uint64
+	for _, b := range content {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func decodeOctets(e element) ([]byte, error) {
+	if e.tag != tagOctetString {
+		return nil, fmt.Errorf("invalid OCTET STRING")
+	}
+	return e.content, nil
+}
+
+func decodeOID(content []byte) (string, error) {
+	if len(content) == 0 {
+		return "", fmt.Errorf("invalid OID")
+	}
+	var arcs []string
+	var v uint64
+	for i, b := range content {
+		v = v<<7 | uint64(b&0x7f)
+		if v > 0xffffffff+80 {
+			return "", fmt.Errorf("invalid OID")
+		}
+		if b&0x80 != 0 {
+			if i == len(content)-1 {
+				return "", fmt.Errorf("invalid OID")
+			}
+			continue
+		}
+		if arcs == nil {
+			switch {
+			case v < 40:
+				arcs = append(arcs, "0", strconv.FormatUint(v, 10))
+			case v < 80:
+				arcs = append(arcs, "1", strconv.FormatUint(v-40, 10))
+			default:
+				arcs = append(arcs, "2", strconv.FormatUint(v-80, 10))
+			}
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, "."), nil
+}
@@ -0,0 +1,388 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// Nachrichtenversionen und msgFlags (RFC 3412)
+const (
+	version2c = 1
+	version3  = 3
+
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+
+	securityModelUSM = 3
+	maxMessageSize   = 65507
+)
+
+// variable ist ein Varbind; value hängt vom Tag ab: int64 (INTEGER), []byte
+// (OCTET STRING), string (OID), net.IP (IpAddress), uint64 (Counter, Gauge,
+// TimeTicks) und nil (NULL, noSuchObject, endOfMibView)
+type variable struct {
+	oid   string
+	tag   byte
+	value interface{}
+}
+
+// missing meldet Varbinds ohne Wert (nicht vorhanden oder Ende der MIB)
+func (v variable) missing() bool {
+	return v.value == nil
+}
+
+// text liefert OCTET STRINGs als bereinigten Text
+func (v variable) text() string {
+	data, ok := v.value.([]byte)
+	if !ok {
+		return ""
+	}
+	out := make([]rune, 0, len(data))
+	for _, r := range string(data) {
+		switch {
+		case r == '\r' || r == '\n' || r == '\t':
+			out = append(out, ' ')
+		case r < 0x20 || r == 0x7f || r == utf8.RuneError:
+			// Steuerzeichen und ungültiges UTF-8 auslassen
+		default:
+			out = append(out, r)
+		}
+	}
+	return strings.Join(strings.Fields(string(out)), " ")
+}
+
+// number liefert numerische Werte (INTEGER, Counter, Gauge, TimeTicks)
+func (v variable) number() uint64 {
+	switch n := v.value.(type) {
+	case int64:
+		if n > 0 {
+			return uint64(n)
+		}
+	case uint64:
+		return n
+	}
+	return 0
+}
+
+func (v variable) encode() ([]byte, error) {
+	oid, err := encodeOID(v.oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	switch v.tag {
+	case tagInteger:
+		value = encodeInteger(tagInteger, v.value.(int64))
+	case tagOctetString, tagOpaque:
+		value = tlv(v.tag, v.value.([]byte))
+	case tagOID:
+		if value, err = encodeOID(v.value.(string)); err != nil {
+			return nil, err
+		}
+	case tagIPAddress:
+		value = tlv(tagIPAddress, v.value.(net.IP).To4())
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		value = encodeUnsigned(v.tag, v.value.(uint64))
+	default:
+		value = tlv(v.tag)
+	}
+	return tlv(tagSequence, oid, value), nil
+}
+
+func decodeVariable(e element) (variable, error) {
+	parts, err := parseSequence(e, tagSequence)
+	if err != nil {
+		return variable{}, err
+	}
+	if len(parts) != 2 || parts[0].tag != tagOID {
+		return variable{}, fmt.Errorf("invalid varbind")
+	}
+	oid, err := decodeOID(parts[0].content)
+	if err != nil {
+		return variable{}, err
+	}
+
+	v := variable{oid: oid, tag: parts[1].tag}
+	switch content := parts[1].content; v.tag {
+	case tagInteger:
+		v.value, err = decodeInteger(parts[1])
+	case tagOctetString, tagOpaque:
+		v.value = append([]byte(nil), content...)
+	case tagOID:
+		v.value, err = decodeOID(content)
+	case tagIPAddress:
+		if len(content) != 4 {
+			return variable{}, fmt.Errorf("invalid IpAddress")
+		}
+		v.value = net.IP(append([]byte(nil), content...))
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		v.value, err = decodeUnsigned(content)
+	}
+	return v, err
+}
+
+// pdu ist eine SNMP-PDU; bei GetBulk stehen in errorStatus/errorIndex
+// non-repeaters und max-repetitions
+type pdu struct {
+	tag         byte
+	requestID   int32
+	errorStatus int
+	errorIndex  int
+	variables   []variable
+}
+
+func (p pdu) encode() ([]byte, error) {
+	var list [][]byte
+	for _, v := range p.variables {
+		encoded, err := v.encode()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, encoded)
+	}
+	return tlv(p.tag,
+		encodeInteger(tagInteger, int64(p.requestID)),
+		encodeInteger(tagInteger, int64(p.errorStatus)),
+		encodeInteger(tagInteger, int64(p.errorIndex)),
+		tlv(tagSequence, list...),
+	), nil
+}
+
+func decodePDU(e element) (pdu, error) {
+	switch e.tag {
+	case pduGet, pduGetNext, pduResponse, pduGetBulk, pduReport:
+	default:
+		return pdu{}, fmt.Errorf("unsupported PDU type 0x%02x", e.tag)
+	}
+	parts, err := parseSequence(e, e.tag)
+	if err != nil {
+		return pdu{}, err
+	}
+	if len(parts) != 4 {
+		return pdu{}, fmt.Errorf("invalid PDU")
+	}
+
+	var numbers [3]int64
+	for i := range numbers {
+		if numbers[i], err = decodeInteger(parts[i]); err != nil {
+			return pdu{}, err
+		}
+	}
+	p := pdu{tag: e.tag, requestID: int32(numbers[0]), errorStatus: int(numbers[1]), errorIndex: int(numbers[2])}
+
+	bindings, err := parseSequence(parts[3], tagSequence)
+	if err != nil {
+		return pdu{}, err
+	}
+	for _, binding := range bindings {
+		v, err := decodeVariable(binding)
+		if err != nil {
+			return pdu{}, err
+		}
+		p.variables = append(p.variables, v)
+	}
+	return p, nil
+}
+
+// securityParameters sind die USM-Parameter einer v3-Nachricht (RFC 3414)
+type securityParameters struct {
+	engineID   []byte
+	boots      int64
+	time       int64
+	user       []byte
+	authParams []byte
+	privParams []byte
+}
+
+func (s securityParameters) encode() []byte {
+	return tlv(tagSequence,
+		tlv(tagOctetString, s.engineID),
+		encodeInteger(tagInteger, s.boots),
+		encodeInteger(tagInteger, s.time),
+		tlv(tagOctetString, s.user),
+		tlv(tagOctetString, s.authParams),
+		tlv(tagOctetString, s.privParams),
+	)
+}
+
+// message ist eine v2c- oder v3-Nachricht. Bei v3 wird der scopedPDU erst
+// mit open geprüft, entschlüsselt und dekodiert.
+type message struct {
+	version   int64
+	community []byte
+
+	msgID           int64
+	flags           byte
+	security        securityParameters
+	contextEngineID []byte
+	contextName     []byte
+	scoped          element
+
+	pdu pdu
+}
+
+// encode kodiert die Nachricht; v3 wird mit keys verschlüsselt und signiert
+func (m *message) encode(keys *usmKeys) ([]byte, error) {
+	body, err := m.pdu.encode()
+	if err != nil {
+		return nil, err
+	}
+	if m.version != version3 {
+		return tlv(tagSequence, encodeInteger(tagInteger, m.version), tlv(tagOctetString, m.community), body), nil
+	}
+
+	scoped := tlv(tagSequence, tlv(tagOctetString, m.contextEngineID), tlv(tagOctetString, m.contextName), body)
+	security := m.security
+	if m.flags&(flagAuth|flagPriv) != 0 && keys == nil {
+		return nil, fmt.Errorf("missing USM keys")
+	}
+	if m.flags&flagPriv != 0 {
+		encrypted, salt, err := keys.encrypt(scoped, security.boots, security.time)
+		if err != nil {
+			return nil, err
+		}
+		scoped, security.privParams = tlv(tagOctetString, encrypted), salt
+	}
+
+	header := tlv(tagSequence,
+		encodeInteger(tagInteger, m.msgID),
+		encodeInteger(tagInteger, maxMessageSize),
+		tlv(tagOctetString, []byte{m.flags}),
+		encodeInteger(tagInteger, securityModelUSM),
+	)
+	build := func() []byte {
+		return tlv(tagSequence, encodeInteger(tagInteger, version3), header, tlv(tagOctetString, security.encode()), scoped)
+	}
+	if m.flags&flagAuth == 0 {
+		return build(), nil
+	}
+
+	// Signatur über die Nachricht mit genullten authParams, danach einsetzen
+	security.authParams = make([]byte, authParamsLength)
+	security.authParams = keys.sign(build())
+	return build(), nil
+}
+
+// decodeMessage dekodiert Kopf und Sicherheitsparameter einer Nachricht
+func decodeMessage(raw []byte) (*message, error) {
+	outer, _, err := parseElement(raw)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := parseSequence(outer, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid SNMP message")
+	}
+
+	m := &message{}
+	if m.version, err = decodeInteger(parts[0]); err != nil {
+		return nil, err
+	}
+	switch m.version {
+	case version2c:
+		if m.community, err = decodeOctets(parts[1]); err != nil {
+			return nil, err
+		}
+		m.pdu, err = decodePDU(parts[2])
+		return m, err
+	case version3:
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %d", m.version)
+	}
+
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid SNMPv3 message")
+	}
+	header, err := parseSequence(parts[1], tagSequence)
+	if err != nil || len(header) != 4 {
+		return nil, fmt.Errorf("invalid SNMPv3 header")
+	}
+	if m.msgID, err = decodeInteger(header[0]); err != nil {
+		return nil, err
+	}
+	flags, err := decodeOctets(header[2])
+	if err != nil || len(flags) != 1 {
+		return nil, fmt.Errorf("invalid SNMPv3 flags")
+	}
+	m.flags = flags[0]
+	if model, err := decodeInteger(header[3]); err != nil || model != securityModelUSM {
+		return nil, fmt.Errorf("unsupported security model")
+	}
+
+	encoded, err := decodeOctets(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	paramsElement, _, err := parseElement(encoded)
+	if err != nil {
+		return nil, err
+	}
+	params, err := parseSequence(paramsElement, tagSequence)
+	if err != nil || len(params) != 6 {
+		return nil, fmt.Errorf("invalid USM parameters")
+	}
+	s := &m.security
+	if s.engineID, err = decodeOctets(params[0]); err != nil {
+		return nil, err
+	}
+	if s.boots, err = decodeInteger(params[1]); err != nil {
+		return nil, err
+	}
+	if s.time, err = decodeInteger(params[2]); err != nil {
+		return nil, err
+	}
+	for i, target := range []*[]byte{&s.user, &s.authParams, &s.privParams} {
+		if *target, err = decodeOctets(params[3+i]); err != nil {
+			return nil, err
+		}
+	}
+
+	m.scoped = parts[3]
+	return m, nil
+}
+
+// open prüft die Signatur einer v3-Nachricht (raw = empfangene Bytes),
+// entschlüsselt den scopedPDU und dekodiert die PDU. keys darf für
+// Nachrichten ohne Authentisierung nil sein.
+func (m *message) open(raw []byte, keys *usmKeys) error {
+	if m.flags&(flagAuth|flagPriv) != 0 && keys == nil {
+		return errUnknownUser
+	}
+	if m.flags&flagAuth != 0 && !keys.verify(raw, m.security.authParams) {
+		return errWrongDigest
+	}
+
+	scoped := m.scoped
+	if m.flags&flagPriv != 0 {
+		if scoped.tag != tagOctetString {
+			return errDecryption
+		}
+		plain, err := keys.decrypt(scoped.content, m.security.privParams, m.security.boots, m.security.time)
+		if err != nil {
+			return errDecryption
+		}
+		if scoped, _, err = parseElement(plain); err != nil {
+			return errDecryption
+		}
+	}
+
+	parts, err := parseSequence(scoped, tagSequence)
+	if err != nil || len(parts) != 3 {
+		return fmt.Errorf("invalid scoped PDU")
+	}
+	if m.contextEngineID, err = decodeOctets(parts[0]); err != nil {
+		return err
+	}
+	if m.contextName, err = decodeOctets(parts[1]); err != nil {
+		return err
+	}
+	m.pdu, err = decodePDU(parts[2])
+	return err
+}
@@ -0,0 +1,271 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// OIDs der USM-Statistik, mit denen Agenten v3-Fehler melden (RFC 3414)
+const (
+	oidUsmStats         = "1.3.6.1.6.3.15.1.1"
+	oidNotInTimeWindows = oidUsmStats + ".2.0"
+	oidUnknownEngineIDs = oidUsmStats + ".4.0"
+)
+
+var reportErrors = map[string]string{
+	oidUsmStats + ".1.0": "unsupported security level",
+	oidNotInTimeWindows:  "not in time window",
+	oidUsmStats + ".3.0": "unknown user name",
+	oidUnknownEngineIDs:  "unknown engine ID",
+	oidUsmStats + ".5.0": "wrong digest (authentication failed)",
+	oidUsmStats + ".6.0": "decryption error",
+}
+
+// session ist eine Verbindung zu einem Agenten (nicht nebenläufig verwendbar)
+type session struct {
+	conn      net.Conn
+	creds     Credentials
+	timeout   time.Duration
+	retries   int
+	requestID int32
+
+	// v3: per Discovery ermittelte Engine und lokalisierte Schlüssel
+	engineID   []byte
+	boots      int64
+	engineTime int64
+	synced     time.Time
+	keys       *usmKeys
+}
+
+func (s *session) nextID() int32 {
+	s.requestID = (s.requestID + 1) & 0x7fffffff
+	return s.requestID
+}
+
+// get fragt einzelne OIDs ab
+func (s *session) get(ctx context.Context, oids ...string) ([]variable, error) {
+	request := pdu{tag: pduGet}
+	for _, oid := range oids {
+		request.variables = append(request.variables, variable{oid: oid, tag: tagNull})
+	}
+	response, err := s.request(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.variables, nil
+}
+
+// walk liest den Teilbaum unter root per GetBulk (höchstens limit Einträge)
+func (s *session) walk(ctx context.Context, root string, limit int) ([]variable, error) {
+	var result []variable
+	last, err := parseOID(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := root
+	for len(result) < limit {
+		response, err := s.request(ctx, pdu{
+			tag:        pduGetBulk,
+			errorIndex: 32, // max-repetitions
+			variables:  []variable{{oid: cursor, tag: tagNull}},
+		})
+		if err != nil {
+			return result, err
+		}
+		if len(response.variables) == 0 {
+			return result, nil
+		}
+		for _, v := range response.variables {
+			if v.tag == tagEndOfMibView || !hasOIDPrefix(v.oid, root) {
+				return result, nil
+			}
+			// Agenten mit fehlerhafter Sortierung würden sonst endlos liefern
+			arcs, err := parseOID(v.oid)
+			if err != nil || compareOIDs(arcs, last) <= 0 {
+				return result, nil
+			}
+			last, cursor = arcs, v.oid
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// request sendet eine PDU und liefert die Response-PDU
+func (s *session) request(ctx context.Context, request pdu) (pdu, error) {
+	var (
+		response pdu
+		err      error
+	)
+	if s.creds.Version == "3" {
+		response, err = s.requestV3(ctx, request)
+	} else {
+		request.requestID = s.nextID()
+		response, err = s.requestV2c(ctx, request)
+	}
+	if err != nil {
+		return pdu{}, err
+	}
+	if response.errorStatus != 0 {
+		return pdu{}, fmt.Errorf("snmp error status %d at index %d", response.errorStatus, response.errorIndex)
+	}
+	return response, nil
+}
+
+func (s *session) requestV2c(ctx context.Context, request pdu) (pdu, error) {
+	packet, err := (&message{version: version2c, community: []byte(s.creds.Community), pdu: request}).encode(nil)
+	if err != nil {
+		return pdu{}, err
+	}
+	response, _, err := s.roundTrip(ctx, packet, func(m *message) bool {
+		return m.version == version2c && m.pdu.tag == pduResponse && m.pdu.requestID == request.requestID
+	})
+	if err != nil {
+		return pdu{}, err
+	}
+	return response.pdu, nil
+}
+
+func (s *session) requestV3(ctx context.Context, request pdu) (pdu, error) {
+	if s.keys == nil {
+		if err := s.discover(ctx); err != nil {
+			return pdu{}, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		request.requestID = s.nextID()
+		msgID := int64(s.nextID())
+		packet, err := (&message{
+			version: version3,
+			msgID:   msgID,
+			flags:   s.keys.level() | flagReportable,
+			security: securityParameters{
+				engineID: s.engineID,
+				boots:    s.boots,
+				time:     s.engineTime + int64(time.Since(s.synced)/time.Second),
+				user:     []byte(s.creds.User),
+			},
+			contextEngineID: s.engineID,
+			pdu:             request,
+		}).encode(s.keys)
+		if err != nil {
+			return pdu{}, err
+		}
+
+		response, raw, err := s.roundTrip(ctx, packet, func(m *message) bool {
+			return m.version == version3 && m.msgID == msgID
+		})
+		if err != nil {
+			return pdu{}, err
+		}
+		if err := response.open(raw, s.keys); err != nil {
+			return pdu{}, fmt.Errorf("snmp v3: %v", err)
+		}
+
+		if response.pdu.tag == pduReport {
+			// Nach der Discovery kennen wir engineBoots/-Time evtl. noch nicht
+			if reportOID(response.pdu) == oidNotInTimeWindows && attempt == 0 {
+				s.sync(response.security)
+				continue
+			}
+			return pdu{}, reportError(response.pdu)
+		}
+		if response.pdu.tag != pduResponse || response.flags&(flagAuth|flagPriv) != s.keys.level() {
+			return pdu{}, fmt.Errorf("snmp v3: unexpected response security level")
+		}
+		return response.pdu, nil
+	}
+}
+
+// discover ermittelt engineID, engineBoots und engineTime des Agenten (RFC 3414 4)
+func (s *session) discover(ctx context.Context) error {
+	msgID := int64(s.nextID())
+	packet, err := (&message{
+		version: version3,
+		msgID:   msgID,
+		flags:   flagReportable,
+		pdu:     pdu{tag: pduGet, requestID: s.nextID()},
+	}).encode(nil)
+	if err != nil {
+		return err
+	}
+
+	response, raw, err := s.roundTrip(ctx, packet, func(m *message) bool {
+		return m.version == version3 && m.msgID == msgID
+	})
+	if err != nil {
+		return err
+	}
+	if err := response.open(raw, nil); err != nil {
+		return fmt.Errorf("snmp v3: %v", err)
+	}
+	if len(response.security.engineID) == 0 {
+		return fmt.Errorf("snmp v3: engine discovery failed")
+	}
+
+	s.engineID = append([]byte(nil), response.security.engineID...)
+	s.sync(response.security)
+	s.keys = newUSMKeys(s.creds, s.engineID)
+	return nil
+}
+
+// sync übernimmt engineBoots/-Time aus einer Antwort des Agenten
+func (s *session) sync(params securityParameters) {
+	s.boots, s.engineTime, s.synced = params.boots, params.time, time.Now()
+}
+
+// roundTrip sendet packet (mit Wiederholungen) und liefert die erste Antwort,
+// die match akzeptiert, samt ihrer Rohdaten
+func (s *session) roundTrip(ctx context.Context, packet []byte, match func(*message) bool) (*message, []byte, error) {
+	buf := make([]byte, maxMessageSize)
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if _, err := s.conn.Write(packet); err != nil {
+			return nil, nil, unreachable(err)
+		}
+
+		deadline := time.Now().Add(s.timeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		_ = s.conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := s.conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, nil, unreachable(err)
+			}
+			raw := append([]byte(nil), buf[:n]...)
+			if m, err := decodeMessage(raw); err == nil && match(m) {
+				return m, raw, nil
+			}
+		}
+	}
+	return nil, nil, ErrNoResponse
+}
+
+// reportOID liefert die Zähler-OID einer Report-PDU
+func reportOID(report pdu) string {
+	if len(report.variables) == 0 {
+		return ""
+	}
+	return report.variables[0].oid
+}
+
+func reportError(report pdu) error {
+	if text, ok := reportErrors[reportOID(report)]; ok {
+		return fmt.Errorf("snmp v3: %s", text)
+	}
+	return fmt.Errorf("snmp v3: agent reported %s", reportOID(report))
+}
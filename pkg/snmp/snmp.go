@@ -0,0 +1,271 @@
+// Package snmp implementiert einen schlanken SNMP-Client (v2c und v3 mit USM)
+// zur Abfrage von System-Gruppe und Interface-Tabelle von Netzwerkgeräten
+// sowie einen minimalen Agenten als Gegenstelle für Tests.
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultPort ist der Standard-Port von SNMP-Agenten
+const DefaultPort = 161
+
+var (
+	// ErrNoResponse meldet, dass der Agent nicht geantwortet hat (auch bei falscher Community)
+	ErrNoResponse = errors.New("no SNMP response")
+	// ErrUnreachable meldet ein ICMP Port/Host Unreachable: weitere Versuche sind sinnlos
+	ErrUnreachable = errors.New("SNMP port unreachable")
+)
+
+// OIDs der System-Gruppe (RFC 1213) und der Interface-Tabellen (RFC 2863)
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSysContact  = "1.3.6.1.2.1.1.4.0"
+	oidSysName     = "1.3.6.1.2.1.1.5.0"
+	oidSysLocation = "1.3.6.1.2.1.1.6.0"
+
+	oidIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	oidIfType        = "1.3.6.1.2.1.2.2.1.3"
+	oidIfSpeed       = "1.3.6.1.2.1.2.2.1.5"
+	oidIfPhysAddress = "1.3.6.1.2.1.2.2.1.6"
+	oidIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	oidIfName        = "1.3.6.1.2.1.31.1.1.1.1"
+	oidIfHighSpeed   = "1.3.6.1.2.1.31.1.1.1.15"
+	oidIfAlias       = "1.3.6.1.2.1.31.1.1.1.18"
+
+	// maxInterfaces begrenzt den Walk pro Spalte (Chassis-Switches haben Tausende)
+	maxInterfaces = 512
+)
+
+// Credentials beschreibt Zugangsdaten für einen Agenten (Konfigurationsschlüssel "snmp")
+//
+//	snmp:
+//	  - community: public
+//	  - version: 3
+//	    user: monitor
+//	    auth_protocol: sha
+//	    auth_password: geheim123
+//	    priv_protocol: aes
+//	    priv_password: geheim456
+type Credentials struct {
+	Version   string `mapstructure:"version"`   // "2c" (Standard) oder "3"
+	Community string `mapstructure:"community"` // v2c
+	Port      int    `mapstructure:"port"`      // Standard 161
+
+	// v3 (USM)
+	User         string `mapstructure:"user"`
+	AuthProtocol string `mapstructure:"auth_protocol"` // md5, sha (leer = noAuthNoPriv)
+	AuthPassword string `mapstructure:"auth_password"`
+	PrivProtocol string `mapstructure:"priv_protocol"` // des, aes (leer = authNoPriv)
+	PrivPassword string `mapstructure:"priv_password"`
+}
+
+// Normalize vereinheitlicht Schreibweisen ("v2c", "SHA1", "AES128") und prüft
+// die Zugangsdaten auf Vollständigkeit
+func (c Credentials) Normalize() (Credentials, error) {
+	switch strings.TrimPrefix(strings.ToLower(c.Version), "v") {
+	case "", "2", "2c":
+		c.Version = "2c"
+		if c.Community == "" {
+			return c, fmt.Errorf("snmp v2c: community is required")
+		}
+		return c, nil
+	case "3":
+		c.Version = "3"
+	default:
+		return c, fmt.Errorf("unsupported SNMP version %q (valid: 2c, 3)", c.Version)
+	}
+
+	if c.User == "" {
+		return c, fmt.Errorf("snmp v3: user is required")
+	}
+	switch strings.ToLower(c.AuthProtocol) {
+	case "":
+		c.AuthProtocol = ""
+	case "md5":
+		c.AuthProtocol = "md5"
+	case "sha", "sha1":
+		c.AuthProtocol = "sha"
+	default:
+		return c, fmt.Errorf("snmp v3: unsupported auth protocol %q (valid: md5, sha)", c.AuthProtocol)
+	}
+	switch strings.ToLower(c.PrivProtocol) {
+	case "":
+		c.PrivProtocol = ""
+	case "des":
+		c.PrivProtocol = "des"
+	case "aes", "aes128":
+		c.PrivProtocol = "aes"
+	default:
+		return c, fmt.Errorf("snmp v3: unsupported privacy protocol %q (valid: des, aes)", c.PrivProtocol)
+	}
+
+	if c.AuthProtocol != "" && len(c.AuthPassword) < 8 {
+		return c, fmt.Errorf("snmp v3: auth password of user %q must have at least 8 characters", c.User)
+	}
+	if c.PrivProtocol != "" {
+		if c.AuthProtocol == "" {
+			return c, fmt.Errorf("snmp v3: privacy requires an auth protocol (user %q)", c.User)
+		}
+		if len(c.PrivPassword) < 8 {
+			return c, fmt.Errorf("snmp v3: privacy password of user %q must have at least 8 characters", c.User)
+		}
+	}
+	return c, nil
+}
+
+// System enthält die per SNMP abgefragten Geräteinformationen
+type System struct {
+	Version    string        `json:"version"` // "2c" oder "3"
+	Name       string        `json:"name,omitempty"`
+	Descr      string        `json:"descr,omitempty"`
+	ObjectID   string        `json:"object_id,omitempty"`
+	Location   string        `json:"location,omitempty"`
+	Contact    string        `json:"contact,omitempty"`
+	UpTime     time.Duration `json:"uptime,omitempty"`
+	Interfaces []Interface   `json:"interfaces,omitempty"`
+}
+
+// Interface ist eine Zeile der Interface-Tabelle (ifTable/ifXTable)
+type Interface struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`            // ifName, sonst ifDescr
+	Descr string `json:"descr,omitempty"` // ifDescr
+	Alias string `json:"alias,omitempty"` // ifAlias (vom Admin vergebene Beschreibung)
+	Type  int    `json:"type,omitempty"`  // IANAifType (6 = Ethernet, 71 = WLAN, 24 = Loopback)
+	Speed uint64 `json:"speed,omitempty"` // Bit/s
+	MAC   string `json:"mac,omitempty"`
+	Up    bool   `json:"up"`
+}
+
+// Query fragt System-Gruppe und Interface-Tabelle eines Agenten ab. Ohne Antwort
+// liefert Query ErrNoResponse (bei v2c auch bei falscher Community), bei ICMP
+// Unreachable ErrUnreachable. Fehler beim Interface-Walk werden ignoriert.
+func Query(ctx context.Context, ip net.IP, creds Credentials, timeout time.Duration) (*System, error) {
+	creds, err := creds.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	port := creds.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	s := &session{conn: conn, creds: creds, timeout: timeout, retries: 1, requestID: int32(time.Now().UnixNano() & 0x3fffffff)}
+	scalars, err := s.get(ctx, oidSysDescr, oidSysObjectID, oidSysUpTime, oidSysContact, oidSysName, oidSysLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	system := &System{Version: creds.Version}
+	for _, v := range scalars {
+		switch v.oid {
+		case oidSysDescr:
+			system.Descr = v.text()
+		case oidSysObjectID:
+			system.ObjectID, _ = v.value.(string)
+		case oidSysUpTime:
+			system.UpTime = time.Duration(v.number()) * 10 * time.Millisecond
+		case oidSysContact:
+			system.Contact = v.text()
+		case oidSysName:
+			system.Name = v.text()
+		case oidSysLocation:
+			system.Location = v.text()
+		}
+	}
+
+	system.Interfaces = s.interfaces(ctx)
+	return system, nil
+}
+
+// interfaces liest die Interface-Tabelle spaltenweise per GetBulk
+func (s *session) interfaces(ctx context.Context) []Interface {
+	rows := make(map[int]*Interface)
+	for _, column := range []string{oidIfDescr, oidIfName, oidIfAlias, oidIfType, oidIfSpeed, oidIfHighSpeed, oidIfPhysAddress, oidIfOperStatus} {
+		values, err := s.walk(ctx, column, maxInterfaces)
+		if column == oidIfDescr && (err != nil || len(values) == 0) {
+			return nil // Keine ifTable - weitere Spalten sparen
+		}
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			index, err := strconv.Atoi(strings.TrimPrefix(v.oid, column+"."))
+			if err != nil {
+				continue
+			}
+			row := rows[index]
+			if row == nil {
+				if column != oidIfDescr {
+					continue // Nur Interfaces aus der ifTable
+				}
+				row = &Interface{Index: index}
+				rows[index] = row
+			}
+			switch column {
+			case oidIfDescr:
+				row.Descr = v.text()
+			case oidIfName:
+				row.Name = v.text()
+			case oidIfAlias:
+				row.Alias = v.text()
+			case oidIfType:
+				row.Type = int(v.number())
+			case oidIfSpeed:
+				if row.Speed == 0 {
+					row.Speed = v.number()
+				}
+			case oidIfHighSpeed:
+				if speed := v.number(); speed > 0 {
+					row.Speed = speed * 1000000 // Mbit/s
+				}
+			case oidIfPhysAddress:
+				if mac, ok := v.value.([]byte); ok && len(mac) == 6 {
+					row.MAC = net.HardwareAddr(mac).String()
+				}
+			case oidIfOperStatus:
+				row.Up = v.number() == 1
+			}
+		}
+	}
+
+	list := make([]Interface, 0, len(rows))
+	for _, row := range rows {
+		if row.Name == "" {
+			row.Name = row.Descr
+		}
+		list = append(list, *row)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Index < list[j].Index })
+	return list
+}
+
+// unreachable ordnet ICMP-Fehler des verbundenen UDP-Sockets ErrUnreachable zu
+func unreachable(err error) error {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	return err
+}
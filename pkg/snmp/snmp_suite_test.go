@@ -0,0 +1,13 @@
+package snmp_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSNMP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SNMP Suite")
+}
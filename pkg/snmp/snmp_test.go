@@ -0,0 +1,174 @@
+package snmp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/snmp"
+)
+
+var _ = Describe("SNMP", func() {
+	localhost := net.ParseIP("127.0.0.1")
+	timeout := 300 * time.Millisecond
+
+	switchMIB := map[string]interface{}{
+		"1.3.6.1.2.1.1.1.0": "Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(7)E4\r\nTechnical Support: http://www.cisco.com/techsupport",
+		"1.3.6.1.2.1.1.2.0": snmp.OID("1.3.6.1.4.1.9.1.1208"),
+		"1.3.6.1.2.1.1.3.0": 36*time.Hour + 90*time.Second,
+		"1.3.6.1.2.1.1.4.0": "noc@example.com",
+		"1.3.6.1.2.1.1.5.0": "core-sw01",
+		"1.3.6.1.2.1.1.6.0": "Serverraum, Rack 3",
+
+		"1.3.6.1.2.1.2.2.1.2.1":     "GigabitEthernet1/0/1",
+		"1.3.6.1.2.1.2.2.1.2.2":     "GigabitEthernet1/0/2",
+		"1.3.6.1.2.1.2.2.1.2.10":    "Vlan10",
+		"1.3.6.1.2.1.2.2.1.3.1":     6,
+		"1.3.6.1.2.1.2.2.1.3.2":     6,
+		"1.3.6.1.2.1.2.2.1.3.10":    53,
+		"1.3.6.1.2.1.2.2.1.5.1":     uint32(1000000000),
+		"1.3.6.1.2.1.2.2.1.5.2":     uint32(100000000),
+		"1.3.6.1.2.1.2.2.1.6.1":     net.HardwareAddr{0x00, 0x1b, 0x54, 0x01, 0x02, 0x03},
+		"1.3.6.1.2.1.2.2.1.8.1":     1,
+		"1.3.6.1.2.1.2.2.1.8.2":     2,
+		"1.3.6.1.2.1.2.2.1.8.10":    1,
+		"1.3.6.1.2.1.31.1.1.1.1.1":  "Gi1/0/1",
+		"1.3.6.1.2.1.31.1.1.1.1.2":  "Gi1/0/2",
+		"1.3.6.1.2.1.31.1.1.1.15.1": uint32(10000),
+		"1.3.6.1.2.1.31.1.1.1.18.1": "Uplink Firewall",
+		"1.3.6.1.2.1.4.1.0":         2, // hinter der ifXTable: Walk-Ende prüfen
+	}
+
+	// serve startet einen In-Process-Agenten auf einem freien UDP-Port
+	serve := func(agent *snmp.Agent) int {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+		go func() { _ = agent.Serve(conn) }()
+		return conn.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	Describe("Query", func() {
+		It("should read system group and interface table via v2c", func() {
+			port := serve(&snmp.Agent{Communities: []string{"secret"}, Objects: switchMIB})
+
+			system, err := snmp.Query(context.Background(), localhost, snmp.Credentials{Community: "secret", Port: port}, timeout)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(system.Version).To(Equal("2c"))
+			Expect(system.Name).To(Equal("core-sw01"))
+			Expect(system.Descr).To(HavePrefix("Cisco IOS Software, C2960X"))
+			Expect(system.Descr).NotTo(ContainSubstring("\n"))
+			Expect(system.ObjectID).To(Equal("1.3.6.1.4.1.9.1.1208"))
+			Expect(system.Location).To(Equal("Serverraum, Rack 3"))
+			Expect(system.Contact).To(Equal("noc@example.com"))
+			Expect(system.UpTime).To(Equal(36*time.Hour + 90*time.Second))
+
+			Expect(system.Interfaces).To(HaveLen(3))
+			Expect(system.Interfaces[0]).To(Equal(snmp.Interface{
+				Index: 1, Name: "Gi1/0/1", Descr: "GigabitEthernet1/0/1", Alias: "Uplink Firewall",
+				Type: 6, Speed: 10000000000, MAC: "00:1b:54:01:02:03", Up: true,
+			}))
+			Expect(system.Interfaces[1].Speed).To(Equal(uint64(100000000)))
+			Expect(system.Interfaces[1].Up).To(BeFalse())
+			Expect(system.Interfaces[2].Name).To(Equal("Vlan10"))
+		})
+
+		It("should get no response for a wrong community", func() {
+			port := serve(&snmp.Agent{Communities: []string{"secret"}, Objects: switchMIB})
+
+			_, err := snmp.Query(context.Background(), localhost, snmp.Credentials{Community: "public", Port: port}, 100*time.Millisecond)
+			Expect(errors.Is(err, snmp.ErrNoResponse)).To(BeTrue(), "error: %v", err)
+		})
+
+		It("should report closed ports as unreachable", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			port := conn.LocalAddr().(*net.UDPAddr).Port
+			Expect(conn.Close()).To(Succeed())
+
+			_, err = snmp.Query(context.Background(), localhost, snmp.Credentials{Community: "public", Port: port}, timeout)
+			Expect(errors.Is(err, snmp.ErrUnreachable)).To(BeTrue(), "error: %v", err)
+		})
+
+		It("should walk tables larger than one GetBulk response", func() {
+			objects := map[string]interface{}{"1.3.6.1.2.1.1.5.0": "stack"}
+			for i := 1; i <= 100; i++ {
+				objects["1.3.6.1.2.1.2.2.1.2."+strconv.Itoa(i)] = "port" + strconv.Itoa(i)
+			}
+			port := serve(&snmp.Agent{Communities: []string{"public"}, Objects: objects})
+
+			system, err := snmp.Query(context.Background(), localhost, snmp.Credentials{Community: "public", Port: port}, timeout)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(system.Interfaces).To(HaveLen(100))
+			Expect(system.Interfaces[99].Name).To(Equal("port100"))
+		})
+
+		DescribeTable("should query via v3 with USM",
+			func(auth, priv string) {
+				user := snmp.Credentials{
+					Version: "3", User: "monitor",
+					AuthProtocol: auth, AuthPassword: "maplesyrup",
+					PrivProtocol: priv, PrivPassword: "privsyrup1",
+				}
+				port := serve(&snmp.Agent{Users: []snmp.Credentials{user}, Objects: switchMIB})
+
+				user.Port = port
+				system, err := snmp.Query(context.Background(), localhost, user, timeout)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(system.Version).To(Equal("3"))
+				Expect(system.Name).To(Equal("core-sw01"))
+				Expect(system.Interfaces).To(HaveLen(3))
+			},
+			Entry("authNoPriv MD5", "md5", ""),
+			Entry("authNoPriv SHA", "sha", ""),
+			Entry("authPriv MD5/DES", "MD5", "DES"),
+			Entry("authPriv SHA/AES", "SHA1", "AES128"),
+		)
+
+		It("should report v3 authentication errors", func() {
+			user := snmp.Credentials{Version: "v3", User: "monitor", AuthProtocol: "sha", AuthPassword: "maplesyrup"}
+			port := serve(&snmp.Agent{Users: []snmp.Credentials{user}, Objects: switchMIB})
+
+			wrong := user
+			wrong.Port, wrong.AuthPassword = port, "wrong-password"
+			_, err := snmp.Query(context.Background(), localhost, wrong, timeout)
+			Expect(err).To(MatchError(ContainSubstring("authentication failed")))
+
+			unknown := user
+			unknown.Port, unknown.User = port, "admin"
+			_, err = snmp.Query(context.Background(), localhost, unknown, timeout)
+			Expect(err).To(MatchError(ContainSubstring("unknown user name")))
+		})
+	})
+
+	Describe("Credentials", func() {
+		It("should normalize and validate credentials", func() {
+			creds, err := snmp.Credentials{Version: "V3", User: "u", AuthProtocol: "SHA1", AuthPassword: "12345678", PrivProtocol: "aes128", PrivPassword: "87654321"}.Normalize()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(creds.Version).To(Equal("3"))
+			Expect(creds.AuthProtocol).To(Equal("sha"))
+			Expect(creds.PrivProtocol).To(Equal("aes"))
+
+			creds, err = snmp.Credentials{Community: "public"}.Normalize()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(creds.Version).To(Equal("2c"))
+
+			for _, invalid := range []snmp.Credentials{
+				{},
+				{Version: "1", Community: "public"},
+				{Version: "3"},
+				{Version: "3", User: "u", AuthProtocol: "sha256", AuthPassword: "12345678"},
+				{Version: "3", User: "u", AuthProtocol: "md5", AuthPassword: "short"},
+				{Version: "3", User: "u", PrivProtocol: "des", PrivPassword: "12345678"},
+			} {
+				_, err := invalid.Normalize()
+				Expect(err).To(HaveOccurred(), "credentials %+v", invalid)
+			}
+		})
+	})
+})
@@ -0,0 +1,194 @@
+package snmp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec // USM: DES-CBC (RFC 3414)
+	"crypto/hmac"
+	"crypto/md5"  //nolint:gosec // USM: HMAC-MD5-96 (RFC 3414)
+	"crypto/sha1" //nolint:gosec // USM: HMAC-SHA-96 (RFC 3414)
+	"encoding/binary"
+	"errors"
+	"hash"
+	"sync/atomic"
+	"time"
+)
+
+// authParamsLength ist die Länge von HMAC-MD5-96 und HMAC-SHA-96
+const authParamsLength = 12
+
+var (
+	errUnknownUser = errors.New("unknown user name")
+	errWrongDigest = errors.New("wrong digest (authentication failed)")
+	errDecryption  = errors.New("decryption error")
+)
+
+// saltCounter liefert die Salt-Werte für DES/AES (RFC 3414 8.1.1.1, RFC 3826 3.1.2.1)
+var saltCounter = uint64(time.Now().UnixNano())
+
+// usmKeys sind die lokalisierten Schlüssel eines Benutzers für eine Engine
+type usmKeys struct {
+	auth    func() hash.Hash // nil = noAuth
+	authKey []byte
+	priv    string // "des", "aes" oder "" (noPriv)
+	privKey []byte
+}
+
+// newUSMKeys lokalisiert die Passwörter der Zugangsdaten auf engineID
+func newUSMKeys(creds Credentials, engineID []byte) *usmKeys {
+	keys := &usmKeys{}
+	switch creds.AuthProtocol {
+	case "md5":
+		keys.auth = md5.New
+	case "sha":
+		keys.auth = sha1.New
+	default:
+		return keys
+	}
+	keys.authKey = passwordToKey(keys.auth, creds.AuthPassword, engineID)
+	if creds.PrivProtocol != "" {
+		keys.priv = creds.PrivProtocol
+		keys.privKey = passwordToKey(keys.auth, creds.PrivPassword, engineID)
+	}
+	return keys
+}
+
+// level liefert die msgFlags der Sicherheitsstufe (noAuthNoPriv, authNoPriv, authPriv)
+func (k *usmKeys) level() byte {
+	var flags byte
+	if k.auth != nil {
+		flags |= flagAuth
+	}
+	if k.priv != "" {
+		flags |= flagPriv
+	}
+	return flags
+}
+
+// passwordToKey bildet den lokalisierten Schlüssel nach RFC 3414 A.2:
+// Hash über 1 MB wiederholtes Passwort, dann Hash(Ku || engineID || Ku)
+func passwordToKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	if password != "" {
+		block := make([]byte, 64)
+		for index, count := 0, 0; count < 1048576; count += len(block) {
+			for i := range block {
+				block[i] = password[index%len(password)]
+				index++
+			}
+			h.Write(block)
+		}
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// sign berechnet die authParams über die Nachricht mit genullten authParams
+func (k *usmKeys) sign(message []byte) []byte {
+	mac := hmac.New(k.auth, k.authKey)
+	mac.Write(message)
+	return mac.Sum(nil)[:authParamsLength]
+}
+
+// verify prüft die authParams einer empfangenen Nachricht
+func (k *usmKeys) verify(raw, authParams []byte) bool {
+	if k.auth == nil || len(authParams) != authParamsLength {
+		return false
+	}
+	field := tlv(tagOctetString, authParams)
+	offset := bytes.Index(raw, field)
+	if offset < 0 {
+		return false
+	}
+	zeroed := append([]byte(nil), raw...)
+	copy(zeroed[offset+2:], make([]byte, authParamsLength))
+	return hmac.Equal(k.sign(zeroed), authParams)
+}
+
+// encrypt verschlüsselt den scopedPDU und liefert die privParams (Salt)
+func (k *usmKeys) encrypt(plain []byte, boots, engineTime int64) ([]byte, []byte, error) {
+	salt := make([]byte, 8)
+	counter := atomic.AddUint64(&saltCounter, 1)
+
+	switch k.priv {
+	case "des":
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], uint32(counter))
+		padded := append([]byte(nil), plain...)
+		if rest := len(padded) % des.BlockSize; rest != 0 {
+			padded = append(padded, make([]byte, des.BlockSize-rest)...)
+		}
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, desIV(k.privKey, salt)).CryptBlocks(out, padded)
+		return out, salt, nil
+	case "aes":
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		binary.BigEndian.PutUint64(salt, counter)
+		out := make([]byte, len(plain))
+		// RFC 3826 schreibt CFB128 vor
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, plain) //nolint:staticcheck
+		return out, salt, nil
+	}
+	return nil, nil, errDecryption
+}
+
+// decrypt entschlüsselt einen scopedPDU mit den privParams der Nachricht
+func (k *usmKeys) decrypt(data, salt []byte, boots, engineTime int64) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, errDecryption
+	}
+
+	switch k.priv {
+	case "des":
+		if len(data) == 0 || len(data)%des.BlockSize != 0 {
+			return nil, errDecryption
+		}
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, desIV(k.privKey, salt)).CryptBlocks(out, data)
+		return out, nil
+	case "aes":
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, data) //nolint:staticcheck
+		return out, nil
+	}
+	return nil, errDecryption
+}
+
+// desIV ist pre-IV (Byte 8-15 des Schlüssels) XOR Salt
+func desIV(privKey, salt []byte) []byte {
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV ist engineBoots || engineTime || Salt
+func aesIV(boots, engineTime int64, salt []byte) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
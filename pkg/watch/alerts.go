@@ -0,0 +1,80 @@
+package watch
+
+import (
+	"net"
+	"time"
+
+	"netspy/pkg/alert"
+)
+
+// SetAlerts verbindet den Monitor mit einem Alert-Bus
+func (m *Monitor) SetAlerts(bus *alert.Bus) {
+	m.alerts = bus
+	m.flaps = alert.NewFlapDetector(0, 0)
+}
+
+// deviceEvent erstellt ein Ereignis für ein Gerät
+func (m *Monitor) deviceEvent(eventType alert.EventType, state *DeviceState, at time.Time) alert.Event {
+	event := alert.NewEvent(eventType, state.Host, state.Status, at)
+	event.Network = m.network
+	event.FlapCount = state.FlapCount
+	return event
+}
+
+// statusEvents erstellt das Ereignis eines Statuswechsels und ggf. ein flapping-Ereignis
+func (m *Monitor) statusEvents(eventType alert.EventType, state *DeviceState, at time.Time) []alert.Event {
+	events := []alert.Event{m.deviceEvent(eventType, state, at)}
+
+	if m.flaps != nil {
+		event := events[0]
+		if m.flaps.Observe(event.DeviceKey(), at) {
+			events = append(events, m.deviceEvent(alert.DeviceFlapping, state, at))
+		}
+	}
+	return events
+}
+
+// publishEvents übergibt Ereignisse an die Empfänger und den Alert-Bus (falls aktiv).
+// Die Bestandsaufnahme des ersten Scans (Initial) wird nicht als Alert gemeldet.
+func (m *Monitor) publishEvents(events []alert.Event) {
+	for _, event := range events {
+		if event.Message == "" {
+			event.Message = event.Summary()
+		}
+		for _, listener := range m.listeners {
+			listener(event)
+		}
+		if m.alerts != nil && !event.Initial {
+			m.alerts.Publish(event)
+		}
+	}
+}
+
+// findMovedFrom sucht die bisherige IP eines Geräts, das in diesem Scan nicht mehr
+// unter ihr geantwortet hat ("" wenn keine)
+func (m *Monitor) findMovedFrom(mac string, currentIPs map[string]bool) string {
+	key := normalizeMAC(mac)
+	if key == "" {
+		return ""
+	}
+	for ipStr, state := range m.deviceStates {
+		if !currentIPs[ipStr] && normalizeMAC(state.Host.MAC) == key {
+			return ipStr
+		}
+	}
+	return ""
+}
+
+// sameMAC vergleicht zwei MAC-Adressen unabhängig von der Schreibweise
+func sameMAC(a, b string) bool {
+	return normalizeMAC(a) == normalizeMAC(b)
+}
+
+// normalizeMAC bringt eine MAC in eine einheitliche Schreibweise ("" wenn ungültig)
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return ""
+	}
+	return hw.String()
+}
@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/scanner"
+)
+
+// certCheckInterval ist der Mindestabstand zwischen zwei Zertifikats-Prüfungen eines Hosts
+const certCheckInterval = time.Hour
+
+// SetCertificates aktiviert die Zertifikats-Inventur: die TLS-Ports der Online-Hosts
+// werden höchstens einmal pro Stunde geprüft, Zertifikate, die innerhalb von
+// warning ablaufen, als cert-expiring gemeldet (0 = alert.DefaultCertWarning)
+func (m *Monitor) SetCertificates(warning time.Duration) error {
+	probe, err := scanner.NewProbe("tls", scanner.Config{Timeout: 500 * time.Millisecond})
+	if err != nil {
+		return err
+	}
+	m.certProbe = probe
+	m.certChecked = make(map[string]time.Time)
+	m.expiry = alert.NewExpiryDetector(warning)
+	return nil
+}
+
+// collectCertificates liest die Zertifikate der Online-Hosts, deren letzte Prüfung
+// länger als certCheckInterval zurückliegt. Die übrigen Hosts behalten ihre
+// bisherigen Zertifikate (siehe updateDeviceStates).
+func (m *Monitor) collectCertificates(ctx context.Context, hosts []scanner.Host, at time.Time) {
+	semaphore := make(chan struct{}, 16)
+	var wg sync.WaitGroup
+
+	for i := range hosts {
+		host := &hosts[i]
+		ipStr := host.IP.String()
+		if !host.Online || at.Sub(m.certChecked[ipStr]) < certCheckInterval {
+			continue
+		}
+		m.certChecked[ipStr] = at
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+			_, _ = m.certProbe.Probe(ctx, host)
+		}()
+	}
+	wg.Wait()
+}
+
+// certificateEvents meldet die Zertifikate eines Geräts, die neu in die Vorwarnzeit
+// fallen oder abgelaufen sind (Aufrufer hält statesMu)
+func (m *Monitor) certificateEvents(state *DeviceState, at time.Time) []alert.Event {
+	if m.expiry == nil {
+		return nil
+	}
+
+	var events []alert.Event
+	for _, svc := range state.Host.Services {
+		cert := svc.Certificate()
+		if cert == nil {
+			continue
+		}
+		event := m.deviceEvent(alert.CertExpiring, state, at)
+		key := event.DeviceKey() + "|" + strconv.Itoa(svc.Port) + "|" + cert.SHA256
+		if !m.expiry.Observe(key, cert.NotAfter, at) {
+			continue
+		}
+		notAfter := cert.NotAfter
+		event.Port = svc.Port
+		event.Certificate = cert.Subject
+		event.NotAfter = &notAfter
+		events = append(events, event)
+	}
+	return events
+}
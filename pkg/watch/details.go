@@ -1,16 +1,18 @@
 package watch
 
 import (
+	"context"
 	"fmt"
 	"net"
-	"os/exec"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"netspy/pkg/discovery"
+	"netspy/pkg/service"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -37,11 +39,13 @@ type HostDetailsModal struct {
 // PortScanResult enthält das Ergebnis eines Port-Scans
 type PortScanResult struct {
 	Port    string
-	Status  string // "open", "closed", "filtered"
+	Status  string // "open", "closed", "filtered", "open|filtered" (UDP ohne Antwort)
 	Service string
 	Banner  string
 	RTT     time.Duration
 	Index   int // Original-Position in der Eingabe
+
+	Certificate *service.Certificate // Server-Zertifikat bei TLS-Ports
 }
 
 // Standard-Ports für Quick-Scan
@@ -251,6 +255,15 @@ func (m *HostDetailsModal) updateDetails() {
 	}
 	sb.WriteString(fmt.Sprintf("[yellow]MAC:[white]       %s\n", mac))
 
+	// IPv6-Adressen desselben Geräts (über die MAC zugeordnet)
+	for i, addr := range m.state.Host.IPv6 {
+		label := "          "
+		if i == 0 {
+			label = "[yellow]IPv6:[white]      "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s [gray](%s)[white]\n", label, addr.IP.String(), addr.Kind))
+	}
+
 	// Vendor
 	vendor := "-"
 	if m.state.Host.Vendor != "" {
@@ -263,8 +276,28 @@ func (m *HostDetailsModal) updateDetails() {
 	if m.state.Host.DeviceType != "" && m.state.Host.DeviceType != "Unknown" {
 		deviceType = m.state.Host.DeviceType
 	}
+	if c := m.state.Host.Classification; c != nil && len(c.Matches) > 0 {
+		deviceType += fmt.Sprintf(" [gray](%d%%)[white]", c.Confidence)
+	}
 	sb.WriteString(fmt.Sprintf("[yellow]Device:[white]    %s\n", deviceType))
 
+	// Warum dieser Typ: ausgelöste Klassifizierungsregeln
+	for i, line := range classificationLines(m.state.Host.Classification) {
+		label := "           "
+		if i == 0 {
+			label = "[yellow]Why:[white]       "
+		}
+		sb.WriteString(label + line + "\n")
+	}
+
+	// Betriebssystem mit Anhaltspunkten (TTL, SYN-ACK, Banner)
+	if hint := m.state.Host.OS; hint != nil && hint.Family != "" {
+		sb.WriteString(fmt.Sprintf("[yellow]OS:[white]        %s [gray](%d%%)[white]\n", hint.Family, hint.Confidence))
+		for _, evidence := range hint.Evidence {
+			sb.WriteString("           [gray]" + tview.Escape(evidence) + "[white]\n")
+		}
+	}
+
 	// Status
 	statusColor := "[green]"
 	if m.state.Status == "offline" {
@@ -291,9 +324,141 @@ func (m *HostDetailsModal) updateDetails() {
 		sb.WriteString("[yellow]Gateway:[white]   [green]Yes[white]\n")
 	}
 
+	// Zertifikate der TLS-Ports (aus dem Scan bzw. dem Port-Scan im Dialog)
+	for i, line := range m.certificateLines() {
+		label := "           "
+		if i == 0 {
+			label = "[yellow]Certs:[white]     "
+		}
+		sb.WriteString(label + line + "\n")
+	}
+
+	// Per DNS-SD beworbene Dienste ("_ipp._tcp "Büro" :631 (HP LaserJet M404)")
+	for i, svc := range m.state.Host.DNSSD {
+		label := "           "
+		if i == 0 {
+			label = "[yellow]DNS-SD:[white]    "
+		}
+		sb.WriteString(label + tview.Escape(svc.String()) + "\n")
+	}
+
+	// UPnP-Gerätebeschreibung ("FRITZ!Box 7590 - AVM Berlin FRITZ!Box 7590 (InternetGatewayDevice)")
+	if upnp := m.state.Host.UPnP; upnp != nil {
+		sb.WriteString("[yellow]UPnP:[white]      " + tview.Escape(upnp.String()) + "\n")
+		if upnp.SerialNumber != "" {
+			sb.WriteString("           Serial " + tview.Escape(upnp.SerialNumber) + "\n")
+		}
+		if names := upnp.ServiceNames(); len(names) > 0 {
+			sb.WriteString("           " + tview.Escape(strings.Join(names, ", ")) + "\n")
+		}
+	}
+
+	// Per LLDP/CDP angekündigte Daten von Switches, Access Points und Telefonen
+	if neighbor := m.state.Host.Neighbor; neighbor != nil {
+		for i, line := range neighborLines(neighbor) {
+			label := "           "
+			if i == 0 {
+				label = fmt.Sprintf("[yellow]%-11s[white]", strings.ToUpper(neighbor.Protocol)+":")
+			}
+			sb.WriteString(label + tview.Escape(line) + "\n")
+		}
+	}
+
+	// DHCP Vendor Class (passiver Modus)
+	if m.state.Host.DHCPVendor != "" {
+		sb.WriteString("[yellow]DHCP:[white]      " + tview.Escape(m.state.Host.DHCPVendor) + "\n")
+	}
+
 	m.detailsView.SetText(sb.String())
 }
 
+// classificationLines erklärt den Gerätetyp: eine Zeile pro ausgelöster Regel mit
+// Gewicht, Typ und Begründung. Regeln für andere Typen sind grau.
+func classificationLines(c *discovery.Classification) []string {
+	if c == nil {
+		return nil
+	}
+	base, _, _ := strings.Cut(c.Type, " (")
+	lines := make([]string, 0, len(c.Matches))
+	for _, match := range c.Matches {
+		color := "[green]"
+		if match.Type != c.Type && match.Type != base {
+			color = "[gray]"
+		}
+		lines = append(lines, fmt.Sprintf("%s+%d %s[white] %s [gray](%s)[white]",
+			color, match.Weight, tview.Escape(match.Type), tview.Escape(match.Reason), tview.Escape(match.Rule)))
+	}
+	return lines
+}
+
+// neighborLines gibt die LLDP/CDP-Daten eines Geräts zeilenweise zurück
+func neighborLines(neighbor *discovery.Neighbor) []string {
+	name := neighbor.SystemName
+	if name == "" {
+		name = neighbor.ChassisID
+	}
+	if neighbor.ManagementIP != nil {
+		name += " (" + neighbor.ManagementIP.String() + ")"
+	}
+	lines := []string{name}
+	if port := neighbor.Port(); port != "" {
+		if neighbor.VLAN > 0 {
+			port += fmt.Sprintf(", VLAN %d", neighbor.VLAN)
+		}
+		lines = append(lines, "Port "+port)
+	}
+	if neighbor.ChassisID != "" && neighbor.ChassisID != neighbor.SystemName {
+		lines = append(lines, "Chassis "+neighbor.ChassisID)
+	}
+	if neighbor.SystemDescription != "" {
+		description, _, _ := strings.Cut(neighbor.SystemDescription, "\n")
+		lines = append(lines, description)
+	}
+	if len(neighbor.Capabilities) > 0 {
+		lines = append(lines, strings.Join(neighbor.Capabilities, ", "))
+	}
+	return lines
+}
+
+// certificateLines gibt pro TLS-Port eine Zeile zurück ("443 nas.local (self-signed, ...)"),
+// rot wenn abgelaufen, gelb wenn es innerhalb von 30 Tagen abläuft
+func (m *HostDetailsModal) certificateLines() []string {
+	certs := make(map[int]*service.Certificate)
+	for _, svc := range m.state.Host.Services {
+		if cert := svc.Certificate(); cert != nil {
+			certs[svc.Port] = cert
+		}
+	}
+	m.scanMu.Lock()
+	for _, result := range m.scanResults {
+		if port, err := strconv.Atoi(result.Port); err == nil && result.Certificate != nil {
+			certs[port] = result.Certificate
+		}
+	}
+	m.scanMu.Unlock()
+
+	ports := make([]int, 0, len(certs))
+	for port := range certs {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	now := time.Now()
+	lines := make([]string, 0, len(ports))
+	for _, port := range ports {
+		cert := certs[port]
+		color := "[white]"
+		switch {
+		case cert.Expired(now):
+			color = "[red]"
+		case cert.ExpiresIn(now) < 30*24*time.Hour:
+			color = "[yellow]"
+		}
+		lines = append(lines, fmt.Sprintf("%d %s%s[white]", port, color, tview.Escape(cert.String())))
+	}
+	return lines
+}
+
 // startPortScan startet einen Port-Scan
 func (m *HostDetailsModal) startPortScan() {
 	if m.scanning {
@@ -326,18 +491,34 @@ func (m *HostDetailsModal) startPortScan() {
 			m.updatePortsTable()
 			m.scanning = false
 			m.scanButton.SetLabel("Scan")
+
+			// Im Port-Scan gefundene Zertifikate in den Details anzeigen
+			for _, result := range results {
+				if result.Certificate != nil {
+					m.updateDetails()
+					break
+				}
+			}
 		})
 	}()
 }
 
 // parsePortList parst eine komma-separierte Port-Liste mit Range-Support
-// Unterstützt: icmp, einzelne Ports (22), Ranges (80-90)
-// Beispiel: "icmp,22,80-90,443" → ["icmp", "22", "80", "81", ..., "90", "443"]
+// Unterstützt: icmp, einzelne Ports (22), Ranges (80-90), "u:" schaltet auf UDP um
+// ("t:" zurück auf TCP), UDP-Ports erhalten die Endung "/udp"
+// Beispiel: "icmp,22,80-90,u:53,161" → ["icmp", "22", "80", ..., "90", "53/udp", "161/udp"]
 func parsePortList(text string) []string {
 	parts := strings.Split(text, ",")
 	var ports []string
+	suffix := ""
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
+		switch prefix := strings.ToLower(p); {
+		case strings.HasPrefix(prefix, "u:"):
+			suffix, p = "/udp", strings.TrimSpace(p[2:])
+		case strings.HasPrefix(prefix, "t:"):
+			suffix, p = "", strings.TrimSpace(p[2:])
+		}
 		if p == "" {
 			continue
 		}
@@ -358,7 +539,7 @@ func parsePortList(text string) []string {
 						end = start + 100
 					}
 					for port := start; port <= end; port++ {
-						ports = append(ports, strconv.Itoa(port))
+						ports = append(ports, strconv.Itoa(port)+suffix)
 					}
 					continue
 				}
@@ -366,7 +547,11 @@ func parsePortList(text string) []string {
 		}
 
 		// Einzelner Port oder "icmp"
-		ports = append(ports, p)
+		if strings.ToLower(p) == "icmp" {
+			ports = append(ports, p)
+		} else {
+			ports = append(ports, p+suffix)
+		}
 	}
 	return ports
 }
@@ -403,17 +588,18 @@ func (m *HostDetailsModal) scanPorts(ports []string) []PortScanResult {
 	return results
 }
 
-// scanSinglePort scannt einen einzelnen Port
+// scanSinglePort scannt einen einzelnen Port und erkennt bei offenen Ports den Dienst
 func (m *HostDetailsModal) scanSinglePort(port string) PortScanResult {
 	result := PortScanResult{
 		Port:    port,
 		Status:  "closed",
-		Service: getServiceName(port),
+		Service: "-",
 		Banner:  "-",
 	}
 
 	// ICMP Ping
 	if strings.ToLower(port) == "icmp" {
+		result.Service = "ping"
 		rtt, ok := m.pingICMP()
 		if ok {
 			result.Status = "open"
@@ -423,6 +609,32 @@ func (m *HostDetailsModal) scanSinglePort(port string) PortScanResult {
 		return result
 	}
 
+	// UDP: Anfrage passend zum Protokoll, Zustand aus Antwort bzw. ICMP-Fehler
+	if udpPort, ok := strings.CutSuffix(port, "/udp"); ok {
+		portNum, _ := strconv.Atoi(udpPort)
+		if name := service.WellKnownUDP(portNum); name != "" {
+			result.Service = name
+		}
+		detector := service.Detector{Timeout: 2 * time.Second}
+		udp := detector.DetectUDP(context.Background(), net.ParseIP(m.ipStr), portNum)
+		result.Status = udp.State
+		if udp.State == service.PortOpen {
+			result.RTT = udp.RTT
+			if udp.Service.Name != "" {
+				result.Service = udp.Service.Name
+			}
+			if summary := udp.Service.Summary(); summary != "" {
+				result.Banner = truncateBanner(summary)
+			}
+		}
+		return result
+	}
+
+	portNum, _ := strconv.Atoi(port)
+	if name := service.WellKnown(portNum); name != "" {
+		result.Service = name
+	}
+
 	// TCP Port Scan
 	start := time.Now()
 	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.ipStr, port), 2*time.Second)
@@ -432,86 +644,37 @@ func (m *HostDetailsModal) scanSinglePort(port string) PortScanResult {
 		}
 		return result
 	}
-	defer conn.Close()
+	conn.Close()
 
 	result.Status = "open"
 	result.RTT = time.Since(start)
 
-	// Einfaches Banner Grabbing für bekannte Ports
-	result.Banner = m.grabBanner(conn, port)
-
-	return result
-}
-
-// pingICMP führt einen ICMP Ping durch
-func (m *HostDetailsModal) pingICMP() (time.Duration, bool) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("ping", "-n", "1", "-w", "2000", m.ipStr)
-	case "darwin":
-		cmd = exec.Command("ping", "-c", "1", "-W", "2000", m.ipStr)
-	default:
-		cmd = exec.Command("ping", "-c", "1", "-W", "2", m.ipStr)
+	// Dienst, Produkt und Version erkennen (Begrüßung bzw. Protokoll-Probes)
+	detector := service.Detector{Timeout: 2 * time.Second, GreetingWait: time.Second}
+	svc := detector.Detect(context.Background(), net.ParseIP(m.ipStr), portNum)
+	if svc.Name != "" {
+		result.Service = svc.Name
 	}
+	if summary := svc.Summary(); summary != "" {
+		result.Banner = truncateBanner(summary)
+	}
+	result.Certificate = svc.Certificate()
 
-	start := time.Now()
-	err := cmd.Run()
-	rtt := time.Since(start)
-
-	return rtt, err == nil
+	return result
 }
 
-// grabBanner versucht ein Banner vom Service zu lesen
-func (m *HostDetailsModal) grabBanner(conn net.Conn, port string) string {
-	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
-
-	buf := make([]byte, 256)
-	n, err := conn.Read(buf)
-	if err != nil || n == 0 {
-		return "-"
-	}
-
-	banner := strings.TrimSpace(string(buf[:n]))
-	// Nur erste Zeile, max 30 Zeichen
-	if idx := strings.Index(banner, "\n"); idx > 0 {
-		banner = banner[:idx]
-	}
-	if len(banner) > 30 {
-		banner = banner[:30] + "..."
+// truncateBanner kürzt ein Banner auf 40 Zeichen, damit die Tabelle ins Modal passt
+func truncateBanner(banner string) string {
+	if len(banner) > 40 {
+		return banner[:40] + "..."
 	}
 	return banner
 }
 
-// getServiceName gibt den bekannten Service-Namen für einen Port zurück
-func getServiceName(port string) string {
-	services := map[string]string{
-		"icmp": "ping",
-		"21":   "ftp",
-		"22":   "ssh",
-		"23":   "telnet",
-		"25":   "smtp",
-		"53":   "dns",
-		"80":   "http",
-		"110":  "pop3",
-		"143":  "imap",
-		"443":  "https",
-		"445":  "smb",
-		"993":  "imaps",
-		"995":  "pop3s",
-		"3306": "mysql",
-		"3389": "rdp",
-		"5432": "postgres",
-		"5900": "vnc",
-		"8080": "http-alt",
-		"8443": "https-alt",
-	}
-
-	if name, ok := services[strings.ToLower(port)]; ok {
-		return name
-	}
-	return "-"
+// pingICMP führt einen ICMP Ping über die gemeinsame ICMP-Engine durch
+func (m *HostDetailsModal) pingICMP() (time.Duration, bool) {
+	rtt, _, err := discovery.SharedICMPEngine().Ping(context.Background(), net.ParseIP(m.ipStr), 2*time.Second)
+	return rtt, err == nil
 }
 
 // updatePortsTable aktualisiert die Port-Ergebnis-Tabelle
@@ -531,7 +694,7 @@ func (m *HostDetailsModal) updatePortsTable() {
 				switch s {
 				case "open":
 					return 0
-				case "filtered":
+				case "filtered", "open|filtered":
 					return 1
 				default:
 					return 2
@@ -558,7 +721,7 @@ func (m *HostDetailsModal) updatePortsTable() {
 		if result.Status == "open" {
 			statusColor = tcell.ColorGreen
 			statusSymbol = "✓"
-		} else if result.Status == "filtered" {
+		} else if result.Status == "filtered" || result.Status == "open|filtered" {
 			statusColor = tcell.ColorYellow
 			statusSymbol = "?"
 		}
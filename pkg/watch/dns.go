@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
 )
 
 // PopulateFromDNSCache fills deviceStates with cached DNS names
@@ -19,17 +20,71 @@ func PopulateFromDNSCache(deviceStates map[string]*DeviceState) {
 			if state.Host.Hostname == "" {
 				state.Host.Hostname = hostname
 				state.Host.HostnameSource = "dns-cache"
-				state.Host.DeviceType = discovery.DetectDeviceType(
-					state.Host.Hostname,
-					state.Host.MAC,
-					state.Host.Vendor,
-					state.Host.Ports,
-				)
+				scanner.Classify(&state.Host)
 			}
 		}
 	}
 }
 
+// collectDNSSD attaches services advertised via DNS-SD (AirPlay, IPP, Chromecast, ...)
+// to online hosts and updates their device type and, if missing, their hostname
+func collectDNSSD(ctx context.Context, hosts []scanner.Host) {
+	services, err := discovery.BrowseDNSSD(ctx, 2*time.Second)
+	if err != nil || len(services) == 0 {
+		return
+	}
+	probe := scanner.NewDNSSDProbe(services)
+	for i := range hosts {
+		if hosts[i].Online {
+			_, _ = probe.Probe(ctx, &hosts[i])
+		}
+	}
+}
+
+// upnpCheckInterval is the minimum time between two SSDP searches in watch mode
+const upnpCheckInterval = 10 * time.Minute
+
+// collectUPnP searches for UPnP devices via SSDP and attaches their device
+// description (friendly name, manufacturer, model, services) to online hosts.
+// Hosts not searched in this scan keep their description (see updateDeviceStates).
+func (m *Monitor) collectUPnP(ctx context.Context, hosts []scanner.Host, at time.Time) {
+	if at.Sub(m.upnpChecked) < upnpCheckInterval {
+		return
+	}
+	m.upnpChecked = at
+
+	devices, err := discovery.DiscoverSSDPDevices(2 * time.Second)
+	if err != nil || len(devices) == 0 {
+		return
+	}
+	known := make(map[string]discovery.SSDPDevice, len(devices))
+	for _, device := range devices {
+		known[device.IP] = device
+	}
+	probe := scanner.NewSSDPProbe(known)
+
+	semaphore := make(chan struct{}, 16)
+	var wg sync.WaitGroup
+	for i := range hosts {
+		host := &hosts[i]
+		if _, found := known[host.IP.String()]; !found || !host.Online {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+			_, _ = probe.Probe(ctx, host)
+		}()
+	}
+	wg.Wait()
+}
+
 // PerformInitialDNSLookups performs fast DNS lookups immediately after scan
 func PerformInitialDNSLookups(ctx context.Context, deviceStates map[string]*DeviceState) {
 	var wg sync.WaitGroup
@@ -55,7 +110,7 @@ func PerformInitialDNSLookups(ctx context.Context, deviceStates map[string]*Devi
 						s.Host.Hostname = hostname
 						s.Host.HostnameSource = "dns"
 						s.LastHostnameLookup = time.Now()
-						s.Host.DeviceType = discovery.DetectDeviceType(s.Host.Hostname, s.Host.MAC, s.Host.Vendor, s.Host.Ports)
+						scanner.Classify(&s.Host)
 					}
 				}
 			}
@@ -102,7 +157,7 @@ func PerformBackgroundDNSLookups(ctx context.Context, deviceStates map[string]*D
 				}
 			}
 			if s.Host.Hostname != "" || s.Host.HostnameSource != "" {
-				s.Host.DeviceType = discovery.DetectDeviceType(s.Host.Hostname, s.Host.MAC, s.Host.Vendor, s.Host.Ports)
+				scanner.Classify(&s.Host)
 			}
 		}(ipStr, state)
 	}
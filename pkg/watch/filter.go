@@ -0,0 +1,51 @@
+package watch
+
+import (
+	"maps"
+	"strconv"
+
+	"netspy/pkg/filter"
+	"netspy/pkg/output"
+)
+
+// FilterAliases sind die Kurzformen der Filter-Felder im Watch-Modus
+// (die von "netspy scan --filter" plus Status, Uptime und Flaps)
+var FilterAliases = withWatchFields(output.FilterAliases, map[string]string{
+	"s":    "status",
+	"flap": "flaps",
+	"up":   "uptime",
+})
+
+// FilterTypes sind die typisierten Filter-Felder (alle anderen sind Text)
+var FilterTypes = withWatchFields(output.FilterTypes, map[string]filter.FieldType{
+	"uptime": filter.TypeDuration,
+	"flaps":  filter.TypeNumber,
+})
+
+// withWatchFields ergänzt eine Kopie der Scan-Felder um die des Watch-Modus,
+// damit beide Filter nicht auseinanderlaufen
+func withWatchFields[V any](scan, watch map[string]V) map[string]V {
+	fields := maps.Clone(scan)
+	maps.Copy(fields, watch)
+	return fields
+}
+
+// NewFilter erstellt einen Filter mit den Feldern und Kurzformen des Watch-Modus
+// (gemeinsam genutzt von tview-Oberfläche und Web-Dashboard)
+func NewFilter(expression string) *filter.Filter {
+	return filter.New(expression).
+		WithIPField("ip").
+		WithAliases(FilterAliases).
+		WithTypes(FilterTypes)
+}
+
+// FilterFields gibt die Filter-Felder eines Geräts zurück (die Host-Felder wie bei
+// "netspy scan --filter" plus Status, Uptime und Flaps).
+// uptime ist wie in der Tabelle bei Offline-Geräten die Downtime.
+func FilterFields(device DeviceSnapshot) map[string]string {
+	fields := output.HostFields(device.Host)
+	fields["status"] = device.Status
+	fields["uptime"] = device.Uptime.String()
+	fields["flaps"] = strconv.Itoa(device.FlapCount)
+	return fields
+}
@@ -0,0 +1,41 @@
+package watch_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/scanner"
+	"netspy/pkg/watch"
+)
+
+var _ = Describe("Filter", func() {
+	device := watch.DeviceSnapshot{
+		Host: scanner.Host{
+			IP:       []byte{192, 168, 1, 10},
+			Ports:    []int{22, 8080},
+			UDPPorts: []int{5353},
+		},
+		Status:    "online",
+		Uptime:    2 * time.Hour,
+		FlapCount: 3,
+	}
+
+	matches := func(expression string) bool {
+		f := watch.NewFilter(expression)
+		Expect(f.Compile()).To(Succeed())
+		return f.Match(watch.FilterFields(device))
+	}
+
+	It("should compare UDP ports as a list like the scan filter", func() {
+		Expect(matches("udp=53")).To(BeFalse())
+		Expect(matches("udp=5353")).To(BeTrue())
+		Expect(matches("u=5353")).To(BeTrue())
+	})
+
+	It("should keep the watch fields", func() {
+		Expect(matches("flaps>2 && uptime>1h")).To(BeTrue())
+		Expect(matches("s=online")).To(BeTrue())
+	})
+})
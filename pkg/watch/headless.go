@@ -0,0 +1,98 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"netspy/pkg/alert"
+)
+
+// Headless ist der Watch-Modus ohne Oberfläche. Jede Zustandsänderung wird als
+// JSON-Objekt in eine eigene Zeile geschrieben (NDJSON), optional gefolgt von einem
+// vollständigen Snapshot nach jedem Scan:
+//
+//	{"type":"device-offline","time":"...","ip":"192.168.1.20",...}
+//	{"type":"snapshot","time":"...","scan":3,"online":12,"offline":1,"devices":[...]}
+type Headless struct {
+	*Monitor
+	snapshots bool
+
+	// AfterScan wird nach jedem Scan aufgerufen, wenn die Hostnamen aufgelöst sind
+	// (z.B. für den Metrics-Textfile-Export)
+	AfterScan func()
+
+	mu     sync.Mutex
+	enc    *json.Encoder
+	err    error // Erster Schreibfehler
+	cancel context.CancelFunc
+}
+
+// snapshotRecord ist die Ausgabezeile eines Snapshots
+type snapshotRecord struct {
+	Type string `json:"type"` // immer "snapshot"
+	Snapshot
+}
+
+// NewHeadless erstellt den Headless-Modus für einen Monitor. Bei snapshots=true wird
+// nach jedem Scan zusätzlich der vollständige Zustand ausgegeben.
+func NewHeadless(monitor *Monitor, out io.Writer, snapshots bool) *Headless {
+	h := &Headless{
+		Monitor:   monitor,
+		snapshots: snapshots,
+		enc:       json.NewEncoder(out),
+	}
+	monitor.OnEvent(h.writeEvent)
+	return h
+}
+
+// Run scannt bis der Context beendet wird. Gibt den ersten Schreibfehler zurück
+// (z.B. volle Platte oder geschlossene Pipe) - danach wird nicht weiter gescannt.
+func (h *Headless) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h.mu.Lock()
+	h.cancel = cancel
+	h.mu.Unlock()
+
+	h.Monitor.Run(ctx, func() {
+		// Hostnamen sofort auflösen (im UI-Modus läuft das im Hintergrund)
+		h.ResolveHostnames(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if h.snapshots {
+			h.write(snapshotRecord{Type: "snapshot", Snapshot: h.Snapshot()})
+		}
+		if h.AfterScan != nil {
+			h.AfterScan()
+		}
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// writeEvent schreibt ein Ereignis
+func (h *Headless) writeEvent(event alert.Event) {
+	h.write(event)
+}
+
+// write schreibt eine Zeile und bricht bei einem Schreibfehler den Scan-Loop ab
+func (h *Headless) write(record interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err != nil {
+		return
+	}
+	if err := h.enc.Encode(record); err != nil {
+		h.err = err
+		if h.cancel != nil {
+			h.cancel()
+		}
+	}
+}
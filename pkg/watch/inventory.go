@@ -0,0 +1,109 @@
+package watch
+
+import (
+	"net"
+	"time"
+
+	"netspy/pkg/inventory"
+	"netspy/pkg/scanner"
+)
+
+// SetInventory aktiviert das persistente Geräte-Inventar und lädt die bekannten Geräte
+// des Netzwerks (First Seen, Flap-Zähler, Offline-Zeit und Hostname bleiben so über
+// Neustarts hinweg erhalten). Die Datenbank wird nur kurz für jeden Zugriff geöffnet,
+// damit parallel laufende "netspy scan --record" Aufrufe nicht blockiert werden.
+func (m *Monitor) SetInventory(path string) error {
+	store, err := inventory.Open(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	devices, err := store.DevicesInNetwork(m.netCIDR)
+	if err != nil {
+		return err
+	}
+
+	// Mehrere Einträge können dieselbe aktuelle IP haben (z.B. ein Host, der erst ohne
+	// und später mit MAC gesehen wurde) - pro IP wird nur einer wiederhergestellt
+	byIP := make(map[string]inventory.Device, len(devices))
+	for _, device := range devices {
+		ip := device.CurrentIP()
+		if existing, ok := byIP[ip]; !ok || preferInventoryDevice(device, existing) {
+			byIP[ip] = device
+		}
+	}
+
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	for _, device := range byIP {
+		state := deviceStateFromInventory(device)
+		m.deviceStates[state.Host.IP.String()] = state
+	}
+
+	m.inventoryPath = path
+	m.inventoryKnown = len(devices) > 0
+	return nil
+}
+
+// preferInventoryDevice entscheidet, ob candidate bei gleicher IP Vorrang vor existing hat:
+// Einträge mit MAC schlagen "ip:"-Einträge, sonst gewinnt der zuletzt gesehene
+func preferInventoryDevice(candidate, existing inventory.Device) bool {
+	if (candidate.MAC != "") != (existing.MAC != "") {
+		return candidate.MAC != ""
+	}
+	return candidate.LastSeen.After(existing.LastSeen)
+}
+
+// deviceStateFromInventory erzeugt einen DeviceState aus einem Inventar-Eintrag.
+// FirstSeenScan bleibt 0, damit bekannte Geräte nicht als "neu" markiert werden.
+func deviceStateFromInventory(device inventory.Device) *DeviceState {
+	hostname, source := device.CurrentHostname()
+
+	return &DeviceState{
+		Host: scanner.Host{
+			IP:             net.ParseIP(device.CurrentIP()),
+			Hostname:       hostname,
+			HostnameSource: source,
+			MAC:            device.MAC,
+			Vendor:         device.Vendor,
+			DeviceType:     device.DeviceType,
+			Online:         device.Status == "online",
+		},
+		FirstSeen:        device.FirstSeen,
+		LastSeen:         device.LastSeen,
+		Status:           device.Status,
+		StatusSince:      device.StatusSince,
+		FlapCount:        device.FlapCount,
+		TotalOfflineTime: device.TotalOfflineTime,
+	}
+}
+
+// recordInventory schreibt den aktuellen Stand nach einem Scan ins Inventar.
+// Verwendet werden die Hosts aus den Device-States (inkl. bereits aufgelöster Hostnamen).
+func (m *Monitor) recordInventory(scanStart time.Time) {
+	if m.inventoryPath == "" {
+		return
+	}
+
+	m.statesMu.RLock()
+	hosts := make([]scanner.Host, 0, len(m.deviceStates))
+	for _, state := range m.deviceStates {
+		if state.Status == "online" {
+			host := state.Host
+			host.Online = true
+			hosts = append(hosts, host)
+		}
+	}
+	m.statesMu.RUnlock()
+
+	store, err := inventory.Open(m.inventoryPath)
+	if err != nil {
+		// Gesperrt oder nicht verfügbar - beim nächsten Scan erneut versuchen
+		return
+	}
+	defer store.Close()
+
+	_, _ = store.Record(inventory.Scan{Time: scanStart, Source: "watch", Mode: m.mode}, m.netCIDR, hosts)
+}
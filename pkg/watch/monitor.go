@@ -0,0 +1,501 @@
+package watch
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
+)
+
+// Monitor führt die periodischen Scans durch und verfolgt den Zustand der Geräte.
+// Er enthält keine UI - die tview-Oberfläche und der Headless-Modus bauen beide darauf auf.
+type Monitor struct {
+	// State
+	deviceStates map[string]*DeviceState
+	statesMu     sync.RWMutex
+	network      string
+	netCIDR      *net.IPNet
+	interval     time.Duration
+	mode         string
+	scanCount    int
+	scanDuration time.Duration
+	lastScan     time.Time
+	isLocal      bool
+	ipv6         bool // IPv6-Nachbarn suchen und den Hosts zuordnen
+
+	inventoryPath  string // Inventar-Datenbank ("" = deaktiviert)
+	inventoryKnown bool   // Das Inventar kannte beim Start schon Geräte dieses Netzwerks
+
+	// Alerting (nil = deaktiviert)
+	alerts *alert.Bus
+	flaps  *alert.FlapDetector
+
+	// Zusätzliche Port-Prüfung (nil = deaktiviert, siehe SetPorts)
+	portProbe scanner.Probe
+
+	// Zertifikats-Inventur (nil = deaktiviert, siehe SetCertificates)
+	certProbe   scanner.Probe
+	certChecked map[string]time.Time // Letzte Prüfung pro IP
+	expiry      *alert.ExpiryDetector
+
+	// Letzte SSDP-Suche samt Abruf der UPnP-Gerätebeschreibungen (siehe collectUPnP)
+	upnpChecked time.Time
+
+	// Passiver Modus: Geräte aus mitgeschnittenem Verkehr (nil = aktiv scannen, siehe SetPassive)
+	passive       *scanner.PassiveCollector
+	passiveWindow time.Duration
+
+	// Per LLDP/CDP angekündigte Nachbarn (nil = deaktiviert, siehe SetNeighbors)
+	neighbors *discovery.NeighborListener
+
+	// Empfänger aller Zustandsänderungen (inkl. der Geräte des ersten Scans)
+	listeners []func(alert.Event)
+
+	// Sofortiger Scan außerhalb des Intervalls (siehe TriggerScan)
+	trigger chan struct{}
+
+	// Thread tracking
+	activeThreads int32
+	threadConfig  ThreadConfig
+}
+
+// NewMonitor erstellt einen Monitor für ein Netzwerk
+func NewMonitor(network string, netCIDR *net.IPNet, mode string, interval time.Duration, maxThreads int) *Monitor {
+	// Check if local subnet
+	isLocal, _ := IsLocalSubnet(netCIDR)
+
+	return &Monitor{
+		deviceStates: make(map[string]*DeviceState),
+		network:      network,
+		netCIDR:      netCIDR,
+		interval:     interval,
+		mode:         mode,
+		isLocal:      isLocal,
+		ipv6:         true,
+		threadConfig: CalculateThreads(netCIDR, maxThreads),
+		trigger:      make(chan struct{}, 1),
+	}
+}
+
+// SetIPv6Discovery aktiviert oder deaktiviert die IPv6-Nachbarsuche (Standard: aktiv)
+func (m *Monitor) SetIPv6Discovery(enabled bool) {
+	m.ipv6 = enabled
+}
+
+// OnEvent registriert einen Empfänger für alle Zustandsänderungen. Anders als der
+// Alert-Bus erhält er auch die Geräte des ersten Scans (Event.Initial = true).
+// Muss vor dem ersten Scan aufgerufen werden.
+func (m *Monitor) OnEvent(fn func(alert.Event)) {
+	m.listeners = append(m.listeners, fn)
+}
+
+// Network gibt das überwachte Netzwerk zurück
+func (m *Monitor) Network() string {
+	return m.network
+}
+
+// Interval gibt das Scan-Intervall zurück
+func (m *Monitor) Interval() time.Duration {
+	return m.interval
+}
+
+// InventoryPath gibt den Pfad der Inventar-Datenbank zurück ("" = deaktiviert)
+func (m *Monitor) InventoryPath() string {
+	return m.inventoryPath
+}
+
+// TriggerScan fordert einen sofortigen Scan an (wirkt nur in Run). Gibt false zurück,
+// wenn bereits ein Scan angefordert ist.
+func (m *Monitor) TriggerScan() bool {
+	select {
+	case m.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stats beschreibt den Stand des Monitors
+type Stats struct {
+	Network       string        `json:"network"`
+	Mode          string        `json:"mode"`
+	Interval      time.Duration `json:"interval"`
+	Scans         int           `json:"scans"`
+	LastScan      time.Time     `json:"last_scan"`
+	ScanDuration  time.Duration `json:"scan_duration"`
+	Devices       int           `json:"devices"`
+	Online        int           `json:"online"`
+	Offline       int           `json:"offline"`
+	ActiveThreads int32         `json:"active_threads"`
+
+	Uplink *discovery.Neighbor `json:"uplink,omitempty"` // Switch-Port des eigenen Interfaces (LLDP/CDP)
+}
+
+// Stats gibt Scan-Statistiken und Gerätezahlen zurück
+func (m *Monitor) Stats() Stats {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+
+	stats := Stats{
+		Network:       m.network,
+		Mode:          m.mode,
+		Interval:      m.interval,
+		Scans:         m.scanCount,
+		LastScan:      m.lastScan,
+		ScanDuration:  m.scanDuration,
+		Devices:       len(m.deviceStates),
+		ActiveThreads: atomic.LoadInt32(&m.activeThreads),
+		Uplink:        m.Uplink(),
+	}
+	for _, state := range m.deviceStates {
+		if state.Status == "online" {
+			stats.Online++
+		} else {
+			stats.Offline++
+		}
+	}
+	return stats
+}
+
+// Run führt den ersten Scan sofort und danach einen Scan pro Intervall durch, bis
+// der Context beendet wird. afterScan (optional) wird nach jedem Scan aufgerufen.
+func (m *Monitor) Run(ctx context.Context, afterScan func()) {
+	scan := func() {
+		if m.Scan(ctx) && afterScan != nil {
+			afterScan()
+		}
+	}
+
+	// Erster Scan sofort
+	scan()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scan()
+		case <-m.trigger:
+			scan()
+			ticker.Reset(m.interval)
+		}
+	}
+}
+
+// Scan führt einen einzelnen Scan durch, aktualisiert die Device-States und speichert
+// sie im Inventar. Gibt false zurück, wenn der Scan abgebrochen wurde.
+func (m *Monitor) Scan(ctx context.Context) bool {
+	scanStart := time.Now()
+
+	// Passiv nur die mitgeschnittenen Geräte übernehmen, sonst scannen
+	var hosts []scanner.Host
+	if m.passive != nil {
+		hosts = m.passiveHosts(scanStart)
+	} else {
+		hosts = m.activeScan(ctx, scanStart)
+	}
+	m.attachNeighbors(hosts)
+
+	// Check if cancelled
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// Stats und Snapshot lesen die Dauer parallel (z.B. über die HTTP-API)
+	m.statesMu.Lock()
+	m.scanDuration = time.Since(scanStart)
+	m.statesMu.Unlock()
+
+	// Device States aktualisieren und im Inventar speichern
+	m.Update(hosts, scanStart)
+	m.recordInventory(scanStart)
+
+	// DNS-Cache vorab laden
+	m.statesMu.Lock()
+	PopulateFromDNSCache(m.deviceStates)
+	m.statesMu.Unlock()
+
+	return true
+}
+
+// activeScan scannt das Netzwerk und ergänzt IPv6-Adressen, DNS-SD-Dienste,
+// UPnP-Beschreibungen, Ports und Zertifikate
+func (m *Monitor) activeScan(ctx context.Context, scanStart time.Time) []scanner.Host {
+	hosts := PerformScanQuiet(ctx, m.network, m.netCIDR, m.mode, &m.activeThreads, m.threadConfig)
+
+	// IPv6-Adressen der Geräte ergänzen (nur lokal - NDP funktioniert nicht über Router)
+	if m.ipv6 && m.isLocal && ctx.Err() == nil {
+		hosts, _ = scanner.DiscoverIPv6(ctx, m.netCIDR, hosts, time.Second)
+	}
+
+	// Per DNS-SD beworbene Dienste (nur lokal - mDNS wird nicht geroutet)
+	if m.isLocal && ctx.Err() == nil {
+		collectDNSSD(ctx, hosts)
+	}
+
+	// UPnP-Gerätebeschreibungen der per SSDP gefundenen Geräte (nur lokal, alle 10 Minuten)
+	if m.isLocal && ctx.Err() == nil {
+		m.collectUPnP(ctx, hosts, scanStart)
+	}
+
+	// Ports aus --ports prüfen (vor den Zertifikaten, die auch offene Ports lesen)
+	if m.portProbe != nil && ctx.Err() == nil {
+		m.collectPorts(ctx, hosts)
+	}
+
+	// Zertifikate der TLS-Ports lesen
+	if m.certProbe != nil && ctx.Err() == nil {
+		m.collectCertificates(ctx, hosts, scanStart)
+	}
+
+	return hosts
+}
+
+// ResolveHostnames löst die noch fehlenden Hostnamen auf (blockiert bis zum Ende).
+// Im passiven Modus entfällt das, da dafür DNS-Anfragen nötig wären.
+func (m *Monitor) ResolveHostnames(ctx context.Context) {
+	if m.passive != nil {
+		return
+	}
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+	PerformInitialDNSLookups(ctx, m.deviceStates)
+}
+
+// DeviceSnapshot ist der Zustand eines Geräts zu einem Zeitpunkt
+type DeviceSnapshot struct {
+	scanner.Host
+	Status           string        `json:"status"`
+	FirstSeen        time.Time     `json:"first_seen"`
+	LastSeen         time.Time     `json:"last_seen"`
+	StatusSince      time.Time     `json:"status_since"`
+	FlapCount        int           `json:"flap_count"`
+	TotalOfflineTime time.Duration `json:"total_offline_time"`
+	Uptime           time.Duration `json:"uptime"` // Online: Zeit seit First Seen ohne Ausfälle, offline: Dauer des Ausfalls
+	New              bool          `json:"new"`    // In einem der letzten beiden Scans neu aufgetaucht
+}
+
+// Snapshot ist der Zustand aller Geräte nach einem Scan
+type Snapshot struct {
+	Time     time.Time        `json:"time"`
+	Network  string           `json:"network"`
+	Scan     int              `json:"scan"`
+	Duration time.Duration    `json:"duration"`
+	Online   int              `json:"online"`
+	Offline  int              `json:"offline"`
+	Devices  []DeviceSnapshot `json:"devices"`
+}
+
+// Snapshot erstellt eine Kopie des aktuellen Zustands (nach IP sortiert)
+func (m *Monitor) Snapshot() Snapshot {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+
+	snapshot := Snapshot{
+		Time:     time.Now(),
+		Network:  m.network,
+		Scan:     m.scanCount,
+		Duration: m.scanDuration,
+		Devices:  make([]DeviceSnapshot, 0, len(m.deviceStates)),
+	}
+
+	for _, state := range m.deviceStates {
+		if state.Status == "online" {
+			snapshot.Online++
+		} else {
+			snapshot.Offline++
+		}
+		snapshot.Devices = append(snapshot.Devices, m.deviceSnapshot(state, snapshot.Time))
+	}
+
+	sort.Slice(snapshot.Devices, func(i, j int) bool {
+		return CompareIPs(snapshot.Devices[i].IP.String(), snapshot.Devices[j].IP.String())
+	})
+
+	return snapshot
+}
+
+// deviceSnapshot erstellt den Snapshot eines Geräts (Aufrufer hält statesMu)
+func (m *Monitor) deviceSnapshot(state *DeviceState, referenceTime time.Time) DeviceSnapshot {
+	return DeviceSnapshot{
+		Host:             state.Host,
+		Status:           state.Status,
+		FirstSeen:        state.FirstSeen,
+		LastSeen:         state.LastSeen,
+		StatusSince:      state.StatusSince,
+		FlapCount:        state.FlapCount,
+		TotalOfflineTime: state.TotalOfflineTime,
+		Uptime:           statusDuration(state, referenceTime),
+		New:              state.FirstSeenScan > 1 && m.scanCount-state.FirstSeenScan < 2,
+	}
+}
+
+// statusDuration berechnet die Uptime (online) bzw. Downtime (offline) eines Geräts
+func statusDuration(state *DeviceState, referenceTime time.Time) time.Duration {
+	if state.Status == "online" {
+		return referenceTime.Sub(state.FirstSeen) - state.TotalOfflineTime
+	}
+	return referenceTime.Sub(state.StatusSince)
+}
+
+// Update übernimmt ein Scan-Ergebnis in die Device-States (zählt als ein Scan) und
+// meldet die dabei erkannten Ereignisse an die Empfänger und den Alert-Bus
+func (m *Monitor) Update(hosts []scanner.Host, scanStart time.Time) {
+	events := m.updateDeviceStates(hosts, scanStart)
+	m.publishEvents(events)
+}
+
+// updateDeviceStates aktualisiert die Device-States basierend auf Scan-Ergebnissen
+// und gibt die dabei erkannten Ereignisse zurück
+func (m *Monitor) updateDeviceStates(hosts []scanner.Host, scanStart time.Time) []alert.Event {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	m.scanCount++
+	m.lastScan = scanStart
+
+	currentIPs := make(map[string]bool)
+	movedMACs := make(map[string]bool) // MACs mit IP-Wechsel (alte IP nicht als offline melden)
+	var added []string
+	var events []alert.Event
+
+	for _, host := range hosts {
+		ipStr := host.IP.String()
+
+		if host.Online {
+			currentIPs[ipStr] = true
+		} else {
+			continue
+		}
+
+		state, exists := m.deviceStates[ipStr]
+
+		if exists {
+			state.LastSeen = scanStart
+
+			// Preserve hostname if already resolved
+			oldHostname := state.Host.Hostname
+			oldSource := state.Host.HostnameSource
+			oldRTT := state.Host.RTT
+			oldIPv6 := state.Host.IPv6
+			oldServices := state.Host.Services
+			oldDNSSD := state.Host.DNSSD
+			oldUPnP := state.Host.UPnP
+			oldOS := state.Host.OS
+			oldMAC := state.Host.MAC
+
+			state.Host = host
+
+			// IPv6-Adressen behalten, wenn die Nachbarsuche sie diesmal nicht geliefert hat
+			if len(state.Host.IPv6) == 0 {
+				state.Host.IPv6 = oldIPv6
+			}
+
+			// Dienste und Zertifikate behalten, wenn sie in diesem Scan nicht geprüft wurden
+			if len(state.Host.Services) == 0 {
+				state.Host.Services = oldServices
+			}
+
+			// Passiv liefert der Collector bereits den besten mitgeschnittenen Namen
+			if oldSource != "" && (m.passive == nil || state.Host.Hostname == "") {
+				state.Host.Hostname = oldHostname
+				state.Host.HostnameSource = oldSource
+			}
+
+			// DNS-SD-Dienste behalten, wenn das Gerät diesmal nicht geantwortet hat
+			if len(state.Host.DNSSD) == 0 && len(oldDNSSD) > 0 {
+				state.Host.DNSSD = oldDNSSD
+				scanner.Classify(&state.Host)
+			}
+
+			// UPnP-Beschreibung behalten - sie wird nur alle 10 Minuten abgerufen
+			if state.Host.UPnP == nil && oldUPnP != nil {
+				state.Host.UPnP = oldUPnP
+				scanner.Classify(&state.Host)
+			}
+
+			// OS-Hinweis behalten - TTL und SYN-ACK liefert nicht jeder Scan
+			if state.Host.OS == nil && oldOS != nil {
+				state.Host.OS = oldOS
+				scanner.Classify(&state.Host)
+			}
+
+			if state.Host.RTT == 0 && oldRTT > 0 {
+				state.Host.RTT = oldRTT
+			}
+
+			if oldMAC != "" && host.MAC != "" && !sameMAC(oldMAC, host.MAC) {
+				event := m.deviceEvent(alert.MACChanged, state, scanStart)
+				event.Old = oldMAC
+				events = append(events, event)
+			}
+
+			if state.Status == "offline" {
+				offlineDuration := scanStart.Sub(state.StatusSince)
+				state.TotalOfflineTime += offlineDuration
+				state.Status = "online"
+				state.StatusSince = scanStart
+				state.FlapCount++
+				events = append(events, m.statusEvents(alert.DeviceBack, state, scanStart)...)
+			}
+		} else {
+			m.deviceStates[ipStr] = &DeviceState{
+				Host:          host,
+				FirstSeen:     scanStart,
+				FirstSeenScan: m.scanCount,
+				LastSeen:      scanStart,
+				Status:        "online",
+				StatusSince:   scanStart,
+			}
+			added = append(added, ipStr)
+		}
+	}
+
+	// Neue IPs: IP-Wechsel, wenn dieselbe MAC bisher unter einer jetzt fehlenden IP lief
+	for _, ipStr := range added {
+		state := m.deviceStates[ipStr]
+		if oldIP := m.findMovedFrom(state.Host.MAC, currentIPs); oldIP != "" {
+			movedMACs[normalizeMAC(state.Host.MAC)] = true
+			event := m.deviceEvent(alert.IPChanged, state, scanStart)
+			event.Old = oldIP
+			events = append(events, event)
+		} else {
+			event := m.deviceEvent(alert.DeviceNew, state, scanStart)
+			// Beim ersten Scan ohne bekannte Geräte (kein oder leeres Inventar) ist jedes
+			// Gerät "neu" - nur Bestandsaufnahme
+			event.Initial = m.scanCount <= 1 && !m.inventoryKnown
+			events = append(events, event)
+		}
+	}
+
+	// Ablaufende Zertifikate der erreichbaren Geräte
+	for _, host := range hosts {
+		if host.Online {
+			events = append(events, m.certificateEvents(m.deviceStates[host.IP.String()], scanStart)...)
+		}
+	}
+
+	// Check for offline devices
+	for ipStr, state := range m.deviceStates {
+		if !currentIPs[ipStr] && state.Status == "online" {
+			state.Status = "offline"
+			state.StatusSince = scanStart
+			state.FlapCount++
+
+			if !movedMACs[normalizeMAC(state.Host.MAC)] {
+				events = append(events, m.statusEvents(alert.DeviceOffline, state, scanStart)...)
+			}
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,365 @@
+package watch_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/discovery"
+	"netspy/pkg/inventory"
+	"netspy/pkg/scanner"
+	"netspy/pkg/service"
+	"netspy/pkg/watch"
+)
+
+var _ = Describe("Monitor", func() {
+	var (
+		monitor *watch.Monitor
+		events  []alert.Event
+		start   time.Time
+	)
+
+	BeforeEach(func() {
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		monitor = watch.NewMonitor("192.0.2.0/24", network, "arp", time.Minute, 0)
+		events = nil
+		monitor.OnEvent(func(event alert.Event) {
+			events = append(events, event)
+		})
+		start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	host := func(ip, mac string) scanner.Host {
+		return scanner.Host{IP: net.ParseIP(ip), MAC: mac, Online: true}
+	}
+
+	types := func() []alert.EventType {
+		var result []alert.EventType
+		for _, event := range events {
+			result = append(result, event.Type)
+		}
+		return result
+	}
+
+	It("should report the first scan as initial device-new events", func() {
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start)
+
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Type).To(Equal(alert.DeviceNew))
+		Expect(events[0].Initial).To(BeTrue())
+		Expect(events[0].Network).To(Equal("192.0.2.0/24"))
+		Expect(events[0].Message).To(Equal("New device 192.0.2.10"))
+
+		monitor.Update([]scanner.Host{
+			host("192.0.2.10", "aa:bb:cc:00:00:01"),
+			host("192.0.2.11", "aa:bb:cc:00:00:02"),
+		}, start.Add(time.Minute))
+
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].IP).To(Equal("192.0.2.11"))
+		Expect(events[1].Initial).To(BeFalse())
+	})
+
+	It("should report offline, back, IP and MAC changes", func() {
+		monitor.Update([]scanner.Host{
+			host("192.0.2.10", "aa:bb:cc:00:00:01"),
+			host("192.0.2.11", "aa:bb:cc:00:00:02"),
+			host("192.0.2.12", "aa:bb:cc:00:00:03"),
+		}, start)
+		events = nil
+
+		// .10 fällt aus, .11 zieht auf .21 um, .12 bekommt eine neue MAC
+		monitor.Update([]scanner.Host{
+			host("192.0.2.21", "aa:bb:cc:00:00:02"),
+			host("192.0.2.12", "aa:bb:cc:00:00:04"),
+		}, start.Add(time.Minute))
+
+		Expect(types()).To(ConsistOf(alert.MACChanged, alert.IPChanged, alert.DeviceOffline))
+		for _, event := range events {
+			switch event.Type {
+			case alert.IPChanged:
+				Expect(event.IP).To(Equal("192.0.2.21"))
+				Expect(event.Old).To(Equal("192.0.2.11"))
+			case alert.MACChanged:
+				Expect(event.Old).To(Equal("aa:bb:cc:00:00:03"))
+			case alert.DeviceOffline:
+				Expect(event.IP).To(Equal("192.0.2.10"))
+				Expect(event.FlapCount).To(Equal(1))
+			}
+		}
+
+		events = nil
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start.Add(2*time.Minute))
+		Expect(types()).To(ContainElement(alert.DeviceBack))
+	})
+
+	It("should not send initial events to the alert bus", func() {
+		sink := &collectingSink{}
+		bus, err := alert.NewBus(alert.Route{Sink: sink})
+		Expect(err).NotTo(HaveOccurred())
+		monitor.SetAlerts(bus)
+
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start)
+		monitor.Update([]scanner.Host{}, start.Add(time.Minute))
+		bus.Close()
+
+		Expect(sink.events).To(HaveLen(1))
+		Expect(sink.events[0].Type).To(Equal(alert.DeviceOffline))
+	})
+
+	It("should treat the first scan as inventory when the inventory knows no devices yet", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "inventory.db")
+		Expect(monitor.SetInventory(path)).To(Succeed())
+
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Initial).To(BeTrue())
+
+		// Mit bekannten Geräten ist ein unbekanntes Gerät schon im ersten Scan wirklich neu
+		store, err := inventory.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		_, err = store.Record(inventory.Scan{Time: start}, network, []scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Close()).To(Succeed())
+
+		monitor = watch.NewMonitor("192.0.2.0/24", network, "arp", time.Minute, 0)
+		events = nil
+		monitor.OnEvent(func(event alert.Event) { events = append(events, event) })
+		Expect(monitor.SetInventory(path)).To(Succeed())
+
+		monitor.Update([]scanner.Host{
+			host("192.0.2.10", "aa:bb:cc:00:00:01"),
+			host("192.0.2.11", "aa:bb:cc:00:00:02"),
+		}, start.Add(time.Hour))
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].IP).To(Equal("192.0.2.11"))
+		Expect(events[0].Initial).To(BeFalse())
+	})
+
+	It("should report expiring certificates once and keep them between checks", func() {
+		Expect(monitor.SetCertificates(30 * 24 * time.Hour)).To(Succeed())
+
+		nas := host("192.0.2.10", "aa:bb:cc:00:00:01")
+		nas.Services = []service.Service{{
+			Port: 443, Protocol: "tcp", Name: "https", TLS: true,
+			Certificates: []service.Certificate{{Subject: "nas.local", SHA256: "ab12", NotAfter: start.Add(10 * 24 * time.Hour)}},
+		}}
+		monitor.Update([]scanner.Host{nas}, start)
+
+		Expect(types()).To(ConsistOf(alert.DeviceNew, alert.CertExpiring))
+		for _, event := range events {
+			if event.Type == alert.CertExpiring {
+				Expect(event.Initial).To(BeFalse())
+				Expect(event.Port).To(Equal(443))
+				Expect(event.Message).To(Equal("Certificate nas.local on 192.0.2.10:443 expires in 10 days (2025-01-11)"))
+			}
+		}
+
+		// Ohne neue Prüfung bleiben die Zertifikate erhalten und werden nicht erneut gemeldet
+		events = nil
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start.Add(time.Minute))
+		Expect(events).To(BeEmpty())
+		Expect(monitor.Snapshot().Devices[0].Services).To(HaveLen(1))
+	})
+
+	It("should keep DNS-SD services when a device did not answer", func() {
+		printer := host("192.0.2.20", "aa:bb:cc:00:00:03")
+		printer.DNSSD = []discovery.DNSSDService{{Type: "_ipp._tcp", Instance: "Büro", Port: 631}}
+		printer.DeviceType = scanner.DetectDeviceType(&printer)
+		monitor.Update([]scanner.Host{printer}, start)
+
+		monitor.Update([]scanner.Host{host("192.0.2.20", "aa:bb:cc:00:00:03")}, start.Add(time.Minute))
+		device := monitor.Snapshot().Devices[0]
+		Expect(device.DNSSD).To(HaveLen(1))
+		Expect(device.DeviceType).To(Equal("Printer"))
+	})
+
+	It("should keep the UPnP description between SSDP searches", func() {
+		router := host("192.0.2.30", "aa:bb:cc:00:00:04")
+		router.UPnP = &discovery.UPnPDevice{
+			DeviceType:   "urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+			FriendlyName: "FRITZ!Box 7590",
+		}
+		monitor.Update([]scanner.Host{router}, start)
+
+		monitor.Update([]scanner.Host{host("192.0.2.30", "aa:bb:cc:00:00:04")}, start.Add(time.Minute))
+		device := monitor.Snapshot().Devices[0]
+		Expect(device.UPnP).NotTo(BeNil())
+		Expect(device.UPnP.FriendlyName).To(Equal("FRITZ!Box 7590"))
+		Expect(device.DeviceType).To(Equal("Network Equipment (Router)"))
+	})
+
+	It("should build the device list from captured traffic in passive mode", func() {
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		collector := scanner.NewPassiveCollector(network)
+		monitor.SetPassive(collector, 10*time.Minute)
+		Expect(monitor.Passive()).To(BeTrue())
+
+		mac, _ := net.ParseMAC("aa:bb:cc:00:00:05")
+		now := time.Now()
+		collector.Add(discovery.Observation{Time: now, Protocol: "dhcp", MAC: mac, IP: net.ParseIP("192.0.2.40"), Hostname: "NPI1A2B3C"})
+		collector.Add(discovery.Observation{Time: now.Add(-time.Hour), Protocol: "arp", IP: net.ParseIP("192.0.2.41")})
+
+		Expect(monitor.Scan(context.Background())).To(BeTrue())
+		snapshot := monitor.Snapshot()
+		Expect(snapshot.Devices).To(HaveLen(1))
+		Expect(snapshot.Devices[0].IP.String()).To(Equal("192.0.2.40"))
+		Expect(snapshot.Devices[0].Hostname).To(Equal("NPI1A2B3C"))
+
+		// Bessere Namen aus späteren Frames ersetzen den ersten
+		collector.Add(discovery.Observation{Time: time.Now(), Protocol: "mdns", MAC: mac, IP: net.ParseIP("192.0.2.40"), Hostname: "drucker-flur"})
+		Expect(monitor.Scan(context.Background())).To(BeTrue())
+		Expect(monitor.Snapshot().Devices[0].Hostname).To(Equal("drucker-flur"))
+	})
+
+	It("should allow reading stats and snapshots while scanning", func() {
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		monitor.SetPassive(scanner.NewPassiveCollector(network), time.Minute)
+
+		// Wie die HTTP-API: parallel zur Scan-Schleife lesen (auffällig mit -race)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				_ = monitor.Stats()
+				_ = monitor.Snapshot()
+			}
+		}()
+		for i := 0; i < 10; i++ {
+			Expect(monitor.Scan(context.Background())).To(BeTrue())
+		}
+		<-done
+		Expect(monitor.Stats().Scans).To(Equal(10))
+	})
+
+	It("should check the ports given with --ports", func() {
+		listener, err := net.Listen("tcp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		port := listener.Addr().(*net.TCPAddr).Port
+
+		_, network, _ := net.ParseCIDR("127.0.0.0/30")
+		monitor = watch.NewMonitor("127.0.0.0/30", network, "icmp", time.Minute, 0)
+		monitor.SetIPv6Discovery(false)
+		Expect(monitor.SetPorts([]int{port}, nil)).To(Succeed())
+
+		Expect(monitor.Scan(context.Background())).To(BeTrue())
+		var ports []int
+		for _, device := range monitor.Snapshot().Devices {
+			if device.IP.String() == "127.0.0.1" {
+				ports = device.Ports
+			}
+		}
+		Expect(ports).To(Equal([]int{port}))
+	})
+
+	It("should attach LLDP neighbours and report the uplink", func() {
+		tlv := func(tlvType int, value ...byte) []byte {
+			return append([]byte{byte(tlvType<<1 | len(value)>>8), byte(len(value))}, value...)
+		}
+		frame := []byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0xaa, 0xbb, 0xcc, 0x00, 0x00, 0x99, 0x88, 0xcc}
+		frame = append(frame, tlv(1, 4, 0xaa, 0xbb, 0xcc, 0x00, 0x00, 0x06)...)
+		frame = append(frame, tlv(2, append([]byte{5}, "Gi1/0/7"...)...)...)
+		frame = append(frame, tlv(5, []byte("sw-keller")...)...)
+		frame = append(frame, tlv(7, 0x00, 0x04, 0x00, 0x04)...)
+		frame = append(frame, tlv(8, 5, 1, 192, 0, 2, 2, 1, 0, 0, 0, 0, 0)...)
+		frame = append(frame, tlv(0)...)
+
+		listener := discovery.NewNeighborListener()
+		Expect(listener.Add(discovery.Frame{Time: start, Data: frame})).To(BeTrue())
+		monitor.SetNeighbors(listener)
+
+		collector := scanner.NewPassiveCollector(nil)
+		monitor.SetPassive(collector, 0)
+		collector.Add(discovery.Observation{Time: start, Protocol: "arp", IP: net.ParseIP("192.0.2.2"), MAC: net.HardwareAddr{0xaa, 0xbb, 0xcc, 0x00, 0x00, 0x06}})
+		Expect(monitor.Scan(context.Background())).To(BeTrue())
+
+		device := monitor.Snapshot().Devices[0]
+		Expect(device.Neighbor).NotTo(BeNil())
+		Expect(device.Neighbor.SystemName).To(Equal("sw-keller"))
+		Expect(device.DeviceType).To(Equal("Network Equipment (Switch)"))
+		Expect(monitor.Stats().Uplink.String()).To(Equal("sw-keller Gi1/0/7 (192.0.2.2)"))
+	})
+
+	It("should prefer the MAC entry when restoring devices with the same IP from the inventory", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "inventory.db")
+		store, err := inventory.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		_, err = store.Record(inventory.Scan{Time: start}, network, []scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")})
+		Expect(err).NotTo(HaveOccurred())
+		// Später ohne MAC gesehen (z.B. ICMP-Scan) - eigener "ip:"-Eintrag mit neuerem Last Seen
+		_, err = store.Record(inventory.Scan{Time: start.Add(time.Hour)}, nil, []scanner.Host{host("192.0.2.10", "")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Close()).To(Succeed())
+
+		Expect(monitor.SetInventory(path)).To(Succeed())
+
+		snapshot := monitor.Snapshot()
+		Expect(snapshot.Devices).To(HaveLen(1))
+		Expect(snapshot.Devices[0].MAC).To(Equal("aa:bb:cc:00:00:01"))
+		Expect(snapshot.Devices[0].FirstSeen).To(Equal(start))
+	})
+
+	It("should create sorted snapshots", func() {
+		monitor.Update([]scanner.Host{
+			host("192.0.2.100", "aa:bb:cc:00:00:01"),
+			host("192.0.2.9", "aa:bb:cc:00:00:02"),
+		}, start)
+		monitor.Update([]scanner.Host{host("192.0.2.9", "aa:bb:cc:00:00:02")}, start.Add(time.Minute))
+
+		snapshot := monitor.Snapshot()
+		Expect(snapshot.Scan).To(Equal(2))
+		Expect(snapshot.Online).To(Equal(1))
+		Expect(snapshot.Offline).To(Equal(1))
+		Expect(snapshot.Devices).To(HaveLen(2))
+		Expect(snapshot.Devices[0].IP.String()).To(Equal("192.0.2.9"))
+		Expect(snapshot.Devices[1].Status).To(Equal("offline"))
+		Expect(snapshot.Devices[1].FirstSeen).To(Equal(start))
+	})
+
+	Describe("Headless", func() {
+		It("should write one JSON object per event", func() {
+			var out bytes.Buffer
+			watch.NewHeadless(monitor, &out, false)
+
+			monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01")}, start)
+			monitor.Update([]scanner.Host{}, start.Add(time.Minute))
+
+			var lines []map[string]interface{}
+			reader := bufio.NewScanner(&out)
+			for reader.Scan() {
+				var line map[string]interface{}
+				Expect(json.Unmarshal(reader.Bytes(), &line)).To(Succeed())
+				lines = append(lines, line)
+			}
+
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[0]).To(HaveKeyWithValue("type", "device-new"))
+			Expect(lines[0]).To(HaveKeyWithValue("initial", true))
+			Expect(lines[1]).To(HaveKeyWithValue("type", "device-offline"))
+			Expect(lines[1]).To(HaveKeyWithValue("ip", "192.0.2.10"))
+		})
+	})
+})
+
+// collectingSink sammelt die vom Alert-Bus zugestellten Ereignisse
+type collectingSink struct {
+	events []alert.Event
+}
+
+func (s *collectingSink) Name() string { return "collecting" }
+
+func (s *collectingSink) Send(_ context.Context, event alert.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
@@ -0,0 +1,39 @@
+package watch
+
+import (
+	"netspy/pkg/discovery"
+	"netspy/pkg/scanner"
+)
+
+// SetNeighbors übergibt den Listener für LLDP/CDP-Ankündigungen: Geräte, die sich
+// ankündigen (Switches, Access Points, Telefone), erhalten nach jedem Scan ihre
+// Nachbar-Daten, Uplink zeigt den Switch-Port des eigenen Interfaces.
+// Muss vor dem ersten Scan aufgerufen werden.
+func (m *Monitor) SetNeighbors(listener *discovery.NeighborListener) {
+	m.neighbors = listener
+}
+
+// Uplink gibt den Switch-Port des eigenen Interfaces zurück (nil = unbekannt)
+func (m *Monitor) Uplink() *discovery.Neighbor {
+	if m.neighbors == nil {
+		return nil
+	}
+	return m.neighbors.Uplink()
+}
+
+// attachNeighbors ordnet die angekündigten Nachbarn über Management-Adresse bzw. MAC
+// den Hosts zu
+func (m *Monitor) attachNeighbors(hosts []scanner.Host) {
+	if m.neighbors == nil {
+		return
+	}
+	for i := range hosts {
+		if hosts[i].Neighbor != nil {
+			continue
+		}
+		if neighbor := m.neighbors.Lookup(hosts[i].IP, hosts[i].MAC); neighbor != nil {
+			hosts[i].Neighbor = neighbor
+			scanner.Classify(&hosts[i])
+		}
+	}
+}
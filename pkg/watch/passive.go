@@ -0,0 +1,32 @@
+package watch
+
+import (
+	"time"
+
+	"netspy/pkg/scanner"
+)
+
+// SetPassive schaltet den Monitor in den passiven Modus: statt zu scannen übernimmt
+// jeder Scan die Geräte, die der Collector in den letzten window mitgeschnitten hat
+// (0 = alle, z.B. für pcap-Dateien). Es werden keine Pakete gesendet - IPv6-Nachbarsuche,
+// DNS-SD, UPnP, Zertifikate und Hostnamen-Auflösung entfallen.
+// Muss vor dem ersten Scan aufgerufen werden.
+func (m *Monitor) SetPassive(collector *scanner.PassiveCollector, window time.Duration) {
+	m.passive = collector
+	m.passiveWindow = window
+}
+
+// Passive meldet, ob der Monitor nur mitgeschnittenen Verkehr auswertet
+func (m *Monitor) Passive() bool {
+	return m.passive != nil
+}
+
+// passiveHosts gibt die zuletzt mitgeschnittenen Geräte zurück. Geräte, die im
+// Zeitfenster nichts gesendet haben, fehlen und gelten damit als offline.
+func (m *Monitor) passiveHosts(scanStart time.Time) []scanner.Host {
+	var since time.Time
+	if m.passiveWindow > 0 {
+		since = scanStart.Add(-m.passiveWindow)
+	}
+	return m.passive.Hosts(since)
+}
@@ -0,0 +1,51 @@
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"netspy/pkg/scanner"
+)
+
+// SetPorts prüft bei jedem Scan zusätzlich diese TCP- und UDP-Ports der Online-Hosts
+// (wie "netspy scan --ports"). Offene Ports landen in Host.Ports bzw. Host.UDPPorts,
+// antwortende UDP-Dienste in Host.Services.
+func (m *Monitor) SetPorts(tcp, udp []int) error {
+	if len(tcp) == 0 && len(udp) == 0 {
+		m.portProbe = nil
+		return nil
+	}
+	probe, err := scanner.NewProbe("ports", scanner.Config{Timeout: 500 * time.Millisecond, Ports: tcp, UDPPorts: udp})
+	if err != nil {
+		return err
+	}
+	m.portProbe = probe
+	return nil
+}
+
+// collectPorts prüft die Ports aller Online-Hosts
+func (m *Monitor) collectPorts(ctx context.Context, hosts []scanner.Host) {
+	semaphore := make(chan struct{}, 16)
+	var wg sync.WaitGroup
+
+	for i := range hosts {
+		host := &hosts[i]
+		if !host.Online {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+			_, _ = m.portProbe.Probe(ctx, host)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultMaxFiles ist die Anzahl der standardmäßig aufbewahrten rotierten Dateien
+const DefaultMaxFiles = 5
+
+// RotatingWriter schreibt an eine Datei und rotiert sie, sobald sie MaxSize erreicht
+// (datei -> datei.1 -> datei.2 ... -> datei.MaxFiles, die älteste wird gelöscht).
+// Geschrieben wird immer in ganzen Write-Aufrufen, eine JSON-Zeile wird also nie geteilt.
+type RotatingWriter struct {
+	path     string
+	maxSize  int64 // 0 = keine Rotation
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingWriter öffnet die Datei zum Anhängen (maxFiles <= 0 = DefaultMaxFiles)
+func NewRotatingWriter(path string, maxSize int64, maxFiles int) (*RotatingWriter, error) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+
+	w := &RotatingWriter{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write schreibt p und rotiert vorher, falls die Datei dadurch zu groß würde
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close schließt die aktuelle Datei
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open öffnet (oder erstellt) die Datei und übernimmt ihre aktuelle Größe
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate verschiebt die vorhandenen Dateien um eine Stelle und beginnt eine neue Datei
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	// Älteste Datei entfernen, Rest nach hinten schieben
+	_ = os.Remove(w.rotatedName(w.maxFiles))
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(w.rotatedName(i), w.rotatedName(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, w.rotatedName(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return w.open()
+}
+
+// rotatedName gibt den Namen der n-ten rotierten Datei zurück
+func (w *RotatingWriter) rotatedName(n int) string {
+	return fmt.Sprintf("%s.%d", w.path, n)
+}
@@ -0,0 +1,62 @@
+package watch_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/watch"
+)
+
+var _ = Describe("RotatingWriter", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "events.ndjson")
+	})
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should append to an existing file", func() {
+		Expect(os.WriteFile(path, []byte("old\n"), 0644)).To(Succeed())
+
+		writer, err := watch.NewRotatingWriter(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write([]byte("new\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		Expect(read(path)).To(Equal("old\nnew\n"))
+	})
+
+	It("should rotate at the size limit and keep only max files", func() {
+		writer, err := watch.NewRotatingWriter(path, 10, 2)
+		Expect(err).NotTo(HaveOccurred())
+		defer writer.Close()
+
+		for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+			_, err := writer.Write([]byte(line))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(read(path)).To(Equal("line-4\n"))
+		Expect(read(path + ".1")).To(Equal("line-3\n"))
+		Expect(read(path + ".2")).To(Equal("line-2\n"))
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+	})
+
+	It("should fail after Close", func() {
+		writer, err := watch.NewRotatingWriter(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		_, err = writer.Write([]byte("x\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"os/exec"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,50 +12,20 @@ import (
 	"netspy/pkg/scanner"
 )
 
-// pingHost sends an ICMP ping using the system ping command
-// Works on Windows, Linux, and macOS without admin rights
-// Returns success status and RTT duration
-func pingHost(ip string, timeout time.Duration) (bool, time.Duration) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "windows":
-		// Windows: -n count, -w timeout in milliseconds
-		timeoutMs := int(timeout.Milliseconds())
-		if timeoutMs < 1 {
-			timeoutMs = 1
-		}
-		cmd = exec.Command("ping", "-n", "1", "-w", fmt.Sprintf("%d", timeoutMs), ip)
-	case "darwin":
-		// macOS: -c count, -W timeout in milliseconds
-		timeoutMs := int(timeout.Milliseconds())
-		if timeoutMs < 1 {
-			timeoutMs = 1
-		}
-		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutMs), ip)
-	default:
-		// Linux: -c count, -W timeout in seconds (minimum 1)
-		timeoutSec := int(timeout.Seconds())
-		if timeoutSec < 1 {
-			timeoutSec = 1
-		}
-		cmd = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", timeoutSec), ip)
-	}
-
-	start := time.Now()
-	err := cmd.Run()
-	rtt := time.Since(start)
-
+// pingHost sendet einen Echo-Request über die gemeinsame ICMP-Engine
+// Liefert Erfolg, RTT und TTL der Antwort
+func pingHost(ctx context.Context, ip net.IP, timeout time.Duration) (bool, time.Duration, int) {
+	rtt, ttl, err := discovery.SharedICMPEngine().Ping(ctx, ip, timeout)
 	if err != nil {
-		return false, 0
+		return false, 0, 0
 	}
-	return true, rtt
+	return true, rtt, ttl
 }
 
-// pingHostForARP sends an ICMP ping just to trigger ARP table population
+// pingHostForARP sends an ICMP echo just to trigger ARP table population
 // Does not care about the result - only triggers network traffic
-func pingHostForARP(ip string, timeout time.Duration) {
-	pingHost(ip, timeout)
+func pingHostForARP(ctx context.Context, ip net.IP, timeout time.Duration) {
+	pingHost(ctx, ip, timeout)
 }
 
 // PerformScanQuiet performs a scan based on the selected mode without output
@@ -72,10 +40,9 @@ func PerformScanQuiet(ctx context.Context, network string, netCIDR *net.IPNet, m
 		hosts, err = PerformARPScanQuiet(ctx, netCIDR, activeThreads, threadConfig)
 	case "icmp":
 		hosts, err = PerformICMPScanQuiet(ctx, netCIDR, activeThreads, threadConfig)
-	case "fast", "thorough", "conservative":
-		hosts, err = PerformNormalScan(network, mode, activeThreads, threadConfig)
 	default:
-		return nil
+		// Eingebaute TCP-Modi oder eine Probe-Pipeline wie "arp+icmp+tcp/22"
+		hosts, err = PerformNormalScan(ctx, network, mode, activeThreads, threadConfig)
 	}
 
 	if err != nil {
@@ -99,18 +66,24 @@ func PerformHybridScanQuiet(ctx context.Context, netCIDR *net.IPNet, activeThrea
 	if isLocal {
 		allHosts := []scanner.Host{}
 
-		// Read existing ARP table first (quietly)
-		existingHosts := ReadCurrentARPTableQuiet(netCIDR)
-		allHosts = append(allHosts, existingHosts...)
+		// Aktiver ARP-Sweep (Raw-Socket) - liefert auch Hosts jenseits des ARP-Caches
+		sweepHosts, sweepErr := SweepARPQuiet(ctx, netCIDR)
+		if sweepErr == nil {
+			finalHosts = sweepHosts
+		} else {
+			// Fallback: Read existing ARP table first (quietly)
+			existingHosts := ReadCurrentARPTableQuiet(netCIDR)
+			allHosts = append(allHosts, existingHosts...)
+
+			// Populate ARP table
+			if err := PopulateARPTableQuiet(ctx, netCIDR); err != nil {
+				return allHosts, err
+			}
 
-		// Populate ARP table
-		if err := PopulateARPTableQuiet(ctx, netCIDR); err != nil {
-			return allHosts, err
+			// Read refreshed ARP table (quietly)
+			finalHosts = ReadCurrentARPTableQuiet(netCIDR)
 		}
 
-		// Read refreshed ARP table (quietly)
-		finalHosts = ReadCurrentARPTableQuiet(netCIDR)
-
 		// Add localhost if it's in the network range
 		localhostIP := GetLocalhostIP(netCIDR)
 		if localhostIP != nil {
@@ -125,9 +98,10 @@ func PerformHybridScanQuiet(ctx context.Context, netCIDR *net.IPNet, activeThrea
 		}
 	}
 
-	// SSDP/UPnP Discovery wird NICHT im ersten Scan gesetzt!
+	// SSDP/UPnP Discovery wird NICHT hier durchgeführt!
 	// Grund: HTTP title detection (z.B. "Hue") hat höhere Priorität
-	// SSDP wird später im Background-DNS-Lookup als letzter Fallback verwendet
+	// SSDP läuft in Monitor.Scan (collectUPnP); der friendlyName ist nur Fallback
+	// und wird von den Background-DNS-Lookups überschrieben
 
 	// Fallback zu ICMP-Scanning wenn keine ARP-Hosts gefunden (fremdes Subnet oder ARP fehlgeschlagen)
 	// ICMP ist besser als TCP für fremde Netzwerke, da viele Hosts keine offenen TCP-Ports haben
@@ -153,13 +127,18 @@ func PerformARPScanQuiet(ctx context.Context, netCIDR *net.IPNet, activeThreads
 
 	// Nur ARP versuchen wenn lokales Netzwerk
 	if isLocal {
-		// Populate ARP table
-		if err := PopulateARPTableQuiet(ctx, netCIDR); err != nil {
-			return nil, err
-		}
+		sweepHosts, sweepErr := SweepARPQuiet(ctx, netCIDR)
+		if sweepErr == nil {
+			hosts = sweepHosts
+		} else {
+			// Fallback ohne Raw-Socket: Populate ARP table
+			if err := PopulateARPTableQuiet(ctx, netCIDR); err != nil {
+				return nil, err
+			}
 
-		// Read ARP table quietly
-		hosts = ReadCurrentARPTableQuiet(netCIDR)
+			// Read ARP table quietly
+			hosts = ReadCurrentARPTableQuiet(netCIDR)
+		}
 	}
 
 	// Fallback zu ICMP-Scanning wenn keine ARP-Hosts gefunden (fremdes Subnet oder ARP fehlgeschlagen)
@@ -179,59 +158,30 @@ func PerformARPScanQuiet(ctx context.Context, netCIDR *net.IPNet, activeThreads
 func PerformICMPScanQuiet(ctx context.Context, netCIDR *net.IPNet, activeThreads *int32, threadConfig ThreadConfig) ([]scanner.Host, error) {
 	ips := discovery.GenerateIPsFromCIDR(netCIDR)
 
-	var hosts []scanner.Host
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
 	// Use configurable concurrency for ICMP scans
 	concurrency := threadConfig.Scan
 	if concurrency == 0 {
 		concurrency = 50 // Default for ICMP
 	}
-	semaphore := make(chan struct{}, concurrency)
 
 	// Longer timeout for ICMP (remote networks may have higher latency)
-	pingTimeout := 1000 * time.Millisecond
-
-	for _, ip := range ips {
-		select {
-		case <-ctx.Done():
-			return hosts, nil
-		default:
-		}
-
-		wg.Add(1)
-		semaphore <- struct{}{}
-
-		go func(targetIP net.IP) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
-
-			atomic.AddInt32(activeThreads, 1)
-			defer atomic.AddInt32(activeThreads, -1)
-
-			// Perform ICMP ping
-			success, rtt := pingHost(targetIP.String(), pingTimeout)
-			if success {
-				host := scanner.Host{
-					IP:     targetIP,
-					RTT:    rtt,
-					Online: true,
-				}
-
-				mu.Lock()
-				hosts = append(hosts, host)
-				mu.Unlock()
-			}
-		}(ip)
+	s := scanner.New(scanner.Config{
+		Concurrency: concurrency,
+		Timeout:     1000 * time.Millisecond,
+		Fast:        true, // DNS lookups happen later in background
+		ICMP:        true,
+		Active:      activeThreads,
+	})
+
+	results, err := s.Scan(ctx, ips)
+	if err != nil {
+		return nil, err
 	}
-
-	wg.Wait()
-	return hosts, nil
+	return scanner.Collect(results), nil
 }
 
 // PerformNormalScan performs normal TCP/Ping scan
-func PerformNormalScan(network string, mode string, activeThreads *int32, threadConfig ThreadConfig) ([]scanner.Host, error) {
+func PerformNormalScan(ctx context.Context, network string, mode string, activeThreads *int32, threadConfig ThreadConfig) ([]scanner.Host, error) {
 	// Parse network input
 	_, netCIDR, err := net.ParseCIDR(network)
 	if err != nil {
@@ -276,15 +226,20 @@ func PerformNormalScan(network string, mode string, activeThreads *int32, thread
 			Thorough:    false,
 			Quiet:       true,
 		}
+
+		// Unbekannte Modi sind Probe-Pipelines
+		if config.Pipeline, err = scanner.ParsePipeline(mode, config); err != nil {
+			return nil, err
+		}
 	}
 
-	s := scanner.New(config)
-	results, err := s.ScanHosts(hosts, activeThreads)
+	config.Active = activeThreads
+	results, err := scanner.New(config).Scan(ctx, hosts)
 	if err != nil {
 		return nil, fmt.Errorf("scan failed: %v", err)
 	}
 
-	return results, nil
+	return scanner.Collect(results), nil
 }
 
 // ReadCurrentARPTableQuiet reads ARP table without any output (for watch mode)
@@ -295,17 +250,34 @@ func ReadCurrentARPTableQuiet(network *net.IPNet) []scanner.Host {
 		return nil
 	}
 
+	return arpEntriesToHosts(arpEntries)
+}
+
+// SweepARPQuiet führt einen aktiven ARP-Sweep über einen Raw-Socket durch (Linux, CAP_NET_RAW)
+// Ohne Raw-Socket kommt ein Fehler zurück - die Aufrufer lesen dann die ARP-Tabelle
+func SweepARPQuiet(ctx context.Context, network *net.IPNet) ([]scanner.Host, error) {
+	arpScanner := discovery.NewARPScanner(500 * time.Millisecond)
+	arpEntries, err := arpScanner.Sweep(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+
+	return arpEntriesToHosts(arpEntries), nil
+}
+
+// arpEntriesToHosts wandelt ARP-Einträge in Scanner-Hosts um
+func arpEntriesToHosts(arpEntries []discovery.ARPEntry) []scanner.Host {
 	var hosts []scanner.Host
 	for _, entry := range arpEntries {
 		vendor := discovery.GetMACVendor(entry.MAC.String())
 		host := scanner.Host{
-			IP:         entry.IP,
-			MAC:        entry.MAC.String(),
-			Vendor:     vendor,
-			RTT:        entry.RTT,
-			Online:     entry.Online,
-			DeviceType: discovery.DetectDeviceType("", entry.MAC.String(), vendor, nil),
+			IP:     entry.IP,
+			MAC:    entry.MAC.String(),
+			Vendor: vendor,
+			RTT:    entry.RTT,
+			Online: entry.Online,
 		}
+		scanner.Classify(&host)
 		hosts = append(hosts, host)
 	}
 
@@ -334,9 +306,9 @@ func PopulateARPTableQuiet(ctx context.Context, network *net.IPNet) error {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			// Use ICMP ping via system command (works without admin rights)
+			// ICMP echo via shared engine (datagram socket, no admin rights needed)
 			// Only triggers ARP, result is ignored
-			pingHostForARP(targetIP.String(), 50*time.Millisecond)
+			pingHostForARP(ctx, targetIP, 50*time.Millisecond)
 		}(ip)
 	}
 
@@ -367,8 +339,8 @@ func GetLocalhostIP(network *net.IPNet) net.IP {
 				ip = v.IP
 			}
 
-			// Check if IP is in the target network
-			if ip != nil && network.Contains(ip) && ip.To4() != nil {
+			// Check if IP is in the target network (IPv4 or IPv6)
+			if ip != nil && network.Contains(ip) {
 				return ip
 			}
 		}
@@ -405,7 +377,8 @@ func GetLocalMAC() string {
 				ip = v.IP
 			}
 
-			if ip != nil && ip.To4() != nil {
+			// IPv4 oder globale/ULA IPv6-Adresse (Link-Local allein reicht nicht)
+			if ip != nil && ip.IsGlobalUnicast() {
 				return iface.HardwareAddr.String()
 			}
 		}
@@ -1,6 +1,7 @@
 package watch
 
 import (
+	"bytes"
 	"net"
 	"sort"
 	"strings"
@@ -232,6 +233,7 @@ func CalculateThreads(netCIDR *net.IPNet, maxThreadsOverride int) ThreadConfig {
 }
 
 // CompareIPs compares two IP addresses for sorting
+// IPv4 addresses sort before IPv6 addresses
 func CompareIPs(ip1, ip2 string) bool {
 	// Parse IPs for proper binary comparison
 	parsedIP1 := net.ParseIP(ip1)
@@ -242,23 +244,14 @@ func CompareIPs(ip1, ip2 string) bool {
 		return ip1 < ip2
 	}
 
-	// Convert to 4-byte representation for IPv4
-	parsedIP1 = parsedIP1.To4()
-	parsedIP2 = parsedIP2.To4()
-
-	if parsedIP1 == nil || parsedIP2 == nil {
-		// Fallback to string comparison
-		return ip1 < ip2
-	}
-
-	// Compare byte by byte
-	for i := 0; i < len(parsedIP1); i++ {
-		if parsedIP1[i] != parsedIP2[i] {
-			return parsedIP1[i] < parsedIP2[i]
-		}
+	// IPv4 vor IPv6
+	is4a, is4b := parsedIP1.To4() != nil, parsedIP2.To4() != nil
+	if is4a != is4b {
+		return is4a
 	}
 
-	return false
+	// Compare byte by byte (16-byte representation works for both families)
+	return bytes.Compare(parsedIP1.To16(), parsedIP2.To16()) < 0
 }
 
 // GetHostname returns the hostname or "-"
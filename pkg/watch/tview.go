@@ -7,12 +7,10 @@ import (
 	"runtime/debug"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"netspy/pkg/crash"
 	"netspy/pkg/filter"
-	"netspy/pkg/scanner"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -42,22 +40,10 @@ type TviewApp struct {
 	filterHistory     []string
 	historyIndex      int // -1 = neue Eingabe, 0+ = Historie durchblättern
 
-	// State
-	deviceStates map[string]*DeviceState
-	statesMu     sync.RWMutex
-	sortState    *SortState
-	network      string
-	netCIDR      *net.IPNet
-	interval     time.Duration
-	mode         string
-	scanCount    int
-	scanDuration time.Duration
-	nextScanIn   time.Duration
-	isLocal      bool
-
-	// Thread tracking
-	activeThreads int32
-	threadConfig  ThreadConfig
+	// State (Scans, Device-States, Inventar und Alerts)
+	*Monitor
+	sortState  *SortState
+	nextScanIn time.Duration
 
 	// Channels
 	ctx    context.Context
@@ -78,24 +64,12 @@ var (
 func NewTviewApp(network string, netCIDR *net.IPNet, mode string, interval time.Duration, maxThreads int) *TviewApp {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Calculate thread config
-	threadConfig := CalculateThreads(netCIDR, maxThreads)
-
-	// Check if local subnet
-	isLocal, _ := IsLocalSubnet(netCIDR)
-
 	w := &TviewApp{
-		app:          tview.NewApplication(),
-		deviceStates: make(map[string]*DeviceState),
-		sortState:    &SortState{Column: SortByIP, Ascending: true},
-		network:      network,
-		netCIDR:      netCIDR,
-		interval:     interval,
-		mode:         mode,
-		isLocal:      isLocal,
-		threadConfig: threadConfig,
-		ctx:          ctx,
-		cancel:       cancel,
+		app:       tview.NewApplication(),
+		Monitor:   NewMonitor(network, netCIDR, mode, interval, maxThreads),
+		sortState: &SortState{Column: SortByIP, Ascending: true},
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	w.setupUI()
@@ -429,6 +403,11 @@ func (w *TviewApp) updateHeader() {
 		networkDisplay, w.mode, w.interval,
 		totalDevices, onlineCount, offlineCount, totalFlaps, FormatDuration(w.scanDuration),
 		w.activeThreads, w.scanCount, FormatDuration(w.nextScanIn))
+
+	// Switch-Port des eigenen Interfaces (LLDP/CDP)
+	if uplink := w.Uplink(); uplink != nil {
+		text += "  [yellow]Uplink:[white] " + tview.Escape(uplink.String())
+	}
 	w.headerView.SetText(text)
 }
 
@@ -483,16 +462,17 @@ func (w *TviewApp) updateTable() {
 	w.statesMu.RLock()
 	defer w.statesMu.RUnlock()
 
+	referenceTime := time.Now()
+
 	// Sortierte IP-Liste erstellen (mit Filter)
 	ips := make([]string, 0, len(w.deviceStates))
-	for ip := range w.deviceStates {
+	for ip, state := range w.deviceStates {
 		// Filter anwenden
-		if w.matchesFilter(ip, w.deviceStates[ip]) {
+		if w.matchesFilter(state, referenceTime) {
 			ips = append(ips, ip)
 		}
 	}
 
-	referenceTime := time.Now()
 	SortIPs(ips, w.deviceStates, w.sortState, referenceTime)
 
 	// Tabelle komplett leeren und Header neu erstellen
@@ -537,6 +517,9 @@ func (w *TviewApp) updateTable() {
 		if state.Host.IsGateway {
 			displayIP += " [G]"
 		}
+		if len(state.Host.IPv6) > 0 && state.Host.IP.To4() != nil {
+			displayIP += " [6]"
+		}
 		if state.Status == "offline" {
 			displayIP += " [!]"
 		}
@@ -576,14 +559,7 @@ func (w *TviewApp) updateTable() {
 		}
 
 		// Uptime/Downtime
-		var statusDuration time.Duration
-		if state.Status == "online" {
-			totalTime := referenceTime.Sub(state.FirstSeen)
-			statusDuration = totalTime - state.TotalOfflineTime
-		} else {
-			statusDuration = referenceTime.Sub(state.StatusSince)
-		}
-		uptimeText := FormatDuration(statusDuration)
+		uptimeText := FormatDuration(statusDuration(state, referenceTime))
 
 		// Flaps
 		flapText := fmt.Sprintf("%d", state.FlapCount)
@@ -706,6 +682,8 @@ FILTER:
   Tab = Vorschlag übernehmen
   Enter = Filter anwenden
   Esc = Filter schließen
+  Syntax: vendor=Apple, rtt>50ms, flaps>=3, uptime<1h,
+          port=22, host~^nas, vendor in (Apple, AVM)
 
 SORTIERUNG:
   i = Sort by IP
@@ -771,46 +749,13 @@ func (w *TviewApp) Stop() {
 
 // scanLoop führt periodische Scans durch
 func (w *TviewApp) scanLoop() {
-	// Erster Scan sofort
-	w.performScan()
-
-	ticker := time.NewTicker(w.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-w.ctx.Done():
-			return
-		case <-ticker.C:
-			w.performScan()
-		}
-	}
+	w.Monitor.Run(w.ctx, w.afterScan)
 }
 
-// performScan führt einen Scan durch und aktualisiert die UI
-func (w *TviewApp) performScan() {
-	scanStart := time.Now()
-
-	// Scan durchführen
-	hosts := PerformScanQuiet(w.ctx, w.network, w.netCIDR, w.mode, &w.activeThreads, w.threadConfig)
-
-	// Check if cancelled
-	if w.ctx.Err() != nil {
-		return
-	}
-
-	w.scanCount++
-	w.scanDuration = time.Since(scanStart)
+// afterScan aktualisiert die UI nach einem Scan und startet die DNS-Lookups
+func (w *TviewApp) afterScan() {
 	w.nextScanIn = w.interval
 
-	// Device States aktualisieren
-	w.updateDeviceStates(hosts, scanStart)
-
-	// DNS-Cache vorab laden
-	w.statesMu.Lock()
-	PopulateFromDNSCache(w.deviceStates)
-	w.statesMu.Unlock()
-
 	// UI aktualisieren (thread-safe)
 	w.app.QueueUpdateDraw(func() {
 		w.updateHeader()
@@ -820,9 +765,7 @@ func (w *TviewApp) performScan() {
 
 	// Background DNS Lookups starten
 	go func() {
-		w.statesMu.Lock()
-		PerformInitialDNSLookups(w.ctx, w.deviceStates)
-		w.statesMu.Unlock()
+		w.ResolveHostnames(w.ctx)
 
 		// UI nach DNS-Updates aktualisieren (alle Komponenten für Konsistenz)
 		w.app.QueueUpdateDraw(func() {
@@ -833,72 +776,6 @@ func (w *TviewApp) performScan() {
 	}()
 }
 
-// updateDeviceStates aktualisiert die Device-States basierend auf Scan-Ergebnissen
-func (w *TviewApp) updateDeviceStates(hosts []scanner.Host, scanStart time.Time) {
-	w.statesMu.Lock()
-	defer w.statesMu.Unlock()
-
-	currentIPs := make(map[string]bool)
-
-	for _, host := range hosts {
-		ipStr := host.IP.String()
-
-		if host.Online {
-			currentIPs[ipStr] = true
-		} else {
-			continue
-		}
-
-		state, exists := w.deviceStates[ipStr]
-
-		if exists {
-			state.LastSeen = scanStart
-
-			// Preserve hostname if already resolved
-			oldHostname := state.Host.Hostname
-			oldSource := state.Host.HostnameSource
-			oldRTT := state.Host.RTT
-
-			state.Host = host
-
-			if oldSource != "" {
-				state.Host.Hostname = oldHostname
-				state.Host.HostnameSource = oldSource
-			}
-
-			if state.Host.RTT == 0 && oldRTT > 0 {
-				state.Host.RTT = oldRTT
-			}
-
-			if state.Status == "offline" {
-				offlineDuration := scanStart.Sub(state.StatusSince)
-				state.TotalOfflineTime += offlineDuration
-				state.Status = "online"
-				state.StatusSince = scanStart
-				state.FlapCount++
-			}
-		} else {
-			w.deviceStates[ipStr] = &DeviceState{
-				Host:          host,
-				FirstSeen:     scanStart,
-				FirstSeenScan: w.scanCount,
-				LastSeen:      scanStart,
-				Status:        "online",
-				StatusSince:   scanStart,
-			}
-		}
-	}
-
-	// Check for offline devices
-	for ipStr, state := range w.deviceStates {
-		if !currentIPs[ipStr] && state.Status == "online" {
-			state.Status = "offline"
-			state.StatusSince = scanStart
-			state.FlapCount++
-		}
-	}
-}
-
 // countdownLoop aktualisiert den Countdown-Timer
 func (w *TviewApp) countdownLoop() {
 	ticker := time.NewTicker(1 * time.Second)
@@ -1201,7 +1078,10 @@ func (w *TviewApp) hideDropdown() {
 // validateFilter prüft ob ein Filter gültig ist und gibt ggf. einen Fehler zurück
 // Nutzt das generische pkg/filter Package
 func validateFilter(filterExpr string) string {
-	return filter.ValidateString(filterExpr)
+	if err := NewFilter(filterExpr).Compile(); err != nil {
+		return err.Error()
+	}
+	return ""
 }
 
 // applyFilter wendet den aktuellen Filter an
@@ -1262,36 +1142,18 @@ func normalizeFilterText(text string) string {
 
 // matchesFilter prüft ob ein Device zum aktuellen Filter passt
 // Nutzt das generische pkg/filter Package
-func (w *TviewApp) matchesFilter(ipStr string, state *DeviceState) bool {
+func (w *TviewApp) matchesFilter(state *DeviceState, referenceTime time.Time) bool {
 	if w.filterText == "" {
 		return true
 	}
 
 	// Filter-Objekt erstellen/aktualisieren wenn nötig
 	if w.filterObj == nil || w.filterObj.Expression != w.filterText {
-		w.filterObj = filter.New(w.filterText).
-			WithIPField("ip").
-			WithAliases(map[string]string{
-				"hostname": "host",
-				"h":        "host",
-				"m":        "mac",
-				"v":        "vendor",
-				"i":        "ip",
-				"s":        "status",
-				"dev":      "device",
-				"type":     "device",
-			})
+		w.filterObj = NewFilter(w.filterText)
 	}
 
-	// Felder-Map für den Filter
-	fields := map[string]string{
-		"ip":     ipStr,
-		"host":   state.Host.Hostname,
-		"mac":    state.Host.MAC,
-		"vendor": state.Host.Vendor,
-		"device": state.Host.DeviceType,
-		"status": state.Status,
-	}
+	// Felder-Map für den Filter (der Ausdruck selbst ist bereits übersetzt)
+	fields := FilterFields(w.deviceSnapshot(state, referenceTime))
 
 	return w.filterObj.Match(fields)
 }
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"netspy/pkg/alert"
+	"netspy/pkg/inventory"
+	"netspy/pkg/metrics"
+	"netspy/pkg/watch"
+)
+
+// sseKeepAlive ist das Intervall der Keep-Alive-Kommentare im Event-Strom
+const sseKeepAlive = 30 * time.Second
+
+// statsResponse ist die Antwort von GET /api/stats
+type statsResponse struct {
+	watch.Stats
+	ReadOnly bool `json:"read_only"`
+}
+
+// deviceResponse ist die Antwort von GET /api/devices/{ip}
+type deviceResponse struct {
+	Device    watch.DeviceSnapshot `json:"device"`
+	Events    []alert.Event        `json:"events"`              // Letzte Ereignisse (neueste zuerst)
+	Inventory *inventory.Device    `json:"inventory,omitempty"` // IP-/Hostname-Historie (falls Inventar aktiv)
+}
+
+// handleStats liefert Scan-Statistiken und Gerätezahlen
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statsResponse{Stats: s.monitor.Stats(), ReadOnly: s.opts.ReadOnly})
+}
+
+// handleDevices liefert alle Geräte, optional gefiltert (?filter=vendor=Apple)
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.monitor.Snapshot()
+
+	if expression := r.URL.Query().Get("filter"); expression != "" {
+		f := watch.NewFilter(expression)
+		if err := f.Compile(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		devices := snapshot.Devices[:0]
+		for _, device := range snapshot.Devices {
+			if f.Match(watch.FilterFields(device)) {
+				devices = append(devices, device)
+			}
+		}
+		snapshot.Devices = devices
+	}
+
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleDevice liefert ein Gerät mit seinen letzten Ereignissen und der Inventar-Historie
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	ip := net.ParseIP(r.PathValue("ip"))
+	if ip == nil {
+		writeError(w, http.StatusBadRequest, "invalid IP address")
+		return
+	}
+
+	var response deviceResponse
+	found := false
+	for _, device := range s.monitor.Snapshot().Devices {
+		if device.IP.Equal(ip) {
+			response.Device = device
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "device not found")
+		return
+	}
+
+	response.Events = s.hub.deviceHistory(ip.String())
+	response.Inventory = s.inventoryDevice(response.Device)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// inventoryDevice lädt den Inventar-Eintrag eines Geräts (nil wenn nicht verfügbar)
+func (s *Server) inventoryDevice(device watch.DeviceSnapshot) *inventory.Device {
+	path := s.monitor.InventoryPath()
+	if path == "" {
+		return nil
+	}
+
+	store, err := inventory.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer store.Close()
+
+	entry, err := store.Device(inventory.DeviceKey(device.Host))
+	if err != nil {
+		return nil
+	}
+	return entry
+}
+
+// handleEvents liefert die Zustandsänderungen als Server-Sent Events.
+// Mit Last-Event-ID werden verpasste Ereignisse nachgeliefert.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	events, missed := s.hub.subscribe(lastID)
+	defer s.hub.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+
+	for _, entry := range missed {
+		if writeSSE(w, entry) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-events:
+			if !ok {
+				// Server fährt herunter oder Client war zu langsam
+				return
+			}
+			if writeSSE(w, entry) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleScan fordert einen sofortigen Scan an (nicht im Read-Only-Modus)
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if s.opts.ReadOnly {
+		writeError(w, http.StatusForbidden, "read-only mode")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]bool{"triggered": s.monitor.TriggerScan()})
+}
+
+// handleMetrics liefert die Metriken im Prometheus-Textformat
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = metrics.Write(w, s.monitor.Stats(), s.monitor.Snapshot(), s.opts.Metrics)
+}
+
+// writeSSE schreibt ein Ereignis im SSE-Format
+func writeSSE(w http.ResponseWriter, entry sequencedEvent) error {
+	data, err := json.Marshal(entry.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", entry.ID, entry.Event.Type, data)
+	return err
+}
+
+// writeJSON schreibt eine JSON-Antwort
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError schreibt eine Fehlerantwort {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// withAuth prüft Token bzw. Basic-Auth, falls konfiguriert. Das Token wird als
+// "Authorization: Bearer <token>" oder als Query-Parameter "token" akzeptiert
+// (EventSource im Browser kann keine Header setzen).
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	if !s.opts.authRequired() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.opts.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="netspy", charset="UTF-8"`)
+		}
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+	})
+}
+
+// authorized meldet, ob die Anfrage gültige Zugangsdaten enthält
+func (s *Server) authorized(r *http.Request) bool {
+	if s.opts.Token != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && equal(token, s.opts.Token) {
+			return true
+		}
+		if token := r.URL.Query().Get("token"); token != "" && equal(token, s.opts.Token) {
+			return true
+		}
+	}
+
+	if s.opts.Username != "" {
+		if user, password, ok := r.BasicAuth(); ok && equal(user, s.opts.Username) && equal(password, s.opts.Password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal vergleicht Zugangsdaten in konstanter Zeit
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
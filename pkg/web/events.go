@@ -0,0 +1,130 @@
+package web
+
+import (
+	"sync"
+
+	"netspy/pkg/alert"
+)
+
+const (
+	recentEvents      = 500 // Ereignisse für die Wiederaufnahme per Last-Event-ID
+	deviceHistorySize = 50  // Ereignisse pro Gerät für /api/devices/{ip}
+	subscriberBuffer  = 64  // Gepufferte Ereignisse pro SSE-Client
+)
+
+// sequencedEvent ist ein Ereignis mit fortlaufender ID (SSE "id:")
+type sequencedEvent struct {
+	ID    uint64
+	Event alert.Event
+}
+
+// hub verteilt die Ereignisse des Monitors an die SSE-Clients und merkt sich
+// die letzten Ereignisse (gesamt und pro Gerät)
+type hub struct {
+	mu          sync.Mutex
+	seq         uint64
+	recent      []sequencedEvent
+	history     map[string][]alert.Event // IP -> Ereignisse (älteste zuerst)
+	subscribers map[chan sequencedEvent]struct{}
+	closed      bool
+}
+
+func newHub() *hub {
+	return &hub{
+		history:     make(map[string][]alert.Event),
+		subscribers: make(map[chan sequencedEvent]struct{}),
+	}
+}
+
+// publish nimmt ein Ereignis vom Monitor entgegen. Langsame Clients, deren Puffer
+// voll ist, werden getrennt (der Browser verbindet sich mit Last-Event-ID neu).
+func (h *hub) publish(event alert.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	entry := sequencedEvent{ID: h.seq, Event: event}
+
+	h.recent = append(h.recent, entry)
+	if len(h.recent) > recentEvents {
+		h.recent = h.recent[len(h.recent)-recentEvents:]
+	}
+
+	history := append(h.history[event.IP], event)
+	if len(history) > deviceHistorySize {
+		history = history[len(history)-deviceHistorySize:]
+	}
+	h.history[event.IP] = history
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- entry:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registriert einen Client. Ereignisse nach lastID werden zuerst
+// zurückgegeben (Wiederaufnahme nach Verbindungsabbruch).
+func (h *hub) subscribe(lastID uint64) (chan sequencedEvent, []sequencedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan sequencedEvent, subscriberBuffer)
+	if h.closed {
+		close(ch)
+		return ch, nil
+	}
+	h.subscribers[ch] = struct{}{}
+
+	var missed []sequencedEvent
+	if lastID > 0 {
+		for _, entry := range h.recent {
+			if entry.ID > lastID {
+				missed = append(missed, entry)
+			}
+		}
+	}
+	return ch, missed
+}
+
+// unsubscribe entfernt einen Client (falls nicht bereits getrennt)
+func (h *hub) unsubscribe(ch chan sequencedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// deviceHistory gibt die letzten Ereignisse eines Geräts zurück (neueste zuerst)
+func (h *hub) deviceHistory(ip string) []alert.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := h.history[ip]
+	events := make([]alert.Event, len(history))
+	for i, event := range history {
+		events[len(history)-1-i] = event
+	}
+	return events
+}
+
+// close trennt alle Clients (beim Herunterfahren)
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
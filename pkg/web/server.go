@@ -0,0 +1,115 @@
+// Package web stellt den Zustand des Watch-Modus über HTTP bereit: eine REST-API,
+// einen Server-Sent-Events-Strom der Zustandsänderungen, Prometheus-Metriken und
+// ein eingebettetes Dashboard.
+package web
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"net"
+	"net/http"
+	"time"
+
+	"netspy/pkg/metrics"
+	"netspy/pkg/watch"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Options konfiguriert den Web-Server
+type Options struct {
+	Addr     string // Listen-Adresse, z.B. ":8080" oder "127.0.0.1:8080"
+	Token    string // Bearer-Token ("" = kein Token)
+	Username string // Basic-Auth ("" = keine Basic-Auth)
+	Password string
+	ReadOnly bool // Schreibende Endpunkte (POST /api/scan) sperren
+
+	Metrics metrics.Options // Kardinalität von /metrics
+}
+
+// authRequired meldet, ob eine Anmeldung konfiguriert ist
+func (o Options) authRequired() bool {
+	return o.Token != "" || o.Username != ""
+}
+
+// Server ist der HTTP-Server des Watch-Modus
+type Server struct {
+	monitor *watch.Monitor
+	opts    Options
+	hub     *hub
+	server  *http.Server
+	addr    net.Addr
+}
+
+// New erstellt den Server und registriert ihn beim Monitor. Muss vor dem ersten Scan
+// aufgerufen werden, damit keine Ereignisse verloren gehen.
+func New(monitor *watch.Monitor, opts Options) *Server {
+	s := &Server{
+		monitor: monitor,
+		opts:    opts,
+		hub:     newHub(),
+	}
+	monitor.OnEvent(s.hub.publish)
+	return s
+}
+
+// Handler gibt den HTTP-Handler mit allen Routen zurück
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/devices", s.handleDevices)
+	mux.HandleFunc("GET /api/devices/{ip}", s.handleDevice)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.HandleFunc("POST /api/scan", s.handleScan)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	return s.withAuth(withHeaders(mux))
+}
+
+// Start öffnet den Listener und bedient Anfragen im Hintergrund. Fehler beim Öffnen
+// (z.B. Port belegt) werden sofort zurückgegeben.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.addr = listener.Addr()
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Serve endet mit http.ErrServerClosed nach Shutdown
+	go func() { _ = s.server.Serve(listener) }()
+	return nil
+}
+
+// Addr gibt die tatsächliche Listen-Adresse zurück (nach Start)
+func (s *Server) Addr() net.Addr {
+	return s.addr
+}
+
+// Shutdown beendet offene Event-Ströme und danach den Server
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.hub.close()
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Shutdown(ctx)
+}
+
+// withHeaders setzt Sicherheits-Header für alle Antworten
+func withHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		next.ServeHTTP(w, r)
+	})
+}
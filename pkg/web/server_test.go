@@ -0,0 +1,220 @@
+package web_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"netspy/pkg/scanner"
+	"netspy/pkg/watch"
+	"netspy/pkg/web"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		monitor *watch.Monitor
+		server  *httptest.Server
+		opts    web.Options
+		start   time.Time
+	)
+
+	host := func(ip, mac, vendor string) scanner.Host {
+		return scanner.Host{IP: net.ParseIP(ip), MAC: mac, Vendor: vendor, Online: true}
+	}
+
+	BeforeEach(func() {
+		_, network, _ := net.ParseCIDR("192.0.2.0/24")
+		monitor = watch.NewMonitor("192.0.2.0/24", network, "arp", time.Minute, 0)
+		opts = web.Options{}
+		start = time.Now().Add(-time.Hour)
+	})
+
+	JustBeforeEach(func() {
+		s := web.New(monitor, opts)
+		server = httptest.NewServer(s.Handler())
+		DeferCleanup(server.Close)
+		DeferCleanup(func() { _ = s.Shutdown(context.Background()) })
+
+		monitor.Update([]scanner.Host{
+			host("192.0.2.10", "aa:bb:cc:00:00:01", "Apple"),
+			host("192.0.2.20", "aa:bb:cc:00:00:02", "Synology"),
+		}, start)
+		monitor.Update([]scanner.Host{host("192.0.2.20", "aa:bb:cc:00:00:02", "Synology")}, start.Add(time.Minute))
+	})
+
+	get := func(path string) (*http.Response, map[string]interface{}) {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		var body map[string]interface{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		return resp, body
+	}
+
+	It("should serve stats", func() {
+		resp, body := get("/api/stats")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(body).To(HaveKeyWithValue("network", "192.0.2.0/24"))
+		Expect(body).To(HaveKeyWithValue("scans", BeEquivalentTo(2)))
+		Expect(body).To(HaveKeyWithValue("online", BeEquivalentTo(1)))
+		Expect(body).To(HaveKeyWithValue("offline", BeEquivalentTo(1)))
+		Expect(body).To(HaveKeyWithValue("read_only", false))
+	})
+
+	It("should list and filter devices", func() {
+		_, body := get("/api/devices")
+		Expect(body["devices"]).To(HaveLen(2))
+
+		_, body = get("/api/devices?filter=" + "vendor%3Dsyno")
+		Expect(body["devices"]).To(HaveLen(1))
+		Expect(body["devices"].([]interface{})[0]).To(HaveKeyWithValue("ip", "192.0.2.20"))
+
+		_, body = get("/api/devices?filter=" + "status%3Doffline")
+		Expect(body["devices"]).To(HaveLen(1))
+		Expect(body["devices"].([]interface{})[0]).To(HaveKeyWithValue("ip", "192.0.2.10"))
+
+		_, body = get("/api/devices?filter=" + "ip%3E192.0.2.15")
+		Expect(body["devices"]).To(HaveLen(1))
+		Expect(body["devices"].([]interface{})[0]).To(HaveKeyWithValue("ip", "192.0.2.20"))
+
+		resp, body := get("/api/devices?filter=" + "vendor%3D%28")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(body).To(HaveKey("error"))
+
+		resp, body = get("/api/devices?filter=" + "uptime%3Esoon")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(body).To(HaveKeyWithValue("error", ContainSubstring("position 8")))
+	})
+
+	It("should return a device with its event history", func() {
+		resp, body := get("/api/devices/192.0.2.10")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(body["device"]).To(HaveKeyWithValue("status", "offline"))
+
+		events := body["events"].([]interface{})
+		Expect(events).To(HaveLen(2))
+		Expect(events[0]).To(HaveKeyWithValue("type", "device-offline"))
+		Expect(events[1]).To(HaveKeyWithValue("type", "device-new"))
+
+		resp, _ = get("/api/devices/192.0.2.99")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should stream events with resumption", func() {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/events", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		lines := make(chan string, 100)
+		go func() {
+			reader := bufio.NewScanner(resp.Body)
+			for reader.Scan() {
+				lines <- reader.Text()
+			}
+			close(lines)
+		}()
+
+		// Verpasste Ereignisse 2 und 3 nachliefern
+		Eventually(lines).Should(Receive(Equal("id: 2")))
+		Eventually(lines).Should(Receive(Equal("event: device-new")))
+		Eventually(lines).Should(Receive(Equal("id: 3")))
+		Eventually(lines).Should(Receive(Equal("event: device-offline")))
+
+		// Live-Ereignis
+		monitor.Update([]scanner.Host{host("192.0.2.10", "aa:bb:cc:00:00:01", "Apple")}, start.Add(2*time.Minute))
+		Eventually(lines).Should(Receive(Equal("event: device-back")))
+		Eventually(lines).Should(Receive(ContainSubstring(`"ip":"192.0.2.10"`)))
+	})
+
+	It("should serve the embedded dashboard", func() {
+		resp, err := http.Get(server.URL + "/")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(data)).To(ContainSubstring("<title>NetSpy</title>"))
+	})
+
+	It("should serve Prometheus metrics", func() {
+		resp, err := http.Get(server.URL + "/metrics")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(string(data)).To(ContainSubstring("netspy_scans_total 2\n"))
+		Expect(string(data)).To(ContainSubstring(`netspy_device_up{ip="192.0.2.10",mac="aa:bb:cc:00:00:01",hostname="",vendor="Apple",device_type=""} 0`))
+	})
+
+	It("should trigger scans", func() {
+		resp, err := http.Post(server.URL+"/api/scan", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	})
+
+	Context("in read-only mode", func() {
+		BeforeEach(func() {
+			opts.ReadOnly = true
+		})
+
+		It("should reject scan requests", func() {
+			resp, err := http.Post(server.URL+"/api/scan", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("with authentication", func() {
+		BeforeEach(func() {
+			opts.Token = "s3cret"
+			opts.Username = "admin"
+			opts.Password = "pw"
+		})
+
+		status := func(req *http.Request) int {
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		It("should require a token or basic auth", func() {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/stats", nil)
+			Expect(status(req)).To(Equal(http.StatusUnauthorized))
+
+			req.Header.Set("Authorization", "Bearer wrong")
+			Expect(status(req)).To(Equal(http.StatusUnauthorized))
+
+			req.Header.Set("Authorization", "Bearer s3cret")
+			Expect(status(req)).To(Equal(http.StatusOK))
+
+			req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/stats?token=s3cret", nil)
+			Expect(status(req)).To(Equal(http.StatusOK))
+
+			req, _ = http.NewRequest(http.MethodGet, server.URL+"/", nil)
+			req.SetBasicAuth("admin", "pw")
+			Expect(status(req)).To(Equal(http.StatusOK))
+
+			req.SetBasicAuth("admin", "nope")
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Basic")).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,286 @@
+// NetSpy Dashboard - spiegelt die tview-Tabelle des Watch-Modus
+"use strict";
+
+const params = new URLSearchParams(location.search);
+const token = params.get("token");
+
+const state = {
+  devices: [],
+  stats: null,
+  filter: params.get("filter") || "",
+  sort: { key: "ip", desc: false },
+  selected: null,
+};
+
+const eventTypes = ["device-new", "device-offline", "device-back", "flapping", "ip-change", "mac-change"];
+
+// api hängt das Token (falls vorhanden) an und wertet JSON-Antworten aus
+async function api(path, options = {}) {
+  const url = new URL(path, location.href);
+  if (token) {
+    url.searchParams.set("token", token);
+  }
+  const response = await fetch(url, options);
+  const body = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function formatDuration(ns) {
+  const seconds = Math.max(0, Math.floor(ns / 1e9));
+  const pad = (n) => String(n).padStart(2, "0");
+  if (seconds < 3600) {
+    return `${pad(Math.floor(seconds / 60))}m${pad(seconds % 60)}s`;
+  }
+  if (seconds < 86400) {
+    return `${pad(Math.floor(seconds / 3600))}h${pad(Math.floor(seconds / 60) % 60)}m`;
+  }
+  return `${pad(Math.floor(seconds / 86400))}d${pad(Math.floor(seconds / 3600) % 24)}h`;
+}
+
+function formatRTT(ns) {
+  if (!ns) {
+    return "-";
+  }
+  if (ns < 1e6) {
+    return `${Math.round(ns / 1e3)}µs`;
+  }
+  return `${(ns / 1e6).toFixed(1)}ms`;
+}
+
+// ipKey sortiert IPv4 numerisch, alles andere als Text
+function ipKey(ip) {
+  const parts = ip.split(".");
+  if (parts.length === 4) {
+    return parts.reduce((acc, part) => acc * 256 + Number(part), 0);
+  }
+  return ip;
+}
+
+function sortValue(device, key) {
+  switch (key) {
+    case "ip":
+      return ipKey(device.ip);
+    case "rtt":
+    case "uptime":
+    case "flap_count":
+      return device[key] || 0;
+    default:
+      return (device[key] || "").toLowerCase();
+  }
+}
+
+function isLocallyAdministered(mac) {
+  const first = parseInt((mac || "").slice(0, 2), 16);
+  return !Number.isNaN(first) && (first & 0x02) !== 0;
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function renderStats() {
+  const s = state.stats;
+  if (!s) {
+    return;
+  }
+  const last = s.last_scan && !s.last_scan.startsWith("0001") ? new Date(s.last_scan).toLocaleTimeString() : "-";
+  document.getElementById("stats").textContent =
+    `${s.network} | mode ${s.mode} | ${s.devices} devices (${s.online} online, ${s.offline} offline) | ` +
+    `scan #${s.scans} at ${last} took ${formatDuration(s.scan_duration)} | threads ${s.active_threads}` +
+    (s.uplink ? ` | uplink ${s.uplink.system_name || s.uplink.chassis_id} ${s.uplink.port_id || ""}` : "");
+  document.getElementById("scan").hidden = s.read_only;
+}
+
+function renderTable() {
+  const devices = [...state.devices].sort((a, b) => {
+    const va = sortValue(a, state.sort.key);
+    const vb = sortValue(b, state.sort.key);
+    const result = va < vb ? -1 : va > vb ? 1 : 0;
+    return state.sort.desc ? -result : result;
+  });
+
+  const tbody = document.querySelector("#devices tbody");
+  tbody.replaceChildren(...devices.map((device) => {
+    const tr = document.createElement("tr");
+    if (device.status === "offline") {
+      tr.classList.add("offline");
+    } else if (device.new) {
+      tr.classList.add("new");
+    }
+
+    let markers = "";
+    if (device.is_gateway) markers += " [G]";
+    if (device.ipv6 && device.ipv6.length) markers += " [6]";
+    if (device.status === "offline") markers += " [!]";
+
+    const ip = cell(device.ip, "ip");
+    if (markers) {
+      const span = document.createElement("span");
+      span.className = "marker";
+      span.textContent = markers;
+      ip.append(span);
+    }
+
+    tr.append(
+      ip,
+      cell(device.hostname || "-"),
+      cell(device.mac || "-", isLocallyAdministered(device.mac) ? "local-mac" : ""),
+      cell(device.vendor || "-"),
+      cell(device.device_type && device.device_type !== "Unknown" ? device.device_type : "-"),
+      cell(formatRTT(device.rtt), "num"),
+      cell(formatDuration(device.uptime), "num"),
+      cell(String(device.flap_count), device.flap_count > 0 ? "num flapping" : "num"),
+    );
+    tr.addEventListener("click", () => showDetails(device.ip));
+    return tr;
+  }));
+
+  document.querySelectorAll("th[data-sort]").forEach((th) => {
+    th.classList.toggle("sorted", th.dataset.sort === state.sort.key);
+    th.classList.toggle("desc", th.dataset.sort === state.sort.key && state.sort.desc);
+  });
+}
+
+async function refresh() {
+  const input = document.getElementById("filter");
+  const error = document.getElementById("filter-error");
+  try {
+    const query = state.filter ? `?filter=${encodeURIComponent(state.filter)}` : "";
+    const [snapshot, stats] = await Promise.all([api(`api/devices${query}`), api("api/stats")]);
+    state.devices = snapshot.devices || [];
+    state.stats = stats;
+    input.classList.remove("invalid");
+    error.textContent = "";
+    renderStats();
+    renderTable();
+    if (state.selected) {
+      showDetails(state.selected);
+    }
+  } catch (err) {
+    input.classList.add("invalid");
+    error.textContent = err.message;
+  }
+}
+
+async function showDetails(ip) {
+  state.selected = ip;
+  let data;
+  try {
+    data = await api(`api/devices/${encodeURIComponent(ip)}`);
+  } catch (err) {
+    return;
+  }
+
+  const device = data.device;
+  document.getElementById("details-title").textContent = device.ip;
+
+  const fields = [
+    ["Status", device.status],
+    ["Hostname", device.hostname ? `${device.hostname} (${device.hostname_source || "?"})` : "-"],
+    ["MAC", device.mac || "-"],
+    ["Vendor", device.vendor || "-"],
+    ["Device", device.device_type || "-"],
+    ["IPv6", (device.ipv6 || []).map((a) => `${a.ip} (${a.kind})`).join(", ") || "-"],
+    ["Ports", (device.ports || []).join(", ") || "-"],
+    ["First seen", new Date(device.first_seen).toLocaleString()],
+    ["Last seen", new Date(device.last_seen).toLocaleString()],
+    ["Status since", new Date(device.status_since).toLocaleString()],
+    ["Flaps", String(device.flap_count)],
+  ];
+  document.getElementById("details-fields").replaceChildren(...fields.flatMap(([name, value]) => {
+    const dt = document.createElement("dt");
+    dt.textContent = name;
+    const dd = document.createElement("dd");
+    dd.textContent = value;
+    return [dt, dd];
+  }));
+
+  const events = data.events || [];
+  document.getElementById("details-events").replaceChildren(...(events.length ? events : [null]).map((event) => {
+    const li = document.createElement("li");
+    li.textContent = event ? `${new Date(event.time).toLocaleString()}  ${event.message}` : "-";
+    return li;
+  }));
+
+  const inventory = data.inventory;
+  document.getElementById("details-inventory").hidden = !inventory;
+  if (inventory) {
+    document.getElementById("details-addresses").replaceChildren(...(inventory.ips || []).map((address) => {
+      const li = document.createElement("li");
+      li.textContent = `${address.ip}  ${new Date(address.first_seen).toLocaleDateString()} – ${new Date(address.last_seen).toLocaleDateString()}`;
+      return li;
+    }));
+  }
+
+  document.getElementById("details").hidden = false;
+}
+
+// connect abonniert den Event-Strom; jede Zustandsänderung lädt die Tabelle neu
+function connect() {
+  const url = new URL("api/events", location.href);
+  if (token) {
+    url.searchParams.set("token", token);
+  }
+  const source = new EventSource(url);
+  const live = document.getElementById("live");
+
+  let pending = null;
+  const scheduleRefresh = () => {
+    if (!pending) {
+      pending = setTimeout(() => {
+        pending = null;
+        refresh();
+      }, 500);
+    }
+  };
+
+  source.onopen = () => live.classList.add("connected");
+  source.onerror = () => live.classList.remove("connected");
+  eventTypes.forEach((type) => source.addEventListener(type, scheduleRefresh));
+}
+
+function init() {
+  const input = document.getElementById("filter");
+  input.value = state.filter;
+
+  let debounce = null;
+  input.addEventListener("input", () => {
+    clearTimeout(debounce);
+    debounce = setTimeout(() => {
+      state.filter = input.value.trim();
+      refresh();
+    }, 250);
+  });
+
+  document.querySelectorAll("th[data-sort]").forEach((th) => {
+    th.addEventListener("click", () => {
+      if (state.sort.key === th.dataset.sort) {
+        state.sort.desc = !state.sort.desc;
+      } else {
+        state.sort = { key: th.dataset.sort, desc: false };
+      }
+      renderTable();
+    });
+  });
+
+  document.getElementById("scan").addEventListener("click", () => api("api/scan", { method: "POST" }).catch(() => {}));
+  document.getElementById("close-details").addEventListener("click", () => {
+    state.selected = null;
+    document.getElementById("details").hidden = true;
+  });
+
+  refresh();
+  connect();
+  // Uptime-Zähler und Stats auch ohne Ereignisse aktuell halten
+  setInterval(refresh, 10000);
+}
+
+init();